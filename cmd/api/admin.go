@@ -395,15 +395,21 @@ func (app *application) adminAddPermissionsForUserHandler(w http.ResponseWriter,
 // adminCreatePaymentPlansHandler() is an admin route that allows the admin
 // to create a new payment/subscription plan. Any plan created and set to 'active'
 // will be shown to all other users. To hide plans, the status should be set to = 'inactive'
+// The price is in minor units of its currency, prices lists what the plan costs in
+// any other currency it is sold in. trial_days gives the plan a free trial and seats
+// makes it a team plan covering that many members of an organization, 1 by default.
 func (app *application) adminCreatePaymentPlansHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
-		Name        string   `json:"name"`
-		Image       string   `json:"image"`
-		Description string   `json:"description"`
-		Duration    string   `json:"duration"`
-		Price       int64    `json:"price"`
-		Features    []string `json:"features"`
-		Status      string   `json:"status"`
+		Name        string       `json:"name"`
+		Image       string       `json:"image"`
+		Description string       `json:"description"`
+		Duration    string       `json:"duration"`
+		Price       data.Money   `json:"price"`
+		Prices      []data.Money `json:"prices"`
+		TrialDays   int32        `json:"trial_days"`
+		Seats       int32        `json:"seats"`
+		Features    []string     `json:"features"`
+		Status      string       `json:"status"`
 	}
 	err := app.readJSON(w, r, &input)
 	if err != nil {
@@ -416,10 +422,16 @@ func (app *application) adminCreatePaymentPlansHandler(w http.ResponseWriter, r
 		Image:       input.Image,
 		Description: input.Description,
 		Duration:    input.Duration,
-		Price:       input.Price,
+		Price:       data.NewMoney(input.Price.Amount, input.Price.Currency),
+		Prices:      app.readPlanPrices(input.Prices),
+		Trial_Days:  input.TrialDays,
+		Seats:       input.Seats,
 		Features:    input.Features,
 		Status:      input.Status,
 	}
+	if paymentPlan.Seats == 0 {
+		paymentPlan.Seats = 1
+	}
 	// validate the data
 	v := validator.New()
 	if data.ValidatePaymentPlan(v, paymentPlan); !v.Valid() {
@@ -445,6 +457,15 @@ func (app *application) adminCreatePaymentPlansHandler(w http.ResponseWriter, r
 	}
 }
 
+// readPlanPrices() normalizes the currencies of the prices an admin gives a plan
+func (app *application) readPlanPrices(prices []data.Money) []data.Money {
+	normalized := []data.Money{}
+	for _, price := range prices {
+		normalized = append(normalized, data.NewMoney(price.Amount, price.Currency))
+	}
+	return normalized
+}
+
 // adminUpdatePaymentPlanHandler() is the admin endpoint that is responsible for updating
 // a payment plan in the DB. It expects a JSON request containing the updated fields.
 // This supports partial updates in that, only the fields that are provided will be updated.
@@ -473,13 +494,16 @@ func (app *application) adminUpdatePaymentPlanHandler(w http.ResponseWriter, r *
 	}
 	// Have the input field that holds the incoming data
 	var input struct {
-		Name        *string  `json:"name"`
-		Image       *string  `json:"image"`
-		Description *string  `json:"description"`
-		Duration    *string  `json:"duration"`
-		Price       *int64   `json:"price"`
-		Features    []string `json:"features"`
-		Status      *string  `json:"status"`
+		Name        *string      `json:"name"`
+		Image       *string      `json:"image"`
+		Description *string      `json:"description"`
+		Duration    *string      `json:"duration"`
+		Price       *data.Money  `json:"price"`
+		Prices      []data.Money `json:"prices"`
+		TrialDays   *int32       `json:"trial_days"`
+		Seats       *int32       `json:"seats"`
+		Features    []string     `json:"features"`
+		Status      *string      `json:"status"`
 	}
 	// read the incoming data
 	err = app.readJSON(w, r, &input)
@@ -502,7 +526,16 @@ func (app *application) adminUpdatePaymentPlanHandler(w http.ResponseWriter, r *
 		paymentPlan.Duration = *input.Duration
 	}
 	if input.Price != nil {
-		paymentPlan.Price = *input.Price
+		paymentPlan.Price = data.NewMoney(input.Price.Amount, input.Price.Currency)
+	}
+	if input.Prices != nil {
+		paymentPlan.Prices = app.readPlanPrices(input.Prices)
+	}
+	if input.TrialDays != nil {
+		paymentPlan.Trial_Days = *input.TrialDays
+	}
+	if input.Seats != nil {
+		paymentPlan.Seats = *input.Seats
 	}
 	if input.Features != nil {
 		paymentPlan.Features = input.Features
@@ -689,6 +722,10 @@ func (app *application) adminCreateNewAnnouncementHandler(w http.ResponseWriter,
 		app.serverErrorResponse(w, r, err)
 		return
 	}
+	// active announcements go out to everyone connected to the notification stream
+	if announcement.IsActive {
+		app.publishNotification(streamEventAnnouncement, announcement)
+	}
 	// write the data back
 	err = app.writeJSON(w, http.StatusCreated, envelope{"announcement": announcement}, nil)
 	if err != nil {
@@ -885,6 +922,8 @@ func (app *application) adminUpdateFeed(w http.ResponseWriter, r *http.Request)
 		app.badRequestResponse(w, r, err)
 		return
 	}
+	// remember the approval status so that we can tell the owner when it changes
+	previousApprovalStatus := adminFeed.Approval_Status
 	// Check if the input fields are empty and if they are, we set them to the current values
 	data.UpdateAdminFeedFields(input, adminFeed)
 	// additional check for the status and priority
@@ -911,6 +950,17 @@ func (app *application) adminUpdateFeed(w http.ResponseWriter, r *http.Request)
 		}
 		return
 	}
+	// let the feed's owner know it was approved or rejected
+	if adminFeed.Approval_Status != previousApprovalStatus {
+		switch adminFeed.Approval_Status {
+		case "approved":
+			app.notifyUser(adminFeed.Feed.UserID, data.InboxTypeFeedApproved,
+				fmt.Sprintf("Your feed %s has been approved", adminFeed.Feed.Name), adminFeed.Feed.ID)
+		case "rejected":
+			app.notifyUser(adminFeed.Feed.UserID, data.InboxTypeFeedRejected,
+				fmt.Sprintf("Your feed %s has been rejected", adminFeed.Feed.Name), adminFeed.Feed.ID)
+		}
+	}
 	// Return a 200 OK status code and the updated feed record in the response body
 	err = app.writeJSON(w, http.StatusOK, envelope{"feed": adminFeed}, nil)
 	if err != nil {
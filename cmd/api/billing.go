@@ -0,0 +1,225 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/blue-davinci/aggregate/internal/data"
+	"github.com/blue-davinci/aggregate/internal/validator"
+	"github.com/google/uuid"
+)
+
+// adminGetBillingRunsHandler() returns the reports of past billing runs, the latest first
+func (app *application) adminGetBillingRunsHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		data.Filters
+	}
+	v := validator.New()
+	qs := r.URL.Query()
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "-started_at")
+	input.Filters.SortSafelist = []string{"-started_at"}
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	runs, metadata, err := app.models.Billing.GetBillingRuns(input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"billing_runs": runs, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// adminGetBillingRunHandler() returns a billing run's report along with what it did
+// with each subscription it picked up.
+func (app *application) adminGetBillingRunHandler(w http.ResponseWriter, r *http.Request) {
+	runID, err := app.readIDIntParam(r, "runID")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	run, err := app.models.Billing.GetBillingRunByID(runID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrBillingRunNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"billing_run": run}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// adminStartBillingRunHandler() starts a billing run without waiting for the schedule.
+// The run carries on in the background, its report can be followed by its ID.
+func (app *application) adminStartBillingRunHandler(w http.ResponseWriter, r *http.Request) {
+	run, err := app.models.Billing.CreateBillingRun(data.BillingTriggerAdmin)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	app.background(func() {
+		app.processBillingRun(run)
+	})
+	err = app.writeJSON(w, http.StatusAccepted, envelope{"billing_run": run}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// runBilling() renews the subscriptions that are due. It is what the payment cron job
+// calls, admins can also start one through adminStartBillingRunHandler().
+func (app *application) runBilling() {
+	run, err := app.models.Billing.CreateBillingRun(data.BillingTriggerSchedule)
+	if err != nil {
+		app.logger.PrintError(err, nil)
+		return
+	}
+	app.processBillingRun(run)
+}
+
+// processBillingRun() claims due subscriptions a batch at a time and tries to renew each.
+// Claimed subscriptions are leased to this run for the billing lock timeout, so other
+// instances running at the same time skip them, and a run stops once a batch comes back
+// short. The lease is extended right before each charge, so a subscription whose lease
+// ran out and was claimed by another instance is skipped rather than charged twice. A
+// renewal that fails is retried on the billing schedule and the user is told each time;
+// once there are no retries left the subscription expires.
+func (app *application) processBillingRun(run *data.BillingRun) {
+	app.logger.PrintInfo("Starting billing run", map[string]string{
+		"Run ID":  fmt.Sprintf("%d", run.ID),
+		"Trigger": run.Trigger,
+	})
+	run.Status = data.BillingRunStatusCompleted
+	batchSize := int32(app.config.billing.batchsize)
+	for {
+		subscriptions, err := app.models.Billing.ClaimDueSubscriptions(batchSize, time.Now().Add(app.config.billing.locktimeout))
+		if err != nil {
+			run.Status = data.BillingRunStatusFailed
+			run.Error_Message = err.Error()
+			app.logger.PrintError(err, map[string]string{"Run ID": fmt.Sprintf("%d", run.ID)})
+			break
+		}
+		for _, subscription := range subscriptions {
+			item := app.renewSubscription(run.ID, subscription)
+			if item == nil {
+				continue
+			}
+			run.Record(item)
+			err = app.models.Billing.CreateBillingRunItem(item)
+			if err != nil {
+				app.logger.PrintError(err, map[string]string{"Subscription ID": subscription.Subscription.ID.String()})
+			}
+		}
+		if len(subscriptions) < int(batchSize) {
+			break
+		}
+	}
+	err := app.models.Billing.FinishBillingRun(run)
+	if err != nil {
+		app.logger.PrintError(err, map[string]string{"Run ID": fmt.Sprintf("%d", run.ID)})
+		return
+	}
+	app.logger.PrintInfo("Finished billing run", map[string]string{
+		"Run ID":    fmt.Sprintf("%d", run.ID),
+		"Processed": fmt.Sprintf("%d", run.Processed),
+		"Renewed":   fmt.Sprintf("%d", run.Renewed),
+		"Expired":   fmt.Sprintf("%d", run.Expired),
+		"Errors":    fmt.Sprintf("%d", run.Errors),
+	})
+}
+
+// renewSubscription() makes one renewal attempt on a claimed subscription and schedules
+// the next one if it didn't go through. It returns nil if the subscription's lease was
+// lost before it could be charged.
+func (app *application) renewSubscription(runID int64, subscription *data.RecurringSubscription) *data.BillingRunItem {
+	item := &data.BillingRunItem{
+		Run_ID:          runID,
+		Subscription_ID: subscription.Subscription.ID,
+		User_ID:         subscription.User_ID,
+		Attempt:         subscription.Renewal_Attempts + 1,
+	}
+	outcome, message, err := app.processRecurringSubscription(subscription)
+	if errors.Is(err, data.ErrRenewalLockLost) {
+		// another run has the subscription now and it's up to that run to report on it
+		app.logger.PrintInfo("Skipping subscription, renewal lock lost", map[string]string{"Subscription ID": subscription.Subscription.ID.String()})
+		return nil
+	}
+	if err != nil {
+		app.logger.PrintError(err, map[string]string{"Subscription ID": subscription.Subscription.ID.String()})
+		outcome, message = data.BillingOutcomeError, err.Error()
+	}
+	item.Outcome, item.Message = outcome, message
+	if outcome == data.BillingOutcomeRenewed {
+		return item
+	}
+	nextAttempt, ok := app.config.billing.schedule.NextAttempt(subscription.Subscription.End_Date, item.Attempt)
+	if !ok {
+		err = app.models.Billing.ExpireSubscription(subscription.Subscription.ID, item.Attempt)
+		if err != nil {
+			app.logger.PrintError(err, map[string]string{"Subscription ID": subscription.Subscription.ID.String()})
+			item.Outcome, item.Message = data.BillingOutcomeError, err.Error()
+			return item
+		}
+		item.Outcome = data.BillingOutcomeExpired
+		app.notifyUser(subscription.User_ID, data.InboxTypeBilling,
+			"We couldn't renew your subscription and it has now expired", uuid.Nil)
+		app.sendDunningEmail(subscription, message, nil)
+		return item
+	}
+	err = app.models.Billing.RecordFailedRenewal(subscription.Subscription.ID, item.Attempt, nextAttempt)
+	if err != nil {
+		app.logger.PrintError(err, map[string]string{"Subscription ID": subscription.Subscription.ID.String()})
+		item.Outcome, item.Message = data.BillingOutcomeError, err.Error()
+		return item
+	}
+	item.Next_Attempt_At = &nextAttempt
+	// a declined charge gets a dunning email; challenged charges already sent the user
+	// the authorization link and errors on our side aren't theirs to fix
+	if outcome == data.BillingOutcomeRetryScheduled {
+		app.notifyUser(subscription.User_ID, data.InboxTypeBilling,
+			fmt.Sprintf("We couldn't renew your subscription, we'll try again on %s", nextAttempt.Format("Jan 2, 2006")), uuid.Nil)
+		app.sendDunningEmail(subscription, message, &nextAttempt)
+	}
+	return item
+}
+
+// sendDunningEmail() tells a user their renewal failed. Without a next attempt it is the
+// final notice that the subscription has expired.
+func (app *application) sendDunningEmail(subscription *data.RecurringSubscription, reason string, nextAttempt *time.Time) {
+	planName := fmt.Sprintf("%d", subscription.Subscription.Plan_ID)
+	plan, err := app.models.Payments.GetPaymentPlanByID(subscription.Subscription.Plan_ID)
+	if err == nil {
+		planName = plan.Name
+	}
+	graceEnds := subscription.Subscription.End_Date.Add(app.config.billing.schedule.Grace_Period)
+	app.background(func() {
+		data := map[string]any{
+			"UserName":  subscription.User_Name,
+			"PlanName":  planName,
+			"Amount":    subscription.Subscription.Price.Major(),
+			"Currency":  subscription.Subscription.Price.Currency,
+			"Reason":    reason,
+			"Final":     nextAttempt == nil,
+			"GraceEnds": graceEnds.Format("Jan 2, 2006"),
+		}
+		if nextAttempt != nil {
+			data["NextAttempt"] = nextAttempt.Format("Jan 2, 2006")
+		}
+		err := app.mailer.Send(subscription.User_Email, "billing_dunning.tmpl", data)
+		if err != nil {
+			app.logger.PrintError(err, nil)
+		}
+	})
+}
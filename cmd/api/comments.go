@@ -2,7 +2,9 @@ package main
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/blue-davinci/aggregate/internal/data"
 	"github.com/blue-davinci/aggregate/internal/validator"
@@ -24,6 +26,10 @@ func (app *application) createCommentHandler(w http.ResponseWriter, r *http.Requ
 		app.badRequestResponse(w, r, err)
 		return
 	}
+	// muted and banned users can't comment
+	if app.commentingRestricted(w, r, app.contextGetUser(r).ID) {
+		return
+	}
 	// Check if parent comment id is nil, if so we use uuid.Nil
 	// This signifies a parent comment rather than a child comment. Can also
 	// Help in filtering in future updates
@@ -38,18 +44,71 @@ func (app *application) createCommentHandler(w http.ResponseWriter, r *http.Requ
 		Parent_Comment_ID: input.Parent_Comment_ID,
 		Comment_Text:      input.Comment_Text,
 	}
-	// validate the Post ID and comment text
+	// replies need their parent to check the post and the thread depth
+	var parent *data.CommentParent
+	if comment.Parent_Comment_ID.Valid {
+		parent, err = app.models.Comments.GetCommentParent(comment.Parent_Comment_ID.UUID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrCommentNotFound):
+				v := validator.New()
+				v.AddError("parent_comment_id", "comment does not exist")
+				app.failedValidationResponse(w, r, v.Errors)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+	}
+	// validate the Post ID, comment text and parent
 	v := validator.New()
-	if data.ValidateComment(v, comment); !v.Valid() {
+	if data.ValidateComment(v, comment, parent, app.config.comments.maxdepth); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
+	// comments that look like spam are held back as pending for an admin to review
+	screened := app.screenComment(comment)
 	// Create the comment
 	err = app.models.Comments.CreateComment(comment)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
+	if screened.Flagged {
+		err = app.models.Moderation.RecordCommentScreening(comment.ID, screened)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+	// store the users the comment mentions, they are notified with the other recipients
+	mentioned, err := app.models.Comments.SetCommentMentions(comment.ID, comment.User_ID, data.FindMentionHandles(comment.Comment_Text))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	comment.Mentions, err = app.models.Comments.GetCommentMentions(comment)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	// Return the comment with a 202 Accepted status code while it awaits review, nobody
+	// is notified of it until it is approved
+	if comment.Status == data.CommentStatusPending {
+		err = app.writeJSON(w, http.StatusAccepted, envelope{"comment": comment, "message": "your comment is awaiting review"}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	// add the comment to the recipients' inboxes and push it to those connected
+	app.background(func() {
+		err := app.models.Inbox.CreateCommentNotifications(comment.ID)
+		if err != nil {
+			app.logger.PrintError(err, nil)
+		}
+		app.publishCommentNotification(comment, mentioned)
+	})
 	// Return the comment with a 201 Created status code
 	err = app.writeJSON(w, http.StatusCreated, envelope{"comment": comment}, nil)
 	if err != nil {
@@ -57,8 +116,10 @@ func (app *application) createCommentHandler(w http.ResponseWriter, r *http.Requ
 	}
 }
 
-// getCommentsForPostHandler retrieves all comments for a specific post based
-// on the posts Post ID
+// getCommentsForPostHandler retrieves the comment threads for a specific post based
+// on the posts Post ID. Top level comments are paginated and each comes with its replies
+// nested up to the configured depth. Passing a parent_id loads more replies for that
+// comment instead, eg: /follow/posts/comments/{postID}?parent_id=...&sort=top&page=2
 func (app *application) getCommentsForPostHandler(w http.ResponseWriter, r *http.Request) {
 	//  Read our post ID as a parameter from the URL
 	postID, err := app.readIDParam(r, "postID")
@@ -72,14 +133,35 @@ func (app *application) getCommentsForPostHandler(w http.ResponseWriter, r *http
 		app.badRequestResponse(w, r, err)
 		return
 	}
-	// Get all comments for the post
-	comments, err := app.models.Comments.GetCommentsForPost(postID, app.contextGetUser(r).ID)
+	var input struct {
+		Parent_ID uuid.UUID
+		data.Filters
+	}
+	v := validator.New()
+	qs := r.URL.Query()
+	if qs.Get("parent_id") != "" {
+		input.Parent_ID, err = app.readIDFromQuery(r, "parent_id")
+		if err != nil {
+			v.AddError("parent_id", "must be a valid UUID")
+		}
+	}
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", data.CommentSortNewest)
+	input.Filters.SortSafelist = []string{data.CommentSortNewest, data.CommentSortOldest, data.CommentSortTop}
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	// Get the comment threads for the post
+	comments, metadata, err := app.models.Comments.GetCommentsForPost(postID, app.contextGetUser(r).ID,
+		input.Parent_ID, app.config.comments.maxdepth, input.Filters.Sort, app.config.comments.editwindow, input.Filters)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 	// Return the comments
-	err = app.writeJSON(w, http.StatusOK, envelope{"comments": comments}, nil)
+	err = app.writeJSON(w, http.StatusOK, envelope{"comments": comments, "metadata": metadata}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -132,6 +214,10 @@ func (app *application) updateUserCommentHandler(w http.ResponseWriter, r *http.
 		app.badRequestResponse(w, r, err)
 		return
 	}
+	// muted and banned users can't comment
+	if app.commentingRestricted(w, r, app.contextGetUser(r).ID) {
+		return
+	}
 	// Create a new update comment
 	comment := &data.Comment{
 		ID:           input.ID,
@@ -145,7 +231,7 @@ func (app *application) updateUserCommentHandler(w http.ResponseWriter, r *http.
 		return
 	}
 	// Check if the comment exists
-	_, err = app.models.Comments.GetCommentByID(comment.ID, app.contextGetUser(r).ID)
+	existing, err := app.models.Comments.GetCommentByID(comment.ID, app.contextGetUser(r).ID)
 	// if there is an error, we check if it is a not found error
 	if err != nil {
 		switch {
@@ -156,8 +242,27 @@ func (app *application) updateUserCommentHandler(w http.ResponseWriter, r *http.
 		}
 		return
 	}
+	// moderated and deleted comments are frozen
+	if existing.Status == data.CommentStatusPending {
+		app.errorResponse(w, r, http.StatusForbidden, "this comment is awaiting review and can not be edited yet")
+		return
+	}
+	if existing.Status != data.CommentStatusVisible {
+		app.errorResponse(w, r, http.StatusForbidden, "this comment has been removed and can no longer be edited")
+		return
+	}
+	// comments are frozen once the edit window has passed
+	if existing.Created_At.Before(data.CommentEditCutoff(app.config.comments.editwindow, time.Now())) {
+		message := fmt.Sprintf("comments can only be edited within %s of being posted", app.config.comments.editwindow)
+		app.errorResponse(w, r, http.StatusForbidden, message)
+		return
+	}
+	// edits are screened like new comments, a flagged one goes back to pending for review
+	comment.User_ID = existing.User_ID
+	comment.Status = data.CommentStatusVisible
+	screened := app.screenCommentEdit(comment)
 	// Update the comment
-	version, err := app.models.Comments.UpdateUserComment(comment, app.contextGetUser(r).ID)
+	version, err := app.models.Comments.UpdateUserComment(comment, app.contextGetUser(r).ID, app.config.comments.editwindow)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrEditConflict):
@@ -167,13 +272,188 @@ func (app *application) updateUserCommentHandler(w http.ResponseWriter, r *http.
 		}
 		return
 	}
+	if screened.Flagged {
+		err = app.models.Moderation.RecordCommentScreening(comment.ID, screened)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+	// refresh the mentions, only users who weren't mentioned before the edit are notified
+	comment.Post_ID = existing.Post_ID
+	comment.Created_At = existing.Created_At
+	comment.Parent_Comment_ID = existing.Parent_Comment_ID
+	mentioned, err := app.models.Comments.SetCommentMentions(comment.ID, comment.User_ID, data.FindMentionHandles(comment.Comment_Text))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	mentions, err := app.models.Comments.GetCommentMentions(comment)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	// nobody is told about mentions in an edit held for review, the approval notifies them
+	if comment.Status == data.CommentStatusPending {
+		err = app.writeJSON(w, http.StatusAccepted,
+			envelope{
+				"message":   "your comment is awaiting review",
+				"version":   version,
+				"edited_at": comment.Edited_At,
+				"mentions":  mentions,
+			}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if len(mentioned) > 0 {
+		app.background(func() {
+			err := app.models.Inbox.CreateMentionNotifications(comment.ID, mentioned)
+			if err != nil {
+				app.logger.PrintError(err, nil)
+			}
+			app.publishMentionNotification(comment, mentioned)
+		})
+	}
 	// Return the comment with a 200 OK status code
 	err = app.writeJSON(w, http.StatusOK,
 		envelope{
-			"message": "comment updated successfully",
-			"version": version,
+			"message":   "comment updated successfully",
+			"version":   version,
+			"edited_at": comment.Edited_At,
+			"mentions":  mentions,
 		}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
+
+// reactToCommentHandler sets the user's reaction to a comment, replacing any reaction they
+// already had, eg: PUT /follow/posts/comments/{commentID}/reactions with {"reaction": "upvote"}
+// The comment's author is told about new reactions but not about changed ones.
+func (app *application) reactToCommentHandler(w http.ResponseWriter, r *http.Request) {
+	commentID, err := app.readIDParam(r, "commentID")
+	if err != nil || commentID == uuid.Nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	var input struct {
+		Reaction string `json:"reaction"`
+	}
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	user := app.contextGetUser(r)
+	reaction := &data.CommentReaction{
+		Comment_ID: commentID,
+		User_ID:    user.ID,
+		Reaction:   input.Reaction,
+	}
+	v := validator.New()
+	if data.ValidateCommentReaction(v, reaction); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	authorID, postID, err := app.models.Comments.GetCommentOwner(commentID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrCommentNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	isNew, err := app.models.Comments.ReactToComment(reaction)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	summary, err := app.models.Comments.GetCommentReactionSummary(commentID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if isNew && authorID != user.ID {
+		app.background(func() {
+			app.sendUserNotification(&data.UserNotification{
+				User_ID:           authorID,
+				Notification_Type: data.InboxTypeCommentReaction,
+				Message:           fmt.Sprintf("%s reacted to your comment with %s", user.Name, reaction.Reaction),
+				Post_ID:           uuid.NullUUID{UUID: postID, Valid: true},
+				Comment_ID:        uuid.NullUUID{UUID: commentID, Valid: true},
+			})
+		})
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"reaction": reaction, "summary": summary}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// removeCommentReactionHandler removes the user's reaction from a comment,
+// eg: DELETE /follow/posts/comments/{commentID}/reactions
+func (app *application) removeCommentReactionHandler(w http.ResponseWriter, r *http.Request) {
+	commentID, err := app.readIDParam(r, "commentID")
+	if err != nil || commentID == uuid.Nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	err = app.models.Comments.RemoveCommentReaction(commentID, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrCommentNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	summary, err := app.models.Comments.GetCommentReactionSummary(commentID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "reaction removed successfully", "summary": summary}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getCommentRevisionsHandler returns the revision history of one of the user's own
+// comments, eg: GET /follow/posts/comments/{commentID}/revisions
+func (app *application) getCommentRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	commentID, err := app.readIDParam(r, "commentID")
+	if err != nil || commentID == uuid.Nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	// other users' comments are reported as not found
+	app.writeCommentRevisions(w, r, commentID, app.contextGetUser(r).ID)
+}
+
+// writeCommentRevisions() responds with the revision history of a comment by the given author
+func (app *application) writeCommentRevisions(w http.ResponseWriter, r *http.Request, commentID uuid.UUID, authorID int64) {
+	comment, err := app.models.Comments.GetCommentByID(commentID, authorID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrCommentNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	revisions, err := app.models.Comments.GetCommentRevisions(comment)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"comment_id": comment.ID, "status": comment.Status, "revisions": revisions}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/blue-davinci/aggregate/internal/data"
+	"github.com/blue-davinci/aggregate/internal/validator"
+	"github.com/google/uuid"
+)
+
+// adminGetAllCouponsHandler() lists the coupons, the newest first. ?code= searches by code
+// and ?status= shows only active or inactive coupons.
+func (app *application) adminGetAllCouponsHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Code   string
+		Status string
+		data.Filters
+	}
+	v := validator.New()
+	qs := r.URL.Query()
+	input.Code = app.readString(qs, "code", "")
+	input.Status = app.readString(qs, "status", "")
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "-created_at")
+	input.Filters.SortSafelist = []string{"-created_at"}
+	v.Check(input.Status == "" || input.Status == data.CouponStatusActive || input.Status == data.CouponStatusInactive,
+		"status", "must be either 'active' or 'inactive'")
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	coupons, metadata, err := app.models.Coupons.GetAllCoupons(input.Code, input.Status, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"coupons": coupons, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// adminGetCouponHandler() returns a coupon along with how often it was redeemed
+func (app *application) adminGetCouponHandler(w http.ResponseWriter, r *http.Request) {
+	couponID, err := app.readIDIntParam(r, "couponID")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	coupon, err := app.models.Coupons.GetCouponByID(couponID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrCouponNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"coupon": coupon}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// adminCreateCouponHandler() creates a promo code. A percent coupon takes percent_off of
+// the price, a fixed one takes amount_off, in minor units of its currency, off prices in
+// that currency. max_redemptions, expires_at and plan_ids are optional limits.
+func (app *application) adminCreateCouponHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Code           string      `json:"code"`
+		Description    string      `json:"description"`
+		DiscountType   string      `json:"discount_type"`
+		PercentOff     int32       `json:"percent_off"`
+		AmountOff      *data.Money `json:"amount_off"`
+		MaxRedemptions int32       `json:"max_redemptions"`
+		ExpiresAt      *time.Time  `json:"expires_at"`
+		PlanIDs        []int32     `json:"plan_ids"`
+		Status         string      `json:"status"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	coupon := &data.Coupon{
+		Code:            data.NormalizeCouponCode(input.Code),
+		Description:     input.Description,
+		Discount_Type:   input.DiscountType,
+		Percent_Off:     input.PercentOff,
+		Max_Redemptions: input.MaxRedemptions,
+		Expires_At:      input.ExpiresAt,
+		Plan_IDs:        input.PlanIDs,
+		Status:          input.Status,
+	}
+	if input.AmountOff != nil {
+		amountOff := data.NewMoney(input.AmountOff.Amount, input.AmountOff.Currency)
+		coupon.Amount_Off = &amountOff
+	}
+	if coupon.Plan_IDs == nil {
+		coupon.Plan_IDs = []int32{}
+	}
+	if coupon.Status == "" {
+		coupon.Status = data.CouponStatusActive
+	}
+	v := validator.New()
+	v.Check(coupon.Expires_At == nil || coupon.Expires_At.After(time.Now()), "expires_at", "must be in the future")
+	if data.ValidateCoupon(v, coupon); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	if ok := app.validateCouponPlans(w, r, coupon.Plan_IDs); !ok {
+		return
+	}
+	err = app.models.Coupons.CreateCoupon(coupon)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateCouponCode):
+			v.AddError("code", "a coupon with this code already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusCreated, envelope{"coupon": coupon}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// adminUpdateCouponHandler() changes a coupon's description, limits, plans or status.
+// The code and the discount can't change as redemptions were made with them, a new
+// coupon is made for that instead.
+func (app *application) adminUpdateCouponHandler(w http.ResponseWriter, r *http.Request) {
+	couponID, err := app.readIDIntParam(r, "couponID")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	coupon, err := app.models.Coupons.GetCouponByID(couponID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrCouponNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	var input struct {
+		Description    *string    `json:"description"`
+		MaxRedemptions *int32     `json:"max_redemptions"`
+		ExpiresAt      *time.Time `json:"expires_at"`
+		PlanIDs        []int32    `json:"plan_ids"`
+		Status         *string    `json:"status"`
+	}
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if input.Description != nil {
+		coupon.Description = *input.Description
+	}
+	if input.MaxRedemptions != nil {
+		coupon.Max_Redemptions = *input.MaxRedemptions
+	}
+	if input.ExpiresAt != nil {
+		coupon.Expires_At = input.ExpiresAt
+	}
+	if input.PlanIDs != nil {
+		coupon.Plan_IDs = input.PlanIDs
+	}
+	if input.Status != nil {
+		coupon.Status = *input.Status
+	}
+	v := validator.New()
+	v.Check(coupon.Max_Redemptions == 0 || coupon.Max_Redemptions >= coupon.Times_Redeemed, "max_redemptions",
+		"must not be less than the times the coupon was already redeemed")
+	if data.ValidateCoupon(v, coupon); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	if input.PlanIDs != nil {
+		if ok := app.validateCouponPlans(w, r, coupon.Plan_IDs); !ok {
+			return
+		}
+	}
+	err = app.models.Coupons.UpdateCoupon(coupon)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"coupon": coupon}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// validateCouponPlans() checks that the plans a coupon is limited to exist, writing the
+// error response when one doesn't.
+func (app *application) validateCouponPlans(w http.ResponseWriter, r *http.Request, planIDs []int32) bool {
+	for _, planID := range planIDs {
+		_, err := app.models.Admin.AdminGetPaymentPlanByID(planID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrPaymentPlanNotFound):
+				v := validator.New()
+				v.AddError("plan_ids", "must only contain existing plans")
+				app.failedValidationResponse(w, r, v.Errors)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return false
+		}
+	}
+	return true
+}
+
+// readPromoCode() reserves one of the redemptions of the coupon behind a promo code for
+// the user and returns it, with what it takes off the price of a plan. It writes the error
+// response itself when the code can't be used, in which case ok is false. A reservation
+// that isn't paid for is released, see releaseCouponRedemption().
+func (app *application) readPromoCode(w http.ResponseWriter, r *http.Request, code string, userID int64, plan *data.Payment_Plan, price data.Money) (*data.CouponRedemption, data.Money, bool) {
+	v := validator.New()
+	coupon, err := app.models.Coupons.GetCouponByCode(code)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrCouponNotFound):
+			v.AddError("promo_code", "is not a valid promo code")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, data.Money{}, false
+	}
+	if data.ValidateCouponRedemption(v, coupon, plan.ID, price, time.Now()); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return nil, data.Money{}, false
+	}
+	discount := coupon.Discount(price)
+	redemption := &data.CouponRedemption{
+		Coupon_ID: coupon.ID,
+		Code:      coupon.Code,
+		User_ID:   userID,
+		Plan_ID:   plan.ID,
+		Discount:  discount,
+	}
+	err = app.models.Coupons.ReserveCouponRedemption(redemption, time.Now().Add(app.config.payments.couponhold))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrCouponAlreadyRedeemed):
+			v.AddError("promo_code", "has already been used on your account")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrCouponFullyRedeemed):
+			v.AddError("promo_code", "has been fully redeemed")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, data.Money{}, false
+	}
+	return redemption, discount, true
+}
+
+// releaseCouponRedemption() gives a reserved redemption back to its coupon when the
+// checkout or trial it was reserved for couldn't be started.
+func (app *application) releaseCouponRedemption(redemption *data.CouponRedemption) {
+	if redemption == nil {
+		return
+	}
+	err := app.models.Coupons.ReleaseCouponRedemption(redemption)
+	if err != nil {
+		app.logger.PrintError(err, map[string]string{"code": redemption.Code})
+	}
+}
+
+// releaseExpiredCouponRedemptions() gives back the redemptions held by checkouts that
+// weren't paid in time and by trials that ended unpaid.
+func (app *application) releaseExpiredCouponRedemptions() {
+	released, err := app.models.Coupons.ReleaseExpiredCouponRedemptions()
+	if err != nil {
+		app.logger.PrintError(err, nil)
+		return
+	}
+	for code, count := range released {
+		app.logger.PrintInfo("Released promo code redemptions", map[string]string{
+			"code":     code,
+			"released": fmt.Sprintf("%d", count),
+		})
+	}
+}
+
+// couponRedemptionForReference() returns the promo code redemption waiting on the charge
+// behind a reference, nil when no code was used on it.
+func (app *application) couponRedemptionForReference(reference string) (*data.CouponRedemption, error) {
+	redemption, err := app.models.Coupons.GetUnredeemedCouponRedemptionByReference(reference)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrCouponRedemptionNotFound):
+			return nil, nil
+		default:
+			return nil, err
+		}
+	}
+	return redemption, nil
+}
+
+// redeemCoupon() counts a promo code against its coupon once the payment it discounted
+// started a subscription. A user who paid a checkout after its redemption was released
+// keeps the discount even when the coupon ran out or they used it again since, those
+// redemptions are only logged.
+func (app *application) redeemCoupon(redemption *data.CouponRedemption, subscriptionID uuid.UUID) error {
+	err := app.models.Coupons.RedeemCoupon(redemption, subscriptionID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrCouponAlreadyRedeemed), errors.Is(err, data.ErrCouponFullyRedeemed),
+			errors.Is(err, data.ErrCouponRedemptionNotFound):
+			app.logger.PrintInfo("promo code not redeemed", map[string]string{
+				"code":  redemption.Code,
+				"error": err.Error(),
+			})
+		default:
+			return err
+		}
+	}
+	return nil
+}
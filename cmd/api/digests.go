@@ -0,0 +1,215 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/blue-davinci/aggregate/internal/data"
+	"github.com/blue-davinci/aggregate/internal/validator"
+)
+
+// getDigestPreferenceHandler() returns the user's digest settings. Users that never
+// opted in get the defaults back with enabled set to false.
+func (app *application) getDigestPreferenceHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	preference, err := app.models.Digests.GetDigestPreference(user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDigestPreferenceNotFound):
+			preference = defaultDigestPreference(user.ID)
+		default:
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"digest": preference}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateDigestPreferenceHandler() opts a user in or out of the email digest and updates
+// the frequency, timezone and delivery time. Only the provided fields are changed.
+func (app *application) updateDigestPreferenceHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Frequency *string `json:"frequency"`
+		Timezone  *string `json:"timezone"`
+		Send_Hour *int32  `json:"send_hour"`
+		Weekday   *int32  `json:"weekday"`
+		Enabled   *bool   `json:"enabled"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	user := app.contextGetUser(r)
+	preference, err := app.models.Digests.GetDigestPreference(user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDigestPreferenceNotFound):
+			// saving preferences for the first time means the user is opting in
+			preference = defaultDigestPreference(user.ID)
+			preference.Enabled = true
+		default:
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+	if input.Frequency != nil {
+		preference.Frequency = *input.Frequency
+	}
+	if input.Timezone != nil {
+		preference.Timezone = *input.Timezone
+	}
+	if input.Send_Hour != nil {
+		preference.Send_Hour = *input.Send_Hour
+	}
+	if input.Weekday != nil {
+		preference.Weekday = *input.Weekday
+	}
+	if input.Enabled != nil {
+		preference.Enabled = *input.Enabled
+	}
+	v := validator.New()
+	if data.ValidateDigestPreference(v, preference); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	err = app.models.Digests.UpsertDigestPreference(preference)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"digest": preference}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// unsubscribeDigestConfirmation is the page people clicking the unsubscribe link land on,
+// its form posts the token back to unsubscribe them.
+var unsubscribeDigestConfirmation = template.Must(template.New("unsubscribe").Parse(`<!doctype html>
+<html>
+  <head>
+    <meta name="viewport" content="width=device-width" />
+    <title>Unsubscribe from the email digest</title>
+  </head>
+  <body>
+    <p>Stop getting the Aggregate email digest?</p>
+    <form method="post" action="?token={{.}}">
+      <button type="submit">Unsubscribe</button>
+    </form>
+  </body>
+</html>
+`))
+
+// confirmUnsubscribeDigestHandler() answers the unsubscribe link in the digests with a
+// page asking the user to confirm. Link scanners and prefetchers follow the link with a
+// GET, so nothing is changed until the form is posted.
+func (app *application) confirmUnsubscribeDigestHandler(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		app.notFoundResponse(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err := unsubscribeDigestConfirmation.Execute(w, token)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// unsubscribeDigestHandler() is the one-click unsubscribe endpoint linked from every
+// digest. Only POST unsubscribes, sent either by the confirmation page or by mail
+// clients on behalf of the user as per RFC 8058.
+func (app *application) unsubscribeDigestHandler(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		app.notFoundResponse(w, r)
+		return
+	}
+	_, err := app.models.Digests.UnsubscribeByToken(token)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDigestPreferenceNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "you have been unsubscribed from the email digest"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// sendDigestEmailsHandler() runs hourly from the notifier's cron job. For every user that
+// opted in we check whether their local send time has come and, if so, mail them the top
+// posts from the feeds they follow since their last digest. Users without new posts are
+// skipped but still marked as sent so that we don't keep checking them for this period.
+func (app *application) sendDigestEmailsHandler() {
+	app.logger.PrintInfo("Running digest Worker...", nil)
+	recipients, err := app.models.Digests.GetEnabledDigestRecipients()
+	if err != nil {
+		app.logger.PrintError(err, nil)
+		return
+	}
+	now := time.Now()
+	for _, recipient := range recipients {
+		if !recipient.Due(now) {
+			continue
+		}
+		posts, err := app.models.Digests.GetDigestPostsForUser(recipient.User_ID, recipient.Since(now), app.config.digest.postsperfeed)
+		if err != nil {
+			app.logger.PrintError(err, map[string]string{
+				"User ID": fmt.Sprintf("%d", recipient.User_ID),
+			})
+			continue
+		}
+		if len(posts) > 0 {
+			unsubscribeURL := app.config.digest.unsubscribeurl + url.QueryEscape(recipient.Unsubscribe_Token)
+			emailData := map[string]any{
+				"name":           recipient.Name,
+				"frequency":      recipient.Frequency,
+				"feeds":          data.GroupDigestPostsByFeed(posts),
+				"frontendURL":    app.config.frontend.baseurl,
+				"unsubscribeURL": unsubscribeURL,
+			}
+			headers := map[string]string{
+				"List-Unsubscribe":      fmt.Sprintf("<%s>", unsubscribeURL),
+				"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+			}
+			err = app.mailer.SendWithHeaders(recipient.Email, "digest.tmpl", emailData, headers)
+			if err != nil {
+				app.logger.PrintError(err, map[string]string{
+					"User ID": fmt.Sprintf("%d", recipient.User_ID),
+				})
+				continue
+			}
+		}
+		err = app.models.Digests.MarkDigestSent(recipient.User_ID, now)
+		if err != nil {
+			app.logger.PrintError(err, nil)
+		}
+		app.logger.PrintInfo("Processed digest", map[string]string{
+			"User ID": fmt.Sprintf("%d", recipient.User_ID),
+			"Posts":   fmt.Sprintf("%d", len(posts)),
+		})
+	}
+}
+
+func defaultDigestPreference(userID int64) *data.DigestPreference {
+	return &data.DigestPreference{
+		User_ID:   userID,
+		Frequency: data.DigestFrequencyDaily,
+		Timezone:  "UTC",
+		Send_Hour: 8,
+		Weekday:   int32(time.Monday),
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/blue-davinci/aggregate/internal/data"
+	"github.com/blue-davinci/aggregate/internal/validator"
+)
+
+// resolveEntitlements() returns what a user's plan entitles them to. Users on the free
+// plan fall back to the limitation flags for any quota the free plan leaves out, which
+// is how they were limited before plans carried their own quotas.
+func (app *application) resolveEntitlements(userID int64) (*data.Entitlements, error) {
+	entitlements, err := app.models.Entitlements.GetUserEntitlements(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !entitlements.Subscribed {
+		entitlements.SetDefaultQuotas(map[string]int64{
+			data.EntitlementFeedsCreated:   int64(app.config.limitations.maxFeedsCreated),
+			data.EntitlementFeedsFollowed:  int64(app.config.limitations.maxFeedsFollowed),
+			data.EntitlementCommentsPerDay: int64(app.config.limitations.maxComments),
+		})
+	}
+	return entitlements, nil
+}
+
+// hasFeature() checks a feature for handlers that only gate part of what they do. It
+// writes the error response itself, callers just return when it reports false.
+func (app *application) hasFeature(w http.ResponseWriter, r *http.Request, feature string) bool {
+	entitlements, err := app.resolveEntitlements(app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return false
+	}
+	if !entitlements.HasFeature(feature) {
+		app.featureUnavailableResponse(w, r)
+		return false
+	}
+	return true
+}
+
+// getEntitlementsHandler() shows the user their plan's quotas next to how much of each
+// they have used along with the features the plan includes.
+func (app *application) getEntitlementsHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	entitlements, err := app.resolveEntitlements(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	limitations, err := app.models.Limitations.GetUserLimitations(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"entitlements": entitlements.Usage(limitations)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// adminGetPlanEntitlementsHandler() returns the quotas and features a payment plan sets
+func (app *application) adminGetPlanEntitlementsHandler(w http.ResponseWriter, r *http.Request) {
+	plan, ok := app.readPaymentPlanParam(w, r)
+	if !ok {
+		return
+	}
+	entitlements, err := app.models.Entitlements.GetPlanEntitlements(plan.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"payment_plan": plan, "entitlements": entitlements}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// adminSetPlanEntitlementsHandler() replaces the quotas and features of a payment plan.
+// It expects the full list, eg: {"entitlements": [{"name": "feeds_created", "quota": 20},
+// {"name": "outbound_feeds", "enabled": true}]}. A quota without a limit is unlimited and
+// anything left out is removed from the plan.
+func (app *application) adminSetPlanEntitlementsHandler(w http.ResponseWriter, r *http.Request) {
+	plan, ok := app.readPaymentPlanParam(w, r)
+	if !ok {
+		return
+	}
+	var input struct {
+		Entitlements []*data.PlanEntitlement `json:"entitlements"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	v := validator.New()
+	if data.ValidatePlanEntitlements(v, input.Entitlements); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	err = app.models.Entitlements.SetPlanEntitlements(plan.ID, input.Entitlements)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	entitlements, err := app.models.Entitlements.GetPlanEntitlements(plan.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"payment_plan": plan, "entitlements": entitlements}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readPaymentPlanParam() reads the {planID} URL param and loads the plan. It writes the
+// error response itself and reports false when there is no such plan.
+func (app *application) readPaymentPlanParam(w http.ResponseWriter, r *http.Request) (*data.Payment_Plan, bool) {
+	planID, err := app.readIDIntParam(r, "planID")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return nil, false
+	}
+	if planID < 1 {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+	plan, err := app.models.Admin.AdminGetPaymentPlanByID(int32(planID))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+	return plan, true
+}
@@ -79,9 +79,16 @@ func (app *application) inactiveAccountResponse(w http.ResponseWriter, r *http.R
 }
 
 // The limitationResponse() method will be used to send a 403 Forbidden status if user
-// is authenticated and not subscribed but has reached their limit for a particular action
+// is authenticated but has reached their plan's limit for a particular action
 func (app *application) limitationResponse(w http.ResponseWriter, r *http.Request) {
-	message := "you have reached your unsubscribed limit for this action"
+	message := "you have reached your plan's limit for this action"
+	app.errorResponse(w, r, http.StatusForbidden, message)
+}
+
+// The featureUnavailableResponse() method will return a 403 Forbidden status when the
+// user's plan doesn't include the feature they are trying to use.
+func (app *application) featureUnavailableResponse(w http.ResponseWriter, r *http.Request) {
+	message := "your plan doesn't include this feature"
 	app.errorResponse(w, r, http.StatusForbidden, message)
 }
 
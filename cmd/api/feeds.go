@@ -141,8 +141,14 @@ func (app *application) getListOfFollowedFeedsHandler(w http.ResponseWriter, r *
 		app.serverErrorResponse(w, r, err)
 		return
 	}
+	// Saved searches are virtual feeds, so they are listed alongside the followed feeds
+	savedSearches, err := app.models.SavedSearches.GetSavedSearchesForUser(app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
 	// Return the feeds in the response body
-	err = app.writeJSON(w, http.StatusOK, envelope{"feeds": feeds, "metadata": metadata}, nil)
+	err = app.writeJSON(w, http.StatusOK, envelope{"feeds": feeds, "saved_searches": savedSearches, "metadata": metadata}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
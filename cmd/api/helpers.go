@@ -218,6 +218,32 @@ func (app *application) readInt(qs url.Values, key string, defaultValue int, v *
 	return i
 }
 
+// The readTime() helper reads a date from the query string. We accept both a plain date
+// i.e 2024-07-01 and a full RFC3339 timestamp. If no key exists we return a zero time,
+// and if the value can't be parsed we add an error to the validator.
+func (app *application) readTime(qs url.Values, key string, v *validator.Validator) time.Time {
+	s := qs.Get(key)
+	if s == "" {
+		return time.Time{}
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t
+	}
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		v.AddError(key, "must be a date (2006-01-02) or an RFC3339 timestamp")
+		return time.Time{}
+	}
+	return t
+}
+
+// The readLocale() helper returns the locale of a request, which picks the currency
+// prices are shown and charged in. A "locale" query parameter such as en-KE wins over
+// the browser's Accept-Language header.
+func (app *application) readLocale(r *http.Request) string {
+	return app.readString(r.URL.Query(), "locale", r.Header.Get("Accept-Language"))
+}
+
 // Retrieve the "id" URL parameter from the current request context, then convert it to
 // an integer and return it. If the operation isn't successful, return 0 and an error.
 func (app *application) readIDIntParam(r *http.Request, parameterName string) (int64, error) {
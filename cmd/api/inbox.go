@@ -0,0 +1,158 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/blue-davinci/aggregate/internal/data"
+	"github.com/blue-davinci/aggregate/internal/validator"
+	"github.com/google/uuid"
+)
+
+const streamEventInboxNotification = "inbox_notification"
+
+// getInboxHandler() returns a page of the user's notification inbox, newest first,
+// eg: GET /notifications/inbox?status=unread&type=reply&page=1&page_size=20
+// status is one of all (default), unread or read. Dismissed notifications are never listed.
+func (app *application) getInboxHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		data.InboxFilters
+		data.Filters
+	}
+	v := validator.New()
+	qs := r.URL.Query()
+	input.InboxFilters.Status = app.readString(qs, "status", data.InboxStatusAll)
+	input.InboxFilters.Notification_Type = app.readString(qs, "type", "")
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "-created_at")
+	input.Filters.SortSafelist = []string{"-created_at"}
+	data.ValidateInboxFilters(v, input.InboxFilters)
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	notifications, unread, metadata, err := app.models.Inbox.GetUserInbox(app.contextGetUser(r).ID, input.InboxFilters, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"notifications": notifications, "unread_count": unread, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateInboxNotificationHandler() marks a notification as read or unread,
+// eg: PATCH /notifications/inbox/{notificationID} with {"read": true}
+func (app *application) updateInboxNotificationHandler(w http.ResponseWriter, r *http.Request) {
+	notificationID, err := app.readIDIntParam(r, "notificationID")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	var input struct {
+		Read *bool `json:"read"`
+	}
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	v := validator.New()
+	if v.Check(input.Read != nil, "read", "must be provided"); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	notification, err := app.models.Inbox.SetUserNotificationRead(notificationID, app.contextGetUser(r).ID, *input.Read)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrInboxNotificationNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"notification": notification}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// markInboxReadHandler() marks every notification in the user's inbox as read
+func (app *application) markInboxReadHandler(w http.ResponseWriter, r *http.Request) {
+	updated, err := app.models.Inbox.MarkAllUserNotificationsRead(app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": fmt.Sprintf("%d notifications marked as read", updated)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// dismissInboxNotificationHandler() removes a notification from the user's inbox,
+// eg: DELETE /notifications/inbox/{notificationID}
+func (app *application) dismissInboxNotificationHandler(w http.ResponseWriter, r *http.Request) {
+	notificationID, err := app.readIDIntParam(r, "notificationID")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	err = app.models.Inbox.DismissUserNotification(notificationID, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrInboxNotificationNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "notification dismissed successfully"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// notifyUser() adds a notification to a single user's inbox and pushes it to them if they
+// are connected to the notification stream. Failures are only logged so that they never
+// break the flow that triggered the notification.
+func (app *application) notifyUser(userID int64, notificationType, message string, feedID uuid.UUID) {
+	app.sendUserNotification(&data.UserNotification{
+		User_ID:           userID,
+		Notification_Type: notificationType,
+		Message:           message,
+		Feed_ID:           uuid.NullUUID{UUID: feedID, Valid: feedID != uuid.Nil},
+	})
+}
+
+// sendUserNotification() is notifyUser() for notifications that point at a post or comment
+func (app *application) sendUserNotification(notification *data.UserNotification) {
+	err := app.models.Inbox.CreateUserNotification(notification)
+	if err != nil {
+		app.logger.PrintError(err, map[string]string{
+			"User ID":           fmt.Sprintf("%d", notification.User_ID),
+			"Notification Type": notification.Notification_Type,
+		})
+		return
+	}
+	app.publishNotification(streamEventInboxNotification, notification, notification.User_ID)
+}
+
+// clearExpiredInboxNotificationsHandler() is the inbox retention job. It runs daily on the
+// notifier's cron and replaces interval based wiping for the inbox.
+func (app *application) clearExpiredInboxNotificationsHandler() {
+	app.logger.PrintInfo("Running inbox retention Worker...", nil)
+	deleted, err := app.models.Inbox.DeleteExpiredUserNotifications(app.config.inbox.retentiondays, app.config.inbox.readretentiondays)
+	if err != nil {
+		app.logger.PrintError(err, nil)
+		return
+	}
+	app.logger.PrintInfo("Deleted expired inbox notifications", map[string]string{
+		"Deleted": fmt.Sprintf("%d", deleted),
+	})
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/blue-davinci/aggregate/internal/data"
+	"github.com/blue-davinci/aggregate/internal/validator"
+)
+
+// getInvoicesHandler() lists the user's invoices, the latest first
+func (app *application) getInvoicesHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		data.Filters
+	}
+	v := validator.New()
+	qs := r.URL.Query()
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "-number")
+	input.Filters.SortSafelist = []string{"-number"}
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	invoices, metadata, err := app.models.Invoices.GetInvoicesByUser(app.contextGetUser(r).ID, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"invoices": invoices, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getInvoiceHandler() returns one of the user's invoices with its line items
+func (app *application) getInvoiceHandler(w http.ResponseWriter, r *http.Request) {
+	invoice, ok := app.readUserInvoice(w, r)
+	if !ok {
+		return
+	}
+	err := app.writeJSON(w, http.StatusOK, envelope{"invoice": invoice}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getInvoicePDFHandler() downloads one of the user's invoices as a PDF
+func (app *application) getInvoicePDFHandler(w http.ResponseWriter, r *http.Request) {
+	invoice, ok := app.readUserInvoice(w, r)
+	if !ok {
+		return
+	}
+	app.writeInvoicePDF(w, invoice)
+}
+
+// readUserInvoice() gets the invoice in the URL, writing a 404 when it doesn't exist or
+// isn't the user's so other users' invoice numbers can't be found out.
+func (app *application) readUserInvoice(w http.ResponseWriter, r *http.Request) (*data.Invoice, bool) {
+	invoice, ok := app.readInvoice(w, r)
+	if ok && invoice.User_ID != app.contextGetUser(r).ID {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+	return invoice, ok
+}
+
+// readInvoice() gets the invoice in the URL, writing the error response when it can't
+func (app *application) readInvoice(w http.ResponseWriter, r *http.Request) (*data.Invoice, bool) {
+	invoiceID, err := app.readIDIntParam(r, "invoiceID")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+	invoice, err := app.models.Invoices.GetInvoiceByID(invoiceID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrInvoiceNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+	return invoice, true
+}
+
+// writeInvoicePDF() sends an invoice as a PDF download named after its number
+func (app *application) writeInvoicePDF(w http.ResponseWriter, invoice *data.Invoice) {
+	document := invoice.PDF(app.config.invoices.settings)
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.pdf"`, invoice.Number))
+	w.Header().Set("Content-Length", strconv.Itoa(len(document)))
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(document)
+}
+
+// getBillingDetailsHandler() returns who the user's invoices are made out to. Users that
+// never saved any get their name and email, which is what their invoices use.
+func (app *application) getBillingDetailsHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	details, err := app.models.Invoices.GetBillingDetails(user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrBillingDetailsNotFound):
+			details = &data.BillingDetails{User_ID: user.ID, Name: user.Name}
+		default:
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"billing_details": details}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateBillingDetailsHandler() saves who the user's invoices are made out to. Only
+// invoices issued afterwards use them.
+func (app *application) updateBillingDetailsHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name    string `json:"name"`
+		Company string `json:"company"`
+		Address string `json:"address"`
+		Country string `json:"country"`
+		TaxID   string `json:"tax_id"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	details := &data.BillingDetails{
+		User_ID: app.contextGetUser(r).ID,
+		Name:    input.Name,
+		Company: input.Company,
+		Address: input.Address,
+		Country: input.Country,
+		Tax_ID:  input.TaxID,
+	}
+	v := validator.New()
+	if data.ValidateBillingDetails(v, details); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	err = app.models.Invoices.SaveBillingDetails(details)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"billing_details": details}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// adminGetInvoicesHandler() lists and searches the invoices, the latest first. ?q= takes a
+// customer's name, email or company or an invoice number, ?user_id= and ?currency= narrow
+// it down and ?from= and ?to= limit when the invoices were issued.
+func (app *application) adminGetInvoicesHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		data.InvoiceSearch
+		data.Filters
+	}
+	v := validator.New()
+	qs := r.URL.Query()
+	input.Query = app.readString(qs, "q", "")
+	input.User_ID = int64(app.readInt(qs, "user_id", 0, v))
+	input.Currency = app.readString(qs, "currency", "")
+	input.From = app.readTime(qs, "from", v)
+	input.To = app.readTime(qs, "to", v)
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "-number")
+	input.Filters.SortSafelist = []string{"-number"}
+	v.Check(input.Currency == "" || len(input.Currency) == 3, "currency", "must be a 3 letter ISO currency code")
+	v.Check(input.From.IsZero() || input.To.IsZero() || !input.To.Before(input.From), "to", "must not be before from")
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	invoices, metadata, err := app.models.Invoices.AdminSearchInvoices(input.InvoiceSearch, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"invoices": invoices, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// adminGetInvoiceHandler() returns any invoice with its line items
+func (app *application) adminGetInvoiceHandler(w http.ResponseWriter, r *http.Request) {
+	invoice, ok := app.readInvoice(w, r)
+	if !ok {
+		return
+	}
+	err := app.writeJSON(w, http.StatusOK, envelope{"invoice": invoice}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// adminGetInvoicePDFHandler() downloads any invoice as a PDF
+func (app *application) adminGetInvoicePDFHandler(w http.ResponseWriter, r *http.Request) {
+	invoice, ok := app.readInvoice(w, r)
+	if !ok {
+		return
+	}
+	app.writeInvoicePDF(w, invoice)
+}
+
+// issueInvoice() issues the invoice for a payment that started a subscription. The user's
+// billing details are copied onto it, falling back to their name and email.
+func (app *application) issueInvoice(payment_detail *data.Payment_Details, lines []data.InvoiceLineItem, user_name, user_email string) (*data.Invoice, error) {
+	details, err := app.models.Invoices.GetBillingDetails(payment_detail.User_ID)
+	if err != nil && !errors.Is(err, data.ErrBillingDetailsNotFound) {
+		return nil, err
+	}
+	billing := data.InvoiceBillingFor(user_name, user_email, details)
+	invoice := data.NewInvoice(payment_detail, lines, billing, app.config.invoices.settings)
+	err = app.models.Invoices.CreateInvoice(invoice)
+	if err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+// invoiceAdjustment() describes why a payment was for less than the plan's price, for the
+// line the difference gets on its invoice.
+func (app *application) invoiceAdjustment(planChange *data.PlanChange, redemption *data.CouponRedemption) string {
+	if redemption != nil {
+		return "Promo code " + redemption.Code
+	}
+	if planChange != nil {
+		return "Credit for unused time on your previous plan"
+	}
+	return "Discount"
+}
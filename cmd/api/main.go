@@ -2,11 +2,14 @@ package main
 
 import (
 	"database/sql"
+	"errors"
 	"expvar"
 	"flag"
 	"fmt"
+	"math"
 	"os"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -15,6 +18,8 @@ import (
 	"github.com/blue-davinci/aggregate/internal/database"
 	"github.com/blue-davinci/aggregate/internal/jsonlog"
 	"github.com/blue-davinci/aggregate/internal/mailer"
+	"github.com/blue-davinci/aggregate/internal/pubsub"
+	"github.com/blue-davinci/aggregate/internal/screening"
 	"github.com/blue-davinci/aggregate/internal/vcs"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
@@ -74,15 +79,72 @@ type config struct {
 		initializationurl                          string
 		verificationurl                            string
 		chargeauthorizationurl                     string
+		refundurl                                  string
 		autosubscriptioninterval                   int64
 		checkexpiredsubscriptioninterval           int64
 		checkexpiredchallengedsubscriptioninterval int64
 	}
+	billing struct {
+		batchsize   int
+		schedule    data.BillingSchedule
+		locktimeout time.Duration
+	}
+	invoices struct {
+		settings data.InvoiceSettings
+	}
+	payments struct {
+		provider          string
+		currencyproviders map[string]string
+		timeout           time.Duration
+		couponhold        time.Duration
+	}
+	stripe struct {
+		secretkey     string
+		webhooksecret string
+		apiurl        string
+		currency      string
+	}
 	frontend struct {
 		baseurl          string
 		activationurl    string
 		passwordreseturl string
 		callback_url     string
+		shareurl         string
+		invitationurl    string
+	}
+	outbound struct {
+		baseurl     string
+		maxitems    int
+		cachemaxage int
+	}
+	stream struct {
+		heartbeat    time.Duration
+		retry        time.Duration
+		replaybuffer int
+	}
+	comments struct {
+		maxdepth   int
+		editwindow time.Duration
+	}
+	screening struct {
+		enabled             bool
+		maxlinks            int
+		duplicatewindow     time.Duration
+		burstwindow         time.Duration
+		burstlimit          int
+		blocklist           []string
+		classifierurl       string
+		classifierkey       string
+		classifierthreshold float64
+		classifiertimeout   time.Duration
+	}
+	inbox struct {
+		retentiondays     int
+		readretentiondays int
+	}
+	digest struct {
+		unsubscribeurl string
+		postsperfeed   int
 	}
 	limitations struct {
 		maxFeedsCreated  int
@@ -96,6 +158,12 @@ type application struct {
 	models data.Models
 	mailer mailer.Mailer
 	wg     sync.WaitGroup
+	// notificationHub fans notifications out to the clients on the notification stream
+	notificationHub *pubsub.Hub
+	// commentScreener holds back new comments that look like spam, nil when screening is off
+	commentScreener *screening.Pipeline
+	// paymentProviders picks the provider each payment goes through
+	paymentProviders *data.PaymentProviders
 }
 
 func main() {
@@ -132,21 +200,94 @@ func main() {
 	flag.StringVar(&cfg.paystack.initializationurl, "paystack-initialization-url", "https://api.paystack.co/transaction/initialize", "Paystack Initialization URL")
 	flag.StringVar(&cfg.paystack.verificationurl, "paystack-verification-url", "https://api.paystack.co/transaction/verify/", "Paystack Verification URL")
 	flag.StringVar(&cfg.paystack.chargeauthorizationurl, "paystack-charge-authorization-url", "https://api.paystack.co/transaction/charge_authorization", "Paystack Charge Authorization URL")
+	flag.StringVar(&cfg.paystack.refundurl, "paystack-refund-url", "https://api.paystack.co/refund", "Paystack Refund URL")
 	flag.Int64Var(&cfg.paystack.autosubscriptioninterval, "paystack-autosubscription-interval",
 		720, "Interval in minutes for the auto subscription") // run auto-subscription checks every 12 hours
 	flag.Int64Var(&cfg.paystack.checkexpiredsubscriptioninterval, "paystack-check-expired-subscription-interval",
 		1440, "Interval in minutes for the check expired subscription") // run check expired subscription every 24 hours
 	flag.Int64Var(&cfg.paystack.checkexpiredchallengedsubscriptioninterval, "paystack-check-expired-challenged-subscription-interval",
 		720, "Interval in minutes for the check expired challenged subscription") // run check expired challenged subscription every 12 hours
+	// Billing runner
+	flag.IntVar(&cfg.billing.batchsize, "billing-batch-size", 50, "Number of due subscriptions the billing runner claims at a time")
+	cfg.billing.schedule.Retry_Days = []int{1, 3, 7}
+	flag.Func("billing-retry-days", "Days after a subscription ends to retry a failed renewal (comma separated, default 1,3,7)", func(val string) error {
+		days, err := data.ParseRetryDays(val)
+		cfg.billing.schedule.Retry_Days = days
+		return err
+	})
+	flag.DurationVar(&cfg.billing.schedule.Grace_Period, "billing-grace-period", 7*24*time.Hour, "How long a subscription stays usable after a failed renewal before it expires")
+	flag.DurationVar(&cfg.billing.locktimeout, "billing-lock-timeout", 15*time.Minute, "How long a billing runner holds the subscriptions it claims")
+	// Invoices
+	flag.StringVar(&cfg.invoices.settings.Seller_Name, "invoice-seller-name", "Aggregate", "Business name invoices are issued by")
+	flag.Func("invoice-seller-address", "Business address shown on invoices, lines separated by ;", func(val string) error {
+		cfg.invoices.settings.Seller_Address = strings.ReplaceAll(val, ";", "\n")
+		return nil
+	})
+	flag.StringVar(&cfg.invoices.settings.Seller_Tax_ID, "invoice-seller-tax-id", "", "Tax registration number shown on invoices")
+	flag.StringVar(&cfg.invoices.settings.Tax_Name, "invoice-tax-name", "VAT", "Name of the tax included in prices")
+	flag.Func("invoice-tax-rate", "Percentage of tax included in prices, eg: 16 (default 0)", func(val string) error {
+		rate, err := strconv.ParseFloat(val, 64)
+		if err != nil || rate < 0 || rate >= 100 {
+			return errors.New("must be a percentage from 0 up to 100")
+		}
+		cfg.invoices.settings.Tax_Rate = int32(math.Round(rate * 100))
+		return nil
+	})
+	// Payment providers
+	flag.StringVar(&cfg.payments.provider, "payment-provider", data.PaymentProviderPaystack, "Payment provider payments go through unless their currency is mapped to another (paystack|stripe)")
+	flag.Func("payment-currency-providers", "Currencies paid through a provider other than the default, eg: EUR=stripe,GBP=stripe", func(val string) error {
+		byCurrency, err := data.ParseCurrencyProviders(val)
+		cfg.payments.currencyproviders = byCurrency
+		return err
+	})
+	flag.DurationVar(&cfg.payments.timeout, "payment-provider-timeout", 15*time.Second, "Timeout for calls to the payment providers")
+	flag.DurationVar(&cfg.payments.couponhold, "coupon-reservation-timeout", time.Hour, "How long a checkout holds the promo code redemption it was started with")
+	flag.StringVar(&cfg.stripe.secretkey, "stripe-secret", os.Getenv("STRIPE_SECRET_KEY"), "Stripe Secret Key, stripe is only available when it is set")
+	flag.StringVar(&cfg.stripe.webhooksecret, "stripe-webhook-secret", os.Getenv("STRIPE_WEBHOOK_SECRET"), "Stripe webhook signing secret")
+	flag.StringVar(&cfg.stripe.apiurl, "stripe-api-url", "https://api.stripe.com", "Stripe API URL")
+	flag.StringVar(&cfg.stripe.currency, "stripe-currency", "USD", "Currency of stripe checkouts that don't name one")
 	// Read the frontend url into the config struct
 	flag.StringVar(&cfg.frontend.baseurl, "frontend-url", "http://localhost:5173", "Frontend URL")
 	flag.StringVar(&cfg.frontend.activationurl, "frontend-activation-url", "http://localhost:5173/verify?token=", "Frontend Activation URL")
 	flag.StringVar(&cfg.frontend.passwordreseturl, "frontend-password-reset-url", "http://localhost:5173/reset/password?token=", "Frontend Password Reset URL")
 	flag.StringVar(&cfg.frontend.callback_url, "frontend-callback-url", "https://adapted-healthy-monitor.ngrok-free.app/v1", "Frontend Callback URL")
+	flag.StringVar(&cfg.frontend.shareurl, "frontend-share-url", "http://localhost:5173/shared/", "Frontend URL share link tokens are appended to")
+	flag.StringVar(&cfg.frontend.invitationurl, "frontend-invitation-url", "http://localhost:5173/organizations/invitations?token=", "Frontend URL organization invitation tokens are appended to")
+	// Outbound feeds
+	flag.StringVar(&cfg.outbound.baseurl, "outbound-feed-url", "http://localhost:4000/v1/feeds/outbound", "Public base URL the outbound feeds are served from")
+	flag.IntVar(&cfg.outbound.maxitems, "outbound-feed-max-items", 50, "Maximum number of posts in an outbound feed")
+	flag.IntVar(&cfg.outbound.cachemaxage, "outbound-feed-cache-max-age", 900, "Cache-Control max-age in seconds for outbound feeds")
+	// Notification stream
+	flag.DurationVar(&cfg.stream.heartbeat, "stream-heartbeat-interval", 15*time.Second, "Interval between heartbeats on the notification stream")
+	flag.DurationVar(&cfg.stream.retry, "stream-retry", 5*time.Second, "Reconnection delay suggested to notification stream clients")
+	flag.IntVar(&cfg.stream.replaybuffer, "stream-replay-buffer", 500, "Number of recent notification stream events kept for Last-Event-ID resumption")
+	// Comment threads
+	flag.IntVar(&cfg.comments.maxdepth, "comments-max-depth", data.DefaultCommentMaxDepth, "Maximum nesting depth of comment threads, top level comments included")
+	flag.DurationVar(&cfg.comments.editwindow, "comments-edit-window", data.DefaultCommentEditWindow, "How long after posting a comment can be edited, 0 for no limit")
+	// Comment screening
+	flag.BoolVar(&cfg.screening.enabled, "comment-screening-enabled", true, "Screen new comments for spam and hold flagged ones for review")
+	flag.IntVar(&cfg.screening.maxlinks, "comment-screening-max-links", 3, "Maximum number of links a comment can carry before it is flagged")
+	flag.DurationVar(&cfg.screening.duplicatewindow, "comment-screening-duplicate-window", 10*time.Minute, "Window within which a user repeating a comment gets it flagged")
+	flag.DurationVar(&cfg.screening.burstwindow, "comment-screening-burst-window", time.Minute, "Window over which a user's comment burst is measured")
+	flag.IntVar(&cfg.screening.burstlimit, "comment-screening-burst-limit", 5, "Number of comments within the burst window after which further comments are flagged")
+	flag.Func("comment-screening-blocklist", "Blocked words and phrases (comma separated)", func(val string) error {
+		cfg.screening.blocklist = strings.Split(val, ",")
+		return nil
+	})
+	flag.StringVar(&cfg.screening.classifierurl, "comment-screening-classifier-url", "", "URL of an external spam classifier, leave empty to not use one")
+	flag.StringVar(&cfg.screening.classifierkey, "comment-screening-classifier-key", os.Getenv("AGGREGATE_CLASSIFIER_KEY"), "API key for the external spam classifier")
+	flag.Float64Var(&cfg.screening.classifierthreshold, "comment-screening-classifier-threshold", 0.9, "Classifier score at or above which a comment is flagged")
+	flag.DurationVar(&cfg.screening.classifiertimeout, "comment-screening-classifier-timeout", 2*time.Second, "Timeout for calls to the external spam classifier")
+	// Notification inbox retention
+	flag.IntVar(&cfg.inbox.retentiondays, "inbox-retention-days", 90, "Days to keep inbox notifications for")
+	flag.IntVar(&cfg.inbox.readretentiondays, "inbox-read-retention-days", 30, "Days to keep read and dismissed inbox notifications for")
+	// Email digests
+	flag.StringVar(&cfg.digest.unsubscribeurl, "digest-unsubscribe-url", "http://localhost:4000/v1/users/digest/unsubscribe?token=", "One-click unsubscribe URL for the email digests")
+	flag.IntVar(&cfg.digest.postsperfeed, "digest-posts-per-feed", data.DefaultDigestPostsPerFeed, "Number of top posts per followed feed in an email digest")
 	// Limitations
-	flag.IntVar(&cfg.limitations.maxFeedsCreated, "max-feeds-created", 5, "Maximum number of feeds a non-registered user can create")
-	flag.IntVar(&cfg.limitations.maxFeedsFollowed, "max-feeds-followed", 5, "Maximum number of feeds a non-registered user can follow")
-	flag.IntVar(&cfg.limitations.maxComments, "max-comments", 10, "Maximum number of comments a non-registered user can make")
+	flag.IntVar(&cfg.limitations.maxFeedsCreated, "max-feeds-created", 5, "Feeds a user without a subscription can create when the free plan sets no feeds_created quota")
+	flag.IntVar(&cfg.limitations.maxFeedsFollowed, "max-feeds-followed", 5, "Feeds a user without a subscription can follow when the free plan sets no feeds_followed quota")
+	flag.IntVar(&cfg.limitations.maxComments, "max-comments", 10, "Comments a day a user without a subscription can make when the free plan sets no comments_per_day quota")
 	// Cors
 	flag.Func("cors-trusted-origins", "Trusted CORS origins (space separated)", func(val string) error {
 		cfg.cors.trustedOrigins = strings.Fields(val)
@@ -179,6 +320,14 @@ func main() {
 		fmt.Printf("Version:\t%s\n", version)
 		os.Exit(0)
 	}
+	if !cfg.billing.schedule.ValidRetryDays() {
+		logger.PrintFatal(errors.New("billing retry days must fall within the billing grace period"), nil)
+	}
+	// a claimed batch is charged one subscription after another, the lease has to outlast
+	// the slowest batch or another instance could claim and charge what's left of it
+	if cfg.billing.locktimeout < time.Duration(cfg.billing.batchsize)*cfg.payments.timeout {
+		logger.PrintFatal(errors.New("billing lock timeout must be at least the billing batch size times the payment provider timeout"), nil)
+	}
 	// create our connection pull
 	db, err := openDB(cfg)
 	if err != nil {
@@ -189,11 +338,22 @@ func main() {
 	// Init our exp metrics variables for server metrics.
 	publishMetrics()
 	// setup our application with all dependancies Injected.
+	models := data.NewModels(db)
+	paymentProviders, err := newPaymentProviders(cfg)
+	if err != nil {
+		logger.PrintFatal(err, nil)
+	}
 	app := &application{
 		config: cfg,
 		logger: logger,
-		models: data.NewModels(db),
+		models: models,
 		mailer: mailer.New(cfg.smtp.host, cfg.smtp.port, cfg.smtp.username, cfg.smtp.password, cfg.smtp.sender),
+		// notification stream hub
+		notificationHub: pubsub.NewHub(cfg.stream.replaybuffer),
+		// comment screening pipeline
+		commentScreener: newCommentScreener(cfg, models.Comments),
+		// payment providers
+		paymentProviders: paymentProviders,
 	}
 	// start our background workers
 	app.startBackgroundWorkers()
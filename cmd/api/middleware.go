@@ -143,51 +143,52 @@ func (app *application) metrics(next http.Handler) http.Handler {
 	})
 }
 
-func (app *application) limitations(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Get the user from the request context.
-		user := app.contextGetUser(r)
-		// now we check if the user has an active subscription, if they do, for now
-		// we will just let them through and execute the next handler in the chain.
-		app.logger.PrintInfo("Checking user limitations", map[string]string{
-			"User ID": strconv.FormatInt(user.ID, 10),
-		})
-		// TODO: Get users subscription that is currently active or cancelled but not expired.
-		_, err := app.models.Payments.GetActiveOrNonExpiredSubscriptionByID(user.ID)
-		if err != nil {
-			// if the user does not have a subscription...
-			switch {
-			case errors.Is(err, data.ErrSubscriptionNotFound):
-				// this means they do not have a subscription and we should check if they have exceeded their limits.
+// The limitations() middleware caps an action by one of the quotas of the user's plan,
+// members of an organization with a seat for them are on the organization's plan and
+// everyone else without a subscription is held to the free plan. It sits behind the
+// dynamic middleware as it needs the user.
+func (app *application) limitations(quota string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Get the user from the request context.
+			user := app.contextGetUser(r)
+			app.logger.PrintInfo("Checking user limitations", map[string]string{
+				"User ID": strconv.FormatInt(user.ID, 10),
+				"Quota":   quota,
+			})
+			entitlements, err := app.resolveEntitlements(user.ID)
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+			// an unlimited quota needs no counting
+			if _, limited := entitlements.Quota(quota); limited {
 				limitations, err := app.models.Limitations.GetUserLimitations(user.ID)
 				if err != nil {
 					app.serverErrorResponse(w, r, err)
 					return
 				}
-				// check if the user has exceeded their feed limit
-				if limitations.Created_Feeds >= int64(app.config.limitations.maxFeedsCreated) {
-					app.limitationResponse(w, r)
-					return
-				}
-				// check if the user has exceeded their followed feed limit
-				if limitations.Followed_Feeds >= int64(app.config.limitations.maxFeedsFollowed) {
-					app.limitationResponse(w, r)
-					return
-				}
-				// check if the user has exceeded their comments limit
-				if limitations.Comments_Today >= int64(app.config.limitations.maxComments) {
+				if !entitlements.Allows(quota, limitations.Used(quota)) {
 					app.limitationResponse(w, r)
 					return
 				}
-			default:
-				// this means there was an error and we should return a server error
-				app.serverErrorResponse(w, r, err)
+			}
+			// if the user has not reached the quota, we call the next handler in the chain.
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// The requireFeature() middleware only lets users whose plan includes a feature through
+func (app *application) requireFeature(feature string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !app.hasFeature(w, r, feature) {
 				return
 			}
-		}
-		// if the user has a subscription or has not exceeded their limitations, we call the next handler in the chain.
-		next.ServeHTTP(w, r)
-	})
+			next.ServeHTTP(w, r)
+		})
+	}
 }
 
 // The rateLimit() middleware will be used to rate limit the number of requests that a
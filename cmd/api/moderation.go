@@ -0,0 +1,343 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/blue-davinci/aggregate/internal/data"
+	"github.com/blue-davinci/aggregate/internal/validator"
+	"github.com/google/uuid"
+)
+
+// reportCommentHandler lets a user report a comment for an admin to review,
+// eg: POST /feeds/follow/posts/comments/{commentID}/reports with {"reason": "spam", "details": "..."}
+func (app *application) reportCommentHandler(w http.ResponseWriter, r *http.Request) {
+	commentID, err := app.readIDParam(r, "commentID")
+	if err != nil || commentID == uuid.Nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	var input struct {
+		Reason  string `json:"reason"`
+		Details string `json:"details"`
+	}
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	user := app.contextGetUser(r)
+	report := &data.CommentReport{
+		Comment_ID:  commentID,
+		Reported_By: user.ID,
+		Reason:      input.Reason,
+		Details:     input.Details,
+	}
+	v := validator.New()
+	if data.ValidateCommentReport(v, report); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	authorID, _, err := app.models.Comments.GetCommentOwner(commentID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrCommentNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if authorID == user.ID {
+		v.AddError("comment_id", "you can not report your own comment")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	err = app.models.Moderation.ReportComment(report)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateCommentReport):
+			v.AddError("comment_id", "you have already reported this comment")
+			app.failedConstraintValidation(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusCreated, envelope{"report": report}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// adminGetModerationQueueHandler returns the reported comments awaiting moderation, the
+// most reported first, eg: GET /admin/comments/reports?status=open&page=1&page_size=20
+// status is one of open (default), resolved or dismissed.
+func (app *application) adminGetModerationQueueHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Status string
+		data.Filters
+	}
+	v := validator.New()
+	qs := r.URL.Query()
+	input.Status = app.readString(qs, "status", data.ReportStatusOpen)
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "-report_count")
+	input.Filters.SortSafelist = []string{"-report_count"}
+	v.Check(validator.PermittedValue(input.Status, data.ReportStatusOpen, data.ReportStatusResolved, data.ReportStatusDismissed),
+		"status", "must be one of open, resolved or dismissed")
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	queue, metadata, err := app.models.Moderation.GetModerationQueue(input.Status, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"comments": queue, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// adminGetCommentModerationHandler returns a comment's reports together with the
+// moderation actions taken on it and why screening flagged it, if it did,
+// eg: GET /admin/comments/{commentID}/moderation
+func (app *application) adminGetCommentModerationHandler(w http.ResponseWriter, r *http.Request) {
+	commentID, err := app.readIDParam(r, "commentID")
+	if err != nil || commentID == uuid.Nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	comment, err := app.models.Moderation.GetCommentForModeration(commentID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrCommentNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	reports, err := app.models.Moderation.GetCommentReports(commentID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	actions, err := app.models.Moderation.GetCommentModerationActions(commentID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	// the screening is only there for comments that were flagged
+	screened, err := app.models.Moderation.GetCommentScreening(commentID)
+	if err != nil && !errors.Is(err, data.ErrCommentScreeningNotFound) {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"status": comment.Status, "reports": reports, "actions": actions, "screening": screened}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// adminGetCommentRevisionsHandler returns the revision history of any comment,
+// eg: GET /admin/comments/{commentID}/revisions
+func (app *application) adminGetCommentRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	commentID, err := app.readIDParam(r, "commentID")
+	if err != nil || commentID == uuid.Nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	authorID, _, err := app.models.Comments.GetCommentOwner(commentID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrCommentNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	app.writeCommentRevisions(w, r, commentID, authorID)
+}
+
+// adminModerateCommentHandler hides, restores or deletes a comment, dismisses its reports
+// or approves or rejects it when screening held it back,
+// eg: PATCH /admin/comments/{commentID} with {"action": "hide", "reason": "..."}
+// The action is recorded against the admin taking it.
+func (app *application) adminModerateCommentHandler(w http.ResponseWriter, r *http.Request) {
+	commentID, err := app.readIDParam(r, "commentID")
+	if err != nil || commentID == uuid.Nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	var input struct {
+		Action string `json:"action"`
+		Reason string `json:"reason"`
+	}
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	comment, err := app.models.Moderation.GetCommentForModeration(commentID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrCommentNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	v := validator.New()
+	if data.ValidateModerationAction(v, input.Action, input.Reason, comment.Status); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	action, err := app.models.Moderation.ModerateComment(comment, input.Action, input.Reason, app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	// approved comments go out to everyone they would have reached when first posted
+	if input.Action == data.ModerationActionApprove {
+		app.background(func() {
+			app.publishApprovedComment(comment)
+		})
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"status": comment.Status, "action": action}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// adminGetCommentRestrictionsHandler lists the users currently muted or banned from
+// commenting, eg: GET /admin/users/restrictions?page=1&page_size=20
+func (app *application) adminGetCommentRestrictionsHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		data.Filters
+	}
+	v := validator.New()
+	qs := r.URL.Query()
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "-created_at")
+	input.Filters.SortSafelist = []string{"-created_at"}
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	restrictions, metadata, err := app.models.Moderation.GetActiveCommentRestrictions(input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"restrictions": restrictions, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// adminRestrictCommenterHandler mutes or bans a user from commenting,
+// eg: PUT /admin/users/{userID}/restriction with {"restriction": "mute", "duration_days": 7, "reason": "..."}
+// A duration of 0 keeps a ban in place until it is lifted.
+func (app *application) adminRestrictCommenterHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := app.readIDIntParam(r, "userID")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	var input struct {
+		Restriction   string `json:"restriction"`
+		Reason        string `json:"reason"`
+		Duration_Days int    `json:"duration_days"`
+	}
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	restriction := &data.CommentRestriction{
+		User_ID:       userID,
+		Restriction:   input.Restriction,
+		Reason:        input.Reason,
+		Restricted_By: app.contextGetUser(r).ID,
+	}
+	v := validator.New()
+	v.Check(userID != restriction.Restricted_By, "user_id", "you can not restrict yourself")
+	if data.ValidateCommentRestriction(v, restriction, input.Duration_Days); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	if input.Duration_Days > 0 {
+		expiresAt := time.Now().UTC().AddDate(0, 0, input.Duration_Days)
+		restriction.Expires_At = &expiresAt
+	}
+	err = app.models.Moderation.RestrictCommenter(restriction)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"restriction": restriction}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// adminLiftCommentRestrictionHandler lets a muted or banned user comment again,
+// eg: DELETE /admin/users/{userID}/restriction
+func (app *application) adminLiftCommentRestrictionHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := app.readIDIntParam(r, "userID")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	err = app.models.Moderation.LiftCommentRestriction(userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrCommentRestrictionNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "restriction lifted successfully"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// commentingRestricted() writes a 403 response and returns true when the user is muted or
+// banned from commenting. Comment handlers call it before accepting any comment text.
+func (app *application) commentingRestricted(w http.ResponseWriter, r *http.Request, userID int64) bool {
+	restriction, err := app.models.Moderation.GetActiveCommentRestriction(userID)
+	if err != nil {
+		if errors.Is(err, data.ErrCommentRestrictionNotFound) {
+			return false
+		}
+		app.serverErrorResponse(w, r, err)
+		return true
+	}
+	verb := "muted"
+	if restriction.Restriction == data.CommentRestrictionBan {
+		verb = "banned"
+	}
+	message := fmt.Sprintf("you have been %s from commenting", verb)
+	if restriction.Expires_At != nil {
+		message = fmt.Sprintf("%s until %s", message, restriction.Expires_At.Format(time.RFC1123))
+	}
+	app.errorResponse(w, r, http.StatusForbidden, message)
+	return true
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/blue-davinci/aggregate/internal/data"
 	"github.com/blue-davinci/aggregate/internal/validator"
@@ -33,6 +34,20 @@ func (app *application) fetchNotificationsHandler() {
 			"Error": "Error adding deleter notifier job",
 		})
 	}
+	// the digest job runs hourly and only mails the users whose local send hour has come
+	_, err = app.config.notifier.cronJob.AddFunc("@hourly", app.sendDigestEmailsHandler)
+	if err != nil {
+		app.logger.PrintError(err, map[string]string{
+			"Error": "Error adding digest job",
+		})
+	}
+	// the inbox keeps notifications per user so it follows a retention policy instead
+	_, err = app.config.notifier.cronJob.AddFunc("@daily", app.clearExpiredInboxNotificationsHandler)
+	if err != nil {
+		app.logger.PrintError(err, map[string]string{
+			"Error": "Error adding inbox retention job",
+		})
+	}
 	// The deleter can wait, but we need to run the startNotificationFetch() first
 	// to proceed with any fetch
 	app.startNotificationFetch()
@@ -66,10 +81,41 @@ func (app *application) startNotificationFetch() {
 		notificationID, err := app.models.Notifications.InsertNotifications(notification)
 		if err != nil {
 			app.logger.PrintError(err, nil)
+			continue
 		}
 		app.logger.PrintInfo("Inserted notification", map[string]string{
 			"Notification ID": fmt.Sprintf("%d", notificationID),
 		})
+		// every follower gets a durable copy in their inbox
+		err = app.models.Inbox.CreateFeedFollowerNotifications(notification.Feed_ID,
+			fmt.Sprintf("%d new posts from %s", notification.Post_Count, notification.Feed_Name))
+		if err != nil {
+			app.logger.PrintError(err, nil)
+		}
+		// push the notification to the followers connected to the notification stream
+		notification.ID = int64(notificationID)
+		notification.Created_At = time.Now().UTC()
+		app.publishFeedNotification(notification)
+	}
+	// Saved searches act as virtual feeds, so we also check for new posts
+	// matching any saved search that has notifications turned on
+	savedSearchNotifications, err := app.models.SavedSearches.FetchSavedSearchNotifications(app.config.notifier.interval)
+	if err != nil {
+		app.logger.PrintError(err, nil)
+	}
+	for _, notification := range savedSearchNotifications {
+		notificationID, err := app.models.SavedSearches.InsertSavedSearchNotification(notification)
+		if err != nil {
+			app.logger.PrintError(err, nil)
+			continue
+		}
+		app.logger.PrintInfo("Inserted saved search notification", map[string]string{
+			"Notification ID": fmt.Sprintf("%d", notificationID),
+			"Saved Search":    notification.Saved_Search_Name,
+		})
+		notification.ID = int64(notificationID)
+		notification.Created_At = time.Now().UTC()
+		app.publishNotification(streamEventSavedSearchNotification, notification, notification.User_ID)
 	}
 }
 
@@ -109,6 +155,12 @@ func (app *application) deleteReadCommentNotificationHandler(w http.ResponseWrit
 		app.serverErrorResponse(w, r, err)
 		return
 	}
+	// the inbox copies are per user, so only the caller's get marked as read
+	err = app.models.Inbox.MarkPostUserNotificationsRead(app.contextGetUser(r).ID, postID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
 	// Return a 200 OK status code along with the deleted notification
 	err = app.writeJSON(w, http.StatusOK, envelope{"message": "comment notification succesfully deleted"}, nil)
 	if err != nil {
@@ -153,6 +205,7 @@ func (app *application) getUserNotificationsHandler(w http.ResponseWriter, r *ht
 	app.logger.PrintInfo("Fetching user notifications", map[string]string{
 		"notifications total":         fmt.Sprintf("%d", len(notifications.Notification)),
 		"comment notifications total": fmt.Sprintf("%d", len(notifications.CommentNotification)),
+		"saved search notifications":  fmt.Sprintf("%d", len(notifications.SavedSearchNotification)),
 	})
 	// Send the notifications to the client
 	err = app.writeJSON(w, http.StatusOK, envelope{"notification_group": notifications}, nil)
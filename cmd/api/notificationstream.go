@@ -0,0 +1,185 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/blue-davinci/aggregate/internal/data"
+	"github.com/blue-davinci/aggregate/internal/pubsub"
+)
+
+// Event types pushed over the notification stream
+const (
+	streamEventFeedNotification        = "feed_notification"
+	streamEventCommentNotification     = "comment_notification"
+	streamEventSavedSearchNotification = "saved_search_notification"
+	streamEventAnnouncement            = "announcement"
+)
+
+// streamNotificationsHandler() is the real-time counterpart of getUserNotificationsHandler().
+// It keeps the connection open and pushes each notification as a Server-Sent Event as soon
+// as it is published, eg: GET /notifications/stream.
+// Clients that reconnect send the Last-Event-ID header and get any buffered events they
+// missed replayed first. A comment line is sent on every heartbeat to keep proxies from
+// closing idle connections.
+func (app *application) streamNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	lastEventID, err := readLastEventID(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	user := app.contextGetUser(r)
+	subscription, err := app.notificationHub.Subscribe(user.ID, lastEventID)
+	if err != nil {
+		switch {
+		case errors.Is(err, pubsub.ErrHubClosed):
+			app.errorResponse(w, r, http.StatusServiceUnavailable, "the server is shutting down, please reconnect shortly")
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	defer app.notificationHub.Unsubscribe(subscription)
+	// Streams are tracked like any other background work so shutdown waits for them
+	// to return once the hub closes.
+	app.wg.Add(1)
+	defer app.wg.Done()
+	// The server's WriteTimeout would otherwise cut the stream off, so we lift the
+	// deadline for this connection only.
+	rc := http.NewResponseController(w)
+	err = rc.SetWriteDeadline(time.Time{})
+	if err != nil && !errors.Is(err, http.ErrNotSupported) {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	// tell the client how long to wait before reconnecting
+	fmt.Fprintf(w, "retry: %d\n\n", app.config.stream.retry.Milliseconds())
+	for _, event := range subscription.Replay {
+		writeStreamEvent(w, event)
+	}
+	if err := rc.Flush(); err != nil {
+		app.logger.PrintError(err, nil)
+		return
+	}
+	heartbeat := time.NewTicker(app.config.stream.heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-subscription.Events:
+			// the hub closed our subscription, either on shutdown or because we fell
+			// behind. The client reconnects and resumes from the last event it got.
+			if !ok {
+				return
+			}
+			writeStreamEvent(w, event)
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// writeStreamEvent() writes a single event in the text/event-stream format. Our payloads
+// are JSON so they never contain newlines.
+func writeStreamEvent(w http.ResponseWriter, event pubsub.Event) {
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Data)
+}
+
+// readLastEventID() returns the ID of the last event the client saw, 0 if it has none
+func readLastEventID(r *http.Request) (uint64, error) {
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseUint(lastEventID, 10, 64)
+	if err != nil {
+		return 0, errors.New("invalid Last-Event-ID")
+	}
+	return id, nil
+}
+
+// publishNotification() pushes a payload to the notification stream, to everyone if no
+// users are passed. Publishing is best effort, the notifications remain available through
+// GET /notifications so we only log failures.
+func (app *application) publishNotification(eventType string, payload any, userIDs ...int64) {
+	_, err := app.notificationHub.Publish(eventType, payload, userIDs...)
+	if err != nil && !errors.Is(err, pubsub.ErrHubClosed) {
+		app.logger.PrintError(err, map[string]string{
+			"Event": eventType,
+		})
+	}
+}
+
+// publishFeedNotification() fans a feed's new posts notification out to its followers
+func (app *application) publishFeedNotification(notification *data.Notification) {
+	userIDs, err := app.models.Notifications.GetFeedFollowerIDs(notification.Feed_ID)
+	if err != nil {
+		app.logger.PrintError(err, nil)
+		return
+	}
+	if len(userIDs) == 0 {
+		return
+	}
+	app.publishNotification(streamEventFeedNotification, notification, userIDs...)
+}
+
+// publishCommentNotification() pushes a new comment to the users that would see it in
+// their comment notifications i.e those who favorited the post, the replied-to author
+// and the users it mentions. Mentioned users get a mention rather than the generic event.
+func (app *application) publishCommentNotification(comment *data.Comment, mentioned []int64) {
+	app.publishMentionNotification(comment, mentioned)
+	recipients, err := app.models.Notifications.GetCommentNotificationRecipients(comment)
+	if err != nil {
+		app.logger.PrintError(err, nil)
+		return
+	}
+	userIDs := slices.DeleteFunc(recipients, func(userID int64) bool {
+		return slices.Contains(mentioned, userID)
+	})
+	if len(userIDs) == 0 {
+		return
+	}
+	notificationType := "Comment on Favorited Post"
+	if comment.Parent_Comment_ID.Valid {
+		notificationType = "Reply to Your Comment"
+	}
+	app.publishNotification(streamEventCommentNotification, newStreamCommentNotification(comment, notificationType), userIDs...)
+}
+
+// publishMentionNotification() pushes a comment to the users it mentions. Edited comments
+// only go through here, for the users the edit newly mentions.
+func (app *application) publishMentionNotification(comment *data.Comment, mentioned []int64) {
+	if len(mentioned) == 0 {
+		return
+	}
+	app.publishNotification(streamEventCommentNotification, newStreamCommentNotification(comment, "You were mentioned"), mentioned...)
+}
+
+func newStreamCommentNotification(comment *data.Comment, notificationType string) *data.CommentNotification {
+	// match the 20 character snippet GetUserCommentNotifications returns
+	snippet := []rune(comment.Comment_Text)
+	if len(snippet) > 20 {
+		snippet = snippet[:20]
+	}
+	return &data.CommentNotification{
+		Comment_ID:       comment.ID,
+		Post_ID:          comment.Post_ID,
+		User_ID:          comment.User_ID,
+		Created_At:       comment.Created_At,
+		Comment_Snippet:  string(snippet),
+		NotificationType: notificationType,
+	}
+}
@@ -0,0 +1,815 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/blue-davinci/aggregate/internal/data"
+	"github.com/blue-davinci/aggregate/internal/validator"
+	"github.com/google/uuid"
+)
+
+// userOrganization() returns the organization of the current user. It writes the error
+// response itself, a 404 for users outside any organization and a 403 when manage is
+// set and the user is only a member, so callers just return when it reports false.
+func (app *application) userOrganization(w http.ResponseWriter, r *http.Request, manage bool) (*data.Organization, bool) {
+	organization, err := app.models.Organizations.GetOrganizationForUser(app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrOrganizationNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+	if manage && !organization.CanManage() {
+		app.notPermittedResponse(w, r)
+		return nil, false
+	}
+	return organization, true
+}
+
+// getOrganizationHandler returns the organization the user belongs to, along with the
+// plan its owner is subscribed to and how many of its seats are free.
+// eg: GET /v1/organizations
+func (app *application) getOrganizationHandler(w http.ResponseWriter, r *http.Request) {
+	organization, ok := app.userOrganization(w, r, false)
+	if !ok {
+		return
+	}
+	err := app.writeJSON(w, http.StatusOK, envelope{"organization": organization}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// createOrganizationHandler creates an organization owned by the user. The owner's
+// subscription pays for the organization, so a plan with more than one seat is needed
+// before anyone else can be invited.
+// eg: POST /v1/organizations {"name": "Acme Newsroom"}
+func (app *application) createOrganizationHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name string `json:"name"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	user := app.contextGetUser(r)
+	organization := &data.Organization{
+		Name:     strings.TrimSpace(input.Name),
+		Owner_ID: user.ID,
+	}
+	v := validator.New()
+	if data.ValidateOrganization(v, organization); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	err = app.models.Organizations.CreateOrganization(organization)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrAlreadyInOrganization):
+			v.AddError("organization", "you already belong to an organization")
+			app.failedConstraintValidation(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	// read it back for the seats the owner's plan gives it
+	organization, err = app.models.Organizations.GetOrganizationForUser(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusCreated, envelope{"organization": organization}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateOrganizationHandler renames the user's organization, owners and admins only.
+// eg: PATCH /v1/organizations {"name": "Acme Research"}
+func (app *application) updateOrganizationHandler(w http.ResponseWriter, r *http.Request) {
+	organization, ok := app.userOrganization(w, r, true)
+	if !ok {
+		return
+	}
+	var input struct {
+		Name *string `json:"name"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if input.Name != nil {
+		organization.Name = strings.TrimSpace(*input.Name)
+	}
+	v := validator.New()
+	if data.ValidateOrganization(v, organization); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	err = app.models.Organizations.UpdateOrganization(organization)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"organization": organization}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteOrganizationHandler deletes the user's organization along with everything shared
+// in it. Only the owner can delete it, the members go back to their own plans.
+// eg: DELETE /v1/organizations
+func (app *application) deleteOrganizationHandler(w http.ResponseWriter, r *http.Request) {
+	organization, ok := app.userOrganization(w, r, false)
+	if !ok {
+		return
+	}
+	if organization.Role != data.OrganizationRoleOwner {
+		app.notPermittedResponse(w, r)
+		return
+	}
+	members, err := app.models.Organizations.GetOrganizationMembers(organization)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.models.Organizations.DeleteOrganization(organization.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrOrganizationNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	message := fmt.Sprintf("The organization %s has been deleted, you are back on your own plan", organization.Name)
+	app.background(func() {
+		for _, member := range members {
+			if member.User_ID != organization.Owner_ID {
+				app.notifyUser(member.User_ID, data.InboxTypeOrganization, message, uuid.Nil)
+			}
+		}
+	})
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "organization deleted successfully"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getOrganizationMembersHandler lists the members of the user's organization in the
+// order they take its seats. Members past the seats of the plan are not seated.
+// eg: GET /v1/organizations/members
+func (app *application) getOrganizationMembersHandler(w http.ResponseWriter, r *http.Request) {
+	organization, ok := app.userOrganization(w, r, false)
+	if !ok {
+		return
+	}
+	members, err := app.models.Organizations.GetOrganizationMembers(organization)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"members": members}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateOrganizationMemberHandler changes the role of a member, only the owner can make
+// members admins or take that away.
+// eg: PATCH /v1/organizations/members/{userID} {"role": "admin"}
+func (app *application) updateOrganizationMemberHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := app.readIDIntParam(r, "userID")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	organization, ok := app.userOrganization(w, r, false)
+	if !ok {
+		return
+	}
+	if organization.Role != data.OrganizationRoleOwner {
+		app.notPermittedResponse(w, r)
+		return
+	}
+	var input struct {
+		Role string `json:"role"`
+	}
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	v := validator.New()
+	if data.ValidateOrganizationRole(v, input.Role); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	err = app.models.Organizations.UpdateOrganizationMemberRole(organization.ID, userID, input.Role)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrOrganizationMemberNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "member role updated successfully"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteOrganizationMemberHandler removes a member from the user's organization. Members
+// can remove themselves to leave it, admins can remove members and the owner anyone but
+// themselves, they delete the organization instead.
+// eg: DELETE /v1/organizations/members/{userID}
+func (app *application) deleteOrganizationMemberHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := app.readIDIntParam(r, "userID")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	organization, ok := app.userOrganization(w, r, false)
+	if !ok {
+		return
+	}
+	leaving := userID == app.contextGetUser(r).ID
+	if !leaving {
+		if !organization.CanManage() {
+			app.notPermittedResponse(w, r)
+			return
+		}
+		if organization.Role == data.OrganizationRoleAdmin {
+			members, err := app.models.Organizations.GetOrganizationMembers(organization)
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+			for _, member := range members {
+				if member.User_ID == userID && member.Role != data.OrganizationRoleMember {
+					app.notPermittedResponse(w, r)
+					return
+				}
+			}
+		}
+	}
+	err = app.models.Organizations.DeleteOrganizationMember(organization.ID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrOrganizationMemberNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if !leaving {
+		app.background(func() {
+			app.notifyUser(userID, data.InboxTypeOrganization, fmt.Sprintf("You have been removed from %s", organization.Name), uuid.Nil)
+		})
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "member removed successfully"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getOrganizationInvitationsHandler lists the invitations of the user's organization that
+// can still be accepted, owners and admins only.
+// eg: GET /v1/organizations/invitations
+func (app *application) getOrganizationInvitationsHandler(w http.ResponseWriter, r *http.Request) {
+	organization, ok := app.userOrganization(w, r, true)
+	if !ok {
+		return
+	}
+	invitations, err := app.models.Organizations.GetOrganizationInvitations(organization.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"invitations": invitations}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// createOrganizationInvitationHandler invites someone to the user's organization by email.
+// Every invitation holds a seat until it is accepted, so there has to be a free one,
+// inviting the same email again just sends a new link. People who already have an
+// account are told in their inbox as well.
+// eg: POST /v1/organizations/invitations {"email": "jane@example.com", "role": "member"}
+func (app *application) createOrganizationInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	organization, ok := app.userOrganization(w, r, true)
+	if !ok {
+		return
+	}
+	var input struct {
+		Email string `json:"email"`
+		Role  string `json:"role"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	user := app.contextGetUser(r)
+	invitation := &data.OrganizationInvitation{
+		Organization_ID:   organization.ID,
+		Organization_Name: organization.Name,
+		Email:             strings.TrimSpace(input.Email),
+		Role:              input.Role,
+		Invited_By:        user.ID,
+		Invited_By_Name:   user.Name,
+	}
+	if invitation.Role == "" {
+		invitation.Role = data.OrganizationRoleMember
+	}
+	v := validator.New()
+	if data.ValidateOrganizationInvitation(v, invitation); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	pending, err := app.models.Organizations.GetOrganizationInvitations(organization.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	reinvite := false
+	for _, earlier := range pending {
+		reinvite = reinvite || strings.EqualFold(earlier.Email, invitation.Email)
+	}
+	if !reinvite && organization.SeatsAvailable() == 0 {
+		v.AddError("seats", "all of the organization's seats are taken, upgrade to a plan with more seats")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	// people without an account yet sign up with the invited email before accepting
+	invitee, err := app.models.Users.GetByEmail(invitation.Email)
+	if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if invitee != nil {
+		_, err = app.models.Organizations.GetOrganizationForUser(invitee.ID)
+		if err == nil {
+			v.AddError("email", "already belongs to an organization")
+			app.failedConstraintValidation(w, r, v.Errors)
+			return
+		}
+		if !errors.Is(err, data.ErrOrganizationNotFound) {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+	err = app.models.Organizations.CreateOrganizationInvitation(invitation)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	app.sendOrganizationInvitationEmail(invitation)
+	if invitee != nil {
+		message := fmt.Sprintf("%s invited you to join %s, check your email to accept", user.Name, organization.Name)
+		app.background(func() {
+			app.notifyUser(invitee.ID, data.InboxTypeOrganization, message, uuid.Nil)
+		})
+	}
+	err = app.writeJSON(w, http.StatusCreated, envelope{"invitation": invitation}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteOrganizationInvitationHandler revokes an invitation, freeing the seat it held.
+// eg: DELETE /v1/organizations/invitations/{invitationID}
+func (app *application) deleteOrganizationInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	invitationID, err := app.readIDIntParam(r, "invitationID")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	organization, ok := app.userOrganization(w, r, true)
+	if !ok {
+		return
+	}
+	err = app.models.Organizations.DeleteOrganizationInvitation(organization.ID, invitationID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrOrganizationInvitationNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "invitation revoked successfully"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// acceptOrganizationInvitationHandler joins the user to an organization with the token
+// from their invitation email. The invitation has to be for the user's own email.
+// eg: POST /v1/organizations/invitations/accept {"token": "Y7QCRZ7FWOWYLXLAOC2VYOLIPY"}
+func (app *application) acceptOrganizationInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Token string `json:"token"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	v := validator.New()
+	if v.Check(input.Token != "", "token", "must be provided"); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	invitation, err := app.models.Organizations.GetOrganizationInvitationByToken(input.Token)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrOrganizationInvitationNotFound):
+			v.AddError("token", "invalid or expired invitation token")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	user := app.contextGetUser(r)
+	if !strings.EqualFold(invitation.Email, user.Email) {
+		app.notPermittedResponse(w, r)
+		return
+	}
+	_, err = app.models.Organizations.AcceptOrganizationInvitation(invitation.ID, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrOrganizationInvitationNotFound):
+			v.AddError("token", "invalid or expired invitation token")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrAlreadyInOrganization):
+			v.AddError("organization", "you already belong to an organization")
+			app.failedConstraintValidation(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	organization, err := app.models.Organizations.GetOrganizationForUser(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	message := fmt.Sprintf("@%s joined %s", user.Handle, organization.Name)
+	app.background(func() {
+		app.notifyUser(organization.Owner_ID, data.InboxTypeOrganization, message, uuid.Nil)
+	})
+	err = app.writeJSON(w, http.StatusOK, envelope{"organization": organization}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getOrganizationFoldersHandler lists the folders of the user's organization
+// eg: GET /v1/organizations/folders
+func (app *application) getOrganizationFoldersHandler(w http.ResponseWriter, r *http.Request) {
+	organization, ok := app.userOrganization(w, r, false)
+	if !ok {
+		return
+	}
+	folders, err := app.models.Organizations.GetOrganizationFolders(organization.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"folders": folders}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// createOrganizationFolderHandler adds a folder for the organization's shared feeds,
+// owners and admins only. eg: POST /v1/organizations/folders {"name": "Competitors"}
+func (app *application) createOrganizationFolderHandler(w http.ResponseWriter, r *http.Request) {
+	organization, ok := app.userOrganization(w, r, true)
+	if !ok {
+		return
+	}
+	var input struct {
+		Name string `json:"name"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	folder := &data.OrganizationFolder{
+		Organization_ID: organization.ID,
+		Name:            strings.TrimSpace(input.Name),
+		Created_By:      app.contextGetUser(r).ID,
+	}
+	v := validator.New()
+	if data.ValidateOrganizationFolder(v, folder); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	err = app.models.Organizations.CreateOrganizationFolder(folder)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateOrganizationFolder):
+			v.AddError("name", "a folder with this name already exists")
+			app.failedConstraintValidation(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusCreated, envelope{"folder": folder}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateOrganizationFolderHandler renames a folder of the user's organization
+// eg: PATCH /v1/organizations/folders/{folderID} {"name": "Industry"}
+func (app *application) updateOrganizationFolderHandler(w http.ResponseWriter, r *http.Request) {
+	folderID, err := app.readIDIntParam(r, "folderID")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	organization, ok := app.userOrganization(w, r, true)
+	if !ok {
+		return
+	}
+	folder, err := app.models.Organizations.GetOrganizationFolderByID(organization.ID, folderID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrOrganizationFolderNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	var input struct {
+		Name *string `json:"name"`
+	}
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if input.Name != nil {
+		folder.Name = strings.TrimSpace(*input.Name)
+	}
+	v := validator.New()
+	if data.ValidateOrganizationFolder(v, folder); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	err = app.models.Organizations.UpdateOrganizationFolder(folder)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		case errors.Is(err, data.ErrDuplicateOrganizationFolder):
+			v.AddError("name", "a folder with this name already exists")
+			app.failedConstraintValidation(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"folder": folder}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteOrganizationFolderHandler removes a folder, its feeds stay shared outside of it
+// eg: DELETE /v1/organizations/folders/{folderID}
+func (app *application) deleteOrganizationFolderHandler(w http.ResponseWriter, r *http.Request) {
+	folderID, err := app.readIDIntParam(r, "folderID")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	organization, ok := app.userOrganization(w, r, true)
+	if !ok {
+		return
+	}
+	err = app.models.Organizations.DeleteOrganizationFolder(organization.ID, folderID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrOrganizationFolderNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "folder deleted successfully"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getOrganizationFeedsHandler lists the feeds shared with the user's organization, all
+// of them or just those in a folder.
+// eg: GET /v1/organizations/feeds?folder_id=2&page=1&page_size=20
+func (app *application) getOrganizationFeedsHandler(w http.ResponseWriter, r *http.Request) {
+	organization, ok := app.userOrganization(w, r, false)
+	if !ok {
+		return
+	}
+	var input struct {
+		FolderID int
+		data.Filters
+	}
+	v := validator.New()
+	qs := r.URL.Query()
+	input.FolderID = app.readInt(qs, "folder_id", 0, v)
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "shared_at")
+	input.Filters.SortSafelist = []string{"shared_at"}
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	feeds, metadata, err := app.models.Organizations.GetOrganizationFeeds(organization.ID, int64(input.FolderID), input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"feeds": feeds, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// shareOrganizationFeedHandler shares a feed with every member of the user's
+// organization, optionally into one of its folders. Any member can share a feed.
+// eg: POST /v1/organizations/feeds {"feed_id": "...", "folder_id": 2}
+func (app *application) shareOrganizationFeedHandler(w http.ResponseWriter, r *http.Request) {
+	organization, ok := app.userOrganization(w, r, false)
+	if !ok {
+		return
+	}
+	var input struct {
+		Feed_ID   uuid.UUID `json:"feed_id"`
+		Folder_ID int64     `json:"folder_id"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	v := validator.New()
+	v.Check(input.Feed_ID != uuid.Nil, "feed_id", "must be provided")
+	v.Check(input.Folder_ID >= 0, "folder_id", "must not be negative")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	user := app.contextGetUser(r)
+	feed := &data.OrganizationFeed{
+		Feed_ID:        input.Feed_ID,
+		Folder_ID:      input.Folder_ID,
+		Shared_By:      user.ID,
+		Shared_By_Name: user.Name,
+	}
+	err = app.models.Organizations.ShareOrganizationFeed(organization.ID, feed)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrOrganizationFeedNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrFeedAlreadyShared):
+			v.AddError("feed_id", "this feed is already shared with the organization")
+			app.failedConstraintValidation(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusCreated, envelope{"feed": feed}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// moveOrganizationFeedHandler moves a shared feed into another folder, a folder_id of 0
+// takes it out of its folder. Owners and admins only.
+// eg: PATCH /v1/organizations/feeds/{feedID} {"folder_id": 3}
+func (app *application) moveOrganizationFeedHandler(w http.ResponseWriter, r *http.Request) {
+	feedID, err := app.readIDParam(r, "feedID")
+	if err != nil || feedID == uuid.Nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	organization, ok := app.userOrganization(w, r, true)
+	if !ok {
+		return
+	}
+	var input struct {
+		Folder_ID int64 `json:"folder_id"`
+	}
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	v := validator.New()
+	if v.Check(input.Folder_ID >= 0, "folder_id", "must not be negative"); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	err = app.models.Organizations.MoveOrganizationFeed(organization.ID, feedID, input.Folder_ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrOrganizationFeedNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "feed moved successfully"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// unshareOrganizationFeedHandler stops sharing a feed with the user's organization.
+// Members can only unshare the feeds they shared, owners and admins any of them.
+// eg: DELETE /v1/organizations/feeds/{feedID}
+func (app *application) unshareOrganizationFeedHandler(w http.ResponseWriter, r *http.Request) {
+	feedID, err := app.readIDParam(r, "feedID")
+	if err != nil || feedID == uuid.Nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	organization, ok := app.userOrganization(w, r, false)
+	if !ok {
+		return
+	}
+	sharedBy := app.contextGetUser(r).ID
+	if organization.CanManage() {
+		sharedBy = 0
+	}
+	err = app.models.Organizations.UnshareOrganizationFeed(organization.ID, feedID, sharedBy)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrOrganizationFeedNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "feed unshared successfully"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// sendOrganizationInvitationEmail() emails the invitation link, the token is only ever
+// sent here.
+func (app *application) sendOrganizationInvitationEmail(invitation *data.OrganizationInvitation) {
+	invitationURL := app.config.frontend.invitationurl + invitation.Token
+	role := "an admin"
+	if invitation.Role == data.OrganizationRoleMember {
+		role = "a member"
+	}
+	app.background(func() {
+		data := map[string]any{
+			"InviterName":      invitation.Invited_By_Name,
+			"OrganizationName": invitation.Organization_Name,
+			"Role":             role,
+			"InvitationURL":    invitationURL,
+			"ExpiresAt":        invitation.Expires_At.Format("Jan 2, 2006"),
+		}
+		err := app.mailer.Send(invitation.Email, "organization_invitation.tmpl", data)
+		if err != nil {
+			app.logger.PrintError(err, nil)
+		}
+	})
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/blue-davinci/aggregate/internal/data"
+	"github.com/blue-davinci/aggregate/internal/validator"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// outboundFeedContentTypes maps each outbound format to the content type we serve it with
+var outboundFeedContentTypes = map[string]string{
+	data.OutboundFormatRSS:  "application/rss+xml; charset=utf-8",
+	data.OutboundFormatAtom: "application/atom+xml; charset=utf-8",
+	data.OutboundFormatJSON: "application/feed+json; charset=utf-8",
+}
+
+// createOutboundFeedHandler() creates a secret-token URL for one of the user's post
+// listings i.e their timeline, a followed feed, a saved search or their favorites.
+// The token is only returned once, so the response includes the ready made URLs.
+func (app *application) createOutboundFeedHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Source          string    `json:"source"`
+		Feed_ID         uuid.UUID `json:"feed_id"`
+		Saved_Search_ID uuid.UUID `json:"saved_search_id"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	user := app.contextGetUser(r)
+	outboundFeed := &data.OutboundFeed{
+		User_ID:         user.ID,
+		Source:          input.Source,
+		Feed_ID:         uuid.NullUUID{UUID: input.Feed_ID, Valid: input.Feed_ID != uuid.Nil},
+		Saved_Search_ID: uuid.NullUUID{UUID: input.Saved_Search_ID, Valid: input.Saved_Search_ID != uuid.Nil},
+	}
+	v := validator.New()
+	if data.ValidateOutboundFeed(v, outboundFeed); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	// make sure whatever we are exposing exists and, for saved searches, belongs to the user
+	switch outboundFeed.Source {
+	case data.OutboundSourceFeed:
+		_, err = app.models.Feeds.GetFeedByID(outboundFeed.Feed_ID.UUID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				v.AddError("feed_id", "feed does not exist")
+				app.failedValidationResponse(w, r, v.Errors)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+	case data.OutboundSourceSavedSearch:
+		_, err = app.models.SavedSearches.GetSavedSearchByID(outboundFeed.Saved_Search_ID.UUID, user.ID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrSavedSearchNotFound):
+				v.AddError("saved_search_id", "saved search does not exist")
+				app.failedValidationResponse(w, r, v.Errors)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+	}
+	err = app.models.OutboundFeeds.CreateOutboundFeed(outboundFeed)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	outboundFeed.URLs = app.outboundFeedURLs(outboundFeed.Token)
+	err = app.writeJSON(w, http.StatusCreated, envelope{"outbound_feed": outboundFeed}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getOutboundFeedsHandler() lists the outbound feeds a user has created
+func (app *application) getOutboundFeedsHandler(w http.ResponseWriter, r *http.Request) {
+	outboundFeeds, err := app.models.OutboundFeeds.GetOutboundFeedsForUser(app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"outbound_feeds": outboundFeeds}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteOutboundFeedHandler() revokes an outbound feed, eg: DELETE /feeds/outbound/{outboundID}
+func (app *application) deleteOutboundFeedHandler(w http.ResponseWriter, r *http.Request) {
+	outboundFeedID, err := app.readIDParam(r, "outboundID")
+	if err != nil || outboundFeedID == uuid.Nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	err = app.models.OutboundFeeds.DeleteOutboundFeed(outboundFeedID, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrOutboundFeedNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "outbound feed revoked successfully"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// renderOutboundFeedHandler() is the public endpoint feed readers poll. The secret token
+// identifies both the user and the listing, eg: GET /feeds/outbound/{token}/atom.
+// We send an ETag and Last-Modified so that well behaved readers get a 304 when nothing
+// has changed.
+func (app *application) renderOutboundFeedHandler(w http.ResponseWriter, r *http.Request) {
+	token := chi.URLParam(r, "token")
+	format := chi.URLParam(r, "format")
+	contentType, ok := outboundFeedContentTypes[format]
+	if token == "" || !ok {
+		app.notFoundResponse(w, r)
+		return
+	}
+	outboundFeed, err := app.models.OutboundFeeds.GetOutboundFeedByToken(token)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrOutboundFeedNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	document, err := app.buildOutboundFeedDocument(outboundFeed, app.outboundFeedURLs(token)[format])
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrSavedSearchNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	body, err := document.Render(format)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	// caching headers
+	checksum := sha256.Sum256(body)
+	etag := fmt.Sprintf(`"%s"`, hex.EncodeToString(checksum[:16]))
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", app.config.outbound.cachemaxage))
+	if !document.Updated.IsZero() {
+		w.Header().Set("Last-Modified", document.Updated.UTC().Format(http.TimeFormat))
+	}
+	if outboundFeedNotModified(r, etag, document.Updated) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// buildOutboundFeedDocument() loads the newest posts for the outbound feed's source and
+// turns them into a document ready for rendering. We reuse the same listings the app
+// serves, newest published first.
+func (app *application) buildOutboundFeedDocument(outboundFeed *data.OutboundFeed, self string) (*data.OutboundFeedDocument, error) {
+	filters := data.Filters{
+		Page:     1,
+		PageSize: app.config.outbound.maxitems,
+		Sort:     data.DefaultPostSort,
+	}
+	var (
+		posts       []*data.RSSFeedWithFavorite
+		title       string
+		description string
+		link        = app.config.frontend.baseurl
+		err         error
+	)
+	switch outboundFeed.Source {
+	case data.OutboundSourceFeed:
+		posts, _, err = app.models.RSSFeedData.GetFollowedRssPostsForUser(outboundFeed.User_ID, "", outboundFeed.Feed_ID.UUID, filters)
+		title, description = "Aggregate - Feed", "Posts from a followed feed"
+		if len(posts) > 0 {
+			title = "Aggregate - " + posts[0].RSSFeed.Channel.Title
+		}
+	case data.OutboundSourceSavedSearch:
+		var savedSearch *data.SavedSearch
+		savedSearch, err = app.models.SavedSearches.GetSavedSearchByID(outboundFeed.Saved_Search_ID.UUID, outboundFeed.User_ID)
+		if err != nil {
+			return nil, err
+		}
+		posts, _, err = app.models.SavedSearches.GetSavedSearchRssPostsForUser(outboundFeed.User_ID, savedSearch.ID, filters)
+		title, description = "Aggregate - "+savedSearch.Name, "Posts matching your saved search"
+	case data.OutboundSourceFavorites:
+		posts, _, err = app.models.RSSFeedData.GetRSSFavoritePostsOnlyForUser(outboundFeed.User_ID, "", uuid.Nil, filters)
+		title, description = "Aggregate - Favorites", "Your favorite posts"
+	default:
+		posts, _, err = app.models.RSSFeedData.GetFollowedRssPostsForUser(outboundFeed.User_ID, "", uuid.Nil, filters)
+		title, description = "Aggregate - Timeline", "Posts from the feeds you follow"
+	}
+	if err != nil {
+		return nil, err
+	}
+	return data.NewOutboundFeedDocument(title, description, link, self, posts), nil
+}
+
+// outboundFeedURLs() returns the URL of each outbound format for a token
+func (app *application) outboundFeedURLs(token string) map[string]string {
+	baseURL := strings.TrimSuffix(app.config.outbound.baseurl, "/")
+	urls := make(map[string]string, len(outboundFeedContentTypes))
+	for format := range outboundFeedContentTypes {
+		urls[format] = fmt.Sprintf("%s/%s/%s", baseURL, token, format)
+	}
+	return urls
+}
+
+// outboundFeedNotModified() checks the conditional request headers. If-None-Match takes
+// precedence over If-Modified-Since as per RFC 9110.
+func outboundFeedNotModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == etag || candidate == "*" {
+				return true
+			}
+		}
+		return false
+	}
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !lastModified.IsZero() {
+		since, err := http.ParseTime(ims)
+		if err == nil && !lastModified.Truncate(time.Second).After(since) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,7 +1,7 @@
 package main
 
 import (
-	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
@@ -10,17 +10,22 @@ import (
 	"github.com/blue-davinci/aggregate/internal/data"
 	"github.com/blue-davinci/aggregate/internal/validator"
 	"github.com/google/uuid"
-	"github.com/hashicorp/go-retryablehttp"
 )
 
 // getPaymentPlansHandler() is a handler that gets all the available subscription plans.
 // it's a simple route, all we do is get the plans from the database and write them to the client.
+// Each plan also carries its price in the currency of the user's locale.
 func (app *application) getPaymentPlansHandler(w http.ResponseWriter, r *http.Request) {
 	plans, err := app.models.Payments.GetPaymentPlans()
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
+	locale := app.readLocale(r)
+	for _, plan := range plans {
+		localPrice := plan.PriceForLocale(locale)
+		plan.Local_Price = &localPrice
+	}
 	err = app.writeJSON(w, http.StatusOK, envelope{"plans": plans}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -105,6 +110,12 @@ func (app *application) updateSubscriptionStatusForUserHandler(w http.ResponseWr
 		app.serverErrorResponse(w, r, err)
 		return
 	}
+	// a cancelled subscription isn't renewed so a downgrade scheduled on it never happens
+	_, err = app.models.Payments.CancelScheduledPlanChange(app.contextGetUser(r).ID)
+	if err != nil && !errors.Is(err, data.ErrPlanChangeNotFound) {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
 	err = app.writeJSON(w, http.StatusOK, envelope{"subscription": subscription}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -147,17 +158,21 @@ func (app *application) updateChallengedTransactionStatus(w http.ResponseWriter,
 }
 
 // initializeTransactionHandler() is a handler that creates an intent for the transaction
-// we get in the plan ID, amount in cts and a callback URL. If the callback URL is not
-// provided, we default to the internal callback URL. The plan ID keeps track of the plan
-// we will be using incase we will ever want to save an intent, which we don't now.
-// We validate the transaction data and then send the data to the transaction client
-// which should send a post request and get back a response which contains a reference as well
-// and more importantly the authorization URL. We then write the response to the client.
+// we get in the plan ID, amount in minor units (cents) and a callback URL. If the callback
+// URL is not provided, we default to the internal callback URL. The plan is charged in the
+// requested currency, or in the currency of the user's locale when none is given.
+// We validate the transaction data and then start a checkout with the provider for that
+// currency, which gives back a reference as well and more importantly the
+// authorization URL. We then write the response to the client.
+// The amount is always the plan's full price, a promo code's discount is taken off here.
+// Plans with a free trial start one instead for users who never had a trial.
 func (app *application) initializeTransactionHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
 		PlanID      int32  `json:"plan_id"`
 		Amount      int64  `json:"amount"`
 		CallBackURL string `json:"callback_url"`
+		Currency    string `json:"currency"`
+		PromoCode   string `json:"promo_code"`
 	}
 	err := app.readJSON(w, r, &input)
 	if err != nil {
@@ -168,7 +183,8 @@ func (app *application) initializeTransactionHandler(w http.ResponseWriter, r *h
 	transactionData := &data.TransactionData{
 		User_ID:     user.ID,
 		Plan_ID:     input.PlanID,
-		Amount:      input.Amount,
+		Amount:      data.NewMoney(input.Amount, input.Currency),
+		Promo_Code:  data.NormalizeCouponCode(input.PromoCode),
 		Email:       user.Email,
 		CallBackURL: input.CallBackURL,
 	}
@@ -210,34 +226,137 @@ func (app *application) initializeTransactionHandler(w http.ResponseWriter, r *h
 		}
 		return
 	}
-	if plan.Price != transactionData.Amount {
+	// get the price in the currency asked for, without one we go by the user's locale
+	price := plan.PriceForLocale(app.readLocale(r))
+	if transactionData.Amount.Currency != "" {
+		var ok bool
+		price, ok = plan.PriceIn(transactionData.Amount.Currency)
+		if !ok {
+			v.AddError("currency", "the plan is not sold in this currency")
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+	}
+	if price.Amount != transactionData.Amount.Amount {
 		// if the amount is not the same as the plan price, we return a 400 error
 		app.badRequestResponse(w, r, errors.New("we could not process the data due to a discrepancy"))
 		return
 	}
-	// we now set the amount into our transaction data
-	transactionData.Amount *= 100 * 100 // we multiply it by 100*100 since the default for paystack is in cents
-	app.logger.PrintInfo("amount", map[string]string{"amount": fmt.Sprintf("%d", transactionData.Amount),
-		"plan":       plan.Name,
-		"plan price": fmt.Sprintf("%d", plan.Price)})
-	initializeResponse, err := app.transactionClient(transactionData, app.config.paystack.initializationurl, data.PaymentOperationInitialize)
+	// a promo code takes its discount off the first payment only, one of the coupon's
+	// redemptions is held for the user until this checkout is paid or given up on
+	var redemption *data.CouponRedemption
+	discount := data.NewMoney(0, price.Currency)
+	if transactionData.Promo_Code != "" {
+		var ok bool
+		redemption, discount, ok = app.readPromoCode(w, r, transactionData.Promo_Code, user.ID, plan, price)
+		if !ok {
+			return
+		}
+		transactionData.Discount = &discount
+	}
+	// a plan with a free trial starts one for users who never had a trial
+	if plan.Trial_Days > 0 {
+		hadTrial, err := app.models.Payments.HasUserHadTrial(user.ID)
+		if err != nil {
+			app.releaseCouponRedemption(redemption)
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		if !hadTrial {
+			app.startTrial(w, r, user, plan, price, redemption, transactionData)
+			return
+		}
+	}
+	// we now set the price into our transaction data, amounts are already in minor units
+	charge := data.NewMoney(price.Amount-discount.Amount, price.Currency)
+	transactionData.Amount = charge
+	app.logger.PrintInfo("amount", map[string]string{"amount": charge.String(), "plan": plan.Name})
+	// the currency decides which provider the checkout goes through
+	provider := app.paymentProviders.ForCurrency(charge.Currency)
+	session, err := provider.InitializeCheckout(r.Context(), &data.Checkout{
+		Email:       transactionData.Email,
+		Amount:      charge.Amount,
+		Currency:    charge.Currency,
+		Description: plan.Name,
+		CallbackURL: transactionData.CallBackURL,
+	})
+	if err != nil {
+		app.releaseCouponRedemption(redemption)
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	// save what this reference is for and who it went through, the webhook needs it to
+	// settle the payment should the user never make it back to verify it
+	err = app.models.Payments.CreatePaymentIntent(&data.PaymentIntent{
+		Reference: session.Reference,
+		Provider:  provider.Name(),
+		User_ID:   user.ID,
+		Plan_ID:   plan.ID,
+		Amount:    charge.Amount,
+	})
 	if err != nil {
+		app.releaseCouponRedemption(redemption)
 		app.serverErrorResponse(w, r, err)
 		return
 	}
-	// We send back the transaction Data incase the frontend needs it as well as the initialize response which
+	// the promo code is redeemed once the checkout is paid
+	if redemption != nil {
+		err = app.models.Coupons.SetCouponRedemptionReference(redemption, session.Reference)
+		if err != nil {
+			app.releaseCouponRedemption(redemption)
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+	// We send back the transaction Data incase the frontend needs it as well as the checkout session which
 	// the frontend will require, using both the auth URL and the reference.
-	err = app.writeJSON(w, http.StatusCreated, envelope{"initialization": initializeResponse.InitializeResponse, "transaction_data": transactionData}, nil)
+	err = app.writeJSON(w, http.StatusCreated, envelope{"initialization": session, "transaction_data": transactionData}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// startTrial() starts a free trial of a plan for the user. The trial subscription is
+// priced at what the plan costs so the billing runner knows what to ask for once it
+// ends, and a promo code used to start it is taken off that first payment. The code's
+// redemption is held by the trial until then.
+func (app *application) startTrial(w http.ResponseWriter, r *http.Request, user *data.User, plan *data.Payment_Plan, price data.Money, redemption *data.CouponRedemption, transactionData *data.TransactionData) {
+	trial := &data.Payment_Details{
+		User_ID:    user.ID,
+		Plan_ID:    plan.ID,
+		Start_Date: time.Now().UTC(),
+		End_Date:   time.Now().UTC().AddDate(0, 0, int(plan.Trial_Days)),
+		Price:      price,
+		Provider:   app.paymentProviders.ForCurrency(price.Currency).Name(),
+	}
+	err := app.models.Payments.CreateTrialSubscription(trial)
+	if err != nil {
+		app.releaseCouponRedemption(redemption)
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if redemption != nil {
+		err = app.models.Coupons.SetCouponRedemptionSubscription(redemption, trial.ID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+	// nothing is charged until the trial ends
+	transactionData.Amount = data.NewMoney(0, price.Currency)
+	app.notifyUser(user.ID, data.InboxTypeBilling,
+		fmt.Sprintf("Your free trial of %s runs until %s", plan.Name, trial.End_Date.Format("Jan 2, 2006")), uuid.Nil)
+	err = app.writeJSON(w, http.StatusCreated, envelope{"trial": trial, "transaction_data": transactionData}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
 
 // verifyTransactionHandler() is a handler that verifies a transaction. We get the reference
-// and the plan ID. We validate the transaction data and then send the data to the transaction
-// client which should send a get request and get back a response which contains the status of the
-// transaction, the message and the card type in additiona to a whole bevy of info. We will
-// only save this data if it was actually successful or send back an error if not
+// and the plan ID. We validate the transaction data and then ask the provider the checkout
+// was started with for the transaction, which contains its status, the message and the card
+// in addition to a whole bevy of info. We will only save this data if it was actually
+// successful or send back an error if not
 func (app *application) verifyTransactionHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
 		Reference string `json:"reference"`
@@ -260,9 +379,11 @@ func (app *application) verifyTransactionHandler(w http.ResponseWriter, r *http.
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
-	verificationUrlEndpoint := fmt.Sprintf("%s%s", app.config.paystack.verificationurl, transactionData.Reference)
-	app.logger.PrintInfo("verification url", map[string]string{"url": verificationUrlEndpoint})
-
+	provider, err := app.paymentProviderForReference(transactionData.Reference)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
 	// we get the plan to fill in required data
 	plan, err := app.models.Payments.GetPaymentPlanByID(transactionData.Plan_ID)
 	if err != nil {
@@ -275,51 +396,71 @@ func (app *application) verifyTransactionHandler(w http.ResponseWriter, r *http.
 		}
 		return
 	}
-	//quick fill for the transactio data
-	transactionData.Amount = plan.Price
-	// we now send the transaction data to the transaction client to verify the transaction
-	verifyResponse, err := app.transactionClient(transactionData, verificationUrlEndpoint, data.PaymentOperationVerify)
+	// we now ask the provider for the transaction
+	transaction, err := provider.VerifyTransaction(r.Context(), transactionData.Reference)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 	app.logger.PrintInfo("verify response", map[string]string{
-		"status":    fmt.Sprintf("%t", verifyResponse.VerifyResponse.Status),
-		"message":   verifyResponse.VerifyResponse.Message,
-		"card_type": verifyResponse.VerifyResponse.Data.Authorization.CardType,
+		"provider":  transaction.Provider,
+		"status":    transaction.Status,
+		"message":   transaction.Message,
+		"card_type": transaction.Authorization.CardType,
 	})
-	// we need to check verifyResponse.data.status to see if its "success" or "failed"
-	// and verifyResponse.data.gateway_response to see if its  "Approved" or "Declined"
-	if verifyResponse.VerifyResponse.Data.Status != "success" && verifyResponse.VerifyResponse.Data.GatewayResponse != "Approved" {
+	if !transaction.Succeeded() {
+		// a checkout that failed gives back the promo code redemption it held
+		if transaction.Status == data.TransactionStatusFailed {
+			err = app.models.Coupons.ReleaseCheckoutCouponRedemption(transactionData.Reference)
+			if err != nil {
+				app.logger.PrintError(err, map[string]string{"reference": transactionData.Reference})
+			}
+		}
 		// we assume this is a failed transaction and return a 400 error
 		failedTransaction := fmt.Sprintf("error: %s\nplan: %s\nemail: %s", data.ErrTransactionDeclined.Error(), plan.Name, user.Email)
 		app.badRequestResponse(w, r, errors.New(failedTransaction))
 		return
 	}
 	// if the transaction was successful, we save the transaction data to the database
-	payment_detail := &data.Payment_Details{
-		User_ID:            user.ID,
-		Plan_ID:            transactionData.Plan_ID,
-		Start_Date:         time.Now().UTC(),
-		End_Date:           app.returnEndDate(plan.Duration, time.Now().UTC()),
-		Price:              verifyResponse.VerifyResponse.Data.Amount / 100,
-		Status:             "active",
-		TransactionID:      verifyResponse.VerifyResponse.Data.ID,
-		Payment_Method:     verifyResponse.VerifyResponse.Data.Channel,
-		Authorization_Code: verifyResponse.VerifyResponse.Data.Authorization.AuthorizationCode,
-		Card_Last4:         verifyResponse.VerifyResponse.Data.Authorization.Last4,
-		Card_Exp_Month:     verifyResponse.VerifyResponse.Data.Authorization.ExpMonth,
-		Card_Exp_Year:      verifyResponse.VerifyResponse.Data.Authorization.ExpYear,
-		Card_Type:          verifyResponse.VerifyResponse.Data.Authorization.CardType,
-		Currency:           verifyResponse.VerifyResponse.Data.Currency,
+	payment_detail := app.newPaymentDetails(user.ID, plan, transaction)
+	//quick fill for the transactio data
+	transactionData.Amount = payment_detail.Price
+	// a plan change only charges part of the plan's price, the subscription it starts
+	// still renews at the full price
+	planChange, err := app.planChangeForReference(transactionData.Reference)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	// so does a payment discounted by a promo code
+	redemption, err := app.couponRedemptionForReference(transactionData.Reference)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	amountCharged := payment_detail.Price
+	if planChange != nil || redemption != nil {
+		if price, ok := plan.PriceIn(amountCharged.Currency); ok {
+			payment_detail.Price = price
+		}
 	}
 
-	err = app.createSubscriptionHandler(payment_detail, plan.Name, user.Name, user.Email, verifyResponse.VerifyResponse.Data.TransactionDate)
+	lines := data.SubscriptionLineItems(plan.Name, payment_detail.Price, amountCharged, app.invoiceAdjustment(planChange, redemption))
+	err = app.createSubscriptionHandler(payment_detail, lines, plan.Name, user.Name, user.Email, transaction.Paid_At)
 	// if we get a constraint validation on the transaction ID, we return a 400 error
 	// as we know we have already processed the same transaction.
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrDuplicateTransaction):
+			// the webhook may have settled the user's own checkout before they made it back
+			intent, intentErr := app.models.Payments.GetPaymentIntentByReference(transactionData.Reference)
+			if intentErr == nil && intent.User_ID == user.ID {
+				err = app.writeJSON(w, http.StatusOK, envelope{"payment_details": payment_detail, "transaction_data": transactionData}, nil)
+				if err != nil {
+					app.serverErrorResponse(w, r, err)
+				}
+				return
+			}
 			v.AddError("transaction", "[RP-T] An error occurred with your transaction. Please try again. If the issue persists, please contact support with this message.")
 			app.failedValidationResponse(w, r, v.Errors)
 		default:
@@ -347,6 +488,27 @@ func (app *application) verifyTransactionHandler(w http.ResponseWriter, r *http.
 			app.serverErrorResponse(w, r, err)
 			return
 		}
+		// the subscription it renews is done with, else the billing runner would go on to
+		// retry and then expire it
+		err = app.models.Payments.UpdateSubscriptionStatus(challengedTransaction.ReferencedSubscriptionID, data.PaymentStatusRenewed, user.ID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+	if planChange != nil {
+		err = app.completePlanChange(planChange, payment_detail, amountCharged)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+	if redemption != nil {
+		err = app.redeemCoupon(redemption, payment_detail.ID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
 	}
 	// We send back the transaction and Payment details Data back incase the frontend needs it
 	// maybe for items such as reciept generation etc.
@@ -356,72 +518,55 @@ func (app *application) verifyTransactionHandler(w http.ResponseWriter, r *http.
 	}
 }
 
-// transactionClient() sets up the client side of this module. The client is different from the scraper
-// and performs tasks differently thus we just made a none-unified one. We set up a retryable client
-// which sets up the body based on the type of transaction request we are doing. If a transaction request is
-// an initialization request i.e PaymentOperationInitialize, we use a POST request to send the transaction
-// DATA to the payment URL while If it is a verification request i.e PaymentOperationVerify, we use a GET request
-// to get the transaction data from the payment URL. We make sure to set relevant headers for each request,
-// most important of which is the PAYSTACK secret KEY.
-// the responses are written in a unified struct and returned to the callers which can access them via
-// their appropriate fields.
-func (app *application) transactionClient(transactionData *data.TransactionData, payment_url string, paymentOperation data.PaymentOperation) (*data.UnifiedPaymentResponse, error) {
-	retryClient := retryablehttp.NewClient()
-	retryClient.RetryMax = 5
-	var body *bytes.Buffer
-	var req *retryablehttp.Request
-	var err error
-	// if the operation is an initialization, we do a quick byte conversion of the
-	// body and set up the request to be a POST request
-	if paymentOperation == data.PaymentOperationInitialize || paymentOperation == data.PaymentOperationRecurring {
-		app.logger.PrintInfo("transaction data", map[string]string{"Transaction data Amount": fmt.Sprintf("%d", transactionData.Amount)})
-		jsonData, err := app.covertToByteArray(transactionData)
-		if err != nil {
-			app.logger.PrintError(err, nil)
-			return nil, err
-		}
-		body = bytes.NewBuffer(jsonData)
-		req, err = retryablehttp.NewRequest("POST", payment_url, body)
-		if err != nil {
-			app.logger.PrintError(err, nil)
-			return nil, err
-		}
-	} else {
-		req, err = retryablehttp.NewRequest("GET", payment_url, nil)
-		if err != nil {
-			app.logger.PrintError(err, nil)
+// paymentProviderForReference() returns the provider a checkout was started with. References
+// from before we saved payment intents can only have gone through the default provider.
+func (app *application) paymentProviderForReference(reference string) (data.PaymentProvider, error) {
+	intent, err := app.models.Payments.GetPaymentIntentByReference(reference)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrPaymentIntentNotFound):
+			return app.paymentProviders.Default(), nil
+		default:
 			return nil, err
 		}
 	}
-
-	// we set the headers for the request, using our token saved in our env file
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", app.config.paystack.secretkey))
-	req.Header.Set("Content-Type", "application/json")
-	resp, err := retryClient.Do(req)
-	if err != nil {
-		app.logger.PrintError(err, nil)
-		return nil, err
+	provider, ok := app.paymentProviders.Get(intent.Provider)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", data.ErrUnknownPaymentProvider, intent.Provider)
 	}
-	defer resp.Body.Close()
+	return provider, nil
+}
 
-	// prep the response reciever
-	var paymentRequest any
-	if paymentOperation == data.PaymentOperationInitialize {
-		paymentRequest = &data.InitializeResponse{}
-	} else {
-		paymentRequest = &data.VerifyResponse{}
-	}
-	//app.logger.PrintInfo("payment request", map[string]string{"request": fmt.Sprintf("%+v", paymentRequest)})
-	// get the response and decode it into our reciever
-	err = app.readJSONFromReader(resp.Body, &paymentRequest)
-	if err != nil {
-		return nil, err
+// newPaymentDetails() fills in the details of a new subscription paid for by a successful
+// provider transaction, it starts now and runs for the plan's duration.
+func (app *application) newPaymentDetails(userID int64, plan *data.Payment_Plan, transaction *data.ProviderTransaction) *data.Payment_Details {
+	return &data.Payment_Details{
+		User_ID:            userID,
+		Plan_ID:            plan.ID,
+		Start_Date:         time.Now().UTC(),
+		End_Date:           app.returnEndDate(plan.Duration, time.Now().UTC()),
+		Price:              app.transactionPrice(plan, transaction),
+		Status:             "active",
+		TransactionID:      transaction.ID,
+		Payment_Method:     transaction.Channel,
+		Authorization_Code: transaction.Authorization.AuthorizationCode,
+		Card_Last4:         transaction.Authorization.Last4,
+		Card_Exp_Month:     transaction.Authorization.ExpMonth,
+		Card_Exp_Year:      transaction.Authorization.ExpYear,
+		Card_Type:          transaction.Authorization.CardType,
+		Provider:           transaction.Provider,
+		Provider_Reference: transaction.Payment_Reference,
 	}
+}
 
-	if paymentOperation == data.PaymentOperationInitialize {
-		return &data.UnifiedPaymentResponse{InitializeResponse: *paymentRequest.(*data.InitializeResponse)}, nil
+// transactionPrice() is what a provider transaction charged. Providers that leave out the
+// currency charged in the plan's own.
+func (app *application) transactionPrice(plan *data.Payment_Plan, transaction *data.ProviderTransaction) data.Money {
+	currency := transaction.Currency
+	if currency == "" {
+		currency = plan.Price.Currency
 	}
-	return &data.UnifiedPaymentResponse{VerifyResponse: *paymentRequest.(*data.VerifyResponse)}, nil
+	return data.NewMoney(transaction.Amount, currency)
 }
 
 // startAutoSubscriptionHandler() is a handler that starts the auto subscription handler.
@@ -442,7 +587,7 @@ func (app *application) startPaymentSubscriptionHandler() {
 	expiryChallengedTransactionInterval := fmt.Sprintf("*/%d * * * *", app.config.paystack.checkexpiredchallengedsubscriptioninterval)
 
 	// add the autosubscription handler to the cron job
-	_, err := app.config.paystack.cronJob.AddFunc(autoSubscriptionInterval, app.autoSubscriptionHandler)
+	_, err := app.config.paystack.cronJob.AddFunc(autoSubscriptionInterval, app.runBilling)
 	if err != nil {
 		app.logger.PrintError(err, map[string]string{
 			"Error": "Error adding autosubscription job",
@@ -457,6 +602,14 @@ func (app *application) startPaymentSubscriptionHandler() {
 		})
 	}
 
+	// promo code redemptions held by checkouts that were never paid are released as well
+	_, err = app.config.paystack.cronJob.AddFunc(expiryCheckInterval, app.releaseExpiredCouponRedemptions)
+	if err != nil {
+		app.logger.PrintError(err, map[string]string{
+			"Error": "Error adding coupon redemption release job",
+		})
+	}
+
 	// add the challenged transaction checker to the cron job
 	_, err = app.config.paystack.cronJob.AddFunc(expiryChallengedTransactionInterval, app.updateExpiredChallengedTransactionStatus)
 	if err != nil {
@@ -470,61 +623,26 @@ func (app *application) startPaymentSubscriptionHandler() {
 	// ------------------------------------------
 	// perform an expired check before we start the cron
 	app.updateExpiredSubscriptionHandler()
-	// perform and check for any expired subscriptions to begin autosubscription
-	app.autoSubscriptionHandler()
+	// perform a billing run for any subscriptions that came due while we were down
+	app.runBilling()
 
 	// start the cron scheduler
 	app.config.paystack.cronJob.Start()
 }
 
-// autoSubscriptionHandler() should handle recurring charges on a subscribers account.
-// It should start by selecting all accounts whose subscription is due for renewal.
-// For each of those accounts, we will need the account email, subscription auth
-// and the amount to recharge. We will then use this information to send the data
-// using our pre-existing client to the initialization endpoint.
-// We will then check the status, checking if the transaction is paused. If it's paused
-// or even failed, we will add it to our table from where the frontend will poll
-// and send a notification to the user. If the transaction is successful, we will
-// add a new subscription and update the challanged item, whether succesful or failed.
-// If successful, send an acknowledgement email to user, if not, send a checkup email instead.
-// No Errors should really be treated as panics, so this methods weill just log the error and proceed
-func (app *application) autoSubscriptionHandler() {
-	app.logger.PrintInfo("auto subscription handler", nil)
-	//setup filters
-	var input struct{ data.Filters }
-	input.Filters.Page = app.readInt(nil, "page", 1, nil)
-	input.Filters.PageSize = app.readInt(nil, "page_size", 5, nil)
-	// get all subscriptions that are due for renewal
-	subscriptions, _, err := app.models.Payments.GetAllExpiredSubscriptions(input.Filters)
-	if err != nil {
-		app.logger.PrintError(err, nil)
-	}
-	// for each subscription, we will send a request to the payment client,
-	// using the charge authorization endpoint instead.
-	for _, subscription := range subscriptions {
-		app.logger.PrintInfo("subscription", map[string]string{"email": subscription.User_Email, "plan": subscription.Authorization_Code})
-		// each item is a subscription, so we concert to a transacyion data
-		err := app.processRecurringSubscription(subscription)
-		if err != nil {
-			app.logger.PrintError(err, nil)
-		}
-	}
-}
-
-// processRecurringSubscription() is the main handler that processes recurring subscriptions.
-// That is, it checks for any and all subscriptions that are expired, and for each of them
-// It proceeds to perform a subsequent charge of them provided they are not cancelled.
-func (app *application) processRecurringSubscription(subscription *data.RecurringSubscription) error {
+// processRecurringSubscription() makes a renewal charge on a subscription that is due and
+// reports how it went as a billing outcome with a message for the run's report. A charge
+// that needs the customer's authorization is left as a challenged transaction, and a
+// subscription that already has one pending isn't charged again.
+func (app *application) processRecurringSubscription(subscription *data.RecurringSubscription) (string, string, error) {
 	app.logger.PrintInfo("Processing recurring subscription", map[string]string{"email": subscription.User_Email, "plan": subscription.Authorization_Code})
-	transactionData := &data.TransactionData{
-		Email:              subscription.User_Email,
-		Amount:             subscription.Subscription.Price * 100,
-		Authorization_Code: subscription.Authorization_Code,
-		CallBackURL:        fmt.Sprintf("%s%s%d", app.config.frontend.callback_url, "/health?plan_id=", subscription.Subscription.Plan_ID),
+	// the renewal goes through the provider the subscription was paid with
+	provider, ok := app.paymentProviders.Get(subscription.Provider)
+	if !ok {
+		return "", "", fmt.Errorf("%w: %s", data.ErrUnknownPaymentProvider, subscription.Provider)
 	}
-	app.logger.PrintInfo(">>>>> Price", map[string]string{"Price": fmt.Sprintf("%d", transactionData.Amount)})
 	// We need  to FIRST check if a user has a challanged transaction, if they do,
-	// there is no need to process it again and we return and proceed with the next subscription.
+	// there is no need to charge them again while they get to authorizing it.
 	challengedTransaction, err := app.models.Payments.GetPendingChallengedTransactionBySubscriptionID(subscription.Subscription.ID)
 	if err != nil {
 		switch {
@@ -532,31 +650,55 @@ func (app *application) processRecurringSubscription(subscription *data.Recurrin
 		case errors.Is(err, data.ErrChallangedTransactionNotFound):
 			// we ignore this error, means a user does not have a challenged transaction
 		default:
-			return err
+			return "", "", err
 		}
 	}
-	// if there is a pending challanged transaction, we return nil and proceed to the next subscription
 	if challengedTransaction != nil {
-		return nil
+		return data.BillingOutcomeChallenged, fmt.Sprintf("awaiting authorization of %s", challengedTransaction.Reference), nil
+	}
+	// trials are started without a card so there is nothing to charge
+	if subscription.Subscription.Is_Trial {
+		return app.convertTrial(provider, subscription)
 	}
 
-	// it will use a post request, and will feed a new charge auth url instead
-	chargeAuthResponse, err := app.transactionClient(transactionData, app.config.paystack.chargeauthorizationurl, data.PaymentOperationRecurring)
+	// a downgrade scheduled for the end of this subscription renews it on the new plan
+	planChange, err := app.applyScheduledPlanChange(subscription)
 	if err != nil {
-		return err
+		return "", "", err
+	}
+	charge := &data.AuthorizationCharge{
+		Email:              subscription.User_Email,
+		Amount:             subscription.Subscription.Price.Amount,
+		Currency:           subscription.Subscription.Price.Currency,
+		Authorization_Code: subscription.Authorization_Code,
+	}
+	app.logger.PrintInfo(">>>>> Price", map[string]string{"Price": subscription.Subscription.Price.String()})
+	// the batch this came in may have taken a while to get to, make sure the lease is still
+	// ours and long enough to cover the charge before we take the user's money
+	err = app.models.Billing.ExtendRenewalLock(subscription, time.Now().Add(app.config.billing.locktimeout))
+	if err != nil {
+		return "", "", err
+	}
+	transaction, err := provider.ChargeAuthorization(context.Background(), charge)
+	if err != nil {
+		return "", "", err
 	}
-	// if the transaction was challanged, it will have 2 distinct items, Paused=true + authorization_url
-	// when it's paused, we will need to add this transaction to the challanged transaction table
-	if chargeAuthResponse.VerifyResponse.Data.Paused {
-		err := app.createChallengedTransaction(subscription, &chargeAuthResponse.VerifyResponse)
+	// if the customer needs to authorize the charge, we add it to the challanged transaction
+	// table and wait for them, the webhook or their verification renews the subscription
+	if transaction.Status == data.TransactionStatusChallenged {
+		err = app.createChallengedTransaction(subscription, transaction)
+		if err == nil && planChange != nil {
+			err = app.models.Payments.SetPlanChangePending(planChange, transaction.Reference)
+		}
 		if err != nil {
-			return err
+			return "", "", err
 		}
+		return data.BillingOutcomeChallenged, transaction.Message, nil
 	}
 	// Get our plan
 	plan, err := app.models.Payments.GetPaymentPlanByID(subscription.Subscription.Plan_ID)
 	if err != nil {
-		return err
+		return "", "", err
 	}
 	// Create a payment Detail as we will use this whether it's successful or not
 	paymentDetails := &data.Payment_Details{
@@ -567,29 +709,37 @@ func (app *application) processRecurringSubscription(subscription *data.Recurrin
 		End_Date:           app.returnEndDate(plan.Duration, time.Now().UTC()),
 		Price:              subscription.Subscription.Price,
 		Authorization_Code: subscription.Authorization_Code,
-		TransactionID:      chargeAuthResponse.VerifyResponse.Data.ID,
-		Payment_Method:     chargeAuthResponse.VerifyResponse.Data.Channel,
-		Card_Last4:         chargeAuthResponse.VerifyResponse.Data.Authorization.Last4,
-		Card_Exp_Month:     chargeAuthResponse.VerifyResponse.Data.Authorization.ExpMonth,
-		Card_Exp_Year:      chargeAuthResponse.VerifyResponse.Data.Authorization.ExpYear,
-		Card_Type:          chargeAuthResponse.VerifyResponse.Data.Authorization.CardType,
-		Currency:           chargeAuthResponse.VerifyResponse.Data.Currency,
+		TransactionID:      transaction.ID,
+		Payment_Method:     transaction.Channel,
+		Card_Last4:         transaction.Authorization.Last4,
+		Card_Exp_Month:     transaction.Authorization.ExpMonth,
+		Card_Exp_Year:      transaction.Authorization.ExpYear,
+		Card_Type:          transaction.Authorization.CardType,
+		Provider:           provider.Name(),
+		Provider_Reference: transaction.Payment_Reference,
 	}
 	// if the transaction was not successful, we will add it to the failed transaction table
-	if chargeAuthResponse.VerifyResponse.Data.Status != "success" && chargeAuthResponse.VerifyResponse.Data.GatewayResponse != "Approved" {
-		err := app.createFailedTransactionHandler(paymentDetails, *chargeAuthResponse.VerifyResponse.Data.Message,
-			chargeAuthResponse.VerifyResponse.Data.Reference,
-			chargeAuthResponse.VerifyResponse.Data.GatewayResponse)
+	// and leave the billing runner to retry it
+	if !transaction.Succeeded() {
 		app.logger.PrintInfo("Adding failed transaction", map[string]string{"Status": paymentDetails.Status, "id": fmt.Sprintf("%d", paymentDetails.ID)})
+		err = app.createFailedTransactionHandler(paymentDetails, transaction.Message, transaction.Reference, transaction.Gateway_Response)
 		if err != nil {
-			return err
+			return "", "", err
 		}
+		return data.BillingOutcomeRetryScheduled, transaction.Gateway_Response, nil
 	}
 	// if the transaction was successful, we save the transaction data to the database
-	err = app.createSubscriptionHandler(paymentDetails, plan.Name, subscription.User_Name,
-		subscription.User_Email, chargeAuthResponse.VerifyResponse.Data.TransactionDate)
+	lines := data.SubscriptionLineItems(plan.Name, paymentDetails.Price, paymentDetails.Price, "")
+	err = app.createSubscriptionHandler(paymentDetails, lines, plan.Name, subscription.User_Name,
+		subscription.User_Email, transaction.Paid_At)
 	if err != nil {
-		return err
+		return "", "", err
+	}
+	if planChange != nil {
+		err = app.completePlanChange(planChange, paymentDetails, paymentDetails.Price)
+		if err != nil {
+			return "", "", err
+		}
 	}
 	// log this successful transaction
 	app.logger.PrintInfo("Adding Successful subscription", map[string]string{"Payment Method": paymentDetails.Payment_Method,
@@ -599,37 +749,97 @@ func (app *application) processRecurringSubscription(subscription *data.Recurrin
 	// as we will now have a new subscription for this specific user which we added above.
 	err = app.models.Payments.UpdateSubscriptionStatus(subscription.Subscription.ID, data.PaymentStatusRenewed, subscription.User_ID)
 	if err != nil {
-		return err
+		return "", "", err
 	}
 	// log a successful update
 	app.logger.PrintInfo("Successfully Updated a subscription",
 		map[string]string{"Subscription ID": subscription.Subscription.ID.String()})
 
-	// we are good so return back a nil error
-	return nil
+	return data.BillingOutcomeRenewed, fmt.Sprintf("renewed until %s", paymentDetails.End_Date.Format("Jan 2, 2006")), nil
+}
+
+// convertTrial() asks the user to pay for a plan once their free trial of it ends. They
+// get a checkout for the plan's price, less the promo code they started the trial with,
+// which is left as a challenged transaction so paying it settles like any renewal that
+// needed their authorization and a trial that isn't paid is retried and then expires.
+func (app *application) convertTrial(provider data.PaymentProvider, subscription *data.RecurringSubscription) (string, string, error) {
+	plan, err := app.models.Payments.GetPaymentPlanByID(subscription.Subscription.Plan_ID)
+	if err != nil {
+		return "", "", err
+	}
+	price := subscription.Subscription.Price
+	redemption, err := app.models.Coupons.GetPendingCouponRedemptionBySubscriptionID(subscription.Subscription.ID)
+	if err != nil && !errors.Is(err, data.ErrCouponRedemptionNotFound) {
+		return "", "", err
+	}
+	if redemption != nil {
+		price = data.NewMoney(price.Amount-redemption.Discount.Amount, price.Currency)
+	}
+	session, err := provider.InitializeCheckout(context.Background(), &data.Checkout{
+		Email:       subscription.User_Email,
+		Amount:      price.Amount,
+		Currency:    price.Currency,
+		Description: plan.Name,
+		CallbackURL: app.config.frontend.callback_url,
+	})
+	if err != nil {
+		return "", "", err
+	}
+	if redemption != nil {
+		err = app.models.Coupons.SetCouponRedemptionReference(redemption, session.Reference)
+		if err != nil {
+			return "", "", err
+		}
+	}
+	transaction := &data.ProviderTransaction{
+		Provider:          provider.Name(),
+		Reference:         session.Reference,
+		Status:            data.TransactionStatusChallenged,
+		Message:           "free trial ended",
+		Amount:            price.Amount,
+		Currency:          price.Currency,
+		Authorization_URL: session.Authorization_URL,
+	}
+	err = app.createChallengedTransaction(subscription, transaction)
+	if err != nil {
+		return "", "", err
+	}
+	return data.BillingOutcomeChallenged, fmt.Sprintf("free trial ended, checkout for %s sent", price), nil
 }
 
 // createSubscriptionHandler() Creates a subscription taking in payment details and user information
 // This handler sends a succesfull transaction email to the user as well if the data is saved succesfully.
-func (app *application) createSubscriptionHandler(payment_detail *data.Payment_Details, plan_name, user_name, user_email, transactionDate string) error {
+// The invoice for the payment is issued from the line items, a subscription that was paid for
+// is kept even if its invoice can't be issued.
+func (app *application) createSubscriptionHandler(payment_detail *data.Payment_Details, lines []data.InvoiceLineItem, plan_name, user_name, user_email, transactionDate string) error {
 	err := app.models.Payments.CreateSubscription(payment_detail)
 	// if we get a constraint validation on the transaction ID, we return a 400 error
 	// as we know we have already processed the same transaction.
 	if err != nil {
 		return err
 	}
+	amountPaid, invoiceNumber := payment_detail.Price, ""
+	invoice, err := app.issueInvoice(payment_detail, lines, user_name, user_email)
+	if err != nil {
+		app.logger.PrintError(err, map[string]string{"subscription id": payment_detail.ID.String()})
+	} else {
+		amountPaid, invoiceNumber = invoice.Total, invoice.Number
+	}
+	app.notifyUser(payment_detail.User_ID, data.InboxTypeBilling,
+		fmt.Sprintf("Your %s subscription is active until %s", plan_name, payment_detail.End_Date.Format("Jan 2, 2006")), uuid.Nil)
 	// We are good, so we send an email acknowledgment to the user.
 	app.background(func() {
 		data := map[string]any{
 			"UserName":        user_name,
 			"TransactionID":   payment_detail.TransactionID,
+			"InvoiceNumber":   invoiceNumber,
 			"PlanName":        plan_name,
-			"AmountPaid":      payment_detail.Price,
-			"Currency":        payment_detail.Currency,
+			"AmountPaid":      amountPaid.Major(),
+			"Currency":        amountPaid.Currency,
 			"PaymentMethod":   payment_detail.Payment_Method,
 			"Date":            payment_detail.Start_Date,
 			"TransactionDate": app.formatDate(transactionDate),
-			"GrandTotal":      payment_detail.Price,
+			"GrandTotal":      amountPaid.Major(),
 		}
 		err = app.mailer.Send(user_email, "subscription_reciept.tmpl", data)
 		if err != nil {
@@ -646,32 +856,50 @@ func (app *application) createFailedTransactionHandler(paymentDetails *data.Paym
 		return err
 	}
 	app.logger.PrintInfo("failed transaction", map[string]string{"Status": paymentDetails.Status, "id": fmt.Sprintf("%d", failedTransactionID)})
+	app.notifyUser(paymentDetails.User_ID, data.InboxTypeBilling, fmt.Sprintf("A payment for your subscription failed: %s", message), uuid.Nil)
 	return nil
 }
 
 // createChallengedTransaction() handler creates a challenged transaction in our database
 // and sends a challange email to the user.
-func (app *application) createChallengedTransaction(subscription *data.RecurringSubscription, VerifyResponse *data.VerifyResponse) error {
+func (app *application) createChallengedTransaction(subscription *data.RecurringSubscription, transaction *data.ProviderTransaction) error {
 	err := app.models.Payments.CreateChallangedTransaction(subscription,
-		VerifyResponse.Data.Authorization_url,
-		*VerifyResponse.Data.Message,
-		VerifyResponse.Data.Reference)
+		transaction.Authorization_URL,
+		transaction.Message,
+		transaction.Reference)
 
 	if err != nil {
 		return err
 	}
+	// once the user authorizes the charge, the webhook renews the subscription through this
+	err = app.models.Payments.CreatePaymentIntent(&data.PaymentIntent{
+		Reference:              transaction.Reference,
+		Provider:               transaction.Provider,
+		User_ID:                subscription.User_ID,
+		Plan_ID:                subscription.Subscription.Plan_ID,
+		Amount:                 transaction.Amount,
+		Renews_Subscription_ID: subscription.Subscription.ID,
+	})
+	if err != nil {
+		return err
+	}
+	message := "Your subscription renewal needs you to authorize the payment, check your email for the link"
+	if subscription.Subscription.Is_Trial {
+		message = "Your free trial has ended, check your email for the link to pay for your subscription"
+	}
+	app.notifyUser(subscription.User_ID, data.InboxTypeBilling, message, uuid.Nil)
 	// send challange email to user notifying them of the challange
 	app.background(func() {
 		data := map[string]any{
 			"UserName":             subscription.User_Name,
-			"TransactionReference": VerifyResponse.Data.Reference,
+			"TransactionReference": transaction.Reference,
 			"PlanName":             subscription.Subscription.Plan_ID,
-			"AmountPaid":           subscription.Subscription.Price,
-			"Currency":             subscription.Currency,
+			"Amount":               subscription.Subscription.Price.Major(),
+			"Currency":             subscription.Subscription.Price.Currency,
 			"PaymentMethod":        "card",
 			"Date":                 app.formatDate(time.Now().UTC().String()),
-			"TransactionDate":      app.formatDate(VerifyResponse.Data.TransactionDate),
-			"GrandTotal":           VerifyResponse.Data.Amount,
+			"TransactionDate":      app.formatDate(transaction.Paid_At),
+			"GrandTotal":           data.NewMoney(transaction.Amount, transaction.Currency).Major(),
 		}
 		err = app.mailer.Send(subscription.User_Email, "challanged_transaction.tmpl", data)
 		if err != nil {
@@ -683,12 +911,12 @@ func (app *application) createChallengedTransaction(subscription *data.Recurring
 }
 
 // updateExpiredSubscriptionHandler() is a bulk job, that is, everytime it's called it will
-// update all expired subscriptions at once and not one by one.
+// expire all past due subscriptions whose grace period is over at once and not one by one.
 // It will get back a slice of all subscriptions that have been updated. We will just log
 // each of them out
 func (app *application) updateExpiredSubscriptionHandler() {
 	app.logger.PrintInfo("Updating expired subscriptions", nil)
-	expiredSubscriptions, err := app.models.Payments.UpdateSubscriptionStatusAfterExpiration()
+	expiredSubscriptions, err := app.models.Payments.UpdateSubscriptionStatusAfterExpiration(time.Now().Add(-app.config.billing.schedule.Grace_Period))
 	if err != nil {
 		app.logger.PrintError(err, nil)
 	}
@@ -696,6 +924,7 @@ func (app *application) updateExpiredSubscriptionHandler() {
 		app.logger.PrintInfo("Updated expired subscription", map[string]string{"Subscription ID": subscription.ID.String(),
 			"User ID":    fmt.Sprintf("%d", subscription.User_ID),
 			"Updated At": subscription.Updated_At.String()})
+		app.notifyUser(subscription.User_ID, data.InboxTypeBilling, "Your subscription has expired", uuid.Nil)
 	}
 }
 
@@ -0,0 +1,365 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/blue-davinci/aggregate/internal/data"
+	"github.com/blue-davinci/aggregate/internal/validator"
+	"github.com/google/uuid"
+)
+
+// previewPlanChangeHandler() shows what moving the user's subscription to the plan in
+// ?plan_id= would cost without changing anything.
+func (app *application) previewPlanChangeHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	planID := app.readInt(r.URL.Query(), "plan_id", 0, v)
+	if v.Check(planID > 0, "plan_id", "must be provided"); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	current, plan, ok := app.readPlanChange(w, r, int32(planID))
+	if !ok {
+		return
+	}
+	quote := data.QuotePlanChange(&current.Subscription, plan, time.Now().UTC())
+	err := app.writeJSON(w, http.StatusOK, envelope{"quote": quote}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// changePlanHandler() moves the user's subscription to another plan. Upgrades are charged
+// the new plan's price less the credit left on the current subscription through the card
+// it was paid with and start straight away for the new plan's full duration. Downgrades
+// are scheduled for when the current subscription ends and the renewal charges the new
+// plan. A downgrade already scheduled is replaced by the new change.
+func (app *application) changePlanHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		PlanID int32 `json:"plan_id"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	v := validator.New()
+	if v.Check(input.PlanID != 0, "plan_id", "must be provided"); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	current, plan, ok := app.readPlanChange(w, r, input.PlanID)
+	if !ok {
+		return
+	}
+	openChange, err := app.models.Payments.GetOpenPlanChangeBySubscriptionID(current.Subscription.ID)
+	if err != nil && !errors.Is(err, data.ErrPlanChangeNotFound) {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if openChange != nil {
+		switch {
+		case openChange.Status == data.PlanChangeStatusScheduled:
+			_, err = app.models.Payments.CancelScheduledPlanChange(current.User_ID)
+		// a charge left unauthorized for as long as a challenged renewal is given
+		// is abandoned and doesn't hold up a new change
+		case time.Since(openChange.Updated_At) > 24*time.Hour:
+			err = app.models.Payments.UpdatePlanChangeStatus(openChange, data.PlanChangeStatusFailed)
+		default:
+			v.AddError("plan_change", "a plan change is waiting for you to authorize its payment")
+			app.failedConstraintValidation(w, r, v.Errors)
+			return
+		}
+		if err != nil && !errors.Is(err, data.ErrPlanChangeNotFound) {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+	quote := data.QuotePlanChange(&current.Subscription, plan, time.Now().UTC())
+	change := &data.PlanChange{
+		User_ID:         current.User_ID,
+		Subscription_ID: current.Subscription.ID,
+		From_Plan_ID:    quote.From_Plan_ID,
+		To_Plan_ID:      quote.To_Plan_ID,
+		Change_Type:     quote.Change_Type,
+		Status:          data.PlanChangeStatusScheduled,
+		Credit:          quote.Credit,
+		Amount_Charged:  data.NewMoney(0, quote.Credit.Currency),
+		Effective_At:    quote.Effective_At,
+	}
+	// upgrades start out pending so a second request can't charge the card again
+	if quote.Change_Type == data.PlanChangeUpgrade {
+		change.Status = data.PlanChangeStatusPending
+	}
+	err = app.models.Payments.CreatePlanChange(change)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrPlanChangeExists):
+			v.AddError("plan_change", "a plan change is already in progress")
+			app.failedConstraintValidation(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if quote.Change_Type == data.PlanChangeDowngrade {
+		app.notifyUser(current.User_ID, data.InboxTypeBilling,
+			fmt.Sprintf("Your subscription moves to the %s plan on %s", plan.Name, quote.Effective_At.Format("Jan 2, 2006")), uuid.Nil)
+		err = app.writeJSON(w, http.StatusAccepted, envelope{"plan_change": change, "quote": quote}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	app.upgradePlan(w, r, current, plan, change, quote)
+}
+
+// upgradePlan() charges an upgrade and starts the subscription it pays for. A charge the
+// customer has to authorize is settled like a challenged renewal, by the verify handler
+// or the webhook, once they do.
+func (app *application) upgradePlan(w http.ResponseWriter, r *http.Request, current *data.RecurringSubscription, plan *data.Payment_Plan, change *data.PlanChange, quote *data.PlanChangeQuote) {
+	provider, ok := app.paymentProviders.Get(current.Provider)
+	if !ok {
+		app.failPlanChange(w, r, change, fmt.Errorf("%w: %s", data.ErrUnknownPaymentProvider, current.Provider))
+		return
+	}
+	// the charge isn't tied to the request, a client hanging up mustn't leave a card
+	// charged without the subscription it paid for
+	transaction, err := provider.ChargeAuthorization(context.Background(), &data.AuthorizationCharge{
+		Email:              current.User_Email,
+		Amount:             quote.Amount_Due.Amount,
+		Currency:           quote.Amount_Due.Currency,
+		Authorization_Code: current.Authorization_Code,
+	})
+	if err != nil {
+		app.failPlanChange(w, r, change, err)
+		return
+	}
+	if transaction.Status == data.TransactionStatusChallenged {
+		// the challenge is raised against the new plan for what is due now
+		upgrade := *current
+		upgrade.Subscription.Plan_ID = plan.ID
+		upgrade.Subscription.Price = quote.Amount_Due
+		err = app.createChallengedTransaction(&upgrade, transaction)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		err = app.models.Payments.SetPlanChangePending(change, transaction.Reference)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		err = app.writeJSON(w, http.StatusAccepted, envelope{"plan_change": change, "quote": quote, "authorization_url": transaction.Authorization_URL}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	paymentDetails := &data.Payment_Details{
+		ID:                 current.Subscription.ID,
+		User_ID:            current.User_ID,
+		Plan_ID:            plan.ID,
+		Start_Date:         time.Now().UTC(),
+		End_Date:           app.returnEndDate(plan.Duration, time.Now().UTC()),
+		Price:              quote.New_Price,
+		Authorization_Code: current.Authorization_Code,
+		TransactionID:      transaction.ID,
+		Payment_Method:     transaction.Channel,
+		Card_Last4:         transaction.Authorization.Last4,
+		Card_Exp_Month:     transaction.Authorization.ExpMonth,
+		Card_Exp_Year:      transaction.Authorization.ExpYear,
+		Card_Type:          transaction.Authorization.CardType,
+		Provider:           provider.Name(),
+		Provider_Reference: transaction.Payment_Reference,
+	}
+	if !transaction.Succeeded() {
+		err = app.models.Payments.UpdatePlanChangeStatus(change, data.PlanChangeStatusFailed)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		err = app.createFailedTransactionHandler(paymentDetails, transaction.Message, transaction.Reference, transaction.Gateway_Response)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		app.badRequestResponse(w, r, fmt.Errorf("%s: %s", data.ErrTransactionDeclined, transaction.Message))
+		return
+	}
+	lines := data.SubscriptionLineItems(plan.Name, paymentDetails.Price, quote.Amount_Due, app.invoiceAdjustment(change, nil))
+	err = app.createSubscriptionHandler(paymentDetails, lines, plan.Name, current.User_Name, current.User_Email, transaction.Paid_At)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.completePlanChange(change, paymentDetails, quote.Amount_Due)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusCreated, envelope{"plan_change": change, "quote": quote, "payment_details": paymentDetails}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// failPlanChange() marks an upgrade whose charge could not be made as failed so it
+// doesn't hold up the next one.
+func (app *application) failPlanChange(w http.ResponseWriter, r *http.Request, change *data.PlanChange, err error) {
+	if statusErr := app.models.Payments.UpdatePlanChangeStatus(change, data.PlanChangeStatusFailed); statusErr != nil {
+		app.logError(r, statusErr)
+	}
+	app.serverErrorResponse(w, r, err)
+}
+
+// cancelPlanChangeHandler() cancels the downgrade scheduled on the user's subscription
+func (app *application) cancelPlanChangeHandler(w http.ResponseWriter, r *http.Request) {
+	change, err := app.models.Payments.CancelScheduledPlanChange(app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrPlanChangeNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"plan_change": change}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getPlanChangesHandler() returns the user's plan change history, newest first
+func (app *application) getPlanChangesHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		data.Filters
+	}
+	v := validator.New()
+	qs := r.URL.Query()
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "id")
+	input.Filters.SortSafelist = []string{"id"}
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	changes, metadata, err := app.models.Payments.GetPlanChangesByUser(app.contextGetUser(r).ID, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"plan_changes": changes, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readPlanChange() loads the user's active subscription and the plan they want to move
+// it to. It writes the error response itself and reports false when the change can't
+// be made.
+func (app *application) readPlanChange(w http.ResponseWriter, r *http.Request, planID int32) (*data.RecurringSubscription, *data.Payment_Plan, bool) {
+	current, err := app.models.Payments.GetSubscriptionForPlanChange(app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrSubscriptionNotFound):
+			v := validator.New()
+			v.AddError("subscription", "you need an active subscription to change plans")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, nil, false
+	}
+	plan, err := app.models.Payments.GetPaymentPlanByID(planID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrPaymentPlanNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, nil, false
+	}
+	v := validator.New()
+	if data.ValidatePlanChange(v, &current.Subscription, plan); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return nil, nil, false
+	}
+	return current, plan, true
+}
+
+// planChangeForReference() returns the plan change waiting on the charge behind a
+// reference, nil when the charge isn't for one.
+func (app *application) planChangeForReference(reference string) (*data.PlanChange, error) {
+	change, err := app.models.Payments.GetPendingPlanChangeByReference(reference)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrPlanChangeNotFound):
+			return nil, nil
+		default:
+			return nil, err
+		}
+	}
+	return change, nil
+}
+
+// applyScheduledPlanChange() moves a subscription that is being renewed onto the plan a
+// downgrade was scheduled for. It returns the change it applied, nil when there is none.
+// A plan that was retired or is no longer sold in the subscription's currency fails the
+// change and the subscription renews as it was.
+func (app *application) applyScheduledPlanChange(subscription *data.RecurringSubscription) (*data.PlanChange, error) {
+	change, err := app.models.Payments.GetOpenPlanChangeBySubscriptionID(subscription.Subscription.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrPlanChangeNotFound):
+			return nil, nil
+		default:
+			return nil, err
+		}
+	}
+	if change.Status != data.PlanChangeStatusScheduled {
+		return nil, nil
+	}
+	plan, err := app.models.Payments.GetPaymentPlanByID(change.To_Plan_ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrPaymentPlanNotFound):
+			return nil, app.models.Payments.UpdatePlanChangeStatus(change, data.PlanChangeStatusFailed)
+		default:
+			return nil, err
+		}
+	}
+	// the renewal stays in the subscription's currency
+	price, ok := plan.PriceIn(subscription.Subscription.Price.Currency)
+	if !ok {
+		return nil, app.models.Payments.UpdatePlanChangeStatus(change, data.PlanChangeStatusFailed)
+	}
+	subscription.Subscription.Plan_ID = plan.ID
+	subscription.Subscription.Price = price
+	return change, nil
+}
+
+// completePlanChange() records the subscription a plan change started along with what
+// was charged for it. The subscription it replaces is marked as renewed so it is
+// neither charged again nor counted as the user's current one.
+func (app *application) completePlanChange(change *data.PlanChange, paymentDetails *data.Payment_Details, amountCharged data.Money) error {
+	err := app.models.Payments.CompletePlanChange(change, paymentDetails.ID, amountCharged)
+	if err != nil && !errors.Is(err, data.ErrPlanChangeNotFound) {
+		return err
+	}
+	err = app.models.Payments.UpdateSubscriptionStatus(change.Subscription_ID, data.PaymentStatusRenewed, change.User_ID)
+	if err != nil {
+		return err
+	}
+	app.logger.PrintInfo("completed plan change", map[string]string{
+		"plan change id":  fmt.Sprintf("%d", change.ID),
+		"subscription id": paymentDetails.ID.String(),
+	})
+	return nil
+}
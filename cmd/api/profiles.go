@@ -0,0 +1,285 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/blue-davinci/aggregate/internal/data"
+	"github.com/blue-davinci/aggregate/internal/validator"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// readProfileFilters() reads the paging shared by the profile and follow listings
+func (app *application) readProfileFilters(r *http.Request, v *validator.Validator) data.Filters {
+	qs := r.URL.Query()
+	return data.Filters{
+		Page:         app.readInt(qs, "page", 1, v),
+		PageSize:     app.readInt(qs, "page_size", 20, v),
+		Sort:         app.readString(qs, "sort", "created_at"),
+		SortSafelist: []string{"created_at"},
+	}
+}
+
+// getUserProfileHandler returns a user's public profile along with the feeds they created
+// and, when they chose to share them, the posts they favorited.
+// eg: GET /v1/users/{handle}?page=1&page_size=20
+// Anonymous visitors may view profiles. Private profiles only show their feeds and
+// favorites to the owner and to accepted followers.
+func (app *application) getUserProfileHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	filters := app.readProfileFilters(r, v)
+	if data.ValidateFilters(v, filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	viewer := app.contextGetUser(r)
+	profile, err := app.models.Profiles.GetProfile(chi.URLParam(r, "handle"), viewer.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrProfileNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	response := envelope{"profile": profile}
+	if !profile.VisibleTo(viewer.ID) {
+		err = app.writeJSON(w, http.StatusOK, response, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	feeds, feedsMetadata, err := app.models.Profiles.GetProfileFeeds(profile.ID, filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	response["feeds"] = feeds
+	response["feeds_metadata"] = feedsMetadata
+	if profile.Share_Favorites || profile.ID == viewer.ID {
+		favorites, favoritesMetadata, err := app.models.Profiles.GetProfileFavorites(profile.ID, filters)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		response["favorites"] = favorites
+		response["favorites_metadata"] = favoritesMetadata
+	}
+	err = app.writeJSON(w, http.StatusOK, response, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// followUserHandler follows the user with the given handle. Following a private profile
+// sends them a follow request instead, which they have to accept.
+// eg: POST /v1/users/{handle}/follow
+func (app *application) followUserHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	profile, err := app.models.Profiles.GetProfile(chi.URLParam(r, "handle"), user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrProfileNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	status, err := app.models.Profiles.FollowUser(user.ID, profile)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrCannotFollowSelf):
+			app.badRequestResponse(w, r, err)
+		case errors.Is(err, data.ErrAlreadyFollowing):
+			app.failedConstraintValidation(w, r, map[string]string{"follow": "you already follow this user or have a pending request"})
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	message := fmt.Sprintf("@%s started following you", user.Handle)
+	if status == data.FollowStatusPending {
+		message = fmt.Sprintf("@%s requested to follow you", user.Handle)
+	}
+	app.notifyUser(profile.ID, data.InboxTypeFollow, message, uuid.Nil)
+	err = app.writeJSON(w, http.StatusCreated, envelope{"follow_status": status}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// unfollowUserHandler stops following a user or withdraws a pending follow request
+// eg: DELETE /v1/users/{handle}/follow
+func (app *application) unfollowUserHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	profile, err := app.models.Profiles.GetProfile(chi.URLParam(r, "handle"), user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrProfileNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.models.Profiles.UnfollowUser(user.ID, profile.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrFollowNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "unfollowed successfully"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getFollowRequestsHandler lists the pending requests to follow the current user
+// eg: GET /v1/users/follows/requests?page=1&page_size=20
+func (app *application) getFollowRequestsHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	filters := app.readProfileFilters(r, v)
+	if data.ValidateFilters(v, filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	requests, metadata, err := app.models.Profiles.GetFollowRequests(app.contextGetUser(r).ID, filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"follow_requests": requests, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// acceptFollowRequestHandler accepts a pending follow request and lets the requester know
+// eg: PUT /v1/users/follows/requests/{userID}
+func (app *application) acceptFollowRequestHandler(w http.ResponseWriter, r *http.Request) {
+	followerID, err := app.readIDIntParam(r, "userID")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	user := app.contextGetUser(r)
+	err = app.models.Profiles.AcceptFollowRequest(user.ID, followerID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrFollowNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	app.notifyUser(followerID, data.InboxTypeFollow, fmt.Sprintf("@%s accepted your follow request", user.Handle), uuid.Nil)
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "follow request accepted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// removeFollowerHandler removes one of the current user's followers, it also declines
+// a pending follow request.
+// eg: DELETE /v1/users/followers/{userID}
+func (app *application) removeFollowerHandler(w http.ResponseWriter, r *http.Request) {
+	followerID, err := app.readIDIntParam(r, "userID")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	err = app.models.Profiles.RemoveFollower(app.contextGetUser(r).ID, followerID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrFollowNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "follower removed successfully"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getProfileSettingsHandler returns the current user's profile privacy settings
+// eg: GET /v1/users/profile/settings
+func (app *application) getProfileSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	settings, err := app.models.Profiles.GetProfileSettings(app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"settings": settings}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateProfileSettingsHandler updates the current user's profile privacy settings, only
+// the fields that are sent are changed.
+// eg: PUT /v1/users/profile/settings {"is_private": true, "share_favorites": false}
+func (app *application) updateProfileSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Is_Private      *bool `json:"is_private"`
+		Share_Favorites *bool `json:"share_favorites"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	settings, err := app.models.Profiles.GetProfileSettings(app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if input.Is_Private != nil {
+		settings.Is_Private = *input.Is_Private
+	}
+	if input.Share_Favorites != nil {
+		settings.Share_Favorites = *input.Share_Favorites
+	}
+	err = app.models.Profiles.UpdateProfileSettings(settings)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"settings": settings}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getFollowActivityHandler returns the new feeds and comments of the users the current
+// user follows, newest first.
+// eg: GET /v1/users/activity?page=1&page_size=20
+func (app *application) getFollowActivityHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	filters := app.readProfileFilters(r, v)
+	if data.ValidateFilters(v, filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	activity, metadata, err := app.models.Profiles.GetFollowActivity(app.contextGetUser(r).ID, filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"activity": activity, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"github.com/blue-davinci/aggregate/internal/data"
+)
+
+// newPaymentProviders() sets up the payment providers from our config. Paystack is always
+// available while stripe is only added when its secret key is set, a default or currency
+// mapped to a provider that isn't set up fails the start.
+func newPaymentProviders(cfg config) (*data.PaymentProviders, error) {
+	providers := []data.PaymentProvider{
+		data.NewPaystackProvider(data.PaystackConfig{
+			SecretKey:              cfg.paystack.secretkey,
+			InitializationURL:      cfg.paystack.initializationurl,
+			VerificationURL:        cfg.paystack.verificationurl,
+			ChargeAuthorizationURL: cfg.paystack.chargeauthorizationurl,
+			RefundURL:              cfg.paystack.refundurl,
+		}, cfg.payments.timeout),
+	}
+	if cfg.stripe.secretkey != "" {
+		providers = append(providers, data.NewStripeProvider(data.StripeConfig{
+			SecretKey:     cfg.stripe.secretkey,
+			WebhookSecret: cfg.stripe.webhooksecret,
+			APIURL:        cfg.stripe.apiurl,
+			Currency:      cfg.stripe.currency,
+		}, cfg.payments.timeout))
+	}
+	return data.NewPaymentProviders(cfg.payments.provider, cfg.payments.currencyproviders, providers...)
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/blue-davinci/aggregate/internal/data"
+	"github.com/blue-davinci/aggregate/internal/validator"
+	"github.com/google/uuid"
+)
+
+// adminCreateRefundHandler() refunds all or part of what a subscription was paid through
+// the provider it was paid with. Leaving out the amount refunds whatever is left and
+// end_access cancels the subscription now instead of letting it run to its end date.
+// The refund is recorded before the provider is asked so a failed one is kept too. It is
+// only marked failed when the provider turns it down, one the provider never answered
+// may have been made and is left pending to be checked with the provider.
+func (app *application) adminCreateRefundHandler(w http.ResponseWriter, r *http.Request) {
+	subscriptionID, err := app.readIDParam(r, "subscriptionID")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	var input struct {
+		Amount    *int64 `json:"amount"`
+		Reason    string `json:"reason"`
+		EndAccess bool   `json:"end_access"`
+	}
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	subscription, err := app.models.Refunds.GetSubscriptionForRefund(subscriptionID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRefundSubscriptionNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	amount := subscription.Refundable()
+	if input.Amount != nil {
+		amount = data.NewMoney(*input.Amount, subscription.Amount_Paid.Currency)
+	}
+	v := validator.New()
+	if data.ValidateRefund(v, subscription, amount, input.Reason); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	provider, ok := app.paymentProviders.Get(subscription.Provider)
+	if !ok {
+		app.serverErrorResponse(w, r, fmt.Errorf("%w: %s", data.ErrUnknownPaymentProvider, subscription.Provider))
+		return
+	}
+	refund := &data.Refund{
+		Amount:       amount,
+		Reason:       input.Reason,
+		Ended_Access: input.EndAccess,
+		Refunded_By:  app.contextGetUser(r).ID,
+	}
+	err = app.models.Refunds.CreateRefund(subscription, refund)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRefundExceedsPayment):
+			v.AddError("amount", "must not be more than what is left of the payment to refund")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	// the refund is recorded already, it goes through even if the admin goes away
+	ctx, cancel := context.WithTimeout(context.Background(), app.config.payments.timeout)
+	defer cancel()
+	providerRefund, err := provider.Refund(ctx, &data.RefundRequest{
+		Payment_Reference: subscription.PaymentReference(),
+		Amount:            amount.Amount,
+	})
+	switch {
+	case err == nil:
+		refund.Status, refund.Provider_Refund_ID = data.RefundStatusFor(providerRefund.Status), providerRefund.ID
+		if refund.Status == data.RefundStatusFailed {
+			refund.Failure_Reason = fmt.Sprintf("refund %s was %s", providerRefund.ID, providerRefund.Status)
+		}
+	case errors.Is(err, data.ErrPaymentProviderRejected):
+		refund.Status, refund.Failure_Reason = data.RefundStatusFailed, err.Error()
+	default:
+		// without an answer we can't tell whether the money went back, the refund stays
+		// pending and keeps counting against the payment until it is checked
+		app.logger.PrintError(err, map[string]string{"Refund ID": fmt.Sprintf("%d", refund.ID)})
+		app.errorResponse(w, r, http.StatusBadGateway, fmt.Sprintf("the payment provider did not answer, refund %d was left pending: %v", refund.ID, err))
+		return
+	}
+	if refund.Status == data.RefundStatusFailed {
+		// a failed refund gives nothing back, it no longer counts against the payment
+		if updateErr := app.models.Refunds.UpdateRefundStatus(refund); updateErr != nil {
+			app.logger.PrintError(updateErr, map[string]string{"Refund ID": fmt.Sprintf("%d", refund.ID)})
+		}
+		app.errorResponse(w, r, http.StatusBadGateway, fmt.Sprintf("the payment provider did not make the refund: %s", refund.Failure_Reason))
+		return
+	}
+	err = app.models.Refunds.UpdateRefundStatus(refund)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if subscription.Invoice_ID != 0 {
+		err = app.models.Refunds.UpdateInvoiceRefundStatus(subscription.Invoice_ID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+	if input.EndAccess {
+		endDate, err := app.models.Refunds.EndSubscriptionAccess(subscription.ID)
+		switch {
+		case err == nil:
+			subscription.End_Date = endDate
+		// subscriptions that already ended have no access left to end
+		case !errors.Is(err, data.ErrRefundSubscriptionNotFound):
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		// an ended subscription isn't renewed so a downgrade scheduled on it never happens
+		_, err = app.models.Payments.CancelScheduledPlanChange(subscription.User_ID)
+		if err != nil && !errors.Is(err, data.ErrPlanChangeNotFound) {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+	message := fmt.Sprintf("We refunded %s of your %s subscription", amount, subscription.Plan_Name)
+	if input.EndAccess {
+		message += " and ended it"
+	}
+	app.notifyUser(subscription.User_ID, data.InboxTypeBilling, message, uuid.Nil)
+	app.sendRefundEmail(subscription, refund)
+	err = app.writeJSON(w, http.StatusCreated, envelope{"refund": refund}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// adminGetSubscriptionRefundsHandler() returns a subscription with what was paid and
+// refunded for it and the refunds made, the latest first.
+func (app *application) adminGetSubscriptionRefundsHandler(w http.ResponseWriter, r *http.Request) {
+	subscriptionID, err := app.readIDParam(r, "subscriptionID")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	subscription, err := app.models.Refunds.GetSubscriptionForRefund(subscriptionID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRefundSubscriptionNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	refunds, err := app.models.Refunds.GetRefundsBySubscriptionID(subscriptionID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"subscription": subscription, "refunds": refunds}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// adminGetRefundsHandler() lists every refund, the latest first. ?status= narrows it down
+// to pending, succeeded or failed refunds.
+func (app *application) adminGetRefundsHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Status string
+		data.Filters
+	}
+	v := validator.New()
+	qs := r.URL.Query()
+	input.Status = app.readString(qs, "status", "")
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "-created_at")
+	input.Filters.SortSafelist = []string{"-created_at"}
+	v.Check(input.Status == "" || validator.PermittedValue(input.Status, data.RefundStatusPending, data.RefundStatusSucceeded, data.RefundStatusFailed),
+		"status", "must be one of pending, succeeded or failed")
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	refunds, metadata, err := app.models.Refunds.GetAllRefunds(input.Status, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"refunds": refunds, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// sendRefundEmail() confirms a refund to the user it was made to
+func (app *application) sendRefundEmail(subscription *data.RefundableSubscription, refund *data.Refund) {
+	pending := refund.Status == data.RefundStatusPending
+	app.background(func() {
+		data := map[string]any{
+			"UserName":    subscription.User_Name,
+			"PlanName":    subscription.Plan_Name,
+			"Amount":      refund.Amount.Major(),
+			"Currency":    refund.Amount.Currency,
+			"Reason":      refund.Reason,
+			"Pending":     pending,
+			"EndedAccess": refund.Ended_Access,
+			"EndDate":     subscription.End_Date.Format("Jan 2, 2006"),
+		}
+		err := app.mailer.Send(subscription.User_Email, "refund_confirmation.tmpl", data)
+		if err != nil {
+			app.logger.PrintError(err, nil)
+		}
+	})
+}
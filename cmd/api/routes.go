@@ -4,6 +4,7 @@ import (
 	"expvar"
 	"net/http"
 
+	"github.com/blue-davinci/aggregate/internal/data"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/cors"
 	"github.com/justinas/alice"
@@ -28,9 +29,6 @@ func (app *application) routes() http.Handler {
 	dynamicMiddleware := alice.New(app.requireAuthenticatedUser, app.requireActivatedUser)
 	// Permission Middleware, this will apply to specific routes that are capped by the permissions
 	adminPermissionMiddleware := alice.New(app.requirePermission("admin:read"))
-	// Limitations Middleware, this will apply to specific routes that are capped by the limitations
-	// and will sit behind the dynamic middleware.
-	limitationsMiddleware := alice.New(app.limitations)
 	// Apply the global middleware to the router
 	router.Use(globalMiddleware)
 	// Make our categorized routes
@@ -46,10 +44,11 @@ func (app *application) routes() http.Handler {
 	v1Router.Mount("/top", app.statisticRoutes())
 
 	v1Router.Mount("/users", app.userRoutes(&dynamicMiddleware))
-	v1Router.Mount("/feeds", app.feedRoutes(&dynamicMiddleware, &limitationsMiddleware))
+	v1Router.Mount("/feeds", app.feedRoutes(&dynamicMiddleware))
 	v1Router.Mount("/search-options", app.searchOptionsRoutes(&dynamicMiddleware))
 	v1Router.Mount("/api", app.apiKeyRoutes())
 	v1Router.Mount("/subscriptions", app.subscriptionRoutes(&dynamicMiddleware))
+	v1Router.With(dynamicMiddleware.Then).Mount("/organizations", app.organizationRoutes())
 
 	// Mount to our base version
 	router.Mount("/v1", v1Router)
@@ -67,6 +66,12 @@ func (app *application) generalRoutes() chi.Router {
 	})
 	generalRoutes.Get("/health", app.healthcheckHandler)
 	generalRoutes.Get("/notifications", app.getUserNotificationsHandler)
+	generalRoutes.Get("/notifications/stream", app.streamNotificationsHandler)
+	// the per user notification inbox
+	generalRoutes.Get("/notifications/inbox", app.getInboxHandler)
+	generalRoutes.Post("/notifications/inbox/read", app.markInboxReadHandler)
+	generalRoutes.Patch("/notifications/inbox/{notificationID}", app.updateInboxNotificationHandler)
+	generalRoutes.Delete("/notifications/inbox/{notificationID}", app.dismissInboxNotificationHandler)
 	return generalRoutes
 }
 
@@ -82,6 +87,23 @@ func (app *application) userRoutes(dynamicMiddleware *alice.Chain) chi.Router {
 	userRoutes.Put("/password", app.updateUserPasswordHandler)
 	// update user info. This will be a dynamically protected route.
 	userRoutes.With(dynamicMiddleware.Then).Patch("/", app.updateUserInformationHandler)
+	// email digests, the unsubscribe link is public and authorized by its token
+	userRoutes.With(dynamicMiddleware.Then).Get("/digest", app.getDigestPreferenceHandler)
+	userRoutes.With(dynamicMiddleware.Then).Put("/digest", app.updateDigestPreferenceHandler)
+	userRoutes.Get("/digest/unsubscribe", app.confirmUnsubscribeDigestHandler)
+	userRoutes.Post("/digest/unsubscribe", app.unsubscribeDigestHandler)
+	// follows, profile privacy and the activity of followed users
+	userRoutes.With(dynamicMiddleware.Then).Get("/activity", app.getFollowActivityHandler)
+	userRoutes.With(dynamicMiddleware.Then).Get("/follows/requests", app.getFollowRequestsHandler)
+	userRoutes.With(dynamicMiddleware.Then).Put("/follows/requests/{userID}", app.acceptFollowRequestHandler)
+	userRoutes.With(dynamicMiddleware.Then).Delete("/followers/{userID}", app.removeFollowerHandler)
+	userRoutes.With(dynamicMiddleware.Then).Get("/profile/settings", app.getProfileSettingsHandler)
+	userRoutes.With(dynamicMiddleware.Then).Put("/profile/settings", app.updateProfileSettingsHandler)
+	// public profiles are open to anonymous visitors, following needs an account. Every
+	// static path above has to be in the reserved handles of internal/data/users.go
+	userRoutes.Get("/{handle}", app.getUserProfileHandler)
+	userRoutes.With(dynamicMiddleware.Then).Post("/{handle}/follow", app.followUserHandler)
+	userRoutes.With(dynamicMiddleware.Then).Delete("/{handle}/follow", app.unfollowUserHandler)
 	return userRoutes
 }
 
@@ -94,11 +116,12 @@ func (app *application) announcementRoutes() chi.Router {
 
 // feedRoutes() provides a router for the /feeds API endpoint.
 // We pass the pointer to the dynamic middleware here because some
-// Of the routes require verified and activated users
-func (app *application) feedRoutes(dynamicMiddleware, limitationsMiddleware *alice.Chain) chi.Router {
+// Of the routes require verified and activated users. Routes capped by the user's plan
+// add the limitations or requireFeature middleware behind it.
+func (app *application) feedRoutes(dynamicMiddleware *alice.Chain) chi.Router {
 	feedRoutes := chi.NewRouter()
 	//authenticated/activated endpoints
-	feedRoutes.With(dynamicMiddleware.Then).With(limitationsMiddleware.Then).Post("/", app.createFeedHandler)
+	feedRoutes.With(dynamicMiddleware.Then).With(app.limitations(data.EntitlementFeedsCreated)).Post("/", app.createFeedHandler)
 	// routes to get favorited posts, favorite and unfavorite posts as well.
 	feedRoutes.With(dynamicMiddleware.Then).Get("/favorites", app.GetRSSFavoritePostsForUserHandler)
 	feedRoutes.With(dynamicMiddleware.Then).Post("/favorites", app.CreateRSSFavoritePostHandler)
@@ -108,16 +131,35 @@ func (app *application) feedRoutes(dynamicMiddleware, limitationsMiddleware *ali
 
 	feedRoutes.With(dynamicMiddleware.Then).Get("/follow", app.getAllFeedsFollowedHandler)
 	feedRoutes.With(dynamicMiddleware.Then).Get("/follow/list", app.getListOfFollowedFeedsHandler)
+	// saved searches, these act as virtual feeds for the user
+	feedRoutes.With(dynamicMiddleware.Then).Get("/saved-searches", app.getSavedSearchesHandler)
+	feedRoutes.With(dynamicMiddleware.Then).With(app.limitations(data.EntitlementSavedSearches)).Post("/saved-searches", app.createSavedSearchHandler)
+	feedRoutes.With(dynamicMiddleware.Then).Patch("/saved-searches/{searchID}", app.updateSavedSearchHandler)
+	feedRoutes.With(dynamicMiddleware.Then).Delete("/saved-searches/{searchID}", app.deleteSavedSearchHandler)
+	feedRoutes.With(dynamicMiddleware.Then).Get("/saved-searches/{searchID}/posts", app.getSavedSearchPostsHandler)
+	// outbound feeds, secret-token URLs that render a user's listings as RSS, Atom or JSON Feed
+	feedRoutes.With(dynamicMiddleware.Then).Get("/outbound", app.getOutboundFeedsHandler)
+	feedRoutes.With(dynamicMiddleware.Then).With(app.requireFeature(data.EntitlementOutboundFeeds)).Post("/outbound", app.createOutboundFeedHandler)
+	feedRoutes.With(dynamicMiddleware.Then).Delete("/outbound/{outboundID}", app.deleteOutboundFeedHandler)
 
-	feedRoutes.With(dynamicMiddleware.Then).Post("/follow", app.createFeedFollowHandler)
+	feedRoutes.With(dynamicMiddleware.Then).With(app.limitations(data.EntitlementFeedsFollowed)).Post("/follow", app.createFeedFollowHandler)
 	feedRoutes.With(dynamicMiddleware.Then).Delete("/follow/{feedID}", app.deleteFeedFollowHandler)
 	feedRoutes.With(dynamicMiddleware.Then).Get("/follow/posts", app.getFollowedRssPostsForUserHandler)
 	feedRoutes.With(dynamicMiddleware.Then).Get("/follow/posts/{postID}", app.getRSSFeedByIDHandler)
-	feedRoutes.With(dynamicMiddleware.Then).With(limitationsMiddleware.Then).Post("/follow/posts/comments", app.createCommentHandler)
+	// sharing posts with other users or through public links
+	feedRoutes.With(dynamicMiddleware.Then).Post("/follow/posts/{postID}/share", app.sharePostHandler)
+	feedRoutes.With(dynamicMiddleware.Then).Get("/follow/posts/{postID}/shares", app.getPostShareCountHandler)
+	feedRoutes.With(dynamicMiddleware.Then).Get("/shares/links", app.getPostShareLinksHandler)
+	feedRoutes.With(dynamicMiddleware.Then).Delete("/shares/links/{shareID}", app.deletePostShareLinkHandler)
+	feedRoutes.With(dynamicMiddleware.Then).With(app.limitations(data.EntitlementCommentsPerDay)).Post("/follow/posts/comments", app.createCommentHandler)
 
 	feedRoutes.With(dynamicMiddleware.Then).Get("/follow/posts/comments/{postID}", app.getCommentsForPostHandler)
 	feedRoutes.With(dynamicMiddleware.Then).Patch("/follow/posts/comments", app.updateUserCommentHandler)
 	feedRoutes.With(dynamicMiddleware.Then).Delete("/follow/posts/comments/{commentID}", app.deleteCommentHandler)
+	feedRoutes.With(dynamicMiddleware.Then).Put("/follow/posts/comments/{commentID}/reactions", app.reactToCommentHandler)
+	feedRoutes.With(dynamicMiddleware.Then).Delete("/follow/posts/comments/{commentID}/reactions", app.removeCommentReactionHandler)
+	feedRoutes.With(dynamicMiddleware.Then).Post("/follow/posts/comments/{commentID}/reports", app.reportCommentHandler)
+	feedRoutes.With(dynamicMiddleware.Then).Get("/follow/posts/comments/{commentID}/revisions", app.getCommentRevisionsHandler)
 
 	feedRoutes.With(dynamicMiddleware.Then).Delete("/follow/posts/comments/notifications/{postID}", app.deleteReadCommentNotificationHandler)
 
@@ -128,6 +170,9 @@ func (app *application) feedRoutes(dynamicMiddleware, limitationsMiddleware *ali
 	feedRoutes.Get("/", app.getAllFeedsHandler)
 	feedRoutes.Get("/{feedID}", app.getFeedWithStatsHandler)
 	feedRoutes.Get("/sample-posts/{feedID}", app.getRandomRSSPostsHandler)
+	feedRoutes.Get("/shared/{token}", app.getSharedPostHandler)
+	// the token authenticates these so that feed readers can poll them
+	feedRoutes.Get("/outbound/{token}/{format}", app.renderOutboundFeedHandler)
 
 	return feedRoutes
 }
@@ -172,12 +217,54 @@ func (app *application) subscriptionRoutes(dynamicMiddleware *alice.Chain) chi.R
 	subscriptionRoutes.With(dynamicMiddleware.Then).Post("/initialize", app.initializeTransactionHandler)
 	subscriptionRoutes.With(dynamicMiddleware.Then).Post("/verify", app.verifyTransactionHandler)
 	subscriptionRoutes.With(dynamicMiddleware.Then).Get("/challenged", app.getPendingChallengedTransactionsByUser)
+	subscriptionRoutes.With(dynamicMiddleware.Then).Get("/entitlements", app.getEntitlementsHandler)
+	subscriptionRoutes.With(dynamicMiddleware.Then).Get("/plan-change/preview", app.previewPlanChangeHandler)
+	subscriptionRoutes.With(dynamicMiddleware.Then).Post("/plan-change", app.changePlanHandler)
+	subscriptionRoutes.With(dynamicMiddleware.Then).Delete("/plan-change", app.cancelPlanChangeHandler)
+	subscriptionRoutes.With(dynamicMiddleware.Then).Get("/plan-changes", app.getPlanChangesHandler)
 	subscriptionRoutes.With(dynamicMiddleware.Then).Patch("/challenged", app.updateChallengedTransactionStatus)
+	subscriptionRoutes.With(dynamicMiddleware.Then).Get("/invoices", app.getInvoicesHandler)
+	subscriptionRoutes.With(dynamicMiddleware.Then).Get("/invoices/{invoiceID}", app.getInvoiceHandler)
+	subscriptionRoutes.With(dynamicMiddleware.Then).Get("/invoices/{invoiceID}.pdf", app.getInvoicePDFHandler)
+	subscriptionRoutes.With(dynamicMiddleware.Then).Get("/billing-details", app.getBillingDetailsHandler)
+	subscriptionRoutes.With(dynamicMiddleware.Then).Put("/billing-details", app.updateBillingDetailsHandler)
 	// plans is free to everyone
 	subscriptionRoutes.Get("/plans", app.getPaymentPlansHandler)
+	// the payment providers call the webhooks themselves, they are authenticated by their
+	// signatures. The bare /webhook is paystack's and predates the other providers.
+	subscriptionRoutes.Post("/webhook", app.paymentWebhookHandler)
+	subscriptionRoutes.Post("/webhook/{provider}", app.paymentWebhookHandler)
 	return subscriptionRoutes
 }
 
+// organizationRoutes() provides a router for the /organizations API endpoint. Every
+// route is about the organization the user belongs to, so none of them take its id.
+func (app *application) organizationRoutes() chi.Router {
+	organizationRoutes := chi.NewRouter()
+	organizationRoutes.Get("/", app.getOrganizationHandler)
+	organizationRoutes.Post("/", app.createOrganizationHandler)
+	organizationRoutes.Patch("/", app.updateOrganizationHandler)
+	organizationRoutes.Delete("/", app.deleteOrganizationHandler)
+	// members and invitations
+	organizationRoutes.Get("/members", app.getOrganizationMembersHandler)
+	organizationRoutes.Patch("/members/{userID}", app.updateOrganizationMemberHandler)
+	organizationRoutes.Delete("/members/{userID}", app.deleteOrganizationMemberHandler)
+	organizationRoutes.Get("/invitations", app.getOrganizationInvitationsHandler)
+	organizationRoutes.Post("/invitations", app.createOrganizationInvitationHandler)
+	organizationRoutes.Post("/invitations/accept", app.acceptOrganizationInvitationHandler)
+	organizationRoutes.Delete("/invitations/{invitationID}", app.deleteOrganizationInvitationHandler)
+	// shared feeds and their folders
+	organizationRoutes.Get("/folders", app.getOrganizationFoldersHandler)
+	organizationRoutes.Post("/folders", app.createOrganizationFolderHandler)
+	organizationRoutes.Patch("/folders/{folderID}", app.updateOrganizationFolderHandler)
+	organizationRoutes.Delete("/folders/{folderID}", app.deleteOrganizationFolderHandler)
+	organizationRoutes.Get("/feeds", app.getOrganizationFeedsHandler)
+	organizationRoutes.Post("/feeds", app.shareOrganizationFeedHandler)
+	organizationRoutes.Patch("/feeds/{feedID}", app.moveOrganizationFeedHandler)
+	organizationRoutes.Delete("/feeds/{feedID}", app.unshareOrganizationFeedHandler)
+	return organizationRoutes
+}
+
 // adminRoutes() provides a router for the /admin API endpoint.
 // It is responsible for the API's general administration
 func (app *application) adminRoutes() chi.Router {
@@ -188,6 +275,15 @@ func (app *application) adminRoutes() chi.Router {
 	adminRoutes.Delete("/announcements/{announcementID}", app.adminDeleteAnnouncmentByIDHandler)
 	// users
 	adminRoutes.Get("/users", app.adminGetAllUsersHandler)
+	adminRoutes.Get("/users/restrictions", app.adminGetCommentRestrictionsHandler)
+	adminRoutes.Put("/users/{userID}/restriction", app.adminRestrictCommenterHandler)
+	adminRoutes.Delete("/users/{userID}/restriction", app.adminLiftCommentRestrictionHandler)
+	// comment moderation
+	adminRoutes.Get("/comments/reports", app.adminGetModerationQueueHandler)
+	adminRoutes.Get("/comments/pending", app.adminGetPendingCommentsHandler)
+	adminRoutes.Get("/comments/{commentID}/moderation", app.adminGetCommentModerationHandler)
+	adminRoutes.Get("/comments/{commentID}/revisions", app.adminGetCommentRevisionsHandler)
+	adminRoutes.Patch("/comments/{commentID}", app.adminModerateCommentHandler)
 	// feeds
 	adminRoutes.Get("/feeds", app.adminGetAllFeedsWithStatistics)
 	adminRoutes.Get("/feeds/approvals", app.adminGetFeedsPendingApprovalHandler)
@@ -208,10 +304,29 @@ func (app *application) adminRoutes() chi.Router {
 	adminRoutes.Get("/payment-plans", app.adminGetPaymentPlansHandler)
 	adminRoutes.Post("/payment-plans", app.adminCreatePaymentPlansHandler)
 	adminRoutes.Patch("/payment-plans/{planID}", app.adminUpdatePaymentPlanHandler)
+	adminRoutes.Get("/payment-plans/{planID}/entitlements", app.adminGetPlanEntitlementsHandler)
+	adminRoutes.Put("/payment-plans/{planID}/entitlements", app.adminSetPlanEntitlementsHandler)
 	// subscriptions
 	adminRoutes.Get("/subscriptions", app.adminGetAllSubscriptionsHandler)
 	adminRoutes.Get("/subscriptions/challenged/{subscriptionID}", app.adminGetChallaengedTransactionsBySubscriptionIDHandler)
 	adminRoutes.Get("/subscriptions/reports", app.adminGetSubscriptionStatsReports)
+	adminRoutes.Get("/subscriptions/{subscriptionID}/refunds", app.adminGetSubscriptionRefundsHandler)
+	adminRoutes.Post("/subscriptions/{subscriptionID}/refunds", app.adminCreateRefundHandler)
+	// refunds
+	adminRoutes.Get("/refunds", app.adminGetRefundsHandler)
+	// billing runs
+	adminRoutes.Get("/billing/runs", app.adminGetBillingRunsHandler)
+	adminRoutes.Post("/billing/runs", app.adminStartBillingRunHandler)
+	adminRoutes.Get("/billing/runs/{runID}", app.adminGetBillingRunHandler)
+	// coupons
+	adminRoutes.Get("/coupons", app.adminGetAllCouponsHandler)
+	adminRoutes.Post("/coupons", app.adminCreateCouponHandler)
+	adminRoutes.Get("/coupons/{couponID}", app.adminGetCouponHandler)
+	adminRoutes.Patch("/coupons/{couponID}", app.adminUpdateCouponHandler)
+	// invoices
+	adminRoutes.Get("/invoices", app.adminGetInvoicesHandler)
+	adminRoutes.Get("/invoices/{invoiceID}", app.adminGetInvoiceHandler)
+	adminRoutes.Get("/invoices/{invoiceID}.pdf", app.adminGetInvoicePDFHandler)
 	// errors
 	adminRoutes.Get("/errors", app.adminGetAllScraperErrorLogs)
 	adminRoutes.Delete("/errors/{errorID}", app.adminDeleteScraperErrorLogByID)
@@ -165,10 +165,14 @@ func (app *application) getFollowedRssPostsForUserHandler(w http.ResponseWriter,
 	//get the page & pagesizes as ints and set to the embedded struct
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
-	// We don't use any sort for this endpoint
-	input.Filters.Sort = app.readString(qs, "", "")
-	// None of the sort values are supported for this endpoint
-	input.Filters.SortSafelist = []string{"", ""}
+	// an opaque cursor from a previous response switches us to keyset pagination
+	input.Filters.Cursor = app.readString(qs, "cursor", "")
+	// sort by published date, scrape date, favorites or comments eg: ?sort=-favorites_count
+	input.Filters.Sort = app.readString(qs, "sort", data.DefaultPostSort)
+	input.Filters.SortSafelist = data.PostSortSafelist
+	// limit the posts to a published date range
+	input.Filters.Since = app.readTime(qs, "since", v)
+	input.Filters.Until = app.readTime(qs, "until", v)
 	// Perform validation
 	if data.ValidateFilters(v, input.Filters); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
@@ -433,10 +437,14 @@ func (app *application) GetDetailedFavoriteRSSPosts(w http.ResponseWriter, r *ht
 	//get the page & pagesizes as ints and set to the embedded struct
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
-	// We don't use any sort for this endpoint
-	input.Filters.Sort = app.readString(qs, "", "")
-	// None of the sort values are supported for this endpoint
-	input.Filters.SortSafelist = []string{"", ""}
+	// an opaque cursor from a previous response switches us to keyset pagination
+	input.Filters.Cursor = app.readString(qs, "cursor", "")
+	// sort by published date, scrape date, favorites or comments eg: ?sort=-favorites_count
+	input.Filters.Sort = app.readString(qs, "sort", data.DefaultPostSort)
+	input.Filters.SortSafelist = data.PostSortSafelist
+	// limit the posts to a published date range
+	input.Filters.Since = app.readTime(qs, "since", v)
+	input.Filters.Until = app.readTime(qs, "until", v)
 	// Perform validation
 	if data.ValidateFilters(v, input.Filters); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
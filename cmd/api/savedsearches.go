@@ -0,0 +1,210 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/blue-davinci/aggregate/internal/data"
+	"github.com/blue-davinci/aggregate/internal/validator"
+	"github.com/google/uuid"
+)
+
+// createSavedSearchHandler() saves a search over the user's followed posts as a named
+// virtual feed. Accepts a name, query, an optional feed_id scope, an optional published
+// date range and whether new matches should trigger notifications.
+func (app *application) createSavedSearchHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name       string     `json:"name"`
+		Query      string     `json:"query"`
+		Feed_ID    uuid.UUID  `json:"feed_id"`
+		Start_Date *time.Time `json:"start_date"`
+		End_Date   *time.Time `json:"end_date"`
+		Notify     bool       `json:"notify"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	savedSearch := &data.SavedSearch{
+		User_ID:    app.contextGetUser(r).ID,
+		Name:       input.Name,
+		Query:      input.Query,
+		Feed_ID:    uuid.NullUUID{UUID: input.Feed_ID, Valid: input.Feed_ID != uuid.Nil},
+		Start_Date: input.Start_Date,
+		End_Date:   input.End_Date,
+		Notify:     input.Notify,
+	}
+	v := validator.New()
+	if data.ValidateSavedSearch(v, savedSearch); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	// notifications for new matches come with the plan
+	if savedSearch.Notify && !app.hasFeature(w, r, data.EntitlementSavedSearchAlerts) {
+		return
+	}
+	err = app.models.SavedSearches.CreateSavedSearch(savedSearch)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateSavedSearch):
+			v.AddError("name", "a saved search with this name already exists")
+			app.failedConstraintValidation(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusCreated, envelope{"saved_search": savedSearch}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getSavedSearchesHandler() returns all the saved searches for a user along with
+// each search's unread count.
+func (app *application) getSavedSearchesHandler(w http.ResponseWriter, r *http.Request) {
+	savedSearches, err := app.models.SavedSearches.GetSavedSearchesForUser(app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"saved_searches": savedSearches}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateSavedSearchHandler() partially updates a saved search. We expect the version
+// to be passed so as to guard against edit conflicts.
+func (app *application) updateSavedSearchHandler(w http.ResponseWriter, r *http.Request) {
+	savedSearchID, err := app.readIDParam(r, "searchID")
+	if err != nil || savedSearchID == uuid.Nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	// get the saved search we are updating
+	savedSearch, err := app.models.SavedSearches.GetSavedSearchByID(savedSearchID, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrSavedSearchNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	var input data.SavedSearchInput
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	data.UpdateSavedSearchFields(&input, savedSearch)
+	v := validator.New()
+	if data.ValidateSavedSearch(v, savedSearch); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	// only switching notifications on needs the plan, they can always be switched off
+	if input.Notify != nil && *input.Notify && !app.hasFeature(w, r, data.EntitlementSavedSearchAlerts) {
+		return
+	}
+	err = app.models.SavedSearches.UpdateSavedSearch(savedSearch)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		case errors.Is(err, data.ErrDuplicateSavedSearch):
+			v.AddError("name", "a saved search with this name already exists")
+			app.failedConstraintValidation(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"saved_search": savedSearch}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteSavedSearchHandler() deletes a saved search, we expect the search ID as a
+// parameter eg: DELETE /feeds/saved-searches/{searchID}
+func (app *application) deleteSavedSearchHandler(w http.ResponseWriter, r *http.Request) {
+	savedSearchID, err := app.readIDParam(r, "searchID")
+	if err != nil || savedSearchID == uuid.Nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	err = app.models.SavedSearches.DeleteSavedSearch(savedSearchID, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrSavedSearchNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "saved search deleted successfully"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getSavedSearchPostsHandler() returns the posts matching a saved search i.e the
+// contents of the virtual feed. Supports the usual page and page_size queries.
+func (app *application) getSavedSearchPostsHandler(w http.ResponseWriter, r *http.Request) {
+	savedSearchID, err := app.readIDParam(r, "searchID")
+	if err != nil || savedSearchID == uuid.Nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	var input struct {
+		data.Filters
+	}
+	v := validator.New()
+	qs := r.URL.Query()
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	// an opaque cursor from a previous response switches us to keyset pagination
+	input.Filters.Cursor = app.readString(qs, "cursor", "")
+	// We don't use any sort for this endpoint
+	input.Filters.Sort = app.readString(qs, "", "")
+	input.Filters.SortSafelist = []string{"", ""}
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	userID := app.contextGetUser(r).ID
+	// make sure the saved search exists for this user
+	savedSearch, err := app.models.SavedSearches.GetSavedSearchByID(savedSearchID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrSavedSearchNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	posts, metadata, err := app.models.SavedSearches.GetSavedSearchRssPostsForUser(userID, savedSearch.ID, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	// opening the search, i.e a request without a cursor, resets the unread count
+	if input.Filters.Cursor == "" {
+		err = app.models.SavedSearches.MarkSavedSearchAsViewed(savedSearch.ID, userID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"saved_search": savedSearch, "followed_rss_posts": posts, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/blue-davinci/aggregate/internal/data"
+	"github.com/blue-davinci/aggregate/internal/screening"
+	"github.com/blue-davinci/aggregate/internal/validator"
+)
+
+// newCommentScreener() builds the comment screening pipeline from our config, the
+// comments model supplies each author's recent comments for the duplicate and burst
+// checks. The external classifier is only added when a URL is configured.
+func newCommentScreener(cfg config, comments data.CommentsModel) *screening.Pipeline {
+	if !cfg.screening.enabled {
+		return nil
+	}
+	checks := []screening.Check{
+		screening.LinkCheck{Max: cfg.screening.maxlinks},
+		screening.NewBlocklistCheck(cfg.screening.blocklist),
+		screening.DuplicateCheck{Window: cfg.screening.duplicatewindow},
+		screening.BurstCheck{Window: cfg.screening.burstwindow, Limit: cfg.screening.burstlimit},
+	}
+	if cfg.screening.classifierurl != "" {
+		checks = append(checks, screening.ClassifierCheck{
+			Classifier: screening.NewHTTPClassifier(cfg.screening.classifierurl, cfg.screening.classifierkey, cfg.screening.classifiertimeout),
+			Threshold:  cfg.screening.classifierthreshold,
+		})
+	}
+	lookback := max(cfg.screening.duplicatewindow, cfg.screening.burstwindow)
+	return screening.New(comments, lookback, checks...)
+}
+
+// screenComment() runs a new comment through the screening pipeline and marks it pending
+// when it is flagged. Screening fails open, a check that errors is logged and the comment
+// is judged on the checks that did run.
+func (app *application) screenComment(comment *data.Comment) *screening.Result {
+	return app.runCommentScreening(comment, false)
+}
+
+// screenCommentEdit() screens the new text of an edited comment the same way, a comment
+// that was published can be held back again if it's edited into spam.
+func (app *application) screenCommentEdit(comment *data.Comment) *screening.Result {
+	return app.runCommentScreening(comment, true)
+}
+
+func (app *application) runCommentScreening(comment *data.Comment, edited bool) *screening.Result {
+	if app.commentScreener == nil {
+		return &screening.Result{}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var result *screening.Result
+	var err error
+	if edited {
+		result, err = app.commentScreener.ScreenEdit(ctx, comment.User_ID, comment.ID.String(), comment.Comment_Text)
+	} else {
+		result, err = app.commentScreener.Screen(ctx, comment.User_ID, comment.Comment_Text)
+	}
+	if err != nil {
+		app.logger.PrintError(err, map[string]string{"comment_id": comment.ID.String()})
+	}
+	if result.Flagged {
+		comment.Status = data.CommentStatusPending
+	}
+	return result
+}
+
+// adminGetPendingCommentsHandler returns the comments screening held back for review,
+// the longest waiting first, eg: GET /admin/comments/pending?page=1&page_size=20
+// Pending comments are approved or rejected through adminModerateCommentHandler.
+func (app *application) adminGetPendingCommentsHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		data.Filters
+	}
+	v := validator.New()
+	qs := r.URL.Query()
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "created_at")
+	input.Filters.SortSafelist = []string{"created_at"}
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	pending, metadata, err := app.models.Moderation.GetPendingComments(input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"comments": pending, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// publishApprovedComment() sends out the notifications a comment skipped while it was
+// pending, as if it had just been posted.
+func (app *application) publishApprovedComment(comment *data.ModeratedComment) {
+	approved, err := app.models.Comments.GetCommentByID(comment.ID, comment.User_ID)
+	if err != nil {
+		if !errors.Is(err, data.ErrCommentNotFound) {
+			app.logger.PrintError(err, nil)
+		}
+		return
+	}
+	mentions, err := app.models.Comments.GetCommentMentions(approved)
+	if err != nil {
+		app.logger.PrintError(err, nil)
+		return
+	}
+	mentioned := []int64{}
+	for _, mention := range mentions {
+		if !slices.Contains(mentioned, mention.User_ID) {
+			mentioned = append(mentioned, mention.User_ID)
+		}
+	}
+	err = app.models.Inbox.CreateCommentNotifications(approved.ID)
+	if err != nil {
+		app.logger.PrintError(err, nil)
+	}
+	app.publishCommentNotification(approved, mentioned)
+}
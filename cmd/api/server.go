@@ -36,6 +36,9 @@ func (app *application) server() error {
 		app.logger.PrintInfo("shutting down server", map[string]string{
 			"signal": s.String(),
 		})
+		// close the notification hub first, open streams never go idle so Shutdown()
+		// would otherwise wait on them until the context expires
+		app.notificationHub.Close()
 		// make a 20sec context
 		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
 		defer cancel()
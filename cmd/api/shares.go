@@ -0,0 +1,189 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/blue-davinci/aggregate/internal/data"
+	"github.com/blue-davinci/aggregate/internal/validator"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// sharePostHandler shares a post, either directly with other users by their handles or
+// through a public link. Direct shares land in each recipient's inbox with the note.
+// eg: POST /v1/feeds/follow/posts/{postID}/share
+// {"share_type": "direct", "recipients": ["jane_doe"], "note": "thought you'd like this"}
+// {"share_type": "link", "note": "worth a read"}
+// The token of a link share is only returned here, along with the ready made URL.
+func (app *application) sharePostHandler(w http.ResponseWriter, r *http.Request) {
+	postID, err := app.readIDParam(r, "postID")
+	if err != nil || postID == uuid.Nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	var input struct {
+		Share_Type string   `json:"share_type"`
+		Recipients []string `json:"recipients"`
+		Note       string   `json:"note"`
+	}
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	user := app.contextGetUser(r)
+	share := &data.PostShare{
+		Post_ID:    postID,
+		User_ID:    user.ID,
+		Share_Type: input.Share_Type,
+		Note:       input.Note,
+		Recipients: data.NormalizeShareRecipients(input.Recipients),
+	}
+	v := validator.New()
+	if data.ValidatePostShare(v, share); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	var recipients []*data.ShareRecipient
+	if share.Share_Type == data.ShareTypeDirect {
+		recipients, err = app.models.Shares.GetShareRecipients(user.ID, share.Recipients)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+	err = app.models.Shares.SharePost(share, recipients)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrPostNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrNoShareRecipients):
+			v.AddError("recipients", "none of these users could be found")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if share.Share_Type == data.ShareTypeLink {
+		share.URL = app.config.frontend.shareurl + share.Token
+	}
+	if len(recipients) > 0 {
+		message := fmt.Sprintf("@%s shared a post with you", user.Handle)
+		if share.Note != "" {
+			message = fmt.Sprintf("@%s shared a post with you: %s", user.Handle, share.Note)
+		}
+		app.background(func() {
+			for _, recipient := range recipients {
+				app.sendUserNotification(&data.UserNotification{
+					User_ID:           recipient.User_ID,
+					Notification_Type: data.InboxTypeShare,
+					Message:           message,
+					Post_ID:           uuid.NullUUID{UUID: postID, Valid: true},
+				})
+			}
+		})
+	}
+	err = app.writeJSON(w, http.StatusCreated, envelope{"share": share}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getPostShareCountHandler returns how often a post has been shared
+// eg: GET /v1/feeds/follow/posts/{postID}/shares
+func (app *application) getPostShareCountHandler(w http.ResponseWriter, r *http.Request) {
+	postID, err := app.readIDParam(r, "postID")
+	if err != nil || postID == uuid.Nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	count, err := app.models.Shares.GetPostShareCount(postID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"shares": count}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getPostShareLinksHandler lists the share links the current user created along with how
+// often each was viewed. eg: GET /v1/feeds/shares/links?page=1&page_size=20
+func (app *application) getPostShareLinksHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		data.Filters
+	}
+	v := validator.New()
+	qs := r.URL.Query()
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "created_at")
+	input.Filters.SortSafelist = []string{"created_at"}
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	links, metadata, err := app.models.Shares.GetPostShareLinksForUser(app.contextGetUser(r).ID, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"share_links": links, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deletePostShareLinkHandler revokes one of the current user's share links
+// eg: DELETE /v1/feeds/shares/links/{shareID}
+func (app *application) deletePostShareLinkHandler(w http.ResponseWriter, r *http.Request) {
+	shareID, err := app.readIDParam(r, "shareID")
+	if err != nil || shareID == uuid.Nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	err = app.models.Shares.DeletePostShareLink(shareID, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrPostShareNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "share link revoked successfully"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getSharedPostHandler serves the preview behind a share link. It is public so anyone
+// the link is passed to can open it, and every view is counted.
+// eg: GET /v1/feeds/shared/{token}
+func (app *application) getSharedPostHandler(w http.ResponseWriter, r *http.Request) {
+	token := chi.URLParam(r, "token")
+	v := validator.New()
+	if data.ValidateAPIKeyPlaintext(v, token, data.APIVerificationLength); !v.Valid() {
+		app.notFoundResponse(w, r)
+		return
+	}
+	preview, err := app.models.Shares.GetSharedPostByToken(token)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrPostShareNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"shared_post": preview}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
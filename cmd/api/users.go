@@ -10,11 +10,16 @@ import (
 	"github.com/blue-davinci/aggregate/internal/validator"
 )
 
+// maxGeneratedHandleAttempts is how many handles registration makes up for a user who
+// didn't pick one before giving up
+const maxGeneratedHandleAttempts = 5
+
 func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
 		Name     string `json:"name"`
 		Email    string `json:"email"`
 		Password string `json:"password"`
+		Handle   string `json:"handle"`
 	}
 	err := app.readJSON(w, r, &input)
 	if err != nil {
@@ -27,6 +32,12 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 		Email:     input.Email,
 		Activated: false,
 		User_Img:  data.DefaultImage, // set the default image for the user
+		Handle:    input.Handle,
+	}
+	// users who don't pick a handle get one made from their name, they can change it later
+	generatedHandle := user.Handle == ""
+	if generatedHandle {
+		user.Handle = data.DefaultHandle(user.Name)
 	}
 	// lets set the password for the user by using the Set method from the password struct
 	err = user.Password.Set(input.Password)
@@ -41,13 +52,21 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	// insert our user to the DB
+	// insert our user to the DB, a handle we made up that is already taken is just
+	// made up again as the user never asked for it
 	err = app.models.Users.Insert(user)
+	for attempt := 1; generatedHandle && errors.Is(err, data.ErrDuplicateHandle) && attempt < maxGeneratedHandleAttempts; attempt++ {
+		user.Handle = data.DefaultHandle(user.Name)
+		err = app.models.Users.Insert(user)
+	}
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrDuplicateEmail):
 			v.AddError("email", "a user with this email address already exists")
 			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrDuplicateHandle) && !generatedHandle:
+			v.AddError("handle", "a user with this handle already exists")
+			app.failedValidationResponse(w, r, v.Errors)
 		default:
 			app.serverErrorResponse(w, r, err)
 		}
@@ -230,14 +249,15 @@ func (app *application) updateUserPasswordHandler(w http.ResponseWriter, r *http
 	}
 }
 
-// updateUserInformation() will allow a user to update their name and avatar/image
+// updateUserInformation() will allow a user to update their name, handle and avatar/image
 // This route also supports partial updates, so a user can update just their name or just their avatar/image.
 func (app *application) updateUserInformationHandler(w http.ResponseWriter, r *http.Request) {
 	// we won't be recieving anything in terms of parameters or queries so
 	// we proceed to read the user update info from the request body
-	// currently a user can only update their name and avatar/image
+	// currently a user can only update their name, handle and avatar/image
 	var input struct {
 		Name    *string `json:"name"`
+		Handle  *string `json:"handle"`
 		Img_Url *string `json:"img_url"`
 	}
 	//lets get the user's details
@@ -252,6 +272,9 @@ func (app *application) updateUserInformationHandler(w http.ResponseWriter, r *h
 	if input.Name != nil {
 		user.Name = *input.Name
 	}
+	if input.Handle != nil {
+		user.Handle = *input.Handle
+	}
 	if input.Img_Url != nil {
 		user.User_Img = *input.Img_Url
 	}
@@ -267,6 +290,9 @@ func (app *application) updateUserInformationHandler(w http.ResponseWriter, r *h
 		switch {
 		case errors.Is(err, data.ErrEditConflict):
 			app.editConflictResponse(w, r)
+		case errors.Is(err, data.ErrDuplicateHandle):
+			v.AddError("handle", "a user with this handle already exists")
+			app.failedValidationResponse(w, r, v.Errors)
 		default:
 			app.serverErrorResponse(w, r, err)
 		}
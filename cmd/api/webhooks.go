@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/blue-davinci/aggregate/internal/data"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// paymentWebhookHandler receives the payment providers' webhook events so payments settle
+// even when the user never makes it back to the frontend to verify them.
+// eg: POST /v1/subscriptions/webhook/stripe with a Stripe-Signature header, the bare
+// POST /v1/subscriptions/webhook is paystack's.
+// The provider checks the signature against the raw body before anything else. Every event
+// is stored by its ID first and a redelivery of a stored event is acknowledged without
+// being processed again. If processing fails we forget the event and answer with an
+// error, the provider then retries it.
+func (app *application) paymentWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "provider")
+	if name == "" {
+		name = data.PaymentProviderPaystack
+	}
+	provider, ok := app.paymentProviders.Get(name)
+	if !ok {
+		app.notFoundResponse(w, r)
+		return
+	}
+	// Use http.MaxBytesReader() to limit the size of the body to 1MB, same as readJSON()
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 1_048_576))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	event, err := provider.ParseWebhook(payload, r.Header)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrInvalidWebhookSignature):
+			app.errorResponse(w, r, http.StatusUnauthorized, "invalid webhook signature")
+		default:
+			app.badRequestResponse(w, r, err)
+		}
+		return
+	}
+	isNew, err := app.models.Payments.RecordWebhookEvent(event)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !isNew {
+		app.logger.PrintInfo("skipping processed webhook event", map[string]string{"provider": event.Provider, "event_id": event.ID})
+		err = app.writeJSON(w, http.StatusOK, envelope{"message": "event already processed"}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	switch event.Type {
+	case data.WebhookPaymentSucceeded:
+		err = app.handleWebhookPaymentSucceeded(provider, event)
+	case data.WebhookAuthorizationDisabled:
+		err = app.handleWebhookAuthorizationDisabled(event)
+	case data.WebhookPaymentFailed:
+		err = app.handleWebhookPaymentFailed(event)
+	default:
+		app.logger.PrintInfo("ignoring webhook event", map[string]string{"provider": event.Provider, "event": event.Provider_Type})
+	}
+	if err != nil {
+		if deleteErr := app.models.Payments.DeleteWebhookEvent(event.ID); deleteErr != nil {
+			app.logger.PrintError(deleteErr, map[string]string{"event_id": event.ID})
+		}
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "event processed"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// handleWebhookPaymentSucceeded() turns a successful payment into a subscription. The
+// payment is matched to what it was started for by its reference, payments we have no
+// intent for are the recurring ones which the subscription job settles as it makes them.
+// The transaction itself is read back from the provider so the subscription is built
+// from the same data the verify handler uses. When the frontend already verified it the
+// subscription exists and we are done.
+func (app *application) handleWebhookPaymentSucceeded(provider data.PaymentProvider, event *data.WebhookEvent) error {
+	intent, err := app.models.Payments.GetPaymentIntentByReference(event.Reference)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrPaymentIntentNotFound):
+			app.logger.PrintInfo("no payment intent for charge", map[string]string{"reference": event.Reference})
+			return nil
+		default:
+			return err
+		}
+	}
+	transaction, err := provider.VerifyTransaction(context.Background(), event.Reference)
+	if err != nil {
+		return err
+	}
+	if !transaction.Succeeded() {
+		return nil
+	}
+	plan, err := app.models.Payments.GetPaymentPlanByID(intent.Plan_ID)
+	if err != nil {
+		return err
+	}
+	payment_detail := app.newPaymentDetails(intent.User_ID, plan, transaction)
+	planChange, err := app.planChangeForReference(event.Reference)
+	if err != nil {
+		return err
+	}
+	redemption, err := app.couponRedemptionForReference(event.Reference)
+	if err != nil {
+		return err
+	}
+	amountCharged := payment_detail.Price
+	if planChange != nil || redemption != nil {
+		if price, ok := plan.PriceIn(amountCharged.Currency); ok {
+			payment_detail.Price = price
+		}
+	}
+	lines := data.SubscriptionLineItems(plan.Name, payment_detail.Price, amountCharged, app.invoiceAdjustment(planChange, redemption))
+	err = app.createSubscriptionHandler(payment_detail, lines, plan.Name, intent.User_Name, intent.User_Email, transaction.Paid_At)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateTransaction):
+			return nil
+		default:
+			return err
+		}
+	}
+	// a challenged renewal is settled just like the verify handler does it and the
+	// subscription it renews is marked as renewed so it isn't charged again
+	challengedTransaction, err := app.models.Payments.GetPendingChallengedTransactionByReference(event.Reference)
+	if err != nil && !errors.Is(err, data.ErrChallangedTransactionNotFound) {
+		return err
+	}
+	if challengedTransaction != nil {
+		_, err = app.models.Payments.UpdateChallengedTransactionStatus(challengedTransaction.ID, intent.User_ID, data.PaymentStatusSuccess)
+		if err != nil {
+			return err
+		}
+	}
+	if planChange != nil {
+		err = app.completePlanChange(planChange, payment_detail, amountCharged)
+		if err != nil {
+			return err
+		}
+	}
+	if redemption != nil {
+		err = app.redeemCoupon(redemption, payment_detail.ID)
+		if err != nil {
+			return err
+		}
+	}
+	if intent.Renews_Subscription_ID != uuid.Nil {
+		err = app.models.Payments.UpdateSubscriptionStatus(intent.Renews_Subscription_ID, data.PaymentStatusRenewed, intent.User_ID)
+		if err != nil {
+			return err
+		}
+	}
+	app.logger.PrintInfo("webhook settled a charge", map[string]string{
+		"provider":       transaction.Provider,
+		"reference":      event.Reference,
+		"transaction id": fmt.Sprintf("%d", transaction.ID),
+	})
+	return nil
+}
+
+// handleWebhookAuthorizationDisabled() cancels the subscriptions paid for with the disabled
+// authorization. Like a cancellation by the user, they stay usable until they end.
+func (app *application) handleWebhookAuthorizationDisabled(event *data.WebhookEvent) error {
+	if event.Authorization_Code == "" {
+		return nil
+	}
+	cancelled, err := app.models.Payments.CancelSubscriptionsByAuthorizationCode(event.Authorization_Code)
+	if err != nil {
+		return err
+	}
+	for _, sub := range cancelled {
+		app.logger.PrintInfo("webhook cancelled a subscription", map[string]string{"Subscription ID": sub.ID.String()})
+		app.notifyUser(sub.User_ID, data.InboxTypeBilling,
+			fmt.Sprintf("Your subscription was cancelled, you keep access until %s", sub.End_Date.Format("Jan 2, 2006")), uuid.Nil)
+	}
+	return nil
+}
+
+// handleWebhookPaymentFailed() records a failed payment against the latest subscription
+// paid for with the same authorization and lets the user know. A checkout that failed
+// also gives back the promo code redemption it held.
+func (app *application) handleWebhookPaymentFailed(event *data.WebhookEvent) error {
+	if event.Reference != "" {
+		err := app.models.Coupons.ReleaseCheckoutCouponRedemption(event.Reference)
+		if err != nil {
+			return err
+		}
+	}
+	if event.Authorization_Code == "" {
+		return nil
+	}
+	paymentDetails, err := app.models.Payments.GetLatestSubscriptionByAuthorizationCode(event.Authorization_Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrSubscriptionNotFound):
+			app.logger.PrintInfo("no subscription for failed payment", map[string]string{"reference": event.Reference})
+			return nil
+		default:
+			return err
+		}
+	}
+	message := event.Message
+	if message == "" {
+		message = "the payment failed"
+	}
+	return app.createFailedTransactionHandler(paymentDetails, message, event.Reference, event.Provider_Type)
+}
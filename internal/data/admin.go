@@ -5,7 +5,6 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"strconv"
 	"strings"
 	"time"
 
@@ -30,7 +29,7 @@ type AdminPaymentHistory struct {
 type AdminChallangedTransaction struct {
 	ChallengedTransaction ChallengedTransaction `json:"challenged_transaction"`
 	PlanID                int32                 `json:"plan_id"`
-	PlanPrice             int64                 `json:"plan_price"`
+	PlanPrice             Money                 `json:"plan_price"`
 	Start_Date            time.Time             `json:"start_date"`
 	End_Date              time.Time             `json:"end_date"`
 	UserName              string                `json:"user_name"`
@@ -79,8 +78,9 @@ type UserStatistics struct {
 }
 
 // SubscriptionStatistics represents the statistics for the subscriptions.
+// Revenue is totalled per currency as amounts in different currencies can't be added.
 type SubscriptionStatistics struct {
-	Total_Revenue            float64 `json:"total_revenue"`
+	Total_Revenue            []Money `json:"total_revenue"`
 	Active_Subscriptions     int64   `json:"active_subscriptions"`
 	Cancelled_Subscriptions  int64   `json:"cancelled_subscriptions"`
 	Expired_Subscriptions    int64   `json:"expired_subscriptions"`
@@ -225,6 +225,10 @@ func (m AdminModel) AdminGetPaymentPlans() ([]*Payment_Plan, error) {
 	if err != nil {
 		return nil, err
 	}
+	prices, err := getAllPlanPrices(ctx, m.DB)
+	if err != nil {
+		return nil, err
+	}
 	payment_plans := []*Payment_Plan{}
 	for _, row := range rows {
 		var payment_plan Payment_Plan
@@ -233,17 +237,18 @@ func (m AdminModel) AdminGetPaymentPlans() ([]*Payment_Plan, error) {
 		payment_plan.Image = row.Image
 		payment_plan.Description = row.Description.String
 		payment_plan.Duration = row.Duration
-		priceStr := row.Price
-		price, err := strconv.ParseFloat(priceStr, 64)
-		if err != nil {
-			return nil, err
+		payment_plan.Price = NewMoney(row.Price, row.Currency)
+		payment_plan.Prices = prices[row.ID]
+		if payment_plan.Prices == nil {
+			payment_plan.Prices = []Money{}
 		}
-		payment_plan.Price = int64(price)
 		payment_plan.Features = row.Features
 		payment_plan.Created_At = row.CreatedAt
 		payment_plan.Updated_At = row.UpdatedAt
 		payment_plan.Status = row.Status
 		payment_plan.Version = row.Version
+		payment_plan.Trial_Days = row.TrialDays
+		payment_plan.Seats = row.Seats
 
 		payment_plans = append(payment_plans, &payment_plan)
 	}
@@ -271,17 +276,18 @@ func (m AdminModel) AdminGetPaymentPlanByID(planID int32) (*Payment_Plan, error)
 	payment_plan.Image = plan.Image
 	payment_plan.Description = plan.Description.String
 	payment_plan.Duration = plan.Duration
-	priceStr := plan.Price
-	price, err := strconv.ParseFloat(priceStr, 64)
+	payment_plan.Price = NewMoney(plan.Price, plan.Currency)
+	payment_plan.Prices, err = getPlanPrices(ctx, m.DB, plan.ID)
 	if err != nil {
 		return nil, err
 	}
-	payment_plan.Price = int64(price)
 	payment_plan.Features = plan.Features
 	payment_plan.Created_At = plan.CreatedAt
 	payment_plan.Updated_At = plan.UpdatedAt
 	payment_plan.Status = plan.Status
 	payment_plan.Version = plan.Version
+	payment_plan.Trial_Days = plan.TrialDays
+	payment_plan.Seats = plan.Seats
 	// we're good, we return the payment_plan
 	return &payment_plan, nil
 }
@@ -340,7 +346,7 @@ func (m AdminModel) AdminGetAllSubscriptions(filters Filters) ([]*AdminPaymentHi
 		payment_history.Card_Exp_Month = row.CardExpMonth.String
 		payment_history.Card_Exp_Year = row.CardExpYear.String
 		payment_history.Card_Type = row.CardType.String
-		payment_history.Currency = row.Currency.String
+		payment_history.Currency = row.Currency
 		payment_history.Created_At = row.CreatedAt
 		// plan details
 		payment_history.Plan_Name = row.PlanName
@@ -353,12 +359,7 @@ func (m AdminModel) AdminGetAllSubscriptions(filters Filters) ([]*AdminPaymentHi
 		subscription.Plan_ID = row.PlanID
 		subscription.Start_Date = row.StartDate
 		subscription.End_Date = row.EndDate
-		priceStr := row.Price
-		price, err := strconv.ParseFloat(priceStr, 64)
-		if err != nil {
-			return nil, Metadata{}, err
-		}
-		subscription.Price = int64(price)
+		subscription.Price = NewMoney(row.Price, row.Currency)
 		subscription.Status = row.Status
 		payment_history.Subscription = subscription
 		// type asser
@@ -402,12 +403,7 @@ func (m AdminModel) AdminGetChallaengedTransactionsBySubscriptionID(subscription
 		// add additional info
 		admin_challenged_transaction.ChallengedTransaction = challenged_transaction
 		admin_challenged_transaction.PlanID = row.PlanID
-		priceStr := row.Price
-		price, err := strconv.ParseFloat(priceStr, 64)
-		if err != nil {
-			return nil, err
-		}
-		admin_challenged_transaction.PlanPrice = int64(price)
+		admin_challenged_transaction.PlanPrice = NewMoney(row.Price, row.Currency)
 		admin_challenged_transaction.Start_Date = row.StartDate
 		admin_challenged_transaction.End_Date = row.EndDate
 		// user data
@@ -430,11 +426,14 @@ func (m AdminModel) AdminGetStatistics() (*AdminStatistics, error) {
 	if err != nil {
 		return nil, err
 	}
-	// Type assertion for TotalRevenue
-	totalRevenue, err := strconv.ParseFloat(queryResult.TotalRevenue, 64)
+	// revenue is kept per currency
+	revenueByCurrency, err := m.DB.RevenueByCurrency(ctx)
 	if err != nil {
-		fmt.Printf("unexpected type for TotalRevenue: %T\n", queryResult.TotalRevenue)
-		return nil, errors.New("unexpected type for TotalRevenue")
+		return nil, err
+	}
+	totalRevenue := []Money{}
+	for _, row := range revenueByCurrency {
+		totalRevenue = append(totalRevenue, NewMoney(row.TotalRevenue, row.Currency))
 	}
 	// Type assertion for MostUsedPaymentMethod
 	mostUsedPaymentMethod, ok := queryResult.MostUsedPaymentMethod.(string)
@@ -467,7 +466,8 @@ func (m AdminModel) AdminGetStatistics() (*AdminStatistics, error) {
 	return adminStatistics, nil
 }
 
-// AdminCreatePaymentPlans() creates a new payment plan in the database.
+// AdminCreatePaymentPlans() creates a new payment plan in the database along with
+// its prices in other currencies.
 func (m AdminModel) AdminCreatePaymentPlans(paymentPlan *Payment_Plan) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -477,9 +477,12 @@ func (m AdminModel) AdminCreatePaymentPlans(paymentPlan *Payment_Plan) error {
 		Image:       paymentPlan.Image,
 		Description: sql.NullString{String: paymentPlan.Description, Valid: paymentPlan.Description != ""},
 		Duration:    paymentPlan.Duration,
-		Price:       strconv.FormatFloat(float64(paymentPlan.Price), 'f', 2, 64),
+		Price:       paymentPlan.Price.Amount,
+		Currency:    paymentPlan.Price.Currency,
 		Features:    paymentPlan.Features,
 		Status:      paymentPlan.Status,
+		TrialDays:   paymentPlan.Trial_Days,
+		Seats:       paymentPlan.Seats,
 	})
 	if err != nil {
 		switch {
@@ -492,8 +495,8 @@ func (m AdminModel) AdminCreatePaymentPlans(paymentPlan *Payment_Plan) error {
 	paymentPlan.ID = queryResult.ID
 	paymentPlan.Created_At = queryResult.CreatedAt
 	paymentPlan.Updated_At = queryResult.UpdatedAt
-
-	return nil
+	// save what it costs in other currencies
+	return setPlanPrices(ctx, m.DB, paymentPlan.ID, paymentPlan.Prices)
 }
 
 // AdminUpdatePaymentPlan() updates a payment plan in the database.
@@ -508,9 +511,12 @@ func (m AdminModel) AdminUpdatePaymentPlan(paymentPlan *Payment_Plan) error {
 		Image:       paymentPlan.Image,
 		Description: sql.NullString{String: paymentPlan.Description, Valid: paymentPlan.Description != ""},
 		Duration:    paymentPlan.Duration,
-		Price:       strconv.FormatFloat(float64(paymentPlan.Price), 'f', 2, 64),
+		Price:       paymentPlan.Price.Amount,
+		Currency:    paymentPlan.Price.Currency,
 		Features:    paymentPlan.Features,
 		Status:      paymentPlan.Status,
+		TrialDays:   paymentPlan.Trial_Days,
+		Seats:       paymentPlan.Seats,
 		Version:     paymentPlan.Version,
 	})
 	// check for an edit conflict, if there was, we return it specifically.
@@ -524,8 +530,8 @@ func (m AdminModel) AdminUpdatePaymentPlan(paymentPlan *Payment_Plan) error {
 	}
 	// update the version
 	paymentPlan.Version = version
-	// payment plan was updated successfully
-	return nil
+	// and the prices in other currencies
+	return setPlanPrices(ctx, m.DB, paymentPlan.ID, paymentPlan.Prices)
 }
 
 // AdminUpdatePermissionCode() updates the permission code for a specific permission.
@@ -0,0 +1,371 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blue-davinci/aggregate/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	BillingTriggerSchedule = "schedule"
+	BillingTriggerAdmin    = "admin"
+)
+
+var (
+	BillingRunStatusRunning   = "running"
+	BillingRunStatusCompleted = "completed"
+	BillingRunStatusFailed    = "failed"
+)
+
+// What happened to a subscription the billing runner tried to renew
+const (
+	BillingOutcomeRenewed        = "renewed"
+	BillingOutcomeRetryScheduled = "retry_scheduled"
+	BillingOutcomeChallenged     = "challenged"
+	BillingOutcomeExpired        = "expired"
+	BillingOutcomeError          = "error"
+)
+
+var (
+	ErrBillingRunNotFound = errors.New("billing run not found")
+	ErrRenewalLockLost    = errors.New("renewal lock lost to another billing run")
+)
+
+type BillingModel struct {
+	DB *database.Queries
+}
+
+// BillingSchedule is when failed renewals are retried, in days after the subscription
+// ended, and how long a subscription stays usable while they are. Retries that would
+// fall after the grace period are never made.
+type BillingSchedule struct {
+	Retry_Days   []int
+	Grace_Period time.Duration
+}
+
+// BillingRun is the report of one pass of the billing runner
+type BillingRun struct {
+	ID                int64             `json:"id"`
+	Trigger           string            `json:"trigger"`
+	Status            string            `json:"status"`
+	Processed         int32             `json:"processed"`
+	Renewed           int32             `json:"renewed"`
+	Retries_Scheduled int32             `json:"retries_scheduled"`
+	Challenged        int32             `json:"challenged"`
+	Expired           int32             `json:"expired"`
+	Errors            int32             `json:"errors"`
+	Error_Message     string            `json:"error_message,omitempty"`
+	Started_At        time.Time         `json:"started_at"`
+	Finished_At       *time.Time        `json:"finished_at"`
+	Items             []*BillingRunItem `json:"items,omitempty"`
+}
+
+// BillingRunItem is what a billing run did with one subscription. Attempt counts the
+// renewal attempts made on it so far, this one included.
+type BillingRunItem struct {
+	ID              int64      `json:"id"`
+	Run_ID          int64      `json:"run_id"`
+	Subscription_ID uuid.UUID  `json:"subscription_id"`
+	User_ID         int64      `json:"user_id"`
+	User_Name       string     `json:"user_name,omitempty"`
+	User_Email      string     `json:"user_email,omitempty"`
+	Attempt         int32      `json:"attempt"`
+	Outcome         string     `json:"outcome"`
+	Message         string     `json:"message"`
+	Next_Attempt_At *time.Time `json:"next_attempt_at"`
+	Created_At      time.Time  `json:"created_at"`
+}
+
+// ParseRetryDays() reads a retry schedule such as "1,3,7". The days must be positive
+// and increasing.
+func ParseRetryDays(value string) ([]int, error) {
+	days := []int{}
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		day, err := strconv.Atoi(field)
+		if err != nil || day < 1 {
+			return nil, fmt.Errorf("invalid retry day %q", field)
+		}
+		if len(days) > 0 && day <= days[len(days)-1] {
+			return nil, fmt.Errorf("retry days must increase, %d follows %d", day, days[len(days)-1])
+		}
+		days = append(days, day)
+	}
+	return days, nil
+}
+
+// NextAttempt() returns when a renewal that has failed attempts times is tried again.
+// ok is false when there are no retries left within the grace period.
+func (s BillingSchedule) NextAttempt(endDate time.Time, attempts int32) (next time.Time, ok bool) {
+	if attempts < 1 || int(attempts) > len(s.Retry_Days) {
+		return time.Time{}, false
+	}
+	next = endDate.AddDate(0, 0, s.Retry_Days[attempts-1])
+	if next.After(endDate.Add(s.Grace_Period)) {
+		return time.Time{}, false
+	}
+	return next, true
+}
+
+// ValidRetryDays() reports whether every retry of a schedule falls within its grace period
+func (s BillingSchedule) ValidRetryDays() bool {
+	return len(s.Retry_Days) == 0 || time.Duration(slices.Max(s.Retry_Days))*24*time.Hour <= s.Grace_Period
+}
+
+// Record() adds an item to the run's counts
+func (r *BillingRun) Record(item *BillingRunItem) {
+	r.Processed++
+	switch item.Outcome {
+	case BillingOutcomeRenewed:
+		r.Renewed++
+	case BillingOutcomeRetryScheduled:
+		r.Retries_Scheduled++
+	case BillingOutcomeChallenged:
+		r.Challenged++
+	case BillingOutcomeExpired:
+		r.Expired++
+	case BillingOutcomeError:
+		r.Errors++
+	}
+}
+
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// ClaimDueSubscriptions() takes a batch of the subscriptions due for renewal for this
+// runner. The rows are picked with SKIP LOCKED and leased until lockedUntil so other
+// instances of the runner pass over them.
+func (m BillingModel) ClaimDueSubscriptions(batchSize int32, lockedUntil time.Time) ([]*RecurringSubscription, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := m.DB.ClaimDueSubscriptions(ctx, database.ClaimDueSubscriptionsParams{
+		Limit:              batchSize,
+		RenewalLockedUntil: sql.NullTime{Time: lockedUntil, Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	subscriptions := []*RecurringSubscription{}
+	for _, row := range rows {
+		subscriptions = append(subscriptions, &RecurringSubscription{
+			Subscription: Subscription{
+				ID:         row.ID,
+				User_ID:    row.UserID,
+				Plan_ID:    row.PlanID,
+				Start_Date: row.StartDate,
+				End_Date:   row.EndDate,
+				Price:      NewMoney(row.Price, row.Currency),
+				Is_Trial:   row.IsTrial,
+			},
+			Provider:             row.Provider,
+			User_ID:              row.UserID,
+			User_Name:            row.Name,
+			User_Email:           row.Email,
+			Authorization_Code:   row.AuthorizationCode.String,
+			Renewal_Attempts:     row.RenewalAttempts,
+			Renewal_Locked_Until: row.RenewalLockedUntil.Time,
+		})
+	}
+	return subscriptions, nil
+}
+
+// ExtendRenewalLock() pushes the lease on a claimed subscription out to lockedUntil. It
+// only goes through while the lease is still the one this runner took, if it lapsed and
+// another instance claimed the subscription, or it was cancelled, we get ErrRenewalLockLost.
+func (m BillingModel) ExtendRenewalLock(subscription *RecurringSubscription, lockedUntil time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	// the column keeps whole seconds, so this is the value we'll compare against next time
+	lockedUntil = lockedUntil.Round(time.Second)
+	rows, err := m.DB.ExtendRenewalLock(ctx, database.ExtendRenewalLockParams{
+		ID:                   subscription.Subscription.ID,
+		RenewalLockedUntil:   sql.NullTime{Time: lockedUntil, Valid: true},
+		RenewalLockedUntil_2: sql.NullTime{Time: subscription.Renewal_Locked_Until, Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrRenewalLockLost
+	}
+	subscription.Renewal_Locked_Until = lockedUntil
+	return nil
+}
+
+// RecordFailedRenewal() leaves a subscription past due until its next renewal attempt
+func (m BillingModel) RecordFailedRenewal(subscriptionID uuid.UUID, attempts int32, nextAttempt time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return m.DB.RecordFailedRenewal(ctx, database.RecordFailedRenewalParams{
+		ID:              subscriptionID,
+		RenewalAttempts: attempts,
+		NextRenewalAt:   sql.NullTime{Time: nextAttempt, Valid: true},
+	})
+}
+
+// ExpireSubscription() expires a subscription whose renewal attempts ran out
+func (m BillingModel) ExpireSubscription(subscriptionID uuid.UUID, attempts int32) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return m.DB.ExpireSubscription(ctx, database.ExpireSubscriptionParams{
+		ID:              subscriptionID,
+		RenewalAttempts: attempts,
+	})
+}
+
+// CreateBillingRun() starts the report of a billing run
+func (m BillingModel) CreateBillingRun(trigger string) (*BillingRun, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	row, err := m.DB.CreateBillingRun(ctx, trigger)
+	if err != nil {
+		return nil, err
+	}
+	return &BillingRun{
+		ID:         row.ID,
+		Trigger:    trigger,
+		Status:     row.Status,
+		Started_At: row.StartedAt,
+	}, nil
+}
+
+// CreateBillingRunItem() adds what was done with a subscription to a run's report
+func (m BillingModel) CreateBillingRunItem(item *BillingRunItem) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	nextAttempt := sql.NullTime{}
+	if item.Next_Attempt_At != nil {
+		nextAttempt = sql.NullTime{Time: *item.Next_Attempt_At, Valid: true}
+	}
+	return m.DB.CreateBillingRunItem(ctx, database.CreateBillingRunItemParams{
+		RunID:          item.Run_ID,
+		SubscriptionID: item.Subscription_ID,
+		UserID:         item.User_ID,
+		Attempt:        item.Attempt,
+		Outcome:        item.Outcome,
+		Message:        item.Message,
+		NextAttemptAt:  nextAttempt,
+	})
+}
+
+// FinishBillingRun() saves the counts and final status of a run
+func (m BillingModel) FinishBillingRun(run *BillingRun) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	finishedAt, err := m.DB.FinishBillingRun(ctx, database.FinishBillingRunParams{
+		ID:               run.ID,
+		Status:           run.Status,
+		Processed:        run.Processed,
+		Renewed:          run.Renewed,
+		RetriesScheduled: run.Retries_Scheduled,
+		Challenged:       run.Challenged,
+		Expired:          run.Expired,
+		Errors:           run.Errors,
+		ErrorMessage:     sql.NullString{String: run.Error_Message, Valid: run.Error_Message != ""},
+	})
+	if err != nil {
+		return err
+	}
+	run.Finished_At = nullTimePtr(finishedAt)
+	return nil
+}
+
+// GetBillingRuns() returns the billing runs, the latest first
+func (m BillingModel) GetBillingRuns(filters Filters) ([]*BillingRun, Metadata, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := m.DB.GetBillingRuns(ctx, database.GetBillingRunsParams{
+		Limit:  int32(filters.limit()),
+		Offset: int32(filters.offset()),
+	})
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	totalRecords := 0
+	runs := []*BillingRun{}
+	for _, row := range rows {
+		totalRecords = int(row.TotalRecords)
+		runs = append(runs, &BillingRun{
+			ID:                row.ID,
+			Trigger:           row.Trigger,
+			Status:            row.Status,
+			Processed:         row.Processed,
+			Renewed:           row.Renewed,
+			Retries_Scheduled: row.RetriesScheduled,
+			Challenged:        row.Challenged,
+			Expired:           row.Expired,
+			Errors:            row.Errors,
+			Error_Message:     row.ErrorMessage.String,
+			Started_At:        row.StartedAt,
+			Finished_At:       nullTimePtr(row.FinishedAt),
+		})
+	}
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return runs, metadata, nil
+}
+
+// GetBillingRunByID() returns a billing run's report along with what it did with each
+// subscription.
+func (m BillingModel) GetBillingRunByID(runID int64) (*BillingRun, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	row, err := m.DB.GetBillingRunByID(ctx, runID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrBillingRunNotFound
+		default:
+			return nil, err
+		}
+	}
+	run := &BillingRun{
+		ID:                row.ID,
+		Trigger:           row.Trigger,
+		Status:            row.Status,
+		Processed:         row.Processed,
+		Renewed:           row.Renewed,
+		Retries_Scheduled: row.RetriesScheduled,
+		Challenged:        row.Challenged,
+		Expired:           row.Expired,
+		Errors:            row.Errors,
+		Error_Message:     row.ErrorMessage.String,
+		Started_At:        row.StartedAt,
+		Finished_At:       nullTimePtr(row.FinishedAt),
+		Items:             []*BillingRunItem{},
+	}
+	items, err := m.DB.GetBillingRunItems(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		run.Items = append(run.Items, &BillingRunItem{
+			ID:              item.ID,
+			Run_ID:          item.RunID,
+			Subscription_ID: item.SubscriptionID,
+			User_ID:         item.UserID,
+			User_Name:       item.Name,
+			User_Email:      item.Email,
+			Attempt:         item.Attempt,
+			Outcome:         item.Outcome,
+			Message:         item.Message,
+			Next_Attempt_At: nullTimePtr(item.NextAttemptAt),
+			Created_At:      item.CreatedAt,
+		})
+	}
+	return run, nil
+}
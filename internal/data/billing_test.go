@@ -0,0 +1,75 @@
+package data
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseRetryDays(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    []int
+		wantErr bool
+	}{
+		{name: "Default Schedule", value: "1,3,7", want: []int{1, 3, 7}},
+		{name: "Spaces", value: " 2, 5 ", want: []int{2, 5}},
+		{name: "No Retries", value: "", want: []int{}},
+		{name: "Not A Number", value: "1,three", wantErr: true},
+		{name: "Zero", value: "0,3", wantErr: true},
+		{name: "Out Of Order", value: "3,1", wantErr: true},
+		{name: "Repeated", value: "1,1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRetryDays(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Got error:%v But Wanted error:%t", err, tt.wantErr)
+			}
+			if !tt.wantErr && !slices.Equal(got, tt.want) {
+				t.Errorf("Got:%v But Wanted:%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBillingScheduleNextAttempt(t *testing.T) {
+	end := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	schedule := BillingSchedule{Retry_Days: []int{1, 3, 7}, Grace_Period: 7 * 24 * time.Hour}
+	tests := []struct {
+		name     string
+		schedule BillingSchedule
+		attempts int32
+		want     time.Time
+		wantOK   bool
+	}{
+		{name: "First Failure", schedule: schedule, attempts: 1, want: end.AddDate(0, 0, 1), wantOK: true},
+		{name: "Second Failure", schedule: schedule, attempts: 2, want: end.AddDate(0, 0, 3), wantOK: true},
+		{name: "Third Failure", schedule: schedule, attempts: 3, want: end.AddDate(0, 0, 7), wantOK: true},
+		{name: "Out Of Retries", schedule: schedule, attempts: 4},
+		{name: "No Attempts Yet", schedule: schedule, attempts: 0},
+		{name: "Retry After Grace", schedule: BillingSchedule{Retry_Days: []int{1, 10}, Grace_Period: 7 * 24 * time.Hour}, attempts: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.schedule.NextAttempt(end, tt.attempts)
+			if ok != tt.wantOK || !got.Equal(tt.want) {
+				t.Errorf("Got:%v,%t But Wanted:%v,%t", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestBillingRunRecord(t *testing.T) {
+	run := &BillingRun{}
+	for _, outcome := range []string{BillingOutcomeRenewed, BillingOutcomeRenewed, BillingOutcomeRetryScheduled,
+		BillingOutcomeChallenged, BillingOutcomeExpired, BillingOutcomeError} {
+		run.Record(&BillingRunItem{Outcome: outcome})
+	}
+	want := BillingRun{Processed: 6, Renewed: 2, Retries_Scheduled: 1, Challenged: 1, Expired: 1, Errors: 1}
+	if run.Processed != want.Processed || run.Renewed != want.Renewed || run.Retries_Scheduled != want.Retries_Scheduled ||
+		run.Challenged != want.Challenged || run.Expired != want.Expired || run.Errors != want.Errors {
+		t.Errorf("Got:%+v But Wanted:%+v", *run, want)
+	}
+}
@@ -1,9 +1,14 @@
 package data
 
 import (
+	"cmp"
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/blue-davinci/aggregate/internal/database"
@@ -13,6 +18,25 @@ import (
 
 var ErrCommentNotFound = errors.New("comment not found")
 
+// Comment thread sort orders
+const (
+	CommentSortNewest = "newest"
+	CommentSortOldest = "oldest"
+	CommentSortTop    = "top"
+	// DefaultCommentMaxDepth is how many levels a thread may have, top level comments included
+	DefaultCommentMaxDepth = 5
+)
+
+// Comment reactions. A downvote takes a point off a comment's score while every other
+// reaction adds one.
+const (
+	CommentReactionUpvote     = "upvote"
+	CommentReactionDownvote   = "downvote"
+	CommentReactionHeart      = "heart"
+	CommentReactionLaugh      = "laugh"
+	CommentReactionInsightful = "insightful"
+)
+
 type CommentsModel struct {
 	DB *database.Queries
 }
@@ -20,8 +44,48 @@ type CommentsModel struct {
 // This PostComment struct represent the data we will send back to the user
 // We include the username of the user who made the comment and the comment itself
 type PostComment struct {
-	Comment   Comment `json:"comment"`
-	User_Name string  `json:"user_name"`
+	Comment     Comment `json:"comment"`
+	User_Name   string  `json:"user_name"`
+	User_Handle string  `json:"user_handle"`
+}
+
+// CommentThread is a comment within a thread. Depth is relative to the comments the
+// thread was loaded from, Reply_Count holds the number of direct replies whether or not
+// they were loaded and Has_More_Replies tells the client to load the rest.
+// Reactions holds the count of each reaction the comment has and User_Reaction is the
+// requesting user's own reaction, empty if they haven't reacted.
+type CommentThread struct {
+	PostComment
+	Depth            int              `json:"depth"`
+	Reply_Count      int64            `json:"reply_count"`
+	Score            int64            `json:"score"`
+	Reactions        map[string]int64 `json:"reactions"`
+	User_Reaction    string           `json:"user_reaction"`
+	Has_More_Replies bool             `json:"has_more_replies"`
+	Replies          []*CommentThread `json:"replies"`
+}
+
+// CommentReaction is a single user's reaction to a comment. Users have one reaction per
+// comment, reacting again replaces it.
+type CommentReaction struct {
+	Comment_ID uuid.UUID `json:"comment_id"`
+	User_ID    int64     `json:"-"`
+	Reaction   string    `json:"reaction"`
+	Created_At time.Time `json:"created_at"`
+	Updated_At time.Time `json:"updated_at"`
+}
+
+// CommentReactionSummary is a comment's reaction counts together with its score
+type CommentReactionSummary struct {
+	Score     int64            `json:"score"`
+	Reactions map[string]int64 `json:"reactions"`
+}
+
+// CommentParent holds what we need to know about a comment being replied to
+type CommentParent struct {
+	ID      uuid.UUID
+	Post_ID uuid.UUID
+	Depth   int
 }
 
 // The Comment struct represents what our what our comments look like
@@ -29,19 +93,28 @@ type PostComment struct {
 // Not IsEditable is a field that will be used to determine if a comment is editable
 // for a specific user. If this user sending this request is the owner of the comment
 // then isEditable will be true otherwise it'll be false.
+// Mentions holds the users mentioned in the comment text. IsEdited and Edited_At tell
+// clients whether and when the author last edited the comment.
 type Comment struct {
-	ID                uuid.UUID     `json:"id"`
-	Post_ID           uuid.UUID     `json:"post_id"`
-	User_ID           int64         `json:"user_id"`
-	Parent_Comment_ID uuid.NullUUID `json:"parent_comment_id"`
-	Comment_Text      string        `json:"comment_text"`
-	Created_At        time.Time     `json:"created_at"`
-	Updated_At        time.Time     `json:"updated_at"`
-	IsEditable        bool          `json:"is_editable"`
-	Version           int32         `json:"version"`
-}
-
-func ValidateComment(v *validator.Validator, comment *Comment) {
+	ID                uuid.UUID        `json:"id"`
+	Post_ID           uuid.UUID        `json:"post_id"`
+	User_ID           int64            `json:"user_id"`
+	Parent_Comment_ID uuid.NullUUID    `json:"parent_comment_id"`
+	Comment_Text      string           `json:"comment_text"`
+	Created_At        time.Time        `json:"created_at"`
+	Updated_At        time.Time        `json:"updated_at"`
+	IsEditable        bool             `json:"is_editable"`
+	IsEdited          bool             `json:"is_edited"`
+	Edited_At         *time.Time       `json:"edited_at"`
+	Version           int32            `json:"version"`
+	Status            string           `json:"status"`
+	Mentions          []CommentMention `json:"mentions"`
+}
+
+// ValidateComment() validates a new comment. parent is the comment being replied to, or
+// nil for a top level comment. A reply must be on the same post as its parent and may not
+// nest deeper than maxDepth levels.
+func ValidateComment(v *validator.Validator, comment *Comment, parent *CommentParent, maxDepth int) {
 	// Check that the post ID is provided
 	v.Check(comment.Post_ID != uuid.Nil, "post_id", "must be provided")
 	_, isvalid := ValidateUUID(comment.Post_ID.String())
@@ -49,6 +122,17 @@ func ValidateComment(v *validator.Validator, comment *Comment) {
 	// Check that the comment text is provided and is not more than 500 bytes long
 	v.Check(comment.Comment_Text != "", "comment_text", "must be provided")
 	v.Check(len(comment.Comment_Text) <= 500, "comment_text", "must not be more than 500 bytes long")
+	if parent != nil {
+		v.Check(parent.Post_ID == comment.Post_ID, "parent_comment_id", "must belong to the same post")
+		v.Check(parent.Depth+1 < maxDepth, "parent_comment_id", fmt.Sprintf("replies can not be nested more than %d levels deep", maxDepth))
+	}
+}
+
+func ValidateCommentReaction(v *validator.Validator, reaction *CommentReaction) {
+	v.Check(reaction.Comment_ID != uuid.Nil, "comment_id", "must be provided")
+	v.Check(reaction.Reaction != "", "reaction", "must be provided")
+	v.Check(validator.PermittedValue(reaction.Reaction, CommentReactionUpvote, CommentReactionDownvote, CommentReactionHeart,
+		CommentReactionLaugh, CommentReactionInsightful), "reaction", "must be one of upvote, downvote, heart, laugh or insightful")
 }
 
 func ValidateUpdateComment(v *validator.Validator, comment *Comment) {
@@ -65,10 +149,15 @@ func ValidateUpdateComment(v *validator.Validator, comment *Comment) {
 
 // CreateComment() creates a new comment in the database, we get the
 // post id, user id and parent comment id from our comment and save it.
+// Comments are published straight away unless their status is already set,
+// which is how screening holds back flagged comments as pending.
 func (m CommentsModel) CreateComment(comment *Comment) error {
 	// create our timeout context. All of them will just be 5 seconds
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
+	if comment.Status == "" {
+		comment.Status = CommentStatusVisible
+	}
 	// Insert the comment into the database
 	queryresult, err := m.DB.CreateComments(ctx, database.CreateCommentsParams{
 		ID:              comment.ID,
@@ -76,6 +165,7 @@ func (m CommentsModel) CreateComment(comment *Comment) error {
 		UserID:          comment.User_ID,
 		ParentCommentID: comment.Parent_Comment_ID,
 		CommentText:     comment.Comment_Text,
+		Status:          comment.Status,
 	})
 
 	if err != nil {
@@ -85,6 +175,7 @@ func (m CommentsModel) CreateComment(comment *Comment) error {
 	comment.Created_At = queryresult.CreatedAt
 	comment.Updated_At = queryresult.UpdatedAt
 	comment.Version = queryresult.Version
+	comment.Status = queryresult.Status
 	// Nowwe need to save the comment notification
 	err = m.CreateCommentNotification(comment.User_ID, comment.ID, comment.Post_ID)
 	if err != nil {
@@ -96,9 +187,10 @@ func (m CommentsModel) CreateComment(comment *Comment) error {
 
 // UpdateUserComment() updates a comment based on the updated comment text
 // as well as the user id and the version of the comment. This is to ensure
-// that the user is updating the correct comment.
+// that the user is updating the correct comment. The text being replaced is kept
+// as a revision and comments older than editWindow can't be updated at all.
 // We return the updated comment's version and an error if there is one.
-func (m CommentsModel) UpdateUserComment(comment *Comment, userID int64) (int32, error) {
+func (m CommentsModel) UpdateUserComment(comment *Comment, userID int64, editWindow time.Duration) (int32, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	// Update the comment in the database
@@ -107,6 +199,8 @@ func (m CommentsModel) UpdateUserComment(comment *Comment, userID int64) (int32,
 		CommentText: comment.Comment_Text,
 		UserID:      userID,
 		Version:     comment.Version,
+		CreatedAt:   CommentEditCutoff(editWindow, time.Now()),
+		Status:      comment.Status,
 	})
 	if err != nil {
 		switch {
@@ -116,25 +210,38 @@ func (m CommentsModel) UpdateUserComment(comment *Comment, userID int64) (int32,
 			return 0, err
 		}
 	}
-	return queryresult, nil
+	comment.IsEdited = queryresult.EditedAt.Valid
+	comment.Edited_At = nullTimeToTime(queryresult.EditedAt)
+	return queryresult.Version, nil
 }
 
+// DeleteComment() deletes a user's comment. Comments that have replies are soft deleted
+// instead so that the thread keeps its structure, they show up as "[removed]".
 func (m CommentsModel) DeleteComment(commentID uuid.UUID, userID int64) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	err := m.DB.DeleteComment(ctx, database.DeleteCommentParams{
+	deleted, err := m.DB.DeleteComment(ctx, database.DeleteCommentParams{
 		ID:     commentID,
 		UserID: userID,
 	})
 	if err != nil {
-		switch {
-		case errors.Is(err, sql.ErrNoRows):
-			return ErrCommentNotFound
-		default:
-			return err
-		}
+		return err
 	}
-	return nil
+	if deleted > 0 {
+		return nil
+	}
+	removed, err := m.DB.RemoveUserComment(ctx, database.RemoveUserCommentParams{
+		ID:     commentID,
+		UserID: userID,
+	})
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return ErrCommentNotFound
+	}
+	// the text is gone so its earlier versions go with it
+	return m.DB.DeleteCommentRevisions(ctx, commentID)
 }
 
 // GetCommentByID() returns a specific comment based on the comment id
@@ -166,29 +273,41 @@ func (m CommentsModel) GetCommentByID(id uuid.UUID, userID int64) (*Comment, err
 		Comment_Text:      row.CommentText,
 		Created_At:        row.CreatedAt,
 		Version:           row.Version,
+		Status:            row.Status,
+		IsEdited:          row.EditedAt.Valid,
+		Edited_At:         nullTimeToTime(row.EditedAt),
 	}
 	return comment, nil
 }
 
-// GetCommentsForPost() returns all comments for a specific post
-// We return a PostComment which includes the comment and the username of the user
-// who made the comment. Helps the  frontend to do its thing.
-func (m CommentsModel) GetCommentsForPost(id uuid.UUID, userID int64) ([]*PostComment, error) {
+// GetCommentsForPost() returns a page of comment threads for a specific post. Threads
+// start at the top level comments, or at the direct replies of parentID when one is given
+// which is how "load more replies" works. Each thread is walked down to maxDepth levels
+// using a recursive CTE and is returned as a nested tree sorted by sort at every level.
+// Comments posted more than editWindow ago are no longer editable.
+func (m CommentsModel) GetCommentsForPost(id uuid.UUID, userID int64, parentID uuid.UUID, maxDepth int, sort string, editWindow time.Duration, filters Filters) ([]*CommentThread, Metadata, error) {
 	// create our timeout context. All of them will just be 5 seconds
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	// Get the comments from the backend for a specific post
 	rows, err := m.DB.GetCommentsForPost(ctx, database.GetCommentsForPostParams{
-		PostID: id,
-		UserID: userID,
+		PostID:  id,
+		UserID:  userID,
+		Column3: parentID,
+		Column4: int32(maxDepth),
+		Column5: sort,
+		Limit:   int32(filters.limit()),
+		Offset:  int32(filters.offset()),
+		Column8: CommentEditCutoff(editWindow, time.Now()),
 	})
 	if err != nil {
-		return nil, err
+		return nil, Metadata{}, err
 	}
-	comments := []*PostComment{}
+	comments := []*CommentThread{}
+	totalRecords := 0
 	for _, row := range rows {
-		var postComment PostComment
-		comment := &Comment{
+		totalRecords = int(row.TotalRecords)
+		comment := Comment{
 			ID:                row.ID,
 			Post_ID:           row.PostID,
 			User_ID:           row.UserID,
@@ -196,13 +315,183 @@ func (m CommentsModel) GetCommentsForPost(id uuid.UUID, userID int64) ([]*PostCo
 			Comment_Text:      row.CommentText,
 			Created_At:        row.CreatedAt,
 			IsEditable:        row.Iseditable,
+			IsEdited:          row.EditedAt.Valid,
+			Edited_At:         nullTimeToTime(row.EditedAt),
 			Version:           row.Version,
+			Status:            row.Status,
+		}
+		reactions := map[string]int64{}
+		if err := json.Unmarshal(row.ReactionCounts, &reactions); err != nil {
+			return nil, Metadata{}, err
+		}
+		mentions := []CommentMention{}
+		if err := json.Unmarshal(row.Mentions, &mentions); err != nil {
+			return nil, Metadata{}, err
+		}
+		comment.Mentions = MentionHints(comment.Comment_Text, mentions)
+		comments = append(comments, &CommentThread{
+			PostComment:   PostComment{Comment: comment, User_Name: row.UserName, User_Handle: row.UserHandle},
+			Depth:         int(row.Depth),
+			Reply_Count:   row.ReplyCount,
+			Score:         row.Score,
+			Reactions:     reactions,
+			User_Reaction: row.UserReaction,
+		})
+	}
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return BuildCommentTree(comments, sort), metadata, nil
+}
+
+// GetCommentParent() returns the post and depth of a comment that is being replied to
+func (m CommentsModel) GetCommentParent(parentID uuid.UUID) (*CommentParent, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	row, err := m.DB.GetCommentDepth(ctx, parentID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrCommentNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &CommentParent{
+		ID:      row.ID,
+		Post_ID: row.PostID,
+		Depth:   int(row.Depth),
+	}, nil
+}
+
+// BuildCommentTree() nests a flat list of thread comments under their parents. Comments
+// whose parent is not in the list are the roots. Roots and every level of replies are
+// sorted the same way the page was, and Has_More_Replies flags comments with replies we
+// did not load, either because of the depth limit or because they are on another page.
+func BuildCommentTree(comments []*CommentThread, sort string) []*CommentThread {
+	index := make(map[uuid.UUID]*CommentThread, len(comments))
+	for _, comment := range comments {
+		comment.Replies = []*CommentThread{}
+		index[comment.Comment.ID] = comment
+	}
+	roots := []*CommentThread{}
+	for _, comment := range comments {
+		parent, ok := index[comment.Comment.Parent_Comment_ID.UUID]
+		if comment.Comment.Parent_Comment_ID.Valid && ok {
+			parent.Replies = append(parent.Replies, comment)
+			continue
+		}
+		roots = append(roots, comment)
+	}
+	sortCommentThreads(roots, sort)
+	return roots
+}
+
+// sortCommentThreads() sorts a level of the tree and then each of its reply levels
+func sortCommentThreads(comments []*CommentThread, sort string) {
+	slices.SortStableFunc(comments, func(a, b *CommentThread) int {
+		switch sort {
+		case CommentSortOldest:
+			return a.Comment.Created_At.Compare(b.Comment.Created_At)
+		case CommentSortTop:
+			if a.Score != b.Score {
+				return cmp.Compare(b.Score, a.Score)
+			}
+			if a.Reply_Count != b.Reply_Count {
+				return cmp.Compare(b.Reply_Count, a.Reply_Count)
+			}
+		}
+		if c := b.Comment.Created_At.Compare(a.Comment.Created_At); c != 0 {
+			return c
+		}
+		return strings.Compare(b.Comment.ID.String(), a.Comment.ID.String())
+	})
+	for _, comment := range comments {
+		comment.Has_More_Replies = int64(len(comment.Replies)) < comment.Reply_Count
+		sortCommentThreads(comment.Replies, sort)
+	}
+}
+
+// GetCommentOwner() returns the author of a comment together with the post it is on
+func (m CommentsModel) GetCommentOwner(commentID uuid.UUID) (int64, uuid.UUID, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	row, err := m.DB.GetCommentOwner(ctx, commentID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, uuid.Nil, ErrCommentNotFound
+		default:
+			return 0, uuid.Nil, err
+		}
+	}
+	return row.UserID, row.PostID, nil
+}
+
+// ReactToComment() sets the user's reaction to a comment, replacing any reaction they
+// already had. The returned bool is true when this is the user's first reaction to the
+// comment rather than a change of reaction.
+func (m CommentsModel) ReactToComment(reaction *CommentReaction) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	row, err := m.DB.UpsertCommentReaction(ctx, database.UpsertCommentReactionParams{
+		CommentID: reaction.Comment_ID,
+		UserID:    reaction.User_ID,
+		Reaction:  reaction.Reaction,
+	})
+	if err != nil {
+		return false, err
+	}
+	reaction.Created_At = row.CreatedAt
+	reaction.Updated_At = row.UpdatedAt
+	// both timestamps come from the same NOW() on insert, an update moves updated_at on
+	return row.CreatedAt.Equal(row.UpdatedAt), nil
+}
+
+// RemoveCommentReaction() removes the user's reaction from a comment
+func (m CommentsModel) RemoveCommentReaction(commentID uuid.UUID, userID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := m.DB.DeleteCommentReaction(ctx, database.DeleteCommentReactionParams{
+		CommentID: commentID,
+		UserID:    userID,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrCommentNotFound
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// GetCommentReactionSummary() returns the reaction counts and score of a single comment
+func (m CommentsModel) GetCommentReactionSummary(commentID uuid.UUID) (*CommentReactionSummary, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := m.DB.GetCommentReactionCounts(ctx, commentID)
+	if err != nil {
+		return nil, err
+	}
+	summary := &CommentReactionSummary{Reactions: map[string]int64{}}
+	for _, row := range rows {
+		summary.Reactions[row.Reaction] = row.Total
+	}
+	summary.Score = CommentReactionScore(summary.Reactions)
+	return summary, nil
+}
+
+// CommentReactionScore() works out a comment's score from its reaction counts
+func CommentReactionScore(reactions map[string]int64) int64 {
+	var score int64
+	for reaction, total := range reactions {
+		if reaction == CommentReactionDownvote {
+			score -= total
+			continue
 		}
-		username := row.UserName
-		postComment = PostComment{Comment: *comment, User_Name: username}
-		comments = append(comments, &postComment)
+		score += total
 	}
-	return comments, nil
+	return score
 }
 
 // CreateCommentNotification() Creates a new comment notification in the database
@@ -0,0 +1,143 @@
+package data
+
+import (
+	"testing"
+	"time"
+
+	"github.com/blue-davinci/aggregate/internal/validator"
+	"github.com/google/uuid"
+)
+
+func TestBuildCommentTree(t *testing.T) {
+	created := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+	comment := func(id, parent uuid.UUID, minutes int, replies, score int64) *CommentThread {
+		return &CommentThread{
+			PostComment: PostComment{Comment: Comment{
+				ID:                id,
+				Parent_Comment_ID: uuid.NullUUID{UUID: parent, Valid: parent != uuid.Nil},
+				Created_At:        created.Add(time.Duration(minutes) * time.Minute),
+			}},
+			Reply_Count: replies,
+			Score:       score,
+		}
+	}
+	first, second, reply, nested := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	rows := func() []*CommentThread {
+		return []*CommentThread{
+			comment(first, uuid.Nil, 0, 1, 0),
+			comment(second, uuid.Nil, 10, 0, 3),
+			comment(reply, first, 5, 2, -1),
+			comment(nested, reply, 6, 0, 0),
+		}
+	}
+	tests := []struct {
+		name      string
+		sort      string
+		wantRoots []uuid.UUID
+	}{
+		{name: "Newest", sort: CommentSortNewest, wantRoots: []uuid.UUID{second, first}},
+		{name: "Oldest", sort: CommentSortOldest, wantRoots: []uuid.UUID{first, second}},
+		{name: "Top", sort: CommentSortTop, wantRoots: []uuid.UUID{second, first}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := BuildCommentTree(rows(), tt.sort)
+			if len(tree) != len(tt.wantRoots) {
+				t.Fatalf("Got:%d roots But Wanted:%d", len(tree), len(tt.wantRoots))
+			}
+			for i, root := range tree {
+				if root.Comment.ID != tt.wantRoots[i] {
+					t.Errorf("Got root:%v But Wanted:%v", root.Comment.ID, tt.wantRoots[i])
+				}
+			}
+			var firstThread *CommentThread
+			for _, root := range tree {
+				if root.Comment.ID == first {
+					firstThread = root
+				}
+			}
+			if len(firstThread.Replies) != 1 || firstThread.Replies[0].Comment.ID != reply {
+				t.Fatalf("Wanted the reply nested under its parent, Got:%v", firstThread.Replies)
+			}
+			replyThread := firstThread.Replies[0]
+			if len(replyThread.Replies) != 1 || replyThread.Replies[0].Comment.ID != nested {
+				t.Fatalf("Wanted the nested reply under the reply, Got:%v", replyThread.Replies)
+			}
+			// the reply has 2 replies but only one was loaded
+			if firstThread.Has_More_Replies || !replyThread.Has_More_Replies {
+				t.Errorf("Got Has_More_Replies first:%v reply:%v But Wanted false and true", firstThread.Has_More_Replies, replyThread.Has_More_Replies)
+			}
+		})
+	}
+}
+
+func TestValidateComment(t *testing.T) {
+	postID := uuid.New()
+	tests := []struct {
+		name     string
+		parent   *CommentParent
+		wantErrs []string
+	}{
+		{name: "Top Level", parent: nil},
+		{name: "Reply", parent: &CommentParent{ID: uuid.New(), Post_ID: postID, Depth: 0}},
+		{name: "Deepest Reply", parent: &CommentParent{ID: uuid.New(), Post_ID: postID, Depth: 3}},
+		{name: "Too Deep", parent: &CommentParent{ID: uuid.New(), Post_ID: postID, Depth: 4}, wantErrs: []string{"parent_comment_id"}},
+		{name: "Other Post", parent: &CommentParent{ID: uuid.New(), Post_ID: uuid.New(), Depth: 0}, wantErrs: []string{"parent_comment_id"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			comment := &Comment{ID: uuid.New(), Post_ID: postID, Comment_Text: "Nice read"}
+			ValidateComment(v, comment, tt.parent, 5)
+			if len(v.Errors) != len(tt.wantErrs) {
+				t.Fatalf("Got:%v But Wanted errors for:%v", v.Errors, tt.wantErrs)
+			}
+			for _, key := range tt.wantErrs {
+				if _, ok := v.Errors[key]; !ok {
+					t.Errorf("Got:%v But Wanted an error for:%v", v.Errors, key)
+				}
+			}
+		})
+	}
+}
+
+func TestCommentReactionScore(t *testing.T) {
+	tests := []struct {
+		name      string
+		reactions map[string]int64
+		want      int64
+	}{
+		{name: "No Reactions", reactions: map[string]int64{}, want: 0},
+		{name: "Votes", reactions: map[string]int64{CommentReactionUpvote: 4, CommentReactionDownvote: 6}, want: -2},
+		{name: "Mixed", reactions: map[string]int64{CommentReactionUpvote: 2, CommentReactionHeart: 1, CommentReactionInsightful: 3, CommentReactionDownvote: 1}, want: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CommentReactionScore(tt.reactions); got != tt.want {
+				t.Errorf("Got:%d But Wanted:%d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateCommentReaction(t *testing.T) {
+	tests := []struct {
+		name     string
+		reaction string
+		wantErr  bool
+	}{
+		{name: "Upvote", reaction: CommentReactionUpvote},
+		{name: "Emoji", reaction: CommentReactionLaugh},
+		{name: "Empty", reaction: "", wantErr: true},
+		{name: "Unknown", reaction: "angry", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateCommentReaction(v, &CommentReaction{Comment_ID: uuid.New(), Reaction: tt.reaction})
+			if _, got := v.Errors["reaction"]; got != tt.wantErr {
+				t.Errorf("Got error:%v But Wanted:%v (%v)", got, tt.wantErr, v.Errors)
+			}
+		})
+	}
+}
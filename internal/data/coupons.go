@@ -0,0 +1,519 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/blue-davinci/aggregate/internal/database"
+	"github.com/blue-davinci/aggregate/internal/validator"
+	"github.com/google/uuid"
+)
+
+const (
+	CouponTypePercent = "percent"
+	CouponTypeFixed   = "fixed"
+)
+
+var (
+	CouponStatusActive       = "active"
+	CouponStatusInactive     = "inactive"
+	RedemptionStatusPending  = "pending"
+	RedemptionStatusRedeemed = "redeemed"
+	RedemptionStatusReleased = "released"
+)
+
+var (
+	ErrCouponNotFound           = errors.New("coupon not found")
+	ErrDuplicateCouponCode      = errors.New("duplicate coupon code")
+	ErrCouponRedemptionNotFound = errors.New("coupon redemption not found")
+	ErrCouponAlreadyRedeemed    = errors.New("coupon already redeemed")
+	ErrCouponFullyRedeemed      = errors.New("coupon fully redeemed")
+)
+
+// CouponCodeRX is what a promo code may look like, letters, digits, - and _
+var CouponCodeRX = regexp.MustCompile(`^[A-Z0-9_-]{3,40}$`)
+
+type CouponsModel struct {
+	DB *database.Queries
+}
+
+// Coupon is a promo code that discounts the first payment of a subscription. A percent
+// coupon takes Percent_Off of any price, a fixed one takes Amount_Off off prices in its
+// currency. A Max_Redemptions of 0 and no Plan_IDs leave the coupon unlimited.
+// Pending_Redemptions are held by checkouts and trials that weren't paid yet, they count
+// against Max_Redemptions until they are redeemed or released.
+type Coupon struct {
+	ID                  int64      `json:"id"`
+	Code                string     `json:"code"`
+	Description         string     `json:"description"`
+	Discount_Type       string     `json:"discount_type"`
+	Percent_Off         int32      `json:"percent_off,omitempty"`
+	Amount_Off          *Money     `json:"amount_off,omitempty"`
+	Max_Redemptions     int32      `json:"max_redemptions"`
+	Times_Redeemed      int32      `json:"times_redeemed"`
+	Pending_Redemptions int32      `json:"pending_redemptions"`
+	Expires_At          *time.Time `json:"expires_at"`
+	Plan_IDs            []int32    `json:"plan_ids"`
+	Status              string     `json:"status"`
+	Created_At          time.Time  `json:"created_at"`
+	Updated_At          time.Time  `json:"updated_at"`
+	Version             int32      `json:"version"`
+}
+
+// CouponRedemption is the use of a coupon on a payment. It is pending until the payment
+// goes through, the discount is what it took off. A checkout's redemption is released
+// when it isn't paid by Expires_At, a trial's when the trial ends unpaid.
+type CouponRedemption struct {
+	ID              int64      `json:"id"`
+	Coupon_ID       int64      `json:"coupon_id"`
+	Code            string     `json:"code"`
+	User_ID         int64      `json:"user_id"`
+	Plan_ID         int32      `json:"plan_id"`
+	Subscription_ID uuid.UUID  `json:"subscription_id"`
+	Reference       string     `json:"reference"`
+	Discount        Money      `json:"discount"`
+	Status          string     `json:"status"`
+	Created_At      time.Time  `json:"created_at"`
+	Redeemed_At     *time.Time `json:"redeemed_at"`
+	Expires_At      *time.Time `json:"expires_at"`
+}
+
+// NormalizeCouponCode() is how a code is saved and looked up, codes aren't case sensitive
+func NormalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// AppliesTo() reports whether the coupon can be used on a plan
+func (c *Coupon) AppliesTo(planID int32) bool {
+	if len(c.Plan_IDs) == 0 {
+		return true
+	}
+	for _, id := range c.Plan_IDs {
+		if id == planID {
+			return true
+		}
+	}
+	return false
+}
+
+// Discount() is what the coupon takes off a price. Percentages round down to a whole
+// minor unit and a fixed amount is never more than the price nor taken off a price in
+// another currency.
+func (c *Coupon) Discount(price Money) Money {
+	switch c.Discount_Type {
+	case CouponTypePercent:
+		return NewMoney(price.Amount*int64(c.Percent_Off)/100, price.Currency)
+	case CouponTypeFixed:
+		if c.Amount_Off == nil || c.Amount_Off.Currency != price.Currency {
+			return NewMoney(0, price.Currency)
+		}
+		return NewMoney(min(c.Amount_Off.Amount, price.Amount), price.Currency)
+	}
+	return NewMoney(0, price.Currency)
+}
+
+// ValidateCoupon() checks a coupon an admin creates or edits
+func ValidateCoupon(v *validator.Validator, coupon *Coupon) {
+	v.Check(coupon.Code != "", "code", "must be provided")
+	v.Check(validator.Matches(coupon.Code, CouponCodeRX), "code", "must be 3 to 40 letters, digits, - or _")
+	v.Check(len(coupon.Description) <= 500, "description", "must not be more than 500 characters")
+	switch coupon.Discount_Type {
+	case CouponTypePercent:
+		v.Check(coupon.Percent_Off >= 1 && coupon.Percent_Off <= 99, "percent_off", "must be between 1 and 99")
+		v.Check(coupon.Amount_Off == nil, "amount_off", "must not be provided for a percent coupon")
+	case CouponTypeFixed:
+		v.Check(coupon.Percent_Off == 0, "percent_off", "must not be provided for a fixed coupon")
+		if v.Check(coupon.Amount_Off != nil, "amount_off", "must be provided"); coupon.Amount_Off != nil {
+			ValidateMoney(v, "amount_off", *coupon.Amount_Off)
+		}
+	default:
+		v.AddError("discount_type", "must be either 'percent' or 'fixed'")
+	}
+	v.Check(coupon.Max_Redemptions >= 0, "max_redemptions", "must not be negative")
+	for _, planID := range coupon.Plan_IDs {
+		v.Check(planID > 0, "plan_ids", "must be valid plan IDs")
+	}
+	v.Check(validator.Unique(coupon.Plan_IDs), "plan_ids", "must not contain duplicate values")
+	v.Check(coupon.Status == CouponStatusActive || coupon.Status == CouponStatusInactive, "status", "must be either 'active' or 'inactive'")
+}
+
+// ValidateCouponRedemption() checks that a user can take a coupon off the price of a
+// plan right now. The discount must leave something to pay, a first period for free is
+// what trials are for.
+func ValidateCouponRedemption(v *validator.Validator, coupon *Coupon, planID int32, price Money, now time.Time) {
+	v.Check(coupon.Status == CouponStatusActive, "promo_code", "is not active")
+	v.Check(coupon.Expires_At == nil || now.Before(*coupon.Expires_At), "promo_code", "has expired")
+	v.Check(coupon.Max_Redemptions == 0 || coupon.Times_Redeemed+coupon.Pending_Redemptions < coupon.Max_Redemptions, "promo_code", "has been fully redeemed")
+	v.Check(coupon.AppliesTo(planID), "promo_code", "does not apply to this plan")
+	if coupon.Discount_Type == CouponTypeFixed && coupon.Amount_Off != nil {
+		v.Check(coupon.Amount_Off.Currency == price.Currency, "promo_code", "can't be used in this currency")
+	}
+	v.Check(coupon.Discount(price).Amount < price.Amount, "promo_code", "can't discount the whole price")
+}
+
+// couponFromRow() builds a coupon from any of the queries that select a whole coupon
+func couponFromRow(row database.Coupon) *Coupon {
+	coupon := &Coupon{
+		ID:                  row.ID,
+		Code:                row.Code,
+		Description:         row.Description,
+		Discount_Type:       row.DiscountType,
+		Percent_Off:         row.PercentOff.Int32,
+		Max_Redemptions:     row.MaxRedemptions.Int32,
+		Times_Redeemed:      row.TimesRedeemed,
+		Pending_Redemptions: row.PendingRedemptions,
+		Expires_At:          nullTimePtr(row.ExpiresAt),
+		Plan_IDs:            row.PlanIds,
+		Status:              row.Status,
+		Created_At:          row.CreatedAt,
+		Updated_At:          row.UpdatedAt,
+		Version:             row.Version,
+	}
+	if coupon.Plan_IDs == nil {
+		coupon.Plan_IDs = []int32{}
+	}
+	if row.AmountOff.Valid {
+		amountOff := NewMoney(row.AmountOff.Int64, row.Currency.String)
+		coupon.Amount_Off = &amountOff
+	}
+	return coupon
+}
+
+func nullTimeFromPtr(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: *t, Valid: true}
+}
+
+// CreateCoupon() saves a new coupon, its code must not be in use.
+func (m CouponsModel) CreateCoupon(coupon *Coupon) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	params := database.CreateCouponParams{
+		Code:           coupon.Code,
+		Description:    coupon.Description,
+		DiscountType:   coupon.Discount_Type,
+		PercentOff:     sql.NullInt32{Int32: coupon.Percent_Off, Valid: coupon.Percent_Off != 0},
+		MaxRedemptions: sql.NullInt32{Int32: coupon.Max_Redemptions, Valid: coupon.Max_Redemptions != 0},
+		ExpiresAt:      nullTimeFromPtr(coupon.Expires_At),
+		PlanIds:        coupon.Plan_IDs,
+		Status:         coupon.Status,
+	}
+	if coupon.Amount_Off != nil {
+		params.AmountOff = sql.NullInt64{Int64: coupon.Amount_Off.Amount, Valid: true}
+		params.Currency = sql.NullString{String: coupon.Amount_Off.Currency, Valid: true}
+	}
+	row, err := m.DB.CreateCoupon(ctx, params)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "coupons_code_key"`:
+			return ErrDuplicateCouponCode
+		default:
+			return err
+		}
+	}
+	coupon.ID = row.ID
+	coupon.Times_Redeemed = row.TimesRedeemed
+	coupon.Created_At = row.CreatedAt
+	coupon.Updated_At = row.UpdatedAt
+	coupon.Version = row.Version
+	return nil
+}
+
+// GetCouponByID() returns a coupon
+func (m CouponsModel) GetCouponByID(couponID int64) (*Coupon, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	row, err := m.DB.GetCouponByID(ctx, couponID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrCouponNotFound
+		default:
+			return nil, err
+		}
+	}
+	return couponFromRow(row), nil
+}
+
+// GetCouponByCode() returns the coupon a promo code is for
+func (m CouponsModel) GetCouponByCode(code string) (*Coupon, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	row, err := m.DB.GetCouponByCode(ctx, NormalizeCouponCode(code))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrCouponNotFound
+		default:
+			return nil, err
+		}
+	}
+	return couponFromRow(row), nil
+}
+
+// GetAllCoupons() returns the coupons whose code contains search, optionally only those
+// with a status, the newest first.
+func (m CouponsModel) GetAllCoupons(search, status string, filters Filters) ([]*Coupon, Metadata, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := m.DB.GetAllCoupons(ctx, database.GetAllCouponsParams{
+		Column1: strings.TrimSpace(search),
+		Column2: status,
+		Limit:   int32(filters.limit()),
+		Offset:  int32(filters.offset()),
+	})
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	totalRecords := 0
+	coupons := []*Coupon{}
+	for _, row := range rows {
+		totalRecords = int(row.TotalRecords)
+		coupons = append(coupons, couponFromRow(database.Coupon{
+			ID:                 row.ID,
+			Code:               row.Code,
+			Description:        row.Description,
+			DiscountType:       row.DiscountType,
+			PercentOff:         row.PercentOff,
+			AmountOff:          row.AmountOff,
+			Currency:           row.Currency,
+			MaxRedemptions:     row.MaxRedemptions,
+			TimesRedeemed:      row.TimesRedeemed,
+			PendingRedemptions: row.PendingRedemptions,
+			ExpiresAt:          row.ExpiresAt,
+			PlanIds:            row.PlanIds,
+			Status:             row.Status,
+			CreatedAt:          row.CreatedAt,
+			UpdatedAt:          row.UpdatedAt,
+			Version:            row.Version,
+		}))
+	}
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return coupons, metadata, nil
+}
+
+// UpdateCoupon() saves the parts of a coupon that can change once it is in use, its
+// description, limits, plans and status.
+func (m CouponsModel) UpdateCoupon(coupon *Coupon) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	row, err := m.DB.UpdateCoupon(ctx, database.UpdateCouponParams{
+		Description:    coupon.Description,
+		MaxRedemptions: sql.NullInt32{Int32: coupon.Max_Redemptions, Valid: coupon.Max_Redemptions != 0},
+		ExpiresAt:      nullTimeFromPtr(coupon.Expires_At),
+		PlanIds:        coupon.Plan_IDs,
+		Status:         coupon.Status,
+		ID:             coupon.ID,
+		Version:        coupon.Version,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+	coupon.Version = row.Version
+	coupon.Updated_At = row.UpdatedAt
+	return nil
+}
+
+// ReserveCouponRedemption() holds one of a coupon's redemptions for a user's checkout
+// until expiresAt, it is made before the checkout so a coupon is never handed out past
+// its max redemptions. A checkout of the user's that still holds the coupon is released
+// first, it was abandoned for this one. ErrCouponFullyRedeemed is returned when the
+// coupon has no redemptions left and ErrCouponAlreadyRedeemed when the user got the
+// discount already or holds it for a trial.
+func (m CouponsModel) ReserveCouponRedemption(redemption *CouponRedemption, expiresAt time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := m.DB.ReleaseUserCheckoutCouponRedemptions(ctx, database.ReleaseUserCheckoutCouponRedemptionsParams{
+		CouponID: redemption.Coupon_ID,
+		UserID:   redemption.User_ID,
+	})
+	if err != nil {
+		return err
+	}
+	row, err := m.DB.ReserveCouponRedemption(ctx, database.ReserveCouponRedemptionParams{
+		ID:        redemption.Coupon_ID,
+		UserID:    redemption.User_ID,
+		PlanID:    redemption.Plan_ID,
+		Discount:  redemption.Discount.Amount,
+		Currency:  redemption.Discount.Currency,
+		ExpiresAt: sql.NullTime{Time: expiresAt, Valid: true},
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrCouponFullyRedeemed
+		case err.Error() == `pq: duplicate key value violates unique constraint "idx_coupon_redemptions_held"`:
+			return ErrCouponAlreadyRedeemed
+		default:
+			return err
+		}
+	}
+	redemption.ID = row.ID
+	redemption.Status = row.Status
+	redemption.Created_At = row.CreatedAt
+	redemption.Expires_At = nullTimePtr(row.ExpiresAt)
+	return nil
+}
+
+// SetCouponRedemptionSubscription() hands a reserved redemption to the trial it started,
+// it is then held until the trial is paid for or ends.
+func (m CouponsModel) SetCouponRedemptionSubscription(redemption *CouponRedemption, subscriptionID uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := m.DB.SetCouponRedemptionSubscription(ctx, database.SetCouponRedemptionSubscriptionParams{
+		ID:             redemption.ID,
+		SubscriptionID: uuid.NullUUID{UUID: subscriptionID, Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+	redemption.Subscription_ID = subscriptionID
+	redemption.Expires_At = nil
+	return nil
+}
+
+// ReleaseCouponRedemption() gives a pending redemption back to its coupon
+func (m CouponsModel) ReleaseCouponRedemption(redemption *CouponRedemption) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := m.DB.ReleaseCouponRedemption(ctx, redemption.ID)
+	if err != nil {
+		return err
+	}
+	redemption.Status = RedemptionStatusReleased
+	return nil
+}
+
+// ReleaseCheckoutCouponRedemption() gives back the redemption held by the checkout behind
+// a reference once its payment failed. A trial's redemption is kept as the checkout that
+// converts it is retried.
+func (m CouponsModel) ReleaseCheckoutCouponRedemption(reference string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return m.DB.ReleaseCheckoutCouponRedemptionByReference(ctx, sql.NullString{String: reference, Valid: true})
+}
+
+// ReleaseExpiredCouponRedemptions() gives back the redemptions of checkouts that weren't
+// paid in time and of trials that ended unpaid, returning how many each coupon got back.
+func (m CouponsModel) ReleaseExpiredCouponRedemptions() (map[string]int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := m.DB.ReleaseExpiredCouponRedemptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	released := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		released[row.Code] = row.Released
+	}
+	return released, nil
+}
+
+// couponRedemptionFromRow() builds a redemption from the unredeemed redemption queries
+func couponRedemptionFromRow(row database.GetUnredeemedCouponRedemptionByReferenceRow) *CouponRedemption {
+	return &CouponRedemption{
+		ID:              row.ID,
+		Coupon_ID:       row.CouponID,
+		Code:            row.Code,
+		User_ID:         row.UserID,
+		Plan_ID:         row.PlanID,
+		Subscription_ID: row.SubscriptionID.UUID,
+		Reference:       row.Reference.String,
+		Discount:        NewMoney(row.Discount, row.Currency),
+		Status:          row.Status,
+		Created_At:      row.CreatedAt,
+		Redeemed_At:     nullTimePtr(row.RedeemedAt),
+	}
+}
+
+// GetUnredeemedCouponRedemptionByReference() returns the redemption waiting on the
+// checkout behind a reference, a released one too as its checkout can still be paid.
+func (m CouponsModel) GetUnredeemedCouponRedemptionByReference(reference string) (*CouponRedemption, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	row, err := m.DB.GetUnredeemedCouponRedemptionByReference(ctx, sql.NullString{String: reference, Valid: true})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrCouponRedemptionNotFound
+		default:
+			return nil, err
+		}
+	}
+	return couponRedemptionFromRow(row), nil
+}
+
+// GetPendingCouponRedemptionBySubscriptionID() returns the redemption a trial was
+// started with, to be taken off the payment that converts it.
+func (m CouponsModel) GetPendingCouponRedemptionBySubscriptionID(subscriptionID uuid.UUID) (*CouponRedemption, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	row, err := m.DB.GetPendingCouponRedemptionBySubscriptionID(ctx, uuid.NullUUID{UUID: subscriptionID, Valid: true})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrCouponRedemptionNotFound
+		default:
+			return nil, err
+		}
+	}
+	return couponRedemptionFromRow(database.GetUnredeemedCouponRedemptionByReferenceRow(row)), nil
+}
+
+// SetCouponRedemptionReference() ties a pending redemption to the checkout it discounts
+func (m CouponsModel) SetCouponRedemptionReference(redemption *CouponRedemption, reference string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := m.DB.SetCouponRedemptionReference(ctx, database.SetCouponRedemptionReferenceParams{
+		ID:        redemption.ID,
+		Reference: sql.NullString{String: reference, Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+	redemption.Reference = reference
+	return nil
+}
+
+// RedeemCoupon() marks a redemption as redeemed by the subscription its payment started
+// and counts it against the coupon. A pending redemption always has room, it was held for
+// this payment. One released before its checkout was paid is only counted while the
+// coupon has redemptions left, else it is left with ErrCouponFullyRedeemed, and a user
+// who got the discount on another payment in the meantime gets ErrCouponAlreadyRedeemed.
+func (m CouponsModel) RedeemCoupon(redemption *CouponRedemption, subscriptionID uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := m.DB.RedeemCoupon(ctx, database.RedeemCouponParams{
+		ID:             redemption.ID,
+		SubscriptionID: uuid.NullUUID{UUID: subscriptionID, Valid: true},
+	})
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "idx_coupon_redemptions_held"`:
+			return ErrCouponAlreadyRedeemed
+		default:
+			return err
+		}
+	}
+	if rows == 0 {
+		coupon, err := m.GetCouponByID(redemption.Coupon_ID)
+		if err == nil && coupon.Max_Redemptions != 0 && coupon.Times_Redeemed+coupon.Pending_Redemptions >= coupon.Max_Redemptions {
+			return ErrCouponFullyRedeemed
+		}
+		return ErrCouponRedemptionNotFound
+	}
+	redemption.Status = RedemptionStatusRedeemed
+	redemption.Subscription_ID = subscriptionID
+	return nil
+}
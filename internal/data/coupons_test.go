@@ -0,0 +1,87 @@
+package data
+
+import (
+	"testing"
+	"time"
+
+	"github.com/blue-davinci/aggregate/internal/validator"
+)
+
+func TestCouponDiscount(t *testing.T) {
+	amountOff := NewMoney(500, "USD")
+	tests := []struct {
+		name   string
+		coupon *Coupon
+		price  Money
+		want   Money
+	}{
+		{name: "Percent", coupon: &Coupon{Discount_Type: CouponTypePercent, Percent_Off: 20}, price: NewMoney(1000, "USD"), want: NewMoney(200, "USD")},
+		{name: "Percent Rounds Down", coupon: &Coupon{Discount_Type: CouponTypePercent, Percent_Off: 15}, price: NewMoney(999, "KES"), want: NewMoney(149, "KES")},
+		{name: "Fixed", coupon: &Coupon{Discount_Type: CouponTypeFixed, Amount_Off: &amountOff}, price: NewMoney(1000, "USD"), want: NewMoney(500, "USD")},
+		{name: "Fixed More Than Price", coupon: &Coupon{Discount_Type: CouponTypeFixed, Amount_Off: &amountOff}, price: NewMoney(300, "USD"), want: NewMoney(300, "USD")},
+		{name: "Fixed Other Currency", coupon: &Coupon{Discount_Type: CouponTypeFixed, Amount_Off: &amountOff}, price: NewMoney(1000, "EUR"), want: NewMoney(0, "EUR")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.coupon.Discount(tt.price); got != tt.want {
+				t.Errorf("Got:%v But Wanted:%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateCoupon(t *testing.T) {
+	amountOff := NewMoney(500, "USD")
+	tests := []struct {
+		name   string
+		coupon *Coupon
+		valid  bool
+	}{
+		{name: "Percent", coupon: &Coupon{Code: "SPRING-20", Discount_Type: CouponTypePercent, Percent_Off: 20, Status: CouponStatusActive}, valid: true},
+		{name: "Fixed", coupon: &Coupon{Code: "FIVE_OFF", Discount_Type: CouponTypeFixed, Amount_Off: &amountOff, Plan_IDs: []int32{1, 2}, Status: CouponStatusActive}, valid: true},
+		{name: "Lower Case Code", coupon: &Coupon{Code: "spring", Discount_Type: CouponTypePercent, Percent_Off: 20, Status: CouponStatusActive}, valid: false},
+		{name: "Whole Price", coupon: &Coupon{Code: "FREE", Discount_Type: CouponTypePercent, Percent_Off: 100, Status: CouponStatusActive}, valid: false},
+		{name: "Fixed Without Amount", coupon: &Coupon{Code: "FIVE_OFF", Discount_Type: CouponTypeFixed, Status: CouponStatusActive}, valid: false},
+		{name: "Both Discounts", coupon: &Coupon{Code: "BOTH", Discount_Type: CouponTypeFixed, Percent_Off: 10, Amount_Off: &amountOff, Status: CouponStatusActive}, valid: false},
+		{name: "Duplicate Plans", coupon: &Coupon{Code: "SPRING", Discount_Type: CouponTypePercent, Percent_Off: 20, Plan_IDs: []int32{1, 1}, Status: CouponStatusActive}, valid: false},
+		{name: "Unknown Type", coupon: &Coupon{Code: "SPRING", Discount_Type: "bogo", Status: CouponStatusActive}, valid: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			if ValidateCoupon(v, tt.coupon); v.Valid() != tt.valid {
+				t.Errorf("Got:%v But Wanted valid:%t", v.Errors, tt.valid)
+			}
+		})
+	}
+}
+
+func TestValidateCouponRedemption(t *testing.T) {
+	now := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	yesterday := now.AddDate(0, 0, -1)
+	amountOff, euros := NewMoney(500, "USD"), NewMoney(500, "EUR")
+	price := NewMoney(1000, "USD")
+	tests := []struct {
+		name   string
+		coupon *Coupon
+		valid  bool
+	}{
+		{name: "Redeemable", coupon: &Coupon{Discount_Type: CouponTypePercent, Percent_Off: 20, Status: CouponStatusActive}, valid: true},
+		{name: "Plan Allowed", coupon: &Coupon{Discount_Type: CouponTypePercent, Percent_Off: 20, Plan_IDs: []int32{2}, Status: CouponStatusActive}, valid: true},
+		{name: "Inactive", coupon: &Coupon{Discount_Type: CouponTypePercent, Percent_Off: 20, Status: CouponStatusInactive}, valid: false},
+		{name: "Expired", coupon: &Coupon{Discount_Type: CouponTypePercent, Percent_Off: 20, Expires_At: &yesterday, Status: CouponStatusActive}, valid: false},
+		{name: "Fully Redeemed", coupon: &Coupon{Discount_Type: CouponTypePercent, Percent_Off: 20, Max_Redemptions: 5, Times_Redeemed: 5, Status: CouponStatusActive}, valid: false},
+		{name: "Other Plan", coupon: &Coupon{Discount_Type: CouponTypePercent, Percent_Off: 20, Plan_IDs: []int32{3}, Status: CouponStatusActive}, valid: false},
+		{name: "Fixed", coupon: &Coupon{Discount_Type: CouponTypeFixed, Amount_Off: &amountOff, Status: CouponStatusActive}, valid: true},
+		{name: "Fixed Other Currency", coupon: &Coupon{Discount_Type: CouponTypeFixed, Amount_Off: &euros, Status: CouponStatusActive}, valid: false},
+		{name: "Fixed Whole Price", coupon: &Coupon{Discount_Type: CouponTypeFixed, Amount_Off: &price, Status: CouponStatusActive}, valid: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			if ValidateCouponRedemption(v, tt.coupon, 2, price, now); v.Valid() != tt.valid {
+				t.Errorf("Got:%v But Wanted valid:%t", v.Errors, tt.valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,295 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+	_ "time/tzdata" // so timezones resolve even on hosts without a zoneinfo database
+
+	"github.com/blue-davinci/aggregate/internal/database"
+	"github.com/blue-davinci/aggregate/internal/validator"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrDigestPreferenceNotFound = errors.New("digest preference not found")
+)
+
+const (
+	DigestFrequencyDaily  = "daily"
+	DigestFrequencyWeekly = "weekly"
+	// DefaultDigestPostsPerFeed is the number of top posts we include per followed feed
+	DefaultDigestPostsPerFeed = 3
+)
+
+type DigestModel struct {
+	DB *database.Queries
+}
+
+// A DigestPreference holds a user's opt-in settings for the daily or weekly digest email.
+// Send_Hour and Weekday are in the user's own Timezone, Weekday is only used for weekly
+// digests and follows time.Weekday i.e 0 is Sunday.
+// The Unsubscribe_Token is embedded in every digest for the one-click unsubscribe link.
+type DigestPreference struct {
+	User_ID           int64      `json:"-"`
+	Frequency         string     `json:"frequency"`
+	Timezone          string     `json:"timezone"`
+	Send_Hour         int32      `json:"send_hour"`
+	Weekday           int32      `json:"weekday"`
+	Enabled           bool       `json:"enabled"`
+	Unsubscribe_Token string     `json:"-"`
+	Last_Sent_At      *time.Time `json:"last_sent_at,omitempty"`
+	Created_At        time.Time  `json:"created_at"`
+	Updated_At        time.Time  `json:"updated_at"`
+}
+
+// DigestRecipient is an enabled digest preference together with who we are mailing
+type DigestRecipient struct {
+	DigestPreference
+	Name  string
+	Email string
+}
+
+// DigestPost is one of the top posts of a followed feed included in a digest
+type DigestPost struct {
+	Feed_ID         uuid.UUID `json:"feed_id"`
+	Feed_Name       string    `json:"feed_name"`
+	ID              uuid.UUID `json:"id"`
+	Title           string    `json:"title"`
+	URL             string    `json:"url"`
+	Published_At    time.Time `json:"published_at"`
+	Favorites_Count int64     `json:"favorites_count"`
+}
+
+// DigestFeed groups the digest posts of a single feed for the email template
+type DigestFeed struct {
+	Name  string
+	Posts []*DigestPost
+}
+
+func ValidateDigestPreference(v *validator.Validator, preference *DigestPreference) {
+	v.Check(validator.PermittedValue(preference.Frequency, DigestFrequencyDaily, DigestFrequencyWeekly), "frequency", "must be either daily or weekly")
+	v.Check(preference.Timezone != "", "timezone", "must be provided")
+	if preference.Timezone != "" {
+		_, err := time.LoadLocation(preference.Timezone)
+		v.Check(err == nil, "timezone", "must be a valid IANA timezone eg: Africa/Nairobi")
+	}
+	v.Check(preference.Send_Hour >= 0 && preference.Send_Hour <= 23, "send_hour", "must be between 0 and 23")
+	v.Check(preference.Weekday >= 0 && preference.Weekday <= 6, "weekday", "must be between 0 (Sunday) and 6 (Saturday)")
+}
+
+// Due() reports whether a digest should go out at the given instant. A digest is due
+// during the user's chosen local hour (and weekday for weekly digests) as long as we
+// have not already sent one for the current period, which keeps the hourly cron from
+// sending duplicates and lets a missed hour catch up on the next run within the period.
+func (p *DigestPreference) Due(now time.Time) bool {
+	if !p.Enabled {
+		return false
+	}
+	location, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		location = time.UTC
+	}
+	local := now.In(location)
+	if int32(local.Hour()) < p.Send_Hour {
+		return false
+	}
+	if p.Frequency == DigestFrequencyWeekly && int32(local.Weekday()) != p.Weekday {
+		return false
+	}
+	if p.Last_Sent_At == nil {
+		return true
+	}
+	return p.Last_Sent_At.Before(p.PeriodStart(now))
+}
+
+// PeriodStart() returns the start of the current digest period, that is the most
+// recent scheduled send time at or before now in the user's timezone.
+func (p *DigestPreference) PeriodStart(now time.Time) time.Time {
+	location, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		location = time.UTC
+	}
+	local := now.In(location)
+	start := time.Date(local.Year(), local.Month(), local.Day(), int(p.Send_Hour), 0, 0, 0, location)
+	if start.After(local) {
+		start = start.AddDate(0, 0, -1)
+	}
+	if p.Frequency == DigestFrequencyWeekly {
+		for int32(start.Weekday()) != p.Weekday {
+			start = start.AddDate(0, 0, -1)
+		}
+	}
+	return start
+}
+
+// Since() returns the point from which posts should be included in the next digest.
+// First time digests look back a single period.
+func (p *DigestPreference) Since(now time.Time) time.Time {
+	if p.Last_Sent_At != nil {
+		return *p.Last_Sent_At
+	}
+	if p.Frequency == DigestFrequencyWeekly {
+		return now.AddDate(0, 0, -7)
+	}
+	return now.AddDate(0, 0, -1)
+}
+
+// GroupDigestPostsByFeed() groups digest posts per feed, keeping the order they came in
+func GroupDigestPostsByFeed(posts []*DigestPost) []*DigestFeed {
+	feeds := []*DigestFeed{}
+	index := make(map[uuid.UUID]*DigestFeed)
+	for _, post := range posts {
+		feed, ok := index[post.Feed_ID]
+		if !ok {
+			feed = &DigestFeed{Name: post.Feed_Name}
+			index[post.Feed_ID] = feed
+			feeds = append(feeds, feed)
+		}
+		feed.Posts = append(feed.Posts, post)
+	}
+	return feeds
+}
+
+// GetDigestPreference() returns a user's digest preference
+func (m DigestModel) GetDigestPreference(userID int64) (*DigestPreference, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	row, err := m.DB.GetDigestPreferenceByUserID(ctx, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrDigestPreferenceNotFound
+		default:
+			return nil, err
+		}
+	}
+	return populateDigestPreference(row), nil
+}
+
+// UpsertDigestPreference() creates or updates a user's digest preference. A new
+// unsubscribe token is only used on creation, existing ones are kept so that links in
+// digests already sent keep on working.
+func (m DigestModel) UpsertDigestPreference(preference *DigestPreference) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	token, err := generateAPI(preference.User_ID, 0, "digest-unsubscribe", APIKeyLength)
+	if err != nil {
+		return err
+	}
+	row, err := m.DB.UpsertDigestPreference(ctx, database.UpsertDigestPreferenceParams{
+		UserID:           preference.User_ID,
+		Frequency:        preference.Frequency,
+		Timezone:         preference.Timezone,
+		SendHour:         preference.Send_Hour,
+		Weekday:          preference.Weekday,
+		Enabled:          preference.Enabled,
+		UnsubscribeToken: token.Plaintext,
+	})
+	if err != nil {
+		return err
+	}
+	*preference = *populateDigestPreference(row)
+	return nil
+}
+
+// GetEnabledDigestRecipients() returns every activated user that has opted into digests.
+// The caller decides who is due using DigestPreference.Due().
+func (m DigestModel) GetEnabledDigestRecipients() ([]*DigestRecipient, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := m.DB.GetEnabledDigestPreferences(ctx)
+	if err != nil {
+		return nil, err
+	}
+	recipients := []*DigestRecipient{}
+	for _, row := range rows {
+		recipients = append(recipients, &DigestRecipient{
+			DigestPreference: *populateDigestPreference(database.DigestPreference{
+				UserID:           row.UserID,
+				Frequency:        row.Frequency,
+				Timezone:         row.Timezone,
+				SendHour:         row.SendHour,
+				Weekday:          row.Weekday,
+				Enabled:          row.Enabled,
+				UnsubscribeToken: row.UnsubscribeToken,
+				LastSentAt:       row.LastSentAt,
+				CreatedAt:        row.CreatedAt,
+				UpdatedAt:        row.UpdatedAt,
+			}),
+			Name:  row.Name,
+			Email: row.Email,
+		})
+	}
+	return recipients, nil
+}
+
+// GetDigestPostsForUser() returns the top posts per followed feed, ranked by favorites,
+// that were scraped after since.
+func (m DigestModel) GetDigestPostsForUser(userID int64, since time.Time, postsPerFeed int) ([]*DigestPost, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := m.DB.GetDigestPostsForUser(ctx, database.GetDigestPostsForUserParams{
+		UserID:    userID,
+		CreatedAt: since,
+		PostRank:  int64(postsPerFeed),
+	})
+	if err != nil {
+		return nil, err
+	}
+	posts := []*DigestPost{}
+	for _, row := range rows {
+		posts = append(posts, &DigestPost{
+			Feed_ID:         row.FeedID,
+			Feed_Name:       row.FeedName,
+			ID:              row.ID,
+			Title:           row.Itemtitle,
+			URL:             row.Itemurl,
+			Published_At:    row.ItempublishedAt,
+			Favorites_Count: row.FavoritesCount,
+		})
+	}
+	return posts, nil
+}
+
+// MarkDigestSent() records when the last digest went out for a user
+func (m DigestModel) MarkDigestSent(userID int64, sentAt time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return m.DB.MarkDigestSent(ctx, database.MarkDigestSentParams{
+		UserID:     userID,
+		LastSentAt: sql.NullTime{Time: sentAt, Valid: true},
+	})
+}
+
+// UnsubscribeByToken() disables digests for the owner of the token
+func (m DigestModel) UnsubscribeByToken(token string) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	userID, err := m.DB.UnsubscribeDigestByToken(ctx, token)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, ErrDigestPreferenceNotFound
+		default:
+			return 0, err
+		}
+	}
+	return userID, nil
+}
+
+func populateDigestPreference(row database.DigestPreference) *DigestPreference {
+	return &DigestPreference{
+		User_ID:           row.UserID,
+		Frequency:         row.Frequency,
+		Timezone:          row.Timezone,
+		Send_Hour:         row.SendHour,
+		Weekday:           row.Weekday,
+		Enabled:           row.Enabled,
+		Unsubscribe_Token: row.UnsubscribeToken,
+		Last_Sent_At:      nullTimeToTime(row.LastSentAt),
+		Created_At:        row.CreatedAt,
+		Updated_At:        row.UpdatedAt,
+	}
+}
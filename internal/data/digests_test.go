@@ -0,0 +1,85 @@
+package data
+
+import (
+	"testing"
+	"time"
+
+	"github.com/blue-davinci/aggregate/internal/validator"
+)
+
+func TestDigestPreferenceDue(t *testing.T) {
+	// Monday 2024-07-01 05:30 UTC is 08:30 in Nairobi (UTC+3)
+	now := time.Date(2024, 7, 1, 5, 30, 0, 0, time.UTC)
+	sentYesterday := now.AddDate(0, 0, -1)
+	sentThisMorning := now.Add(-10 * time.Minute)
+	tests := []struct {
+		name       string
+		preference DigestPreference
+		want       bool
+	}{
+		{name: "Daily At Local Hour", preference: DigestPreference{Enabled: true, Frequency: DigestFrequencyDaily, Timezone: "Africa/Nairobi", Send_Hour: 8}, want: true},
+		{name: "Daily Before Local Hour", preference: DigestPreference{Enabled: true, Frequency: DigestFrequencyDaily, Timezone: "Africa/Nairobi", Send_Hour: 9}},
+		{name: "Daily Catches Up Later In The Day", preference: DigestPreference{Enabled: true, Frequency: DigestFrequencyDaily, Timezone: "Africa/Nairobi", Send_Hour: 6, Last_Sent_At: &sentYesterday}, want: true},
+		{name: "Daily Already Sent", preference: DigestPreference{Enabled: true, Frequency: DigestFrequencyDaily, Timezone: "Africa/Nairobi", Send_Hour: 8, Last_Sent_At: &sentThisMorning}},
+		{name: "Daily Respects Timezone", preference: DigestPreference{Enabled: true, Frequency: DigestFrequencyDaily, Timezone: "America/New_York", Send_Hour: 8}},
+		{name: "Weekly On Weekday", preference: DigestPreference{Enabled: true, Frequency: DigestFrequencyWeekly, Timezone: "Africa/Nairobi", Send_Hour: 8, Weekday: int32(time.Monday), Last_Sent_At: &sentYesterday}, want: true},
+		{name: "Weekly Other Weekday", preference: DigestPreference{Enabled: true, Frequency: DigestFrequencyWeekly, Timezone: "Africa/Nairobi", Send_Hour: 8, Weekday: int32(time.Friday)}},
+		{name: "Disabled", preference: DigestPreference{Frequency: DigestFrequencyDaily, Timezone: "UTC"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.preference.Due(now); got != tt.want {
+				t.Errorf("Got:%v But Wanted:%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDigestPreferencePeriodStart(t *testing.T) {
+	nairobi, _ := time.LoadLocation("Africa/Nairobi")
+	// Wednesday 2024-07-03 10:00 in Nairobi
+	now := time.Date(2024, 7, 3, 10, 0, 0, 0, nairobi)
+	tests := []struct {
+		name       string
+		preference DigestPreference
+		want       time.Time
+	}{
+		{name: "Daily Today", preference: DigestPreference{Frequency: DigestFrequencyDaily, Timezone: "Africa/Nairobi", Send_Hour: 8}, want: time.Date(2024, 7, 3, 8, 0, 0, 0, nairobi)},
+		{name: "Daily Yesterday", preference: DigestPreference{Frequency: DigestFrequencyDaily, Timezone: "Africa/Nairobi", Send_Hour: 18}, want: time.Date(2024, 7, 2, 18, 0, 0, 0, nairobi)},
+		{name: "Weekly", preference: DigestPreference{Frequency: DigestFrequencyWeekly, Timezone: "Africa/Nairobi", Send_Hour: 8, Weekday: int32(time.Monday)}, want: time.Date(2024, 7, 1, 8, 0, 0, 0, nairobi)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.preference.PeriodStart(now); !got.Equal(tt.want) {
+				t.Errorf("Got:%v But Wanted:%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateDigestPreference(t *testing.T) {
+	tests := []struct {
+		name       string
+		preference DigestPreference
+		wantErrs   []string
+	}{
+		{name: "Valid", preference: DigestPreference{Frequency: DigestFrequencyWeekly, Timezone: "Europe/Berlin", Send_Hour: 7, Weekday: 5}},
+		{name: "Unknown Frequency", preference: DigestPreference{Frequency: "hourly", Timezone: "UTC"}, wantErrs: []string{"frequency"}},
+		{name: "Unknown Timezone", preference: DigestPreference{Frequency: DigestFrequencyDaily, Timezone: "Mars/Olympus"}, wantErrs: []string{"timezone"}},
+		{name: "Out Of Range", preference: DigestPreference{Frequency: DigestFrequencyDaily, Timezone: "UTC", Send_Hour: 24, Weekday: 7}, wantErrs: []string{"send_hour", "weekday"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateDigestPreference(v, &tt.preference)
+			if len(v.Errors) != len(tt.wantErrs) {
+				t.Fatalf("Got:%v But Wanted errors for:%v", v.Errors, tt.wantErrs)
+			}
+			for _, key := range tt.wantErrs {
+				if _, ok := v.Errors[key]; !ok {
+					t.Errorf("Got:%v But Wanted an error for:%v", v.Errors, key)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,252 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/blue-davinci/aggregate/internal/database"
+	"github.com/blue-davinci/aggregate/internal/validator"
+)
+
+// The quotas a plan can set. Each is counted against the user's usage and a plan that
+// doesn't set one leaves it unlimited.
+const (
+	EntitlementFeedsCreated   = "feeds_created"
+	EntitlementFeedsFollowed  = "feeds_followed"
+	EntitlementCommentsPerDay = "comments_per_day"
+	EntitlementSavedSearches  = "saved_searches"
+)
+
+// The features a plan can switch on. A plan that doesn't list one goes without it.
+const (
+	EntitlementOutboundFeeds     = "outbound_feeds"
+	EntitlementSavedSearchAlerts = "saved_search_alerts"
+)
+
+var (
+	// QuotaEntitlements lists the quotas in the order they are shown
+	QuotaEntitlements = []string{EntitlementFeedsCreated, EntitlementFeedsFollowed, EntitlementCommentsPerDay, EntitlementSavedSearches}
+	// FeatureEntitlements lists the features in the order they are shown
+	FeatureEntitlements = []string{EntitlementOutboundFeeds, EntitlementSavedSearchAlerts}
+)
+
+type EntitlementsModel struct {
+	DB *database.Queries
+}
+
+// PlanEntitlement is one quota or feature of a plan. Quota only applies to quotas,
+// where nil is unlimited, and Enabled only to features.
+type PlanEntitlement struct {
+	Name       string    `json:"name"`
+	Quota      *int64    `json:"quota"`
+	Enabled    bool      `json:"enabled"`
+	Created_At time.Time `json:"created_at"`
+	Updated_At time.Time `json:"updated_at"`
+}
+
+// Entitlements is what a user is entitled to through the plan of their current
+// subscription, the plan of their organization when it has a seat for them or, without
+// either, the free plan. Plan_ID is 0 when there is no free plan and Organization_ID
+// is only set when the plan is the organization's.
+type Entitlements struct {
+	Plan_ID         int32
+	Plan_Name       string
+	Subscribed      bool
+	Organization_ID int64
+	Quotas          map[string]*int64
+	Features        map[string]bool
+}
+
+// QuotaUsage is a quota next to how much of it the user has used. Limit and Remaining
+// are nil for unlimited quotas.
+type QuotaUsage struct {
+	Name      string `json:"name"`
+	Limit     *int64 `json:"limit"`
+	Used      int64  `json:"used"`
+	Remaining *int64 `json:"remaining"`
+	Unlimited bool   `json:"unlimited"`
+}
+
+// EntitlementsUsage is a user's entitlements as we show them to the user
+type EntitlementsUsage struct {
+	Plan_ID         int32           `json:"plan_id"`
+	Plan_Name       string          `json:"plan_name"`
+	Subscribed      bool            `json:"subscribed"`
+	Organization_ID int64           `json:"organization_id,omitempty"`
+	Quotas          []QuotaUsage    `json:"quotas"`
+	Features        map[string]bool `json:"features"`
+}
+
+func isQuotaEntitlement(name string) bool {
+	return slices.Contains(QuotaEntitlements, name)
+}
+
+func isFeatureEntitlement(name string) bool {
+	return slices.Contains(FeatureEntitlements, name)
+}
+
+func ValidatePlanEntitlements(v *validator.Validator, entitlements []*PlanEntitlement) {
+	seen := make(map[string]bool)
+	for _, entitlement := range entitlements {
+		key := fmt.Sprintf("entitlements.%s", entitlement.Name)
+		switch {
+		case isQuotaEntitlement(entitlement.Name):
+			if entitlement.Quota != nil {
+				v.Check(*entitlement.Quota >= 0, key, "quota must not be negative")
+			}
+		case isFeatureEntitlement(entitlement.Name):
+			v.Check(entitlement.Quota == nil, key, "features don't take a quota")
+		default:
+			v.AddError(key, "unknown entitlement")
+		}
+		v.Check(!seen[entitlement.Name], key, "must only be provided once")
+		seen[entitlement.Name] = true
+	}
+}
+
+// Quota() returns the limit of a quota, limited is false when it is unlimited
+func (e *Entitlements) Quota(name string) (limit int64, limited bool) {
+	quota := e.Quotas[name]
+	if quota == nil {
+		return 0, false
+	}
+	return *quota, true
+}
+
+// Allows() reports whether a quota has room for one more given what is already used
+func (e *Entitlements) Allows(name string, used int64) bool {
+	limit, limited := e.Quota(name)
+	return !limited || used < limit
+}
+
+// HasFeature() reports whether the plan switches a feature on
+func (e *Entitlements) HasFeature(name string) bool {
+	return e.Features[name]
+}
+
+// SetDefaultQuotas() fills in the quotas the plan doesn't set
+func (e *Entitlements) SetDefaultQuotas(defaults map[string]int64) {
+	for name, limit := range defaults {
+		if _, ok := e.Quotas[name]; !ok {
+			e.Quotas[name] = &limit
+		}
+	}
+}
+
+// Usage() lines up every quota with the user's usage, every feature is listed whether
+// the plan has it or not.
+func (e *Entitlements) Usage(limitations *LimitationsItmes) *EntitlementsUsage {
+	usage := &EntitlementsUsage{
+		Plan_ID:         e.Plan_ID,
+		Plan_Name:       e.Plan_Name,
+		Subscribed:      e.Subscribed,
+		Organization_ID: e.Organization_ID,
+		Quotas:          []QuotaUsage{},
+		Features:        make(map[string]bool),
+	}
+	for _, name := range QuotaEntitlements {
+		quota := QuotaUsage{Name: name, Used: limitations.Used(name), Unlimited: true}
+		if limit, limited := e.Quota(name); limited {
+			remaining := max(limit-quota.Used, 0)
+			quota.Limit, quota.Remaining, quota.Unlimited = &limit, &remaining, false
+		}
+		usage.Quotas = append(usage.Quotas, quota)
+	}
+	for _, name := range FeatureEntitlements {
+		usage.Features[name] = e.HasFeature(name)
+	}
+	return usage
+}
+
+// GetUserEntitlements() resolves the plan a user is on and what it entitles them to
+func (m EntitlementsModel) GetUserEntitlements(userID int64) (*Entitlements, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := m.DB.GetUserPlanEntitlements(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	entitlements := &Entitlements{
+		Quotas:   make(map[string]*int64),
+		Features: make(map[string]bool),
+	}
+	for _, row := range rows {
+		entitlements.Plan_ID = row.PlanID
+		entitlements.Plan_Name = row.PlanName
+		entitlements.Subscribed = row.Subscribed
+		entitlements.Organization_ID = row.OrganizationID.Int64
+		// a plan without entitlements still comes back as a single row
+		if !row.Entitlement.Valid {
+			continue
+		}
+		switch {
+		case isQuotaEntitlement(row.Entitlement.String):
+			var quota *int64
+			if row.Quota.Valid {
+				quota = &row.Quota.Int64
+			}
+			entitlements.Quotas[row.Entitlement.String] = quota
+		case isFeatureEntitlement(row.Entitlement.String):
+			entitlements.Features[row.Entitlement.String] = row.Enabled.Bool
+		}
+	}
+	return entitlements, nil
+}
+
+// GetPlanEntitlements() returns the quotas and features a plan sets
+func (m EntitlementsModel) GetPlanEntitlements(planID int32) ([]*PlanEntitlement, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := m.DB.GetPlanEntitlements(ctx, planID)
+	if err != nil {
+		return nil, err
+	}
+	entitlements := []*PlanEntitlement{}
+	for _, row := range rows {
+		entitlement := &PlanEntitlement{
+			Name:       row.Name,
+			Enabled:    row.Enabled,
+			Created_At: row.CreatedAt,
+			Updated_At: row.UpdatedAt,
+		}
+		if row.Quota.Valid {
+			entitlement.Quota = &row.Quota.Int64
+		}
+		entitlements = append(entitlements, entitlement)
+	}
+	return entitlements, nil
+}
+
+// SetPlanEntitlements() replaces a plan's entitlements, the ones left out are removed
+func (m EntitlementsModel) SetPlanEntitlements(planID int32, entitlements []*PlanEntitlement) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	names := []string{}
+	for _, entitlement := range entitlements {
+		quota := sql.NullInt64{}
+		if entitlement.Quota != nil {
+			quota = sql.NullInt64{Int64: *entitlement.Quota, Valid: true}
+		}
+		// quotas are always on, their limit is what restricts them
+		entitlement.Enabled = entitlement.Enabled || isQuotaEntitlement(entitlement.Name)
+		row, err := m.DB.UpsertPlanEntitlement(ctx, database.UpsertPlanEntitlementParams{
+			PlanID:  planID,
+			Name:    entitlement.Name,
+			Quota:   quota,
+			Enabled: entitlement.Enabled,
+		})
+		if err != nil {
+			return err
+		}
+		entitlement.Created_At = row.CreatedAt
+		entitlement.Updated_At = row.UpdatedAt
+		names = append(names, entitlement.Name)
+	}
+	return m.DB.DeletePlanEntitlementsExcept(ctx, database.DeletePlanEntitlementsExceptParams{
+		PlanID:  planID,
+		Column2: names,
+	})
+}
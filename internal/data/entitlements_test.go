@@ -0,0 +1,159 @@
+package data
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/blue-davinci/aggregate/internal/validator"
+)
+
+func quotaOf(limit int64) *int64 {
+	return &limit
+}
+
+func TestEntitlementsAllows(t *testing.T) {
+	entitlements := &Entitlements{
+		Quotas: map[string]*int64{
+			EntitlementFeedsCreated:  quotaOf(5),
+			EntitlementFeedsFollowed: nil,
+			EntitlementSavedSearches: quotaOf(0),
+		},
+	}
+	tests := []struct {
+		name  string
+		quota string
+		used  int64
+		want  bool
+	}{
+		{name: "Under The Limit", quota: EntitlementFeedsCreated, used: 4, want: true},
+		{name: "At The Limit", quota: EntitlementFeedsCreated, used: 5, want: false},
+		{name: "Unlimited", quota: EntitlementFeedsFollowed, used: 10_000, want: true},
+		{name: "Not Set", quota: EntitlementCommentsPerDay, used: 10_000, want: true},
+		{name: "Zero Quota", quota: EntitlementSavedSearches, used: 0, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := entitlements.Allows(tt.quota, tt.used); got != tt.want {
+				t.Errorf("Got:%t But Wanted:%t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEntitlementsSetDefaultQuotas(t *testing.T) {
+	entitlements := &Entitlements{
+		Quotas: map[string]*int64{
+			EntitlementFeedsCreated:  quotaOf(2),
+			EntitlementFeedsFollowed: nil,
+		},
+	}
+	entitlements.SetDefaultQuotas(map[string]int64{
+		EntitlementFeedsCreated:   5,
+		EntitlementFeedsFollowed:  5,
+		EntitlementCommentsPerDay: 10,
+	})
+	tests := []struct {
+		quota       string
+		wantLimit   int64
+		wantLimited bool
+	}{
+		// the plan's own quota and its unlimited quota are kept
+		{quota: EntitlementFeedsCreated, wantLimit: 2, wantLimited: true},
+		{quota: EntitlementFeedsFollowed, wantLimit: 0, wantLimited: false},
+		{quota: EntitlementCommentsPerDay, wantLimit: 10, wantLimited: true},
+		{quota: EntitlementSavedSearches, wantLimit: 0, wantLimited: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.quota, func(t *testing.T) {
+			limit, limited := entitlements.Quota(tt.quota)
+			if limit != tt.wantLimit || limited != tt.wantLimited {
+				t.Errorf("Got:%d,%t But Wanted:%d,%t", limit, limited, tt.wantLimit, tt.wantLimited)
+			}
+		})
+	}
+}
+
+func TestEntitlementsUsage(t *testing.T) {
+	entitlements := &Entitlements{
+		Plan_ID:    2,
+		Plan_Name:  "Monthly",
+		Subscribed: true,
+		Quotas: map[string]*int64{
+			EntitlementFeedsCreated:   quotaOf(20),
+			EntitlementCommentsPerDay: quotaOf(10),
+		},
+		Features: map[string]bool{EntitlementOutboundFeeds: true},
+	}
+	limitations := &LimitationsItmes{Created_Feeds: 3, Followed_Feeds: 41, Comments_Today: 12, Saved_Searches: 1}
+	got := entitlements.Usage(limitations)
+	want := &EntitlementsUsage{
+		Plan_ID:    2,
+		Plan_Name:  "Monthly",
+		Subscribed: true,
+		Quotas: []QuotaUsage{
+			{Name: EntitlementFeedsCreated, Limit: quotaOf(20), Used: 3, Remaining: quotaOf(17)},
+			{Name: EntitlementFeedsFollowed, Used: 41, Unlimited: true},
+			// comments made before a downgrade can put a user over, remaining stops at 0
+			{Name: EntitlementCommentsPerDay, Limit: quotaOf(10), Used: 12, Remaining: quotaOf(0)},
+			{Name: EntitlementSavedSearches, Used: 1, Unlimited: true},
+		},
+		Features: map[string]bool{EntitlementOutboundFeeds: true, EntitlementSavedSearchAlerts: false},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Got:%+v But Wanted:%+v", got, want)
+	}
+}
+
+func TestValidatePlanEntitlements(t *testing.T) {
+	tests := []struct {
+		name         string
+		entitlements []*PlanEntitlement
+		wantErrors   []string
+	}{
+		{
+			name: "Valid",
+			entitlements: []*PlanEntitlement{
+				{Name: EntitlementFeedsCreated, Quota: quotaOf(20)},
+				{Name: EntitlementFeedsFollowed},
+				{Name: EntitlementOutboundFeeds, Enabled: true},
+			},
+		},
+		{
+			name:         "Unknown",
+			entitlements: []*PlanEntitlement{{Name: "priority_support", Enabled: true}},
+			wantErrors:   []string{"entitlements.priority_support"},
+		},
+		{
+			name:         "Negative Quota",
+			entitlements: []*PlanEntitlement{{Name: EntitlementSavedSearches, Quota: quotaOf(-1)}},
+			wantErrors:   []string{"entitlements.saved_searches"},
+		},
+		{
+			name:         "Feature With Quota",
+			entitlements: []*PlanEntitlement{{Name: EntitlementOutboundFeeds, Quota: quotaOf(3)}},
+			wantErrors:   []string{"entitlements.outbound_feeds"},
+		},
+		{
+			name: "Duplicate",
+			entitlements: []*PlanEntitlement{
+				{Name: EntitlementFeedsCreated, Quota: quotaOf(5)},
+				{Name: EntitlementFeedsCreated, Quota: quotaOf(10)},
+			},
+			wantErrors: []string{"entitlements.feeds_created"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidatePlanEntitlements(v, tt.entitlements)
+			if len(v.Errors) != len(tt.wantErrors) {
+				t.Fatalf("Got:%v But Wanted errors for:%v", v.Errors, tt.wantErrors)
+			}
+			for _, key := range tt.wantErrors {
+				if _, ok := v.Errors[key]; !ok {
+					t.Errorf("Got:%v But Wanted an error for:%s", v.Errors, key)
+				}
+			}
+		})
+	}
+}
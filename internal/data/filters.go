@@ -1,10 +1,20 @@
 package data
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"math"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/blue-davinci/aggregate/internal/validator"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
 )
 
 // Define a new Metadata struct for holding the pagination metadata.
@@ -14,6 +24,18 @@ type Metadata struct {
 	FirstPage    int `json:"first_page,omitempty"`
 	LastPage     int `json:"last_page,omitempty"`
 	TotalRecords int `json:"total_records,omitempty"`
+	// Keyset pagination tokens for post listings, pass either back as ?cursor=
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+// Cursor is the decoded form of the opaque next_cursor/prev_cursor tokens. It holds
+// the keyset i.e (itempublished_at, id) of the post at the edge of a page and whether
+// we are paging backwards, towards newer posts, from it.
+type Cursor struct {
+	Published_At time.Time `json:"t"`
+	ID           uuid.UUID `json:"i"`
+	Backward     bool      `json:"b,omitempty"`
 }
 
 // Add a SortSafelist field to hold the supported sort values.
@@ -22,6 +44,11 @@ type Filters struct {
 	PageSize     int
 	Sort         string
 	SortSafelist []string
+	// Cursor is only used by the post listings. When set, Page is ignored.
+	Cursor string
+	// Since and Until limit post listings to a published date range, zero means unset
+	Since time.Time
+	Until time.Time
 }
 
 func ValidateFilters(v *validator.Validator, f Filters) {
@@ -32,6 +59,41 @@ func ValidateFilters(v *validator.Validator, f Filters) {
 	v.Check(f.PageSize <= 100, "page_size", "must be a maximum of 100")
 	// Check that the sort parameter matches a value in the safelist.
 	v.Check(validator.PermittedValue(f.Sort, f.SortSafelist...), "sort", "invalid sort value")
+	// Check that the cursor, if provided, is one we handed out
+	if f.Cursor != "" {
+		_, err := DecodeCursor(f.Cursor)
+		v.Check(err == nil, "cursor", "must be a valid cursor")
+		// cursors hold a published date so they only work with the default sort
+		v.Check(f.keysetSortable(), "cursor", "can only be used with the default sort")
+	}
+	// Check that the date range, if provided, makes sense
+	if !f.Since.IsZero() && !f.Until.IsZero() {
+		v.Check(f.Until.After(f.Since), "until", "must be after since")
+	}
+}
+
+// EncodeCursor() turns a cursor into the opaque token we hand out in the Metadata
+func EncodeCursor(c Cursor) string {
+	js, err := json.Marshal(c)
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(js)
+}
+
+// DecodeCursor() reverses EncodeCursor(). Any token that does not decode into a
+// cursor with a post ID and a publish date returns an ErrInvalidCursor.
+func DecodeCursor(token string) (Cursor, error) {
+	var c Cursor
+	js, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	err = json.Unmarshal(js, &c)
+	if err != nil || c.ID == uuid.Nil || c.Published_At.IsZero() {
+		return Cursor{}, ErrInvalidCursor
+	}
+	return c, nil
 }
 
 // Check that the client-provided Sort field matches one of the entries in our safelist
@@ -55,6 +117,24 @@ func (f Filters) sortDirection() string {
 	return "ASC"
 }
 
+// usesCursor() reports whether the request should be served with keyset pagination
+func (f Filters) usesCursor() bool {
+	return f.Cursor != ""
+}
+
+// keysetSortable() reports whether the listing is ordered by (itempublished_at, id) in
+// which case we can hand out and accept cursors.
+func (f Filters) keysetSortable() bool {
+	return f.Sort == "" || f.Sort == DefaultPostSort
+}
+
+// cursor() returns the decoded cursor. Filters are validated beforehand so we can
+// safely ignore the error.
+func (f Filters) cursor() Cursor {
+	c, _ := DecodeCursor(f.Cursor)
+	return c
+}
+
 func (f Filters) limit() int {
 	return f.PageSize
 }
@@ -81,3 +161,36 @@ func calculateMetadata(totalRecords, page, pageSize int) Metadata {
 		TotalRecords: totalRecords,
 	}
 }
+
+// calculateCursorMetadata() builds the keyset pagination metadata for a page of posts.
+// first and last are the keysets of the first and last posts on the page, while hasPrev
+// and hasNext say whether there are newer and older posts respectively.
+func calculateCursorMetadata(pageSize int, first, last Cursor, hasPrev, hasNext bool) Metadata {
+	metadata := Metadata{PageSize: pageSize}
+	if hasPrev {
+		first.Backward = true
+		metadata.PrevCursor = EncodeCursor(first)
+	}
+	if hasNext {
+		last.Backward = false
+		metadata.NextCursor = EncodeCursor(last)
+	}
+	return metadata
+}
+
+// trimCursorPage() takes the rows of a keyset query, which are fetched with a limit of
+// page_size+1, and returns the page in newest-first order alongside whether there are
+// newer (hasPrev) and older (hasNext) posts beyond it. Backward queries return rows
+// oldest-first so we reverse them here.
+func trimCursorPage[T any](rows []T, filters Filters) (page []T, hasPrev, hasNext bool) {
+	hasMore := len(rows) > filters.limit()
+	if hasMore {
+		rows = rows[:filters.limit()]
+	}
+	if filters.cursor().Backward {
+		slices.Reverse(rows)
+		// we came from an older page, so there is always a next page
+		return rows, hasMore, true
+	}
+	return rows, true, hasMore
+}
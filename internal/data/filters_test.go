@@ -0,0 +1,107 @@
+package data
+
+import (
+	"testing"
+	"time"
+
+	"github.com/blue-davinci/aggregate/internal/validator"
+	"github.com/google/uuid"
+)
+
+func TestDecodeCursor(t *testing.T) {
+	published := time.Date(2024, 7, 1, 12, 30, 0, 0, time.UTC)
+	postID := uuid.New()
+	tests := []struct {
+		name    string
+		token   string
+		want    Cursor
+		wantErr bool
+	}{
+		{name: "Next Cursor", token: EncodeCursor(Cursor{Published_At: published, ID: postID}), want: Cursor{Published_At: published, ID: postID}},
+		{name: "Prev Cursor", token: EncodeCursor(Cursor{Published_At: published, ID: postID, Backward: true}), want: Cursor{Published_At: published, ID: postID, Backward: true}},
+		{name: "Not Base64", token: "not a cursor!", wantErr: true},
+		{name: "Not JSON", token: "bm90IGpzb24", wantErr: true},
+		{name: "Missing ID", token: EncodeCursor(Cursor{Published_At: published}), wantErr: true},
+		{name: "Missing Date", token: EncodeCursor(Cursor{ID: postID}), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeCursor(tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DecodeCursor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !got.Published_At.Equal(tt.want.Published_At) || got.ID != tt.want.ID || got.Backward != tt.want.Backward {
+				t.Errorf("Got:%v But Wanted:%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrimCursorPage(t *testing.T) {
+	forward := EncodeCursor(Cursor{Published_At: time.Now(), ID: uuid.New()})
+	backward := EncodeCursor(Cursor{Published_At: time.Now(), ID: uuid.New(), Backward: true})
+	tests := []struct {
+		name        string
+		rows        []int
+		cursor      string
+		want        []int
+		wantHasPrev bool
+		wantHasNext bool
+	}{
+		{name: "Forward With More", rows: []int{5, 4, 3}, cursor: forward, want: []int{5, 4}, wantHasPrev: true, wantHasNext: true},
+		{name: "Forward Last Page", rows: []int{5, 4}, cursor: forward, want: []int{5, 4}, wantHasPrev: true, wantHasNext: false},
+		{name: "Backward With More", rows: []int{3, 4, 5}, cursor: backward, want: []int{4, 3}, wantHasPrev: true, wantHasNext: true},
+		{name: "Backward First Page", rows: []int{4, 5}, cursor: backward, want: []int{5, 4}, wantHasPrev: false, wantHasNext: true},
+		{name: "Empty", rows: []int{}, cursor: forward, want: []int{}, wantHasPrev: true, wantHasNext: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, hasPrev, hasNext := trimCursorPage(tt.rows, Filters{PageSize: 2, Cursor: tt.cursor})
+			if len(got) != len(tt.want) {
+				t.Fatalf("Got:%v But Wanted:%v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Got:%v But Wanted:%v", got, tt.want)
+					break
+				}
+			}
+			if hasPrev != tt.wantHasPrev || hasNext != tt.wantHasNext {
+				t.Errorf("Got hasPrev:%v hasNext:%v But Wanted hasPrev:%v hasNext:%v", hasPrev, hasNext, tt.wantHasPrev, tt.wantHasNext)
+			}
+		})
+	}
+}
+
+func TestValidateFilters(t *testing.T) {
+	since := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	cursor := EncodeCursor(Cursor{Published_At: since, ID: uuid.New()})
+	tests := []struct {
+		name     string
+		filters  Filters
+		wantErrs []string
+	}{
+		{name: "Defaults", filters: Filters{Page: 1, PageSize: 20, Sort: DefaultPostSort}},
+		{name: "Favorites Sort", filters: Filters{Page: 1, PageSize: 20, Sort: "-favorites_count"}},
+		{name: "Unknown Sort", filters: Filters{Page: 1, PageSize: 20, Sort: "-itemtitle"}, wantErrs: []string{"sort"}},
+		{name: "Cursor With Default Sort", filters: Filters{Page: 1, PageSize: 20, Sort: DefaultPostSort, Cursor: cursor}},
+		{name: "Cursor With Other Sort", filters: Filters{Page: 1, PageSize: 20, Sort: "comments_count", Cursor: cursor}, wantErrs: []string{"cursor"}},
+		{name: "Date Range", filters: Filters{Page: 1, PageSize: 20, Sort: DefaultPostSort, Since: since, Until: since.AddDate(0, 0, 7)}},
+		{name: "Inverted Date Range", filters: Filters{Page: 1, PageSize: 20, Sort: DefaultPostSort, Since: since, Until: since.AddDate(0, 0, -7)}, wantErrs: []string{"until"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.filters.SortSafelist = PostSortSafelist
+			v := validator.New()
+			ValidateFilters(v, tt.filters)
+			if len(v.Errors) != len(tt.wantErrs) {
+				t.Fatalf("Got:%v But Wanted errors for:%v", v.Errors, tt.wantErrs)
+			}
+			for _, key := range tt.wantErrs {
+				if _, ok := v.Errors[key]; !ok {
+					t.Errorf("Got:%v But Wanted an error for:%v", v.Errors, key)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,386 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/blue-davinci/aggregate/internal/database"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrProfileNotFound  = errors.New("profile not found")
+	ErrFollowNotFound   = errors.New("follow not found")
+	ErrAlreadyFollowing = errors.New("already following")
+	ErrCannotFollowSelf = errors.New("cannot follow yourself")
+)
+
+const (
+	FollowStatusAccepted = "accepted"
+	FollowStatusPending  = "pending"
+)
+
+const (
+	ActivityTypeFeed    = "feed"
+	ActivityTypeComment = "comment"
+)
+
+// ProfilesModel covers public profiles, user to user follows and the activity feed
+// built from them.
+type ProfilesModel struct {
+	DB *database.Queries
+}
+
+// UserProfile is a user's public profile as seen by a particular viewer. Follow_Status
+// is the viewer's own follow of this user and is empty when they don't follow them.
+type UserProfile struct {
+	ID              int64     `json:"id"`
+	Name            string    `json:"name"`
+	Handle          string    `json:"handle"`
+	User_Img        string    `json:"user_img"`
+	Created_At      time.Time `json:"created_at"`
+	Is_Private      bool      `json:"is_private"`
+	Share_Favorites bool      `json:"share_favorites"`
+	Follower_Count  int64     `json:"follower_count"`
+	Following_Count int64     `json:"following_count"`
+	Follow_Status   string    `json:"follow_status"`
+}
+
+// VisibleTo() reports whether the viewer may see the feeds and favorites on this profile.
+// Public profiles are visible to everyone, private ones only to the owner and to
+// followers whose request was accepted.
+func (p *UserProfile) VisibleTo(viewerID int64) bool {
+	return !p.Is_Private || p.ID == viewerID || p.Follow_Status == FollowStatusAccepted
+}
+
+// ProfileFeed is a feed listed on its creator's profile
+type ProfileFeed struct {
+	ID               uuid.UUID `json:"id"`
+	Name             string    `json:"name"`
+	Url              string    `json:"url"`
+	Img_Url          string    `json:"img_url"`
+	Feed_Type        string    `json:"feed_type"`
+	Feed_Description string    `json:"feed_description"`
+	Created_At       time.Time `json:"created_at"`
+	Follow_Count     int64     `json:"follow_count"`
+}
+
+// ProfileFavorite is a post the user favorited, shown when they share their favorites
+type ProfileFavorite struct {
+	Post_ID      uuid.UUID `json:"post_id"`
+	Title        string    `json:"title"`
+	Url          string    `json:"url"`
+	Img_Url      string    `json:"img_url"`
+	Published_At time.Time `json:"published_at"`
+	Feed_ID      uuid.UUID `json:"feed_id"`
+	Feed_Name    string    `json:"feed_name"`
+	Favorited_At time.Time `json:"favorited_at"`
+}
+
+// ProfileSettings holds a user's privacy choices for their profile
+type ProfileSettings struct {
+	User_ID         int64     `json:"-"`
+	Is_Private      bool      `json:"is_private"`
+	Share_Favorites bool      `json:"share_favorites"`
+	Updated_At      time.Time `json:"updated_at"`
+}
+
+// FollowRequest is a pending follow of a private profile
+type FollowRequest struct {
+	User_ID    int64     `json:"user_id"`
+	Name       string    `json:"name"`
+	Handle     string    `json:"handle"`
+	User_Img   string    `json:"user_img"`
+	Created_At time.Time `json:"created_at"`
+}
+
+// Activity is one entry in the activity feed, either a feed a followed user created or
+// a comment they made. Post and comment fields are only set for comments.
+type Activity struct {
+	Activity_Type string     `json:"activity_type"`
+	User_ID       int64      `json:"user_id"`
+	User_Name     string     `json:"user_name"`
+	User_Handle   string     `json:"user_handle"`
+	User_Img      string     `json:"user_img"`
+	Feed_ID       uuid.UUID  `json:"feed_id"`
+	Feed_Name     string     `json:"feed_name"`
+	Post_ID       *uuid.UUID `json:"post_id,omitempty"`
+	Post_Title    string     `json:"post_title,omitempty"`
+	Comment_ID    *uuid.UUID `json:"comment_id,omitempty"`
+	Comment_Text  string     `json:"comment_text,omitempty"`
+	Created_At    time.Time  `json:"created_at"`
+}
+
+// GetProfile() returns the profile of the activated user with the given handle as seen
+// by viewerID, which is 0 for anonymous visitors.
+func (m ProfilesModel) GetProfile(handle string, viewerID int64) (*UserProfile, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	row, err := m.DB.GetUserProfileByHandle(ctx, database.GetUserProfileByHandleParams{
+		Handle:     handle,
+		FollowerID: viewerID,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrProfileNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &UserProfile{
+		ID:              row.ID,
+		Name:            row.Name,
+		Handle:          row.Handle,
+		User_Img:        row.UserImg,
+		Created_At:      row.CreatedAt,
+		Is_Private:      row.IsPrivate,
+		Share_Favorites: row.ShareFavorites,
+		Follower_Count:  row.FollowerCount,
+		Following_Count: row.FollowingCount,
+		Follow_Status:   row.FollowStatus,
+	}, nil
+}
+
+// GetProfileFeeds() returns the approved, visible feeds a user created, newest first
+func (m ProfilesModel) GetProfileFeeds(userID int64, filters Filters) ([]*ProfileFeed, Metadata, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := m.DB.GetProfileFeeds(ctx, database.GetProfileFeedsParams{
+		UserID: userID,
+		Limit:  int32(filters.limit()),
+		Offset: int32(filters.offset()),
+	})
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	feeds := []*ProfileFeed{}
+	totalRecords := 0
+	for _, row := range rows {
+		totalRecords = int(row.TotalRecords)
+		feeds = append(feeds, &ProfileFeed{
+			ID:               row.ID,
+			Name:             row.Name,
+			Url:              row.Url,
+			Img_Url:          row.ImgUrl,
+			Feed_Type:        row.FeedType,
+			Feed_Description: row.FeedDescription,
+			Created_At:       row.CreatedAt,
+			Follow_Count:     row.FollowCount,
+		})
+	}
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return feeds, metadata, nil
+}
+
+// GetProfileFavorites() returns the posts a user favorited, most recently favorited first
+func (m ProfilesModel) GetProfileFavorites(userID int64, filters Filters) ([]*ProfileFavorite, Metadata, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := m.DB.GetProfileFavorites(ctx, database.GetProfileFavoritesParams{
+		UserID: userID,
+		Limit:  int32(filters.limit()),
+		Offset: int32(filters.offset()),
+	})
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	favorites := []*ProfileFavorite{}
+	totalRecords := 0
+	for _, row := range rows {
+		totalRecords = int(row.TotalRecords)
+		favorites = append(favorites, &ProfileFavorite{
+			Post_ID:      row.ID,
+			Title:        row.Itemtitle,
+			Url:          row.Itemurl,
+			Img_Url:      row.ImgUrl,
+			Published_At: row.ItempublishedAt,
+			Feed_ID:      row.FeedID,
+			Feed_Name:    row.FeedName,
+			Favorited_At: row.FavoritedAt,
+		})
+	}
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return favorites, metadata, nil
+}
+
+// FollowUser() makes followerID follow the profile. Follows of private profiles are
+// created pending and only count once the profile owner accepts them.
+func (m ProfilesModel) FollowUser(followerID int64, profile *UserProfile) (string, error) {
+	if followerID == profile.ID {
+		return "", ErrCannotFollowSelf
+	}
+	status := FollowStatusAccepted
+	if profile.Is_Private {
+		status = FollowStatusPending
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	follow, err := m.DB.InsertUserFollow(ctx, database.InsertUserFollowParams{
+		FollowerID: followerID,
+		FollowedID: profile.ID,
+		Status:     status,
+	})
+	if err != nil {
+		switch {
+		// the insert does nothing on conflict, so no row means the follow already exists
+		case errors.Is(err, sql.ErrNoRows):
+			return "", ErrAlreadyFollowing
+		default:
+			return "", err
+		}
+	}
+	return follow.Status, nil
+}
+
+// UnfollowUser() removes followerID's follow of followedID, pending or not
+func (m ProfilesModel) UnfollowUser(followerID, followedID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := m.DB.DeleteUserFollow(ctx, database.DeleteUserFollowParams{
+		FollowerID: followerID,
+		FollowedID: followedID,
+	})
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrFollowNotFound
+	}
+	return nil
+}
+
+// RemoveFollower() lets a user drop one of their followers or decline a follow request
+func (m ProfilesModel) RemoveFollower(userID, followerID int64) error {
+	return m.UnfollowUser(followerID, userID)
+}
+
+// GetFollowRequests() returns the pending follow requests for a user, oldest first
+func (m ProfilesModel) GetFollowRequests(userID int64, filters Filters) ([]*FollowRequest, Metadata, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := m.DB.GetFollowRequests(ctx, database.GetFollowRequestsParams{
+		FollowedID: userID,
+		Limit:      int32(filters.limit()),
+		Offset:     int32(filters.offset()),
+	})
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	requests := []*FollowRequest{}
+	totalRecords := 0
+	for _, row := range rows {
+		totalRecords = int(row.TotalRecords)
+		requests = append(requests, &FollowRequest{
+			User_ID:    row.ID,
+			Name:       row.Name,
+			Handle:     row.Handle,
+			User_Img:   row.UserImg,
+			Created_At: row.CreatedAt,
+		})
+	}
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return requests, metadata, nil
+}
+
+// AcceptFollowRequest() accepts followerID's pending request to follow userID
+func (m ProfilesModel) AcceptFollowRequest(userID, followerID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := m.DB.AcceptFollowRequest(ctx, database.AcceptFollowRequestParams{
+		FollowerID: followerID,
+		FollowedID: userID,
+	})
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrFollowNotFound
+	}
+	return nil
+}
+
+// GetProfileSettings() returns a user's profile settings. Users who never changed them
+// get the defaults, a public profile that doesn't share favorites.
+func (m ProfilesModel) GetProfileSettings(userID int64) (*ProfileSettings, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	settings, err := m.DB.GetProfileSettings(ctx, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return &ProfileSettings{User_ID: userID}, nil
+		default:
+			return nil, err
+		}
+	}
+	return &ProfileSettings{
+		User_ID:         settings.UserID,
+		Is_Private:      settings.IsPrivate,
+		Share_Favorites: settings.ShareFavorites,
+		Updated_At:      settings.UpdatedAt,
+	}, nil
+}
+
+// UpdateProfileSettings() saves a user's profile settings. Making a profile public
+// accepts every follow request still waiting on it.
+func (m ProfilesModel) UpdateProfileSettings(settings *ProfileSettings) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	updated, err := m.DB.UpsertProfileSettings(ctx, database.UpsertProfileSettingsParams{
+		UserID:         settings.User_ID,
+		IsPrivate:      settings.Is_Private,
+		ShareFavorites: settings.Share_Favorites,
+	})
+	if err != nil {
+		return err
+	}
+	settings.Updated_At = updated.UpdatedAt
+	if !settings.Is_Private {
+		return m.DB.AcceptAllFollowRequests(ctx, settings.User_ID)
+	}
+	return nil
+}
+
+// GetFollowActivity() returns the feeds and comments recently created by the users a
+// user follows, newest first. Pending follows don't contribute.
+func (m ProfilesModel) GetFollowActivity(userID int64, filters Filters) ([]*Activity, Metadata, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := m.DB.GetFollowActivity(ctx, database.GetFollowActivityParams{
+		FollowerID: userID,
+		Limit:      int32(filters.limit()),
+		Offset:     int32(filters.offset()),
+	})
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	activities := []*Activity{}
+	totalRecords := 0
+	for _, row := range rows {
+		totalRecords = int(row.TotalRecords)
+		activity := &Activity{
+			Activity_Type: row.ActivityType,
+			User_ID:       row.UserID,
+			User_Name:     row.UserName,
+			User_Handle:   row.UserHandle,
+			User_Img:      row.UserImg,
+			Feed_ID:       row.FeedID,
+			Feed_Name:     row.FeedName,
+			Post_Title:    row.PostTitle,
+			Comment_Text:  row.CommentText,
+			Created_At:    row.CreatedAt,
+		}
+		if row.PostID.Valid {
+			activity.Post_ID = &row.PostID.UUID
+		}
+		if row.CommentID.Valid {
+			activity.Comment_ID = &row.CommentID.UUID
+		}
+		activities = append(activities, activity)
+	}
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return activities, metadata, nil
+}
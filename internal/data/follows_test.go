@@ -0,0 +1,58 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/blue-davinci/aggregate/internal/validator"
+)
+
+func TestUserProfileVisibleTo(t *testing.T) {
+	tests := []struct {
+		name         string
+		isPrivate    bool
+		followStatus string
+		viewerID     int64
+		want         bool
+	}{
+		{name: "Public Anonymous", viewerID: 0, want: true},
+		{name: "Public Stranger", viewerID: 2, want: true},
+		{name: "Private Anonymous", isPrivate: true, viewerID: 0},
+		{name: "Private Stranger", isPrivate: true, viewerID: 2},
+		{name: "Private Pending Follower", isPrivate: true, followStatus: FollowStatusPending, viewerID: 2},
+		{name: "Private Accepted Follower", isPrivate: true, followStatus: FollowStatusAccepted, viewerID: 2, want: true},
+		{name: "Private Owner", isPrivate: true, viewerID: 1, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile := &UserProfile{ID: 1, Is_Private: tt.isPrivate, Follow_Status: tt.followStatus}
+			if got := profile.VisibleTo(tt.viewerID); got != tt.want {
+				t.Errorf("Got:%v But Wanted:%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateHandle(t *testing.T) {
+	tests := []struct {
+		name    string
+		handle  string
+		wantErr bool
+	}{
+		{name: "Valid", handle: "jane_doe"},
+		{name: "Empty", handle: "", wantErr: true},
+		{name: "Too Short", handle: "jd", wantErr: true},
+		{name: "Invalid Characters", handle: "jane-doe", wantErr: true},
+		{name: "Reserved", handle: "activity", wantErr: true},
+		{name: "Reserved Mixed Case", handle: "Follows", wantErr: true},
+		{name: "Contains Reserved", handle: "activity_fan"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateHandle(v, tt.handle)
+			if v.Valid() == tt.wantErr {
+				t.Errorf("Got:%v But Wanted error:%v", v.Errors, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,248 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/blue-davinci/aggregate/internal/database"
+	"github.com/blue-davinci/aggregate/internal/validator"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInboxNotificationNotFound = errors.New("notification not found")
+)
+
+// Inbox notification types
+const (
+	InboxTypeNewPosts        = "new_posts"
+	InboxTypeReply           = "reply"
+	InboxTypeMention         = "mention"
+	InboxTypeFavoriteComment = "favorite_comment"
+	InboxTypeCommentReaction = "comment_reaction"
+	InboxTypeFeedApproved    = "feed_approved"
+	InboxTypeFeedRejected    = "feed_rejected"
+	InboxTypeBilling         = "billing"
+	InboxTypeFollow          = "follow"
+	InboxTypeShare           = "share"
+	InboxTypeOrganization    = "organization"
+)
+
+// Inbox read states a user can filter by
+const (
+	InboxStatusAll    = "all"
+	InboxStatusUnread = "unread"
+	InboxStatusRead   = "read"
+)
+
+type InboxModel struct {
+	DB *database.Queries
+}
+
+// UserNotification is a single entry in a user's durable notification inbox. Unlike the
+// interval based notifications, inbox entries belong to one user and stay around until
+// the retention job removes them. Dismissed entries are hidden from the inbox straight away.
+type UserNotification struct {
+	ID                int64         `json:"id"`
+	User_ID           int64         `json:"-"`
+	Notification_Type string        `json:"notification_type"`
+	Message           string        `json:"message"`
+	Feed_ID           uuid.NullUUID `json:"feed_id"`
+	Post_ID           uuid.NullUUID `json:"post_id"`
+	Comment_ID        uuid.NullUUID `json:"comment_id"`
+	Read              bool          `json:"read"`
+	Read_At           *time.Time    `json:"read_at,omitempty"`
+	Created_At        time.Time     `json:"created_at"`
+}
+
+// InboxFilters narrows down the inbox listing by read state and notification type
+type InboxFilters struct {
+	Status            string
+	Notification_Type string
+}
+
+func ValidateInboxFilters(v *validator.Validator, filters InboxFilters) {
+	v.Check(validator.PermittedValue(filters.Status, InboxStatusAll, InboxStatusUnread, InboxStatusRead), "status", "must be one of all, unread or read")
+	if filters.Notification_Type != "" {
+		v.Check(validator.PermittedValue(filters.Notification_Type, InboxTypeNewPosts, InboxTypeReply, InboxTypeMention, InboxTypeFavoriteComment,
+			InboxTypeCommentReaction, InboxTypeFeedApproved, InboxTypeFeedRejected, InboxTypeBilling, InboxTypeFollow, InboxTypeShare,
+			InboxTypeOrganization), "type", "invalid notification type")
+	}
+}
+
+// CreateUserNotification() adds a single notification to a user's inbox
+func (m InboxModel) CreateUserNotification(notification *UserNotification) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	row, err := m.DB.InsertUserNotification(ctx, database.InsertUserNotificationParams{
+		UserID:           notification.User_ID,
+		NotificationType: notification.Notification_Type,
+		Message:          notification.Message,
+		FeedID:           notification.Feed_ID,
+		PostID:           notification.Post_ID,
+		CommentID:        notification.Comment_ID,
+	})
+	if err != nil {
+		return err
+	}
+	*notification = *populateUserNotification(row)
+	return nil
+}
+
+// CreateFeedFollowerNotifications() adds a new posts notification to the inbox of
+// everyone following the feed.
+func (m InboxModel) CreateFeedFollowerNotifications(feedID uuid.UUID, message string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return m.DB.InsertFeedFollowerNotifications(ctx, database.InsertFeedFollowerNotificationsParams{
+		FeedID:  feedID,
+		Column2: message,
+	})
+}
+
+// CreateCommentNotifications() adds a new comment to the inbox of the replied-to author
+// as a reply, to the users it mentions as a mention, and to everyone who favorited the
+// post as a comment on a favorited post.
+func (m InboxModel) CreateCommentNotifications(commentID uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return m.DB.InsertCommentNotifications(ctx, commentID)
+}
+
+// GetUserInbox() returns a page of the user's inbox, newest first, together with the
+// number of unread notifications.
+func (m InboxModel) GetUserInbox(userID int64, inboxFilters InboxFilters, filters Filters) ([]*UserNotification, int64, Metadata, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := m.DB.GetUserNotificationsInbox(ctx, database.GetUserNotificationsInboxParams{
+		UserID:  userID,
+		Column2: inboxFilters.Status,
+		Column3: inboxFilters.Notification_Type,
+		Limit:   int32(filters.limit()),
+		Offset:  int32(filters.offset()),
+	})
+	if err != nil {
+		return nil, 0, Metadata{}, err
+	}
+	notifications := []*UserNotification{}
+	totalRecords := 0
+	for _, row := range rows {
+		totalRecords = int(row.TotalRecords)
+		notifications = append(notifications, populateUserNotification(database.UserNotification{
+			ID:               row.ID,
+			UserID:           row.UserID,
+			NotificationType: row.NotificationType,
+			Message:          row.Message,
+			FeedID:           row.FeedID,
+			PostID:           row.PostID,
+			CommentID:        row.CommentID,
+			ReadAt:           row.ReadAt,
+			DismissedAt:      row.DismissedAt,
+			CreatedAt:        row.CreatedAt,
+		}))
+	}
+	unread, err := m.DB.GetUnreadUserNotificationCount(ctx, userID)
+	if err != nil {
+		return nil, 0, Metadata{}, err
+	}
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return notifications, unread, metadata, nil
+}
+
+// SetUserNotificationRead() marks a notification as read or unread
+func (m InboxModel) SetUserNotificationRead(notificationID, userID int64, read bool) (*UserNotification, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	row, err := m.DB.MarkUserNotificationRead(ctx, database.MarkUserNotificationReadParams{
+		ID:      notificationID,
+		UserID:  userID,
+		Column3: read,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrInboxNotificationNotFound
+		default:
+			return nil, err
+		}
+	}
+	return populateUserNotification(row), nil
+}
+
+// MarkAllUserNotificationsRead() marks the whole inbox as read and returns how many
+// notifications were updated.
+func (m InboxModel) MarkAllUserNotificationsRead(userID int64) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return m.DB.MarkAllUserNotificationsRead(ctx, userID)
+}
+
+// MarkPostUserNotificationsRead() marks a user's notifications about a post as read
+func (m InboxModel) MarkPostUserNotificationsRead(userID int64, postID uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return m.DB.MarkPostUserNotificationsRead(ctx, database.MarkPostUserNotificationsReadParams{
+		UserID: userID,
+		PostID: uuid.NullUUID{UUID: postID, Valid: true},
+	})
+}
+
+// DismissUserNotification() hides a notification from the user's inbox
+func (m InboxModel) DismissUserNotification(notificationID, userID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := m.DB.DismissUserNotification(ctx, database.DismissUserNotificationParams{
+		ID:     notificationID,
+		UserID: userID,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrInboxNotificationNotFound
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateMentionNotifications() adds a mention notification to the inbox of each of the
+// given users. It is used for users newly mentioned when a comment is edited, new comments
+// notify their mentions through CreateCommentNotifications().
+func (m InboxModel) CreateMentionNotifications(commentID uuid.UUID, userIDs []int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return m.DB.InsertMentionNotifications(ctx, database.InsertMentionNotificationsParams{
+		ID:      commentID,
+		Column2: userIDs,
+	})
+}
+
+// DeleteExpiredUserNotifications() applies the inbox retention policy. Every notification
+// older than retentionDays is removed, while read and dismissed ones are removed once
+// they are older than readRetentionDays.
+func (m InboxModel) DeleteExpiredUserNotifications(retentionDays, readRetentionDays int) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return m.DB.DeleteExpiredUserNotifications(ctx, database.DeleteExpiredUserNotificationsParams{
+		Column1: int32(retentionDays),
+		Column2: int32(readRetentionDays),
+	})
+}
+
+func populateUserNotification(row database.UserNotification) *UserNotification {
+	return &UserNotification{
+		ID:                row.ID,
+		User_ID:           row.UserID,
+		Notification_Type: row.NotificationType,
+		Message:           row.Message,
+		Feed_ID:           row.FeedID,
+		Post_ID:           row.PostID,
+		Comment_ID:        row.CommentID,
+		Read:              row.ReadAt.Valid,
+		Read_At:           nullTimeToTime(row.ReadAt),
+		Created_At:        row.CreatedAt,
+	}
+}
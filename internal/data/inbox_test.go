@@ -0,0 +1,36 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/blue-davinci/aggregate/internal/validator"
+)
+
+func TestValidateInboxFilters(t *testing.T) {
+	tests := []struct {
+		name     string
+		filters  InboxFilters
+		wantErrs []string
+	}{
+		{name: "All", filters: InboxFilters{Status: InboxStatusAll}},
+		{name: "Unread Replies", filters: InboxFilters{Status: InboxStatusUnread, Notification_Type: InboxTypeReply}},
+		{name: "Read Billing", filters: InboxFilters{Status: InboxStatusRead, Notification_Type: InboxTypeBilling}},
+		{name: "Unknown Status", filters: InboxFilters{Status: "archived"}, wantErrs: []string{"status"}},
+		{name: "Unread Mentions", filters: InboxFilters{Status: InboxStatusUnread, Notification_Type: InboxTypeMention}},
+		{name: "Unknown Type", filters: InboxFilters{Status: InboxStatusAll, Notification_Type: "birthday"}, wantErrs: []string{"type"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateInboxFilters(v, tt.filters)
+			if len(v.Errors) != len(tt.wantErrs) {
+				t.Fatalf("Got:%v But Wanted errors for:%v", v.Errors, tt.wantErrs)
+			}
+			for _, key := range tt.wantErrs {
+				if _, ok := v.Errors[key]; !ok {
+					t.Errorf("Got:%v But Wanted an error for:%v", v.Errors, key)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package data
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/blue-davinci/aggregate/internal/pdf"
+)
+
+// the layout of an invoice on an A4 page, in points
+const (
+	invoiceMargin    = 50.0
+	invoiceRight     = pdf.A4Width - invoiceMargin
+	invoiceTop       = pdf.A4Height - invoiceMargin
+	invoiceBottom    = 90.0
+	invoiceLineSpace = 15.0
+	// the right edges of the quantity, unit price and amount columns
+	invoiceQtyColumn    = 360.0
+	invoiceUnitColumn   = 450.0
+	invoiceAmountColumn = invoiceRight
+)
+
+// PDF() renders the invoice as a PDF document. The seller comes from the settings the
+// invoice is rendered with, the customer and amounts are the ones it was issued with.
+func (invoice *Invoice) PDF(settings InvoiceSettings) []byte {
+	doc := pdf.New(fmt.Sprintf("Invoice %s", invoice.Number))
+	page := doc.AddPage()
+
+	// who the invoice is from on the left and what it is on the right
+	y := invoiceTop
+	seller := settings.Seller_Name
+	if seller == "" {
+		seller = "Aggregate"
+	}
+	page.Text(invoiceMargin, y, pdf.HelveticaBold, 16, pdf.Truncate(pdf.HelveticaBold, 16, seller, 280))
+	page.TextRight(invoiceRight, y, pdf.HelveticaBold, 20, "INVOICE")
+	sellerLines := splitInvoiceLines(settings.Seller_Address)
+	if settings.Seller_Tax_ID != "" {
+		sellerLines = append(sellerLines, "Tax ID: "+settings.Seller_Tax_ID)
+	}
+	details := []string{
+		"Invoice number: " + invoice.Number,
+		"Date: " + invoice.Issued_At.UTC().Format("Jan 2, 2006"),
+		"Status: " + invoice.Status,
+	}
+	y -= 20
+	for i := 0; i < max(len(sellerLines), len(details)); i++ {
+		if i < len(sellerLines) {
+			page.Text(invoiceMargin, y, pdf.Helvetica, 10, pdf.Truncate(pdf.Helvetica, 10, sellerLines[i], 280))
+		}
+		if i < len(details) {
+			page.TextRight(invoiceRight, y, pdf.Helvetica, 10, details[i])
+		}
+		y -= invoiceLineSpace
+	}
+
+	// who it is made out to
+	y -= 20
+	page.Text(invoiceMargin, y, pdf.HelveticaBold, 11, "Bill to")
+	y -= invoiceLineSpace
+	billTo := []string{invoice.Billing.Name, invoice.Billing.Company, invoice.Billing.Email}
+	billTo = append(billTo, splitInvoiceLines(invoice.Billing.Address)...)
+	billTo = append(billTo, invoice.Billing.Country)
+	if invoice.Billing.Tax_ID != "" {
+		billTo = append(billTo, "Tax ID: "+invoice.Billing.Tax_ID)
+	}
+	for _, line := range billTo {
+		if line == "" {
+			continue
+		}
+		page.Text(invoiceMargin, y, pdf.Helvetica, 10, pdf.Truncate(pdf.Helvetica, 10, line, 300))
+		y -= invoiceLineSpace
+	}
+
+	// the line items, carried onto a new page when they run out of room
+	y -= 20
+	header := func() {
+		page.Rect(invoiceMargin, y-6, invoiceRight-invoiceMargin, 20, 0.93)
+		page.Text(invoiceMargin+6, y, pdf.HelveticaBold, 10, "Description")
+		page.TextRight(invoiceQtyColumn, y, pdf.HelveticaBold, 10, "Qty")
+		page.TextRight(invoiceUnitColumn, y, pdf.HelveticaBold, 10, "Unit price")
+		page.TextRight(invoiceAmountColumn-6, y, pdf.HelveticaBold, 10, "Amount")
+		y -= 24
+	}
+	header()
+	for _, line := range invoice.Line_Items {
+		if y < invoiceBottom {
+			page = doc.AddPage()
+			y = invoiceTop
+			header()
+		}
+		page.Text(invoiceMargin+6, y, pdf.Helvetica, 10, pdf.Truncate(pdf.Helvetica, 10, line.Description, 250))
+		page.TextRight(invoiceQtyColumn, y, pdf.Helvetica, 10, fmt.Sprintf("%d", line.Quantity))
+		page.TextRight(invoiceUnitColumn, y, pdf.Helvetica, 10, line.Unit_Amount.String())
+		page.TextRight(invoiceAmountColumn-6, y, pdf.Helvetica, 10, line.Amount.String())
+		y -= 8
+		page.Line(invoiceMargin, y, invoiceRight, y, 0.5, 0.85)
+		y -= invoiceLineSpace
+	}
+
+	// the totals under the amount column
+	if y < invoiceBottom+60 {
+		page = doc.AddPage()
+		y = invoiceTop
+	}
+	y -= 5
+	totals := [][2]string{{"Subtotal", invoice.Subtotal.String()}}
+	if invoice.Discount.Amount > 0 {
+		totals = append(totals, [2]string{"Discount", "-" + invoice.Discount.String()})
+	}
+	for _, total := range totals {
+		page.TextRight(invoiceUnitColumn, y, pdf.Helvetica, 10, total[0])
+		page.TextRight(invoiceAmountColumn-6, y, pdf.Helvetica, 10, total[1])
+		y -= invoiceLineSpace
+	}
+	page.TextRight(invoiceUnitColumn, y, pdf.HelveticaBold, 11, "Total")
+	page.TextRight(invoiceAmountColumn-6, y, pdf.HelveticaBold, 11, invoice.Total.String())
+	y -= invoiceLineSpace
+	if invoice.Tax_Rate > 0 {
+		taxName := invoice.Tax_Name
+		if taxName == "" {
+			taxName = "Tax"
+		}
+		page.TextRight(invoiceUnitColumn, y, pdf.Helvetica, 9, fmt.Sprintf("Includes %s at %s", taxName, FormatTaxRate(invoice.Tax_Rate)))
+		page.TextRight(invoiceAmountColumn-6, y, pdf.Helvetica, 9, invoice.Tax.String())
+		y -= invoiceLineSpace
+	}
+
+	// how it was paid
+	y -= 20
+	paidWith := fmt.Sprintf("Paid with %s via %s, transaction %d", invoice.Payment_Method, invoice.Provider, invoice.Transaction_ID)
+	if invoice.Payment_Method == "" {
+		paidWith = fmt.Sprintf("Paid via %s, transaction %d", invoice.Provider, invoice.Transaction_ID)
+	}
+	page.Text(invoiceMargin, y, pdf.Helvetica, 9, pdf.Truncate(pdf.Helvetica, 9, paidWith, invoiceRight-invoiceMargin))
+	page.Text(invoiceMargin, invoiceMargin, pdf.Helvetica, 9, "Thank you for your subscription.")
+	return doc.Bytes()
+}
+
+// splitInvoiceLines() splits a multi line address into its lines, leaving out blank ones
+func splitInvoiceLines(s string) []string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
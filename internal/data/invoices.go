@@ -0,0 +1,452 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blue-davinci/aggregate/internal/database"
+	"github.com/blue-davinci/aggregate/internal/validator"
+	"github.com/google/uuid"
+)
+
+const (
+	InvoiceStatusPaid              = "paid"
+	InvoiceStatusPartiallyRefunded = "partially_refunded"
+	InvoiceStatusRefunded          = "refunded"
+	InvoiceNumberPrefix            = "INV-"
+)
+
+var (
+	ErrInvoiceNotFound        = errors.New("invoice not found")
+	ErrBillingDetailsNotFound = errors.New("billing details not found")
+)
+
+// CountryCodeRX is an ISO 3166 alpha-2 country code such as KE
+var CountryCodeRX = regexp.MustCompile(`^[A-Z]{2}$`)
+
+type InvoicesModel struct {
+	DB *database.Queries
+}
+
+// BillingDetails are who a user's invoices are made out to. Empty fields are left off
+// the invoice and the user's own name stands in for an empty Name.
+type BillingDetails struct {
+	User_ID    int64     `json:"user_id"`
+	Name       string    `json:"name"`
+	Company    string    `json:"company"`
+	Address    string    `json:"address"`
+	Country    string    `json:"country"`
+	Tax_ID     string    `json:"tax_id"`
+	Updated_At time.Time `json:"updated_at"`
+	Version    int32     `json:"version"`
+}
+
+// InvoiceBilling is the copy of the billing details an invoice was issued with, later
+// changes to a user's details don't touch invoices already issued.
+type InvoiceBilling struct {
+	Name    string `json:"name"`
+	Email   string `json:"email"`
+	Company string `json:"company,omitempty"`
+	Address string `json:"address,omitempty"`
+	Country string `json:"country,omitempty"`
+	Tax_ID  string `json:"tax_id,omitempty"`
+}
+
+// InvoiceSettings hold who issues the invoices and the tax charged. Prices include the
+// tax, Tax_Rate is in basis points so 1600 is 16%.
+type InvoiceSettings struct {
+	Seller_Name    string
+	Seller_Address string
+	Seller_Tax_ID  string
+	Tax_Name       string
+	Tax_Rate       int32
+}
+
+// InvoiceLineItem is a line of an invoice, discounts and credits are lines with a
+// negative amount.
+type InvoiceLineItem struct {
+	Description string `json:"description"`
+	Quantity    int32  `json:"quantity"`
+	Unit_Amount Money  `json:"unit_amount"`
+	Amount      Money  `json:"amount"`
+}
+
+// Invoice is issued for each payment that starts a subscription. Total is what was
+// charged, Subtotal less the Discount, and Tax is the part of it that is tax.
+type Invoice struct {
+	ID              int64             `json:"id"`
+	Number          string            `json:"number"`
+	User_ID         int64             `json:"user_id"`
+	Subscription_ID uuid.UUID         `json:"subscription_id"`
+	Plan_ID         int32             `json:"plan_id"`
+	Transaction_ID  int64             `json:"transaction_id"`
+	Provider        string            `json:"provider"`
+	Payment_Method  string            `json:"payment_method"`
+	Subtotal        Money             `json:"subtotal"`
+	Discount        Money             `json:"discount"`
+	Tax             Money             `json:"tax"`
+	Tax_Rate        int32             `json:"tax_rate"`
+	Tax_Name        string            `json:"tax_name"`
+	Total           Money             `json:"total"`
+	Billing         InvoiceBilling    `json:"billing"`
+	Status          string            `json:"status"`
+	Issued_At       time.Time         `json:"issued_at"`
+	Line_Items      []InvoiceLineItem `json:"line_items,omitempty"`
+}
+
+// InvoiceSearch narrows the invoices admins list. Query matches the customer's name,
+// email or company, or an invoice number. Zero values don't narrow the search.
+type InvoiceSearch struct {
+	Query    string
+	User_ID  int64
+	Currency string
+	From     time.Time
+	To       time.Time
+}
+
+// FormatInvoiceNumber() is how invoice numbers are shown, such as INV-000042
+func FormatInvoiceNumber(number int64) string {
+	return fmt.Sprintf("%s%06d", InvoiceNumberPrefix, number)
+}
+
+// ParseInvoiceNumber() reads an invoice number as it is shown, or without its prefix and
+// leading zeros, returning false when s isn't one.
+func ParseInvoiceNumber(s string) (int64, bool) {
+	s = strings.TrimSpace(strings.ToUpper(s))
+	s = strings.TrimPrefix(s, InvoiceNumberPrefix)
+	number, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || number < 1 {
+		return 0, false
+	}
+	return number, true
+}
+
+// InclusiveTax() is the tax within a total that includes it, rounded half up to the
+// currency's minor unit. rate is in basis points.
+func InclusiveTax(total Money, rate int32) Money {
+	if rate <= 0 || total.Amount <= 0 {
+		return NewMoney(0, total.Currency)
+	}
+	divisor := int64(10000 + rate)
+	return NewMoney((total.Amount*int64(rate)*2+divisor)/(divisor*2), total.Currency)
+}
+
+// FormatTaxRate() formats a rate in basis points as a percentage, 1600 is "16%" and
+// 825 is "8.25%".
+func FormatTaxRate(rate int32) string {
+	return strconv.FormatFloat(float64(rate)/100, 'f', -1, 64) + "%"
+}
+
+// SubscriptionLineItems() are the lines of an invoice for a subscription to a plan that
+// costs price and was charged. When less was charged the difference is a line of its own
+// described by adjustment, such as a promo code or the credit left from a plan change.
+func SubscriptionLineItems(planName string, price, charged Money, adjustment string) []InvoiceLineItem {
+	lines := []InvoiceLineItem{{
+		Description: planName + " subscription",
+		Quantity:    1,
+		Unit_Amount: price,
+		Amount:      price,
+	}}
+	if charged.Currency == price.Currency && charged.Amount < price.Amount {
+		credit := NewMoney(charged.Amount-price.Amount, price.Currency)
+		lines = append(lines, InvoiceLineItem{Description: adjustment, Quantity: 1, Unit_Amount: credit, Amount: credit})
+	}
+	return lines
+}
+
+// NewInvoice() prepares the invoice for a subscription payment out of its line items.
+// The amounts are totalled from the lines and the tax is worked out of the total.
+func NewInvoice(payment *Payment_Details, lines []InvoiceLineItem, billing InvoiceBilling, settings InvoiceSettings) *Invoice {
+	currency := payment.Price.Currency
+	var subtotal, discount int64
+	for _, line := range lines {
+		if line.Amount.Amount < 0 {
+			discount -= line.Amount.Amount
+		} else {
+			subtotal += line.Amount.Amount
+		}
+	}
+	total := NewMoney(subtotal-discount, currency)
+	return &Invoice{
+		User_ID:         payment.User_ID,
+		Subscription_ID: payment.ID,
+		Plan_ID:         payment.Plan_ID,
+		Transaction_ID:  payment.TransactionID,
+		Provider:        payment.Provider,
+		Payment_Method:  payment.Payment_Method,
+		Subtotal:        NewMoney(subtotal, currency),
+		Discount:        NewMoney(discount, currency),
+		Tax:             InclusiveTax(total, settings.Tax_Rate),
+		Tax_Rate:        settings.Tax_Rate,
+		Tax_Name:        settings.Tax_Name,
+		Total:           total,
+		Billing:         billing,
+		Status:          InvoiceStatusPaid,
+		Line_Items:      lines,
+	}
+}
+
+// InvoiceBillingFor() is who an invoice for the user is made out to, their billing
+// details when they saved some and else just their name and email.
+func InvoiceBillingFor(name, email string, details *BillingDetails) InvoiceBilling {
+	billing := InvoiceBilling{Name: name, Email: email}
+	if details == nil {
+		return billing
+	}
+	if details.Name != "" {
+		billing.Name = details.Name
+	}
+	billing.Company = details.Company
+	billing.Address = details.Address
+	billing.Country = details.Country
+	billing.Tax_ID = details.Tax_ID
+	return billing
+}
+
+func ValidateBillingDetails(v *validator.Validator, details *BillingDetails) {
+	v.Check(len(details.Name) <= 200, "name", "must not be more than 200 bytes long")
+	v.Check(len(details.Company) <= 200, "company", "must not be more than 200 bytes long")
+	v.Check(len(details.Address) <= 500, "address", "must not be more than 500 bytes long")
+	v.Check(details.Country == "" || validator.Matches(details.Country, CountryCodeRX), "country", "must be a 2 letter ISO country code")
+	v.Check(len(details.Tax_ID) <= 50, "tax_id", "must not be more than 50 bytes long")
+}
+
+// GetBillingDetails() returns the details a user's invoices are made out to
+func (m InvoicesModel) GetBillingDetails(userID int64) (*BillingDetails, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	row, err := m.DB.GetBillingDetails(ctx, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrBillingDetailsNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &BillingDetails{
+		User_ID:    row.UserID,
+		Name:       row.Name,
+		Company:    row.Company,
+		Address:    row.Address,
+		Country:    row.Country,
+		Tax_ID:     row.TaxID,
+		Updated_At: row.UpdatedAt,
+		Version:    row.Version,
+	}, nil
+}
+
+// SaveBillingDetails() saves a user's billing details, replacing any they had
+func (m InvoicesModel) SaveBillingDetails(details *BillingDetails) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	row, err := m.DB.UpsertBillingDetails(ctx, database.UpsertBillingDetailsParams{
+		UserID:  details.User_ID,
+		Name:    details.Name,
+		Company: details.Company,
+		Address: details.Address,
+		Country: details.Country,
+		TaxID:   details.Tax_ID,
+	})
+	if err != nil {
+		return err
+	}
+	details.Updated_At = row.UpdatedAt
+	details.Version = row.Version
+	return nil
+}
+
+// CreateInvoice() issues an invoice, giving it the next invoice number, and saves its
+// line items.
+func (m InvoicesModel) CreateInvoice(invoice *Invoice) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	row, err := m.DB.CreateInvoice(ctx, database.CreateInvoiceParams{
+		UserID:         invoice.User_ID,
+		SubscriptionID: uuid.NullUUID{UUID: invoice.Subscription_ID, Valid: invoice.Subscription_ID != uuid.Nil},
+		PlanID:         sql.NullInt32{Int32: invoice.Plan_ID, Valid: invoice.Plan_ID != 0},
+		TransactionID:  invoice.Transaction_ID,
+		Provider:       invoice.Provider,
+		PaymentMethod:  invoice.Payment_Method,
+		Currency:       invoice.Total.Currency,
+		Subtotal:       invoice.Subtotal.Amount,
+		Discount:       invoice.Discount.Amount,
+		Tax:            invoice.Tax.Amount,
+		TaxRate:        invoice.Tax_Rate,
+		TaxName:        invoice.Tax_Name,
+		Total:          invoice.Total.Amount,
+		BillingName:    invoice.Billing.Name,
+		BillingEmail:   invoice.Billing.Email,
+		BillingCompany: invoice.Billing.Company,
+		BillingAddress: invoice.Billing.Address,
+		BillingCountry: invoice.Billing.Country,
+		BillingTaxID:   invoice.Billing.Tax_ID,
+	})
+	if err != nil {
+		return err
+	}
+	invoice.ID = row.ID
+	invoice.Number = FormatInvoiceNumber(row.Number)
+	invoice.Status = row.Status
+	invoice.Issued_At = row.IssuedAt
+	for i, line := range invoice.Line_Items {
+		err = m.DB.CreateInvoiceLineItem(ctx, database.CreateInvoiceLineItemParams{
+			InvoiceID:   invoice.ID,
+			Position:    int32(i + 1),
+			Description: line.Description,
+			Quantity:    line.Quantity,
+			UnitAmount:  line.Unit_Amount.Amount,
+			Amount:      line.Amount.Amount,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetInvoiceByID() returns an invoice along with its line items
+func (m InvoicesModel) GetInvoiceByID(invoiceID int64) (*Invoice, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	row, err := m.DB.GetInvoiceByID(ctx, invoiceID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrInvoiceNotFound
+		default:
+			return nil, err
+		}
+	}
+	invoice := invoiceFromRow(row)
+	lines, err := m.DB.GetInvoiceLineItems(ctx, invoice.ID)
+	if err != nil {
+		return nil, err
+	}
+	invoice.Line_Items = []InvoiceLineItem{}
+	for _, line := range lines {
+		invoice.Line_Items = append(invoice.Line_Items, InvoiceLineItem{
+			Description: line.Description,
+			Quantity:    line.Quantity,
+			Unit_Amount: NewMoney(line.UnitAmount, row.Currency),
+			Amount:      NewMoney(line.Amount, row.Currency),
+		})
+	}
+	return invoice, nil
+}
+
+// GetInvoicesByUser() returns a user's invoices, the latest first. Line items are left
+// out of listings.
+func (m InvoicesModel) GetInvoicesByUser(userID int64, filters Filters) ([]*Invoice, Metadata, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := m.DB.GetInvoicesByUser(ctx, database.GetInvoicesByUserParams{
+		UserID: userID,
+		Limit:  int32(filters.limit()),
+		Offset: int32(filters.offset()),
+	})
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	totalRecords := 0
+	invoices := []*Invoice{}
+	for _, row := range rows {
+		totalRecords = int(row.TotalRecords)
+		// both listings select the same columns
+		invoices = append(invoices, invoiceFromListRow(database.AdminSearchInvoicesRow(row)))
+	}
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return invoices, metadata, nil
+}
+
+// AdminSearchInvoices() lists the invoices matching a search, the latest first
+func (m InvoicesModel) AdminSearchInvoices(search InvoiceSearch, filters Filters) ([]*Invoice, Metadata, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	query := strings.TrimSpace(search.Query)
+	number, _ := ParseInvoiceNumber(query)
+	rows, err := m.DB.AdminSearchInvoices(ctx, database.AdminSearchInvoicesParams{
+		Column1: query,
+		Column2: number,
+		Column3: search.User_ID,
+		Column4: strings.ToUpper(search.Currency),
+		Column5: search.From,
+		Column6: search.To,
+		Limit:   int32(filters.limit()),
+		Offset:  int32(filters.offset()),
+	})
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	totalRecords := 0
+	invoices := []*Invoice{}
+	for _, row := range rows {
+		totalRecords = int(row.TotalRecords)
+		invoices = append(invoices, invoiceFromListRow(row))
+	}
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return invoices, metadata, nil
+}
+
+func invoiceFromListRow(row database.AdminSearchInvoicesRow) *Invoice {
+	return invoiceFromRow(database.Invoice{
+		ID:             row.ID,
+		Number:         row.Number,
+		UserID:         row.UserID,
+		SubscriptionID: row.SubscriptionID,
+		PlanID:         row.PlanID,
+		TransactionID:  row.TransactionID,
+		Provider:       row.Provider,
+		PaymentMethod:  row.PaymentMethod,
+		Currency:       row.Currency,
+		Subtotal:       row.Subtotal,
+		Discount:       row.Discount,
+		Tax:            row.Tax,
+		TaxRate:        row.TaxRate,
+		TaxName:        row.TaxName,
+		Total:          row.Total,
+		BillingName:    row.BillingName,
+		BillingEmail:   row.BillingEmail,
+		BillingCompany: row.BillingCompany,
+		BillingAddress: row.BillingAddress,
+		BillingCountry: row.BillingCountry,
+		BillingTaxID:   row.BillingTaxID,
+		Status:         row.Status,
+		IssuedAt:       row.IssuedAt,
+	})
+}
+
+func invoiceFromRow(row database.Invoice) *Invoice {
+	return &Invoice{
+		ID:              row.ID,
+		Number:          FormatInvoiceNumber(row.Number),
+		User_ID:         row.UserID,
+		Subscription_ID: row.SubscriptionID.UUID,
+		Plan_ID:         row.PlanID.Int32,
+		Transaction_ID:  row.TransactionID,
+		Provider:        row.Provider,
+		Payment_Method:  row.PaymentMethod,
+		Subtotal:        NewMoney(row.Subtotal, row.Currency),
+		Discount:        NewMoney(row.Discount, row.Currency),
+		Tax:             NewMoney(row.Tax, row.Currency),
+		Tax_Rate:        row.TaxRate,
+		Tax_Name:        row.TaxName,
+		Total:           NewMoney(row.Total, row.Currency),
+		Billing: InvoiceBilling{
+			Name:    row.BillingName,
+			Email:   row.BillingEmail,
+			Company: row.BillingCompany,
+			Address: row.BillingAddress,
+			Country: row.BillingCountry,
+			Tax_ID:  row.BillingTaxID,
+		},
+		Status:    row.Status,
+		Issued_At: row.IssuedAt,
+	}
+}
@@ -0,0 +1,173 @@
+package data
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/blue-davinci/aggregate/internal/validator"
+	"github.com/google/uuid"
+)
+
+func TestInclusiveTax(t *testing.T) {
+	tests := []struct {
+		name  string
+		total Money
+		rate  int32
+		want  int64
+	}{
+		{name: "Sixteen Percent", total: NewMoney(11600, "KES"), rate: 1600, want: 1600},
+		{name: "Rounds Up", total: NewMoney(1000, "USD"), rate: 2000, want: 167},
+		{name: "Nineteen Percent", total: NewMoney(999, "EUR"), rate: 1900, want: 160},
+		{name: "No Tax", total: NewMoney(1000, "USD"), rate: 0, want: 0},
+		{name: "Nothing Charged", total: NewMoney(0, "USD"), rate: 1600, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := InclusiveTax(tt.total, tt.rate)
+			if got.Amount != tt.want || got.Currency != tt.total.Currency {
+				t.Errorf("Got:%v But Wanted:%d %s", got, tt.want, tt.total.Currency)
+			}
+		})
+	}
+}
+
+func TestParseInvoiceNumber(t *testing.T) {
+	tests := []struct {
+		name   string
+		s      string
+		want   int64
+		wantOK bool
+	}{
+		{name: "As Shown", s: "INV-000042", want: 42, wantOK: true},
+		{name: "Lower Case", s: " inv-42 ", want: 42, wantOK: true},
+		{name: "Just Digits", s: "0042", want: 42, wantOK: true},
+		{name: "Email", s: "jane@example.com", wantOK: false},
+		{name: "Zero", s: "INV-000000", wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseInvoiceNumber(tt.s)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("Got:%d, %v But Wanted:%d, %v", got, ok, tt.want, tt.wantOK)
+			}
+			if ok && FormatInvoiceNumber(got) != "INV-000042" {
+				t.Errorf("Got:%s But Wanted:INV-000042", FormatInvoiceNumber(got))
+			}
+		})
+	}
+}
+
+func TestNewInvoice(t *testing.T) {
+	payment := &Payment_Details{
+		ID:             uuid.New(),
+		User_ID:        7,
+		Plan_ID:        2,
+		Price:          NewMoney(2000, "USD"),
+		TransactionID:  99,
+		Payment_Method: "card",
+		Provider:       PaymentProviderStripe,
+	}
+	settings := InvoiceSettings{Tax_Name: "VAT", Tax_Rate: 2000}
+	tests := []struct {
+		name         string
+		charged      Money
+		wantLines    int
+		wantDiscount int64
+		wantTotal    int64
+		wantTax      int64
+	}{
+		{name: "Full Price", charged: NewMoney(2000, "USD"), wantLines: 1, wantDiscount: 0, wantTotal: 2000, wantTax: 333},
+		{name: "Promo Code", charged: NewMoney(1500, "USD"), wantLines: 2, wantDiscount: 500, wantTotal: 1500, wantTax: 250},
+		{name: "Other Currency", charged: NewMoney(1500, "EUR"), wantLines: 1, wantDiscount: 0, wantTotal: 2000, wantTax: 333},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := SubscriptionLineItems("Pro", payment.Price, tt.charged, "Promo code SPRING25")
+			invoice := NewInvoice(payment, lines, InvoiceBilling{Name: "Jane", Email: "jane@example.com"}, settings)
+			if len(invoice.Line_Items) != tt.wantLines {
+				t.Fatalf("Got:%d lines But Wanted:%d", len(invoice.Line_Items), tt.wantLines)
+			}
+			if invoice.Subtotal.Amount != 2000 {
+				t.Errorf("Subtotal Got:%d But Wanted:2000", invoice.Subtotal.Amount)
+			}
+			if invoice.Discount.Amount != tt.wantDiscount {
+				t.Errorf("Discount Got:%d But Wanted:%d", invoice.Discount.Amount, tt.wantDiscount)
+			}
+			if invoice.Total.Amount != tt.wantTotal {
+				t.Errorf("Total Got:%d But Wanted:%d", invoice.Total.Amount, tt.wantTotal)
+			}
+			if invoice.Tax.Amount != tt.wantTax {
+				t.Errorf("Tax Got:%d But Wanted:%d", invoice.Tax.Amount, tt.wantTax)
+			}
+			if invoice.Subscription_ID != payment.ID || invoice.Status != InvoiceStatusPaid {
+				t.Errorf("invoice not issued for the payment")
+			}
+		})
+	}
+}
+
+func TestInvoiceBillingFor(t *testing.T) {
+	got := InvoiceBillingFor("Jane", "jane@example.com", &BillingDetails{Company: "Acme", Country: "KE"})
+	want := InvoiceBilling{Name: "Jane", Email: "jane@example.com", Company: "Acme", Country: "KE"}
+	if got != want {
+		t.Errorf("Got:%+v But Wanted:%+v", got, want)
+	}
+	got = InvoiceBillingFor("Jane", "jane@example.com", &BillingDetails{Name: "Jane Doe"})
+	if got.Name != "Jane Doe" {
+		t.Errorf("Got:%s But Wanted:Jane Doe", got.Name)
+	}
+}
+
+func TestValidateBillingDetails(t *testing.T) {
+	tests := []struct {
+		name    string
+		details BillingDetails
+		valid   bool
+	}{
+		{name: "Valid", details: BillingDetails{Name: "Jane", Country: "KE", Tax_ID: "P051234567X"}, valid: true},
+		{name: "Empty", details: BillingDetails{}, valid: true},
+		{name: "Lower Case Country", details: BillingDetails{Country: "ke"}, valid: false},
+		{name: "Country Name", details: BillingDetails{Country: "Kenya"}, valid: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateBillingDetails(v, &tt.details)
+			if v.Valid() != tt.valid {
+				t.Errorf("Got:%v But Wanted:%v, errors: %v", v.Valid(), tt.valid, v.Errors)
+			}
+		})
+	}
+}
+
+func TestInvoicePDF(t *testing.T) {
+	invoice := &Invoice{
+		Number:         "INV-000042",
+		Provider:       PaymentProviderPaystack,
+		Payment_Method: "card",
+		Subtotal:       NewMoney(2000, "KES"),
+		Discount:       NewMoney(500, "KES"),
+		Tax:            NewMoney(207, "KES"),
+		Tax_Rate:       1600,
+		Tax_Name:       "VAT",
+		Total:          NewMoney(1500, "KES"),
+		Billing:        InvoiceBilling{Name: "Jane (Acme)", Email: "jane@example.com", Address: "1 Moi Avenue\nNairobi"},
+		Status:         InvoiceStatusPaid,
+		Issued_At:      time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC),
+		Line_Items: []InvoiceLineItem{
+			{Description: "Pro subscription", Quantity: 1, Unit_Amount: NewMoney(2000, "KES"), Amount: NewMoney(2000, "KES")},
+			{Description: "Promo code SPRING25", Quantity: 1, Unit_Amount: NewMoney(-500, "KES"), Amount: NewMoney(-500, "KES")},
+		},
+	}
+	out := invoice.PDF(InvoiceSettings{Seller_Name: "Aggregate Ltd", Seller_Tax_ID: "P000000000A"})
+	if !bytes.HasPrefix(out, []byte("%PDF-")) {
+		t.Fatalf("not a PDF")
+	}
+	for _, want := range []string{"Invoice number: INV-000042", "Jane \\(Acme\\)", "Nairobi", "KES -5.00",
+		"Includes VAT at 16%", "Date: Jul 1, 2024", "Tax ID: P000000000A"} {
+		if !bytes.Contains(out, []byte(want)) {
+			t.Errorf("PDF is missing %q", want)
+		}
+	}
+}
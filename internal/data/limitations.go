@@ -16,6 +16,7 @@ type LimitationsItmes struct {
 	Followed_Feeds int64 `json:"followed_feeds"`
 	Created_Feeds  int64 `json:"created_feeds"`
 	Comments_Today int64 `json:"comments_today"`
+	Saved_Searches int64 `json:"saved_searches"`
 }
 
 // Get the limitations for a user
@@ -33,8 +34,24 @@ func (m LimitationsModel) GetUserLimitations(userID int64) (*LimitationsItmes, e
 		Followed_Feeds: queryResult.FollowedFeeds,
 		Created_Feeds:  queryResult.CreatedFeeds,
 		Comments_Today: queryResult.CommentsToday,
+		Saved_Searches: queryResult.SavedSearches,
 	}
 
 	// Return the limitations
 	return &limitations, nil
 }
+
+// Used() returns how much of a quota entitlement the user has used
+func (l *LimitationsItmes) Used(entitlement string) int64 {
+	switch entitlement {
+	case EntitlementFeedsCreated:
+		return l.Created_Feeds
+	case EntitlementFeedsFollowed:
+		return l.Followed_Feeds
+	case EntitlementCommentsPerDay:
+		return l.Comments_Today
+	case EntitlementSavedSearches:
+		return l.Saved_Searches
+	}
+	return 0
+}
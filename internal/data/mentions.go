@@ -0,0 +1,138 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/blue-davinci/aggregate/internal/database"
+	"github.com/google/uuid"
+)
+
+// MaxCommentMentions caps how many users a single comment can notify
+const MaxCommentMentions = 10
+
+// mentionRX finds @handle mentions. The @ has to start the text or follow a character
+// that can't be part of a handle, so email addresses are not picked up as mentions.
+var mentionRX = regexp.MustCompile(`(?:^|[^\w@])@([a-zA-Z0-9_]{3,30})\b`)
+
+// CommentMention is a user mentioned in a comment. Start and End are the character
+// offsets of the "@handle" within the comment text and are there as a rendering hint
+// so clients can link mentions without parsing the text themselves.
+type CommentMention struct {
+	User_ID int64  `json:"user_id"`
+	Handle  string `json:"handle"`
+	Start   int    `json:"start"`
+	End     int    `json:"end"`
+}
+
+// FindMentionHandles() returns the distinct handles mentioned in a comment, lower cased
+// and in the order they first appear, up to MaxCommentMentions of them.
+func FindMentionHandles(text string) []string {
+	handles := []string{}
+	seen := make(map[string]bool)
+	for _, match := range mentionRX.FindAllStringSubmatch(text, -1) {
+		handle := strings.ToLower(match[1])
+		if seen[handle] {
+			continue
+		}
+		if len(handles) == MaxCommentMentions {
+			break
+		}
+		seen[handle] = true
+		handles = append(handles, handle)
+	}
+	return handles
+}
+
+// MentionHints() locates every occurrence of the resolved mentions within the comment
+// text. Handles in the text that did not resolve to a user are left out.
+func MentionHints(text string, mentions []CommentMention) []CommentMention {
+	resolved := make(map[string]int64, len(mentions))
+	for _, mention := range mentions {
+		resolved[strings.ToLower(mention.Handle)] = mention.User_ID
+	}
+	hints := []CommentMention{}
+	for _, match := range mentionRX.FindAllStringSubmatchIndex(text, -1) {
+		// match[2]:match[3] is the handle, the @ sits right before it
+		handle := text[match[2]:match[3]]
+		userID, ok := resolved[strings.ToLower(handle)]
+		if !ok {
+			continue
+		}
+		start := utf8.RuneCountInString(text[:match[2]-1])
+		hints = append(hints, CommentMention{
+			User_ID: userID,
+			Handle:  handle,
+			Start:   start,
+			End:     start + 1 + utf8.RuneCountInString(handle),
+		})
+	}
+	return hints
+}
+
+// DefaultHandle() derives a handle from a user's name for users who don't pick one,
+// eg: "Jane Doe" becomes "janedoe_48213907". The random suffix keeps clashes unlikely,
+// even for the names that have no usable characters and all start with "user".
+func DefaultHandle(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_' {
+			b.WriteRune(r)
+		}
+		if b.Len() == 20 {
+			break
+		}
+	}
+	handle := b.String()
+	if handle == "" {
+		handle = "user"
+	}
+	return fmt.Sprintf("%s_%08d", handle, rand.IntN(100000000))
+}
+
+// SetCommentMentions() stores the users mentioned in a comment, replacing the ones that
+// are no longer mentioned after an edit. Handles that don't belong to anyone, and the
+// author's own handle, are ignored. It returns the users who were newly mentioned.
+func (m CommentsModel) SetCommentMentions(commentID uuid.UUID, authorID int64, handles []string) ([]int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := m.DB.DeleteStaleCommentMentions(ctx, database.DeleteStaleCommentMentionsParams{
+		CommentID: commentID,
+		Column2:   handles,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(handles) == 0 {
+		return []int64{}, nil
+	}
+	userIDs, err := m.DB.InsertCommentMentions(ctx, database.InsertCommentMentionsParams{
+		CommentID: commentID,
+		Column2:   handles,
+		ID:        authorID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return userIDs, nil
+}
+
+// GetCommentMentions() returns the rendering hints for the users mentioned in a comment
+func (m CommentsModel) GetCommentMentions(comment *Comment) ([]CommentMention, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := m.DB.GetCommentMentions(ctx, comment.ID)
+	if err != nil {
+		return nil, err
+	}
+	mentions := []CommentMention{}
+	for _, row := range rows {
+		mentions = append(mentions, CommentMention{User_ID: row.UserID, Handle: row.Handle})
+	}
+	return MentionHints(comment.Comment_Text, mentions), nil
+}
@@ -0,0 +1,86 @@
+package data
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/blue-davinci/aggregate/internal/validator"
+)
+
+func TestFindMentionHandles(t *testing.T) {
+	many := []string{}
+	for i := 0; i < MaxCommentMentions+2; i++ {
+		many = append(many, fmt.Sprintf("@user_%02d", i))
+	}
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{name: "No Mentions", text: "Nice read", want: []string{}},
+		{name: "Single", text: "@jane_doe agreed", want: []string{"jane_doe"}},
+		{name: "Case Insensitive Duplicates", text: "@Jane and @jane, thoughts @bob_1?", want: []string{"jane", "bob_1"}},
+		{name: "Email Is Not A Mention", text: "mail me at jane@example.com", want: []string{}},
+		{name: "Too Short", text: "hi @ab", want: []string{}},
+		{name: "Too Long", text: "@" + strings.Repeat("a", 31), want: []string{}},
+		{name: "Punctuation", text: "(@alice), @bob.", want: []string{"alice", "bob"}},
+		{name: "Capped", text: strings.Join(many, " "), want: []string{"user_00", "user_01", "user_02", "user_03", "user_04", "user_05", "user_06", "user_07", "user_08", "user_09"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FindMentionHandles(tt.text)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Got:%v But Wanted:%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMentionHints(t *testing.T) {
+	resolved := []CommentMention{{User_ID: 1, Handle: "jane"}, {User_ID: 2, Handle: "bob"}}
+	tests := []struct {
+		name string
+		text string
+		want []CommentMention
+	}{
+		{name: "Resolved Only", text: "@jane meet @ghost", want: []CommentMention{{User_ID: 1, Handle: "jane", Start: 0, End: 5}}},
+		{name: "Keeps Written Case", text: "hey @Bob", want: []CommentMention{{User_ID: 2, Handle: "Bob", Start: 4, End: 8}}},
+		{name: "Repeated", text: "@bob @bob", want: []CommentMention{{User_ID: 2, Handle: "bob", Start: 0, End: 4}, {User_ID: 2, Handle: "bob", Start: 5, End: 9}}},
+		{name: "Character Offsets", text: "héllo @jane", want: []CommentMention{{User_ID: 1, Handle: "jane", Start: 6, End: 11}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MentionHints(tt.text, resolved)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Got:%v But Wanted:%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultHandle(t *testing.T) {
+	tests := []struct {
+		name       string
+		userName   string
+		wantPrefix string
+	}{
+		{name: "Plain", userName: "Jane Doe", wantPrefix: "janedoe_"},
+		{name: "No Usable Characters", userName: "Élan ✓", wantPrefix: "lan_"},
+		{name: "Empty", userName: "", wantPrefix: "user_"},
+		{name: "Long", userName: strings.Repeat("abc", 20), wantPrefix: strings.Repeat("abc", 20)[:20] + "_"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handle := DefaultHandle(tt.userName)
+			if !strings.HasPrefix(handle, tt.wantPrefix) {
+				t.Errorf("Got:%s But Wanted prefix:%s", handle, tt.wantPrefix)
+			}
+			v := validator.New()
+			if ValidateHandle(v, handle); !v.Valid() {
+				t.Errorf("Got an invalid handle %s: %v", handle, v.Errors)
+			}
+		})
+	}
+}
@@ -27,6 +27,19 @@ type Models struct {
 	Admin         AdminModel
 	ErrorLogs     ErrorLogsDataModel
 	Announcements AnnouncementModel
+	SavedSearches SavedSearchesModel
+	OutboundFeeds OutboundFeedsModel
+	Digests       DigestModel
+	Inbox         InboxModel
+	Moderation    ModerationModel
+	Profiles      ProfilesModel
+	Shares        SharesModel
+	Entitlements  EntitlementsModel
+	Billing       BillingModel
+	Coupons       CouponsModel
+	Invoices      InvoicesModel
+	Refunds       RefundsModel
+	Organizations OrganizationsModel
 	//feed models
 }
 
@@ -46,5 +59,18 @@ func NewModels(db *database.Queries) Models {
 		Admin:         AdminModel{DB: db},
 		ErrorLogs:     ErrorLogsDataModel{DB: db},
 		Announcements: AnnouncementModel{DB: db},
+		SavedSearches: SavedSearchesModel{DB: db},
+		OutboundFeeds: OutboundFeedsModel{DB: db},
+		Digests:       DigestModel{DB: db},
+		Inbox:         InboxModel{DB: db},
+		Moderation:    ModerationModel{DB: db},
+		Profiles:      ProfilesModel{DB: db},
+		Shares:        SharesModel{DB: db},
+		Entitlements:  EntitlementsModel{DB: db},
+		Billing:       BillingModel{DB: db},
+		Coupons:       CouponsModel{DB: db},
+		Invoices:      InvoicesModel{DB: db},
+		Refunds:       RefundsModel{DB: db},
+		Organizations: OrganizationsModel{DB: db},
 	}
 }
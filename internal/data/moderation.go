@@ -0,0 +1,449 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/blue-davinci/aggregate/internal/database"
+	"github.com/blue-davinci/aggregate/internal/validator"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrDuplicateCommentReport     = errors.New("comment already reported")
+	ErrCommentRestrictionNotFound = errors.New("comment restriction not found")
+)
+
+// Comment statuses. Hidden comments can be restored by an admin while removed ones are
+// soft deleted, both keep their place in the thread and show as "[removed]". Pending
+// comments were flagged by screening and are only shown to their author until an admin
+// approves or rejects them.
+const (
+	CommentStatusVisible = "visible"
+	CommentStatusPending = "pending"
+	CommentStatusHidden  = "hidden"
+	CommentStatusRemoved = "removed"
+)
+
+// Reasons a comment can be reported for
+const (
+	ReportReasonSpam       = "spam"
+	ReportReasonHarassment = "harassment"
+	ReportReasonHate       = "hate"
+	ReportReasonOffTopic   = "off_topic"
+	ReportReasonOther      = "other"
+)
+
+// Report states. Reports are resolved when an admin hides or deletes the comment and
+// dismissed when the comment is restored or left as it is.
+const (
+	ReportStatusOpen      = "open"
+	ReportStatusResolved  = "resolved"
+	ReportStatusDismissed = "dismissed"
+)
+
+// Moderation actions an admin can take on a comment
+const (
+	ModerationActionHide    = "hide"
+	ModerationActionRestore = "restore"
+	ModerationActionDelete  = "delete"
+	ModerationActionDismiss = "dismiss"
+	ModerationActionApprove = "approve"
+	ModerationActionReject  = "reject"
+)
+
+// Comment restrictions. Mutes are temporary while bans last until they are lifted
+// unless they are given an expiry.
+const (
+	CommentRestrictionMute = "mute"
+	CommentRestrictionBan  = "ban"
+	// MaxRestrictionDays is the longest a restriction can be set for
+	MaxRestrictionDays = 3650
+)
+
+type ModerationModel struct {
+	DB *database.Queries
+}
+
+// CommentReport is a user's report of a comment
+type CommentReport struct {
+	ID                    int64     `json:"id"`
+	Comment_ID            uuid.UUID `json:"comment_id"`
+	Reported_By           int64     `json:"reported_by"`
+	Reported_By_User_Name string    `json:"reported_by_user_name,omitempty"`
+	Reason                string    `json:"reason"`
+	Details               string    `json:"details"`
+	Status                string    `json:"status"`
+	Created_At            time.Time `json:"created_at"`
+}
+
+// ModerationQueueItem is a reported comment in the admin moderation queue together with
+// a summary of its reports.
+type ModerationQueueItem struct {
+	Comment_ID       uuid.UUID `json:"comment_id"`
+	Post_ID          uuid.UUID `json:"post_id"`
+	User_ID          int64     `json:"user_id"`
+	User_Name        string    `json:"user_name"`
+	Comment_Text     string    `json:"comment_text"`
+	Status           string    `json:"status"`
+	Created_At       time.Time `json:"created_at"`
+	Report_Count     int64     `json:"report_count"`
+	Reasons          []string  `json:"reasons"`
+	Last_Reported_At time.Time `json:"last_reported_at"`
+}
+
+// ModeratedComment holds what we need to know about a comment before moderating it
+type ModeratedComment struct {
+	ID          uuid.UUID
+	User_ID     int64
+	Status      string
+	Has_Replies bool
+}
+
+// CommentModerationAction records an admin's action on a comment and who took it, the
+// same way RejectedFeed records who rejected a feed.
+type CommentModerationAction struct {
+	ID                 int64         `json:"id"`
+	Comment_ID         uuid.NullUUID `json:"comment_id"`
+	Comment_User_ID    int64         `json:"comment_user_id"`
+	Action             string        `json:"action"`
+	Reason             string        `json:"reason"`
+	Acted_By           int64         `json:"acted_by"`
+	Acted_By_User_Name string        `json:"acted_by_user_name"`
+	Created_At         time.Time     `json:"created_at"`
+}
+
+// CommentRestriction keeps a user from commenting. A nil Expires_At means the
+// restriction lasts until an admin lifts it.
+type CommentRestriction struct {
+	User_ID                 int64      `json:"user_id"`
+	User_Name               string     `json:"user_name,omitempty"`
+	Restriction             string     `json:"restriction"`
+	Reason                  string     `json:"reason"`
+	Expires_At              *time.Time `json:"expires_at"`
+	Restricted_By           int64      `json:"restricted_by"`
+	Restricted_By_User_Name string     `json:"restricted_by_user_name,omitempty"`
+	Created_At              time.Time  `json:"created_at"`
+}
+
+func ValidateCommentReport(v *validator.Validator, report *CommentReport) {
+	v.Check(report.Reason != "", "reason", "must be provided")
+	v.Check(validator.PermittedValue(report.Reason, ReportReasonSpam, ReportReasonHarassment, ReportReasonHate,
+		ReportReasonOffTopic, ReportReasonOther), "reason", "must be one of spam, harassment, hate, off_topic or other")
+	v.Check(len(report.Details) <= 500, "details", "must not be more than 500 bytes long")
+}
+
+// ValidateModerationAction() checks the action is one we know and that it makes sense for
+// the comment's current status i.e only visible comments can be hidden and only hidden
+// ones restored.
+func ValidateModerationAction(v *validator.Validator, action, reason, status string) {
+	v.Check(validator.PermittedValue(action, ModerationActionHide, ModerationActionRestore, ModerationActionDelete,
+		ModerationActionDismiss, ModerationActionApprove, ModerationActionReject), "action",
+		"must be one of hide, restore, delete, dismiss, approve or reject")
+	v.Check(len(reason) <= 500, "reason", "must not be more than 500 bytes long")
+	switch action {
+	case ModerationActionHide:
+		v.Check(status == CommentStatusVisible, "action", "only visible comments can be hidden")
+	case ModerationActionRestore:
+		v.Check(status == CommentStatusHidden, "action", "only hidden comments can be restored")
+	case ModerationActionDelete:
+		v.Check(status != CommentStatusRemoved, "action", "comment has already been deleted")
+	case ModerationActionApprove, ModerationActionReject:
+		v.Check(status == CommentStatusPending, "action", "only pending comments can be approved or rejected")
+	}
+}
+
+// ValidateCommentRestriction() validates a restriction set for durationDays days, where
+// 0 means until it is lifted. Mutes must always have a duration.
+func ValidateCommentRestriction(v *validator.Validator, restriction *CommentRestriction, durationDays int) {
+	v.Check(validator.PermittedValue(restriction.Restriction, CommentRestrictionMute, CommentRestrictionBan), "restriction", "must be either mute or ban")
+	v.Check(durationDays >= 0, "duration_days", "must not be negative")
+	v.Check(durationDays <= MaxRestrictionDays, "duration_days", "must not be more than 10 years")
+	if restriction.Restriction == CommentRestrictionMute {
+		v.Check(durationDays > 0, "duration_days", "must be provided for a mute")
+	}
+	v.Check(len(restriction.Reason) <= 500, "reason", "must not be more than 500 bytes long")
+}
+
+// ReportComment() saves a user's report of a comment. Users can only report a comment once.
+func (m ModerationModel) ReportComment(report *CommentReport) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	row, err := m.DB.InsertCommentReport(ctx, database.InsertCommentReportParams{
+		CommentID:  report.Comment_ID,
+		ReportedBy: report.Reported_By,
+		Reason:     report.Reason,
+		Details:    report.Details,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrDuplicateCommentReport
+		default:
+			return err
+		}
+	}
+	report.ID = row.ID
+	report.Status = row.Status
+	report.Created_At = row.CreatedAt
+	return nil
+}
+
+// GetModerationQueue() returns the reported comments whose reports are in the given state,
+// the most reported first.
+func (m ModerationModel) GetModerationQueue(status string, filters Filters) ([]*ModerationQueueItem, Metadata, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := m.DB.GetCommentModerationQueue(ctx, database.GetCommentModerationQueueParams{
+		Status: status,
+		Limit:  int32(filters.limit()),
+		Offset: int32(filters.offset()),
+	})
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	queue := []*ModerationQueueItem{}
+	totalRecords := 0
+	for _, row := range rows {
+		totalRecords = int(row.TotalRecords)
+		queue = append(queue, &ModerationQueueItem{
+			Comment_ID:       row.ID,
+			Post_ID:          row.PostID,
+			User_ID:          row.UserID,
+			User_Name:        row.UserName,
+			Comment_Text:     row.CommentText,
+			Status:           row.Status,
+			Created_At:       row.CreatedAt,
+			Report_Count:     row.ReportCount,
+			Reasons:          row.Reasons,
+			Last_Reported_At: row.LastReportedAt,
+		})
+	}
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return queue, metadata, nil
+}
+
+// GetCommentReports() returns every report made against a comment, newest first
+func (m ModerationModel) GetCommentReports(commentID uuid.UUID) ([]*CommentReport, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := m.DB.GetCommentReports(ctx, commentID)
+	if err != nil {
+		return nil, err
+	}
+	reports := []*CommentReport{}
+	for _, row := range rows {
+		reports = append(reports, &CommentReport{
+			ID:                    row.ID,
+			Comment_ID:            row.CommentID,
+			Reported_By:           row.ReportedBy,
+			Reported_By_User_Name: row.ReportedByUserName,
+			Reason:                row.Reason,
+			Details:               row.Details,
+			Status:                row.Status,
+			Created_At:            row.CreatedAt,
+		})
+	}
+	return reports, nil
+}
+
+// GetCommentForModeration() returns the status of a comment and whether it has replies
+func (m ModerationModel) GetCommentForModeration(commentID uuid.UUID) (*ModeratedComment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	row, err := m.DB.GetCommentForModeration(ctx, commentID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrCommentNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &ModeratedComment{
+		ID:          row.ID,
+		User_ID:     row.UserID,
+		Status:      row.Status,
+		Has_Replies: row.HasReplies,
+	}, nil
+}
+
+// ModerateComment() applies an admin's action to a comment, settles its open reports and
+// records the action against the admin. Deleted comments are always soft deleted so that
+// their replies and the audit trail stay in place.
+func (m ModerationModel) ModerateComment(comment *ModeratedComment, action, reason string, adminID int64) (*CommentModerationAction, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var err error
+	reportStatus := ReportStatusResolved
+	switch action {
+	case ModerationActionHide:
+		err = m.DB.SetCommentStatus(ctx, database.SetCommentStatusParams{ID: comment.ID, Status: CommentStatusHidden})
+		comment.Status = CommentStatusHidden
+	case ModerationActionRestore:
+		err = m.DB.SetCommentStatus(ctx, database.SetCommentStatusParams{ID: comment.ID, Status: CommentStatusVisible})
+		comment.Status = CommentStatusVisible
+		reportStatus = ReportStatusDismissed
+	case ModerationActionDelete:
+		err = m.DB.ModerationRemoveComment(ctx, comment.ID)
+		comment.Status = CommentStatusRemoved
+	case ModerationActionDismiss:
+		reportStatus = ReportStatusDismissed
+	case ModerationActionApprove:
+		err = m.DB.SetCommentStatus(ctx, database.SetCommentStatusParams{ID: comment.ID, Status: CommentStatusVisible})
+		comment.Status = CommentStatusVisible
+		reportStatus = ReportStatusDismissed
+	case ModerationActionReject:
+		err = m.DB.ModerationRemoveComment(ctx, comment.ID)
+		comment.Status = CommentStatusRemoved
+	}
+	if err != nil {
+		return nil, err
+	}
+	_, err = m.DB.ResolveCommentReports(ctx, database.ResolveCommentReportsParams{
+		CommentID:  comment.ID,
+		Status:     reportStatus,
+		ResolvedBy: sql.NullInt64{Int64: adminID, Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	row, err := m.DB.InsertCommentModerationAction(ctx, database.InsertCommentModerationActionParams{
+		CommentID:     uuid.NullUUID{UUID: comment.ID, Valid: true},
+		CommentUserID: sql.NullInt64{Int64: comment.User_ID, Valid: true},
+		Action:        action,
+		Reason:        reason,
+		ActedBy:       sql.NullInt64{Int64: adminID, Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &CommentModerationAction{
+		ID:              row.ID,
+		Comment_ID:      row.CommentID,
+		Comment_User_ID: row.CommentUserID.Int64,
+		Action:          row.Action,
+		Reason:          row.Reason,
+		Acted_By:        row.ActedBy.Int64,
+		Created_At:      row.CreatedAt,
+	}, nil
+}
+
+// GetCommentModerationActions() returns the moderation history of a comment, newest first
+func (m ModerationModel) GetCommentModerationActions(commentID uuid.UUID) ([]*CommentModerationAction, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := m.DB.GetCommentModerationActions(ctx, uuid.NullUUID{UUID: commentID, Valid: true})
+	if err != nil {
+		return nil, err
+	}
+	actions := []*CommentModerationAction{}
+	for _, row := range rows {
+		actions = append(actions, &CommentModerationAction{
+			ID:                 row.ID,
+			Comment_ID:         row.CommentID,
+			Comment_User_ID:    row.CommentUserID.Int64,
+			Action:             row.Action,
+			Reason:             row.Reason,
+			Acted_By:           row.ActedBy.Int64,
+			Acted_By_User_Name: row.ActedByUserName.String,
+			Created_At:         row.CreatedAt,
+		})
+	}
+	return actions, nil
+}
+
+// RestrictCommenter() mutes or bans a user from commenting, replacing any restriction
+// they already have.
+func (m ModerationModel) RestrictCommenter(restriction *CommentRestriction) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	row, err := m.DB.UpsertCommentRestriction(ctx, database.UpsertCommentRestrictionParams{
+		UserID:       restriction.User_ID,
+		Restriction:  restriction.Restriction,
+		Reason:       restriction.Reason,
+		ExpiresAt:    timeToNullTime(restriction.Expires_At),
+		RestrictedBy: sql.NullInt64{Int64: restriction.Restricted_By, Valid: true},
+	})
+	if err != nil {
+		switch {
+		case err.Error() == `pq: insert or update on table "comment_restrictions" violates foreign key constraint "comment_restrictions_user_id_fkey"`:
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+	restriction.Created_At = row.CreatedAt
+	return nil
+}
+
+// LiftCommentRestriction() lets a muted or banned user comment again
+func (m ModerationModel) LiftCommentRestriction(userID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := m.DB.DeleteCommentRestriction(ctx, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrCommentRestrictionNotFound
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// GetActiveCommentRestriction() returns the user's restriction if it hasn't expired
+func (m ModerationModel) GetActiveCommentRestriction(userID int64) (*CommentRestriction, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	row, err := m.DB.GetActiveCommentRestriction(ctx, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrCommentRestrictionNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &CommentRestriction{
+		User_ID:       row.UserID,
+		Restriction:   row.Restriction,
+		Reason:        row.Reason,
+		Expires_At:    nullTimeToTime(row.ExpiresAt),
+		Restricted_By: row.RestrictedBy.Int64,
+		Created_At:    row.CreatedAt,
+	}, nil
+}
+
+// GetActiveCommentRestrictions() returns every restriction that hasn't expired, newest first
+func (m ModerationModel) GetActiveCommentRestrictions(filters Filters) ([]*CommentRestriction, Metadata, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := m.DB.GetActiveCommentRestrictions(ctx, database.GetActiveCommentRestrictionsParams{
+		Limit:  int32(filters.limit()),
+		Offset: int32(filters.offset()),
+	})
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	restrictions := []*CommentRestriction{}
+	totalRecords := 0
+	for _, row := range rows {
+		totalRecords = int(row.TotalRecords)
+		restrictions = append(restrictions, &CommentRestriction{
+			User_ID:                 row.UserID,
+			User_Name:               row.UserName,
+			Restriction:             row.Restriction,
+			Reason:                  row.Reason,
+			Expires_At:              nullTimeToTime(row.ExpiresAt),
+			Restricted_By:           row.RestrictedBy.Int64,
+			Restricted_By_User_Name: row.RestrictedByUserName.String,
+			Created_At:              row.CreatedAt,
+		})
+	}
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return restrictions, metadata, nil
+}
@@ -0,0 +1,110 @@
+package data
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/blue-davinci/aggregate/internal/validator"
+	"github.com/google/uuid"
+)
+
+func TestValidateModerationAction(t *testing.T) {
+	tests := []struct {
+		name    string
+		action  string
+		reason  string
+		status  string
+		wantErr string
+	}{
+		{name: "Hide Visible", action: ModerationActionHide, status: CommentStatusVisible},
+		{name: "Hide Hidden", action: ModerationActionHide, status: CommentStatusHidden, wantErr: "action"},
+		{name: "Restore Hidden", action: ModerationActionRestore, status: CommentStatusHidden},
+		{name: "Restore Removed", action: ModerationActionRestore, status: CommentStatusRemoved, wantErr: "action"},
+		{name: "Delete Hidden", action: ModerationActionDelete, status: CommentStatusHidden},
+		{name: "Delete Removed", action: ModerationActionDelete, status: CommentStatusRemoved, wantErr: "action"},
+		{name: "Dismiss", action: ModerationActionDismiss, status: CommentStatusVisible},
+		{name: "Approve Pending", action: ModerationActionApprove, status: CommentStatusPending},
+		{name: "Reject Pending", action: ModerationActionReject, status: CommentStatusPending},
+		{name: "Approve Visible", action: ModerationActionApprove, status: CommentStatusVisible, wantErr: "action"},
+		{name: "Hide Pending", action: ModerationActionHide, status: CommentStatusPending, wantErr: "action"},
+		{name: "Unknown Action", action: "ban", status: CommentStatusVisible, wantErr: "action"},
+		{name: "Long Reason", action: ModerationActionHide, reason: strings.Repeat("a", 501), status: CommentStatusVisible, wantErr: "reason"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateModerationAction(v, tt.action, tt.reason, tt.status)
+			if tt.wantErr == "" {
+				if !v.Valid() {
+					t.Errorf("Got:%v But Wanted no errors", v.Errors)
+				}
+				return
+			}
+			if _, ok := v.Errors[tt.wantErr]; !ok {
+				t.Errorf("Got:%v But Wanted an error for:%s", v.Errors, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateCommentRestriction(t *testing.T) {
+	tests := []struct {
+		name         string
+		restriction  string
+		durationDays int
+		wantErr      string
+	}{
+		{name: "Mute For A Week", restriction: CommentRestrictionMute, durationDays: 7},
+		{name: "Mute Without Duration", restriction: CommentRestrictionMute, durationDays: 0, wantErr: "duration_days"},
+		{name: "Permanent Ban", restriction: CommentRestrictionBan, durationDays: 0},
+		{name: "Temporary Ban", restriction: CommentRestrictionBan, durationDays: 30},
+		{name: "Negative Duration", restriction: CommentRestrictionBan, durationDays: -1, wantErr: "duration_days"},
+		{name: "Too Long", restriction: CommentRestrictionBan, durationDays: MaxRestrictionDays + 1, wantErr: "duration_days"},
+		{name: "Unknown Restriction", restriction: "suspend", durationDays: 1, wantErr: "restriction"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateCommentRestriction(v, &CommentRestriction{User_ID: 1, Restriction: tt.restriction}, tt.durationDays)
+			if tt.wantErr == "" {
+				if !v.Valid() {
+					t.Errorf("Got:%v But Wanted no errors", v.Errors)
+				}
+				return
+			}
+			if _, ok := v.Errors[tt.wantErr]; !ok {
+				t.Errorf("Got:%v But Wanted an error for:%s", v.Errors, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateCommentReport(t *testing.T) {
+	tests := []struct {
+		name    string
+		reason  string
+		details string
+		wantErr string
+	}{
+		{name: "Spam", reason: ReportReasonSpam},
+		{name: "Other With Details", reason: ReportReasonOther, details: "links to a phishing site"},
+		{name: "Missing Reason", reason: "", wantErr: "reason"},
+		{name: "Unknown Reason", reason: "boring", wantErr: "reason"},
+		{name: "Long Details", reason: ReportReasonOther, details: strings.Repeat("a", 501), wantErr: "details"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateCommentReport(v, &CommentReport{Comment_ID: uuid.New(), Reported_By: 1, Reason: tt.reason, Details: tt.details})
+			if tt.wantErr == "" {
+				if !v.Valid() {
+					t.Errorf("Got:%v But Wanted no errors", v.Errors)
+				}
+				return
+			}
+			if _, ok := v.Errors[tt.wantErr]; !ok {
+				t.Errorf("Got:%v But Wanted an error for:%s", v.Errors, tt.wantErr)
+			}
+		})
+	}
+}
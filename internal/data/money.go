@@ -0,0 +1,106 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/blue-davinci/aggregate/internal/validator"
+)
+
+// DefaultCurrency is the currency of prices that don't name one.
+const DefaultCurrency = "USD"
+
+// Money is an amount in whole minor units (cents) of an ISO 4217 currency. Keeping
+// amounts as integers means what a user is shown is exactly what is charged and saved.
+type Money struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// currencyExponents lists the currencies whose minor unit isn't a hundredth
+var currencyExponents = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0,
+	"PYG": 0, "RWF": 0, "UGX": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+}
+
+// regionCurrencies maps the region of a locale to the currency it pays in
+var regionCurrencies = map[string]string{
+	"US": "USD", "GB": "GBP", "CA": "CAD", "AU": "AUD", "NZ": "NZD", "IN": "INR",
+	"JP": "JPY", "CN": "CNY", "CH": "CHF", "SE": "SEK", "NO": "NOK", "DK": "DKK",
+	"KE": "KES", "NG": "NGN", "GH": "GHS", "ZA": "ZAR", "UG": "UGX", "TZ": "TZS",
+	"RW": "RWF", "EG": "EGP", "CI": "XOF", "BR": "BRL", "MX": "MXN",
+	"AT": "EUR", "BE": "EUR", "CY": "EUR", "DE": "EUR", "EE": "EUR", "ES": "EUR",
+	"FI": "EUR", "FR": "EUR", "GR": "EUR", "HR": "EUR", "IE": "EUR", "IT": "EUR",
+	"LT": "EUR", "LU": "EUR", "LV": "EUR", "MT": "EUR", "NL": "EUR", "PT": "EUR",
+	"SI": "EUR", "SK": "EUR",
+}
+
+// NewMoney() returns an amount of minor units in the currency
+func NewMoney(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: strings.ToUpper(currency)}
+}
+
+// Exponent() is the number of decimal places of the currency's major unit
+func (m Money) Exponent() int {
+	if exponent, ok := currencyExponents[strings.ToUpper(m.Currency)]; ok {
+		return exponent
+	}
+	return 2
+}
+
+// Major() formats the amount in the currency's major unit, so 1050 USD is "10.50".
+func (m Money) Major() string {
+	exponent := m.Exponent()
+	sign, amount := "", m.Amount
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+	digits := strconv.FormatInt(amount, 10)
+	if exponent == 0 {
+		return sign + digits
+	}
+	if len(digits) <= exponent {
+		digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-exponent] + "." + digits[len(digits)-exponent:]
+}
+
+// String() formats the money for people, such as "USD 10.50"
+func (m Money) String() string {
+	return fmt.Sprintf("%s %s", m.Currency, m.Major())
+}
+
+// MarshalJSON() adds the formatted amount to what clients get so they don't have to
+// know each currency's minor unit.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Amount    int64  `json:"amount"`
+		Currency  string `json:"currency"`
+		Formatted string `json:"formatted"`
+	}{m.Amount, m.Currency, m.String()})
+}
+
+// ValidateMoney() checks that a price is a positive amount in a 3 letter currency
+func ValidateMoney(v *validator.Validator, key string, m Money) {
+	v.Check(m.Amount > 0, key, "must be a positive amount in minor units")
+	v.Check(len(m.Currency) == 3, key, "must have a 3 letter ISO currency code")
+}
+
+// CurrencyForLocale() returns the currency of a locale such as "en-KE" or an
+// Accept-Language header. The first language with a region we know is used and an
+// empty string is returned when none is found.
+func CurrencyForLocale(locale string) string {
+	for _, tag := range strings.Split(locale, ",") {
+		tag, _, _ = strings.Cut(strings.TrimSpace(tag), ";")
+		parts := strings.FieldsFunc(tag, func(r rune) bool { return r == '-' || r == '_' })
+		for _, part := range parts[min(1, len(parts)):] {
+			if currency, ok := regionCurrencies[strings.ToUpper(part)]; ok && len(part) == 2 {
+				return currency
+			}
+		}
+	}
+	return ""
+}
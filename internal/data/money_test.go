@@ -0,0 +1,85 @@
+package data
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMoneyMajor(t *testing.T) {
+	tests := []struct {
+		name  string
+		money Money
+		want  string
+	}{
+		{name: "Cents", money: NewMoney(1050, "usd"), want: "10.50"},
+		{name: "Less Than One", money: NewMoney(5, "USD"), want: "0.05"},
+		{name: "Zero", money: NewMoney(0, "KES"), want: "0.00"},
+		{name: "Negative", money: NewMoney(-250, "EUR"), want: "-2.50"},
+		{name: "No Minor Unit", money: NewMoney(1500, "JPY"), want: "1500"},
+		{name: "Three Decimals", money: NewMoney(1500, "KWD"), want: "1.500"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.money.Major(); got != tt.want {
+				t.Errorf("Got:%s But Wanted:%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoneyMarshalJSON(t *testing.T) {
+	got, err := json.Marshal(NewMoney(99900, "kes"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"amount":99900,"currency":"KES","formatted":"KES 999.00"}`
+	if string(got) != want {
+		t.Errorf("Got:%s But Wanted:%s", got, want)
+	}
+}
+
+func TestCurrencyForLocale(t *testing.T) {
+	tests := []struct {
+		name   string
+		locale string
+		want   string
+	}{
+		{name: "Tag", locale: "en-KE", want: "KES"},
+		{name: "Underscore", locale: "en_GB", want: "GBP"},
+		{name: "Eurozone", locale: "de-DE", want: "EUR"},
+		{name: "Accept Language", locale: "fr;q=0.9, en-NG;q=0.8", want: "NGN"},
+		{name: "Script Subtag", locale: "zh-Hans-CN", want: "CNY"},
+		{name: "Language Only", locale: "en", want: ""},
+		{name: "Unknown Region", locale: "en-AQ", want: ""},
+		{name: "Empty", locale: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CurrencyForLocale(tt.locale); got != tt.want {
+				t.Errorf("Got:%q But Wanted:%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPaymentPlanPriceForLocale(t *testing.T) {
+	plan := &Payment_Plan{Price: NewMoney(1000, "USD"), Prices: []Money{NewMoney(130000, "KES"), NewMoney(900, "EUR")}}
+	tests := []struct {
+		name   string
+		locale string
+		want   Money
+	}{
+		{name: "Sold In Currency", locale: "sw-KE", want: NewMoney(130000, "KES")},
+		{name: "Eurozone", locale: "fr-FR", want: NewMoney(900, "EUR")},
+		{name: "Plan Currency", locale: "en-US", want: NewMoney(1000, "USD")},
+		{name: "Not Sold In Currency", locale: "en-NG", want: NewMoney(1000, "USD")},
+		{name: "No Locale", locale: "", want: NewMoney(1000, "USD")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := plan.PriceForLocale(tt.locale); got != tt.want {
+				t.Errorf("Got:%v But Wanted:%v", got, tt.want)
+			}
+		})
+	}
+}
@@ -14,11 +14,12 @@ type NotificationsModel struct {
 }
 
 // Thi struct represents the entirety of what our notifications look like
-// we will return a notification group currently made up of a Post Notification
-// and a Comment Notification
+// we will return a notification group currently made up of a Post Notification,
+// a Comment Notification and a Saved Search Notification
 type NotificationsGroup struct {
-	Notification        []*Notification
-	CommentNotification []*CommentNotification
+	Notification            []*Notification
+	CommentNotification     []*CommentNotification
+	SavedSearchNotification []*SavedSearchNotification
 }
 
 type Notification struct {
@@ -73,7 +74,8 @@ func (m *NotificationsModel) FetchAndStoreNotifications(interval int64) ([]*Noti
 
 // GetUserNotifications() is an endpoint function that retrieves all notifications
 // for a specific user within a specified interval. This function currently works
-// on an on-demand basis/poll basis. It is not a real-time notification system.
+// on an on-demand basis/poll basis. Real-time delivery is handled by the notification
+// stream which pushes the same notifications as they are created.
 func (m *NotificationsModel) GetUserNotifications(userID int64, interval int64) (*NotificationsGroup, error) {
 	// Create a new context with a 5 second timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -93,6 +95,11 @@ func (m *NotificationsModel) GetUserNotifications(userID int64, interval int64)
 	if err != nil {
 		return nil, err
 	}
+	// Get the user's saved search notifications within the same interval
+	savedSearchNotifications, err := m.GetUserSavedSearchNotifications(userID, interval)
+	if err != nil {
+		return nil, err
+	}
 	// Make a slice of notifications
 	notifications := []*Notification{}
 	// Loop through the notification group and append to the notifications slice
@@ -108,6 +115,7 @@ func (m *NotificationsModel) GetUserNotifications(userID int64, interval int64)
 	var notificationsGroup NotificationsGroup
 	notificationsGroup.Notification = notifications
 	notificationsGroup.CommentNotification = commentNotifications
+	notificationsGroup.SavedSearchNotification = savedSearchNotifications
 	// Return the notifications
 	return &notificationsGroup, nil
 }
@@ -144,6 +152,34 @@ func (m *NotificationsModel) GetUserCommentNotifications(userID int64) ([]*Comme
 	return commentnotifications, nil
 }
 
+// GetUserSavedSearchNotifications() retrieves the saved search notifications for a user
+// within the specified interval. Each notification holds the number of new posts that
+// matched one of the user's saved searches.
+func (m *NotificationsModel) GetUserSavedSearchNotifications(userID int64, interval int64) ([]*SavedSearchNotification, error) {
+	// Create a new context with a 5 second timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := m.DB.GetUserSavedSearchNotifications(ctx, database.GetUserSavedSearchNotificationsParams{
+		UserID:  userID,
+		Column2: interval,
+	})
+	if err != nil {
+		return nil, err
+	}
+	savedSearchNotifications := []*SavedSearchNotification{}
+	for _, row := range rows {
+		savedSearchNotifications = append(savedSearchNotifications, &SavedSearchNotification{
+			ID:                int64(row.NotificationID),
+			Saved_Search_ID:   row.SavedSearchID,
+			Saved_Search_Name: row.SavedSearchName,
+			User_ID:           userID,
+			Post_Count:        int(row.PostCount),
+			Created_At:        row.CreatedAt,
+		})
+	}
+	return savedSearchNotifications, nil
+}
+
 // InsertNotifications() inserts a new notification into our notifications table.
 // Uses the passed in notification struct and returns an id of the inserted notification.
 func (m *NotificationsModel) InsertNotifications(notification *Notification) (int32, error) {
@@ -172,6 +208,11 @@ func (m *NotificationsModel) ClearOldNotifications(interval int64) error {
 	if err != nil {
 		return err
 	}
+	// saved search notifications follow the same retention
+	err = m.DB.ClearSavedSearchNotifications(ctx, interval)
+	if err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -188,3 +229,32 @@ func (m *NotificationsModel) DeleteReadCommentNotification(postID uuid.UUID) err
 	}
 	return nil
 }
+
+// GetFeedFollowerIDs() returns the IDs of the users following a feed. It is used to
+// fan out a feed's notification to everyone who follows it.
+func (m *NotificationsModel) GetFeedFollowerIDs(feedID uuid.UUID) ([]int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	userIDs, err := m.DB.GetFeedFollowerIDs(ctx, feedID)
+	if err != nil {
+		return nil, err
+	}
+	return userIDs, nil
+}
+
+// GetCommentNotificationRecipients() returns the users a new comment should be pushed to,
+// that is everyone who favorited the post plus the author of the comment being replied to.
+// The commenter is never notified about their own comment.
+func (m *NotificationsModel) GetCommentNotificationRecipients(comment *Comment) ([]int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	userIDs, err := m.DB.GetCommentNotificationRecipients(ctx, database.GetCommentNotificationRecipientsParams{
+		PostID: comment.Post_ID,
+		ID:     comment.Parent_Comment_ID.UUID,
+		UserID: comment.User_ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return userIDs, nil
+}
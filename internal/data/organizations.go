@@ -0,0 +1,650 @@
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/blue-davinci/aggregate/internal/database"
+	"github.com/blue-davinci/aggregate/internal/validator"
+	"github.com/google/uuid"
+)
+
+// An organization's owner pays for it through their own subscription, admins help
+// manage its members, invitations and folders and members just share in it.
+const (
+	OrganizationRoleOwner  = "owner"
+	OrganizationRoleAdmin  = "admin"
+	OrganizationRoleMember = "member"
+)
+
+const (
+	// OrganizationInvitationTTL is how long an invitation can be accepted for
+	OrganizationInvitationTTL = 7 * 24 * time.Hour
+	ScopeOrganizationInvite   = "organization-invite"
+	MaxOrganizationNameLength = 100
+)
+
+var (
+	ErrOrganizationNotFound           = errors.New("organization not found")
+	ErrAlreadyInOrganization          = errors.New("user already belongs to an organization")
+	ErrOrganizationMemberNotFound     = errors.New("organization member not found")
+	ErrOrganizationInvitationNotFound = errors.New("organization invitation not found")
+	ErrOrganizationFolderNotFound     = errors.New("organization folder not found")
+	ErrDuplicateOrganizationFolder    = errors.New("organization folder already exists")
+	ErrOrganizationFeedNotFound       = errors.New("organization feed or folder not found")
+	ErrFeedAlreadyShared              = errors.New("feed already shared with the organization")
+)
+
+type OrganizationsModel struct {
+	DB *database.Queries
+}
+
+// Organization is the organization a user belongs to as they see it. Role is the
+// user's own role in it. The plan is the one the owner is subscribed to and Seats how
+// many members it covers, 1 when the owner has no subscription. Pending invitations
+// hold a seat until they are accepted, revoked or expire.
+type Organization struct {
+	ID                  int64     `json:"id"`
+	Name                string    `json:"name"`
+	Owner_ID            int64     `json:"owner_id"`
+	Role                string    `json:"role"`
+	Plan_ID             int32     `json:"plan_id,omitempty"`
+	Plan_Name           string    `json:"plan_name,omitempty"`
+	Seats               int32     `json:"seats"`
+	Members             int64     `json:"members"`
+	Pending_Invitations int64     `json:"pending_invitations"`
+	Seats_Available     int64     `json:"seats_available"`
+	Joined_At           time.Time `json:"joined_at"`
+	Created_At          time.Time `json:"created_at"`
+	Updated_At          time.Time `json:"updated_at"`
+	Version             int32     `json:"version"`
+}
+
+// OrganizationMember is a member of an organization. Seats are taken by the owner
+// first and then by members in the order they joined, members past the plan's seats
+// stay in the organization but fall back to their own plan until a seat frees up.
+type OrganizationMember struct {
+	User_ID   int64     `json:"user_id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	Handle    string    `json:"handle"`
+	Role      string    `json:"role"`
+	Seat      int64     `json:"seat"`
+	Seated    bool      `json:"seated"`
+	Joined_At time.Time `json:"joined_at"`
+}
+
+// OrganizationInvitation is an invitation to join an organization sent by email. We
+// only hold the hash of its token, so Token is only set when the invitation is created
+// and it is never sent back in a response, only in the email.
+type OrganizationInvitation struct {
+	ID                int64     `json:"id"`
+	Organization_ID   int64     `json:"organization_id"`
+	Organization_Name string    `json:"organization_name,omitempty"`
+	Email             string    `json:"email"`
+	Role              string    `json:"role"`
+	Invited_By        int64     `json:"-"`
+	Invited_By_Name   string    `json:"invited_by,omitempty"`
+	Token             string    `json:"-"`
+	Expires_At        time.Time `json:"expires_at"`
+	Created_At        time.Time `json:"created_at"`
+}
+
+// OrganizationFolder groups an organization's shared feeds. Feeds is how many of
+// them are in it.
+type OrganizationFolder struct {
+	ID              int64     `json:"id"`
+	Organization_ID int64     `json:"-"`
+	Name            string    `json:"name"`
+	Created_By      int64     `json:"created_by"`
+	Feeds           int64     `json:"feeds"`
+	Created_At      time.Time `json:"created_at"`
+	Updated_At      time.Time `json:"updated_at"`
+	Version         int32     `json:"version"`
+}
+
+// OrganizationFeed is a feed shared with every member of an organization. Folder_ID
+// is 0 for feeds that are not in a folder.
+type OrganizationFeed struct {
+	Feed_ID          uuid.UUID `json:"feed_id"`
+	Name             string    `json:"name"`
+	URL              string    `json:"url"`
+	Image_URL        string    `json:"img_url"`
+	Feed_Type        string    `json:"feed_type"`
+	Feed_Description string    `json:"feed_description"`
+	Folder_ID        int64     `json:"folder_id"`
+	Folder_Name      string    `json:"folder_name"`
+	Shared_By        int64     `json:"shared_by"`
+	Shared_By_Name   string    `json:"shared_by_name"`
+	Shared_At        time.Time `json:"shared_at"`
+}
+
+// CanManage() reports whether the user can manage the organization's members,
+// invitations, folders and feeds.
+func (o *Organization) CanManage() bool {
+	return o.Role == OrganizationRoleOwner || o.Role == OrganizationRoleAdmin
+}
+
+// SeatsAvailable() is how many more members can be invited. It is never negative,
+// even after the owner moves to a plan with fewer seats than there are members.
+func (o *Organization) SeatsAvailable() int64 {
+	return max(int64(o.Seats)-o.Members-o.Pending_Invitations, 0)
+}
+
+func ValidateOrganization(v *validator.Validator, organization *Organization) {
+	v.Check(organization.Name != "", "name", "must be provided")
+	v.Check(len(organization.Name) <= MaxOrganizationNameLength, "name", "must not be more than 100 bytes long")
+}
+
+// ValidateOrganizationRole() checks a role that can be given to a member, there is
+// only ever the one owner.
+func ValidateOrganizationRole(v *validator.Validator, role string) {
+	v.Check(validator.PermittedValue(role, OrganizationRoleAdmin, OrganizationRoleMember), "role", "must be one of admin or member")
+}
+
+func ValidateOrganizationInvitation(v *validator.Validator, invitation *OrganizationInvitation) {
+	ValidateEmail(v, invitation.Email)
+	ValidateOrganizationRole(v, invitation.Role)
+}
+
+func ValidateOrganizationFolder(v *validator.Validator, folder *OrganizationFolder) {
+	v.Check(folder.Name != "", "name", "must be provided")
+	v.Check(len(folder.Name) <= MaxOrganizationNameLength, "name", "must not be more than 100 bytes long")
+}
+
+// CreateOrganization() creates an organization with its owner as its first member.
+// A user can only belong to one organization, so owners of or members in another one
+// get an ErrAlreadyInOrganization.
+func (m OrganizationsModel) CreateOrganization(organization *Organization) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	row, err := m.DB.CreateOrganization(ctx, database.CreateOrganizationParams{
+		Name:    organization.Name,
+		OwnerID: organization.Owner_ID,
+	})
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "organization_members_user_id_key"`:
+			return ErrAlreadyInOrganization
+		default:
+			return err
+		}
+	}
+	organization.ID = row.ID
+	organization.Role = OrganizationRoleOwner
+	organization.Created_At = row.CreatedAt
+	organization.Updated_At = row.UpdatedAt
+	organization.Version = row.Version
+	return nil
+}
+
+// GetOrganizationForUser() returns the organization a user belongs to along with the
+// seats its owner's plan gives it.
+func (m OrganizationsModel) GetOrganizationForUser(userID int64) (*Organization, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	row, err := m.DB.GetOrganizationForUser(ctx, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrOrganizationNotFound
+		default:
+			return nil, err
+		}
+	}
+	organization := &Organization{
+		ID:                  row.ID,
+		Name:                row.Name,
+		Owner_ID:            row.OwnerID,
+		Role:                row.Role,
+		Plan_ID:             row.PlanID.Int32,
+		Plan_Name:           row.PlanName.String,
+		Seats:               row.Seats,
+		Members:             row.Members,
+		Pending_Invitations: row.PendingInvitations,
+		Joined_At:           row.JoinedAt,
+		Created_At:          row.CreatedAt,
+		Updated_At:          row.UpdatedAt,
+		Version:             row.Version,
+	}
+	organization.Seats_Available = organization.SeatsAvailable()
+	return organization, nil
+}
+
+func (m OrganizationsModel) UpdateOrganization(organization *Organization) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	row, err := m.DB.UpdateOrganization(ctx, database.UpdateOrganizationParams{
+		Name:    organization.Name,
+		ID:      organization.ID,
+		Version: organization.Version,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+	organization.Updated_At = row.UpdatedAt
+	organization.Version = row.Version
+	return nil
+}
+
+// DeleteOrganization() removes an organization along with its members, invitations,
+// folders and shared feeds. The members go back to their own plans.
+func (m OrganizationsModel) DeleteOrganization(organizationID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := m.DB.DeleteOrganization(ctx, organizationID)
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrOrganizationNotFound
+	}
+	return nil
+}
+
+// GetOrganizationMembers() lists an organization's members in the order they take
+// its seats, marking the ones the plan covers.
+func (m OrganizationsModel) GetOrganizationMembers(organization *Organization) ([]*OrganizationMember, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := m.DB.GetOrganizationMembers(ctx, organization.ID)
+	if err != nil {
+		return nil, err
+	}
+	members := []*OrganizationMember{}
+	for _, row := range rows {
+		members = append(members, &OrganizationMember{
+			User_ID:   row.UserID,
+			Name:      row.Name,
+			Email:     row.Email,
+			Handle:    row.Handle,
+			Role:      row.Role,
+			Seat:      row.Seat,
+			Seated:    row.Seat <= int64(organization.Seats),
+			Joined_At: row.JoinedAt,
+		})
+	}
+	return members, nil
+}
+
+// UpdateOrganizationMemberRole() changes a member's role, the owner's role can't be
+// changed so they get an ErrOrganizationMemberNotFound like users outside the organization.
+func (m OrganizationsModel) UpdateOrganizationMemberRole(organizationID, userID int64, role string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := m.DB.UpdateOrganizationMemberRole(ctx, database.UpdateOrganizationMemberRoleParams{
+		OrganizationID: organizationID,
+		UserID:         userID,
+		Role:           role,
+	})
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrOrganizationMemberNotFound
+	}
+	return nil
+}
+
+// DeleteOrganizationMember() removes a member from an organization. The owner can't be
+// removed, they delete the organization instead.
+func (m OrganizationsModel) DeleteOrganizationMember(organizationID, userID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := m.DB.DeleteOrganizationMember(ctx, database.DeleteOrganizationMemberParams{
+		OrganizationID: organizationID,
+		UserID:         userID,
+	})
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrOrganizationMemberNotFound
+	}
+	return nil
+}
+
+// CreateOrganizationInvitation() saves an invitation with a new token, set on the passed
+// invitation, that expires after OrganizationInvitationTTL. Inviting an email that
+// already has an invitation replaces it, so the earlier token stops working.
+func (m OrganizationsModel) CreateOrganizationInvitation(invitation *OrganizationInvitation) error {
+	token, err := generateAPI(invitation.Invited_By, OrganizationInvitationTTL, ScopeOrganizationInvite, APIKeyLength)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	row, err := m.DB.CreateOrganizationInvitation(ctx, database.CreateOrganizationInvitationParams{
+		OrganizationID: invitation.Organization_ID,
+		Email:          invitation.Email,
+		Role:           invitation.Role,
+		TokenHash:      token.Hash,
+		InvitedBy:      sql.NullInt64{Int64: invitation.Invited_By, Valid: invitation.Invited_By != 0},
+		ExpiresAt:      token.Expiry,
+	})
+	if err != nil {
+		return err
+	}
+	invitation.ID = row.ID
+	invitation.Token = token.Plaintext
+	invitation.Expires_At = token.Expiry
+	invitation.Created_At = row.CreatedAt
+	return nil
+}
+
+// GetOrganizationInvitations() lists the invitations of an organization that can still
+// be accepted, newest first.
+func (m OrganizationsModel) GetOrganizationInvitations(organizationID int64) ([]*OrganizationInvitation, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := m.DB.GetOrganizationInvitations(ctx, organizationID)
+	if err != nil {
+		return nil, err
+	}
+	invitations := []*OrganizationInvitation{}
+	for _, row := range rows {
+		invitations = append(invitations, &OrganizationInvitation{
+			ID:              row.ID,
+			Organization_ID: organizationID,
+			Email:           row.Email,
+			Role:            row.Role,
+			Invited_By:      row.InvitedBy.Int64,
+			Invited_By_Name: row.InvitedByName,
+			Expires_At:      row.ExpiresAt,
+			Created_At:      row.CreatedAt,
+		})
+	}
+	return invitations, nil
+}
+
+func (m OrganizationsModel) DeleteOrganizationInvitation(organizationID, invitationID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := m.DB.DeleteOrganizationInvitation(ctx, database.DeleteOrganizationInvitationParams{
+		ID:             invitationID,
+		OrganizationID: organizationID,
+	})
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrOrganizationInvitationNotFound
+	}
+	return nil
+}
+
+// GetOrganizationInvitationByToken() looks up an invitation using the plaintext token
+// from the email. Expired, revoked or unknown tokens return an
+// ErrOrganizationInvitationNotFound.
+func (m OrganizationsModel) GetOrganizationInvitationByToken(token string) (*OrganizationInvitation, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tokenHash := sha256.Sum256([]byte(token))
+	row, err := m.DB.GetOrganizationInvitationByToken(ctx, tokenHash[:])
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrOrganizationInvitationNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &OrganizationInvitation{
+		ID:                row.ID,
+		Organization_ID:   row.OrganizationID,
+		Organization_Name: row.OrganizationName,
+		Email:             row.Email,
+		Role:              row.Role,
+		Expires_At:        row.ExpiresAt,
+	}, nil
+}
+
+// AcceptOrganizationInvitation() turns an invitation into a membership, the seat the
+// invitation held goes to the new member.
+func (m OrganizationsModel) AcceptOrganizationInvitation(invitationID, userID int64) (*OrganizationMember, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	row, err := m.DB.AcceptOrganizationInvitation(ctx, database.AcceptOrganizationInvitationParams{
+		ID:      invitationID,
+		Column2: userID,
+	})
+	if err != nil {
+		switch {
+		// the invitation was accepted or revoked in the meantime
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrOrganizationInvitationNotFound
+		case err.Error() == `pq: duplicate key value violates unique constraint "organization_members_user_id_key"`:
+			return nil, ErrAlreadyInOrganization
+		default:
+			return nil, err
+		}
+	}
+	return &OrganizationMember{
+		User_ID:   userID,
+		Role:      row.Role,
+		Joined_At: row.JoinedAt,
+	}, nil
+}
+
+func (m OrganizationsModel) CreateOrganizationFolder(folder *OrganizationFolder) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	row, err := m.DB.CreateOrganizationFolder(ctx, database.CreateOrganizationFolderParams{
+		OrganizationID: folder.Organization_ID,
+		Name:           folder.Name,
+		CreatedBy:      sql.NullInt64{Int64: folder.Created_By, Valid: folder.Created_By != 0},
+	})
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "organization_folders_organization_id_name_key"`:
+			return ErrDuplicateOrganizationFolder
+		default:
+			return err
+		}
+	}
+	folder.ID = row.ID
+	folder.Created_At = row.CreatedAt
+	folder.Updated_At = row.UpdatedAt
+	folder.Version = row.Version
+	return nil
+}
+
+func (m OrganizationsModel) GetOrganizationFolders(organizationID int64) ([]*OrganizationFolder, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := m.DB.GetOrganizationFolders(ctx, organizationID)
+	if err != nil {
+		return nil, err
+	}
+	folders := []*OrganizationFolder{}
+	for _, row := range rows {
+		folders = append(folders, &OrganizationFolder{
+			ID:              row.ID,
+			Organization_ID: organizationID,
+			Name:            row.Name,
+			Created_By:      row.CreatedBy.Int64,
+			Feeds:           row.Feeds,
+			Created_At:      row.CreatedAt,
+			Updated_At:      row.UpdatedAt,
+			Version:         row.Version,
+		})
+	}
+	return folders, nil
+}
+
+func (m OrganizationsModel) GetOrganizationFolderByID(organizationID, folderID int64) (*OrganizationFolder, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	row, err := m.DB.GetOrganizationFolderByID(ctx, database.GetOrganizationFolderByIDParams{
+		ID:             folderID,
+		OrganizationID: organizationID,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrOrganizationFolderNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &OrganizationFolder{
+		ID:              row.ID,
+		Organization_ID: row.OrganizationID,
+		Name:            row.Name,
+		Created_By:      row.CreatedBy.Int64,
+		Created_At:      row.CreatedAt,
+		Updated_At:      row.UpdatedAt,
+		Version:         row.Version,
+	}, nil
+}
+
+func (m OrganizationsModel) UpdateOrganizationFolder(folder *OrganizationFolder) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	row, err := m.DB.UpdateOrganizationFolder(ctx, database.UpdateOrganizationFolderParams{
+		Name:           folder.Name,
+		ID:             folder.ID,
+		OrganizationID: folder.Organization_ID,
+		Version:        folder.Version,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		case err.Error() == `pq: duplicate key value violates unique constraint "organization_folders_organization_id_name_key"`:
+			return ErrDuplicateOrganizationFolder
+		default:
+			return err
+		}
+	}
+	folder.Updated_At = row.UpdatedAt
+	folder.Version = row.Version
+	return nil
+}
+
+// DeleteOrganizationFolder() removes a folder, the feeds in it stay shared outside of
+// any folder.
+func (m OrganizationsModel) DeleteOrganizationFolder(organizationID, folderID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := m.DB.DeleteOrganizationFolder(ctx, database.DeleteOrganizationFolderParams{
+		ID:             folderID,
+		OrganizationID: organizationID,
+	})
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrOrganizationFolderNotFound
+	}
+	return nil
+}
+
+// ShareOrganizationFeed() shares a feed with every member of an organization, in the
+// passed feed's folder unless its Folder_ID is 0. Only approved feeds that aren't hidden
+// can be shared, other feeds and folders of other organizations return an
+// ErrOrganizationFeedNotFound.
+func (m OrganizationsModel) ShareOrganizationFeed(organizationID int64, feed *OrganizationFeed) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	sharedAt, err := m.DB.ShareOrganizationFeed(ctx, database.ShareOrganizationFeedParams{
+		Column1: organizationID,
+		ID:      feed.Feed_ID,
+		Column3: feed.Folder_ID,
+		Column4: feed.Shared_By,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrOrganizationFeedNotFound
+		case err.Error() == `pq: duplicate key value violates unique constraint "organization_feeds_pkey"`:
+			return ErrFeedAlreadyShared
+		default:
+			return err
+		}
+	}
+	feed.Shared_At = sharedAt
+	return nil
+}
+
+// GetOrganizationFeeds() lists the feeds shared with an organization, the ones in the
+// given folder when folderID isn't 0.
+func (m OrganizationsModel) GetOrganizationFeeds(organizationID, folderID int64, filters Filters) ([]*OrganizationFeed, Metadata, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := m.DB.GetOrganizationFeeds(ctx, database.GetOrganizationFeedsParams{
+		OrganizationID: organizationID,
+		Column2:        folderID,
+		Limit:          int32(filters.limit()),
+		Offset:         int32(filters.offset()),
+	})
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	totalRecords := 0
+	feeds := []*OrganizationFeed{}
+	for _, row := range rows {
+		totalRecords = int(row.TotalRecords)
+		feeds = append(feeds, &OrganizationFeed{
+			Feed_ID:          row.ID,
+			Name:             row.Name,
+			URL:              row.Url,
+			Image_URL:        row.ImgUrl,
+			Feed_Type:        row.FeedType,
+			Feed_Description: row.FeedDescription,
+			Folder_ID:        row.FolderID.Int64,
+			Folder_Name:      row.FolderName,
+			Shared_By:        row.SharedBy.Int64,
+			Shared_By_Name:   row.SharedByName,
+			Shared_At:        row.SharedAt,
+		})
+	}
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return feeds, metadata, nil
+}
+
+// MoveOrganizationFeed() moves a shared feed into another folder of the organization,
+// or out of its folder when folderID is 0.
+func (m OrganizationsModel) MoveOrganizationFeed(organizationID int64, feedID uuid.UUID, folderID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := m.DB.MoveOrganizationFeed(ctx, database.MoveOrganizationFeedParams{
+		OrganizationID: organizationID,
+		FeedID:         feedID,
+		Column3:        folderID,
+	})
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrOrganizationFeedNotFound
+	}
+	return nil
+}
+
+// UnshareOrganizationFeed() stops sharing a feed with an organization. A userID other
+// than 0 only unshares the feed if that user shared it.
+func (m OrganizationsModel) UnshareOrganizationFeed(organizationID int64, feedID uuid.UUID, userID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := m.DB.UnshareOrganizationFeed(ctx, database.UnshareOrganizationFeedParams{
+		OrganizationID: organizationID,
+		FeedID:         feedID,
+		Column3:        userID,
+	})
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrOrganizationFeedNotFound
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/blue-davinci/aggregate/internal/validator"
+)
+
+func TestOrganizationSeatsAvailable(t *testing.T) {
+	tests := []struct {
+		name         string
+		organization Organization
+		want         int64
+	}{
+		{name: "No Plan", organization: Organization{Seats: 1, Members: 1}, want: 0},
+		{name: "Team Plan", organization: Organization{Seats: 5, Members: 2}, want: 3},
+		{name: "Pending Invitations", organization: Organization{Seats: 5, Members: 2, Pending_Invitations: 2}, want: 1},
+		{name: "Full", organization: Organization{Seats: 5, Members: 3, Pending_Invitations: 2}, want: 0},
+		{name: "Downgraded", organization: Organization{Seats: 1, Members: 4, Pending_Invitations: 1}, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.organization.SeatsAvailable(); got != tt.want {
+				t.Errorf("Got:%d But Wanted:%d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrganizationCanManage(t *testing.T) {
+	tests := []struct {
+		name string
+		role string
+		want bool
+	}{
+		{name: "Owner", role: OrganizationRoleOwner, want: true},
+		{name: "Admin", role: OrganizationRoleAdmin, want: true},
+		{name: "Member", role: OrganizationRoleMember, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			organization := Organization{Role: tt.role}
+			if got := organization.CanManage(); got != tt.want {
+				t.Errorf("Got:%t But Wanted:%t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateOrganizationInvitation(t *testing.T) {
+	tests := []struct {
+		name       string
+		invitation OrganizationInvitation
+		wantKey    string
+	}{
+		{name: "Member", invitation: OrganizationInvitation{Email: "jane@example.com", Role: OrganizationRoleMember}},
+		{name: "Admin", invitation: OrganizationInvitation{Email: "jane@example.com", Role: OrganizationRoleAdmin}},
+		{name: "Owner", invitation: OrganizationInvitation{Email: "jane@example.com", Role: OrganizationRoleOwner}, wantKey: "role"},
+		{name: "No Role", invitation: OrganizationInvitation{Email: "jane@example.com"}, wantKey: "role"},
+		{name: "Invalid Email", invitation: OrganizationInvitation{Email: "jane", Role: OrganizationRoleMember}, wantKey: "email"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateOrganizationInvitation(v, &tt.invitation)
+			if tt.wantKey == "" && !v.Valid() {
+				t.Errorf("Got errors:%v But Wanted none", v.Errors)
+			}
+			if _, ok := v.Errors[tt.wantKey]; tt.wantKey != "" && !ok {
+				t.Errorf("Got errors:%v But Wanted one for %s", v.Errors, tt.wantKey)
+			}
+		})
+	}
+}
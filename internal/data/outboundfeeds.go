@@ -0,0 +1,454 @@
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"time"
+
+	"github.com/blue-davinci/aggregate/internal/database"
+	"github.com/blue-davinci/aggregate/internal/validator"
+	"github.com/google/uuid"
+)
+
+// The sources an outbound feed can be built from and the formats we can render it in.
+// There are no folders yet, so a single followed feed is the narrowest source.
+const (
+	OutboundSourceTimeline    = "timeline"
+	OutboundSourceFeed        = "feed"
+	OutboundSourceSavedSearch = "saved_search"
+	OutboundSourceFavorites   = "favorites"
+
+	OutboundFormatRSS  = "rss"
+	OutboundFormatAtom = "atom"
+	OutboundFormatJSON = "json"
+)
+
+var (
+	ErrOutboundFeedNotFound = errors.New("outbound feed not found")
+)
+
+type OutboundFeedsModel struct {
+	DB *database.Queries
+}
+
+// OutboundFeed represents a secret-token URL that renders one of a user's post
+// listings as a feed. The plaintext token is only ever returned on creation.
+type OutboundFeed struct {
+	ID               uuid.UUID         `json:"id"`
+	User_ID          int64             `json:"-"`
+	Source           string            `json:"source"`
+	Feed_ID          uuid.NullUUID     `json:"feed_id"`
+	Saved_Search_ID  uuid.NullUUID     `json:"saved_search_id"`
+	Token            string            `json:"token,omitempty"`
+	URLs             map[string]string `json:"urls,omitempty"`
+	Created_At       time.Time         `json:"created_at"`
+	Last_Accessed_At *time.Time        `json:"last_accessed_at,omitempty"`
+}
+
+// OutboundFeedDocument is the format agnostic form of a rendered feed, it is filled
+// from a post listing and then rendered as RSS, Atom or JSON Feed.
+type OutboundFeedDocument struct {
+	Title       string
+	Description string
+	Link        string // the page the feed represents, we use the frontend
+	Self        string // the URL the feed is served from
+	Updated     time.Time
+	Items       []OutboundFeedItem
+}
+
+type OutboundFeedItem struct {
+	ID        uuid.UUID
+	Title     string
+	Link      string
+	Summary   string
+	Content   string
+	ImageURL  string
+	Source    string
+	Published time.Time
+	Scraped   time.Time
+}
+
+func ValidateOutboundFeed(v *validator.Validator, outboundFeed *OutboundFeed) {
+	v.Check(validator.PermittedValue(outboundFeed.Source,
+		OutboundSourceTimeline, OutboundSourceFeed, OutboundSourceSavedSearch, OutboundSourceFavorites),
+		"source", "must be one of timeline, feed, saved_search or favorites")
+	switch outboundFeed.Source {
+	case OutboundSourceFeed:
+		v.Check(outboundFeed.Feed_ID.Valid, "feed_id", "must be provided for a feed source")
+	case OutboundSourceSavedSearch:
+		v.Check(outboundFeed.Saved_Search_ID.Valid, "saved_search_id", "must be provided for a saved search source")
+	}
+}
+
+// CreateOutboundFeed() generates a new secret token for an outbound feed and saves its
+// hash. The token is generated just like our API keys and set on the passed feed.
+func (m OutboundFeedsModel) CreateOutboundFeed(outboundFeed *OutboundFeed) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	token, err := generateAPI(outboundFeed.User_ID, 0, "outbound-feed", APIKeyLength)
+	if err != nil {
+		return err
+	}
+	// only hold the feed scopes relevant to the source
+	if outboundFeed.Source != OutboundSourceFeed {
+		outboundFeed.Feed_ID = uuid.NullUUID{}
+	}
+	if outboundFeed.Source != OutboundSourceSavedSearch {
+		outboundFeed.Saved_Search_ID = uuid.NullUUID{}
+	}
+	row, err := m.DB.CreateOutboundFeed(ctx, database.CreateOutboundFeedParams{
+		TokenHash:     token.Hash,
+		UserID:        outboundFeed.User_ID,
+		Source:        outboundFeed.Source,
+		FeedID:        outboundFeed.Feed_ID,
+		SavedSearchID: outboundFeed.Saved_Search_ID,
+	})
+	if err != nil {
+		return err
+	}
+	outboundFeed.ID = row.ID
+	outboundFeed.Created_At = row.CreatedAt
+	outboundFeed.Token = token.Plaintext
+	return nil
+}
+
+// GetOutboundFeedsForUser() returns all the outbound feeds a user has created. Tokens
+// are not returned as we only hold their hashes.
+func (m OutboundFeedsModel) GetOutboundFeedsForUser(userID int64) ([]*OutboundFeed, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := m.DB.GetOutboundFeedsForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	outboundFeeds := []*OutboundFeed{}
+	for _, row := range rows {
+		outboundFeeds = append(outboundFeeds, &OutboundFeed{
+			ID:               row.ID,
+			User_ID:          row.UserID,
+			Source:           row.Source,
+			Feed_ID:          row.FeedID,
+			Saved_Search_ID:  row.SavedSearchID,
+			Created_At:       row.CreatedAt,
+			Last_Accessed_At: nullTimeToTime(row.LastAccessedAt),
+		})
+	}
+	return outboundFeeds, nil
+}
+
+// GetOutboundFeedByToken() looks up an outbound feed using the plaintext token from the
+// URL, recording the access as it goes. Revoked or unknown tokens return an
+// ErrOutboundFeedNotFound.
+func (m OutboundFeedsModel) GetOutboundFeedByToken(token string) (*OutboundFeed, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tokenHash := sha256.Sum256([]byte(token))
+	row, err := m.DB.GetOutboundFeedByToken(ctx, tokenHash[:])
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrOutboundFeedNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &OutboundFeed{
+		ID:               row.ID,
+		User_ID:          row.UserID,
+		Source:           row.Source,
+		Feed_ID:          row.FeedID,
+		Saved_Search_ID:  row.SavedSearchID,
+		Created_At:       row.CreatedAt,
+		Last_Accessed_At: nullTimeToTime(row.LastAccessedAt),
+	}, nil
+}
+
+// DeleteOutboundFeed() revokes an outbound feed. Its token stops working immediately.
+func (m OutboundFeedsModel) DeleteOutboundFeed(outboundFeedID uuid.UUID, userID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := m.DB.DeleteOutboundFeed(ctx, database.DeleteOutboundFeedParams{
+		ID:     outboundFeedID,
+		UserID: userID,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrOutboundFeedNotFound
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// NewOutboundFeedDocument() builds a document from a post listing. The posts carry their
+// published date as a string, so we parse it back and fall back to the scrape date.
+func NewOutboundFeedDocument(title, description, link, self string, posts []*RSSFeedWithFavorite) *OutboundFeedDocument {
+	document := &OutboundFeedDocument{
+		Title:       title,
+		Description: description,
+		Link:        link,
+		Self:        self,
+	}
+	for _, post := range posts {
+		if post.RSSFeed == nil || len(post.RSSFeed.Channel.Item) == 0 {
+			continue
+		}
+		item := post.RSSFeed.Channel.Item[0]
+		published, err := time.Parse(postPubDateLayout, item.PubDate)
+		if err != nil {
+			published = post.RSSFeed.Createdat
+		}
+		document.Items = append(document.Items, OutboundFeedItem{
+			ID:        post.RSSFeed.ID,
+			Title:     item.Title,
+			Link:      item.Link,
+			Summary:   item.Description,
+			Content:   item.Content,
+			ImageURL:  item.ImageURL,
+			Source:    post.RSSFeed.Channel.Title,
+			Published: published,
+			Scraped:   post.RSSFeed.Createdat,
+		})
+		if published.After(document.Updated) {
+			document.Updated = published
+		}
+		if post.RSSFeed.Createdat.After(document.Updated) {
+			document.Updated = post.RSSFeed.Createdat
+		}
+	}
+	return document
+}
+
+// postPubDateLayout is the layout of time.Time.String(), which is how the post
+// listings format RSSItem.PubDate.
+const postPubDateLayout = "2006-01-02 15:04:05.999999999 -0700 MST"
+
+// itemGUID() gives each post a stable, globally unique identifier across all formats.
+func itemGUID(id uuid.UUID) string {
+	return "urn:uuid:" + id.String()
+}
+
+// RSS 2.0
+type rssDocument struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	AtomNS  string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	AtomLink      atomLink  `xml:"atom:link"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string        `xml:"title"`
+	Link        string        `xml:"link"`
+	Description string        `xml:"description"`
+	GUID        rssGUID       `xml:"guid"`
+	PubDate     string        `xml:"pubDate"`
+	Source      string        `xml:"category,omitempty"`
+	Enclosure   *rssEnclosure `xml:"enclosure,omitempty"`
+}
+
+type rssGUID struct {
+	IsPermaLink string `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Length int    `xml:"length,attr"`
+}
+
+// RenderRSS() renders the document as RSS 2.0
+func (d *OutboundFeedDocument) RenderRSS() ([]byte, error) {
+	channel := rssChannel{
+		Title:       d.Title,
+		Link:        d.Link,
+		Description: d.Description,
+		AtomLink:    atomLink{Href: d.Self, Rel: "self", Type: "application/rss+xml"},
+	}
+	if !d.Updated.IsZero() {
+		channel.LastBuildDate = d.Updated.UTC().Format(time.RFC1123Z)
+	}
+	for _, item := range d.Items {
+		rssItem := rssItem{
+			Title:       item.Title,
+			Link:        item.Link,
+			Description: item.Summary,
+			GUID:        rssGUID{IsPermaLink: "false", Value: itemGUID(item.ID)},
+			PubDate:     item.Published.UTC().Format(time.RFC1123Z),
+			Source:      item.Source,
+		}
+		if item.ImageURL != "" {
+			rssItem.Enclosure = &rssEnclosure{URL: item.ImageURL, Type: "image/jpeg"}
+		}
+		channel.Items = append(channel.Items, rssItem)
+	}
+	return marshalXML(rssDocument{Version: "2.0", AtomNS: "http://www.w3.org/2005/Atom", Channel: channel})
+}
+
+// Atom 1.0
+type atomFeed struct {
+	XMLName xml.Name    `xml:"feed"`
+	XMLNS   string      `xml:"xmlns,attr"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Updated   string      `xml:"updated"`
+	Published string      `xml:"published"`
+	Links     []atomLink  `xml:"link"`
+	Summary   atomText    `xml:"summary"`
+	Content   *atomText   `xml:"content,omitempty"`
+	Author    *atomAuthor `xml:"author,omitempty"`
+}
+
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+// RenderAtom() renders the document as Atom 1.0
+func (d *OutboundFeedDocument) RenderAtom() ([]byte, error) {
+	updated := d.Updated
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+	feed := atomFeed{
+		XMLNS:   "http://www.w3.org/2005/Atom",
+		ID:      d.Self,
+		Title:   d.Title,
+		Updated: updated.UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: d.Link, Rel: "alternate", Type: "text/html"},
+			{Href: d.Self, Rel: "self", Type: "application/atom+xml"},
+		},
+	}
+	for _, item := range d.Items {
+		entry := atomEntry{
+			ID:        itemGUID(item.ID),
+			Title:     item.Title,
+			Updated:   item.Scraped.UTC().Format(time.RFC3339),
+			Published: item.Published.UTC().Format(time.RFC3339),
+			Links:     []atomLink{{Href: item.Link, Rel: "alternate"}},
+			Summary:   atomText{Type: "html", Value: item.Summary},
+		}
+		if item.Content != "" {
+			entry.Content = &atomText{Type: "html", Value: item.Content}
+		}
+		if item.Source != "" {
+			entry.Author = &atomAuthor{Name: item.Source}
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	return marshalXML(feed)
+}
+
+// JSON Feed 1.1
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url,omitempty"`
+	FeedURL     string         `json:"feed_url,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedItem struct {
+	ID            string            `json:"id"`
+	URL           string            `json:"url,omitempty"`
+	Title         string            `json:"title,omitempty"`
+	ContentHTML   string            `json:"content_html,omitempty"`
+	Summary       string            `json:"summary,omitempty"`
+	Image         string            `json:"image,omitempty"`
+	DatePublished string            `json:"date_published,omitempty"`
+	DateModified  string            `json:"date_modified,omitempty"`
+	Authors       []jsonFeedAuthors `json:"authors,omitempty"`
+}
+
+type jsonFeedAuthors struct {
+	Name string `json:"name"`
+}
+
+// RenderJSONFeed() renders the document as JSON Feed 1.1
+func (d *OutboundFeedDocument) RenderJSONFeed() ([]byte, error) {
+	feed := jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       d.Title,
+		HomePageURL: d.Link,
+		FeedURL:     d.Self,
+		Description: d.Description,
+		Items:       []jsonFeedItem{},
+	}
+	for _, item := range d.Items {
+		jsonItem := jsonFeedItem{
+			ID:            itemGUID(item.ID),
+			URL:           item.Link,
+			Title:         item.Title,
+			ContentHTML:   item.Content,
+			Summary:       item.Summary,
+			Image:         item.ImageURL,
+			DatePublished: item.Published.UTC().Format(time.RFC3339),
+			DateModified:  item.Scraped.UTC().Format(time.RFC3339),
+		}
+		// content_html is required when there is no text content
+		if jsonItem.ContentHTML == "" {
+			jsonItem.ContentHTML = item.Summary
+		}
+		if item.Source != "" {
+			jsonItem.Authors = []jsonFeedAuthors{{Name: item.Source}}
+		}
+		feed.Items = append(feed.Items, jsonItem)
+	}
+	return json.MarshalIndent(feed, "", "\t")
+}
+
+// Render() renders the document in the requested format
+func (d *OutboundFeedDocument) Render(format string) ([]byte, error) {
+	switch format {
+	case OutboundFormatRSS:
+		return d.RenderRSS()
+	case OutboundFormatAtom:
+		return d.RenderAtom()
+	default:
+		return d.RenderJSONFeed()
+	}
+}
+
+// marshalXML() marshals an XML document adding the XML header
+func marshalXML(v any) ([]byte, error) {
+	body, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
@@ -0,0 +1,59 @@
+package data
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mmcdole/gofeed"
+)
+
+func TestOutboundFeedDocumentRender(t *testing.T) {
+	published := time.Date(2024, 7, 1, 12, 30, 0, 0, time.UTC)
+	post := &RSSFeedWithFavorite{RSSFeed: &RSSFeed{ID: uuid.New(), Createdat: published.Add(time.Hour)}}
+	post.RSSFeed.Channel.Title = "Go Blog"
+	post.RSSFeed.Channel.Item = []RSSItem{{
+		Title:       "Range Over Func",
+		Link:        "https://go.dev/blog/range-functions",
+		Description: "<p>Iterators & more</p>",
+		PubDate:     published.String(),
+	}}
+	document := NewOutboundFeedDocument("Aggregate - Timeline", "Posts from the feeds you follow",
+		"http://localhost:5173", "http://localhost:4000/v1/feeds/outbound/token", []*RSSFeedWithFavorite{post})
+
+	tests := []struct {
+		name     string
+		format   string
+		wantType string
+	}{
+		{name: "RSS 2.0", format: OutboundFormatRSS, wantType: "rss"},
+		{name: "Atom 1.0", format: OutboundFormatAtom, wantType: "atom"},
+		{name: "JSON Feed", format: OutboundFormatJSON, wantType: "json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := document.Render(tt.format)
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			feed, err := gofeed.NewParser().Parse(bytes.NewReader(body))
+			if err != nil {
+				t.Fatalf("unable to parse the rendered feed: %v", err)
+			}
+			if feed.FeedType != tt.wantType {
+				t.Errorf("Got feed type:%v But Wanted:%v", feed.FeedType, tt.wantType)
+			}
+			if feed.Title != document.Title || len(feed.Items) != 1 {
+				t.Fatalf("Got title:%v items:%d But Wanted title:%v items:1", feed.Title, len(feed.Items), document.Title)
+			}
+			item := feed.Items[0]
+			if item.Title != "Range Over Func" || item.Link != "https://go.dev/blog/range-functions" {
+				t.Errorf("Got item:%v %v", item.Title, item.Link)
+			}
+			if item.PublishedParsed == nil || !item.PublishedParsed.Equal(published) {
+				t.Errorf("Got published:%v But Wanted:%v", item.PublishedParsed, published)
+			}
+		})
+	}
+}
@@ -5,7 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"strconv"
+	"strings"
 	"time"
 
 	"github.com/blue-davinci/aggregate/internal/database"
@@ -13,14 +13,6 @@ import (
 	"github.com/google/uuid"
 )
 
-type PaymentOperation int8
-
-const (
-	PaymentOperationInitialize PaymentOperation = iota
-	PaymentOperationVerify
-	PaymentOperationRecurring
-)
-
 var (
 	ErrTransactionDeclined           = errors.New("transaction declined")
 	ErrDuplicateTransaction          = errors.New("duplicate transaction")
@@ -31,6 +23,7 @@ var (
 
 var (
 	PaymentStatusActive    = "active"
+	PaymentStatusPastDue   = "past_due"
 	PaymentStatusRenewed   = "renewed"
 	PaymentStatusExpired   = "expired"
 	PaymentStatusCancelled = "cancelled"
@@ -125,11 +118,6 @@ type Data struct {
 	Subaccount         map[string]interface{} `json:"subaccount"`
 }
 
-type UnifiedPaymentResponse struct {
-	VerifyResponse     VerifyResponse     `json:"verify_response"`
-	InitializeResponse InitializeResponse `json:"initialize_response"`
-}
-
 type VerifyResponse struct {
 	Status  bool   `json:"status"`
 	Message string `json:"message"`
@@ -138,7 +126,8 @@ type VerifyResponse struct {
 
 type InitializeRequest struct {
 	Email       string `json:"email"`
-	Amount      int    `json:"amount"`
+	Amount      int64  `json:"amount"`
+	Currency    string `json:"currency,omitempty"`
 	CallbackURL string `json:"callback_url"`
 }
 
@@ -152,10 +141,14 @@ type InitializeResponse struct {
 	} `json:"data"`
 }
 
+// TransactionData is a payment as the client asked for it. Amount is what is charged,
+// after the discount of a promo code if one was used.
 type TransactionData struct {
 	User_ID            int64  `json:"-"`
 	Plan_ID            int32  `json:"plan_id"`
-	Amount             int64  `json:"amount"`
+	Amount             Money  `json:"amount"`
+	Promo_Code         string `json:"promo_code,omitempty"`
+	Discount           *Money `json:"discount,omitempty"`
 	Email              string `json:"email"`
 	CallBackURL        string `json:"callback_url"`
 	Reference          string `json:"reference"`
@@ -164,13 +157,22 @@ type TransactionData struct {
 
 // Payment_Plan struct represents all the info we will
 // return in relation to our subscription plans.
+// Price is in the plan's own currency and Prices holds what
+// the plan costs in the other currencies it is sold in. Local_Price
+// is what a user is charged given their locale. Trial_Days is how long a
+// free trial of the plan runs, 0 when it has none. Seats is how many members
+// of the subscriber's organization the plan covers, 1 for individual plans.
 type Payment_Plan struct {
 	ID          int32     `json:"id"`
 	Name        string    `json:"name"`
 	Image       string    `json:"image"`
 	Description string    `json:"description"`
 	Duration    string    `json:"duration"`
-	Price       int64     `json:"amount"`
+	Price       Money     `json:"price"`
+	Prices      []Money   `json:"prices"`
+	Local_Price *Money    `json:"local_price,omitempty"`
+	Trial_Days  int32     `json:"trial_days"`
+	Seats       int32     `json:"seats"`
 	Features    []string  `json:"features"`
 	Created_At  time.Time `json:"created_at"`
 	Updated_At  time.Time `json:"updated_at"`
@@ -178,6 +180,30 @@ type Payment_Plan struct {
 	Version     int32     `json:"version"`
 }
 
+// PriceIn() returns what the plan costs in a currency. An empty currency
+// is the plan's own price.
+func (p *Payment_Plan) PriceIn(currency string) (Money, bool) {
+	currency = strings.ToUpper(currency)
+	if currency == "" || currency == p.Price.Currency {
+		return p.Price, true
+	}
+	for _, price := range p.Prices {
+		if price.Currency == currency {
+			return price, true
+		}
+	}
+	return Money{}, false
+}
+
+// PriceForLocale() returns the plan's price in the currency of a user's locale,
+// falling back to the plan's own price when it isn't sold in that currency.
+func (p *Payment_Plan) PriceForLocale(locale string) Money {
+	if price, ok := p.PriceIn(CurrencyForLocale(locale)); ok {
+		return price
+	}
+	return p.Price
+}
+
 // Payment_Confirmation
 // id, user_id, plan_id, start_date, end_date, status, transaction_id;
 type Payment_Details struct {
@@ -186,7 +212,7 @@ type Payment_Details struct {
 	Plan_ID            int32     `json:"plan_id"`
 	Start_Date         time.Time `json:"start_date"`
 	End_Date           time.Time `json:"end_date"`
-	Price              int64     `json:"price"`
+	Price              Money     `json:"price"`
 	Status             string    `json:"status"`
 	TransactionID      int64     `json:"-"`
 	Payment_Method     string    `json:"payment_method"`
@@ -195,7 +221,9 @@ type Payment_Details struct {
 	Card_Exp_Month     string    `json:"-"`
 	Card_Exp_Year      string    `json:"-"`
 	Card_Type          string    `json:"card_type"`
-	Currency           string    `json:"currency"`
+	Provider           string    `json:"provider"`
+	Provider_Reference string    `json:"-"`
+	Is_Trial           bool      `json:"is_trial"`
 	Created_At         time.Time `json:"created_at"`
 	Updated_At         time.Time `json:"updated_at"`
 }
@@ -224,18 +252,21 @@ type Subscription struct {
 	Plan_ID    int32     `json:"plan_id"`
 	Start_Date time.Time `json:"start_date"`
 	End_Date   time.Time `json:"end_date"`
-	Price      int64     `json:"price"`
+	Price      Money     `json:"price"`
 	Status     string    `json:"status"`
+	Is_Trial   bool      `json:"is_trial"`
 	Updated_At time.Time `json:"updated_at"`
 }
 
 type RecurringSubscription struct {
-	Subscription       Subscription `json:"subscription"`
-	Currency           string       `json:"currency"`
-	User_ID            int64        `json:"user_id"`
-	User_Name          string       `json:"user_name"`
-	User_Email         string       `json:"user_email"`
-	Authorization_Code string       `json:"authorization_code"`
+	Subscription         Subscription `json:"subscription"`
+	Provider             string       `json:"provider"`
+	User_ID              int64        `json:"user_id"`
+	User_Name            string       `json:"user_name"`
+	User_Email           string       `json:"user_email"`
+	Authorization_Code   string       `json:"authorization_code"`
+	Renewal_Attempts     int32        `json:"renewal_attempts"`
+	Renewal_Locked_Until time.Time    `json:"renewal_locked_until"`
 }
 
 type ChallengedTransaction struct {
@@ -251,10 +282,14 @@ type ChallengedTransaction struct {
 
 // ValidateTransactionData will validate the initialization transaction data provided by the client.
 func ValidateTransactionData(v *validator.Validator, transactionData *TransactionData) {
-	//amount
-	v.Check(transactionData.Amount != 0, "amount", "must be varied")
+	//amount, in minor units of the currency
+	v.Check(transactionData.Amount.Amount > 0, "amount", "must be varied")
 	// plan id
 	v.Check(transactionData.Plan_ID != 0, "plan_id", "must be provided")
+	// currency, optional and defaults to the user's locale
+	if transactionData.Amount.Currency != "" {
+		v.Check(len(transactionData.Amount.Currency) == 3, "currency", "must be a 3 letter ISO currency code")
+	}
 }
 
 // ValidateVerificationData will validate the validation transaction data provided by the client.
@@ -291,7 +326,17 @@ func ValidatePaymentPlan(v *validator.Validator, paymentPlan *Payment_Plan) {
 	v.Check(paymentPlan.Description != "", "description", "must be provided")
 	v.Check(len(paymentPlan.Description) <= 500, "description", "must not be more than 500 characters")
 	v.Check(paymentPlan.Duration != "", "duration", "must be provided")
-	v.Check(paymentPlan.Price != 0, "price", "must be provided")
+	ValidateMoney(v, "price", paymentPlan.Price)
+	currencies := map[string]bool{paymentPlan.Price.Currency: true}
+	for _, price := range paymentPlan.Prices {
+		ValidateMoney(v, "prices", price)
+		v.Check(!currencies[price.Currency], "prices", "must have one price per currency")
+		currencies[price.Currency] = true
+	}
+	v.Check(paymentPlan.Trial_Days >= 0, "trial_days", "must not be negative")
+	v.Check(paymentPlan.Trial_Days <= 365, "trial_days", "must not be more than a year")
+	v.Check(paymentPlan.Seats >= 1, "seats", "must be at least 1")
+	v.Check(paymentPlan.Seats <= 1000, "seats", "must not be more than 1000")
 	v.Check(len(paymentPlan.Features) != 0, "features", "must be provided")
 	v.Check(paymentPlan.Status != "", "status", "must be provided")
 	v.Check(paymentPlan.Status == "active" || paymentPlan.Status == "inactive", "status", "must be either 'active' or 'inactive'")
@@ -318,13 +363,9 @@ func (m PaymentsModel) GetSubscriptionByID(userID int64) (*Subscription, error)
 	userSub.Plan_ID = subscription.PlanID
 	userSub.Start_Date = subscription.StartDate
 	userSub.End_Date = subscription.EndDate
-	priceStr := subscription.Price
-	price, err := strconv.ParseFloat(priceStr, 64)
-	if err != nil {
-		return nil, err
-	}
 	userSub.Status = subscription.Status
-	userSub.Price = int64(price)
+	userSub.Is_Trial = subscription.IsTrial
+	userSub.Price = NewMoney(subscription.Price, subscription.Currency)
 	// we're good, we return the subscription
 	return &userSub, nil
 }
@@ -351,13 +392,8 @@ func (m PaymentsModel) GetActiveOrNonExpiredSubscriptionByID(userID int64) (*Sub
 	userSub.Plan_ID = subscription.PlanID
 	userSub.Start_Date = subscription.StartDate
 	userSub.End_Date = subscription.EndDate
-	priceStr := subscription.Price
-	price, err := strconv.ParseFloat(priceStr, 64)
-	if err != nil {
-		return nil, err
-	}
 	userSub.Status = subscription.Status
-	userSub.Price = int64(price)
+	userSub.Price = NewMoney(subscription.Price, subscription.Currency)
 	// we're good, we return the subscription
 	return &userSub, nil
 }
@@ -365,19 +401,18 @@ func (m PaymentsModel) GetActiveOrNonExpiredSubscriptionByID(userID int64) (*Sub
 // CreateSubscription will create a new subscription for a user.
 // This takes in payment details provided from the client and is only activated when
 // the payment is successful. We return an error if the transaction fails.
-// We also return an error if we detect a pre-existing transaction/auth code
-// which denotes an already existing and active subscription.
+// We also return an error if the provider's reference for the payment was already
+// saved which denotes an already existing and active subscription.
 func (m PaymentsModel) CreateSubscription(payment_detail *Payment_Details) error {
 	// create our timeout context. All of them will just be 5 seconds
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	priceStr := strconv.FormatFloat(float64(payment_detail.Price), 'f', 2, 64)
 	queyresult, err := m.DB.CreateSubscription(ctx, database.CreateSubscriptionParams{
 		UserID:            payment_detail.User_ID,
 		PlanID:            payment_detail.Plan_ID,
 		StartDate:         payment_detail.Start_Date,
 		EndDate:           payment_detail.End_Date,
-		Price:             priceStr,
+		Price:             payment_detail.Price.Amount,
 		Status:            PaymentStatusActive, // set it to active
 		TransactionID:     payment_detail.TransactionID,
 		PaymentMethod:     sql.NullString{String: payment_detail.Payment_Method, Valid: payment_detail.Payment_Method != ""},
@@ -386,12 +421,14 @@ func (m PaymentsModel) CreateSubscription(payment_detail *Payment_Details) error
 		CardExpMonth:      sql.NullString{String: payment_detail.Card_Exp_Month, Valid: payment_detail.Card_Exp_Month != ""},
 		CardExpYear:       sql.NullString{String: payment_detail.Card_Exp_Year, Valid: payment_detail.Card_Exp_Year != ""},
 		CardType:          sql.NullString{String: payment_detail.Card_Type, Valid: payment_detail.Card_Type != ""},
-		Currency:          sql.NullString{String: payment_detail.Currency, Valid: payment_detail.Currency != ""},
+		Currency:          payment_detail.Price.Currency,
+		Provider:          payment_detail.Provider,
+		ProviderReference: sql.NullString{String: payment_detail.Provider_Reference, Valid: payment_detail.Provider_Reference != ""},
 	})
 	if err != nil {
 		switch {
 		case err.Error() == `pq: duplicate key value violates unique constraint "subscriptions_transaction_id_key"` ||
-			err.Error() == `pq: duplicate key value violates unique constraint "subscriptions_transaction_id_authorization_code_key"`:
+			err.Error() == `pq: duplicate key value violates unique constraint "idx_subscriptions_provider_reference"`:
 			return ErrDuplicateTransaction
 		default:
 			return err
@@ -405,6 +442,39 @@ func (m PaymentsModel) CreateSubscription(payment_detail *Payment_Details) error
 	return nil
 }
 
+// CreateTrialSubscription() starts a free trial of a plan. The trial is an active
+// subscription priced at what the plan costs once the trial is over, there is no
+// transaction nor card behind it.
+func (m PaymentsModel) CreateTrialSubscription(payment_detail *Payment_Details) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	queryResult, err := m.DB.CreateTrialSubscription(ctx, database.CreateTrialSubscriptionParams{
+		UserID:    payment_detail.User_ID,
+		PlanID:    payment_detail.Plan_ID,
+		StartDate: payment_detail.Start_Date,
+		EndDate:   payment_detail.End_Date,
+		Price:     payment_detail.Price.Amount,
+		Currency:  payment_detail.Price.Currency,
+		Provider:  payment_detail.Provider,
+	})
+	if err != nil {
+		return err
+	}
+	payment_detail.ID = queryResult.ID
+	payment_detail.Status = PaymentStatusActive
+	payment_detail.Is_Trial = true
+	payment_detail.Created_At = queryResult.CreatedAt
+	payment_detail.Updated_At = queryResult.UpdatedAt
+	return nil
+}
+
+// HasUserHadTrial() reports whether a user ever started a free trial, each user gets one.
+func (m PaymentsModel) HasUserHadTrial(userID int64) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return m.DB.HasUserHadTrial(ctx, userID)
+}
+
 func (m PaymentsModel) CreateChallangedTransaction(subscription *RecurringSubscription, url, challanged_error, reference string) error {
 	// create our timeout context. All of them will just be 5 seconds
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -429,19 +499,19 @@ func (m PaymentsModel) CreateFailedTransaction(paymentDetails *Payment_Details,
 	// create our timeout context. All of them will just be 5 seconds
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	priceStr := strconv.FormatFloat(float64(paymentDetails.Price), 'f', 2, 64)
 	queryResult, err := m.DB.CreateFailedTransaction(ctx, database.CreateFailedTransactionParams{
 		UserID:            paymentDetails.User_ID,
 		SubscriptionID:    paymentDetails.ID,
 		AuthorizationCode: sql.NullString{String: paymentDetails.Authorization_Code, Valid: true},
 		Reference:         reference,
-		Amount:            priceStr,
+		Amount:            paymentDetails.Price.Amount,
 		FailureReason:     sql.NullString{String: failure_reason, Valid: true},
 		ErrorCode:         sql.NullString{String: error_code, Valid: true},
 		CardLast4:         sql.NullString{String: paymentDetails.Card_Last4, Valid: true},
 		CardExpMonth:      sql.NullString{String: paymentDetails.Card_Exp_Month, Valid: true},
 		CardExpYear:       sql.NullString{String: paymentDetails.Card_Exp_Year, Valid: true},
 		CardType:          sql.NullString{String: paymentDetails.Card_Type, Valid: true},
+		Currency:          sql.NullString{String: paymentDetails.Price.Currency, Valid: paymentDetails.Price.Currency != ""},
 	})
 	if err != nil {
 		return 0, err
@@ -502,7 +572,7 @@ func (m PaymentsModel) GetAllSubscriptionsByID(userID int64, filters Filters) ([
 		payment_history.Card_Exp_Month = row.CardExpMonth.String
 		payment_history.Card_Exp_Year = row.CardExpYear.String
 		payment_history.Card_Type = row.CardType.String
-		payment_history.Currency = row.Currency.String
+		payment_history.Currency = row.Currency
 		payment_history.Created_At = row.CreatedAt
 		// plan details
 		payment_history.Plan_Name = row.PlanName
@@ -515,13 +585,9 @@ func (m PaymentsModel) GetAllSubscriptionsByID(userID int64, filters Filters) ([
 		subscription.Plan_ID = row.PlanID
 		subscription.Start_Date = row.StartDate
 		subscription.End_Date = row.EndDate
-		priceStr := row.Price
-		price, err := strconv.ParseFloat(priceStr, 64)
-		if err != nil {
-			return nil, Metadata{}, err
-		}
-		subscription.Price = int64(price)
+		subscription.Price = NewMoney(row.Price, row.Currency)
 		subscription.Status = row.Status
+		subscription.Is_Trial = row.IsTrial
 		payment_history.Subscription = subscription
 		payment_histories = append(payment_histories, &payment_history)
 	}
@@ -531,51 +597,6 @@ func (m PaymentsModel) GetAllSubscriptionsByID(userID int64, filters Filters) ([
 	return payment_histories, metadata, nil
 }
 
-// GetAllExpiredSubscriptions() returns all the expired subscriptions.
-// The function supports pagination and metadata reporting.
-// It works in tandem with our expiration checker checking for all items
-// whose dates have passed and are expired
-func (m PaymentsModel) GetAllExpiredSubscriptions(filters Filters) ([]*RecurringSubscription, Metadata, error) {
-	// create our timeout context. All of them will just be 5 seconds
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	rows, err := m.DB.GetAllExpiredSubscriptions(ctx, database.GetAllExpiredSubscriptionsParams{
-		Limit:  int32(filters.limit()),
-		Offset: int32(filters.offset()),
-	})
-	if err != nil {
-		fmt.Println("Error: ", err)
-		return nil, Metadata{}, err
-	}
-	recurring_subscriptions := []*RecurringSubscription{}
-	totalRecords := 0
-	for _, row := range rows {
-		var recurring_subscription RecurringSubscription
-		totalRecords = int(row.TotalRecords)
-		recurring_subscription.User_ID = row.UserID
-		recurring_subscription.User_Name = row.Name
-		recurring_subscription.User_Email = row.Email
-		recurring_subscription.Authorization_Code = row.AuthorizationCode.String
-		recurring_subscription.Currency = row.Currency.String
-		// fill in the subscription details
-		var subscription Subscription
-		subscription.ID = row.SubscriptionID
-		subscription.Plan_ID = row.PlanID
-		priceStr, err := strconv.ParseFloat(row.Price, 64)
-		if err != nil {
-			return nil, Metadata{}, err
-		}
-		subscription.Price = int64(priceStr)
-		// Add the subscription to the recurring_subscription
-		recurring_subscription.Subscription = subscription
-		// fill in the subscription details
-		recurring_subscriptions = append(recurring_subscriptions, &recurring_subscription)
-	}
-	// calculate the metadata
-	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
-	return recurring_subscriptions, metadata, nil
-}
-
 // GetPaymentDetailsByID will return an individual plan giving back all
 // available information about the plan.
 func (m PaymentsModel) GetPaymentPlanByID(plan_ID int32) (*Payment_Plan, error) {
@@ -598,16 +619,18 @@ func (m PaymentsModel) GetPaymentPlanByID(plan_ID int32) (*Payment_Plan, error)
 	payment_plan.Image = plan.Image
 	payment_plan.Description = plan.Description.String
 	payment_plan.Duration = plan.Duration
-	priceStr := plan.Price
-	price, err := strconv.ParseFloat(priceStr, 64)
-	if err != nil {
-		return nil, err
-	}
-	payment_plan.Price = int64(price)
+	payment_plan.Price = NewMoney(plan.Price, plan.Currency)
 	payment_plan.Features = plan.Features
 	payment_plan.Created_At = plan.CreatedAt
 	payment_plan.Updated_At = plan.UpdatedAt
 	payment_plan.Status = plan.Status
+	payment_plan.Trial_Days = plan.TrialDays
+	payment_plan.Seats = plan.Seats
+	// and what it costs in the other currencies it is sold in
+	payment_plan.Prices, err = getPlanPrices(ctx, m.DB, plan.ID)
+	if err != nil {
+		return nil, err
+	}
 	// we're good, we return the payment_plan
 	return &payment_plan, nil
 }
@@ -623,6 +646,10 @@ func (m PaymentsModel) GetPaymentPlans() ([]*Payment_Plan, error) {
 	if err != nil {
 		return nil, err
 	}
+	prices, err := getAllPlanPrices(ctx, m.DB)
+	if err != nil {
+		return nil, err
+	}
 	payment_plans := []*Payment_Plan{}
 	for _, row := range rows {
 		var payment_plan Payment_Plan
@@ -631,16 +658,17 @@ func (m PaymentsModel) GetPaymentPlans() ([]*Payment_Plan, error) {
 		payment_plan.Image = row.Image
 		payment_plan.Description = row.Description.String
 		payment_plan.Duration = row.Duration
-		priceStr := row.Price
-		price, err := strconv.ParseFloat(priceStr, 64)
-		if err != nil {
-			return nil, err
+		payment_plan.Price = NewMoney(row.Price, row.Currency)
+		payment_plan.Prices = prices[row.ID]
+		if payment_plan.Prices == nil {
+			payment_plan.Prices = []Money{}
 		}
-		payment_plan.Price = int64(price)
 		payment_plan.Features = row.Features
 		payment_plan.Created_At = row.CreatedAt
 		payment_plan.Updated_At = row.UpdatedAt
 		payment_plan.Status = row.Status
+		payment_plan.Trial_Days = row.TrialDays
+		payment_plan.Seats = row.Seats
 
 		payment_plans = append(payment_plans, &payment_plan)
 	}
@@ -728,14 +756,14 @@ func (m PaymentsModel) UpdateSubscriptionStatus(subscriptionID uuid.UUID, status
 	return nil
 }
 
-// UpdateSubscriptionStatusAfterExpiration will update the status of all subscriptions
-// setting the ones that are expired to the status "expired".
-func (m PaymentsModel) UpdateSubscriptionStatusAfterExpiration() ([]*Subscription, error) {
+// UpdateSubscriptionStatusAfterExpiration will update the status of all past due
+// subscriptions that ended before the cutoff, i.e whose grace period is over, to "expired".
+func (m PaymentsModel) UpdateSubscriptionStatusAfterExpiration(cutoff time.Time) ([]*Subscription, error) {
 	// create our timeout context. All of them will just be 5 seconds
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	// will ignore the result
-	rows, err := m.DB.UpdateSubscriptionStatusAfterExpiration(ctx)
+	rows, err := m.DB.UpdateSubscriptionStatusAfterExpiration(ctx, cutoff)
 	if err != nil {
 		return nil, err
 	}
@@ -804,3 +832,50 @@ func (m PaymentsModel) UpdateExpiredChallengedTransactionStatus() ([]*Challenged
 
 	return challengedSubscriptions, nil
 }
+
+// getPlanPrices() returns what a plan costs in its other currencies
+func getPlanPrices(ctx context.Context, db *database.Queries, planID int32) ([]Money, error) {
+	rows, err := db.GetPaymentPlanPrices(ctx, planID)
+	if err != nil {
+		return nil, err
+	}
+	prices := []Money{}
+	for _, row := range rows {
+		prices = append(prices, NewMoney(row.Amount, row.Currency))
+	}
+	return prices, nil
+}
+
+// getAllPlanPrices() returns the prices of every plan in its other currencies keyed by plan ID
+func getAllPlanPrices(ctx context.Context, db *database.Queries) (map[int32][]Money, error) {
+	rows, err := db.GetAllPaymentPlanPrices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	prices := make(map[int32][]Money)
+	for _, row := range rows {
+		prices[row.PlanID] = append(prices[row.PlanID], NewMoney(row.Amount, row.Currency))
+	}
+	return prices, nil
+}
+
+// setPlanPrices() saves what a plan costs in its other currencies, removing the
+// currencies it is no longer sold in.
+func setPlanPrices(ctx context.Context, db *database.Queries, planID int32, prices []Money) error {
+	currencies := []string{}
+	for _, price := range prices {
+		err := db.UpsertPaymentPlanPrice(ctx, database.UpsertPaymentPlanPriceParams{
+			PlanID:   planID,
+			Currency: price.Currency,
+			Amount:   price.Amount,
+		})
+		if err != nil {
+			return err
+		}
+		currencies = append(currencies, price.Currency)
+	}
+	return db.DeletePaymentPlanPricesExcept(ctx, database.DeletePaymentPlanPricesExceptParams{
+		PlanID:  planID,
+		Column2: currencies,
+	})
+}
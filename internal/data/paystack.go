@@ -0,0 +1,371 @@
+package data
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PaystackSignatureHeader carries the HMAC-SHA512 of the raw webhook body, keyed with our
+// secret key.
+const PaystackSignatureHeader = "x-paystack-signature"
+
+// The webhook events we act on, paystack sends plenty of others which we acknowledge
+// and ignore.
+const (
+	PaystackEventChargeSuccess        = "charge.success"
+	PaystackEventSubscriptionDisable  = "subscription.disable"
+	PaystackEventInvoicePaymentFailed = "invoice.payment_failed"
+)
+
+// PaystackEvent is a webhook event as sent by paystack. The data is kept raw until we
+// know which event we got, Decode() then fills in the matching struct.
+type PaystackEvent struct {
+	Event  string          `json:"event"`
+	Data   json.RawMessage `json:"data"`
+	dataID int64
+}
+
+// PaystackCustomer is the part of a webhook's customer we need to find the user
+type PaystackCustomer struct {
+	Email        string `json:"email"`
+	CustomerCode string `json:"customer_code"`
+}
+
+// PaystackChargeData is the data of a charge.success event. It is a slimmer Data as the
+// webhook sends objects in places where the verify endpoint sends strings.
+type PaystackChargeData struct {
+	ID              int64            `json:"id"`
+	Status          string           `json:"status"`
+	Reference       string           `json:"reference"`
+	Amount          int64            `json:"amount"`
+	GatewayResponse string           `json:"gateway_response"`
+	PaidAt          string           `json:"paid_at"`
+	Channel         string           `json:"channel"`
+	Currency        string           `json:"currency"`
+	Authorization   Authorization    `json:"authorization"`
+	Customer        PaystackCustomer `json:"customer"`
+}
+
+// PaystackSubscriptionData is the data of a subscription.disable event
+type PaystackSubscriptionData struct {
+	ID               int64            `json:"id"`
+	SubscriptionCode string           `json:"subscription_code"`
+	Status           string           `json:"status"`
+	Authorization    Authorization    `json:"authorization"`
+	Customer         PaystackCustomer `json:"customer"`
+}
+
+// PaystackInvoiceData is the data of an invoice.payment_failed event
+type PaystackInvoiceData struct {
+	ID            int64            `json:"id"`
+	InvoiceCode   string           `json:"invoice_code"`
+	Amount        int64            `json:"amount"`
+	Status        string           `json:"status"`
+	Description   string           `json:"description"`
+	Authorization Authorization    `json:"authorization"`
+	Customer      PaystackCustomer `json:"customer"`
+	Transaction   struct {
+		Reference string `json:"reference"`
+		Status    string `json:"status"`
+		Amount    int64  `json:"amount"`
+		Currency  string `json:"currency"`
+	} `json:"transaction"`
+}
+
+// SignPaystackPayload() returns the hex encoded HMAC-SHA512 paystack sends along with
+// a webhook body.
+func SignPaystackPayload(payload []byte, secret string) string {
+	mac := hmac.New(sha512.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyPaystackSignature() reports whether the signature matches the raw body. Without
+// a secret nothing can be verified so every signature is rejected.
+func VerifyPaystackSignature(payload []byte, signature, secret string) bool {
+	if secret == "" || signature == "" {
+		return false
+	}
+	expected := SignPaystackPayload(payload, secret)
+	return hmac.Equal([]byte(expected), []byte(strings.ToLower(signature)))
+}
+
+// ParsePaystackEvent() decodes a webhook body far enough to know the event and the id
+// of the object it is about, which together make up the event's ID().
+func ParsePaystackEvent(payload []byte) (*PaystackEvent, error) {
+	var event PaystackEvent
+	err := json.Unmarshal(payload, &event)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWebhookEvent, err)
+	}
+	if event.Event == "" || len(event.Data) == 0 {
+		return nil, ErrInvalidWebhookEvent
+	}
+	var object struct {
+		ID int64 `json:"id"`
+	}
+	err = json.Unmarshal(event.Data, &object)
+	if err != nil || object.ID == 0 {
+		return nil, ErrInvalidWebhookEvent
+	}
+	event.dataID = object.ID
+	return &event, nil
+}
+
+// ID() identifies an event for deduplication. Paystack redelivers an event until we
+// acknowledge it, each delivery is about the same object.
+func (e *PaystackEvent) ID() string {
+	return e.Event + ":" + strconv.FormatInt(e.dataID, 10)
+}
+
+// Decode() unmarshals the event's data into dst
+func (e *PaystackEvent) Decode(dst any) error {
+	return json.Unmarshal(e.Data, dst)
+}
+
+// PaystackConfig holds the secret key and endpoints of a paystack account
+type PaystackConfig struct {
+	SecretKey              string
+	InitializationURL      string
+	VerificationURL        string
+	ChargeAuthorizationURL string
+	RefundURL              string
+}
+
+// PaystackProvider takes payments through paystack
+type PaystackProvider struct {
+	config PaystackConfig
+	client *http.Client
+}
+
+// NewPaystackProvider() creates a PaystackProvider whose requests give up after timeout
+func NewPaystackProvider(config PaystackConfig, timeout time.Duration) *PaystackProvider {
+	return &PaystackProvider{
+		config: config,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (p *PaystackProvider) Name() string {
+	return PaymentProviderPaystack
+}
+
+// InitializeCheckout() initializes a transaction, the customer pays for it on paystack's
+// checkout page and is then sent back to the callback URL with the reference.
+func (p *PaystackProvider) InitializeCheckout(ctx context.Context, checkout *Checkout) (*CheckoutSession, error) {
+	var response InitializeResponse
+	err := p.do(ctx, http.MethodPost, p.config.InitializationURL, &InitializeRequest{
+		Email:       checkout.Email,
+		Amount:      checkout.Amount,
+		Currency:    checkout.Currency,
+		CallbackURL: checkout.CallbackURL,
+	}, &response)
+	if err != nil {
+		return nil, err
+	}
+	return &CheckoutSession{
+		Provider:          PaymentProviderPaystack,
+		Authorization_URL: response.Data.AuthorizationURL,
+		Access_Code:       response.Data.AccessCode,
+		Reference:         response.Data.Reference,
+	}, nil
+}
+
+func (p *PaystackProvider) VerifyTransaction(ctx context.Context, reference string) (*ProviderTransaction, error) {
+	var response VerifyResponse
+	err := p.do(ctx, http.MethodGet, p.config.VerificationURL+url.PathEscape(reference), nil, &response)
+	if err != nil {
+		return nil, err
+	}
+	return paystackTransaction(&response.Data), nil
+}
+
+// ChargeAuthorization() charges a saved card. Paystack may pause the charge and ask the
+// customer to authorize it, the transaction is then challenged and carries the URL they
+// authorize it at.
+func (p *PaystackProvider) ChargeAuthorization(ctx context.Context, charge *AuthorizationCharge) (*ProviderTransaction, error) {
+	var response VerifyResponse
+	err := p.do(ctx, http.MethodPost, p.config.ChargeAuthorizationURL, &struct {
+		Email             string `json:"email"`
+		Amount            int64  `json:"amount"`
+		Currency          string `json:"currency,omitempty"`
+		AuthorizationCode string `json:"authorization_code"`
+	}{charge.Email, charge.Amount, charge.Currency, charge.Authorization_Code}, &response)
+	if err != nil {
+		return nil, err
+	}
+	return paystackTransaction(&response.Data), nil
+}
+
+func (p *PaystackProvider) Refund(ctx context.Context, refund *RefundRequest) (*ProviderRefund, error) {
+	body := map[string]any{"transaction": refund.Payment_Reference}
+	if refund.Amount > 0 {
+		body["amount"] = refund.Amount
+	}
+	var response struct {
+		Data struct {
+			ID       int64  `json:"id"`
+			Amount   int64  `json:"amount"`
+			Currency string `json:"currency"`
+			Status   string `json:"status"`
+		} `json:"data"`
+	}
+	err := p.do(ctx, http.MethodPost, p.config.RefundURL, body, &response)
+	if err != nil {
+		return nil, err
+	}
+	return &ProviderRefund{
+		Provider: PaymentProviderPaystack,
+		ID:       strconv.FormatInt(response.Data.ID, 10),
+		Amount:   response.Data.Amount,
+		Currency: response.Data.Currency,
+		Status:   response.Data.Status,
+	}, nil
+}
+
+// ParseWebhook() checks the x-paystack-signature against our secret key and translates
+// the events we act on.
+func (p *PaystackProvider) ParseWebhook(payload []byte, header http.Header) (*WebhookEvent, error) {
+	if !VerifyPaystackSignature(payload, header.Get(PaystackSignatureHeader), p.config.SecretKey) {
+		return nil, ErrInvalidWebhookSignature
+	}
+	event, err := ParsePaystackEvent(payload)
+	if err != nil {
+		return nil, err
+	}
+	webhookEvent := &WebhookEvent{
+		ID:            event.ID(),
+		Provider:      PaymentProviderPaystack,
+		Provider_Type: event.Event,
+	}
+	switch event.Event {
+	case PaystackEventChargeSuccess:
+		var charge PaystackChargeData
+		if err := event.Decode(&charge); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidWebhookEvent, err)
+		}
+		if charge.Status == "success" {
+			webhookEvent.Type = WebhookPaymentSucceeded
+		}
+		webhookEvent.Reference = charge.Reference
+		webhookEvent.Authorization_Code = charge.Authorization.AuthorizationCode
+		webhookEvent.Amount = charge.Amount
+		webhookEvent.Currency = charge.Currency
+	case PaystackEventSubscriptionDisable:
+		var subscription PaystackSubscriptionData
+		if err := event.Decode(&subscription); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidWebhookEvent, err)
+		}
+		webhookEvent.Type = WebhookAuthorizationDisabled
+		webhookEvent.Authorization_Code = subscription.Authorization.AuthorizationCode
+	case PaystackEventInvoicePaymentFailed:
+		var invoice PaystackInvoiceData
+		if err := event.Decode(&invoice); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidWebhookEvent, err)
+		}
+		webhookEvent.Type = WebhookPaymentFailed
+		webhookEvent.Authorization_Code = invoice.Authorization.AuthorizationCode
+		webhookEvent.Reference = invoice.Transaction.Reference
+		if webhookEvent.Reference == "" {
+			webhookEvent.Reference = invoice.InvoiceCode
+		}
+		webhookEvent.Message = invoice.Description
+		webhookEvent.Amount = invoice.Amount
+		webhookEvent.Currency = invoice.Transaction.Currency
+	}
+	return webhookEvent, nil
+}
+
+// do() sends a request to paystack and decodes its reply into dst. Paystack answers
+// requests it refuses with a false status and a message saying why.
+func (p *PaystackProvider) do(ctx context.Context, method, url string, body, dst any) error {
+	var reader io.Reader
+	if body != nil {
+		jsonData, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(jsonData)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.config.SecretKey)
+	req.Header.Set("Content-Type", "application/json")
+	res, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	raw, err := io.ReadAll(io.LimitReader(res.Body, 1_048_576))
+	if err != nil {
+		return err
+	}
+	var reply struct {
+		Status  bool   `json:"status"`
+		Message string `json:"message"`
+	}
+	err = json.Unmarshal(raw, &reply)
+	if err != nil {
+		return fmt.Errorf("decoding paystack response with status %d: %w", res.StatusCode, err)
+	}
+	if !reply.Status {
+		err = fmt.Errorf("paystack responded with status %d: %s", res.StatusCode, reply.Message)
+		if providerRejected(res.StatusCode) {
+			return fmt.Errorf("%w: %w", ErrPaymentProviderRejected, err)
+		}
+		return err
+	}
+	err = json.Unmarshal(raw, dst)
+	if err != nil {
+		return fmt.Errorf("decoding paystack response: %w", err)
+	}
+	return nil
+}
+
+// paystackTransaction() translates paystack's transaction data. A transaction counts as
+// successful when either its status or the gateway says so, just as we always took it.
+func paystackTransaction(d *Data) *ProviderTransaction {
+	transaction := &ProviderTransaction{
+		Provider:          PaymentProviderPaystack,
+		ID:                d.ID,
+		Reference:         d.Reference,
+		Payment_Reference: d.Reference,
+		Gateway_Response:  d.GatewayResponse,
+		Message:           d.GatewayResponse,
+		Amount:            d.Amount,
+		Currency:          d.Currency,
+		Channel:           d.Channel,
+		Paid_At:           d.TransactionDate,
+		Authorization_URL: d.Authorization_url,
+		Authorization:     d.Authorization,
+		Customer_Email:    d.Customer.Email,
+	}
+	if d.Message != nil && *d.Message != "" {
+		transaction.Message = *d.Message
+	}
+	if transaction.Paid_At == "" {
+		transaction.Paid_At = d.PaidAt
+	}
+	switch {
+	case d.Paused:
+		transaction.Status = TransactionStatusChallenged
+	case d.Status == "success" || d.GatewayResponse == "Approved":
+		transaction.Status = TransactionStatusSuccess
+	default:
+		transaction.Status = TransactionStatusFailed
+	}
+	return transaction
+}
@@ -0,0 +1,407 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// the fixtures in testdata/paystack were signed with this key, each .sig file holds the
+// x-paystack-signature paystack would send along with the matching .json body
+const testPaystackSecret = "sk_test_webhook_fixture"
+
+func readPaystackFixture(t *testing.T, name string) ([]byte, string) {
+	t.Helper()
+	payload, err := os.ReadFile(filepath.Join("testdata", "paystack", name+".json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, err := os.ReadFile(filepath.Join("testdata", "paystack", name+".sig"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return payload, strings.TrimSpace(string(signature))
+}
+
+func TestVerifyPaystackSignature(t *testing.T) {
+	payload, signature := readPaystackFixture(t, "charge_success")
+	tampered := []byte(strings.Replace(string(payload), `"amount": 100000`, `"amount": 100`, 1))
+	tests := []struct {
+		name      string
+		payload   []byte
+		signature string
+		secret    string
+		want      bool
+	}{
+		{name: "Signed Fixture", payload: payload, signature: signature, secret: testPaystackSecret, want: true},
+		{name: "Upper Case Signature", payload: payload, signature: strings.ToUpper(signature), secret: testPaystackSecret, want: true},
+		{name: "Tampered Body", payload: tampered, signature: signature, secret: testPaystackSecret, want: false},
+		{name: "Wrong Secret", payload: payload, signature: signature, secret: "sk_test_other", want: false},
+		{name: "Missing Signature", payload: payload, signature: "", secret: testPaystackSecret, want: false},
+		{name: "No Secret Configured", payload: payload, signature: SignPaystackPayload(payload, ""), secret: "", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyPaystackSignature(tt.payload, tt.signature, tt.secret); got != tt.want {
+				t.Errorf("Got:%t But Wanted:%t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePaystackEvent(t *testing.T) {
+	tests := []struct {
+		fixture string
+		wantID  string
+		check   func(t *testing.T, event *PaystackEvent)
+	}{
+		{
+			fixture: "charge_success",
+			wantID:  "charge.success:4099260516",
+			check: func(t *testing.T, event *PaystackEvent) {
+				var charge PaystackChargeData
+				if err := event.Decode(&charge); err != nil {
+					t.Fatal(err)
+				}
+				if charge.Reference != "re4lyvq3s3" || charge.Amount != 100000 || charge.Status != "success" {
+					t.Errorf("Got:%+v But Wanted the fixture's reference, amount and status", charge)
+				}
+				if charge.Authorization.AuthorizationCode != "AUTH_0kq3v2jxr1" || charge.Customer.Email != "jane@example.com" {
+					t.Errorf("Got:%+v But Wanted the fixture's authorization and customer", charge)
+				}
+			},
+		},
+		{
+			fixture: "subscription_disable",
+			wantID:  "subscription.disable:539422",
+			check: func(t *testing.T, event *PaystackEvent) {
+				var subscription PaystackSubscriptionData
+				if err := event.Decode(&subscription); err != nil {
+					t.Fatal(err)
+				}
+				if subscription.SubscriptionCode != "SUB_vsyqdmlzble3uii" || subscription.Authorization.AuthorizationCode != "AUTH_0kq3v2jxr1" {
+					t.Errorf("Got:%+v But Wanted the fixture's subscription and authorization", subscription)
+				}
+			},
+		},
+		{
+			fixture: "invoice_payment_failed",
+			wantID:  "invoice.payment_failed:3953015",
+			check: func(t *testing.T, event *PaystackEvent) {
+				var invoice PaystackInvoiceData
+				if err := event.Decode(&invoice); err != nil {
+					t.Fatal(err)
+				}
+				if invoice.Description != "Insufficient Funds" || invoice.Transaction.Reference != "v5f1h9c2zq" {
+					t.Errorf("Got:%+v But Wanted the fixture's description and transaction", invoice)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			payload, signature := readPaystackFixture(t, tt.fixture)
+			if !VerifyPaystackSignature(payload, signature, testPaystackSecret) {
+				t.Fatal("fixture signature does not verify")
+			}
+			event, err := ParsePaystackEvent(payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if event.ID() != tt.wantID {
+				t.Errorf("Got:%s But Wanted:%s", event.ID(), tt.wantID)
+			}
+			tt.check(t, event)
+		})
+	}
+}
+
+func TestParsePaystackEventInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+	}{
+		{name: "Not JSON", payload: "event=charge.success"},
+		{name: "No Event", payload: `{"data": {"id": 1}}`},
+		{name: "No Data", payload: `{"event": "charge.success"}`},
+		{name: "No Object ID", payload: `{"event": "charge.success", "data": {"reference": "abc"}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParsePaystackEvent([]byte(tt.payload)); !errors.Is(err, ErrInvalidWebhookEvent) {
+				t.Errorf("Got:%v But Wanted:%v", err, ErrInvalidWebhookEvent)
+			}
+		})
+	}
+}
+
+// newFakePaystack() starts a local stand-in for paystack's API. Every request must carry
+// our secret key, JSON bodies are handed to the route's handler decoded.
+func newFakePaystack(t *testing.T, routes map[string]func(body map[string]any) (int, string)) *PaystackProvider {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer "+testPaystackSecret {
+			t.Errorf("Got Authorization:%q But Wanted the secret key", got)
+		}
+		route, ok := routes[r.Method+" "+r.URL.Path]
+		if !ok {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+			return
+		}
+		body := map[string]any{}
+		if r.Method == http.MethodPost {
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("decoding request body: %v", err)
+			}
+		}
+		status, reply := route(body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		fmt.Fprint(w, reply)
+	}))
+	t.Cleanup(server.Close)
+	return NewPaystackProvider(PaystackConfig{
+		SecretKey:              testPaystackSecret,
+		InitializationURL:      server.URL + "/transaction/initialize",
+		VerificationURL:        server.URL + "/transaction/verify/",
+		ChargeAuthorizationURL: server.URL + "/transaction/charge_authorization",
+		RefundURL:              server.URL + "/refund",
+	}, 5*time.Second)
+}
+
+func TestPaystackProviderInitializeCheckout(t *testing.T) {
+	provider := newFakePaystack(t, map[string]func(map[string]any) (int, string){
+		"POST /transaction/initialize": func(body map[string]any) (int, string) {
+			if body["email"] != "jane@example.com" || body["amount"] != float64(500000) || body["callback_url"] != "https://aggregate.test/verify" {
+				t.Errorf("Got body:%v But Wanted the checkout's email, amount and callback", body)
+			}
+			if _, ok := body["currency"]; ok {
+				t.Errorf("Got currency:%v But Wanted none so paystack uses the account's", body["currency"])
+			}
+			return http.StatusOK, `{"status": true, "message": "Authorization URL created", "data": {
+				"authorization_url": "https://checkout.paystack.com/0peioxfhpn", "access_code": "0peioxfhpn", "reference": "7PVGX8MEk85tgeEpVDtD"}}`
+		},
+	})
+	session, err := provider.InitializeCheckout(context.Background(), &Checkout{
+		Email:       "jane@example.com",
+		Amount:      500000,
+		CallbackURL: "https://aggregate.test/verify",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := CheckoutSession{
+		Provider:          PaymentProviderPaystack,
+		Authorization_URL: "https://checkout.paystack.com/0peioxfhpn",
+		Access_Code:       "0peioxfhpn",
+		Reference:         "7PVGX8MEk85tgeEpVDtD",
+	}
+	if *session != want {
+		t.Errorf("Got:%+v But Wanted:%+v", *session, want)
+	}
+}
+
+func TestPaystackProviderVerifyTransaction(t *testing.T) {
+	tests := []struct {
+		name       string
+		reply      string
+		wantStatus string
+		wantErr    bool
+	}{
+		{
+			name: "Success",
+			reply: `{"status": true, "message": "Verification successful", "data": {"id": 4099260516, "status": "success",
+				"reference": "re4lyvq3s3", "amount": 100000, "gateway_response": "Successful", "channel": "card", "currency": "NGN",
+				"transaction_date": "2024-08-22T09:15:02.000Z", "authorization": {"authorization_code": "AUTH_0kq3v2jxr1",
+				"last4": "4081", "exp_month": "12", "exp_year": "2030", "card_type": "visa "}, "customer": {"email": "jane@example.com"}}}`,
+			wantStatus: TransactionStatusSuccess,
+		},
+		{
+			name: "Declined",
+			reply: `{"status": true, "message": "Verification successful", "data": {"id": 4099260517, "status": "failed",
+				"reference": "re4lyvq3s3", "amount": 100000, "gateway_response": "Declined"}}`,
+			wantStatus: TransactionStatusFailed,
+		},
+		{
+			name:    "Unknown Reference",
+			reply:   `{"status": false, "message": "Transaction reference not found"}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := newFakePaystack(t, map[string]func(map[string]any) (int, string){
+				"GET /transaction/verify/re4lyvq3s3": func(map[string]any) (int, string) {
+					if tt.wantErr {
+						return http.StatusBadRequest, tt.reply
+					}
+					return http.StatusOK, tt.reply
+				},
+			})
+			transaction, err := provider.VerifyTransaction(context.Background(), "re4lyvq3s3")
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "Transaction reference not found") {
+					t.Errorf("Got:%v But Wanted paystack's message", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if transaction.Status != tt.wantStatus || transaction.Reference != "re4lyvq3s3" || transaction.Payment_Reference != "re4lyvq3s3" {
+				t.Errorf("Got:%+v But Wanted status %s for the reference", transaction, tt.wantStatus)
+			}
+			if tt.wantStatus == TransactionStatusSuccess {
+				if transaction.ID != 4099260516 || transaction.Authorization.AuthorizationCode != "AUTH_0kq3v2jxr1" || transaction.Paid_At != "2024-08-22T09:15:02.000Z" {
+					t.Errorf("Got:%+v But Wanted the transaction's id, authorization and date", transaction)
+				}
+			}
+		})
+	}
+}
+
+func TestPaystackProviderChargeAuthorization(t *testing.T) {
+	tests := []struct {
+		name        string
+		reply       string
+		wantStatus  string
+		wantMessage string
+		wantURL     string
+	}{
+		{
+			name: "Approved",
+			reply: `{"status": true, "message": "Charge attempted", "data": {"id": 51, "status": "success", "reference": "rc01",
+				"amount": 50000, "gateway_response": "Approved", "currency": "KES", "transaction_date": "2024-09-01T00:00:00.000Z"}}`,
+			wantStatus:  TransactionStatusSuccess,
+			wantMessage: "Approved",
+		},
+		{
+			name: "Paused",
+			reply: `{"status": true, "message": "Charge attempted", "data": {"id": 52, "status": "success", "paused": true,
+				"reference": "rc02", "amount": 50000, "gateway_response": "Approved", "message": "Please authorize",
+				"authorization_url": "https://checkout.paystack.com/resume/rc02"}}`,
+			wantStatus:  TransactionStatusChallenged,
+			wantMessage: "Please authorize",
+			wantURL:     "https://checkout.paystack.com/resume/rc02",
+		},
+		{
+			name: "Declined",
+			reply: `{"status": true, "message": "Charge attempted", "data": {"id": 53, "status": "failed", "reference": "rc03",
+				"amount": 50000, "gateway_response": "Insufficient Funds"}}`,
+			wantStatus:  TransactionStatusFailed,
+			wantMessage: "Insufficient Funds",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := newFakePaystack(t, map[string]func(map[string]any) (int, string){
+				"POST /transaction/charge_authorization": func(body map[string]any) (int, string) {
+					if body["authorization_code"] != "AUTH_0kq3v2jxr1" || body["amount"] != float64(50000) || body["currency"] != "KES" {
+						t.Errorf("Got body:%v But Wanted the charge's authorization, amount and currency", body)
+					}
+					return http.StatusOK, tt.reply
+				},
+			})
+			transaction, err := provider.ChargeAuthorization(context.Background(), &AuthorizationCharge{
+				Email:              "jane@example.com",
+				Amount:             50000,
+				Currency:           "KES",
+				Authorization_Code: "AUTH_0kq3v2jxr1",
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if transaction.Status != tt.wantStatus || transaction.Message != tt.wantMessage || transaction.Authorization_URL != tt.wantURL {
+				t.Errorf("Got:%+v But Wanted status %s, message %q and URL %q", transaction, tt.wantStatus, tt.wantMessage, tt.wantURL)
+			}
+		})
+	}
+}
+
+func TestPaystackProviderRefund(t *testing.T) {
+	tests := []struct {
+		name       string
+		amount     int64
+		wantAmount any
+	}{
+		{name: "Full", amount: 0, wantAmount: nil},
+		{name: "Partial", amount: 2500, wantAmount: float64(2500)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := newFakePaystack(t, map[string]func(map[string]any) (int, string){
+				"POST /refund": func(body map[string]any) (int, string) {
+					if body["transaction"] != "re4lyvq3s3" || body["amount"] != tt.wantAmount {
+						t.Errorf("Got body:%v But Wanted the reference and amount %v", body, tt.wantAmount)
+					}
+					return http.StatusOK, `{"status": true, "message": "Refund has been queued for processing", "data": {
+						"id": 3018284, "amount": 2500, "currency": "NGN", "status": "pending"}}`
+				},
+			})
+			refund, err := provider.Refund(context.Background(), &RefundRequest{Payment_Reference: "re4lyvq3s3", Amount: tt.amount})
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := ProviderRefund{Provider: PaymentProviderPaystack, ID: "3018284", Amount: 2500, Currency: "NGN", Status: "pending"}
+			if *refund != want {
+				t.Errorf("Got:%+v But Wanted:%+v", *refund, want)
+			}
+		})
+	}
+}
+
+func TestPaystackProviderParseWebhook(t *testing.T) {
+	provider := NewPaystackProvider(PaystackConfig{SecretKey: testPaystackSecret}, time.Second)
+	tests := []struct {
+		fixture string
+		want    WebhookEvent
+	}{
+		{
+			fixture: "charge_success",
+			want: WebhookEvent{ID: "charge.success:4099260516", Provider: PaymentProviderPaystack, Type: WebhookPaymentSucceeded,
+				Provider_Type: PaystackEventChargeSuccess, Reference: "re4lyvq3s3", Authorization_Code: "AUTH_0kq3v2jxr1", Amount: 100000, Currency: "USD"},
+		},
+		{
+			fixture: "subscription_disable",
+			want: WebhookEvent{ID: "subscription.disable:539422", Provider: PaymentProviderPaystack, Type: WebhookAuthorizationDisabled,
+				Provider_Type: PaystackEventSubscriptionDisable, Authorization_Code: "AUTH_0kq3v2jxr1"},
+		},
+		{
+			fixture: "invoice_payment_failed",
+			want: WebhookEvent{ID: "invoice.payment_failed:3953015", Provider: PaymentProviderPaystack, Type: WebhookPaymentFailed,
+				Provider_Type: PaystackEventInvoicePaymentFailed, Reference: "v5f1h9c2zq", Authorization_Code: "AUTH_0kq3v2jxr1",
+				Amount: 100000, Currency: "USD", Message: "Insufficient Funds"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			payload, signature := readPaystackFixture(t, tt.fixture)
+			header := http.Header{}
+			header.Set(PaystackSignatureHeader, signature)
+			event, err := provider.ParseWebhook(payload, header)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *event != tt.want {
+				t.Errorf("Got:%+v But Wanted:%+v", *event, tt.want)
+			}
+		})
+	}
+	t.Run("Bad Signature", func(t *testing.T) {
+		payload, _ := readPaystackFixture(t, "charge_success")
+		header := http.Header{}
+		header.Set(PaystackSignatureHeader, SignPaystackPayload(payload, "sk_test_other"))
+		if _, err := provider.ParseWebhook(payload, header); !errors.Is(err, ErrInvalidWebhookSignature) {
+			t.Errorf("Got:%v But Wanted:%v", err, ErrInvalidWebhookSignature)
+		}
+	})
+}
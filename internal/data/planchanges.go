@@ -0,0 +1,349 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/blue-davinci/aggregate/internal/database"
+	"github.com/blue-davinci/aggregate/internal/validator"
+	"github.com/google/uuid"
+)
+
+const (
+	PlanChangeUpgrade   = "upgrade"
+	PlanChangeDowngrade = "downgrade"
+)
+
+var (
+	PlanChangeStatusPending   = "pending"
+	PlanChangeStatusScheduled = "scheduled"
+	PlanChangeStatusCompleted = "completed"
+	PlanChangeStatusCancelled = "cancelled"
+	PlanChangeStatusFailed    = "failed"
+)
+
+var (
+	ErrPlanChangeNotFound = errors.New("plan change not found")
+	ErrPlanChangeExists   = errors.New("subscription already has a plan change waiting")
+)
+
+// PlanChange is a subscription moving from one plan to another. Credit and
+// Amount_Charged are in the subscription's currency, New_Subscription_ID is set
+// once the change is completed.
+type PlanChange struct {
+	ID                  int64     `json:"id"`
+	User_ID             int64     `json:"user_id"`
+	Subscription_ID     uuid.UUID `json:"subscription_id"`
+	New_Subscription_ID uuid.UUID `json:"new_subscription_id"`
+	From_Plan_ID        int32     `json:"from_plan_id"`
+	From_Plan_Name      string    `json:"from_plan_name,omitempty"`
+	To_Plan_ID          int32     `json:"to_plan_id"`
+	To_Plan_Name        string    `json:"to_plan_name,omitempty"`
+	Change_Type         string    `json:"change_type"`
+	Status              string    `json:"status"`
+	Credit              Money     `json:"credit"`
+	Amount_Charged      Money     `json:"amount_charged"`
+	Reference           string    `json:"reference,omitempty"`
+	Effective_At        time.Time `json:"effective_at"`
+	Created_At          time.Time `json:"created_at"`
+	Updated_At          time.Time `json:"updated_at"`
+}
+
+// PlanChangeQuote is what moving the current subscription to another plan costs.
+// Upgrades are due now and start straight away, downgrades cost nothing now and take
+// effect when the current subscription ends, renewing at New_Price. Everything is
+// priced in the currency of the current subscription.
+type PlanChangeQuote struct {
+	Change_Type   string    `json:"change_type"`
+	From_Plan_ID  int32     `json:"from_plan_id"`
+	To_Plan_ID    int32     `json:"to_plan_id"`
+	Current_Price Money     `json:"current_price"`
+	New_Price     Money     `json:"new_price"`
+	Credit        Money     `json:"credit"`
+	Amount_Due    Money     `json:"amount_due"`
+	Effective_At  time.Time `json:"effective_at"`
+}
+
+func ValidatePlanChange(v *validator.Validator, current *Subscription, plan *Payment_Plan) {
+	v.Check(plan.ID != current.Plan_ID, "plan_id", "is already your plan")
+	v.Check(!current.Is_Trial, "plan_id", "can't be changed during a free trial")
+	price, ok := plan.PriceIn(current.Price.Currency)
+	v.Check(ok, "plan_id", "is not sold in the currency of your subscription")
+	v.Check(!ok || price.Amount > 0, "plan_id", "cancel your subscription to move to a free plan")
+}
+
+// ProratedCredit() is what is left of a subscription's price for the time it still
+// has to run, counted to the second.
+func ProratedCredit(subscription *Subscription, now time.Time) Money {
+	total := subscription.End_Date.Sub(subscription.Start_Date)
+	remaining := subscription.End_Date.Sub(now)
+	if total <= 0 || remaining <= 0 {
+		return NewMoney(0, subscription.Price.Currency)
+	}
+	remaining = min(remaining, total)
+	credit := subscription.Price.Amount * int64(remaining/time.Second) / int64(total/time.Second)
+	return NewMoney(credit, subscription.Price.Currency)
+}
+
+// QuotePlanChange() prices moving a subscription to a plan. A plan priced above the
+// subscription is an upgrade, anything else waits for the subscription to end.
+// The plan is priced in the subscription's currency, which ValidatePlanChange() checks
+// it is sold in.
+func QuotePlanChange(current *Subscription, plan *Payment_Plan, now time.Time) *PlanChangeQuote {
+	newPrice, _ := plan.PriceIn(current.Price.Currency)
+	quote := &PlanChangeQuote{
+		Change_Type:   PlanChangeDowngrade,
+		From_Plan_ID:  current.Plan_ID,
+		To_Plan_ID:    plan.ID,
+		Current_Price: current.Price,
+		New_Price:     newPrice,
+		Credit:        NewMoney(0, current.Price.Currency),
+		Amount_Due:    NewMoney(0, current.Price.Currency),
+		Effective_At:  current.End_Date,
+	}
+	if newPrice.Amount > current.Price.Amount {
+		quote.Change_Type = PlanChangeUpgrade
+		quote.Credit = ProratedCredit(current, now)
+		quote.Amount_Due = NewMoney(newPrice.Amount-quote.Credit.Amount, current.Price.Currency)
+		quote.Effective_At = now
+	}
+	return quote
+}
+
+func planChangeFromRow(row database.SubscriptionPlanChange) *PlanChange {
+	return &PlanChange{
+		ID:                  row.ID,
+		User_ID:             row.UserID,
+		Subscription_ID:     row.SubscriptionID,
+		New_Subscription_ID: row.NewSubscriptionID.UUID,
+		From_Plan_ID:        row.FromPlanID,
+		To_Plan_ID:          row.ToPlanID,
+		Change_Type:         row.ChangeType,
+		Status:              row.Status,
+		Credit:              NewMoney(row.Credit, row.Currency.String),
+		Amount_Charged:      NewMoney(row.AmountCharged, row.Currency.String),
+		Reference:           row.Reference.String,
+		Effective_At:        row.EffectiveAt,
+		Created_At:          row.CreatedAt,
+		Updated_At:          row.UpdatedAt,
+	}
+}
+
+// GetSubscriptionForPlanChange() returns the active subscription of a user along with
+// what is needed to charge it. Cancelled subscriptions can't change plans.
+func (m PaymentsModel) GetSubscriptionForPlanChange(userID int64) (*RecurringSubscription, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	row, err := m.DB.GetSubscriptionForPlanChange(ctx, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrSubscriptionNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &RecurringSubscription{
+		Subscription: Subscription{
+			ID:         row.ID,
+			User_ID:    row.UserID,
+			Plan_ID:    row.PlanID,
+			Start_Date: row.StartDate,
+			End_Date:   row.EndDate,
+			Price:      NewMoney(row.Price, row.Currency),
+			Status:     row.Status,
+			Is_Trial:   row.IsTrial,
+		},
+		Provider:           row.Provider,
+		User_ID:            row.UserID,
+		User_Name:          row.Name,
+		User_Email:         row.Email,
+		Authorization_Code: row.AuthorizationCode.String,
+	}, nil
+}
+
+// CreatePlanChange() saves a plan change. A subscription can only have one change
+// pending or scheduled at a time.
+func (m PaymentsModel) CreatePlanChange(change *PlanChange) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	row, err := m.DB.CreatePlanChange(ctx, database.CreatePlanChangeParams{
+		UserID:         change.User_ID,
+		SubscriptionID: change.Subscription_ID,
+		FromPlanID:     change.From_Plan_ID,
+		ToPlanID:       change.To_Plan_ID,
+		ChangeType:     change.Change_Type,
+		Status:         change.Status,
+		Credit:         change.Credit.Amount,
+		AmountCharged:  change.Amount_Charged.Amount,
+		Currency:       sql.NullString{String: change.Credit.Currency, Valid: change.Credit.Currency != ""},
+		Reference:      sql.NullString{String: change.Reference, Valid: change.Reference != ""},
+		EffectiveAt:    change.Effective_At,
+	})
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "idx_subscription_plan_changes_open"`:
+			return ErrPlanChangeExists
+		default:
+			return err
+		}
+	}
+	change.ID = row.ID
+	change.Created_At = row.CreatedAt
+	change.Updated_At = row.UpdatedAt
+	return nil
+}
+
+// GetOpenPlanChangeBySubscriptionID() returns the change pending or scheduled on a subscription
+func (m PaymentsModel) GetOpenPlanChangeBySubscriptionID(subscriptionID uuid.UUID) (*PlanChange, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	row, err := m.DB.GetOpenPlanChangeBySubscriptionID(ctx, subscriptionID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrPlanChangeNotFound
+		default:
+			return nil, err
+		}
+	}
+	return planChangeFromRow(row), nil
+}
+
+// GetPendingPlanChangeByReference() returns the change waiting on the charge behind a
+// transaction reference.
+func (m PaymentsModel) GetPendingPlanChangeByReference(reference string) (*PlanChange, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	row, err := m.DB.GetPendingPlanChangeByReference(ctx, sql.NullString{String: reference, Valid: true})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrPlanChangeNotFound
+		default:
+			return nil, err
+		}
+	}
+	return planChangeFromRow(row), nil
+}
+
+// CompletePlanChange() records the subscription a change started and what was charged
+// for it. Changes that were already completed or cancelled are left alone.
+func (m PaymentsModel) CompletePlanChange(change *PlanChange, newSubscriptionID uuid.UUID, amountCharged Money) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	updatedAt, err := m.DB.CompletePlanChange(ctx, database.CompletePlanChangeParams{
+		ID:                change.ID,
+		NewSubscriptionID: uuid.NullUUID{UUID: newSubscriptionID, Valid: true},
+		AmountCharged:     amountCharged.Amount,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrPlanChangeNotFound
+		default:
+			return err
+		}
+	}
+	change.Status = PlanChangeStatusCompleted
+	change.New_Subscription_ID = newSubscriptionID
+	change.Amount_Charged = amountCharged
+	change.Updated_At = updatedAt
+	return nil
+}
+
+// SetPlanChangePending() marks a change as waiting on the customer to authorize the
+// charge behind reference.
+func (m PaymentsModel) SetPlanChangePending(change *PlanChange, reference string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	updatedAt, err := m.DB.SetPlanChangePending(ctx, database.SetPlanChangePendingParams{
+		ID:        change.ID,
+		Reference: sql.NullString{String: reference, Valid: true},
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrPlanChangeNotFound
+		default:
+			return err
+		}
+	}
+	change.Status = PlanChangeStatusPending
+	change.Reference = reference
+	change.Updated_At = updatedAt
+	return nil
+}
+
+// UpdatePlanChangeStatus() sets the status of a plan change
+func (m PaymentsModel) UpdatePlanChangeStatus(change *PlanChange, status string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	updatedAt, err := m.DB.UpdatePlanChangeStatus(ctx, database.UpdatePlanChangeStatusParams{
+		ID:     change.ID,
+		Status: status,
+	})
+	if err != nil {
+		return err
+	}
+	change.Status = status
+	change.Updated_At = updatedAt
+	return nil
+}
+
+// CancelScheduledPlanChange() cancels the downgrade a user has scheduled
+func (m PaymentsModel) CancelScheduledPlanChange(userID int64) (*PlanChange, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	row, err := m.DB.CancelScheduledPlanChange(ctx, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrPlanChangeNotFound
+		default:
+			return nil, err
+		}
+	}
+	return planChangeFromRow(row), nil
+}
+
+// GetPlanChangesByUser() returns a user's plan change history, newest first
+func (m PaymentsModel) GetPlanChangesByUser(userID int64, filters Filters) ([]*PlanChange, Metadata, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := m.DB.GetPlanChangesByUser(ctx, database.GetPlanChangesByUserParams{
+		UserID: userID,
+		Limit:  int32(filters.limit()),
+		Offset: int32(filters.offset()),
+	})
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	totalRecords := 0
+	changes := []*PlanChange{}
+	for _, row := range rows {
+		totalRecords = int(row.TotalRecords)
+		changes = append(changes, &PlanChange{
+			ID:                  row.ID,
+			User_ID:             row.UserID,
+			Subscription_ID:     row.SubscriptionID,
+			New_Subscription_ID: row.NewSubscriptionID.UUID,
+			From_Plan_ID:        row.FromPlanID,
+			From_Plan_Name:      row.FromPlanName,
+			To_Plan_ID:          row.ToPlanID,
+			To_Plan_Name:        row.ToPlanName,
+			Change_Type:         row.ChangeType,
+			Status:              row.Status,
+			Credit:              NewMoney(row.Credit, row.Currency.String),
+			Amount_Charged:      NewMoney(row.AmountCharged, row.Currency.String),
+			Reference:           row.Reference.String,
+			Effective_At:        row.EffectiveAt,
+			Created_At:          row.CreatedAt,
+			Updated_At:          row.UpdatedAt,
+		})
+	}
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return changes, metadata, nil
+}
@@ -0,0 +1,110 @@
+package data
+
+import (
+	"testing"
+	"time"
+
+	"github.com/blue-davinci/aggregate/internal/validator"
+)
+
+func TestProratedCredit(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	subscription := &Subscription{Start_Date: start, End_Date: start.AddDate(0, 0, 30), Price: NewMoney(3000, "USD")}
+	tests := []struct {
+		name string
+		now  time.Time
+		want int64
+	}{
+		{name: "Just Started", now: start, want: 3000},
+		{name: "A Third Used", now: start.AddDate(0, 0, 10), want: 2000},
+		{name: "Part Of A Day", now: start.AddDate(0, 0, 29).Add(12 * time.Hour), want: 50},
+		{name: "Ended", now: start.AddDate(0, 0, 30), want: 0},
+		{name: "Long Ended", now: start.AddDate(0, 2, 0), want: 0},
+		{name: "Before It Started", now: start.Add(-time.Hour), want: 3000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ProratedCredit(subscription, tt.now); got != NewMoney(tt.want, "USD") {
+				t.Errorf("Got:%v But Wanted:%d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuotePlanChange(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 30)
+	now := start.AddDate(0, 0, 15)
+	usd := func(amount int64) Money { return NewMoney(amount, "USD") }
+	kes := func(amount int64) Money { return NewMoney(amount, "KES") }
+	monthly := &Subscription{Plan_ID: 2, Start_Date: start, End_Date: end, Price: usd(1000)}
+	tests := []struct {
+		name    string
+		current *Subscription
+		plan    *Payment_Plan
+		want    PlanChangeQuote
+	}{
+		{
+			name:    "Upgrade",
+			current: monthly,
+			plan:    &Payment_Plan{ID: 3, Price: usd(10000)},
+			want: PlanChangeQuote{Change_Type: PlanChangeUpgrade, From_Plan_ID: 2, To_Plan_ID: 3, Current_Price: usd(1000),
+				New_Price: usd(10000), Credit: usd(500), Amount_Due: usd(9500), Effective_At: now},
+		},
+		{
+			name:    "Downgrade",
+			current: monthly,
+			plan:    &Payment_Plan{ID: 4, Price: usd(500)},
+			want: PlanChangeQuote{Change_Type: PlanChangeDowngrade, From_Plan_ID: 2, To_Plan_ID: 4, Current_Price: usd(1000),
+				New_Price: usd(500), Credit: usd(0), Amount_Due: usd(0), Effective_At: end},
+		},
+		{
+			name:    "Same Price",
+			current: monthly,
+			plan:    &Payment_Plan{ID: 5, Price: usd(1000)},
+			want: PlanChangeQuote{Change_Type: PlanChangeDowngrade, From_Plan_ID: 2, To_Plan_ID: 5, Current_Price: usd(1000),
+				New_Price: usd(1000), Credit: usd(0), Amount_Due: usd(0), Effective_At: end},
+		},
+		{
+			name:    "Subscription Currency",
+			current: &Subscription{Plan_ID: 2, Start_Date: start, End_Date: end, Price: kes(130000)},
+			plan:    &Payment_Plan{ID: 3, Price: usd(10000), Prices: []Money{kes(1300000)}},
+			want: PlanChangeQuote{Change_Type: PlanChangeUpgrade, From_Plan_ID: 2, To_Plan_ID: 3, Current_Price: kes(130000),
+				New_Price: kes(1300000), Credit: kes(65000), Amount_Due: kes(1235000), Effective_At: now},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := QuotePlanChange(tt.current, tt.plan, now); *got != tt.want {
+				t.Errorf("Got:%+v But Wanted:%+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidatePlanChange(t *testing.T) {
+	current := &Subscription{Plan_ID: 2, Price: NewMoney(1000, "KES")}
+	tests := []struct {
+		name  string
+		trial bool
+		plan  *Payment_Plan
+		valid bool
+	}{
+		{name: "Other Plan", plan: &Payment_Plan{ID: 3, Price: NewMoney(10000, "KES")}, valid: true},
+		{name: "Priced In Currency", plan: &Payment_Plan{ID: 3, Price: NewMoney(100, "USD"), Prices: []Money{NewMoney(13000, "KES")}}, valid: true},
+		{name: "Not Sold In Currency", plan: &Payment_Plan{ID: 3, Price: NewMoney(100, "USD")}, valid: false},
+		{name: "Same Plan", plan: &Payment_Plan{ID: 2, Price: NewMoney(1000, "KES")}, valid: false},
+		{name: "Free Plan", plan: &Payment_Plan{ID: 1, Price: NewMoney(0, "KES")}, valid: false},
+		{name: "During Trial", trial: true, plan: &Payment_Plan{ID: 3, Price: NewMoney(10000, "KES")}, valid: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			current := *current
+			current.Is_Trial = tt.trial
+			if ValidatePlanChange(v, &current, tt.plan); v.Valid() != tt.valid {
+				t.Errorf("Got:%v But Wanted valid:%t", v.Errors, tt.valid)
+			}
+		})
+	}
+}
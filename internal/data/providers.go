@@ -0,0 +1,194 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// The payment providers we can take payments through
+const (
+	PaymentProviderPaystack = "paystack"
+	PaymentProviderStripe   = "stripe"
+)
+
+// A provider transaction ends up in one of these. A challenged transaction is waiting on
+// the customer to authorize it, a pending one on them to finish paying.
+const (
+	TransactionStatusSuccess    = "success"
+	TransactionStatusFailed     = "failed"
+	TransactionStatusChallenged = "challenged"
+	TransactionStatusPending    = "pending"
+)
+
+var (
+	ErrUnknownPaymentProvider = errors.New("unknown payment provider")
+	// ErrPaymentProviderRejected is wrapped by the errors of requests a provider answered
+	// and turned down, unlike ones that timed out or never reached it.
+	ErrPaymentProviderRejected = errors.New("payment provider rejected the request")
+)
+
+// providerRejected() reports whether a provider's response status turns a request down
+// for good, retrying a timed out or rate limited request may still go through.
+func providerRejected(statusCode int) bool {
+	return statusCode >= http.StatusBadRequest && statusCode < http.StatusInternalServerError &&
+		statusCode != http.StatusRequestTimeout && statusCode != http.StatusTooManyRequests
+}
+
+// PaymentProvider is a payment processor we take payments through. Paystack and Stripe
+// implement it, which one a payment goes through is decided by PaymentProviders.
+type PaymentProvider interface {
+	// Name is what the provider is stored as on subscriptions and payment intents
+	Name() string
+	// InitializeCheckout starts a payment the customer completes on the provider's page
+	InitializeCheckout(ctx context.Context, checkout *Checkout) (*CheckoutSession, error)
+	// VerifyTransaction looks up the outcome of a checkout by its reference
+	VerifyTransaction(ctx context.Context, reference string) (*ProviderTransaction, error)
+	// ChargeAuthorization charges a saved authorization without the customer present
+	ChargeAuthorization(ctx context.Context, charge *AuthorizationCharge) (*ProviderTransaction, error)
+	// Refund gives back all or part of a payment
+	Refund(ctx context.Context, refund *RefundRequest) (*ProviderRefund, error)
+	// ParseWebhook verifies a webhook request's signature and returns its event
+	ParseWebhook(payload []byte, header http.Header) (*WebhookEvent, error)
+}
+
+// Checkout is a payment to start. Amount is in the currency's minor unit and an empty
+// Currency leaves it to the provider's default.
+type Checkout struct {
+	Email       string
+	Amount      int64
+	Currency    string
+	Description string
+	CallbackURL string
+}
+
+// CheckoutSession is a started payment. The customer is sent to Authorization_URL and
+// the payment is verified by its Reference once they are back.
+type CheckoutSession struct {
+	Provider          string `json:"provider"`
+	Authorization_URL string `json:"authorization_url"`
+	Access_Code       string `json:"access_code,omitempty"`
+	Reference         string `json:"reference"`
+}
+
+// AuthorizationCharge is a charge against an authorization saved from an earlier payment
+type AuthorizationCharge struct {
+	Email              string
+	Amount             int64
+	Currency           string
+	Authorization_Code string
+}
+
+// ProviderTransaction is a payment as a provider reports it. Reference is what the
+// payment was started with while Payment_Reference is what the provider refunds it by,
+// they only differ for providers whose checkout wraps the payment. ID is the provider's
+// numeric id for the payment and is 0 for providers that don't have one.
+type ProviderTransaction struct {
+	Provider          string
+	ID                int64
+	Reference         string
+	Payment_Reference string
+	Status            string
+	Gateway_Response  string
+	Message           string
+	Amount            int64
+	Currency          string
+	Channel           string
+	Paid_At           string
+	Authorization_URL string
+	Authorization     Authorization
+	Customer_Email    string
+}
+
+// Succeeded reports whether the customer was charged
+func (t *ProviderTransaction) Succeeded() bool {
+	return t.Status == TransactionStatusSuccess
+}
+
+// RefundRequest refunds a payment by its Payment_Reference. An Amount of 0 refunds all
+// of it.
+type RefundRequest struct {
+	Payment_Reference string
+	Amount            int64
+}
+
+// ProviderRefund is a refund as the provider reports it
+type ProviderRefund struct {
+	Provider string `json:"provider"`
+	ID       string `json:"id"`
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+	Status   string `json:"status"`
+}
+
+// PaymentProviders holds the configured providers and picks the one a payment goes
+// through. Payments in a currency mapped to a provider go through it, everything else
+// through the deployment's default.
+type PaymentProviders struct {
+	defaultProvider string
+	byCurrency      map[string]string
+	providers       map[string]PaymentProvider
+}
+
+// NewPaymentProviders() sets up the providers, failing when the default or a currency is
+// mapped to a provider that isn't configured.
+func NewPaymentProviders(defaultProvider string, byCurrency map[string]string, providers ...PaymentProvider) (*PaymentProviders, error) {
+	p := &PaymentProviders{
+		defaultProvider: defaultProvider,
+		byCurrency:      make(map[string]string),
+		providers:       make(map[string]PaymentProvider),
+	}
+	for _, provider := range providers {
+		p.providers[provider.Name()] = provider
+	}
+	if _, ok := p.providers[defaultProvider]; !ok {
+		return nil, fmt.Errorf("%w: %q is not configured", ErrUnknownPaymentProvider, defaultProvider)
+	}
+	for currency, name := range byCurrency {
+		if _, ok := p.providers[name]; !ok {
+			return nil, fmt.Errorf("%w: %q for %s is not configured", ErrUnknownPaymentProvider, name, currency)
+		}
+		p.byCurrency[strings.ToUpper(currency)] = name
+	}
+	return p, nil
+}
+
+// Get() returns a provider by its name
+func (p *PaymentProviders) Get(name string) (PaymentProvider, bool) {
+	provider, ok := p.providers[name]
+	return provider, ok
+}
+
+// Default() returns the deployment's default provider
+func (p *PaymentProviders) Default() PaymentProvider {
+	return p.providers[p.defaultProvider]
+}
+
+// ForCurrency() returns the provider payments in a currency go through
+func (p *PaymentProviders) ForCurrency(currency string) PaymentProvider {
+	if name, ok := p.byCurrency[strings.ToUpper(currency)]; ok {
+		return p.providers[name]
+	}
+	return p.Default()
+}
+
+// ParseCurrencyProviders() reads a currency to provider mapping such as
+// "EUR=stripe,GBP=stripe".
+func ParseCurrencyProviders(value string) (map[string]string, error) {
+	byCurrency := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		currency, name, found := strings.Cut(pair, "=")
+		currency, name = strings.ToUpper(strings.TrimSpace(currency)), strings.ToLower(strings.TrimSpace(name))
+		if !found || len(currency) != 3 || name == "" {
+			return nil, fmt.Errorf("invalid currency provider %q, expected a pair such as EUR=stripe", pair)
+		}
+		byCurrency[currency] = name
+	}
+	return byCurrency, nil
+}
@@ -0,0 +1,82 @@
+package data
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPaymentProviders(t *testing.T) {
+	paystack := NewPaystackProvider(PaystackConfig{}, time.Second)
+	stripe := NewStripeProvider(StripeConfig{}, time.Second)
+	providers, err := NewPaymentProviders(PaymentProviderPaystack, map[string]string{"eur": PaymentProviderStripe}, paystack, stripe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		currency string
+		want     string
+	}{
+		{currency: "", want: PaymentProviderPaystack},
+		{currency: "NGN", want: PaymentProviderPaystack},
+		{currency: "EUR", want: PaymentProviderStripe},
+		{currency: "eur", want: PaymentProviderStripe},
+	}
+	for _, tt := range tests {
+		t.Run(tt.currency, func(t *testing.T) {
+			if got := providers.ForCurrency(tt.currency).Name(); got != tt.want {
+				t.Errorf("Got:%s But Wanted:%s", got, tt.want)
+			}
+		})
+	}
+	if _, ok := providers.Get("braintree"); ok {
+		t.Error("Got a provider that was never configured")
+	}
+}
+
+func TestNewPaymentProvidersUnconfigured(t *testing.T) {
+	paystack := NewPaystackProvider(PaystackConfig{}, time.Second)
+	tests := []struct {
+		name            string
+		defaultProvider string
+		byCurrency      map[string]string
+	}{
+		{name: "Default", defaultProvider: PaymentProviderStripe},
+		{name: "Currency", defaultProvider: PaymentProviderPaystack, byCurrency: map[string]string{"EUR": PaymentProviderStripe}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewPaymentProviders(tt.defaultProvider, tt.byCurrency, paystack); !errors.Is(err, ErrUnknownPaymentProvider) {
+				t.Errorf("Got:%v But Wanted:%v", err, ErrUnknownPaymentProvider)
+			}
+		})
+	}
+}
+
+func TestParseCurrencyProviders(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    map[string]string
+		wantErr bool
+	}{
+		{name: "Empty", value: "", want: map[string]string{}},
+		{name: "Pairs", value: "eur=Stripe, GBP=stripe", want: map[string]string{"EUR": "stripe", "GBP": "stripe"}},
+		{name: "Trailing Comma", value: "EUR=stripe,", want: map[string]string{"EUR": "stripe"}},
+		{name: "Missing Provider", value: "EUR=", wantErr: true},
+		{name: "Not A Pair", value: "EUR", wantErr: true},
+		{name: "Bad Currency", value: "EURO=stripe", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCurrencyProviders(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Got error:%v But Wanted error:%t", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Got:%v But Wanted:%v", got, tt.want)
+			}
+		})
+	}
+}
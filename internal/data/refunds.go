@@ -0,0 +1,302 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blue-davinci/aggregate/internal/database"
+	"github.com/blue-davinci/aggregate/internal/validator"
+	"github.com/google/uuid"
+)
+
+const (
+	RefundStatusPending   = "pending"
+	RefundStatusSucceeded = "succeeded"
+	RefundStatusFailed    = "failed"
+)
+
+var (
+	ErrRefundExceedsPayment       = errors.New("refund exceeds what is left of the payment")
+	ErrRefundSubscriptionNotFound = errors.New("subscription to refund not found")
+)
+
+type RefundsModel struct {
+	DB *database.Queries
+}
+
+// Refund gives back all or part of what a subscription was paid. It is pending until
+// the provider settles it, a failed one says why in Failure_Reason.
+type Refund struct {
+	ID                 int64     `json:"id"`
+	Subscription_ID    uuid.UUID `json:"subscription_id"`
+	User_ID            int64     `json:"user_id"`
+	Transaction_ID     int64     `json:"transaction_id"`
+	Invoice_ID         int64     `json:"invoice_id,omitempty"`
+	Provider           string    `json:"provider"`
+	Provider_Refund_ID string    `json:"provider_refund_id,omitempty"`
+	Amount             Money     `json:"amount"`
+	Reason             string    `json:"reason"`
+	Status             string    `json:"status"`
+	Failure_Reason     string    `json:"failure_reason,omitempty"`
+	Ended_Access       bool      `json:"ended_access"`
+	Refunded_By        int64     `json:"refunded_by,omitempty"`
+	Created_At         time.Time `json:"created_at"`
+	Updated_At         time.Time `json:"updated_at"`
+}
+
+// RefundableSubscription is a subscription with what was paid for it and how much of
+// that has been refunded. Amount_Paid is its invoice's total, or its price less any
+// promo code for subscriptions from before invoices.
+type RefundableSubscription struct {
+	ID                 uuid.UUID `json:"id"`
+	User_ID            int64     `json:"user_id"`
+	User_Name          string    `json:"-"`
+	User_Email         string    `json:"-"`
+	Plan_ID            int32     `json:"plan_id"`
+	Plan_Name          string    `json:"plan_name"`
+	Status             string    `json:"status"`
+	Is_Trial           bool      `json:"is_trial"`
+	Transaction_ID     int64     `json:"transaction_id"`
+	Provider           string    `json:"provider"`
+	Provider_Reference string    `json:"-"`
+	End_Date           time.Time `json:"end_date"`
+	Invoice_ID         int64     `json:"invoice_id,omitempty"`
+	Amount_Paid        Money     `json:"amount_paid"`
+	Amount_Refunded    Money     `json:"amount_refunded"`
+}
+
+// Refundable() is what is left of the payment to refund
+func (s *RefundableSubscription) Refundable() Money {
+	return NewMoney(max(s.Amount_Paid.Amount-s.Amount_Refunded.Amount, 0), s.Amount_Paid.Currency)
+}
+
+// PaymentReference() is what the provider knows the payment by. Paystack payments saved
+// before references were kept are refunded by their transaction id, which it also takes.
+func (s *RefundableSubscription) PaymentReference() string {
+	if s.Provider_Reference == "" && s.Provider == PaymentProviderPaystack {
+		return strconv.FormatInt(s.Transaction_ID, 10)
+	}
+	return s.Provider_Reference
+}
+
+// RefundStatusFor() maps a refund status as the provider reports it onto ours. Paystack
+// reports "processed" once the money is on its way and Stripe "succeeded", anything not
+// yet settled stays pending until it is looked at again.
+func RefundStatusFor(providerStatus string) string {
+	switch strings.ToLower(providerStatus) {
+	case "succeeded", "processed":
+		return RefundStatusSucceeded
+	case "failed", "cancelled", "canceled":
+		return RefundStatusFailed
+	}
+	return RefundStatusPending
+}
+
+// ValidateRefund() checks that a refund of amount can be made for a subscription. Free
+// trials and payments that were fully discounted have nothing to give back.
+func ValidateRefund(v *validator.Validator, subscription *RefundableSubscription, amount Money, reason string) {
+	v.Check(!subscription.Is_Trial, "subscription", "is a free trial and was not paid for")
+	v.Check(subscription.PaymentReference() != "", "subscription", "has no payment to refund")
+	v.Check(amount.Amount > 0, "amount", "must be a positive amount in minor units")
+	v.Check(amount.Currency == subscription.Amount_Paid.Currency, "amount", "must be in the currency the subscription was paid in")
+	remaining := subscription.Refundable()
+	if remaining.Amount == 0 {
+		v.AddError("amount", "nothing is left of the payment to refund")
+	}
+	v.Check(amount.Amount <= remaining.Amount, "amount", "must not be more than the "+remaining.String()+" left to refund")
+	v.Check(len(reason) <= 500, "reason", "must not be more than 500 characters")
+}
+
+// GetSubscriptionForRefund() returns a subscription with what was paid and refunded for it
+func (m RefundsModel) GetSubscriptionForRefund(subscriptionID uuid.UUID) (*RefundableSubscription, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	row, err := m.DB.GetSubscriptionForRefund(ctx, subscriptionID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRefundSubscriptionNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &RefundableSubscription{
+		ID:                 row.ID,
+		User_ID:            row.UserID,
+		User_Name:          row.UserName,
+		User_Email:         row.UserEmail,
+		Plan_ID:            row.PlanID,
+		Plan_Name:          row.PlanName,
+		Status:             row.Status,
+		Is_Trial:           row.IsTrial,
+		Transaction_ID:     row.TransactionID,
+		Provider:           row.Provider,
+		Provider_Reference: row.ProviderReference.String,
+		End_Date:           row.EndDate,
+		Invoice_ID:         row.InvoiceID.Int64,
+		Amount_Paid:        NewMoney(row.AmountPaid, row.Currency),
+		Amount_Refunded:    NewMoney(row.AmountRefunded, row.Currency),
+	}, nil
+}
+
+// CreateRefund() saves a pending refund before the provider is asked for it. The refund
+// is added to the subscription's refunded amount in the same statement, only while that
+// stays within what was paid, so two refunds made at once wait on the subscription row
+// and can't give back more than the payment. ErrRefundExceedsPayment is returned when
+// it would.
+func (m RefundsModel) CreateRefund(subscription *RefundableSubscription, refund *Refund) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	row, err := m.DB.CreateRefund(ctx, database.CreateRefundParams{
+		SubscriptionID: subscription.ID,
+		UserID:         subscription.User_ID,
+		TransactionID:  subscription.Transaction_ID,
+		InvoiceID:      sql.NullInt64{Int64: subscription.Invoice_ID, Valid: subscription.Invoice_ID != 0},
+		Provider:       subscription.Provider,
+		Amount:         refund.Amount.Amount,
+		Currency:       refund.Amount.Currency,
+		Reason:         refund.Reason,
+		EndedAccess:    refund.Ended_Access,
+		RefundedBy:     sql.NullInt64{Int64: refund.Refunded_By, Valid: refund.Refunded_By != 0},
+		Column11:       subscription.Amount_Paid.Amount,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRefundExceedsPayment
+		default:
+			return err
+		}
+	}
+	refund.ID = row.ID
+	refund.Subscription_ID = subscription.ID
+	refund.User_ID = subscription.User_ID
+	refund.Transaction_ID = subscription.Transaction_ID
+	refund.Invoice_ID = subscription.Invoice_ID
+	refund.Provider = subscription.Provider
+	refund.Status = row.Status
+	refund.Created_At = row.CreatedAt
+	refund.Updated_At = row.UpdatedAt
+	return nil
+}
+
+// UpdateRefundStatus() records what became of a refund at the provider. A refund that
+// failed no longer counts against the subscription's refunded amount.
+func (m RefundsModel) UpdateRefundStatus(refund *Refund) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	updatedAt, err := m.DB.UpdateRefundStatus(ctx, database.UpdateRefundStatusParams{
+		ID:               refund.ID,
+		Status:           refund.Status,
+		ProviderRefundID: refund.Provider_Refund_ID,
+		FailureReason:    refund.Failure_Reason,
+	})
+	if err != nil {
+		return err
+	}
+	refund.Updated_At = updatedAt
+	return nil
+}
+
+// UpdateInvoiceRefundStatus() marks an invoice refunded or partially refunded by how much
+// of its subscription's payment has been given back.
+func (m RefundsModel) UpdateInvoiceRefundStatus(invoiceID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return m.DB.UpdateInvoiceRefundStatus(ctx, invoiceID)
+}
+
+// EndSubscriptionAccess() cancels a subscription and ends it now rather than at the end
+// of the period it was paid for. It returns when access ended.
+func (m RefundsModel) EndSubscriptionAccess(subscriptionID uuid.UUID) (time.Time, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	endDate, err := m.DB.EndSubscriptionAccess(ctx, subscriptionID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return time.Time{}, ErrRefundSubscriptionNotFound
+		default:
+			return time.Time{}, err
+		}
+	}
+	return endDate, nil
+}
+
+// GetRefundsBySubscriptionID() returns the refunds made for a subscription, the latest first
+func (m RefundsModel) GetRefundsBySubscriptionID(subscriptionID uuid.UUID) ([]*Refund, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := m.DB.GetRefundsBySubscriptionID(ctx, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	refunds := []*Refund{}
+	for _, row := range rows {
+		refunds = append(refunds, refundFromRow(row))
+	}
+	return refunds, nil
+}
+
+// GetAllRefunds() lists every refund, the latest first, optionally only those in a status
+func (m RefundsModel) GetAllRefunds(status string, filters Filters) ([]*Refund, Metadata, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := m.DB.GetAllRefunds(ctx, database.GetAllRefundsParams{
+		Column1: status,
+		Limit:   int32(filters.limit()),
+		Offset:  int32(filters.offset()),
+	})
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	totalRecords := 0
+	refunds := []*Refund{}
+	for _, row := range rows {
+		totalRecords = int(row.TotalRecords)
+		refunds = append(refunds, refundFromRow(database.Refund{
+			ID:               row.ID,
+			SubscriptionID:   row.SubscriptionID,
+			UserID:           row.UserID,
+			TransactionID:    row.TransactionID,
+			InvoiceID:        row.InvoiceID,
+			Provider:         row.Provider,
+			ProviderRefundID: row.ProviderRefundID,
+			Amount:           row.Amount,
+			Currency:         row.Currency,
+			Reason:           row.Reason,
+			Status:           row.Status,
+			FailureReason:    row.FailureReason,
+			EndedAccess:      row.EndedAccess,
+			RefundedBy:       row.RefundedBy,
+			CreatedAt:        row.CreatedAt,
+			UpdatedAt:        row.UpdatedAt,
+		}))
+	}
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return refunds, metadata, nil
+}
+
+func refundFromRow(row database.Refund) *Refund {
+	return &Refund{
+		ID:                 row.ID,
+		Subscription_ID:    row.SubscriptionID,
+		User_ID:            row.UserID,
+		Transaction_ID:     row.TransactionID,
+		Invoice_ID:         row.InvoiceID.Int64,
+		Provider:           row.Provider,
+		Provider_Refund_ID: row.ProviderRefundID,
+		Amount:             NewMoney(row.Amount, row.Currency),
+		Reason:             row.Reason,
+		Status:             row.Status,
+		Failure_Reason:     row.FailureReason,
+		Ended_Access:       row.EndedAccess,
+		Refunded_By:        row.RefundedBy.Int64,
+		Created_At:         row.CreatedAt,
+		Updated_At:         row.UpdatedAt,
+	}
+}
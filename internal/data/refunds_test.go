@@ -0,0 +1,88 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/blue-davinci/aggregate/internal/validator"
+)
+
+func TestRefundStatusFor(t *testing.T) {
+	tests := []struct {
+		name   string
+		status string
+		want   string
+	}{
+		{name: "Stripe Succeeded", status: "succeeded", want: RefundStatusSucceeded},
+		{name: "Paystack Processed", status: "processed", want: RefundStatusSucceeded},
+		{name: "Paystack Pending", status: "pending", want: RefundStatusPending},
+		{name: "Stripe Requires Action", status: "requires_action", want: RefundStatusPending},
+		{name: "Stripe Canceled", status: "canceled", want: RefundStatusFailed},
+		{name: "Failed Upper Case", status: "FAILED", want: RefundStatusFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RefundStatusFor(tt.status); got != tt.want {
+				t.Errorf("Got:%s But Wanted:%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRefundablePaymentReference(t *testing.T) {
+	tests := []struct {
+		name         string
+		subscription RefundableSubscription
+		want         string
+	}{
+		{name: "Stripe", subscription: RefundableSubscription{Provider: PaymentProviderStripe, Provider_Reference: "pi_123", Transaction_ID: 9}, want: "pi_123"},
+		{name: "Paystack", subscription: RefundableSubscription{Provider: PaymentProviderPaystack, Provider_Reference: "ref_1", Transaction_ID: 9}, want: "ref_1"},
+		{name: "Paystack Before References", subscription: RefundableSubscription{Provider: PaymentProviderPaystack, Transaction_ID: 9}, want: "9"},
+		{name: "Stripe Without Reference", subscription: RefundableSubscription{Provider: PaymentProviderStripe, Transaction_ID: 9}, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.subscription.PaymentReference(); got != tt.want {
+				t.Errorf("Got:%q But Wanted:%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateRefund(t *testing.T) {
+	paid := RefundableSubscription{
+		Provider:           PaymentProviderStripe,
+		Provider_Reference: "pi_123",
+		Amount_Paid:        NewMoney(2000, "USD"),
+		Amount_Refunded:    NewMoney(500, "USD"),
+	}
+	trial := paid
+	trial.Is_Trial = true
+	refunded := paid
+	refunded.Amount_Refunded = NewMoney(2000, "USD")
+	tests := []struct {
+		name         string
+		subscription RefundableSubscription
+		amount       Money
+		wantKey      string
+	}{
+		{name: "Partial", subscription: paid, amount: NewMoney(1000, "USD")},
+		{name: "What Is Left", subscription: paid, amount: paid.Refundable()},
+		{name: "More Than Is Left", subscription: paid, amount: NewMoney(1600, "USD"), wantKey: "amount"},
+		{name: "Other Currency", subscription: paid, amount: NewMoney(1000, "EUR"), wantKey: "amount"},
+		{name: "Nothing", subscription: paid, amount: NewMoney(0, "USD"), wantKey: "amount"},
+		{name: "Fully Refunded", subscription: refunded, amount: refunded.Refundable(), wantKey: "amount"},
+		{name: "Free Trial", subscription: trial, amount: NewMoney(1000, "USD"), wantKey: "subscription"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateRefund(v, &tt.subscription, tt.amount, "")
+			if tt.wantKey == "" && !v.Valid() {
+				t.Errorf("Got errors:%v But Wanted none", v.Errors)
+			}
+			if _, ok := v.Errors[tt.wantKey]; tt.wantKey != "" && !ok {
+				t.Errorf("Got errors:%v But Wanted one for %s", v.Errors, tt.wantKey)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package data
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultCommentEditWindow is how long after posting a comment its author can edit it
+const DefaultCommentEditWindow = 24 * time.Hour
+
+// CommentRevision is a comment's text at one of its versions. Created_At is when that
+// text was written and Replaced_At when an edit replaced it, nil for the current text.
+type CommentRevision struct {
+	Version      int32      `json:"version"`
+	Comment_Text string     `json:"comment_text"`
+	Created_At   time.Time  `json:"created_at"`
+	Replaced_At  *time.Time `json:"replaced_at"`
+}
+
+// CommentEditCutoff() returns the oldest a comment can be, going by its creation time,
+// and still be edited. A window of 0 or less keeps comments editable forever.
+func CommentEditCutoff(window time.Duration, now time.Time) time.Time {
+	if window <= 0 {
+		return time.Time{}
+	}
+	return now.Add(-window)
+}
+
+// GetCommentRevisions() returns a comment's revision history newest first, starting with
+// its current text.
+func (m CommentsModel) GetCommentRevisions(comment *Comment) ([]*CommentRevision, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := m.DB.GetCommentRevisions(ctx, comment.ID)
+	if err != nil {
+		return nil, err
+	}
+	current := &CommentRevision{
+		Version:      comment.Version,
+		Comment_Text: comment.Comment_Text,
+		Created_At:   comment.Created_At,
+	}
+	if comment.Edited_At != nil {
+		current.Created_At = *comment.Edited_At
+	}
+	revisions := []*CommentRevision{current}
+	for _, row := range rows {
+		replacedAt := row.ReplacedAt
+		revisions = append(revisions, &CommentRevision{
+			Version:      row.Version,
+			Comment_Text: row.CommentText,
+			Created_At:   row.CreatedAt,
+			Replaced_At:  &replacedAt,
+		})
+	}
+	return revisions, nil
+}
@@ -0,0 +1,29 @@
+package data
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCommentEditCutoff(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name         string
+		window       time.Duration
+		createdAt    time.Time
+		wantEditable bool
+	}{
+		{name: "Within Window", window: time.Hour, createdAt: now.Add(-30 * time.Minute), wantEditable: true},
+		{name: "At The Edge", window: time.Hour, createdAt: now.Add(-time.Hour), wantEditable: true},
+		{name: "Window Passed", window: time.Hour, createdAt: now.Add(-61 * time.Minute)},
+		{name: "No Window", window: 0, createdAt: now.AddDate(-5, 0, 0), wantEditable: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := !tt.createdAt.Before(CommentEditCutoff(tt.window, now))
+			if got != tt.wantEditable {
+				t.Errorf("Got editable:%v But Wanted:%v", got, tt.wantEditable)
+			}
+		})
+	}
+}
@@ -31,6 +31,16 @@ const (
 	DefaultImageURL = "https://images.unsplash.com/photo-1542396601-dca920ea2807?q=80&w=1351&auto=format&fit=crop&ixlib=rb-4.0.3&ixid=M3wxMjA3fDB8MHxwaG90by1wYWdlfHx8fGVufDB8fHx8fA%3D%3D"
 )
 
+// Sorting for the post listings. Posts are ordered newest published first by default.
+const DefaultPostSort = "-published_at"
+
+var PostSortSafelist = []string{
+	"published_at", "-published_at",
+	"created_at", "-created_at",
+	"favorites_count", "-favorites_count",
+	"comments_count", "-comments_count",
+}
+
 var (
 	// Context Error
 	ErrContextDeadline        = errors.New("timeout exceeded while fetching feeds")
@@ -126,6 +136,10 @@ func (m RSSFeedDataModel) MarkFeedAsFetched(feed uuid.UUID) error {
 // to show whether the post is in the user's favorites so that the frontend can set it
 // as a favorite or not
 func (m RSSFeedDataModel) GetFollowedRssPostsForUser(userID int64, feed_name string, feed_id uuid.UUID, filters Filters) ([]*RSSFeedWithFavorite, Metadata, error) {
+	// cursor requests are served by the keyset query
+	if filters.usesCursor() {
+		return m.getFollowedRssPostsForUserByCursor(userID, feed_name, feed_id, filters)
+	}
 	// create our timeout context. All of them will just be 5 seconds
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -133,6 +147,9 @@ func (m RSSFeedDataModel) GetFollowedRssPostsForUser(userID int64, feed_name str
 		UserID:  userID,
 		Column2: feed_name,
 		Column3: feed_id,
+		Column4: filters.Since,
+		Column5: filters.Until,
+		Column6: filters.Sort,
 		Limit:   int32(filters.limit()),
 		Offset:  int32(filters.offset()),
 	})
@@ -178,7 +195,77 @@ func (m RSSFeedDataModel) GetFollowedRssPostsForUser(userID int64, feed_name str
 		metadata = calculateMetadata(totalRecords, filters.Page, filters.PageSize)
 		rssFeedWithFavorites = append(rssFeedWithFavorites, &rssFeedWithFavorite)
 	}
+	// hand out cursors as well so that clients can switch to keyset pagination from any
+	// page. Other sorts aren't keyed on the published date so they stick to pages.
+	if len(rssFeedPosts) > 0 && filters.keysetSortable() {
+		first, last := rssFeedPosts[0], rssFeedPosts[len(rssFeedPosts)-1]
+		cursors := calculateCursorMetadata(filters.PageSize,
+			Cursor{Published_At: first.ItempublishedAt, ID: first.ID},
+			Cursor{Published_At: last.ItempublishedAt, ID: last.ID},
+			filters.Page > 1, filters.offset()+len(rssFeedPosts) < totalRecords)
+		metadata.PrevCursor, metadata.NextCursor = cursors.PrevCursor, cursors.NextCursor
+	}
+	return rssFeedWithFavorites, metadata, nil
+}
 
+// getFollowedRssPostsForUserByCursor() is the keyset counterpart of GetFollowedRssPostsForUser().
+// It pages from the (itempublished_at, id) held in the cursor which means new posts
+// arriving mid-scroll won't shift the pages. We skip the total count for this one.
+func (m RSSFeedDataModel) getFollowedRssPostsForUserByCursor(userID int64, feed_name string, feed_id uuid.UUID, filters Filters) ([]*RSSFeedWithFavorite, Metadata, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	cursor := filters.cursor()
+	rows, err := m.DB.GetFollowedRssPostsForUserByCursor(ctx, database.GetFollowedRssPostsForUserByCursorParams{
+		UserID:  userID,
+		Column2: feed_name,
+		Column3: feed_id,
+		Column4: cursor.Published_At,
+		Column5: cursor.ID,
+		Column6: cursor.Backward,
+		Limit:   int32(filters.limit() + 1),
+		Column8: filters.Since,
+		Column9: filters.Until,
+	})
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	rows, hasPrev, hasNext := trimCursorPage(rows, filters)
+	rssFeedWithFavorites := []*RSSFeedWithFavorite{}
+	for _, row := range rows {
+		var rssFeed RSSFeed
+		// General info
+		rssFeed.ID = row.ID
+		rssFeed.Createdat = row.CreatedAt
+		rssFeed.Updatedat = row.UpdatedAt
+		rssFeed.Feed_ID = row.FeedID
+		// Channel info
+		rssFeed.Channel.Title = row.Channeltitle
+		rssFeed.Channel.Description = row.Channeldescription.String
+		rssFeed.Channel.Link = row.Channelurl.String
+		rssFeed.Channel.Language = row.Channellanguage.String
+		// Item Info
+		rssFeed.Channel.Item = append(rssFeed.Channel.Item, RSSItem{
+			Title:       row.Itemtitle,
+			Link:        row.Itemurl,
+			Description: row.Itemdescription.String,
+			Content:     row.Itemcontent.String,
+			PubDate:     row.ItempublishedAt.String(),
+			ImageURL:    row.ImgUrl,
+		})
+		rssFeedWithFavorites = append(rssFeedWithFavorites, &RSSFeedWithFavorite{
+			RSSFeed:    &rssFeed,
+			IsFavorite: row.IsFavorite,
+			IsFollowed: true,
+		})
+	}
+	if len(rows) == 0 {
+		return rssFeedWithFavorites, Metadata{}, nil
+	}
+	first, last := rows[0], rows[len(rows)-1]
+	metadata := calculateCursorMetadata(filters.PageSize,
+		Cursor{Published_At: first.ItempublishedAt, ID: first.ID},
+		Cursor{Published_At: last.ItempublishedAt, ID: last.ID},
+		hasPrev, hasNext)
 	return rssFeedWithFavorites, metadata, nil
 }
 
@@ -405,6 +492,9 @@ func (m RSSFeedDataModel) GetRandomRSSPosts(feedID uuid.UUID, filters Filters) (
 // This will get the RSS Favorite Posts for a user only, it gets the User ID and the filters
 // and returns a subset of all posts followed by a user
 func (m RSSFeedDataModel) GetRSSFavoritePostsOnlyForUser(userID int64, feed_name string, feed_id uuid.UUID, filters Filters) ([]*RSSFeedWithFavorite, Metadata, error) {
+	if filters.usesCursor() {
+		return m.getRSSFavoritePostsOnlyForUserByCursor(userID, feed_name, feed_id, filters)
+	}
 	// create our timeout context. All of them will just be 5 seconds
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -412,6 +502,9 @@ func (m RSSFeedDataModel) GetRSSFavoritePostsOnlyForUser(userID int64, feed_name
 		UserID:  userID,
 		Column2: feed_name,
 		Column3: feed_id,
+		Column4: filters.Since,
+		Column5: filters.Until,
+		Column6: filters.Sort,
 		Limit:   int32(filters.limit()),
 		Offset:  int32(filters.offset()),
 	})
@@ -454,6 +547,74 @@ func (m RSSFeedDataModel) GetRSSFavoritePostsOnlyForUser(userID int64, feed_name
 		metadata = calculateMetadata(totalRecords, filters.Page, filters.PageSize)
 		favoritePosts = append(favoritePosts, &favoritePost)
 	}
+	if len(rssFeedPosts) > 0 && filters.keysetSortable() {
+		first, last := rssFeedPosts[0], rssFeedPosts[len(rssFeedPosts)-1]
+		cursors := calculateCursorMetadata(filters.PageSize,
+			Cursor{Published_At: first.ItempublishedAt, ID: first.ID},
+			Cursor{Published_At: last.ItempublishedAt, ID: last.ID},
+			filters.Page > 1, filters.offset()+len(rssFeedPosts) < totalRecords)
+		metadata.PrevCursor, metadata.NextCursor = cursors.PrevCursor, cursors.NextCursor
+	}
+	return favoritePosts, metadata, nil
+}
+
+// getRSSFavoritePostsOnlyForUserByCursor() serves the favorites listing using keyset
+// pagination, see getFollowedRssPostsForUserByCursor()
+func (m RSSFeedDataModel) getRSSFavoritePostsOnlyForUserByCursor(userID int64, feed_name string, feed_id uuid.UUID, filters Filters) ([]*RSSFeedWithFavorite, Metadata, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	cursor := filters.cursor()
+	rows, err := m.DB.GetRSSFavoritePostsOnlyForUserByCursor(ctx, database.GetRSSFavoritePostsOnlyForUserByCursorParams{
+		UserID:  userID,
+		Column2: feed_name,
+		Column3: feed_id,
+		Column4: cursor.Published_At,
+		Column5: cursor.ID,
+		Column6: cursor.Backward,
+		Limit:   int32(filters.limit() + 1),
+		Column8: filters.Since,
+		Column9: filters.Until,
+	})
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	rows, hasPrev, hasNext := trimCursorPage(rows, filters)
+	favoritePosts := []*RSSFeedWithFavorite{}
+	for _, row := range rows {
+		var rssPost RSSFeed
+		// General info
+		rssPost.ID = row.ID
+		rssPost.Createdat = row.CreatedAt
+		rssPost.Updatedat = row.UpdatedAt
+		rssPost.Feed_ID = row.FeedID
+		// Channel info
+		rssPost.Channel.Title = row.Channeltitle
+		rssPost.Channel.Description = row.Channeldescription.String
+		rssPost.Channel.Link = row.Channelurl.String
+		rssPost.Channel.Language = row.Channellanguage.String
+		// Item Info
+		rssPost.Channel.Item = append(rssPost.Channel.Item, RSSItem{
+			Title:       row.Itemtitle,
+			Link:        row.Itemurl,
+			Description: row.Itemdescription.String,
+			Content:     row.Itemcontent.String,
+			PubDate:     row.ItempublishedAt.String(),
+			ImageURL:    row.ImgUrl,
+		})
+		favoritePosts = append(favoritePosts, &RSSFeedWithFavorite{
+			RSSFeed:    &rssPost,
+			IsFavorite: row.IsFavorite,
+			IsFollowed: row.IsFollowedFeed.(bool),
+		})
+	}
+	if len(rows) == 0 {
+		return favoritePosts, Metadata{}, nil
+	}
+	first, last := rows[0], rows[len(rows)-1]
+	metadata := calculateCursorMetadata(filters.PageSize,
+		Cursor{Published_At: first.ItempublishedAt, ID: first.ID},
+		Cursor{Published_At: last.ItempublishedAt, ID: last.ID},
+		hasPrev, hasNext)
 	return favoritePosts, metadata, nil
 }
 
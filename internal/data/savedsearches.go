@@ -0,0 +1,438 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/blue-davinci/aggregate/internal/database"
+	"github.com/blue-davinci/aggregate/internal/validator"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrSavedSearchNotFound  = errors.New("saved search not found")
+	ErrDuplicateSavedSearch = errors.New("duplicate saved search")
+)
+
+type SavedSearchesModel struct {
+	DB *database.Queries
+}
+
+// A SavedSearch is a named search over a user's followed posts that behaves like
+// a virtual feed. The Query is matched against the post title the same way the
+// 'name' query works on /feeds/follow/posts, and can be scoped to a single feed
+// as well as a published date range.
+// Unread_Count holds the number of matching posts scraped since the search was last viewed.
+type SavedSearch struct {
+	ID             uuid.UUID     `json:"id"`
+	User_ID        int64         `json:"-"`
+	Name           string        `json:"name"`
+	Query          string        `json:"query"`
+	Feed_ID        uuid.NullUUID `json:"feed_id"`
+	Start_Date     *time.Time    `json:"start_date,omitempty"`
+	End_Date       *time.Time    `json:"end_date,omitempty"`
+	Notify         bool          `json:"notify"`
+	Unread_Count   int64         `json:"unread_count"`
+	Last_Viewed_At time.Time     `json:"last_viewed_at"`
+	Created_At     time.Time     `json:"created_at"`
+	Updated_At     time.Time     `json:"updated_at"`
+	Version        int32         `json:"version"`
+}
+
+// SavedSearchInput is used for partial updates of a saved search
+type SavedSearchInput struct {
+	Name       *string    `json:"name"`
+	Query      *string    `json:"query"`
+	Feed_ID    *uuid.UUID `json:"feed_id"`
+	Start_Date *time.Time `json:"start_date"`
+	End_Date   *time.Time `json:"end_date"`
+	Notify     *bool      `json:"notify"`
+	Version    int32      `json:"version"`
+}
+
+// SavedSearchNotification represents the number of new posts that matched a saved
+// search within the notifier's interval.
+type SavedSearchNotification struct {
+	ID                int64     `json:"id"`
+	Saved_Search_ID   uuid.UUID `json:"saved_search_id"`
+	Saved_Search_Name string    `json:"saved_search_name"`
+	User_ID           int64     `json:"-"`
+	Post_Count        int       `json:"post_count"`
+	Created_At        time.Time `json:"created_at"`
+}
+
+func ValidateSavedSearch(v *validator.Validator, savedSearch *SavedSearch) {
+	v.Check(savedSearch.Name != "", "name", "must be provided")
+	v.Check(len(savedSearch.Name) <= 100, "name", "must not be more than 100 bytes long")
+	v.Check(len(savedSearch.Query) <= 500, "query", "must not be more than 500 bytes long")
+	if savedSearch.Feed_ID.Valid {
+		v.Check(savedSearch.Feed_ID.UUID != uuid.Nil, "feed_id", "must be a valid UUID")
+	}
+	if savedSearch.Start_Date != nil && savedSearch.End_Date != nil {
+		v.Check(savedSearch.End_Date.After(*savedSearch.Start_Date), "end_date", "must be after the start date")
+	}
+}
+
+// UpdateSavedSearchFields() applies the non-nil fields of a SavedSearchInput to a saved search
+func UpdateSavedSearchFields(input *SavedSearchInput, savedSearch *SavedSearch) {
+	if input.Name != nil {
+		savedSearch.Name = *input.Name
+	}
+	if input.Query != nil {
+		savedSearch.Query = *input.Query
+	}
+	if input.Feed_ID != nil {
+		// a nil UUID clears the feed scope
+		savedSearch.Feed_ID = uuid.NullUUID{UUID: *input.Feed_ID, Valid: *input.Feed_ID != uuid.Nil}
+	}
+	if input.Start_Date != nil {
+		savedSearch.Start_Date = zeroTimeToNil(*input.Start_Date)
+	}
+	if input.End_Date != nil {
+		savedSearch.End_Date = zeroTimeToNil(*input.End_Date)
+	}
+	if input.Notify != nil {
+		savedSearch.Notify = *input.Notify
+	}
+	savedSearch.Version = input.Version
+}
+
+// CreateSavedSearch() saves a new search for a user. The name of a saved search is
+// unique per user so we return an ErrDuplicateSavedSearch if it already exists.
+func (m SavedSearchesModel) CreateSavedSearch(savedSearch *SavedSearch) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	queryResult, err := m.DB.CreateSavedSearch(ctx, database.CreateSavedSearchParams{
+		UserID:    savedSearch.User_ID,
+		Name:      savedSearch.Name,
+		Query:     savedSearch.Query,
+		FeedID:    savedSearch.Feed_ID,
+		StartDate: timeToNullTime(savedSearch.Start_Date),
+		EndDate:   timeToNullTime(savedSearch.End_Date),
+		Notify:    savedSearch.Notify,
+	})
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "saved_searches_user_id_name_key"`:
+			return ErrDuplicateSavedSearch
+		default:
+			return err
+		}
+	}
+	savedSearch.ID = queryResult.ID
+	savedSearch.Last_Viewed_At = queryResult.LastViewedAt
+	savedSearch.Created_At = queryResult.CreatedAt
+	savedSearch.Updated_At = queryResult.UpdatedAt
+	savedSearch.Version = queryResult.Version
+	return nil
+}
+
+// GetSavedSearchesForUser() returns all saved searches for a user together with
+// the number of unread posts that match each of them.
+func (m SavedSearchesModel) GetSavedSearchesForUser(userID int64) ([]*SavedSearch, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := m.DB.GetSavedSearchesForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	savedSearches := []*SavedSearch{}
+	for _, row := range rows {
+		savedSearches = append(savedSearches, &SavedSearch{
+			ID:             row.ID,
+			User_ID:        row.UserID,
+			Name:           row.Name,
+			Query:          row.Query,
+			Feed_ID:        row.FeedID,
+			Start_Date:     nullTimeToTime(row.StartDate),
+			End_Date:       nullTimeToTime(row.EndDate),
+			Notify:         row.Notify,
+			Unread_Count:   row.UnreadCount,
+			Last_Viewed_At: row.LastViewedAt,
+			Created_At:     row.CreatedAt,
+			Updated_At:     row.UpdatedAt,
+			Version:        row.Version,
+		})
+	}
+	return savedSearches, nil
+}
+
+// GetSavedSearchByID() returns a single saved search belonging to the user
+func (m SavedSearchesModel) GetSavedSearchByID(savedSearchID uuid.UUID, userID int64) (*SavedSearch, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	row, err := m.DB.GetSavedSearchByID(ctx, database.GetSavedSearchByIDParams{
+		ID:     savedSearchID,
+		UserID: userID,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrSavedSearchNotFound
+		default:
+			return nil, err
+		}
+	}
+	savedSearch := &SavedSearch{
+		ID:             row.ID,
+		User_ID:        row.UserID,
+		Name:           row.Name,
+		Query:          row.Query,
+		Feed_ID:        row.FeedID,
+		Start_Date:     nullTimeToTime(row.StartDate),
+		End_Date:       nullTimeToTime(row.EndDate),
+		Notify:         row.Notify,
+		Last_Viewed_At: row.LastViewedAt,
+		Created_At:     row.CreatedAt,
+		Updated_At:     row.UpdatedAt,
+		Version:        row.Version,
+	}
+	return savedSearch, nil
+}
+
+// UpdateSavedSearch() updates a saved search, using the version to guard against
+// edit conflicts.
+func (m SavedSearchesModel) UpdateSavedSearch(savedSearch *SavedSearch) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	queryResult, err := m.DB.UpdateSavedSearch(ctx, database.UpdateSavedSearchParams{
+		Name:      savedSearch.Name,
+		Query:     savedSearch.Query,
+		FeedID:    savedSearch.Feed_ID,
+		StartDate: timeToNullTime(savedSearch.Start_Date),
+		EndDate:   timeToNullTime(savedSearch.End_Date),
+		Notify:    savedSearch.Notify,
+		ID:        savedSearch.ID,
+		UserID:    savedSearch.User_ID,
+		Version:   savedSearch.Version,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		case err.Error() == `pq: duplicate key value violates unique constraint "saved_searches_user_id_name_key"`:
+			return ErrDuplicateSavedSearch
+		default:
+			return err
+		}
+	}
+	savedSearch.Version = queryResult.Version
+	savedSearch.Updated_At = queryResult.UpdatedAt
+	return nil
+}
+
+// DeleteSavedSearch() deletes a saved search belonging to the user
+func (m SavedSearchesModel) DeleteSavedSearch(savedSearchID uuid.UUID, userID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := m.DB.DeleteSavedSearch(ctx, database.DeleteSavedSearchParams{
+		ID:     savedSearchID,
+		UserID: userID,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrSavedSearchNotFound
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// GetSavedSearchRssPostsForUser() returns the posts that match a saved search. Just like
+// GetFollowedRssPostsForUser() only posts from followed feeds are returned and each post
+// carries an isFavorite field.
+func (m SavedSearchesModel) GetSavedSearchRssPostsForUser(userID int64, savedSearchID uuid.UUID, filters Filters) ([]*RSSFeedWithFavorite, Metadata, error) {
+	if filters.usesCursor() {
+		return m.getSavedSearchRssPostsForUserByCursor(userID, savedSearchID, filters)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := m.DB.GetSavedSearchRssPostsForUser(ctx, database.GetSavedSearchRssPostsForUserParams{
+		UserID: userID,
+		ID:     savedSearchID,
+		Limit:  int32(filters.limit()),
+		Offset: int32(filters.offset()),
+	})
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	totalRecords := 0
+	rssFeedWithFavorites := []*RSSFeedWithFavorite{}
+	for _, row := range rows {
+		var rssFeed RSSFeed
+		totalRecords = int(row.TotalCount)
+		// General info
+		rssFeed.ID = row.ID
+		rssFeed.Createdat = row.CreatedAt
+		rssFeed.Updatedat = row.UpdatedAt
+		rssFeed.Feed_ID = row.FeedID
+		// Channel info
+		rssFeed.Channel.Title = row.Channeltitle
+		rssFeed.Channel.Description = row.Channeldescription.String
+		rssFeed.Channel.Link = row.Channelurl.String
+		rssFeed.Channel.Language = row.Channellanguage.String
+		// Item Info
+		rssFeed.Channel.Item = append(rssFeed.Channel.Item, RSSItem{
+			Title:       row.Itemtitle,
+			Link:        row.Itemurl,
+			Description: row.Itemdescription.String,
+			Content:     row.Itemcontent.String,
+			PubDate:     row.ItempublishedAt.String(),
+			ImageURL:    row.ImgUrl,
+		})
+		rssFeedWithFavorites = append(rssFeedWithFavorites, &RSSFeedWithFavorite{
+			RSSFeed:    &rssFeed,
+			IsFavorite: row.IsFavorite,
+			IsFollowed: true,
+		})
+	}
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	if len(rows) > 0 {
+		first, last := rows[0], rows[len(rows)-1]
+		cursors := calculateCursorMetadata(filters.PageSize,
+			Cursor{Published_At: first.ItempublishedAt, ID: first.ID},
+			Cursor{Published_At: last.ItempublishedAt, ID: last.ID},
+			filters.Page > 1, filters.offset()+len(rows) < totalRecords)
+		metadata.PrevCursor, metadata.NextCursor = cursors.PrevCursor, cursors.NextCursor
+	}
+	return rssFeedWithFavorites, metadata, nil
+}
+
+// getSavedSearchRssPostsForUserByCursor() serves the saved search posts using keyset
+// pagination, see GetSavedSearchRssPostsForUser()
+func (m SavedSearchesModel) getSavedSearchRssPostsForUserByCursor(userID int64, savedSearchID uuid.UUID, filters Filters) ([]*RSSFeedWithFavorite, Metadata, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	cursor := filters.cursor()
+	rows, err := m.DB.GetSavedSearchRssPostsForUserByCursor(ctx, database.GetSavedSearchRssPostsForUserByCursorParams{
+		UserID:  userID,
+		ID:      savedSearchID,
+		Column3: cursor.Published_At,
+		Column4: cursor.ID,
+		Column5: cursor.Backward,
+		Limit:   int32(filters.limit() + 1),
+	})
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	rows, hasPrev, hasNext := trimCursorPage(rows, filters)
+	rssFeedWithFavorites := []*RSSFeedWithFavorite{}
+	for _, row := range rows {
+		var rssFeed RSSFeed
+		// General info
+		rssFeed.ID = row.ID
+		rssFeed.Createdat = row.CreatedAt
+		rssFeed.Updatedat = row.UpdatedAt
+		rssFeed.Feed_ID = row.FeedID
+		// Channel info
+		rssFeed.Channel.Title = row.Channeltitle
+		rssFeed.Channel.Description = row.Channeldescription.String
+		rssFeed.Channel.Link = row.Channelurl.String
+		rssFeed.Channel.Language = row.Channellanguage.String
+		// Item Info
+		rssFeed.Channel.Item = append(rssFeed.Channel.Item, RSSItem{
+			Title:       row.Itemtitle,
+			Link:        row.Itemurl,
+			Description: row.Itemdescription.String,
+			Content:     row.Itemcontent.String,
+			PubDate:     row.ItempublishedAt.String(),
+			ImageURL:    row.ImgUrl,
+		})
+		rssFeedWithFavorites = append(rssFeedWithFavorites, &RSSFeedWithFavorite{
+			RSSFeed:    &rssFeed,
+			IsFavorite: row.IsFavorite,
+			IsFollowed: true,
+		})
+	}
+	if len(rows) == 0 {
+		return rssFeedWithFavorites, Metadata{}, nil
+	}
+	first, last := rows[0], rows[len(rows)-1]
+	metadata := calculateCursorMetadata(filters.PageSize,
+		Cursor{Published_At: first.ItempublishedAt, ID: first.ID},
+		Cursor{Published_At: last.ItempublishedAt, ID: last.ID},
+		hasPrev, hasNext)
+	return rssFeedWithFavorites, metadata, nil
+}
+
+// MarkSavedSearchAsViewed() resets the unread count of a saved search. It is kept apart
+// from GetSavedSearchRssPostsForUser() so that readers such as the outbound feeds can
+// list the posts without touching the user's unread count.
+func (m SavedSearchesModel) MarkSavedSearchAsViewed(savedSearchID uuid.UUID, userID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := m.DB.MarkSavedSearchAsViewed(ctx, database.MarkSavedSearchAsViewedParams{
+		ID:     savedSearchID,
+		UserID: userID,
+	})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// FetchSavedSearchNotifications() is the saved search counterpart of FetchAndStoreNotifications().
+// It counts the posts scraped within the interval that match each saved search which
+// has notifications turned on.
+func (m SavedSearchesModel) FetchSavedSearchNotifications(interval int64) ([]*SavedSearchNotification, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := m.DB.FetchSavedSearchNotifications(ctx, interval)
+	if err != nil {
+		return nil, err
+	}
+	notifications := []*SavedSearchNotification{}
+	for _, row := range rows {
+		notifications = append(notifications, &SavedSearchNotification{
+			Saved_Search_ID:   row.SavedSearchID,
+			Saved_Search_Name: row.SavedSearchName,
+			User_ID:           row.UserID,
+			Post_Count:        int(row.PostCount),
+		})
+	}
+	return notifications, nil
+}
+
+// InsertSavedSearchNotification() saves a saved search notification and returns its ID
+func (m SavedSearchesModel) InsertSavedSearchNotification(notification *SavedSearchNotification) (int32, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	notificationID, err := m.DB.InsertSavedSearchNotification(ctx, database.InsertSavedSearchNotificationParams{
+		SavedSearchID: notification.Saved_Search_ID,
+		UserID:        notification.User_ID,
+		PostCount:     int32(notification.Post_Count),
+		CreatedAt:     time.Now().UTC(),
+	})
+	if err != nil {
+		return 0, err
+	}
+	return notificationID, nil
+}
+
+// timeToNullTime() converts an optional time into a sql.NullTime
+func timeToNullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: *t, Valid: true}
+}
+
+// nullTimeToTime() converts a sql.NullTime into an optional time
+func nullTimeToTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// zeroTimeToNil() treats a zero time as "not set"
+func zeroTimeToNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,119 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/blue-davinci/aggregate/internal/database"
+	"github.com/blue-davinci/aggregate/internal/screening"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrCommentScreeningNotFound = errors.New("comment screening not found")
+)
+
+// CommentScreening records why the screening pipeline held a comment back
+type CommentScreening struct {
+	Comment_ID       uuid.UUID `json:"comment_id"`
+	Reasons          []string  `json:"reasons"`
+	Classifier_Score float64   `json:"classifier_score"`
+	Created_At       time.Time `json:"created_at"`
+}
+
+// PendingComment is a comment awaiting review in the admin pending list
+type PendingComment struct {
+	Comment_ID       uuid.UUID `json:"comment_id"`
+	Post_ID          uuid.UUID `json:"post_id"`
+	User_ID          int64     `json:"user_id"`
+	User_Name        string    `json:"user_name"`
+	Comment_Text     string    `json:"comment_text"`
+	Created_At       time.Time `json:"created_at"`
+	Reasons          []string  `json:"reasons"`
+	Classifier_Score float64   `json:"classifier_score"`
+}
+
+// RecentSubmissions() returns the comments a user made since the given time, newest first.
+// It lets the comments model serve as the screening pipeline's history.
+func (m CommentsModel) RecentSubmissions(ctx context.Context, userID int64, since time.Time) ([]screening.Submission, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	rows, err := m.DB.GetRecentUserComments(ctx, database.GetRecentUserCommentsParams{
+		UserID:    userID,
+		CreatedAt: since,
+	})
+	if err != nil {
+		return nil, err
+	}
+	submissions := []screening.Submission{}
+	for _, row := range rows {
+		submissions = append(submissions, screening.Submission{ID: row.ID.String(), Text: row.CommentText, CreatedAt: row.CreatedAt})
+	}
+	return submissions, nil
+}
+
+// RecordCommentScreening() saves the reasons a comment was flagged by screening, replacing
+// those of an earlier screening when an edit is flagged again.
+func (m ModerationModel) RecordCommentScreening(commentID uuid.UUID, result *screening.Result) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return m.DB.InsertCommentScreening(ctx, database.InsertCommentScreeningParams{
+		CommentID:       commentID,
+		Reasons:         result.Reasons,
+		ClassifierScore: result.Score,
+	})
+}
+
+// GetCommentScreening() returns why a comment was flagged, if it ever was
+func (m ModerationModel) GetCommentScreening(commentID uuid.UUID) (*CommentScreening, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	row, err := m.DB.GetCommentScreening(ctx, commentID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrCommentScreeningNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &CommentScreening{
+		Comment_ID:       row.CommentID,
+		Reasons:          row.Reasons,
+		Classifier_Score: row.ClassifierScore,
+		Created_At:       row.CreatedAt,
+	}, nil
+}
+
+// GetPendingComments() returns the comments held back by screening, oldest first so that
+// the ones waiting longest are reviewed first.
+func (m ModerationModel) GetPendingComments(filters Filters) ([]*PendingComment, Metadata, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := m.DB.GetPendingComments(ctx, database.GetPendingCommentsParams{
+		Limit:  int32(filters.limit()),
+		Offset: int32(filters.offset()),
+	})
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	pending := []*PendingComment{}
+	totalRecords := 0
+	for _, row := range rows {
+		totalRecords = int(row.TotalRecords)
+		pending = append(pending, &PendingComment{
+			Comment_ID:       row.ID,
+			Post_ID:          row.PostID,
+			User_ID:          row.UserID,
+			User_Name:        row.UserName,
+			Comment_Text:     row.CommentText,
+			Created_At:       row.CreatedAt,
+			Reasons:          row.Reasons,
+			Classifier_Score: row.ClassifierScore,
+		})
+	}
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return pending, metadata, nil
+}
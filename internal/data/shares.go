@@ -0,0 +1,311 @@
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/blue-davinci/aggregate/internal/database"
+	"github.com/blue-davinci/aggregate/internal/validator"
+	"github.com/google/uuid"
+)
+
+// A post is either shared directly with other users, who find it in their inbox, or
+// through a public link anyone can open for a preview of the post.
+const (
+	ShareTypeDirect = "direct"
+	ShareTypeLink   = "link"
+)
+
+const (
+	// MaxShareRecipients caps how many users a single direct share can notify
+	MaxShareRecipients = 20
+	MaxShareNoteLength = 500
+)
+
+var (
+	ErrPostShareNotFound = errors.New("post share not found")
+	ErrNoShareRecipients = errors.New("none of the recipients could be found")
+)
+
+type SharesModel struct {
+	DB *database.Queries
+}
+
+// PostShare is a post shared by a user. Recipients are the handles of the users a
+// direct share reached. Token and URL are only set on link shares and, as we only hold
+// the token's hash, only when the share is created.
+type PostShare struct {
+	ID         uuid.UUID `json:"id"`
+	Post_ID    uuid.UUID `json:"post_id"`
+	User_ID    int64     `json:"-"`
+	Share_Type string    `json:"share_type"`
+	Note       string    `json:"note"`
+	Recipients []string  `json:"recipients,omitempty"`
+	Token      string    `json:"token,omitempty"`
+	URL        string    `json:"url,omitempty"`
+	Created_At time.Time `json:"created_at"`
+}
+
+// SharedPostPreview is what anonymous visitors see when they open a share link. Like
+// the sample posts it carries just enough of the post to wet their appetite, the link
+// to the original article is left out.
+type SharedPostPreview struct {
+	Share_ID         uuid.UUID `json:"share_id"`
+	Note             string    `json:"note"`
+	Shared_By        string    `json:"shared_by"`
+	Shared_By_Handle string    `json:"shared_by_handle"`
+	Shared_At        time.Time `json:"shared_at"`
+	Post_ID          uuid.UUID `json:"post_id"`
+	Feed_ID          uuid.UUID `json:"feed_id"`
+	Channel_Title    string    `json:"channel_title"`
+	Title            string    `json:"title"`
+	Description      string    `json:"description"`
+	Image_URL        string    `json:"image_url"`
+	Published_At     time.Time `json:"published_at"`
+	Share_Count      int64     `json:"share_count"`
+	View_Count       int64     `json:"view_count"`
+}
+
+// PostShareLink is one of a user's share links as listed for them to manage
+type PostShareLink struct {
+	ID             uuid.UUID  `json:"id"`
+	Post_ID        uuid.UUID  `json:"post_id"`
+	Post_Title     string     `json:"post_title"`
+	Note           string     `json:"note"`
+	View_Count     int64      `json:"view_count"`
+	Created_At     time.Time  `json:"created_at"`
+	Last_Viewed_At *time.Time `json:"last_viewed_at,omitempty"`
+}
+
+// PostShareCount is how often a post has been shared, revoked links included
+type PostShareCount struct {
+	Post_ID       uuid.UUID `json:"post_id"`
+	Direct_Shares int64     `json:"direct_shares"`
+	Link_Shares   int64     `json:"link_shares"`
+	Total_Shares  int64     `json:"total_shares"`
+}
+
+// ShareRecipient is a user a post was shared with
+type ShareRecipient struct {
+	User_ID int64
+	Handle  string
+}
+
+// NormalizeShareRecipients() lower cases the handles a post is being shared with, drops
+// any leading @ and removes duplicates, keeping the order they were given in.
+func NormalizeShareRecipients(handles []string) []string {
+	normalized := []string{}
+	seen := make(map[string]bool)
+	for _, handle := range handles {
+		handle = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(handle), "@"))
+		if seen[handle] {
+			continue
+		}
+		seen[handle] = true
+		normalized = append(normalized, handle)
+	}
+	return normalized
+}
+
+func ValidatePostShare(v *validator.Validator, share *PostShare) {
+	v.Check(validator.PermittedValue(share.Share_Type, ShareTypeDirect, ShareTypeLink), "share_type", "must be one of direct or link")
+	v.Check(len(share.Note) <= MaxShareNoteLength, "note", "must not be more than 500 bytes long")
+	if share.Share_Type != ShareTypeDirect {
+		return
+	}
+	v.Check(len(share.Recipients) > 0, "recipients", "must be provided for a direct share")
+	v.Check(len(share.Recipients) <= MaxShareRecipients, "recipients", "must not contain more than 20 users")
+	for _, handle := range share.Recipients {
+		if !validator.Matches(handle, validator.HandleRX) {
+			v.AddError("recipients", "must only contain valid handles")
+			break
+		}
+	}
+}
+
+// GetShareRecipients() resolves the handles a post is being shared with to activated
+// users. The sharer is left out and so are handles that don't belong to anyone.
+func (m SharesModel) GetShareRecipients(userID int64, handles []string) ([]*ShareRecipient, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := m.DB.GetShareRecipients(ctx, database.GetShareRecipientsParams{
+		Column1: handles,
+		ID:      userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	recipients := []*ShareRecipient{}
+	for _, row := range rows {
+		recipients = append(recipients, &ShareRecipient{User_ID: row.ID, Handle: row.Handle})
+	}
+	return recipients, nil
+}
+
+// SharePost() saves a share and counts it against the post. Direct shares are saved
+// with the resolved recipients, link shares get a new secret token generated just like
+// our API keys which is set on the passed share.
+func (m SharesModel) SharePost(share *PostShare, recipients []*ShareRecipient) error {
+	var tokenHash []byte
+	if share.Share_Type == ShareTypeLink {
+		token, err := generateAPI(share.User_ID, 0, "post-share", APIKeyLength)
+		if err != nil {
+			return err
+		}
+		share.Token = token.Plaintext
+		tokenHash = token.Hash
+	} else if len(recipients) == 0 {
+		return ErrNoShareRecipients
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	row, err := m.DB.CreatePostShare(ctx, database.CreatePostShareParams{
+		ID:        share.Post_ID,
+		UserID:    share.User_ID,
+		ShareType: share.Share_Type,
+		Note:      share.Note,
+		TokenHash: tokenHash,
+	})
+	if err != nil {
+		switch {
+		// the share is only inserted when the post exists
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrPostNotFound
+		default:
+			return err
+		}
+	}
+	share.ID = row.ID
+	share.Created_At = row.CreatedAt
+	counts := database.IncrementPostShareCountParams{PostID: share.Post_ID}
+	if share.Share_Type == ShareTypeLink {
+		counts.LinkShares = 1
+	} else {
+		recipientIDs := []int64{}
+		share.Recipients = []string{}
+		for _, recipient := range recipients {
+			recipientIDs = append(recipientIDs, recipient.User_ID)
+			share.Recipients = append(share.Recipients, recipient.Handle)
+		}
+		err = m.DB.InsertPostShareRecipients(ctx, database.InsertPostShareRecipientsParams{
+			ShareID: share.ID,
+			Column2: recipientIDs,
+		})
+		if err != nil {
+			return err
+		}
+		counts.DirectShares = 1
+	}
+	return m.DB.IncrementPostShareCount(ctx, counts)
+}
+
+// GetSharedPostByToken() looks up the post behind a share link using the plaintext token
+// from the URL and counts the view. Revoked or unknown tokens return an
+// ErrPostShareNotFound.
+func (m SharesModel) GetSharedPostByToken(token string) (*SharedPostPreview, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tokenHash := sha256.Sum256([]byte(token))
+	row, err := m.DB.GetSharedPostByToken(ctx, tokenHash[:])
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrPostShareNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &SharedPostPreview{
+		Share_ID:         row.ID,
+		Note:             row.Note,
+		Shared_By:        row.SharedByName,
+		Shared_By_Handle: row.SharedByHandle,
+		Shared_At:        row.CreatedAt,
+		Post_ID:          row.PostID,
+		Feed_ID:          row.FeedID,
+		Channel_Title:    row.Channeltitle,
+		Title:            row.Itemtitle,
+		Description:      row.Itemdescription.String,
+		Image_URL:        row.ImgUrl,
+		Published_At:     row.ItempublishedAt,
+		Share_Count:      row.ShareCount,
+		View_Count:       row.ViewCount,
+	}, nil
+}
+
+// GetPostShareCount() returns how often a post has been shared, posts that were never
+// shared have a count of zero.
+func (m SharesModel) GetPostShareCount(postID uuid.UUID) (*PostShareCount, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	count, err := m.DB.GetPostShareCount(ctx, postID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return &PostShareCount{Post_ID: postID}, nil
+		default:
+			return nil, err
+		}
+	}
+	return &PostShareCount{
+		Post_ID:       count.PostID,
+		Direct_Shares: count.DirectShares,
+		Link_Shares:   count.LinkShares,
+		Total_Shares:  count.DirectShares + count.LinkShares,
+	}, nil
+}
+
+// GetPostShareLinksForUser() returns the share links a user created, newest first.
+// Tokens are not returned as we only hold their hashes.
+func (m SharesModel) GetPostShareLinksForUser(userID int64, filters Filters) ([]*PostShareLink, Metadata, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := m.DB.GetPostShareLinksForUser(ctx, database.GetPostShareLinksForUserParams{
+		UserID: userID,
+		Limit:  int32(filters.limit()),
+		Offset: int32(filters.offset()),
+	})
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	links := []*PostShareLink{}
+	totalRecords := 0
+	for _, row := range rows {
+		totalRecords = int(row.TotalRecords)
+		links = append(links, &PostShareLink{
+			ID:             row.ID,
+			Post_ID:        row.PostID,
+			Post_Title:     row.Itemtitle,
+			Note:           row.Note,
+			View_Count:     row.ViewCount,
+			Created_At:     row.CreatedAt,
+			Last_Viewed_At: nullTimeToTime(row.LastViewedAt),
+		})
+	}
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return links, metadata, nil
+}
+
+// DeletePostShareLink() revokes one of a user's share links, its token stops working
+// immediately.
+func (m SharesModel) DeletePostShareLink(shareID uuid.UUID, userID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := m.DB.DeletePostShareLink(ctx, database.DeletePostShareLinkParams{
+		ID:     shareID,
+		UserID: userID,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrPostShareNotFound
+		default:
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package data
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/blue-davinci/aggregate/internal/validator"
+)
+
+func TestNormalizeShareRecipients(t *testing.T) {
+	tests := []struct {
+		name    string
+		handles []string
+		want    []string
+	}{
+		{name: "Plain", handles: []string{"jane", "bob_1"}, want: []string{"jane", "bob_1"}},
+		{name: "Leading At And Spaces", handles: []string{" @Jane ", "@bob_1"}, want: []string{"jane", "bob_1"}},
+		{name: "Duplicates", handles: []string{"jane", "@JANE", "bob_1", "jane"}, want: []string{"jane", "bob_1"}},
+		{name: "Empty", handles: nil, want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeShareRecipients(tt.handles); !slices.Equal(got, tt.want) {
+				t.Errorf("Got:%v But Wanted:%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidatePostShare(t *testing.T) {
+	tooMany := []string{}
+	for i := 0; i <= MaxShareRecipients; i++ {
+		tooMany = append(tooMany, fmt.Sprintf("user_%d", i))
+	}
+	tests := []struct {
+		name    string
+		share   PostShare
+		wantErr string
+	}{
+		{name: "Direct", share: PostShare{Share_Type: ShareTypeDirect, Recipients: []string{"jane"}, Note: "thought you'd like this"}},
+		{name: "Link", share: PostShare{Share_Type: ShareTypeLink}},
+		{name: "Link Ignores Recipients", share: PostShare{Share_Type: ShareTypeLink, Recipients: []string{"not a handle"}}},
+		{name: "Unknown Type", share: PostShare{Share_Type: "email"}, wantErr: "share_type"},
+		{name: "Direct Without Recipients", share: PostShare{Share_Type: ShareTypeDirect}, wantErr: "recipients"},
+		{name: "Too Many Recipients", share: PostShare{Share_Type: ShareTypeDirect, Recipients: tooMany}, wantErr: "recipients"},
+		{name: "Invalid Handle", share: PostShare{Share_Type: ShareTypeDirect, Recipients: []string{"jane", "j"}}, wantErr: "recipients"},
+		{name: "Long Note", share: PostShare{Share_Type: ShareTypeLink, Note: strings.Repeat("a", MaxShareNoteLength+1)}, wantErr: "note"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidatePostShare(v, &tt.share)
+			if tt.wantErr == "" {
+				if !v.Valid() {
+					t.Errorf("Got:%v But Wanted no errors", v.Errors)
+				}
+				return
+			}
+			if _, ok := v.Errors[tt.wantErr]; !ok {
+				t.Errorf("Got:%v But Wanted an error for:%s", v.Errors, tt.wantErr)
+			}
+		})
+	}
+}
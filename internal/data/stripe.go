@@ -0,0 +1,494 @@
+package data
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StripeSignatureHeader carries the timestamp and HMAC-SHA256 signatures of a stripe
+// webhook, eg: t=1492774577,v1=5257a869e7...
+const StripeSignatureHeader = "Stripe-Signature"
+
+// stripeSignatureTolerance is how old a signed webhook may be before we refuse it as a
+// possible replay
+const stripeSignatureTolerance = 5 * time.Minute
+
+// stripeRenewalMetadata marks the payment intents we create to renew a subscription
+const stripeRenewalMetadata = "aggregate_renewal"
+
+// StripeConfig holds the keys of a stripe account. Currency is used for checkouts that
+// don't name one.
+type StripeConfig struct {
+	SecretKey     string
+	WebhookSecret string
+	APIURL        string
+	Currency      string
+}
+
+// StripeProvider takes payments through stripe. Checkouts are stripe checkout sessions
+// which save the card for off-session payment intents when the subscription renews. The
+// card's payment method ID serves as the authorization code.
+type StripeProvider struct {
+	config StripeConfig
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewStripeProvider() creates a StripeProvider whose requests give up after timeout
+func NewStripeProvider(config StripeConfig, timeout time.Duration) *StripeProvider {
+	return &StripeProvider{
+		config: config,
+		client: &http.Client{Timeout: timeout},
+		now:    time.Now,
+	}
+}
+
+type stripeError struct {
+	StatusCode    int
+	Type          string          `json:"type"`
+	Code          string          `json:"code"`
+	Message       string          `json:"message"`
+	PaymentIntent json.RawMessage `json:"payment_intent"`
+}
+
+func (e *stripeError) Error() string {
+	return fmt.Sprintf("stripe responded with status %d: %s", e.StatusCode, e.Message)
+}
+
+func (e *stripeError) Unwrap() error {
+	if providerRejected(e.StatusCode) {
+		return ErrPaymentProviderRejected
+	}
+	return nil
+}
+
+type stripeCard struct {
+	Brand    string `json:"brand"`
+	Last4    string `json:"last4"`
+	ExpMonth int    `json:"exp_month"`
+	ExpYear  int    `json:"exp_year"`
+	Country  string `json:"country"`
+}
+
+type stripePaymentMethod struct {
+	ID       string     `json:"id"`
+	Type     string     `json:"type"`
+	Customer string     `json:"customer"`
+	Card     stripeCard `json:"card"`
+}
+
+// stripePaymentIntent is a payment intent. Its payment method is a plain ID unless it
+// was expanded, paymentMethod() reads either.
+type stripePaymentIntent struct {
+	ID               string            `json:"id"`
+	Status           string            `json:"status"`
+	Amount           int64             `json:"amount"`
+	Currency         string            `json:"currency"`
+	Created          int64             `json:"created"`
+	ReceiptEmail     string            `json:"receipt_email"`
+	PaymentMethod    json.RawMessage   `json:"payment_method"`
+	Metadata         map[string]string `json:"metadata"`
+	LastPaymentError *struct {
+		Code          string               `json:"code"`
+		Message       string               `json:"message"`
+		PaymentMethod *stripePaymentMethod `json:"payment_method"`
+	} `json:"last_payment_error"`
+}
+
+// stripeCheckoutSession is a checkout session, its payment intent is only an object
+// when it was expanded.
+type stripeCheckoutSession struct {
+	ID              string          `json:"id"`
+	URL             string          `json:"url"`
+	Status          string          `json:"status"`
+	PaymentStatus   string          `json:"payment_status"`
+	AmountTotal     int64           `json:"amount_total"`
+	Currency        string          `json:"currency"`
+	PaymentIntent   json.RawMessage `json:"payment_intent"`
+	CustomerDetails struct {
+		Email string `json:"email"`
+	} `json:"customer_details"`
+}
+
+func (p *StripeProvider) Name() string {
+	return PaymentProviderStripe
+}
+
+// InitializeCheckout() creates a checkout session for a one off payment whose card is
+// saved for later off-session charges. Stripe fills in the session's ID as the reference
+// on the way back to the callback URL.
+func (p *StripeProvider) InitializeCheckout(ctx context.Context, checkout *Checkout) (*CheckoutSession, error) {
+	description := checkout.Description
+	if description == "" {
+		description = "Subscription"
+	}
+	separator := "?"
+	if strings.Contains(checkout.CallbackURL, "?") {
+		separator = "&"
+	}
+	form := url.Values{
+		"mode":                                   {"payment"},
+		"customer_email":                         {checkout.Email},
+		"customer_creation":                      {"always"},
+		"success_url":                            {checkout.CallbackURL + separator + "reference={CHECKOUT_SESSION_ID}"},
+		"cancel_url":                             {checkout.CallbackURL},
+		"line_items[0][quantity]":                {"1"},
+		"line_items[0][price_data][currency]":    {p.currency(checkout.Currency)},
+		"line_items[0][price_data][unit_amount]": {strconv.FormatInt(checkout.Amount, 10)},
+		"line_items[0][price_data][product_data][name]": {description},
+		"payment_intent_data[setup_future_usage]":       {"off_session"},
+	}
+	var session stripeCheckoutSession
+	err := p.do(ctx, http.MethodPost, "/v1/checkout/sessions", form, &session)
+	if err != nil {
+		return nil, err
+	}
+	return &CheckoutSession{
+		Provider:          PaymentProviderStripe,
+		Authorization_URL: session.URL,
+		Reference:         session.ID,
+	}, nil
+}
+
+// VerifyTransaction() looks up a checkout session along with its payment intent and the
+// card it was paid with.
+func (p *StripeProvider) VerifyTransaction(ctx context.Context, reference string) (*ProviderTransaction, error) {
+	var session stripeCheckoutSession
+	path := "/v1/checkout/sessions/" + url.PathEscape(reference) + "?expand[]=payment_intent.payment_method"
+	err := p.do(ctx, http.MethodGet, path, nil, &session)
+	if err != nil {
+		return nil, err
+	}
+	transaction := &ProviderTransaction{
+		Provider:       PaymentProviderStripe,
+		Amount:         session.AmountTotal,
+		Currency:       strings.ToUpper(session.Currency),
+		Customer_Email: session.CustomerDetails.Email,
+	}
+	var paymentIntent stripePaymentIntent
+	if stripeExpanded(session.PaymentIntent, &paymentIntent) {
+		transaction = stripeTransaction(&paymentIntent)
+		transaction.Customer_Email = session.CustomerDetails.Email
+	}
+	transaction.Reference = session.ID
+	transaction.Gateway_Response = session.PaymentStatus
+	switch {
+	case session.PaymentStatus == "paid":
+		transaction.Status = TransactionStatusSuccess
+	case session.Status == "expired":
+		transaction.Status = TransactionStatusFailed
+	default:
+		transaction.Status = TransactionStatusPending
+	}
+	return transaction, nil
+}
+
+// ChargeAuthorization() charges a saved card off-session. Stripe needs the customer the
+// card is attached to, so the payment method is looked up first. A declined card is a
+// failed transaction and not an error.
+func (p *StripeProvider) ChargeAuthorization(ctx context.Context, charge *AuthorizationCharge) (*ProviderTransaction, error) {
+	var paymentMethod stripePaymentMethod
+	err := p.do(ctx, http.MethodGet, "/v1/payment_methods/"+url.PathEscape(charge.Authorization_Code), nil, &paymentMethod)
+	if err != nil {
+		return nil, err
+	}
+	if paymentMethod.Customer == "" {
+		return nil, fmt.Errorf("stripe payment method %s is not attached to a customer", paymentMethod.ID)
+	}
+	form := url.Values{
+		"amount":         {strconv.FormatInt(charge.Amount, 10)},
+		"currency":       {p.currency(charge.Currency)},
+		"customer":       {paymentMethod.Customer},
+		"payment_method": {paymentMethod.ID},
+		"off_session":    {"true"},
+		"confirm":        {"true"},
+		"receipt_email":  {charge.Email},
+		"metadata[" + stripeRenewalMetadata + "]": {"true"},
+		"expand[]": {"payment_method"},
+	}
+	var paymentIntent stripePaymentIntent
+	err = p.do(ctx, http.MethodPost, "/v1/payment_intents", form, &paymentIntent)
+	if err != nil {
+		var stripeErr *stripeError
+		if errors.As(err, &stripeErr) && len(stripeErr.PaymentIntent) > 0 {
+			if json.Unmarshal(stripeErr.PaymentIntent, &paymentIntent) == nil {
+				transaction := stripeTransaction(&paymentIntent)
+				transaction.Status = TransactionStatusFailed
+				transaction.Gateway_Response = stripeErr.Code
+				transaction.Message = stripeErr.Message
+				transaction.Authorization = stripeAuthorization(&paymentMethod)
+				return transaction, nil
+			}
+		}
+		return nil, err
+	}
+	return stripeTransaction(&paymentIntent), nil
+}
+
+func (p *StripeProvider) Refund(ctx context.Context, refund *RefundRequest) (*ProviderRefund, error) {
+	form := url.Values{"payment_intent": {refund.Payment_Reference}}
+	if refund.Amount > 0 {
+		form.Set("amount", strconv.FormatInt(refund.Amount, 10))
+	}
+	var response struct {
+		ID       string `json:"id"`
+		Amount   int64  `json:"amount"`
+		Currency string `json:"currency"`
+		Status   string `json:"status"`
+	}
+	err := p.do(ctx, http.MethodPost, "/v1/refunds", form, &response)
+	if err != nil {
+		return nil, err
+	}
+	return &ProviderRefund{
+		Provider: PaymentProviderStripe,
+		ID:       response.ID,
+		Amount:   response.Amount,
+		Currency: strings.ToUpper(response.Currency),
+		Status:   response.Status,
+	}, nil
+}
+
+// ParseWebhook() checks the Stripe-Signature against the webhook signing secret and
+// translates the events we act on. Failed renewals are left out, the renewal that made
+// the charge already recorded them.
+func (p *StripeProvider) ParseWebhook(payload []byte, header http.Header) (*WebhookEvent, error) {
+	err := VerifyStripeSignature(payload, header.Get(StripeSignatureHeader), p.config.WebhookSecret, p.now())
+	if err != nil {
+		return nil, err
+	}
+	var event struct {
+		ID   string `json:"id"`
+		Type string `json:"type"`
+		Data struct {
+			Object json.RawMessage `json:"object"`
+		} `json:"data"`
+	}
+	err = json.Unmarshal(payload, &event)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWebhookEvent, err)
+	}
+	if event.ID == "" || event.Type == "" || len(event.Data.Object) == 0 {
+		return nil, ErrInvalidWebhookEvent
+	}
+	webhookEvent := &WebhookEvent{
+		ID:            event.ID,
+		Provider:      PaymentProviderStripe,
+		Provider_Type: event.Type,
+	}
+	switch event.Type {
+	case "checkout.session.completed", "checkout.session.async_payment_succeeded":
+		var session stripeCheckoutSession
+		if err := json.Unmarshal(event.Data.Object, &session); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidWebhookEvent, err)
+		}
+		if session.PaymentStatus == "paid" {
+			webhookEvent.Type = WebhookPaymentSucceeded
+		}
+		webhookEvent.Reference = session.ID
+		webhookEvent.Amount = session.AmountTotal
+		webhookEvent.Currency = strings.ToUpper(session.Currency)
+	case "payment_intent.payment_failed":
+		var paymentIntent stripePaymentIntent
+		if err := json.Unmarshal(event.Data.Object, &paymentIntent); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidWebhookEvent, err)
+		}
+		if paymentIntent.Metadata[stripeRenewalMetadata] == "true" {
+			break
+		}
+		transaction := stripeTransaction(&paymentIntent)
+		webhookEvent.Type = WebhookPaymentFailed
+		webhookEvent.Reference = paymentIntent.ID
+		webhookEvent.Authorization_Code = transaction.Authorization.AuthorizationCode
+		webhookEvent.Amount = paymentIntent.Amount
+		webhookEvent.Currency = transaction.Currency
+		webhookEvent.Message = transaction.Message
+	case "payment_method.detached":
+		var paymentMethod stripePaymentMethod
+		if err := json.Unmarshal(event.Data.Object, &paymentMethod); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidWebhookEvent, err)
+		}
+		webhookEvent.Type = WebhookAuthorizationDisabled
+		webhookEvent.Authorization_Code = paymentMethod.ID
+	}
+	return webhookEvent, nil
+}
+
+// SignStripePayload() returns the Stripe-Signature stripe would send along with a webhook
+// body at the given time.
+func SignStripePayload(payload []byte, secret string, timestamp time.Time) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+	return "t=" + t + ",v1=" + stripeSignature(payload, secret, t)
+}
+
+// VerifyStripeSignature() checks a Stripe-Signature header. One of its v1 signatures has
+// to match and its timestamp has to be within our tolerance of now.
+func VerifyStripeSignature(payload []byte, header, secret string, now time.Time) error {
+	if secret == "" || header == "" {
+		return ErrInvalidWebhookSignature
+	}
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidWebhookSignature
+	}
+	age := now.Sub(time.Unix(seconds, 0))
+	if age > stripeSignatureTolerance || age < -stripeSignatureTolerance {
+		return ErrInvalidWebhookSignature
+	}
+	expected := stripeSignature(payload, secret, timestamp)
+	for _, signature := range signatures {
+		if hmac.Equal([]byte(expected), []byte(signature)) {
+			return nil
+		}
+	}
+	return ErrInvalidWebhookSignature
+}
+
+func stripeSignature(payload []byte, secret, timestamp string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// currency() returns the lower cased currency stripe expects, falling back to ours
+func (p *StripeProvider) currency(currency string) string {
+	if currency == "" {
+		currency = p.config.Currency
+	}
+	return strings.ToLower(currency)
+}
+
+// do() sends a form encoded request to stripe's API and decodes its reply into dst.
+// Stripe answers refused requests with an error object, which is returned as a
+// *stripeError.
+func (p *StripeProvider) do(ctx context.Context, method, path string, form url.Values, dst any) error {
+	var reader io.Reader
+	if form != nil {
+		reader = bytes.NewBufferString(form.Encode())
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(p.config.APIURL, "/")+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.config.SecretKey)
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	res, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	raw, err := io.ReadAll(io.LimitReader(res.Body, 1_048_576))
+	if err != nil {
+		return err
+	}
+	if res.StatusCode >= http.StatusBadRequest {
+		var reply struct {
+			Error stripeError `json:"error"`
+		}
+		if err := json.Unmarshal(raw, &reply); err != nil {
+			return fmt.Errorf("stripe responded with status %d", res.StatusCode)
+		}
+		reply.Error.StatusCode = res.StatusCode
+		return &reply.Error
+	}
+	err = json.Unmarshal(raw, dst)
+	if err != nil {
+		return fmt.Errorf("decoding stripe response: %w", err)
+	}
+	return nil
+}
+
+// stripeTransaction() translates a payment intent. Renewals are charged off-session so
+// an intent that needs the customer to authenticate is as good as failed.
+func stripeTransaction(paymentIntent *stripePaymentIntent) *ProviderTransaction {
+	transaction := &ProviderTransaction{
+		Provider:          PaymentProviderStripe,
+		Reference:         paymentIntent.ID,
+		Payment_Reference: paymentIntent.ID,
+		Gateway_Response:  paymentIntent.Status,
+		Message:           paymentIntent.Status,
+		Amount:            paymentIntent.Amount,
+		Currency:          strings.ToUpper(paymentIntent.Currency),
+		Customer_Email:    paymentIntent.ReceiptEmail,
+	}
+	if paymentIntent.Created != 0 {
+		transaction.Paid_At = time.Unix(paymentIntent.Created, 0).UTC().Format(time.RFC3339)
+	}
+	var paymentMethod stripePaymentMethod
+	if stripeExpanded(paymentIntent.PaymentMethod, &paymentMethod) {
+		transaction.Authorization = stripeAuthorization(&paymentMethod)
+		transaction.Channel = paymentMethod.Type
+	} else if json.Unmarshal(paymentIntent.PaymentMethod, &paymentMethod.ID) == nil {
+		transaction.Authorization.AuthorizationCode = paymentMethod.ID
+	}
+	if paymentIntent.LastPaymentError != nil {
+		transaction.Gateway_Response = paymentIntent.LastPaymentError.Code
+		transaction.Message = paymentIntent.LastPaymentError.Message
+		if transaction.Authorization.AuthorizationCode == "" && paymentIntent.LastPaymentError.PaymentMethod != nil {
+			transaction.Authorization = stripeAuthorization(paymentIntent.LastPaymentError.PaymentMethod)
+		}
+	}
+	switch paymentIntent.Status {
+	case "succeeded":
+		transaction.Status = TransactionStatusSuccess
+	case "processing":
+		transaction.Status = TransactionStatusPending
+	default:
+		transaction.Status = TransactionStatusFailed
+	}
+	return transaction
+}
+
+// stripeExpanded() decodes a field stripe only sends as an object when it was expanded.
+// It reports false for IDs and nulls, leaving dst as it was.
+func stripeExpanded(raw json.RawMessage, dst any) bool {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || raw[0] != '{' {
+		return false
+	}
+	return json.Unmarshal(raw, dst) == nil
+}
+
+// stripeAuthorization() fills in an Authorization from a card payment method
+func stripeAuthorization(paymentMethod *stripePaymentMethod) Authorization {
+	authorization := Authorization{
+		AuthorizationCode: paymentMethod.ID,
+		Channel:           paymentMethod.Type,
+		Last4:             paymentMethod.Card.Last4,
+		CardType:          paymentMethod.Card.Brand,
+		Brand:             paymentMethod.Card.Brand,
+		CountryCode:       paymentMethod.Card.Country,
+		Reusable:          true,
+	}
+	if paymentMethod.Card.ExpMonth != 0 {
+		authorization.ExpMonth = fmt.Sprintf("%02d", paymentMethod.Card.ExpMonth)
+		authorization.ExpYear = strconv.Itoa(paymentMethod.Card.ExpYear)
+	}
+	return authorization
+}
@@ -0,0 +1,390 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+const (
+	testStripeSecret        = "sk_test_fake_stripe"
+	testStripeWebhookSecret = "whsec_test_fixture"
+)
+
+// newFakeStripe() starts a local stand-in for stripe's API. Every request must carry our
+// secret key, form bodies and query strings are handed to the route's handler parsed.
+func newFakeStripe(t *testing.T, routes map[string]func(form url.Values) (int, string)) *StripeProvider {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer "+testStripeSecret {
+			t.Errorf("Got Authorization:%q But Wanted the secret key", got)
+		}
+		route, ok := routes[r.Method+" "+r.URL.Path]
+		if !ok {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("parsing request form: %v", err)
+		}
+		status, reply := route(r.Form)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		fmt.Fprint(w, reply)
+	}))
+	t.Cleanup(server.Close)
+	return NewStripeProvider(StripeConfig{
+		SecretKey:     testStripeSecret,
+		WebhookSecret: testStripeWebhookSecret,
+		APIURL:        server.URL,
+		Currency:      "USD",
+	}, 5*time.Second)
+}
+
+func TestStripeProviderInitializeCheckout(t *testing.T) {
+	tests := []struct {
+		name         string
+		currency     string
+		callback     string
+		wantCurrency string
+		wantSuccess  string
+	}{
+		{
+			name:         "Default Currency",
+			callback:     "https://aggregate.test/verify",
+			wantCurrency: "usd",
+			wantSuccess:  "https://aggregate.test/verify?reference={CHECKOUT_SESSION_ID}",
+		},
+		{
+			name:         "Callback With Query",
+			currency:     "EUR",
+			callback:     "https://aggregate.test/verify?plan_id=2",
+			wantCurrency: "eur",
+			wantSuccess:  "https://aggregate.test/verify?plan_id=2&reference={CHECKOUT_SESSION_ID}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := newFakeStripe(t, map[string]func(url.Values) (int, string){
+				"POST /v1/checkout/sessions": func(form url.Values) (int, string) {
+					want := map[string]string{
+						"mode":                                   "payment",
+						"customer_email":                         "jane@example.com",
+						"success_url":                            tt.wantSuccess,
+						"cancel_url":                             tt.callback,
+						"line_items[0][price_data][currency]":    tt.wantCurrency,
+						"line_items[0][price_data][unit_amount]": "1500",
+						"line_items[0][price_data][product_data][name]": "Pro",
+						"payment_intent_data[setup_future_usage]":       "off_session",
+					}
+					for key, value := range want {
+						if form.Get(key) != value {
+							t.Errorf("Got %s:%q But Wanted:%q", key, form.Get(key), value)
+						}
+					}
+					return http.StatusOK, `{"id": "cs_test_a1b2c3", "object": "checkout.session", "url": "https://checkout.stripe.com/c/pay/cs_test_a1b2c3"}`
+				},
+			})
+			session, err := provider.InitializeCheckout(context.Background(), &Checkout{
+				Email:       "jane@example.com",
+				Amount:      1500,
+				Currency:    tt.currency,
+				Description: "Pro",
+				CallbackURL: tt.callback,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := CheckoutSession{
+				Provider:          PaymentProviderStripe,
+				Authorization_URL: "https://checkout.stripe.com/c/pay/cs_test_a1b2c3",
+				Reference:         "cs_test_a1b2c3",
+			}
+			if *session != want {
+				t.Errorf("Got:%+v But Wanted:%+v", *session, want)
+			}
+		})
+	}
+}
+
+func TestStripeProviderVerifyTransaction(t *testing.T) {
+	tests := []struct {
+		name       string
+		reply      string
+		wantStatus string
+	}{
+		{
+			name: "Paid",
+			reply: `{"id": "cs_test_a1b2c3", "status": "complete", "payment_status": "paid", "amount_total": 1500, "currency": "usd",
+				"customer_details": {"email": "jane@example.com"},
+				"payment_intent": {"id": "pi_3Pq", "status": "succeeded", "amount": 1500, "currency": "usd", "created": 1724318102,
+					"payment_method": {"id": "pm_1Pq", "type": "card", "customer": "cus_Qh",
+						"card": {"brand": "visa", "last4": "4242", "exp_month": 4, "exp_year": 2030, "country": "US"}}}}`,
+			wantStatus: TransactionStatusSuccess,
+		},
+		{
+			name:       "Open",
+			reply:      `{"id": "cs_test_a1b2c3", "status": "open", "payment_status": "unpaid", "amount_total": 1500, "currency": "usd", "payment_intent": null}`,
+			wantStatus: TransactionStatusPending,
+		},
+		{
+			name:       "Expired",
+			reply:      `{"id": "cs_test_a1b2c3", "status": "expired", "payment_status": "unpaid", "amount_total": 1500, "currency": "usd", "payment_intent": null}`,
+			wantStatus: TransactionStatusFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := newFakeStripe(t, map[string]func(url.Values) (int, string){
+				"GET /v1/checkout/sessions/cs_test_a1b2c3": func(form url.Values) (int, string) {
+					if form.Get("expand[]") != "payment_intent.payment_method" {
+						t.Errorf("Got expand:%q But Wanted the payment intent's payment method", form.Get("expand[]"))
+					}
+					return http.StatusOK, tt.reply
+				},
+			})
+			transaction, err := provider.VerifyTransaction(context.Background(), "cs_test_a1b2c3")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if transaction.Status != tt.wantStatus || transaction.Reference != "cs_test_a1b2c3" || transaction.Currency != "USD" {
+				t.Errorf("Got:%+v But Wanted status %s for the session", transaction, tt.wantStatus)
+			}
+			if tt.wantStatus != TransactionStatusSuccess {
+				return
+			}
+			want := Authorization{AuthorizationCode: "pm_1Pq", Channel: "card", Last4: "4242", ExpMonth: "04", ExpYear: "2030",
+				CardType: "visa", Brand: "visa", CountryCode: "US", Reusable: true}
+			if transaction.Payment_Reference != "pi_3Pq" || transaction.Customer_Email != "jane@example.com" || transaction.Paid_At != "2024-08-22T09:15:02Z" {
+				t.Errorf("Got:%+v But Wanted the payment intent's reference, the customer's email and the payment date", transaction)
+			}
+			if transaction.Authorization != want {
+				t.Errorf("Got:%+v But Wanted:%+v", transaction.Authorization, want)
+			}
+		})
+	}
+}
+
+func TestStripeProviderChargeAuthorization(t *testing.T) {
+	paymentMethod := `{"id": "pm_1Pq", "type": "card", "customer": "cus_Qh", "card": {"brand": "visa", "last4": "4242", "exp_month": 4, "exp_year": 2030}}`
+	tests := []struct {
+		name        string
+		status      int
+		reply       string
+		wantStatus  string
+		wantMessage string
+	}{
+		{
+			name:   "Succeeded",
+			status: http.StatusOK,
+			reply: `{"id": "pi_3Rn", "status": "succeeded", "amount": 1500, "currency": "usd", "created": 1724318102,
+				"metadata": {"aggregate_renewal": "true"}, "payment_method": ` + paymentMethod + `}`,
+			wantStatus:  TransactionStatusSuccess,
+			wantMessage: "succeeded",
+		},
+		{
+			name:   "Declined",
+			status: http.StatusPaymentRequired,
+			reply: `{"error": {"type": "card_error", "code": "card_declined", "message": "Your card was declined.",
+				"payment_intent": {"id": "pi_3Rd", "status": "requires_payment_method", "amount": 1500, "currency": "usd", "payment_method": null}}}`,
+			wantStatus:  TransactionStatusFailed,
+			wantMessage: "Your card was declined.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := newFakeStripe(t, map[string]func(url.Values) (int, string){
+				"GET /v1/payment_methods/pm_1Pq": func(url.Values) (int, string) {
+					return http.StatusOK, paymentMethod
+				},
+				"POST /v1/payment_intents": func(form url.Values) (int, string) {
+					want := map[string]string{
+						"amount":                      "1500",
+						"currency":                    "usd",
+						"customer":                    "cus_Qh",
+						"payment_method":              "pm_1Pq",
+						"off_session":                 "true",
+						"confirm":                     "true",
+						"metadata[aggregate_renewal]": "true",
+					}
+					for key, value := range want {
+						if form.Get(key) != value {
+							t.Errorf("Got %s:%q But Wanted:%q", key, form.Get(key), value)
+						}
+					}
+					return tt.status, tt.reply
+				},
+			})
+			transaction, err := provider.ChargeAuthorization(context.Background(), &AuthorizationCharge{
+				Email:              "jane@example.com",
+				Amount:             1500,
+				Currency:           "USD",
+				Authorization_Code: "pm_1Pq",
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if transaction.Status != tt.wantStatus || transaction.Message != tt.wantMessage {
+				t.Errorf("Got:%+v But Wanted status %s and message %q", transaction, tt.wantStatus, tt.wantMessage)
+			}
+			if transaction.Authorization.AuthorizationCode != "pm_1Pq" || transaction.Authorization.Last4 != "4242" {
+				t.Errorf("Got:%+v But Wanted the charged card", transaction.Authorization)
+			}
+		})
+	}
+	t.Run("API Error", func(t *testing.T) {
+		provider := newFakeStripe(t, map[string]func(url.Values) (int, string){
+			"GET /v1/payment_methods/pm_gone": func(url.Values) (int, string) {
+				return http.StatusNotFound, `{"error": {"type": "invalid_request_error", "code": "resource_missing", "message": "No such PaymentMethod: 'pm_gone'"}}`
+			},
+		})
+		_, err := provider.ChargeAuthorization(context.Background(), &AuthorizationCharge{Amount: 1500, Authorization_Code: "pm_gone"})
+		var stripeErr *stripeError
+		if !errors.As(err, &stripeErr) || stripeErr.StatusCode != http.StatusNotFound || stripeErr.Code != "resource_missing" {
+			t.Errorf("Got:%v But Wanted stripe's not found error", err)
+		}
+	})
+}
+
+func TestStripeProviderRefund(t *testing.T) {
+	tests := []struct {
+		name       string
+		amount     int64
+		wantAmount string
+	}{
+		{name: "Full", amount: 0, wantAmount: ""},
+		{name: "Partial", amount: 500, wantAmount: "500"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := newFakeStripe(t, map[string]func(url.Values) (int, string){
+				"POST /v1/refunds": func(form url.Values) (int, string) {
+					if form.Get("payment_intent") != "pi_3Pq" || form.Get("amount") != tt.wantAmount {
+						t.Errorf("Got form:%v But Wanted the payment intent and amount %q", form, tt.wantAmount)
+					}
+					return http.StatusOK, `{"id": "re_3Pq", "amount": 500, "currency": "usd", "status": "succeeded"}`
+				},
+			})
+			refund, err := provider.Refund(context.Background(), &RefundRequest{Payment_Reference: "pi_3Pq", Amount: tt.amount})
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := ProviderRefund{Provider: PaymentProviderStripe, ID: "re_3Pq", Amount: 500, Currency: "USD", Status: "succeeded"}
+			if *refund != want {
+				t.Errorf("Got:%+v But Wanted:%+v", *refund, want)
+			}
+		})
+	}
+}
+
+func TestVerifyStripeSignature(t *testing.T) {
+	payload := []byte(`{"id": "evt_1", "type": "checkout.session.completed"}`)
+	now := time.Date(2024, 8, 22, 9, 15, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		header string
+		secret string
+		want   error
+	}{
+		{name: "Signed Now", header: SignStripePayload(payload, testStripeWebhookSecret, now), secret: testStripeWebhookSecret},
+		{name: "Within Tolerance", header: SignStripePayload(payload, testStripeWebhookSecret, now.Add(-4*time.Minute)), secret: testStripeWebhookSecret},
+		{
+			name:   "Second Signature Matches",
+			header: SignStripePayload(payload, "whsec_rolled", now) + ",v1=" + stripeSignature(payload, testStripeWebhookSecret, fmt.Sprint(now.Unix())),
+			secret: testStripeWebhookSecret,
+		},
+		{name: "Too Old", header: SignStripePayload(payload, testStripeWebhookSecret, now.Add(-6*time.Minute)), secret: testStripeWebhookSecret, want: ErrInvalidWebhookSignature},
+		{name: "Wrong Secret", header: SignStripePayload(payload, "whsec_other", now), secret: testStripeWebhookSecret, want: ErrInvalidWebhookSignature},
+		{name: "No Timestamp", header: "v1=" + stripeSignature(payload, testStripeWebhookSecret, ""), secret: testStripeWebhookSecret, want: ErrInvalidWebhookSignature},
+		{name: "Missing Header", header: "", secret: testStripeWebhookSecret, want: ErrInvalidWebhookSignature},
+		{name: "No Secret Configured", header: SignStripePayload(payload, "", now), secret: "", want: ErrInvalidWebhookSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := VerifyStripeSignature(payload, tt.header, tt.secret, now); !errors.Is(err, tt.want) {
+				t.Errorf("Got:%v But Wanted:%v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestStripeProviderParseWebhook(t *testing.T) {
+	now := time.Date(2024, 8, 22, 9, 15, 0, 0, time.UTC)
+	provider := NewStripeProvider(StripeConfig{WebhookSecret: testStripeWebhookSecret}, time.Second)
+	provider.now = func() time.Time { return now }
+	tests := []struct {
+		name    string
+		payload string
+		want    WebhookEvent
+	}{
+		{
+			name: "Checkout Completed",
+			payload: `{"id": "evt_1", "type": "checkout.session.completed", "data": {"object": {"id": "cs_test_a1b2c3",
+				"payment_status": "paid", "amount_total": 1500, "currency": "usd", "payment_intent": "pi_3Pq"}}}`,
+			want: WebhookEvent{ID: "evt_1", Provider: PaymentProviderStripe, Type: WebhookPaymentSucceeded,
+				Provider_Type: "checkout.session.completed", Reference: "cs_test_a1b2c3", Amount: 1500, Currency: "USD"},
+		},
+		{
+			name: "Checkout Awaiting Payment",
+			payload: `{"id": "evt_2", "type": "checkout.session.completed", "data": {"object": {"id": "cs_test_d4e5",
+				"payment_status": "unpaid", "amount_total": 1500, "currency": "usd"}}}`,
+			want: WebhookEvent{ID: "evt_2", Provider: PaymentProviderStripe, Provider_Type: "checkout.session.completed",
+				Reference: "cs_test_d4e5", Amount: 1500, Currency: "USD"},
+		},
+		{
+			name: "Payment Failed",
+			payload: `{"id": "evt_3", "type": "payment_intent.payment_failed", "data": {"object": {"id": "pi_3Rd",
+				"status": "requires_payment_method", "amount": 1500, "currency": "usd", "payment_method": "pm_1Pq",
+				"last_payment_error": {"code": "card_declined", "message": "Your card was declined."}}}}`,
+			want: WebhookEvent{ID: "evt_3", Provider: PaymentProviderStripe, Type: WebhookPaymentFailed, Provider_Type: "payment_intent.payment_failed",
+				Reference: "pi_3Rd", Authorization_Code: "pm_1Pq", Amount: 1500, Currency: "USD", Message: "Your card was declined."},
+		},
+		{
+			name: "Renewal Failed",
+			payload: `{"id": "evt_4", "type": "payment_intent.payment_failed", "data": {"object": {"id": "pi_3Re",
+				"status": "requires_payment_method", "amount": 1500, "currency": "usd", "metadata": {"aggregate_renewal": "true"}}}}`,
+			want: WebhookEvent{ID: "evt_4", Provider: PaymentProviderStripe, Provider_Type: "payment_intent.payment_failed"},
+		},
+		{
+			name:    "Card Detached",
+			payload: `{"id": "evt_5", "type": "payment_method.detached", "data": {"object": {"id": "pm_1Pq", "type": "card"}}}`,
+			want: WebhookEvent{ID: "evt_5", Provider: PaymentProviderStripe, Type: WebhookAuthorizationDisabled,
+				Provider_Type: "payment_method.detached", Authorization_Code: "pm_1Pq"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			header.Set(StripeSignatureHeader, SignStripePayload([]byte(tt.payload), testStripeWebhookSecret, now))
+			event, err := provider.ParseWebhook([]byte(tt.payload), header)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *event != tt.want {
+				t.Errorf("Got:%+v But Wanted:%+v", *event, tt.want)
+			}
+		})
+	}
+	t.Run("Bad Signature", func(t *testing.T) {
+		payload := []byte(tests[0].payload)
+		header := http.Header{}
+		header.Set(StripeSignatureHeader, SignStripePayload(payload, "whsec_other", now))
+		if _, err := provider.ParseWebhook(payload, header); !errors.Is(err, ErrInvalidWebhookSignature) {
+			t.Errorf("Got:%v But Wanted:%v", err, ErrInvalidWebhookSignature)
+		}
+	})
+	t.Run("Invalid Event", func(t *testing.T) {
+		payload := []byte(`{"type": "checkout.session.completed"}`)
+		header := http.Header{}
+		header.Set(StripeSignatureHeader, SignStripePayload(payload, testStripeWebhookSecret, now))
+		if _, err := provider.ParseWebhook(payload, header); !errors.Is(err, ErrInvalidWebhookEvent) {
+			t.Errorf("Got:%v But Wanted:%v", err, ErrInvalidWebhookEvent)
+		}
+	})
+}
@@ -10,7 +10,7 @@ import (
 // RevenueByPlan represents the revenue generated by each subscription plan.
 type RevenueByPlan struct {
 	PlanName     string
-	TotalRevenue int64
+	TotalRevenue Money
 }
 
 // ChallengedTransactionsOutcome represents the outcome of challenged transactions and the count of each status.
@@ -22,7 +22,7 @@ type ChallengedTransactionsOutcome struct {
 // RevenueByPaymentMethod represents the total revenue generated by each payment method.
 type RevenueByPaymentMethod struct {
 	PaymentMethod string
-	Revenue       float64
+	Revenue       Money
 }
 
 // SubscriptionOverTime represents the count of subscriptions over time, grouped by day.
@@ -43,6 +43,20 @@ type SubscriptionsByCurrency struct {
 	CurrencyCount int64
 }
 
+// CouponRedemptions represents how often a promo code was redeemed and how much it took off, per currency.
+type CouponRedemptions struct {
+	Code          string
+	Redemptions   int64
+	TotalDiscount Money
+}
+
+// RefundsByCurrency represents how many refunds were made and how much they gave back, per currency.
+type RefundsByCurrency struct {
+	Currency      string
+	Refunds       int64
+	TotalRefunded Money
+}
+
 // SubscriptionStats contains all the reports, including single subscription statistics and multiple grouped reports.
 type SubscriptionStats struct {
 	SingleSubscriptionReport SingleSubscriptionReport `json:"single_subscription_report"`
@@ -62,21 +76,28 @@ type MultiReport struct {
 	RevenueByPaymentMethod  []RevenueByPaymentMethod        `json:"revenue_by_payment_method"`
 	SubscriptionsOverTime   []SubscriptionOverTime          `json:"subscriptions_over_time"`
 	SubscriptionsByCurrency []SubscriptionsByCurrency       `json:"subscriptions_by_currency"`
+	CouponRedemptions       []CouponRedemptions             `json:"coupon_redemptions"`
+	Refunds                 []RefundsByCurrency             `json:"refunds"`
 }
 
 // SingleSubscriptionReport encapsulates various single-point statistics such as total active subscriptions, churn rate, etc.
+// Revenue figures have one entry per currency subscriptions were paid in, less promo code discounts
+// and refunds and leaving out free trials. The trial conversion rate is the share of ended trials that were paid for.
 type SingleSubscriptionReport struct {
 	TotalActiveSubscriptions          int64                       `json:"total_active_subscriptions"`
 	ChurnRate                         float64                     `json:"churn_rate"`
-	AverageRevenuePerUser             float64                     `json:"average_revenue_per_user"`
+	AverageRevenuePerUser             []Money                     `json:"average_revenue_per_user"`
 	AverageDuration                   string                      `json:"average_duration"`
-	TotalRevenue                      float64                     `json:"total_revenue"`
+	TotalRevenue                      []Money                     `json:"total_revenue"`
 	MostPopularPaymentMethod          string                      `json:"most_popular_payment_method"`
 	MostSubscribedPlan                int64                       `json:"most_subscribed_plan"`
 	ChallengedRate                    float64                     `json:"challenged_rate"`
 	FailedTransactionsCount           int64                       `json:"failed_transactions_count"`
 	NearExpiryCount                   int64                       `json:"near_expiry_count"`
 	NewVsCancelledSubscriptionsReport NewVsCancelledSubscriptions `json:"new_vs_cancelled_subscriptions"`
+	TotalRedemptions                  int64                       `json:"total_redemptions"`
+	ActiveTrials                      int64                       `json:"active_trials"`
+	TrialConversionRate               float64                     `json:"trial_conversion_rate"`
 }
 
 // getSingleReports() retrieves an aggregated statistical report on the subscription data from the database.
@@ -94,13 +115,15 @@ func (m AdminModel) getSingleReports(ctx context.Context) (*SingleSubscriptionRe
 	if err != nil {
 		return nil, err
 	}
-	averageRevenuePerUser, err := convertToFloat64(singleReports.AverageRevenuePerUser)
+	// Revenue, per currency
+	revenueByCurrency, err := m.DB.RevenueByCurrency(ctx)
 	if err != nil {
 		return nil, err
 	}
-	totalRevenue, err := convertToFloat64(singleReports.TotalRevenue)
-	if err != nil {
-		return nil, err
+	averageRevenuePerUser, totalRevenue := []Money{}, []Money{}
+	for _, revenue := range revenueByCurrency {
+		averageRevenuePerUser = append(averageRevenuePerUser, NewMoney(revenue.AverageRevenuePerUser, revenue.Currency))
+		totalRevenue = append(totalRevenue, NewMoney(revenue.TotalRevenue, revenue.Currency))
 	}
 	challengedRate, err := convertToFloat64(singleReports.ChallengedRate)
 	if err != nil {
@@ -125,6 +148,25 @@ func (m AdminModel) getSingleReports(ctx context.Context) (*SingleSubscriptionRe
 		return nil, err
 	}
 
+	// Free trials and how many turned into paid subscriptions.
+	trials, err := m.DB.TrialsReport(ctx)
+	if err != nil {
+		return nil, err
+	}
+	trialConversionRate := 0.0
+	if trials.EndedTrials > 0 {
+		trialConversionRate = float64(trials.ConvertedTrials) / float64(trials.EndedTrials)
+	}
+	// Promo code redemptions across all coupons.
+	redemptions, err := m.DB.CouponRedemptionsByCoupon(ctx)
+	if err != nil {
+		return nil, err
+	}
+	totalRedemptions := int64(0)
+	for _, redemption := range redemptions {
+		totalRedemptions += redemption.Redemptions
+	}
+
 	// Build the final single subscription report.
 	singleReport := &SingleSubscriptionReport{
 		TotalActiveSubscriptions:          totalActiveSubscriptions,
@@ -138,6 +180,9 @@ func (m AdminModel) getSingleReports(ctx context.Context) (*SingleSubscriptionRe
 		FailedTransactionsCount:           failedTransactionsCount,
 		NearExpiryCount:                   nearExpiryCount,
 		NewVsCancelledSubscriptionsReport: *newVsCancelledSubscriptions,
+		TotalRedemptions:                  totalRedemptions,
+		ActiveTrials:                      trials.ActiveTrials,
+		TrialConversionRate:               trialConversionRate,
 	}
 
 	return singleReport, nil
@@ -178,13 +223,9 @@ func (m AdminModel) getMultiReport(ctx context.Context) (*MultiReport, error) {
 		return nil, err
 	}
 	for _, plan := range revenueByPlan {
-		convertedTotalRevenue, err := convertToInt64(plan.TotalRevenue)
-		if err != nil {
-			return nil, err
-		}
 		revenueByPlanData = append(revenueByPlanData, RevenueByPlan{
 			PlanName:     plan.PlanName,
-			TotalRevenue: convertedTotalRevenue,
+			TotalRevenue: NewMoney(plan.TotalRevenue, plan.Currency),
 		})
 	}
 
@@ -212,13 +253,9 @@ func (m AdminModel) getMultiReport(ctx context.Context) (*MultiReport, error) {
 	}
 	var revenueByPaymentMethodData []RevenueByPaymentMethod
 	for _, paymentMethod := range revenueByPaymentMethod {
-		convertedRevenue, err := convertToFloat64(paymentMethod.Revenue)
-		if err != nil {
-			return nil, err
-		}
 		revenueByPaymentMethodData = append(revenueByPaymentMethodData, RevenueByPaymentMethod{
 			PaymentMethod: paymentMethod.PaymentMethod.String,
-			Revenue:       convertedRevenue,
+			Revenue:       NewMoney(paymentMethod.Revenue, paymentMethod.Currency),
 		})
 	}
 
@@ -251,11 +288,39 @@ func (m AdminModel) getMultiReport(ctx context.Context) (*MultiReport, error) {
 			return nil, err
 		}
 		subscriptionsByCurrencyData = append(subscriptionsByCurrencyData, SubscriptionsByCurrency{
-			Currency:      currency.Currency.String,
+			Currency:      currency.Currency,
 			CurrencyCount: convertedCurrencyCount,
 		})
 	}
 
+	// Get promo code redemptions by coupon data.
+	redemptions, err := m.DB.CouponRedemptionsByCoupon(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var couponRedemptionsData []CouponRedemptions
+	for _, redemption := range redemptions {
+		couponRedemptionsData = append(couponRedemptionsData, CouponRedemptions{
+			Code:          redemption.Code,
+			Redemptions:   redemption.Redemptions,
+			TotalDiscount: NewMoney(redemption.TotalDiscount, redemption.Currency),
+		})
+	}
+
+	// Get refunds by currency data, failed refunds gave nothing back.
+	refunds, err := m.DB.RefundsByCurrency(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var refundsData []RefundsByCurrency
+	for _, refund := range refunds {
+		refundsData = append(refundsData, RefundsByCurrency{
+			Currency:      refund.Currency,
+			Refunds:       refund.Refunds,
+			TotalRefunded: NewMoney(refund.TotalRefunded, refund.Currency),
+		})
+	}
+
 	// Build and return the multi-report.
 	return &MultiReport{
 		RevenueByPlan:           revenueByPlanData,
@@ -263,6 +328,8 @@ func (m AdminModel) getMultiReport(ctx context.Context) (*MultiReport, error) {
 		RevenueByPaymentMethod:  revenueByPaymentMethodData,
 		SubscriptionsOverTime:   subscriptionsOverTimeData,
 		SubscriptionsByCurrency: subscriptionsByCurrencyData,
+		CouponRedemptions:       couponRedemptionsData,
+		Refunds:                 refundsData,
 	}, nil
 }
 
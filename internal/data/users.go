@@ -5,6 +5,7 @@ import (
 	"crypto/sha256"
 	"database/sql"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/blue-davinci/aggregate/internal/database"
@@ -18,7 +19,8 @@ type UserModel struct {
 
 // Define a custom ErrDuplicateEmail error.
 var (
-	ErrDuplicateEmail = errors.New("duplicate email")
+	ErrDuplicateEmail  = errors.New("duplicate email")
+	ErrDuplicateHandle = errors.New("duplicate handle")
 )
 
 // Define Default Image:
@@ -79,6 +81,7 @@ type User struct {
 	Activated bool      `json:"-"`
 	Version   int       `json:"-"`
 	User_Img  string    `json:"user_img"`
+	Handle    string    `json:"handle"`
 }
 
 func ValidateEmail(v *validator.Validator, email string) {
@@ -92,6 +95,16 @@ func ValidateName(v *validator.Validator, name string) {
 	v.Check(name != "", "email", "must be provided")
 	v.Check(len(name) <= 500, "name", "must not be more than 500 bytes long")
 }
+
+// reservedHandles are the paths under /v1/users that would otherwise be read as a
+// profile handle. Migration 43 renames the users who took one before it was reserved.
+var reservedHandles = []string{"activated", "activity", "digest", "followers", "follows", "password", "profile"}
+
+func ValidateHandle(v *validator.Validator, handle string) {
+	v.Check(handle != "", "handle", "must be provided")
+	v.Check(validator.Matches(handle, validator.HandleRX), "handle", "must be 3 to 30 letters, digits or underscores")
+	v.Check(!validator.PermittedValue(strings.ToLower(handle), reservedHandles...), "handle", "is reserved")
+}
 func ValidatePasswordPlaintext(v *validator.Validator, password string) {
 	v.Check(password != "", "password", "must be provided")
 	v.Check(len(password) >= 8, "password", "must be at least 8 bytes long")
@@ -104,6 +117,8 @@ func ValidateUser(v *validator.Validator, user *User) {
 	ValidateEmail(v, user.Email)
 	// Validate Image
 	ValidateImageURL(v, user.User_Img)
+	// Validate the public handle other users mention them by
+	ValidateHandle(v, user.Handle)
 	// If the plaintext password is not nil, call the standalone
 	// ValidatePasswordPlaintext() helper.
 	if user.Password.plaintext != nil {
@@ -129,6 +144,7 @@ func (m UserModel) Insert(user *User) error {
 		Email:        user.Email,
 		PasswordHash: user.Password.hash,
 		Activated:    user.Activated,
+		Handle:       user.Handle,
 	})
 	user.ID = createduser.ID
 	user.CreatedAt = createduser.CreatedAt
@@ -137,6 +153,8 @@ func (m UserModel) Insert(user *User) error {
 		switch {
 		case err.Error() == `pq: duplicate key value violates unique constraint "users_email_key"`:
 			return ErrDuplicateEmail
+		case err.Error() == `pq: duplicate key value violates unique constraint "users_handle_key"`:
+			return ErrDuplicateHandle
 		default:
 			return err
 		}
@@ -179,6 +197,7 @@ func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error)
 		Activated: queryresult.Activated,
 		Version:   int(queryresult.Version),
 		User_Img:  queryresult.UserImg,
+		Handle:    queryresult.Handle,
 	}
 	return &user, nil
 }
@@ -210,6 +229,7 @@ func (m UserModel) GetByEmail(email string) (*User, error) {
 		Activated: queryresult.Activated,
 		Version:   int(queryresult.Version),
 		User_Img:  queryresult.UserImg,
+		Handle:    queryresult.Handle,
 	}
 	return user, nil
 }
@@ -226,6 +246,7 @@ func (m UserModel) Update(user *User) error {
 		PasswordHash: user.Password.hash,
 		Activated:    user.Activated,
 		UserImg:      user.User_Img,
+		Handle:       user.Handle,
 		Version:      int32(user.Version),
 		ID:           user.ID,
 	})
@@ -234,6 +255,8 @@ func (m UserModel) Update(user *User) error {
 		switch {
 		case err.Error() == `pq: duplicate key value violates unique constraint "users_email_key"`:
 			return ErrDuplicateEmail
+		case err.Error() == `pq: duplicate key value violates unique constraint "users_handle_key"`:
+			return ErrDuplicateHandle
 		case errors.Is(err, sql.ErrNoRows):
 			return ErrEditConflict
 		default:
@@ -0,0 +1,168 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/blue-davinci/aggregate/internal/database"
+	"github.com/google/uuid"
+)
+
+// What a webhook event means for us, whichever provider sent it. Events we don't act on
+// keep an empty Type.
+const (
+	WebhookPaymentSucceeded      = "payment.succeeded"
+	WebhookPaymentFailed         = "payment.failed"
+	WebhookAuthorizationDisabled = "authorization.disabled"
+)
+
+var (
+	ErrInvalidWebhookEvent     = errors.New("invalid webhook event")
+	ErrInvalidWebhookSignature = errors.New("invalid webhook signature")
+	ErrPaymentIntentNotFound   = errors.New("payment intent not found")
+)
+
+// WebhookEvent is a provider's webhook event translated for us. ID is unique per
+// provider and stays the same across redeliveries, Provider_Type is the event's name in
+// the provider's own terms.
+type WebhookEvent struct {
+	ID                 string
+	Provider           string
+	Type               string
+	Provider_Type      string
+	Reference          string
+	Authorization_Code string
+	Amount             int64
+	Currency           string
+	Message            string
+}
+
+// PaymentIntent is what a transaction reference was started for and with which
+// provider. Renews_Subscription_ID is only set when the reference belongs to a
+// challenged renewal.
+type PaymentIntent struct {
+	Reference              string
+	Provider               string
+	User_ID                int64
+	User_Name              string
+	User_Email             string
+	Plan_ID                int32
+	Amount                 int64
+	Renews_Subscription_ID uuid.UUID
+}
+
+// RecordWebhookEvent() stores an event before it is processed. It returns false when the
+// event was already stored, meaning it is a redelivery we have handled or are handling.
+func (m PaymentsModel) RecordWebhookEvent(event *WebhookEvent) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := m.DB.InsertPaymentWebhookEvent(ctx, database.InsertPaymentWebhookEventParams{
+		EventID:   event.ID,
+		Provider:  event.Provider,
+		EventType: event.Provider_Type,
+	})
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
+
+// DeleteWebhookEvent() forgets an event whose processing failed so that the provider's
+// next delivery of it is processed again.
+func (m PaymentsModel) DeleteWebhookEvent(eventID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return m.DB.DeletePaymentWebhookEvent(ctx, eventID)
+}
+
+// CreatePaymentIntent() saves what a transaction reference was started for. Saving the
+// same reference twice keeps the first intent.
+func (m PaymentsModel) CreatePaymentIntent(intent *PaymentIntent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return m.DB.CreatePaymentIntent(ctx, database.CreatePaymentIntentParams{
+		Reference:            intent.Reference,
+		UserID:               intent.User_ID,
+		PlanID:               intent.Plan_ID,
+		Amount:               intent.Amount,
+		RenewsSubscriptionID: uuid.NullUUID{UUID: intent.Renews_Subscription_ID, Valid: intent.Renews_Subscription_ID != uuid.Nil},
+		Provider:             intent.Provider,
+	})
+}
+
+// GetPaymentIntentByReference() returns the intent behind a transaction reference along
+// with the name and email of the user who started it.
+func (m PaymentsModel) GetPaymentIntentByReference(reference string) (*PaymentIntent, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	row, err := m.DB.GetPaymentIntentByReference(ctx, reference)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrPaymentIntentNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &PaymentIntent{
+		Reference:              row.Reference,
+		Provider:               row.Provider,
+		User_ID:                row.UserID,
+		User_Name:              row.Name,
+		User_Email:             row.Email,
+		Plan_ID:                row.PlanID,
+		Amount:                 row.Amount,
+		Renews_Subscription_ID: row.RenewsSubscriptionID.UUID,
+	}, nil
+}
+
+// CancelSubscriptionsByAuthorizationCode() cancels the active subscriptions paid for with
+// an authorization. They stay usable until they end but are no longer renewed.
+func (m PaymentsModel) CancelSubscriptionsByAuthorizationCode(authorizationCode string) ([]*Subscription, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := m.DB.CancelSubscriptionsByAuthorizationCode(ctx, sql.NullString{String: authorizationCode, Valid: true})
+	if err != nil {
+		return nil, err
+	}
+	subscriptions := []*Subscription{}
+	for _, row := range rows {
+		subscriptions = append(subscriptions, &Subscription{
+			ID:       row.ID,
+			User_ID:  row.UserID,
+			End_Date: row.EndDate,
+			Status:   PaymentStatusCancelled,
+		})
+	}
+	return subscriptions, nil
+}
+
+// GetLatestSubscriptionByAuthorizationCode() returns the most recent subscription paid for
+// with an authorization, filled in as the payment details a failed transaction is
+// recorded against.
+func (m PaymentsModel) GetLatestSubscriptionByAuthorizationCode(authorizationCode string) (*Payment_Details, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	row, err := m.DB.GetLatestSubscriptionByAuthorizationCode(ctx, sql.NullString{String: authorizationCode, Valid: true})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrSubscriptionNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &Payment_Details{
+		ID:                 row.ID,
+		User_ID:            row.UserID,
+		Plan_ID:            row.PlanID,
+		Price:              NewMoney(row.Price, row.Currency),
+		Authorization_Code: authorizationCode,
+		Card_Last4:         row.CardLast4.String,
+		Card_Exp_Month:     row.CardExpMonth.String,
+		Card_Exp_Year:      row.CardExpYear.String,
+		Card_Type:          row.CardType.String,
+	}, nil
+}
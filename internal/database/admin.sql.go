@@ -69,11 +69,11 @@ func (q *Queries) AdminCreateNewPermission(ctx context.Context, code string) (Pe
 
 const adminCreatePaymentPlan = `-- name: AdminCreatePaymentPlan :one
 INSERT INTO payment_plans (
-    name, image, description, duration, price, features, status
+    name, image, description, duration, price, currency, features, status, trial_days, seats
 ) VALUES (
-    $1, $2, $3, $4, $5, $6, $7
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
 )
-RETURNING id, name, image, description, duration, price, features, created_at, updated_at, status, version
+RETURNING *
 `
 
 type AdminCreatePaymentPlanParams struct {
@@ -81,9 +81,12 @@ type AdminCreatePaymentPlanParams struct {
 	Image       string
 	Description sql.NullString
 	Duration    string
-	Price       string
+	Price       int64
+	Currency    string
 	Features    []string
 	Status      string
+	TrialDays   int32
+	Seats       int32
 }
 
 func (q *Queries) AdminCreatePaymentPlan(ctx context.Context, arg AdminCreatePaymentPlanParams) (PaymentPlan, error) {
@@ -93,8 +96,11 @@ func (q *Queries) AdminCreatePaymentPlan(ctx context.Context, arg AdminCreatePay
 		arg.Description,
 		arg.Duration,
 		arg.Price,
+		arg.Currency,
 		pq.Array(arg.Features),
 		arg.Status,
+		arg.TrialDays,
+		arg.Seats,
 	)
 	var i PaymentPlan
 	err := row.Scan(
@@ -109,6 +115,9 @@ func (q *Queries) AdminCreatePaymentPlan(ctx context.Context, arg AdminCreatePay
 		&i.UpdatedAt,
 		&i.Status,
 		&i.Version,
+		&i.Currency,
+		&i.TrialDays,
+		&i.Seats,
 	)
 	return i, err
 }
@@ -221,7 +230,7 @@ func (q *Queries) AdminGetAllAnnouncments(ctx context.Context, arg AdminGetAllAn
 }
 
 const adminGetAllPaymentPlans = `-- name: AdminGetAllPaymentPlans :many
-SELECT id, name, image, description, duration, price, features, created_at, updated_at, status,version
+SELECT id, name, image, description, duration, price, features, created_at, updated_at, status, version, currency, trial_days, seats
 FROM payment_plans
 ORDER BY status ASC, price
 `
@@ -247,6 +256,9 @@ func (q *Queries) AdminGetAllPaymentPlans(ctx context.Context) ([]PaymentPlan, e
 			&i.UpdatedAt,
 			&i.Status,
 			&i.Version,
+			&i.Currency,
+			&i.TrialDays,
+			&i.Seats,
 		); err != nil {
 			return nil, err
 		}
@@ -315,7 +327,7 @@ type AdminGetAllSubscriptionsRow struct {
 	PlanDuration              string
 	StartDate                 time.Time
 	EndDate                   time.Time
-	Price                     string
+	Price                     int64
 	Status                    string
 	TransactionID             int64
 	PaymentMethod             sql.NullString
@@ -323,7 +335,7 @@ type AdminGetAllSubscriptionsRow struct {
 	CardExpMonth              sql.NullString
 	CardExpYear               sql.NullString
 	CardType                  sql.NullString
-	Currency                  sql.NullString
+	Currency                  string
 	CreatedAt                 time.Time
 	UpdatedAt                 time.Time
 	HasChallengedTransactions bool
@@ -472,6 +484,7 @@ SELECT
     ct.status,
     s.plan_id,
     s.price,
+    s.currency,
     s.start_date,
     s.end_date
 FROM 
@@ -501,7 +514,8 @@ type AdminGetChallengedTransactionsBySubscriptionIDRow struct {
 	UpdatedAt                time.Time
 	Status                   string
 	PlanID                   int32
-	Price                    string
+	Price                    int64
+	Currency                 string
 	StartDate                time.Time
 	EndDate                  time.Time
 }
@@ -529,6 +543,7 @@ func (q *Queries) AdminGetChallengedTransactionsBySubscriptionID(ctx context.Con
 			&i.Status,
 			&i.PlanID,
 			&i.Price,
+			&i.Currency,
 			&i.StartDate,
 			&i.EndDate,
 		); err != nil {
@@ -546,7 +561,7 @@ func (q *Queries) AdminGetChallengedTransactionsBySubscriptionID(ctx context.Con
 }
 
 const adminGetPaymentPlanByID = `-- name: AdminGetPaymentPlanByID :one
-SELECT id, name, image, description, duration, price, features, created_at, updated_at, status, version
+SELECT id, name, image, description, duration, price, features, created_at, updated_at, status, version, currency, trial_days, seats
 FROM payment_plans
 WHERE id = $1
 `
@@ -566,6 +581,9 @@ func (q *Queries) AdminGetPaymentPlanByID(ctx context.Context, id int32) (Paymen
 		&i.UpdatedAt,
 		&i.Status,
 		&i.Version,
+		&i.Currency,
+		&i.TrialDays,
+		&i.Seats,
 	)
 	return i, err
 }
@@ -581,7 +599,6 @@ user_stats AS (
 ),
 subscription_stats AS (
     SELECT
-        COALESCE(SUM(price), 0)::numeric AS total_revenue,
         COUNT(*) FILTER (WHERE status = 'active') AS active_subscriptions,
         COUNT(*) FILTER (WHERE status = 'cancelled') AS cancelled_subscriptions,
         COUNT(*) FILTER (WHERE status = 'expired') AS expired_subscriptions,
@@ -604,7 +621,6 @@ SELECT
     us.total_users,
     us.active_users,
     us.new_signups,
-    ss.total_revenue,
     ss.active_subscriptions,
     ss.cancelled_subscriptions,
     ss.expired_subscriptions,
@@ -618,7 +634,6 @@ type AdminGetStatisticsRow struct {
 	TotalUsers             int64
 	ActiveUsers            int64
 	NewSignups             int64
-	TotalRevenue           string
 	ActiveSubscriptions    int64
 	CancelledSubscriptions int64
 	ExpiredSubscriptions   int64
@@ -638,7 +653,6 @@ func (q *Queries) AdminGetStatistics(ctx context.Context) (AdminGetStatisticsRow
 		&i.TotalUsers,
 		&i.ActiveUsers,
 		&i.NewSignups,
-		&i.TotalRevenue,
 		&i.ActiveSubscriptions,
 		&i.CancelledSubscriptions,
 		&i.ExpiredSubscriptions,
@@ -657,12 +671,15 @@ SET
     description = $3,
     duration = $4,
     price = $5,
-    features = $6,
-    status = $7,
+    currency = $6,
+    features = $7,
+    status = $8,
+    trial_days = $9,
+    seats = $10,
     version = version + 1,
     updated_at = now()
 WHERE 
-    id = $8 AND version = $9
+    id = $11 AND version = $12
 RETURNING version
 `
 
@@ -671,9 +688,12 @@ type AdminUpdatePaymentPlanParams struct {
 	Image       string
 	Description sql.NullString
 	Duration    string
-	Price       string
+	Price       int64
+	Currency    string
 	Features    []string
 	Status      string
+	TrialDays   int32
+	Seats       int32
 	ID          int32
 	Version     int32
 }
@@ -685,8 +705,11 @@ func (q *Queries) AdminUpdatePaymentPlan(ctx context.Context, arg AdminUpdatePay
 		arg.Description,
 		arg.Duration,
 		arg.Price,
+		arg.Currency,
 		pq.Array(arg.Features),
 		arg.Status,
+		arg.TrialDays,
+		arg.Seats,
 		arg.ID,
 		arg.Version,
 	)
@@ -26,7 +26,7 @@ func (q *Queries) DeletAllAPIKeysForUser(ctx context.Context, arg DeletAllAPIKey
 }
 
 const getForToken = `-- name: GetForToken :one
-SELECT users.id, users.created_at, users.name, users.email, users.password_hash, users.activated, users.version, users.user_img
+SELECT users.id, users.created_at, users.name, users.email, users.password_hash, users.activated, users.version, users.user_img, users.handle
 FROM users
 INNER JOIN api_keys
 ON users.id = api_keys.user_id
@@ -53,6 +53,7 @@ func (q *Queries) GetForToken(ctx context.Context, arg GetForTokenParams) (User,
 		&i.Activated,
 		&i.Version,
 		&i.UserImg,
+		&i.Handle,
 	)
 	return i, err
 }
@@ -7,15 +7,18 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createComments = `-- name: CreateComments :one
-INSERT INTO comments (id, post_id, user_id, parent_comment_id, comment_text)
-VALUES ($1, $2, $3, $4, $5)
-RETURNING id, post_id, user_id, parent_comment_id, comment_text, created_at, updated_at, version
+INSERT INTO comments (id, post_id, user_id, parent_comment_id, comment_text, status)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, post_id, user_id, parent_comment_id, comment_text, created_at, updated_at, version, status, edited_at
 `
 
 type CreateCommentsParams struct {
@@ -24,6 +27,7 @@ type CreateCommentsParams struct {
 	UserID          int64
 	ParentCommentID uuid.NullUUID
 	CommentText     string
+	Status          string
 }
 
 func (q *Queries) CreateComments(ctx context.Context, arg CreateCommentsParams) (Comment, error) {
@@ -33,6 +37,7 @@ func (q *Queries) CreateComments(ctx context.Context, arg CreateCommentsParams)
 		arg.UserID,
 		arg.ParentCommentID,
 		arg.CommentText,
+		arg.Status,
 	)
 	var i Comment
 	err := row.Scan(
@@ -44,12 +49,15 @@ func (q *Queries) CreateComments(ctx context.Context, arg CreateCommentsParams)
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Version,
+		&i.Status,
+		&i.EditedAt,
 	)
 	return i, err
 }
 
-const deleteComment = `-- name: DeleteComment :exec
+const deleteComment = `-- name: DeleteComment :execrows
 DELETE FROM comments WHERE id = $1 AND user_id = $2
+AND NOT EXISTS (SELECT 1 FROM comments r WHERE r.parent_comment_id = comments.id)
 `
 
 type DeleteCommentParams struct {
@@ -57,8 +65,54 @@ type DeleteCommentParams struct {
 	UserID int64
 }
 
-func (q *Queries) DeleteComment(ctx context.Context, arg DeleteCommentParams) error {
-	_, err := q.db.ExecContext(ctx, deleteComment, arg.ID, arg.UserID)
+func (q *Queries) DeleteComment(ctx context.Context, arg DeleteCommentParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteComment, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteCommentReaction = `-- name: DeleteCommentReaction :one
+DELETE FROM comment_reactions
+WHERE comment_id = $1 AND user_id = $2
+RETURNING comment_id
+`
+
+type DeleteCommentReactionParams struct {
+	CommentID uuid.UUID
+	UserID    int64
+}
+
+func (q *Queries) DeleteCommentReaction(ctx context.Context, arg DeleteCommentReactionParams) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, deleteCommentReaction, arg.CommentID, arg.UserID)
+	var comment_id uuid.UUID
+	err := row.Scan(&comment_id)
+	return comment_id, err
+}
+
+const deleteCommentRevisions = `-- name: DeleteCommentRevisions :exec
+DELETE FROM comment_revisions
+WHERE comment_id = $1
+`
+
+func (q *Queries) DeleteCommentRevisions(ctx context.Context, commentID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteCommentRevisions, commentID)
+	return err
+}
+
+const deleteStaleCommentMentions = `-- name: DeleteStaleCommentMentions :exec
+DELETE FROM comment_mentions
+WHERE comment_id = $1 AND NOT (handle = ANY($2::citext[]))
+`
+
+type DeleteStaleCommentMentionsParams struct {
+	CommentID uuid.UUID
+	Column2   []string
+}
+
+func (q *Queries) DeleteStaleCommentMentions(ctx context.Context, arg DeleteStaleCommentMentionsParams) error {
+	_, err := q.db.ExecContext(ctx, deleteStaleCommentMentions, arg.CommentID, pq.Array(arg.Column2))
 	return err
 }
 
@@ -71,7 +125,9 @@ SELECT
     comment_text,
     created_at,
     updated_at,
-    version
+    version,
+    status,
+    edited_at
 FROM comments
 WHERE id = $1 AND user_id = $2
 `
@@ -93,45 +149,300 @@ func (q *Queries) GetCommentByID(ctx context.Context, arg GetCommentByIDParams)
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Version,
+		&i.Status,
+		&i.EditedAt,
 	)
 	return i, err
 }
 
-const getCommentsForPost = `-- name: GetCommentsForPost :many
+const getCommentDepth = `-- name: GetCommentDepth :one
+WITH RECURSIVE ancestors AS (
+    SELECT c.id, c.parent_comment_id, 0 AS depth
+    FROM comments c
+    WHERE c.id = $1
+    UNION ALL
+    SELECT c.id, c.parent_comment_id, a.depth + 1
+    FROM comments c
+    JOIN ancestors a ON c.id = a.parent_comment_id
+)
 SELECT 
-    comments.id, 
-    comments.post_id, 
-    comments.user_id, 
-    users.name as user_name, 
-    comments.parent_comment_id, 
-    comments.comment_text, 
-    comments.created_at,
-    comments.version,
-    CASE WHEN comments.user_id = $2 THEN true ELSE false END AS isEditable
+    comments.id,
+    comments.post_id,
+    (SELECT MAX(depth) FROM ancestors)::int AS depth
 FROM comments
-JOIN users ON comments.user_id = users.id
-WHERE comments.post_id = $1
+WHERE comments.id = $1
 `
 
-type GetCommentsForPostParams struct {
+type GetCommentDepthRow struct {
+	ID     uuid.UUID
 	PostID uuid.UUID
+	Depth  int32
+}
+
+func (q *Queries) GetCommentDepth(ctx context.Context, id uuid.UUID) (GetCommentDepthRow, error) {
+	row := q.db.QueryRowContext(ctx, getCommentDepth, id)
+	var i GetCommentDepthRow
+	err := row.Scan(&i.ID, &i.PostID, &i.Depth)
+	return i, err
+}
+
+const getCommentMentions = `-- name: GetCommentMentions :many
+SELECT user_id, handle FROM comment_mentions
+WHERE comment_id = $1
+ORDER BY created_at, handle
+`
+
+type GetCommentMentionsRow struct {
 	UserID int64
+	Handle string
+}
+
+func (q *Queries) GetCommentMentions(ctx context.Context, commentID uuid.UUID) ([]GetCommentMentionsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getCommentMentions, commentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetCommentMentionsRow
+	for rows.Next() {
+		var i GetCommentMentionsRow
+		if err := rows.Scan(&i.UserID, &i.Handle); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getCommentOwner = `-- name: GetCommentOwner :one
+SELECT user_id, post_id FROM comments
+WHERE id = $1
+`
+
+type GetCommentOwnerRow struct {
+	UserID int64
+	PostID uuid.UUID
+}
+
+func (q *Queries) GetCommentOwner(ctx context.Context, id uuid.UUID) (GetCommentOwnerRow, error) {
+	row := q.db.QueryRowContext(ctx, getCommentOwner, id)
+	var i GetCommentOwnerRow
+	err := row.Scan(&i.UserID, &i.PostID)
+	return i, err
+}
+
+const getCommentReactionCounts = `-- name: GetCommentReactionCounts :many
+SELECT reaction, COUNT(*) AS total
+FROM comment_reactions
+WHERE comment_id = $1
+GROUP BY reaction
+ORDER BY reaction
+`
+
+type GetCommentReactionCountsRow struct {
+	Reaction string
+	Total    int64
+}
+
+func (q *Queries) GetCommentReactionCounts(ctx context.Context, commentID uuid.UUID) ([]GetCommentReactionCountsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getCommentReactionCounts, commentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetCommentReactionCountsRow
+	for rows.Next() {
+		var i GetCommentReactionCountsRow
+		if err := rows.Scan(&i.Reaction, &i.Total); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getCommentRevisions = `-- name: GetCommentRevisions :many
+SELECT version, comment_text, created_at, replaced_at
+FROM comment_revisions
+WHERE comment_id = $1
+ORDER BY version DESC
+`
+
+type GetCommentRevisionsRow struct {
+	Version     int32
+	CommentText string
+	CreatedAt   time.Time
+	ReplacedAt  time.Time
+}
+
+func (q *Queries) GetCommentRevisions(ctx context.Context, commentID uuid.UUID) ([]GetCommentRevisionsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getCommentRevisions, commentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetCommentRevisionsRow
+	for rows.Next() {
+		var i GetCommentRevisionsRow
+		if err := rows.Scan(
+			&i.Version,
+			&i.CommentText,
+			&i.CreatedAt,
+			&i.ReplacedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getCommentsForPost = `-- name: GetCommentsForPost :many
+WITH RECURSIVE page AS (
+    -- the page of comments whose threads we return, top level ones unless a parent is given
+    SELECT
+        c.id,
+        count(*) OVER() AS total_records
+    FROM comments c
+    WHERE c.post_id = $1
+        AND COALESCE(c.parent_comment_id, '00000000-0000-0000-0000-000000000000') = $3::uuid
+        AND (c.status <> 'pending' OR c.user_id = $2) -- pending comments are only shown to their author
+    ORDER BY
+        CASE WHEN $5::text = 'oldest' THEN c.created_at END ASC,
+        CASE WHEN $5::text = 'top' THEN (
+            SELECT COALESCE(SUM(CASE WHEN cr.reaction = 'downvote' THEN -1 ELSE 1 END), 0)
+            FROM comment_reactions cr WHERE cr.comment_id = c.id
+        ) END DESC,
+        CASE WHEN $5::text = 'top' THEN (
+            SELECT COUNT(*) FROM comments r WHERE r.parent_comment_id = c.id AND r.status <> 'pending'
+        ) END DESC,
+        c.created_at DESC,
+        c.id DESC
+    LIMIT $6 OFFSET $7
+), thread AS (
+    SELECT
+        c.id, c.post_id, c.user_id, c.parent_comment_id, c.comment_text, c.status, c.created_at, c.edited_at, c.version,
+        0 AS depth,
+        page.total_records
+    FROM comments c
+    JOIN page ON c.id = page.id
+    UNION ALL
+    SELECT
+        c.id, c.post_id, c.user_id, c.parent_comment_id, c.comment_text, c.status, c.created_at, c.edited_at, c.version,
+        t.depth + 1,
+        t.total_records
+    FROM comments c
+    JOIN thread t ON c.parent_comment_id = t.id
+    WHERE t.depth + 1 < $4::int  -- Parameter 4: maximum depth
+        AND (c.status <> 'pending' OR c.user_id = $2)
+)
+SELECT 
+    t.total_records,
+    t.id, 
+    t.post_id, 
+    t.user_id, 
+    users.name as user_name, 
+    users.handle as user_handle,
+    t.parent_comment_id, 
+    -- moderated and deleted comments keep their place in the thread without their text
+    CASE WHEN t.status IN ('visible', 'pending') THEN t.comment_text ELSE '[removed]' END AS comment_text,
+    t.status,
+    t.created_at,
+    t.edited_at,
+    t.version,
+    t.depth,
+    (
+        SELECT COUNT(*) FROM comments r
+        WHERE r.parent_comment_id = t.id AND (r.status <> 'pending' OR r.user_id = $2)
+    ) AS reply_count,
+    COALESCE(rc.score, 0)::bigint AS score,
+    COALESCE(rc.reaction_counts, '{}')::jsonb AS reaction_counts,
+    COALESCE(ur.reaction, '') AS user_reaction,
+    COALESCE((
+        SELECT jsonb_agg(jsonb_build_object('user_id', m.user_id, 'handle', m.handle))
+        FROM comment_mentions m WHERE m.comment_id = t.id
+    ), '[]')::jsonb AS mentions,
+    CASE WHEN t.user_id = $2 AND t.status = 'visible' AND t.created_at >= $8::timestamptz THEN true ELSE false END AS isEditable
+FROM thread t
+JOIN users ON t.user_id = users.id
+LEFT JOIN LATERAL (
+    -- downvotes count against a comment while every other reaction counts for it
+    SELECT
+        SUM(CASE WHEN counts.reaction = 'downvote' THEN -counts.total ELSE counts.total END) AS score,
+        jsonb_object_agg(counts.reaction, counts.total) AS reaction_counts
+    FROM (
+        SELECT cr.reaction, COUNT(*) AS total
+        FROM comment_reactions cr
+        WHERE cr.comment_id = t.id
+        GROUP BY cr.reaction
+    ) counts
+) rc ON TRUE
+LEFT JOIN comment_reactions ur ON ur.comment_id = t.id AND ur.user_id = $2
+ORDER BY t.depth, t.created_at
+`
+
+type GetCommentsForPostParams struct {
+	PostID  uuid.UUID
+	UserID  int64
+	Column3 uuid.UUID
+	Column4 int32
+	Column5 string
+	Limit   int32
+	Offset  int32
+	Column8 time.Time
 }
 
 type GetCommentsForPostRow struct {
+	TotalRecords    int64
 	ID              uuid.UUID
 	PostID          uuid.UUID
 	UserID          int64
 	UserName        string
+	UserHandle      string
 	ParentCommentID uuid.NullUUID
 	CommentText     string
+	Status          string
 	CreatedAt       time.Time
+	EditedAt        sql.NullTime
 	Version         int32
+	Depth           int32
+	ReplyCount      int64
+	Score           int64
+	ReactionCounts  json.RawMessage
+	UserReaction    string
+	Mentions        json.RawMessage
 	Iseditable      bool
 }
 
 func (q *Queries) GetCommentsForPost(ctx context.Context, arg GetCommentsForPostParams) ([]GetCommentsForPostRow, error) {
-	rows, err := q.db.QueryContext(ctx, getCommentsForPost, arg.PostID, arg.UserID)
+	rows, err := q.db.QueryContext(ctx, getCommentsForPost,
+		arg.PostID,
+		arg.UserID,
+		arg.Column3,
+		arg.Column4,
+		arg.Column5,
+		arg.Limit,
+		arg.Offset,
+		arg.Column8,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -140,14 +451,24 @@ func (q *Queries) GetCommentsForPost(ctx context.Context, arg GetCommentsForPost
 	for rows.Next() {
 		var i GetCommentsForPostRow
 		if err := rows.Scan(
+			&i.TotalRecords,
 			&i.ID,
 			&i.PostID,
 			&i.UserID,
 			&i.UserName,
+			&i.UserHandle,
 			&i.ParentCommentID,
 			&i.CommentText,
+			&i.Status,
 			&i.CreatedAt,
+			&i.EditedAt,
 			&i.Version,
+			&i.Depth,
+			&i.ReplyCount,
+			&i.Score,
+			&i.ReactionCounts,
+			&i.UserReaction,
+			&i.Mentions,
 			&i.Iseditable,
 		); err != nil {
 			return nil, err
@@ -163,11 +484,117 @@ func (q *Queries) GetCommentsForPost(ctx context.Context, arg GetCommentsForPost
 	return items, nil
 }
 
+const getRecentUserComments = `-- name: GetRecentUserComments :many
+SELECT id, comment_text, created_at
+FROM comments
+WHERE user_id = $1 AND created_at > $2
+ORDER BY created_at DESC
+LIMIT 100
+`
+
+type GetRecentUserCommentsParams struct {
+	UserID    int64
+	CreatedAt time.Time
+}
+
+type GetRecentUserCommentsRow struct {
+	ID          uuid.UUID
+	CommentText string
+	CreatedAt   time.Time
+}
+
+func (q *Queries) GetRecentUserComments(ctx context.Context, arg GetRecentUserCommentsParams) ([]GetRecentUserCommentsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getRecentUserComments, arg.UserID, arg.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRecentUserCommentsRow
+	for rows.Next() {
+		var i GetRecentUserCommentsRow
+		if err := rows.Scan(&i.ID, &i.CommentText, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const insertCommentMentions = `-- name: InsertCommentMentions :many
+INSERT INTO comment_mentions (comment_id, user_id, handle)
+SELECT $1, users.id, users.handle
+FROM users
+WHERE users.handle = ANY($2::citext[]) AND users.id <> $3  -- users can't mention themselves
+ON CONFLICT (comment_id, user_id) DO NOTHING
+RETURNING user_id
+`
+
+type InsertCommentMentionsParams struct {
+	CommentID uuid.UUID
+	Column2   []string
+	ID        int64
+}
+
+func (q *Queries) InsertCommentMentions(ctx context.Context, arg InsertCommentMentionsParams) ([]int64, error) {
+	rows, err := q.db.QueryContext(ctx, insertCommentMentions, arg.CommentID, pq.Array(arg.Column2), arg.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int64
+	for rows.Next() {
+		var user_id int64
+		if err := rows.Scan(&user_id); err != nil {
+			return nil, err
+		}
+		items = append(items, user_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const removeUserComment = `-- name: RemoveUserComment :execrows
+UPDATE comments
+SET status = 'removed', comment_text = '', updated_at = NOW(), version = version + 1
+WHERE id = $1 AND user_id = $2
+`
+
+type RemoveUserCommentParams struct {
+	ID     uuid.UUID
+	UserID int64
+}
+
+func (q *Queries) RemoveUserComment(ctx context.Context, arg RemoveUserCommentParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, removeUserComment, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const updateUserComment = `-- name: UpdateUserComment :one
+WITH revision AS (
+    -- keep the text being replaced, it became current when the comment was created or last edited
+    INSERT INTO comment_revisions (comment_id, version, comment_text, created_at)
+    SELECT c.id, c.version, c.comment_text, COALESCE(c.edited_at, c.created_at)
+    FROM comments c
+    WHERE c.id = $2 AND c.user_id = $3 AND c.version = $4 AND c.status = 'visible' AND c.created_at >= $5
+)
 UPDATE comments
-SET comment_text = $1, updated_at = now(), version = version + 1
-WHERE id = $2 AND user_id = $3 AND version = $4
-RETURNING version
+SET comment_text = $1, status = $6, updated_at = now(), edited_at = now(), version = version + 1
+WHERE id = $2 AND user_id = $3 AND version = $4 AND status = 'visible' AND created_at >= $5
+RETURNING version, edited_at
 `
 
 type UpdateUserCommentParams struct {
@@ -175,16 +602,52 @@ type UpdateUserCommentParams struct {
 	ID          uuid.UUID
 	UserID      int64
 	Version     int32
+	CreatedAt   time.Time
+	Status      string
 }
 
-func (q *Queries) UpdateUserComment(ctx context.Context, arg UpdateUserCommentParams) (int32, error) {
+type UpdateUserCommentRow struct {
+	Version  int32
+	EditedAt sql.NullTime
+}
+
+func (q *Queries) UpdateUserComment(ctx context.Context, arg UpdateUserCommentParams) (UpdateUserCommentRow, error) {
 	row := q.db.QueryRowContext(ctx, updateUserComment,
 		arg.CommentText,
 		arg.ID,
 		arg.UserID,
 		arg.Version,
+		arg.CreatedAt,
+		arg.Status,
+	)
+	var i UpdateUserCommentRow
+	err := row.Scan(&i.Version, &i.EditedAt)
+	return i, err
+}
+
+const upsertCommentReaction = `-- name: UpsertCommentReaction :one
+INSERT INTO comment_reactions (comment_id, user_id, reaction)
+VALUES ($1, $2, $3)
+ON CONFLICT (comment_id, user_id) DO UPDATE
+SET reaction = EXCLUDED.reaction, updated_at = NOW()
+RETURNING comment_id, user_id, reaction, created_at, updated_at
+`
+
+type UpsertCommentReactionParams struct {
+	CommentID uuid.UUID
+	UserID    int64
+	Reaction  string
+}
+
+func (q *Queries) UpsertCommentReaction(ctx context.Context, arg UpsertCommentReactionParams) (CommentReaction, error) {
+	row := q.db.QueryRowContext(ctx, upsertCommentReaction, arg.CommentID, arg.UserID, arg.Reaction)
+	var i CommentReaction
+	err := row.Scan(
+		&i.CommentID,
+		&i.UserID,
+		&i.Reaction,
+		&i.CreatedAt,
+		&i.UpdatedAt,
 	)
-	var version int32
-	err := row.Scan(&version)
-	return version, err
+	return i, err
 }
@@ -15,7 +15,8 @@ WITH user_activities AS (
     u.id AS user_id, 
     COUNT(DISTINCT ff.feed_id) AS followed_feeds,
     COUNT(DISTINCT f.id) AS created_feeds,
-    COUNT(DISTINCT c.id) AS comments_today
+    COUNT(DISTINCT c.id) AS comments_today,
+    (SELECT COUNT(*) FROM saved_searches ss WHERE ss.user_id = u.id) AS saved_searches
   FROM
     users u
   LEFT JOIN
@@ -30,7 +31,7 @@ WITH user_activities AS (
     u.id
 )
 
-SELECT user_id, followed_feeds, created_feeds, comments_today FROM user_activities
+SELECT user_id, followed_feeds, created_feeds, comments_today, saved_searches FROM user_activities
 `
 
 type GetUserLimitationsRow struct {
@@ -38,6 +39,7 @@ type GetUserLimitationsRow struct {
 	FollowedFeeds int64
 	CreatedFeeds  int64
 	CommentsToday int64
+	SavedSearches int64
 }
 
 func (q *Queries) GetUserLimitations(ctx context.Context, id int64) (GetUserLimitationsRow, error) {
@@ -48,6 +50,7 @@ func (q *Queries) GetUserLimitations(ctx context.Context, id int64) (GetUserLimi
 		&i.FollowedFeeds,
 		&i.CreatedFeeds,
 		&i.CommentsToday,
+		&i.SavedSearches,
 	)
 	return i, err
 }
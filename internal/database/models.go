@@ -37,6 +37,44 @@ type ApiKey struct {
 	Scope  string
 }
 
+type BillingDetail struct {
+	UserID    int64
+	Name      string
+	Company   string
+	Address   string
+	Country   string
+	TaxID     string
+	UpdatedAt time.Time
+	Version   int32
+}
+
+type BillingRun struct {
+	ID               int64
+	Trigger          string
+	Status           string
+	Processed        int32
+	Renewed          int32
+	RetriesScheduled int32
+	Challenged       int32
+	Expired          int32
+	Errors           int32
+	ErrorMessage     sql.NullString
+	StartedAt        time.Time
+	FinishedAt       sql.NullTime
+}
+
+type BillingRunItem struct {
+	ID             int64
+	RunID          int64
+	SubscriptionID uuid.UUID
+	UserID         int64
+	Attempt        int32
+	Outcome        string
+	Message        string
+	NextAttemptAt  sql.NullTime
+	CreatedAt      time.Time
+}
+
 type ChallengedTransaction struct {
 	ID                       int64
 	UserID                   int64
@@ -57,6 +95,25 @@ type Comment struct {
 	CreatedAt       time.Time
 	UpdatedAt       time.Time
 	Version         int32
+	Status          string
+	EditedAt        sql.NullTime
+}
+
+type CommentMention struct {
+	CommentID uuid.UUID
+	UserID    int64
+	Handle    string
+	CreatedAt time.Time
+}
+
+type CommentModerationAction struct {
+	ID            int64
+	CommentID     uuid.NullUUID
+	CommentUserID sql.NullInt64
+	Action        string
+	Reason        string
+	ActedBy       sql.NullInt64
+	CreatedAt     time.Time
 }
 
 type CommentNotification struct {
@@ -67,6 +124,85 @@ type CommentNotification struct {
 	CreatedAt time.Time
 }
 
+type CommentReaction struct {
+	CommentID uuid.UUID
+	UserID    int64
+	Reaction  string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type CommentReport struct {
+	ID         int64
+	CommentID  uuid.UUID
+	ReportedBy int64
+	Reason     string
+	Details    string
+	Status     string
+	ResolvedBy sql.NullInt64
+	ResolvedAt sql.NullTime
+	CreatedAt  time.Time
+}
+
+type CommentRestriction struct {
+	UserID       int64
+	Restriction  string
+	Reason       string
+	ExpiresAt    sql.NullTime
+	RestrictedBy sql.NullInt64
+	CreatedAt    time.Time
+}
+
+type CommentRevision struct {
+	ID          int64
+	CommentID   uuid.UUID
+	Version     int32
+	CommentText string
+	CreatedAt   time.Time
+	ReplacedAt  time.Time
+}
+
+type CommentScreening struct {
+	CommentID       uuid.UUID
+	Reasons         []string
+	ClassifierScore float64
+	CreatedAt       time.Time
+}
+
+type Coupon struct {
+	ID                 int64
+	Code               string
+	Description        string
+	DiscountType       string
+	PercentOff         sql.NullInt32
+	AmountOff          sql.NullInt64
+	Currency           sql.NullString
+	MaxRedemptions     sql.NullInt32
+	TimesRedeemed      int32
+	PendingRedemptions int32
+	ExpiresAt          sql.NullTime
+	PlanIds            []int32
+	Status             string
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+	Version            int32
+}
+
+type CouponRedemption struct {
+	ID             int64
+	CouponID       int64
+	UserID         int64
+	PlanID         int32
+	SubscriptionID uuid.NullUUID
+	Reference      sql.NullString
+	Discount       int64
+	Currency       string
+	Status         string
+	CreatedAt      time.Time
+	RedeemedAt     sql.NullTime
+	ExpiresAt      sql.NullTime
+}
+
 type FailedTransaction struct {
 	ID                int64
 	UserID            int64
@@ -74,7 +210,7 @@ type FailedTransaction struct {
 	AttemptDate       time.Time
 	AuthorizationCode sql.NullString
 	Reference         string
-	Amount            string
+	Amount            int64
 	FailureReason     sql.NullString
 	ErrorCode         sql.NullString
 	CardLast4         sql.NullString
@@ -83,6 +219,20 @@ type FailedTransaction struct {
 	CardType          sql.NullString
 	CreatedAt         time.Time
 	UpdatedAt         time.Time
+	Currency          sql.NullString
+}
+
+type DigestPreference struct {
+	UserID           int64
+	Frequency        string
+	Timezone         string
+	SendHour         int32
+	Weekday          int32
+	Enabled          bool
+	UnsubscribeToken string
+	LastSentAt       sql.NullTime
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
 }
 
 type Feed struct {
@@ -118,6 +268,47 @@ type FeedRejection struct {
 	Reason     string
 }
 
+type Invoice struct {
+	ID             int64
+	Number         int64
+	UserID         int64
+	SubscriptionID uuid.NullUUID
+	PlanID         sql.NullInt32
+	TransactionID  int64
+	Provider       string
+	PaymentMethod  string
+	Currency       string
+	Subtotal       int64
+	Discount       int64
+	Tax            int64
+	TaxRate        int32
+	TaxName        string
+	Total          int64
+	BillingName    string
+	BillingEmail   string
+	BillingCompany string
+	BillingAddress string
+	BillingCountry string
+	BillingTaxID   string
+	Status         string
+	IssuedAt       time.Time
+}
+
+type InvoiceLineItem struct {
+	ID          int64
+	InvoiceID   int64
+	Position    int32
+	Description string
+	Quantity    int32
+	UnitAmount  int64
+	Amount      int64
+}
+
+type InvoiceNumber struct {
+	ID         bool
+	LastNumber int64
+}
+
 type Notification struct {
 	ID        int32
 	FeedID    uuid.UUID
@@ -126,18 +317,111 @@ type Notification struct {
 	CreatedAt time.Time
 }
 
+type Organization struct {
+	ID        int64
+	Name      string
+	OwnerID   int64
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Version   int32
+}
+
+type OrganizationFeed struct {
+	OrganizationID int64
+	FeedID         uuid.UUID
+	FolderID       sql.NullInt64
+	SharedBy       sql.NullInt64
+	CreatedAt      time.Time
+}
+
+type OrganizationFolder struct {
+	ID             int64
+	OrganizationID int64
+	Name           string
+	CreatedBy      sql.NullInt64
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	Version        int32
+}
+
+type OrganizationInvitation struct {
+	ID             int64
+	OrganizationID int64
+	Email          string
+	Role           string
+	TokenHash      []byte
+	InvitedBy      sql.NullInt64
+	ExpiresAt      time.Time
+	CreatedAt      time.Time
+}
+
+type OrganizationMember struct {
+	OrganizationID int64
+	UserID         int64
+	Role           string
+	JoinedAt       time.Time
+}
+
+type OutboundFeed struct {
+	ID             uuid.UUID
+	TokenHash      []byte
+	UserID         int64
+	Source         string
+	FeedID         uuid.NullUUID
+	SavedSearchID  uuid.NullUUID
+	CreatedAt      time.Time
+	LastAccessedAt sql.NullTime
+}
+
+type PaymentIntent struct {
+	Reference            string
+	UserID               int64
+	PlanID               int32
+	Amount               int64
+	RenewsSubscriptionID uuid.NullUUID
+	CreatedAt            time.Time
+	Provider             string
+}
+
 type PaymentPlan struct {
 	ID          int32
 	Name        string
 	Image       string
 	Description sql.NullString
 	Duration    string
-	Price       string
+	Price       int64
 	Features    []string
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
 	Status      string
 	Version     int32
+	Currency    string
+	TrialDays   int32
+	Seats       int32
+}
+
+type PaymentPlanPrice struct {
+	PlanID    int32
+	Currency  string
+	Amount    int64
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type PaymentWebhookEvent struct {
+	EventID    string
+	Provider   string
+	EventType  string
+	ReceivedAt time.Time
+}
+
+type PlanEntitlement struct {
+	PlanID    int32
+	Name      string
+	Quota     sql.NullInt64
+	Enabled   bool
+	CreatedAt time.Time
+	UpdatedAt time.Time
 }
 
 type Permission struct {
@@ -145,6 +429,29 @@ type Permission struct {
 	Code string
 }
 
+type PostShare struct {
+	ID           uuid.UUID
+	PostID       uuid.UUID
+	UserID       int64
+	ShareType    string
+	Note         string
+	TokenHash    []byte
+	ViewCount    int64
+	CreatedAt    time.Time
+	LastViewedAt sql.NullTime
+}
+
+type PostShareCount struct {
+	PostID       uuid.UUID
+	DirectShares int64
+	LinkShares   int64
+}
+
+type PostShareRecipient struct {
+	ShareID     uuid.UUID
+	RecipientID int64
+}
+
 type Postfavorite struct {
 	ID        int64
 	PostID    uuid.UUID
@@ -153,6 +460,32 @@ type Postfavorite struct {
 	CreatedAt time.Time
 }
 
+type ProfileSetting struct {
+	UserID         int64
+	IsPrivate      bool
+	ShareFavorites bool
+	UpdatedAt      time.Time
+}
+
+type Refund struct {
+	ID               int64
+	SubscriptionID   uuid.UUID
+	UserID           int64
+	TransactionID    int64
+	InvoiceID        sql.NullInt64
+	Provider         string
+	ProviderRefundID string
+	Amount           int64
+	Currency         string
+	Reason           string
+	Status           string
+	FailureReason    string
+	EndedAccess      bool
+	RefundedBy       sql.NullInt64
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
 type RssfeedPost struct {
 	ID                 uuid.UUID
 	CreatedAt          time.Time
@@ -170,6 +503,29 @@ type RssfeedPost struct {
 	Itemcontent        sql.NullString
 }
 
+type SavedSearch struct {
+	ID           uuid.UUID
+	UserID       int64
+	Name         string
+	Query        string
+	FeedID       uuid.NullUUID
+	StartDate    sql.NullTime
+	EndDate      sql.NullTime
+	Notify       bool
+	LastViewedAt time.Time
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Version      int32
+}
+
+type SavedSearchNotification struct {
+	ID            int32
+	SavedSearchID uuid.UUID
+	UserID        int64
+	PostCount     int32
+	CreatedAt     time.Time
+}
+
 type ScraperErrorLog struct {
 	ID              int32
 	ErrorType       string
@@ -188,21 +544,46 @@ type ScraperErrorLog struct {
 }
 
 type Subscription struct {
-	ID                uuid.UUID
+	ID                 uuid.UUID
+	UserID             int64
+	PlanID             int32
+	StartDate          time.Time
+	EndDate            time.Time
+	Price              int64
+	Status             string
+	TransactionID      int64
+	PaymentMethod      sql.NullString
+	AuthorizationCode  sql.NullString
+	CardLast4          sql.NullString
+	CardExpMonth       sql.NullString
+	CardExpYear        sql.NullString
+	CardType           sql.NullString
+	Currency           string
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+	Provider           string
+	ProviderReference  sql.NullString
+	RenewalAttempts    int32
+	NextRenewalAt      sql.NullTime
+	RenewalLockedUntil sql.NullTime
+	IsTrial            bool
+	AmountRefunded     int64
+}
+
+type SubscriptionPlanChange struct {
+	ID                int64
 	UserID            int64
-	PlanID            int32
-	StartDate         time.Time
-	EndDate           time.Time
-	Price             string
+	SubscriptionID    uuid.UUID
+	NewSubscriptionID uuid.NullUUID
+	FromPlanID        int32
+	ToPlanID          int32
+	ChangeType        string
 	Status            string
-	TransactionID     int64
-	PaymentMethod     sql.NullString
-	AuthorizationCode sql.NullString
-	CardLast4         sql.NullString
-	CardExpMonth      sql.NullString
-	CardExpYear       sql.NullString
-	CardType          sql.NullString
+	Credit            int64
+	AmountCharged     int64
 	Currency          sql.NullString
+	Reference         sql.NullString
+	EffectiveAt       time.Time
 	CreatedAt         time.Time
 	UpdatedAt         time.Time
 }
@@ -216,6 +597,27 @@ type User struct {
 	Activated    bool
 	Version      int32
 	UserImg      string
+	Handle       string
+}
+
+type UserFollow struct {
+	FollowerID int64
+	FollowedID int64
+	Status     string
+	CreatedAt  time.Time
+}
+
+type UserNotification struct {
+	ID               int64
+	UserID           int64
+	NotificationType string
+	Message          string
+	FeedID           uuid.NullUUID
+	PostID           uuid.NullUUID
+	CommentID        uuid.NullUUID
+	ReadAt           sql.NullTime
+	DismissedAt      sql.NullTime
+	CreatedAt        time.Time
 }
 
 type UsersPermission struct {
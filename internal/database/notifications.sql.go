@@ -102,6 +102,71 @@ func (q *Queries) FetchAndStoreNotifications(ctx context.Context, dollar_1 inter
 	return items, nil
 }
 
+const getCommentNotificationRecipients = `-- name: GetCommentNotificationRecipients :many
+SELECT pf.user_id FROM postfavorites pf  -- users who favorited the post
+WHERE pf.post_id = $1 AND pf.user_id <> $3
+UNION
+SELECT c.user_id FROM comments c  -- the author of the comment being replied to
+WHERE c.id = $2 AND c.user_id <> $3
+`
+
+type GetCommentNotificationRecipientsParams struct {
+	PostID uuid.UUID
+	ID     uuid.UUID
+	UserID int64
+}
+
+func (q *Queries) GetCommentNotificationRecipients(ctx context.Context, arg GetCommentNotificationRecipientsParams) ([]int64, error) {
+	rows, err := q.db.QueryContext(ctx, getCommentNotificationRecipients, arg.PostID, arg.ID, arg.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int64
+	for rows.Next() {
+		var user_id int64
+		if err := rows.Scan(&user_id); err != nil {
+			return nil, err
+		}
+		items = append(items, user_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFeedFollowerIDs = `-- name: GetFeedFollowerIDs :many
+SELECT user_id FROM feed_follows
+WHERE feed_id = $1
+`
+
+func (q *Queries) GetFeedFollowerIDs(ctx context.Context, feedID uuid.UUID) ([]int64, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedFollowerIDs, feedID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int64
+	for rows.Next() {
+		var user_id int64
+		if err := rows.Scan(&user_id); err != nil {
+			return nil, err
+		}
+		items = append(items, user_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getUserCommentNotifications = `-- name: GetUserCommentNotifications :many
 (
     -- Comment Notifications for Favorited Posts
@@ -121,6 +186,7 @@ const getUserCommentNotifications = `-- name: GetUserCommentNotifications :many
     INNER JOIN
         rssfeed_posts rp ON c.post_id = rp.id
     WHERE
+        c.status = 'visible' AND
         c.post_id IN (
             SELECT pf.post_id
             FROM postfavorites pf
@@ -146,12 +212,39 @@ UNION ALL
     INNER JOIN
         rssfeed_posts rp ON c.post_id = rp.id
     WHERE
+        c.status = 'visible' AND
         c.parent_comment_id IN (
             SELECT id
             FROM comments
             WHERE user_id = $1
         )
 )
+UNION ALL
+(
+    -- Mention Notifications
+    SELECT
+        cn.id AS notification_id,
+        cn.post_id AS post_id,
+        'You were mentioned' AS notification_type,
+        c.id AS comment_id,
+        COALESCE(c.parent_comment_id, '00000000-0000-0000-0000-000000000000') AS replied_comment_id,
+        LEFT(c.comment_text, 20) AS comment_snippet,
+        rp.itemtitle AS post_title,
+        cn.created_at
+    FROM
+        comment_notifications cn
+    INNER JOIN
+        comments c ON cn.comment_id = c.id
+    INNER JOIN
+        rssfeed_posts rp ON c.post_id = rp.id
+    WHERE
+        c.status = 'visible' AND
+        c.id IN (
+            SELECT cm.comment_id
+            FROM comment_mentions cm
+            WHERE cm.user_id = $1
+        )
+)
 ORDER BY
     created_at DESC
 `
@@ -64,8 +64,9 @@ INSERT INTO failed_transactions (
     card_last4, 
     card_exp_month, 
     card_exp_year, 
-    card_type
-) VALUES ($1, $2, NOW(), $3, $4, $5, $6, $7, $8, $9, $10, $11)
+    card_type,
+    currency
+) VALUES ($1, $2, NOW(), $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 RETURNING id, created_at, updated_at
 `
 
@@ -74,13 +75,14 @@ type CreateFailedTransactionParams struct {
 	SubscriptionID    uuid.UUID
 	AuthorizationCode sql.NullString
 	Reference         string
-	Amount            string
+	Amount            int64
 	FailureReason     sql.NullString
 	ErrorCode         sql.NullString
 	CardLast4         sql.NullString
 	CardExpMonth      sql.NullString
 	CardExpYear       sql.NullString
 	CardType          sql.NullString
+	Currency          sql.NullString
 }
 
 type CreateFailedTransactionRow struct {
@@ -102,6 +104,7 @@ func (q *Queries) CreateFailedTransaction(ctx context.Context, arg CreateFailedT
 		arg.CardExpMonth,
 		arg.CardExpYear,
 		arg.CardType,
+		arg.Currency,
 	)
 	var i CreateFailedTransactionRow
 	err := row.Scan(&i.ID, &i.CreatedAt, &i.UpdatedAt)
@@ -112,9 +115,10 @@ const createSubscription = `-- name: CreateSubscription :one
 INSERT INTO subscriptions (
 		user_id, plan_id, start_date, end_date, price, status, 
 		transaction_id, payment_method, authorization_code, 
-		card_last4, card_exp_month, card_exp_year, card_type, currency
+		card_last4, card_exp_month, card_exp_year, card_type, currency,
+		provider, provider_reference
 ) VALUES (
-	$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14
+	$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16
 )
 RETURNING id, created_at, updated_at
 `
@@ -124,7 +128,7 @@ type CreateSubscriptionParams struct {
 	PlanID            int32
 	StartDate         time.Time
 	EndDate           time.Time
-	Price             string
+	Price             int64
 	Status            string
 	TransactionID     int64
 	PaymentMethod     sql.NullString
@@ -133,7 +137,9 @@ type CreateSubscriptionParams struct {
 	CardExpMonth      sql.NullString
 	CardExpYear       sql.NullString
 	CardType          sql.NullString
-	Currency          sql.NullString
+	Currency          string
+	Provider          string
+	ProviderReference sql.NullString
 }
 
 type CreateSubscriptionRow struct {
@@ -158,12 +164,54 @@ func (q *Queries) CreateSubscription(ctx context.Context, arg CreateSubscription
 		arg.CardExpYear,
 		arg.CardType,
 		arg.Currency,
+		arg.Provider,
+		arg.ProviderReference,
 	)
 	var i CreateSubscriptionRow
 	err := row.Scan(&i.ID, &i.CreatedAt, &i.UpdatedAt)
 	return i, err
 }
 
+const createTrialSubscription = `-- name: CreateTrialSubscription :one
+INSERT INTO subscriptions (
+    user_id, plan_id, start_date, end_date, price, status, transaction_id, currency, provider, is_trial
+) VALUES (
+    $1, $2, $3, $4, $5, 'active', 0, $6, $7, TRUE
+)
+RETURNING id, created_at, updated_at
+`
+
+type CreateTrialSubscriptionParams struct {
+	UserID    int64
+	PlanID    int32
+	StartDate time.Time
+	EndDate   time.Time
+	Price     int64
+	Currency  string
+	Provider  string
+}
+
+type CreateTrialSubscriptionRow struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (q *Queries) CreateTrialSubscription(ctx context.Context, arg CreateTrialSubscriptionParams) (CreateTrialSubscriptionRow, error) {
+	row := q.db.QueryRowContext(ctx, createTrialSubscription,
+		arg.UserID,
+		arg.PlanID,
+		arg.StartDate,
+		arg.EndDate,
+		arg.Price,
+		arg.Currency,
+		arg.Provider,
+	)
+	var i CreateTrialSubscriptionRow
+	err := row.Scan(&i.ID, &i.CreatedAt, &i.UpdatedAt)
+	return i, err
+}
+
 const getActiveOrNonExpiredSubscriptionByID = `-- name: GetActiveOrNonExpiredSubscriptionByID :one
 SELECT 
     s.id, 
@@ -191,7 +239,7 @@ JOIN
     payment_plans p ON s.plan_id = p.id
 WHERE 
     s.user_id = $1
-    AND s.status IN ('active', 'cancelled')
+    AND s.status IN ('active', 'past_due', 'cancelled')
     AND (s.status != 'cancelled' OR s.end_date > now())  -- Only include 'cancelled' if end_date is in the future
 ORDER BY 
     s.start_date DESC
@@ -207,7 +255,7 @@ type GetActiveOrNonExpiredSubscriptionByIDRow struct {
 	PlanDuration  string
 	StartDate     time.Time
 	EndDate       time.Time
-	Price         string
+	Price         int64
 	Status        string
 	TransactionID int64
 	PaymentMethod sql.NullString
@@ -215,7 +263,7 @@ type GetActiveOrNonExpiredSubscriptionByIDRow struct {
 	CardExpMonth  sql.NullString
 	CardExpYear   sql.NullString
 	CardType      sql.NullString
-	Currency      sql.NullString
+	Currency      string
 	CreatedAt     time.Time
 	UpdatedAt     time.Time
 }
@@ -247,77 +295,6 @@ func (q *Queries) GetActiveOrNonExpiredSubscriptionByID(ctx context.Context, use
 	return i, err
 }
 
-const getAllExpiredSubscriptions = `-- name: GetAllExpiredSubscriptions :many
-SELECT count(*) OVER() as total_records,
-    s.id AS subscription_id,
-    s.authorization_code,
-    s.plan_id,
-    s.price,
-    s.currency,
-    s.user_id,
-    u.email,
-    u.name
-FROM 
-    subscriptions s
-JOIN 
-    users u ON s.user_id = u.id
-WHERE 
-    s.status = 'expired' AND s.end_date < NOW()
-ORDER BY 
-    s.end_date ASC
-LIMIT $1 OFFSET $2
-`
-
-type GetAllExpiredSubscriptionsParams struct {
-	Limit  int32
-	Offset int32
-}
-
-type GetAllExpiredSubscriptionsRow struct {
-	TotalRecords      int64
-	SubscriptionID    uuid.UUID
-	AuthorizationCode sql.NullString
-	PlanID            int32
-	Price             string
-	Currency          sql.NullString
-	UserID            int64
-	Email             string
-	Name              string
-}
-
-func (q *Queries) GetAllExpiredSubscriptions(ctx context.Context, arg GetAllExpiredSubscriptionsParams) ([]GetAllExpiredSubscriptionsRow, error) {
-	rows, err := q.db.QueryContext(ctx, getAllExpiredSubscriptions, arg.Limit, arg.Offset)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var items []GetAllExpiredSubscriptionsRow
-	for rows.Next() {
-		var i GetAllExpiredSubscriptionsRow
-		if err := rows.Scan(
-			&i.TotalRecords,
-			&i.SubscriptionID,
-			&i.AuthorizationCode,
-			&i.PlanID,
-			&i.Price,
-			&i.Currency,
-			&i.UserID,
-			&i.Email,
-			&i.Name,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
-}
-
 const getAllSubscriptionsByID = `-- name: GetAllSubscriptionsByID :many
 SELECT 
     count(*) OVER() as total_records,
@@ -367,7 +344,7 @@ type GetAllSubscriptionsByIDRow struct {
 	PlanDuration  string
 	StartDate     time.Time
 	EndDate       time.Time
-	Price         string
+	Price         int64
 	Status        string
 	TransactionID int64
 	PaymentMethod sql.NullString
@@ -375,9 +352,10 @@ type GetAllSubscriptionsByIDRow struct {
 	CardExpMonth  sql.NullString
 	CardExpYear   sql.NullString
 	CardType      sql.NullString
-	Currency      sql.NullString
+	Currency      string
 	CreatedAt     time.Time
 	UpdatedAt     time.Time
+	IsTrial       bool
 }
 
 func (q *Queries) GetAllSubscriptionsByID(ctx context.Context, arg GetAllSubscriptionsByIDParams) ([]GetAllSubscriptionsByIDRow, error) {
@@ -410,6 +388,7 @@ func (q *Queries) GetAllSubscriptionsByID(ctx context.Context, arg GetAllSubscri
 			&i.Currency,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.IsTrial,
 		); err != nil {
 			return nil, err
 		}
@@ -425,7 +404,7 @@ func (q *Queries) GetAllSubscriptionsByID(ctx context.Context, arg GetAllSubscri
 }
 
 const getPaymentPlanByID = `-- name: GetPaymentPlanByID :one
-SELECT id, name, image, description, duration, price, features, created_at, updated_at, status
+SELECT id, name, image, description, duration, price, currency, features, created_at, updated_at, status, trial_days, seats
 FROM payment_plans
 WHERE id = $1 AND status = 'active'
 `
@@ -436,11 +415,14 @@ type GetPaymentPlanByIDRow struct {
 	Image       string
 	Description sql.NullString
 	Duration    string
-	Price       string
+	Price       int64
+	Currency    string
 	Features    []string
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
 	Status      string
+	TrialDays   int32
+	Seats       int32
 }
 
 func (q *Queries) GetPaymentPlanByID(ctx context.Context, id int32) (GetPaymentPlanByIDRow, error) {
@@ -453,16 +435,19 @@ func (q *Queries) GetPaymentPlanByID(ctx context.Context, id int32) (GetPaymentP
 		&i.Description,
 		&i.Duration,
 		&i.Price,
+		&i.Currency,
 		pq.Array(&i.Features),
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Status,
+		&i.TrialDays,
+		&i.Seats,
 	)
 	return i, err
 }
 
 const getPaymentPlans = `-- name: GetPaymentPlans :many
-SELECT id, name, image, description, duration, price, features, created_at, updated_at, status
+SELECT id, name, image, description, duration, price, currency, features, created_at, updated_at, status, trial_days, seats
 FROM payment_plans
 WHERE status = 'active'
 ORDER BY price
@@ -474,11 +459,14 @@ type GetPaymentPlansRow struct {
 	Image       string
 	Description sql.NullString
 	Duration    string
-	Price       string
+	Price       int64
+	Currency    string
 	Features    []string
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
 	Status      string
+	TrialDays   int32
+	Seats       int32
 }
 
 func (q *Queries) GetPaymentPlans(ctx context.Context) ([]GetPaymentPlansRow, error) {
@@ -497,10 +485,13 @@ func (q *Queries) GetPaymentPlans(ctx context.Context) ([]GetPaymentPlansRow, er
 			&i.Description,
 			&i.Duration,
 			&i.Price,
+			&i.Currency,
 			pq.Array(&i.Features),
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.Status,
+			&i.TrialDays,
+			&i.Seats,
 		); err != nil {
 			return nil, err
 		}
@@ -631,7 +622,7 @@ func (q *Queries) GetPendingChallengedTransactionsByUser(ctx context.Context, us
 }
 
 const getSubscriptionByID = `-- name: GetSubscriptionByID :one
-SELECT id, user_id, plan_id, start_date, end_date, price, status
+SELECT id, user_id, plan_id, start_date, end_date, price, currency, status, is_trial
 FROM subscriptions
 WHERE user_id = $1 AND status = 'active' AND end_date > NOW()
 `
@@ -642,8 +633,10 @@ type GetSubscriptionByIDRow struct {
 	PlanID    int32
 	StartDate time.Time
 	EndDate   time.Time
-	Price     string
+	Price     int64
+	Currency  string
 	Status    string
+	IsTrial   bool
 }
 
 func (q *Queries) GetSubscriptionByID(ctx context.Context, userID int64) (GetSubscriptionByIDRow, error) {
@@ -656,11 +649,26 @@ func (q *Queries) GetSubscriptionByID(ctx context.Context, userID int64) (GetSub
 		&i.StartDate,
 		&i.EndDate,
 		&i.Price,
+		&i.Currency,
 		&i.Status,
+		&i.IsTrial,
 	)
 	return i, err
 }
 
+const hasUserHadTrial = `-- name: HasUserHadTrial :one
+SELECT EXISTS (
+    SELECT 1 FROM subscriptions WHERE user_id = $1 AND is_trial
+)
+`
+
+func (q *Queries) HasUserHadTrial(ctx context.Context, userID int64) (bool, error) {
+	row := q.db.QueryRowContext(ctx, hasUserHadTrial, userID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
 const updateChallengedTransactionStatus = `-- name: UpdateChallengedTransactionStatus :one
 UPDATE challenged_transactions
 SET status = $1, updated_at = NOW()
@@ -751,9 +759,9 @@ func (q *Queries) UpdateSubscriptionStatus(ctx context.Context, arg UpdateSubscr
 
 const updateSubscriptionStatusAfterExpiration = `-- name: UpdateSubscriptionStatusAfterExpiration :many
 UPDATE subscriptions
-SET status = 'expired', updated_at = NOW()
-WHERE end_date < CURRENT_DATE
-AND status NOT IN ('expired', 'renewed', 'cancelled')
+SET status = 'expired', next_renewal_at = NULL, updated_at = NOW()
+WHERE end_date < $1
+AND status = 'past_due'
 RETURNING id, user_id, updated_at
 `
 
@@ -763,8 +771,8 @@ type UpdateSubscriptionStatusAfterExpirationRow struct {
 	UpdatedAt time.Time
 }
 
-func (q *Queries) UpdateSubscriptionStatusAfterExpiration(ctx context.Context) ([]UpdateSubscriptionStatusAfterExpirationRow, error) {
-	rows, err := q.db.QueryContext(ctx, updateSubscriptionStatusAfterExpiration)
+func (q *Queries) UpdateSubscriptionStatusAfterExpiration(ctx context.Context, endDate time.Time) ([]UpdateSubscriptionStatusAfterExpirationRow, error) {
+	rows, err := q.db.QueryContext(ctx, updateSubscriptionStatusAfterExpiration, endDate)
 	if err != nil {
 		return nil, err
 	}
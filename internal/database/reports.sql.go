@@ -25,22 +25,12 @@ subscription_churn_rate AS (
     WHERE status = 'cancelled' AND end_date >= NOW() - INTERVAL '30 days'
 ),
 
-arpu AS (
-    SELECT COALESCE(AVG(price), 0.0) AS average_revenue_per_user
-    FROM subscriptions
-),
-
 average_subscription_duration AS (
     SELECT COALESCE(AVG(end_date - start_date), INTERVAL '0 seconds') AS average_duration
     FROM subscriptions
     WHERE status = 'cancelled'
 ),
 
-total_revenue AS (
-    SELECT COALESCE(SUM(price), 0) AS total_revenue
-    FROM subscriptions
-),
-
 most_popular_payment_method AS (
     SELECT 
         COALESCE(payment_method, '') AS payment_method
@@ -79,9 +69,7 @@ subscriptions_near_expiry AS (
 SELECT 
     (SELECT total_active_subscriptions FROM active_subscriptions) AS total_active_subscriptions,
     (SELECT churn_rate FROM subscription_churn_rate) AS churn_rate,
-    (SELECT average_revenue_per_user FROM arpu) AS average_revenue_per_user,
     (SELECT EXTRACT(EPOCH FROM average_duration) FROM average_subscription_duration) AS average_duration,
-    (SELECT total_revenue FROM total_revenue) AS total_revenue,
     (SELECT payment_method FROM most_popular_payment_method) AS most_popular_payment_method,
     (SELECT plan_id FROM most_subscribed_plan) AS most_subscribed_plan,
     (SELECT challenged_rate FROM challenged_transactions_rate) AS challenged_rate,
@@ -92,9 +80,7 @@ SELECT
 type AdminSubscriptionSingleReportsRow struct {
 	TotalActiveSubscriptions interface{}
 	ChurnRate                interface{}
-	AverageRevenuePerUser    interface{}
 	AverageDuration          string
-	TotalRevenue             interface{}
 	MostPopularPaymentMethod string
 	MostSubscribedPlan       int32
 	ChallengedRate           interface{}
@@ -104,9 +90,7 @@ type AdminSubscriptionSingleReportsRow struct {
 
 // Total Active Subscriptions
 // Subscription Churn Rate
-// Average Revenue Per User (ARPU)
 // Average Subscription Duration
-// Total Revenue
 // Most Popular Payment Method
 // Most Subscribed Plan
 // Challenged Transactions Rate
@@ -118,9 +102,7 @@ func (q *Queries) AdminSubscriptionSingleReports(ctx context.Context) (AdminSubs
 	err := row.Scan(
 		&i.TotalActiveSubscriptions,
 		&i.ChurnRate,
-		&i.AverageRevenuePerUser,
 		&i.AverageDuration,
-		&i.TotalRevenue,
 		&i.MostPopularPaymentMethod,
 		&i.MostSubscribedPlan,
 		&i.ChallengedRate,
@@ -184,17 +166,69 @@ func (q *Queries) NewVsCancelledSubscriptions(ctx context.Context) (NewVsCancell
 	return i, err
 }
 
+const revenueByCurrency = `-- name: RevenueByCurrency :many
+SELECT 
+    s.currency,
+    CAST(SUM(s.price - COALESCE(r.discount, 0) - COALESCE(rf.amount, 0)) AS BIGINT) AS total_revenue,
+    CAST(ROUND(AVG(s.price - COALESCE(r.discount, 0) - COALESCE(rf.amount, 0))) AS BIGINT) AS average_revenue_per_user
+FROM subscriptions s
+LEFT JOIN coupon_redemptions r ON r.subscription_id = s.id AND r.status = 'redeemed'
+LEFT JOIN (
+    SELECT subscription_id, SUM(amount) AS amount FROM refunds WHERE status <> 'failed' GROUP BY subscription_id
+) rf ON rf.subscription_id = s.id
+WHERE NOT s.is_trial
+GROUP BY s.currency
+ORDER BY s.currency
+`
+
+type RevenueByCurrencyRow struct {
+	Currency              string
+	TotalRevenue          int64
+	AverageRevenuePerUser int64
+}
+
+func (q *Queries) RevenueByCurrency(ctx context.Context) ([]RevenueByCurrencyRow, error) {
+	rows, err := q.db.QueryContext(ctx, revenueByCurrency)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RevenueByCurrencyRow
+	for rows.Next() {
+		var i RevenueByCurrencyRow
+		if err := rows.Scan(&i.Currency, &i.TotalRevenue, &i.AverageRevenuePerUser); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const revenueByPaymentMethod = `-- name: RevenueByPaymentMethod :many
 SELECT 
-    payment_method, 
-    COALESCE(SUM(price), 0) AS revenue
-FROM subscriptions
-GROUP BY payment_method
+    s.payment_method, 
+    s.currency,
+    CAST(SUM(s.price - COALESCE(r.discount, 0) - COALESCE(rf.amount, 0)) AS BIGINT) AS revenue
+FROM subscriptions s
+LEFT JOIN coupon_redemptions r ON r.subscription_id = s.id AND r.status = 'redeemed'
+LEFT JOIN (
+    SELECT subscription_id, SUM(amount) AS amount FROM refunds WHERE status <> 'failed' GROUP BY subscription_id
+) rf ON rf.subscription_id = s.id
+WHERE NOT s.is_trial
+GROUP BY s.payment_method, s.currency
+ORDER BY s.payment_method, s.currency
 `
 
 type RevenueByPaymentMethodRow struct {
 	PaymentMethod sql.NullString
-	Revenue       interface{}
+	Currency      string
+	Revenue       int64
 }
 
 func (q *Queries) RevenueByPaymentMethod(ctx context.Context) ([]RevenueByPaymentMethodRow, error) {
@@ -206,7 +240,7 @@ func (q *Queries) RevenueByPaymentMethod(ctx context.Context) ([]RevenueByPaymen
 	var items []RevenueByPaymentMethodRow
 	for rows.Next() {
 		var i RevenueByPaymentMethodRow
-		if err := rows.Scan(&i.PaymentMethod, &i.Revenue); err != nil {
+		if err := rows.Scan(&i.PaymentMethod, &i.Currency, &i.Revenue); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -223,14 +257,22 @@ func (q *Queries) RevenueByPaymentMethod(ctx context.Context) ([]RevenueByPaymen
 const revenueByPlan = `-- name: RevenueByPlan :many
 SELECT 
     pp.name AS plan_name, 
-    CAST(SUM(s.price) AS BIGINT) AS total_revenue
+    s.currency,
+    CAST(SUM(s.price - COALESCE(r.discount, 0) - COALESCE(rf.amount, 0)) AS BIGINT) AS total_revenue
 FROM subscriptions s
 JOIN payment_plans pp ON s.plan_id = pp.id
-GROUP BY pp.name
+LEFT JOIN coupon_redemptions r ON r.subscription_id = s.id AND r.status = 'redeemed'
+LEFT JOIN (
+    SELECT subscription_id, SUM(amount) AS amount FROM refunds WHERE status <> 'failed' GROUP BY subscription_id
+) rf ON rf.subscription_id = s.id
+WHERE NOT s.is_trial
+GROUP BY pp.name, s.currency
+ORDER BY pp.name, s.currency
 `
 
 type RevenueByPlanRow struct {
 	PlanName     string
+	Currency     string
 	TotalRevenue int64
 }
 
@@ -243,7 +285,7 @@ func (q *Queries) RevenueByPlan(ctx context.Context) ([]RevenueByPlanRow, error)
 	var items []RevenueByPlanRow
 	for rows.Next() {
 		var i RevenueByPlanRow
-		if err := rows.Scan(&i.PlanName, &i.TotalRevenue); err != nil {
+		if err := rows.Scan(&i.PlanName, &i.Currency, &i.TotalRevenue); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -266,7 +308,7 @@ GROUP BY currency
 `
 
 type SubscriptionsByCurrencyRow struct {
-	Currency      sql.NullString
+	Currency      string
 	CurrencyCount interface{}
 }
 
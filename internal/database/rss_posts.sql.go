@@ -165,15 +165,29 @@ LEFT JOIN (
 WHERE 
     ($2 = '' OR to_tsvector('simple', p.itemtitle) @@ plainto_tsquery('simple', $2))  -- Parameter 2: itemtitle (full-text search for item title)
     AND ($3::uuid = '00000000-0000-0000-0000-000000000000' OR p.feed_id = $3::uuid)  -- Parameter 3: feed_id (filter by feed_id if provided)
+    AND ($4::timestamptz = '0001-01-01 00:00:00+00' OR p.itempublished_at >= $4::timestamptz)  -- Parameter 4: since (published date)
+    AND ($5::timestamptz = '0001-01-01 00:00:00+00' OR p.itempublished_at <= $5::timestamptz)  -- Parameter 5: until (published date)
 ORDER BY 
-    p.created_at DESC
-LIMIT $4 OFFSET $5
+    CASE WHEN $6::text = 'published_at' THEN p.itempublished_at END ASC,  -- Parameter 6: sort
+    CASE WHEN $6::text = 'created_at' THEN p.created_at END ASC,
+    CASE WHEN $6::text = '-created_at' THEN p.created_at END DESC,
+    -- the counts are only taken when sorting by them, and only for the posts that matched
+    CASE WHEN $6::text = 'favorites_count' THEN (SELECT COUNT(*) FROM postfavorites c WHERE c.post_id = p.id) END ASC,
+    CASE WHEN $6::text = '-favorites_count' THEN (SELECT COUNT(*) FROM postfavorites c WHERE c.post_id = p.id) END DESC,
+    CASE WHEN $6::text = 'comments_count' THEN (SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id) END ASC,
+    CASE WHEN $6::text = '-comments_count' THEN (SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id) END DESC,
+    p.itempublished_at DESC,  -- default, -published_at
+    p.id DESC
+LIMIT $7 OFFSET $8
 `
 
 type GetFollowedRssPostsForUserParams struct {
 	UserID  int64
 	Column2 interface{}
 	Column3 uuid.UUID
+	Column4 time.Time
+	Column5 time.Time
+	Column6 string
 	Limit   int32
 	Offset  int32
 }
@@ -202,6 +216,9 @@ func (q *Queries) GetFollowedRssPostsForUser(ctx context.Context, arg GetFollowe
 		arg.UserID,
 		arg.Column2,
 		arg.Column3,
+		arg.Column4,
+		arg.Column5,
+		arg.Column6,
 		arg.Limit,
 		arg.Offset,
 	)
@@ -243,6 +260,125 @@ func (q *Queries) GetFollowedRssPostsForUser(ctx context.Context, arg GetFollowe
 	return items, nil
 }
 
+const getFollowedRssPostsForUserByCursor = `-- name: GetFollowedRssPostsForUserByCursor :many
+SELECT 
+    p.id, p.created_at, p.updated_at, p.channeltitle, p.channelurl, p.channeldescription, p.channellanguage, p.itemtitle, p.itemdescription, p.itempublished_at, p.itemurl, p.img_url, p.feed_id, p.itemcontent, 
+    COALESCE(pf.is_favorite, false) AS is_favorite
+FROM 
+    rssfeed_posts p
+JOIN (
+    SELECT 
+        ff.feed_id 
+    FROM 
+        feed_follows ff
+    WHERE 
+        ff.user_id = $1  -- Parameter 1: user_id
+) ff ON p.feed_id = ff.feed_id
+LEFT JOIN (
+    SELECT 
+        pf.post_id,
+        true AS is_favorite
+    FROM 
+        postfavorites pf
+    WHERE 
+        pf.user_id = $1  -- Parameter 1: user_id
+) pf ON p.id = pf.post_id
+WHERE 
+    ($2 = '' OR to_tsvector('simple', p.itemtitle) @@ plainto_tsquery('simple', $2))  -- Parameter 2: itemtitle (full-text search for item title)
+    AND ($3::uuid = '00000000-0000-0000-0000-000000000000' OR p.feed_id = $3::uuid)  -- Parameter 3: feed_id (filter by feed_id if provided)
+    AND ($8::timestamptz = '0001-01-01 00:00:00+00' OR p.itempublished_at >= $8::timestamptz)  -- Parameter 8: since (published date)
+    AND ($9::timestamptz = '0001-01-01 00:00:00+00' OR p.itempublished_at <= $9::timestamptz)  -- Parameter 9: until (published date)
+    AND (
+        ($6::boolean = false AND (p.itempublished_at, p.id) < ($4::timestamptz, $5::uuid))  -- Parameters 4 and 5: cursor keyset, older posts
+        OR ($6::boolean = true AND (p.itempublished_at, p.id) > ($4::timestamptz, $5::uuid))  -- Parameter 6: backward, newer posts
+    )
+ORDER BY 
+    CASE WHEN $6::boolean THEN p.itempublished_at END ASC,
+    CASE WHEN $6::boolean THEN p.id END ASC,
+    p.itempublished_at DESC,
+    p.id DESC
+LIMIT $7
+`
+
+type GetFollowedRssPostsForUserByCursorParams struct {
+	UserID  int64
+	Column2 interface{}
+	Column3 uuid.UUID
+	Column4 time.Time
+	Column5 uuid.UUID
+	Column6 bool
+	Limit   int32
+	Column8 time.Time
+	Column9 time.Time
+}
+
+type GetFollowedRssPostsForUserByCursorRow struct {
+	ID                 uuid.UUID
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+	Channeltitle       string
+	Channelurl         sql.NullString
+	Channeldescription sql.NullString
+	Channellanguage    sql.NullString
+	Itemtitle          string
+	Itemdescription    sql.NullString
+	ItempublishedAt    time.Time
+	Itemurl            string
+	ImgUrl             string
+	FeedID             uuid.UUID
+	Itemcontent        sql.NullString
+	IsFavorite         bool
+}
+
+func (q *Queries) GetFollowedRssPostsForUserByCursor(ctx context.Context, arg GetFollowedRssPostsForUserByCursorParams) ([]GetFollowedRssPostsForUserByCursorRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFollowedRssPostsForUserByCursor,
+		arg.UserID,
+		arg.Column2,
+		arg.Column3,
+		arg.Column4,
+		arg.Column5,
+		arg.Column6,
+		arg.Limit,
+		arg.Column8,
+		arg.Column9,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFollowedRssPostsForUserByCursorRow
+	for rows.Next() {
+		var i GetFollowedRssPostsForUserByCursorRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Channeltitle,
+			&i.Channelurl,
+			&i.Channeldescription,
+			&i.Channellanguage,
+			&i.Itemtitle,
+			&i.Itemdescription,
+			&i.ItempublishedAt,
+			&i.Itemurl,
+			&i.ImgUrl,
+			&i.FeedID,
+			&i.Itemcontent,
+			&i.IsFavorite,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getRSSFavoritePostsForUser = `-- name: GetRSSFavoritePostsForUser :many
 
 
@@ -310,15 +446,29 @@ WHERE
     f.user_id = $1  -- Parameter 1: user_id
     AND ($2 = '' OR to_tsvector('simple', p.itemtitle) @@ plainto_tsquery('simple', $2))  -- Parameter 2: itemtitle (full-text search for item title)
     AND ($3::uuid = '00000000-0000-0000-0000-000000000000' OR p.feed_id = $3::uuid)  -- Parameter 3: feed_id (filter by feed_id if provided)
+    AND ($4::timestamptz = '0001-01-01 00:00:00+00' OR p.itempublished_at >= $4::timestamptz)  -- Parameter 4: since (published date)
+    AND ($5::timestamptz = '0001-01-01 00:00:00+00' OR p.itempublished_at <= $5::timestamptz)  -- Parameter 5: until (published date)
 ORDER BY 
-    p.created_at DESC
-LIMIT $4 OFFSET $5
+    CASE WHEN $6::text = 'published_at' THEN p.itempublished_at END ASC,  -- Parameter 6: sort
+    CASE WHEN $6::text = 'created_at' THEN p.created_at END ASC,
+    CASE WHEN $6::text = '-created_at' THEN p.created_at END DESC,
+    -- the counts are only taken when sorting by them, and only for the posts that matched
+    CASE WHEN $6::text = 'favorites_count' THEN (SELECT COUNT(*) FROM postfavorites c WHERE c.post_id = p.id) END ASC,
+    CASE WHEN $6::text = '-favorites_count' THEN (SELECT COUNT(*) FROM postfavorites c WHERE c.post_id = p.id) END DESC,
+    CASE WHEN $6::text = 'comments_count' THEN (SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id) END ASC,
+    CASE WHEN $6::text = '-comments_count' THEN (SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id) END DESC,
+    p.itempublished_at DESC,  -- default, -published_at
+    p.id DESC
+LIMIT $7 OFFSET $8
 `
 
 type GetRSSFavoritePostsOnlyForUserParams struct {
 	UserID  int64
 	Column2 interface{}
 	Column3 uuid.UUID
+	Column4 time.Time
+	Column5 time.Time
+	Column6 string
 	Limit   int32
 	Offset  int32
 }
@@ -348,6 +498,9 @@ func (q *Queries) GetRSSFavoritePostsOnlyForUser(ctx context.Context, arg GetRSS
 		arg.UserID,
 		arg.Column2,
 		arg.Column3,
+		arg.Column4,
+		arg.Column5,
+		arg.Column6,
 		arg.Limit,
 		arg.Offset,
 	)
@@ -390,6 +543,129 @@ func (q *Queries) GetRSSFavoritePostsOnlyForUser(ctx context.Context, arg GetRSS
 	return items, nil
 }
 
+const getRSSFavoritePostsOnlyForUserByCursor = `-- name: GetRSSFavoritePostsOnlyForUserByCursor :many
+SELECT 
+    p.id,
+    p.created_at,
+    p.updated_at,
+    p.channeltitle,
+    p.channelurl,
+    p.channeldescription,
+    p.channellanguage,
+    p.itemtitle,
+    p.itemdescription,
+    p.itemcontent,
+    p.itempublished_at,
+    p.itemurl,
+    p.img_url,
+    p.feed_id,
+    true AS is_favorite,
+    COALESCE(ff.user_id IS NOT NULL, false) AS is_followed_feed
+FROM 
+    rssfeed_posts p
+JOIN 
+    postfavorites f ON p.id = f.post_id
+LEFT JOIN
+    feed_follows ff ON p.feed_id = ff.feed_id AND ff.user_id = $1
+WHERE 
+    f.user_id = $1  -- Parameter 1: user_id
+    AND ($2 = '' OR to_tsvector('simple', p.itemtitle) @@ plainto_tsquery('simple', $2))  -- Parameter 2: itemtitle (full-text search for item title)
+    AND ($3::uuid = '00000000-0000-0000-0000-000000000000' OR p.feed_id = $3::uuid)  -- Parameter 3: feed_id (filter by feed_id if provided)
+    AND ($8::timestamptz = '0001-01-01 00:00:00+00' OR p.itempublished_at >= $8::timestamptz)  -- Parameter 8: since (published date)
+    AND ($9::timestamptz = '0001-01-01 00:00:00+00' OR p.itempublished_at <= $9::timestamptz)  -- Parameter 9: until (published date)
+    AND (
+        ($6::boolean = false AND (p.itempublished_at, p.id) < ($4::timestamptz, $5::uuid))  -- Parameters 4 and 5: cursor keyset, older posts
+        OR ($6::boolean = true AND (p.itempublished_at, p.id) > ($4::timestamptz, $5::uuid))  -- Parameter 6: backward, newer posts
+    )
+ORDER BY 
+    CASE WHEN $6::boolean THEN p.itempublished_at END ASC,
+    CASE WHEN $6::boolean THEN p.id END ASC,
+    p.itempublished_at DESC,
+    p.id DESC
+LIMIT $7
+`
+
+type GetRSSFavoritePostsOnlyForUserByCursorParams struct {
+	UserID  int64
+	Column2 interface{}
+	Column3 uuid.UUID
+	Column4 time.Time
+	Column5 uuid.UUID
+	Column6 bool
+	Limit   int32
+	Column8 time.Time
+	Column9 time.Time
+}
+
+type GetRSSFavoritePostsOnlyForUserByCursorRow struct {
+	ID                 uuid.UUID
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+	Channeltitle       string
+	Channelurl         sql.NullString
+	Channeldescription sql.NullString
+	Channellanguage    sql.NullString
+	Itemtitle          string
+	Itemdescription    sql.NullString
+	Itemcontent        sql.NullString
+	ItempublishedAt    time.Time
+	Itemurl            string
+	ImgUrl             string
+	FeedID             uuid.UUID
+	IsFavorite         bool
+	IsFollowedFeed     interface{}
+}
+
+func (q *Queries) GetRSSFavoritePostsOnlyForUserByCursor(ctx context.Context, arg GetRSSFavoritePostsOnlyForUserByCursorParams) ([]GetRSSFavoritePostsOnlyForUserByCursorRow, error) {
+	rows, err := q.db.QueryContext(ctx, getRSSFavoritePostsOnlyForUserByCursor,
+		arg.UserID,
+		arg.Column2,
+		arg.Column3,
+		arg.Column4,
+		arg.Column5,
+		arg.Column6,
+		arg.Limit,
+		arg.Column8,
+		arg.Column9,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRSSFavoritePostsOnlyForUserByCursorRow
+	for rows.Next() {
+		var i GetRSSFavoritePostsOnlyForUserByCursorRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Channeltitle,
+			&i.Channelurl,
+			&i.Channeldescription,
+			&i.Channellanguage,
+			&i.Itemtitle,
+			&i.Itemdescription,
+			&i.Itemcontent,
+			&i.ItempublishedAt,
+			&i.Itemurl,
+			&i.ImgUrl,
+			&i.FeedID,
+			&i.IsFavorite,
+			&i.IsFollowedFeed,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getRandomRSSPosts = `-- name: GetRandomRSSPosts :many
 SELECT id, created_at, updated_at, channeltitle, channelurl, channeldescription, channellanguage, itemtitle, itemdescription, itempublished_at, itemurl, img_url, feed_id, itemcontent
 FROM rssfeed_posts
@@ -11,8 +11,8 @@ import (
 )
 
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (name, email, password_hash, activated)
-VALUES ($1, $2, $3, $4)
+INSERT INTO users (name, email, password_hash, activated, handle)
+VALUES ($1, $2, $3, $4, $5)
 RETURNING id, created_at, version
 `
 
@@ -21,6 +21,7 @@ type CreateUserParams struct {
 	Email        string
 	PasswordHash []byte
 	Activated    bool
+	Handle       string
 }
 
 type CreateUserRow struct {
@@ -35,6 +36,7 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (CreateU
 		arg.Email,
 		arg.PasswordHash,
 		arg.Activated,
+		arg.Handle,
 	)
 	var i CreateUserRow
 	err := row.Scan(&i.ID, &i.CreatedAt, &i.Version)
@@ -42,7 +44,7 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (CreateU
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, created_at, name, email, password_hash, activated, version, user_img
+SELECT id, created_at, name, email, password_hash, activated, version, user_img, handle
 FROM users WHERE email = $1
 `
 
@@ -58,14 +60,15 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.Activated,
 		&i.Version,
 		&i.UserImg,
+		&i.Handle,
 	)
 	return i, err
 }
 
 const update = `-- name: Update :one
 UPDATE users
-SET name = $1, email = $2, password_hash = $3, activated = $4, version = version + 1, user_img=$5
-WHERE id = $6 AND version = $7
+SET name = $1, email = $2, password_hash = $3, activated = $4, version = version + 1, user_img=$5, handle = $6
+WHERE id = $7 AND version = $8
 RETURNING version
 `
 
@@ -75,6 +78,7 @@ type UpdateParams struct {
 	PasswordHash []byte
 	Activated    bool
 	UserImg      string
+	Handle       string
 	ID           int64
 	Version      int32
 }
@@ -86,6 +90,7 @@ func (q *Queries) Update(ctx context.Context, arg UpdateParams) (int32, error) {
 		arg.PasswordHash,
 		arg.Activated,
 		arg.UserImg,
+		arg.Handle,
 		arg.ID,
 		arg.Version,
 	)
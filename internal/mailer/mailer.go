@@ -38,6 +38,12 @@ func New(host string, port int, username, password, sender string) Mailer {
 // as the first parameter, the name of the file containing the templates, and any
 // dynamic data for the templates as an any parameter.
 func (m Mailer) Send(recipient, templateFile string, data any) error {
+	return m.SendWithHeaders(recipient, templateFile, data, nil)
+}
+
+// SendWithHeaders() works just like Send() but also sets any extra headers on the
+// message, eg: the List-Unsubscribe headers for the digest emails.
+func (m Mailer) SendWithHeaders(recipient, templateFile string, data any, headers map[string]string) error {
 	// Use the ParseFS() method to parse the required template file from the embedded
 	// file system.
 	tmpl, err := template.New("email").ParseFS(templateFS, "templates/"+templateFile)
@@ -73,6 +79,9 @@ func (m Mailer) Send(recipient, templateFile string, data any) error {
 	msg.SetHeader("To", recipient)
 	msg.SetHeader("From", m.sender)
 	msg.SetHeader("Subject", subject.String())
+	for header, value := range headers {
+		msg.SetHeader(header, value)
+	}
 	msg.SetBody("text/plain", plainBody.String())
 	msg.AddAlternative("text/html", htmlBody.String())
 	// Call the DialAndSend() method on the dialer, passing in the message to send. This
@@ -0,0 +1,112 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func TestDocumentBytes(t *testing.T) {
+	doc := New("Invoice INV-000001")
+	first := doc.AddPage()
+	first.Text(50, 800, HelveticaBold, 18, "Invoice (paid)")
+	first.Line(50, 790, 545, 790, 0.5, 0.8)
+	doc.AddPage().TextRight(545, 800, Helvetica, 10, "USD 10.00")
+	out := doc.Bytes()
+
+	if !bytes.HasPrefix(out, []byte("%PDF-1.4\n")) {
+		t.Fatalf("missing header, got %q", out[:20])
+	}
+	if !bytes.HasSuffix(out, []byte("%%EOF\n")) {
+		t.Errorf("missing end of file marker")
+	}
+	if !bytes.Contains(out, []byte(`(Invoice \(paid\)) Tj`)) {
+		t.Errorf("text was not escaped")
+	}
+	if !bytes.Contains(out, []byte("/Kids [6 0 R 8 0 R] /Count 2")) {
+		t.Errorf("page tree doesn't list both pages")
+	}
+	// every entry of the cross reference table has to point at the object it numbers
+	xref := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(out)
+	if xref == nil {
+		t.Fatal("missing startxref")
+	}
+	start, _ := strconv.Atoi(string(xref[1]))
+	if !bytes.HasPrefix(out[start:], []byte("xref\n0 10\n")) {
+		t.Fatalf("startxref doesn't point at the xref table")
+	}
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(out[start:], -1)
+	if len(entries) != 9 {
+		t.Fatalf("Got:%d objects But Wanted:9", len(entries))
+	}
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(string(entry[1]))
+		want := fmt.Sprintf("%d 0 obj\n", i+1)
+		if !bytes.HasPrefix(out[offset:], []byte(want)) {
+			t.Errorf("object %d is not at offset %d", i+1, offset)
+		}
+	}
+}
+
+func TestWidth(t *testing.T) {
+	tests := []struct {
+		name string
+		font Font
+		s    string
+		want float64
+	}{
+		{name: "Regular", font: Helvetica, s: "Hi", want: 10 * (722 + 222) / 1000.0},
+		{name: "Bold", font: HelveticaBold, s: "Hi", want: 10 * (722 + 278) / 1000.0},
+		{name: "Outside ASCII", font: Helvetica, s: "é", want: 5.56},
+		{name: "Empty", font: Helvetica, s: "", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Width(tt.font, 10, tt.s); got != tt.want {
+				t.Errorf("Got:%v But Wanted:%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name     string
+		s        string
+		maxWidth float64
+		want     string
+	}{
+		{name: "Fits", s: "Pro plan", maxWidth: 100, want: "Pro plan"},
+		{name: "Too Long", s: "Pro plan yearly", maxWidth: 50, want: "Pro plan..."},
+		{name: "Nothing Fits", s: "Pro", maxWidth: 1, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Truncate(Helvetica, 10, tt.s, tt.maxWidth); got != tt.want {
+				t.Errorf("Got:%q But Wanted:%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want string
+	}{
+		{name: "ASCII", s: "Total", want: "Total"},
+		{name: "Latin-1", s: "Café", want: "Caf\xe9"},
+		{name: "Euro", s: "€5", want: "\x805"},
+		{name: "Unsupported", s: "日本", want: "??"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := encode(tt.s); got != tt.want {
+				t.Errorf("Got:%q But Wanted:%q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+// Package pubsub provides an in-process publish/subscribe hub used to push
+// notifications to connected clients in real time.
+package pubsub
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	ErrHubClosed = errors.New("hub closed")
+)
+
+const (
+	// subscriberBufferSize is how many events may queue up for a single subscriber
+	// before we consider it too slow and drop it. Dropped subscribers reconnect and
+	// resume from their Last-Event-ID.
+	subscriberBufferSize = 32
+)
+
+// An Event is a single message published to the hub. IDs increase monotonically
+// and are shared by all recipients of the event.
+type Event struct {
+	ID         uint64
+	Type       string
+	Data       []byte
+	Created_At time.Time
+	// recipients holds the users the event is meant for, nil means everyone
+	recipients map[int64]struct{}
+}
+
+// visibleTo() reports whether a user is a recipient of the event
+func (e *Event) visibleTo(userID int64) bool {
+	if e.recipients == nil {
+		return true
+	}
+	_, ok := e.recipients[userID]
+	return ok
+}
+
+// A Subscription is a single connected client. Replay holds the buffered events the
+// client missed since the Last-Event-ID it resumed from, Events delivers the live ones
+// and is closed once the subscription ends, be it through Unsubscribe(), the hub closing
+// or the subscriber falling behind.
+type Subscription struct {
+	UserID int64
+	Replay []Event
+	Events <-chan Event
+	events chan Event
+}
+
+// Hub fans out published events to the subscriptions of their recipients. It keeps
+// the most recent events in a ring buffer so that reconnecting clients can resume.
+type Hub struct {
+	mu          sync.Mutex
+	nextID      uint64
+	buffer      []Event
+	bufferSize  int
+	subscribers map[int64]map[*Subscription]struct{}
+	closed      bool
+}
+
+// NewHub() creates a hub that remembers the last bufferSize events for resumption.
+// Event IDs are seeded from the clock so that IDs handed out before a restart are
+// always lower than the new ones.
+func NewHub(bufferSize int) *Hub {
+	if bufferSize < 0 {
+		bufferSize = 0
+	}
+	return &Hub{
+		nextID:      uint64(time.Now().UnixMilli()),
+		bufferSize:  bufferSize,
+		subscribers: make(map[int64]map[*Subscription]struct{}),
+	}
+}
+
+// Publish() marshals the payload as JSON and sends it to the given users, or to every
+// subscriber when no users are passed. It returns the published event's ID.
+func (h *Hub) Publish(eventType string, payload any, userIDs ...int64) (uint64, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return 0, err
+	}
+	event := Event{
+		Type:       eventType,
+		Data:       data,
+		Created_At: time.Now(),
+	}
+	if len(userIDs) > 0 {
+		event.recipients = make(map[int64]struct{}, len(userIDs))
+		for _, userID := range userIDs {
+			event.recipients[userID] = struct{}{}
+		}
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return 0, ErrHubClosed
+	}
+	h.nextID++
+	event.ID = h.nextID
+	if h.bufferSize > 0 {
+		if len(h.buffer) == h.bufferSize {
+			h.buffer = h.buffer[1:]
+		}
+		h.buffer = append(h.buffer, event)
+	}
+	if event.recipients == nil {
+		for userID := range h.subscribers {
+			h.deliver(userID, event)
+		}
+	} else {
+		for userID := range event.recipients {
+			h.deliver(userID, event)
+		}
+	}
+	return event.ID, nil
+}
+
+// deliver() sends an event to each of a user's subscriptions. A subscriber whose buffer
+// is full is dropped rather than blocking every other publisher. Callers hold the lock.
+func (h *Hub) deliver(userID int64, event Event) {
+	for subscription := range h.subscribers[userID] {
+		select {
+		case subscription.events <- event:
+		default:
+			h.remove(subscription)
+		}
+	}
+}
+
+// Subscribe() registers a new subscription for a user. Buffered events after
+// lastEventID that the user may see are returned in Replay, pass 0 for none.
+func (h *Hub) Subscribe(userID int64, lastEventID uint64) (*Subscription, error) {
+	events := make(chan Event, subscriberBufferSize)
+	subscription := &Subscription{
+		UserID: userID,
+		Events: events,
+		events: events,
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil, ErrHubClosed
+	}
+	if lastEventID > 0 {
+		for _, event := range h.buffer {
+			if event.ID > lastEventID && event.visibleTo(userID) {
+				subscription.Replay = append(subscription.Replay, event)
+			}
+		}
+	}
+	if h.subscribers[userID] == nil {
+		h.subscribers[userID] = make(map[*Subscription]struct{})
+	}
+	h.subscribers[userID][subscription] = struct{}{}
+	return subscription, nil
+}
+
+// Unsubscribe() removes a subscription. It is safe to call more than once.
+func (h *Hub) Unsubscribe(subscription *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.remove(subscription)
+}
+
+// remove() closes and forgets a subscription. Callers hold the lock.
+func (h *Hub) remove(subscription *Subscription) {
+	subscriptions, ok := h.subscribers[subscription.UserID]
+	if !ok {
+		return
+	}
+	if _, ok := subscriptions[subscription]; !ok {
+		return
+	}
+	delete(subscriptions, subscription)
+	close(subscription.events)
+	if len(subscriptions) == 0 {
+		delete(h.subscribers, subscription.UserID)
+	}
+}
+
+// Subscribers() returns the number of active subscriptions
+func (h *Hub) Subscribers() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	total := 0
+	for _, subscriptions := range h.subscribers {
+		total += len(subscriptions)
+	}
+	return total
+}
+
+// Close() ends every subscription and rejects any further publishes or subscribes.
+// It is used during a graceful shutdown so that long lived streams return.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return
+	}
+	h.closed = true
+	for _, subscriptions := range h.subscribers {
+		for subscription := range subscriptions {
+			h.remove(subscription)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package pubsub
+
+import (
+	"testing"
+)
+
+func TestHubPublish(t *testing.T) {
+	tests := []struct {
+		name       string
+		recipients []int64
+		wantAlice  bool
+		wantBob    bool
+	}{
+		{name: "Single User", recipients: []int64{1}, wantAlice: true},
+		{name: "Many Users", recipients: []int64{1, 2}, wantAlice: true, wantBob: true},
+		{name: "Broadcast", wantAlice: true, wantBob: true},
+		{name: "Other User", recipients: []int64{3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub := NewHub(10)
+			defer hub.Close()
+			alice, _ := hub.Subscribe(1, 0)
+			bob, _ := hub.Subscribe(2, 0)
+			id, err := hub.Publish("notification", map[string]string{"hello": "world"}, tt.recipients...)
+			if err != nil {
+				t.Fatalf("Publish() error = %v", err)
+			}
+			if got := received(alice, id); got != tt.wantAlice {
+				t.Errorf("Got alice received:%v But Wanted:%v", got, tt.wantAlice)
+			}
+			if got := received(bob, id); got != tt.wantBob {
+				t.Errorf("Got bob received:%v But Wanted:%v", got, tt.wantBob)
+			}
+		})
+	}
+}
+
+func TestHubReplay(t *testing.T) {
+	hub := NewHub(3)
+	defer hub.Close()
+	var ids []uint64
+	for i := 0; i < 5; i++ {
+		recipient := int64(1)
+		if i == 3 {
+			recipient = 2
+		}
+		id, _ := hub.Publish("notification", i, recipient)
+		ids = append(ids, id)
+	}
+	tests := []struct {
+		name        string
+		lastEventID uint64
+		want        []uint64
+	}{
+		{name: "No Last Event ID", lastEventID: 0},
+		{name: "Resume Within Buffer", lastEventID: ids[2], want: []uint64{ids[4]}},
+		{name: "Resume Before Buffer", lastEventID: ids[0], want: []uint64{ids[2], ids[4]}},
+		{name: "Up To Date", lastEventID: ids[4]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subscription, err := hub.Subscribe(1, tt.lastEventID)
+			if err != nil {
+				t.Fatalf("Subscribe() error = %v", err)
+			}
+			defer hub.Unsubscribe(subscription)
+			if len(subscription.Replay) != len(tt.want) {
+				t.Fatalf("Got:%d replayed events But Wanted:%d", len(subscription.Replay), len(tt.want))
+			}
+			for i, event := range subscription.Replay {
+				if event.ID != tt.want[i] {
+					t.Errorf("Got event:%d But Wanted:%d", event.ID, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestHubDropsSlowSubscribers(t *testing.T) {
+	hub := NewHub(0)
+	defer hub.Close()
+	subscription, _ := hub.Subscribe(1, 0)
+	for i := 0; i <= subscriberBufferSize; i++ {
+		hub.Publish("notification", i, 1)
+	}
+	if hub.Subscribers() != 0 {
+		t.Fatalf("Got:%d subscribers But Wanted the slow subscriber dropped", hub.Subscribers())
+	}
+	count := 0
+	for range subscription.Events {
+		count++
+	}
+	if count != subscriberBufferSize {
+		t.Errorf("Got:%d events But Wanted:%d", count, subscriberBufferSize)
+	}
+}
+
+func TestHubClose(t *testing.T) {
+	hub := NewHub(10)
+	subscription, _ := hub.Subscribe(1, 0)
+	hub.Close()
+	if _, ok := <-subscription.Events; ok {
+		t.Errorf("Wanted the subscription to be closed")
+	}
+	if _, err := hub.Subscribe(1, 0); err != ErrHubClosed {
+		t.Errorf("Got:%v But Wanted:%v", err, ErrHubClosed)
+	}
+	if _, err := hub.Publish("notification", nil); err != ErrHubClosed {
+		t.Errorf("Got:%v But Wanted:%v", err, ErrHubClosed)
+	}
+	// closing and unsubscribing again should be a no-op
+	hub.Close()
+	hub.Unsubscribe(subscription)
+}
+
+// received() reports whether the next queued event on a subscription has the given ID
+func received(subscription *Subscription, id uint64) bool {
+	select {
+	case event := <-subscription.Events:
+		return event.ID == id
+	default:
+		return false
+	}
+}
@@ -0,0 +1,114 @@
+package screening
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// A Classification is a classifier's verdict on a piece of text. Score is the
+// probability the text is spam, between 0 and 1.
+type Classification struct {
+	Score float64 `json:"score"`
+	Label string  `json:"label"`
+}
+
+// Classifier scores text for spam. It is the extension point for an external
+// classification service.
+type Classifier interface {
+	Classify(ctx context.Context, text string) (Classification, error)
+}
+
+// ClassifierCheck flags content the classifier scores at or above Threshold
+type ClassifierCheck struct {
+	Classifier Classifier
+	Threshold  float64
+}
+
+func (c ClassifierCheck) Check(ctx context.Context, content *Content) (Finding, error) {
+	classification, err := c.Classifier.Classify(ctx, content.Text)
+	if err != nil {
+		return Finding{}, fmt.Errorf("classifying content: %w", err)
+	}
+	finding := Finding{Score: classification.Score}
+	if classification.Score >= c.Threshold {
+		finding.Flagged = true
+		finding.Reason = fmt.Sprintf("classified as %s with a score of %.2f", classification.Label, classification.Score)
+	}
+	return finding, nil
+}
+
+// HTTPClassifier calls an external classification service. It POSTs {"text": "..."} to
+// URL and expects {"score": 0.97, "label": "spam"} back. APIKey, when set, is sent as a
+// bearer token.
+type HTTPClassifier struct {
+	URL    string
+	APIKey string
+	Client *http.Client
+}
+
+// NewHTTPClassifier() creates an HTTPClassifier whose requests give up after timeout
+func NewHTTPClassifier(url, apiKey string, timeout time.Duration) *HTTPClassifier {
+	return &HTTPClassifier{
+		URL:    url,
+		APIKey: apiKey,
+		Client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (c *HTTPClassifier) Classify(ctx context.Context, text string) (Classification, error) {
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return Classification{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(body))
+	if err != nil {
+		return Classification{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+	res, err := c.Client.Do(req)
+	if err != nil {
+		return Classification{}, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return Classification{}, fmt.Errorf("classifier responded with status %d", res.StatusCode)
+	}
+	var classification Classification
+	err = json.NewDecoder(res.Body).Decode(&classification)
+	if err != nil {
+		return Classification{}, fmt.Errorf("decoding classifier response: %w", err)
+	}
+	if classification.Label == "" {
+		classification.Label = "spam"
+	}
+	return classification, nil
+}
+
+// FakeClassifier is a local stand-in for an external classifier, used in tests and in
+// development. Text containing any of its Terms scores 1 and everything else scores 0.
+// Err, when set, is returned from every call.
+type FakeClassifier struct {
+	Terms []string
+	Err   error
+}
+
+func (c FakeClassifier) Classify(ctx context.Context, text string) (Classification, error) {
+	if c.Err != nil {
+		return Classification{}, c.Err
+	}
+	lower := strings.ToLower(text)
+	for _, term := range c.Terms {
+		if strings.Contains(lower, strings.ToLower(term)) {
+			return Classification{Score: 1, Label: "spam"}, nil
+		}
+	}
+	return Classification{Label: "ham"}, nil
+}
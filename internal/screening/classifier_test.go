@@ -0,0 +1,52 @@
+package screening
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHTTPClassifier(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		body      string
+		wantScore float64
+		wantLabel string
+		wantErr   bool
+	}{
+		{name: "Spam", status: http.StatusOK, body: `{"score": 0.97, "label": "spam"}`, wantScore: 0.97, wantLabel: "spam"},
+		{name: "Missing Label", status: http.StatusOK, body: `{"score": 0.2}`, wantScore: 0.2, wantLabel: "spam"},
+		{name: "Server Error", status: http.StatusInternalServerError, body: `{}`, wantErr: true},
+		{name: "Bad JSON", status: http.StatusOK, body: `not json`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+					t.Errorf("Got Authorization:%q But Wanted:%q", got, "Bearer secret")
+				}
+				var input struct {
+					Text string `json:"text"`
+				}
+				if err := json.NewDecoder(r.Body).Decode(&input); err != nil || input.Text != "hello" {
+					t.Errorf("Got text:%q err:%v But Wanted:%q", input.Text, err, "hello")
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+			classifier := NewHTTPClassifier(server.URL, "secret", time.Second)
+			got, err := classifier.Classify(context.Background(), "hello")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Got error:%v But Wanted error:%v", err, tt.wantErr)
+			}
+			if got.Score != tt.wantScore || got.Label != tt.wantLabel {
+				t.Errorf("Got:%+v But Wanted score:%v label:%s", got, tt.wantScore, tt.wantLabel)
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+// Package screening runs user submitted content through a pipeline of spam checks before
+// it is published. Each check looks at the content on its own and any of them can flag it,
+// the caller decides what happens to flagged content.
+package screening
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Content is what gets screened. Recent holds the author's recent submissions and is
+// filled in by the pipeline from its History before the checks run.
+type Content struct {
+	UserID int64
+	Text   string
+	Recent []Submission
+	Now    time.Time
+}
+
+// Submission is one of the author's earlier submissions
+type Submission struct {
+	ID        string
+	Text      string
+	CreatedAt time.Time
+}
+
+// A Finding is the outcome of a single check. Score is only set by checks that produce
+// one, such as the classifier check.
+type Finding struct {
+	Flagged bool
+	Reason  string
+	Score   float64
+}
+
+// Result is the outcome of running content through the whole pipeline
+type Result struct {
+	Flagged bool
+	Reasons []string
+	Score   float64
+}
+
+// A Check inspects content and reports whether it looks like spam
+type Check interface {
+	Check(ctx context.Context, content *Content) (Finding, error)
+}
+
+// History looks up a user's submissions since a given time, newest first
+type History interface {
+	RecentSubmissions(ctx context.Context, userID int64, since time.Time) ([]Submission, error)
+}
+
+// Pipeline runs content through its checks in order
+type Pipeline struct {
+	checks   []Check
+	history  History
+	lookback time.Duration
+	now      func() time.Time
+}
+
+// New() creates a pipeline. history may be nil, in which case checks that rely on the
+// author's recent submissions never flag anything. lookback is how far back those
+// submissions are loaded and should cover the longest window of any check.
+func New(history History, lookback time.Duration, checks ...Check) *Pipeline {
+	return &Pipeline{
+		checks:   checks,
+		history:  history,
+		lookback: lookback,
+		now:      time.Now,
+	}
+}
+
+// Screen() runs the content through every check and collects the reasons it was flagged.
+// A check that fails does not stop the others, its error is returned alongside whatever
+// the remaining checks found so the caller can fail open.
+func (p *Pipeline) Screen(ctx context.Context, userID int64, text string) (*Result, error) {
+	return p.screen(ctx, userID, "", text)
+}
+
+// ScreenEdit() screens the new text of an edited submission. The submission being edited
+// is left out of the author's history so its old text isn't held against the new one.
+func (p *Pipeline) ScreenEdit(ctx context.Context, userID int64, id, text string) (*Result, error) {
+	return p.screen(ctx, userID, id, text)
+}
+
+func (p *Pipeline) screen(ctx context.Context, userID int64, editedID, text string) (*Result, error) {
+	content := &Content{UserID: userID, Text: text, Now: p.now()}
+	var errs []error
+	if p.history != nil && p.lookback > 0 {
+		recent, err := p.history.RecentSubmissions(ctx, userID, content.Now.Add(-p.lookback))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("loading history: %w", err))
+		}
+		for _, submission := range recent {
+			if editedID == "" || submission.ID != editedID {
+				content.Recent = append(content.Recent, submission)
+			}
+		}
+	}
+	result := &Result{Reasons: []string{}}
+	for _, check := range p.checks {
+		finding, err := check.Check(ctx, content)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if finding.Score > result.Score {
+			result.Score = finding.Score
+		}
+		if finding.Flagged {
+			result.Flagged = true
+			result.Reasons = append(result.Reasons, finding.Reason)
+		}
+	}
+	return result, errors.Join(errs...)
+}
+
+// linkRX matches anything that reads as a link, with or without a scheme
+var linkRX = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+`)
+
+// LinkCheck flags content carrying more than Max links
+type LinkCheck struct {
+	Max int
+}
+
+func (c LinkCheck) Check(ctx context.Context, content *Content) (Finding, error) {
+	links := len(linkRX.FindAllStringIndex(content.Text, -1))
+	if links > c.Max {
+		return Finding{Flagged: true, Reason: fmt.Sprintf("contains %d links, at most %d are allowed", links, c.Max)}, nil
+	}
+	return Finding{}, nil
+}
+
+// BlocklistCheck flags content containing any of a list of blocked words or phrases.
+// Matching ignores case and only matches whole words.
+type BlocklistCheck struct {
+	rx *regexp.Regexp
+}
+
+// NewBlocklistCheck() builds a blocklist check from a list of terms, empty terms are skipped
+func NewBlocklistCheck(terms []string) BlocklistCheck {
+	quoted := []string{}
+	for _, term := range terms {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		quoted = append(quoted, regexp.QuoteMeta(term))
+	}
+	if len(quoted) == 0 {
+		return BlocklistCheck{}
+	}
+	return BlocklistCheck{rx: regexp.MustCompile(`(?i)\b(?:` + strings.Join(quoted, "|") + `)\b`)}
+}
+
+func (c BlocklistCheck) Check(ctx context.Context, content *Content) (Finding, error) {
+	if c.rx == nil {
+		return Finding{}, nil
+	}
+	if match := c.rx.FindString(content.Text); match != "" {
+		return Finding{Flagged: true, Reason: fmt.Sprintf("contains the blocked term %q", strings.ToLower(match))}, nil
+	}
+	return Finding{}, nil
+}
+
+// DuplicateCheck flags content the author already submitted within Window. Texts are
+// compared ignoring case and whitespace so padding a copy does not get it through.
+type DuplicateCheck struct {
+	Window time.Duration
+}
+
+func (c DuplicateCheck) Check(ctx context.Context, content *Content) (Finding, error) {
+	text := normalize(content.Text)
+	since := content.Now.Add(-c.Window)
+	for _, submission := range content.Recent {
+		if submission.CreatedAt.Before(since) {
+			continue
+		}
+		if normalize(submission.Text) == text {
+			return Finding{Flagged: true, Reason: "duplicates a recent comment"}, nil
+		}
+	}
+	return Finding{}, nil
+}
+
+// normalize() lower cases the text and collapses its whitespace
+func normalize(text string) string {
+	return strings.Join(strings.Fields(strings.ToLower(text)), " ")
+}
+
+// BurstCheck flags content from authors who already submitted Limit or more times
+// within Window.
+type BurstCheck struct {
+	Window time.Duration
+	Limit  int
+}
+
+func (c BurstCheck) Check(ctx context.Context, content *Content) (Finding, error) {
+	since := content.Now.Add(-c.Window)
+	count := 0
+	for _, submission := range content.Recent {
+		if !submission.CreatedAt.Before(since) {
+			count++
+		}
+	}
+	if count >= c.Limit {
+		return Finding{Flagged: true, Reason: fmt.Sprintf("%d comments in the last %s", count+1, c.Window)}, nil
+	}
+	return Finding{}, nil
+}
@@ -0,0 +1,166 @@
+package screening
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeHistory serves a fixed set of submissions, filtering them the way the database would
+type fakeHistory struct {
+	submissions []Submission
+	err         error
+}
+
+func (h fakeHistory) RecentSubmissions(ctx context.Context, userID int64, since time.Time) ([]Submission, error) {
+	recent := []Submission{}
+	for _, submission := range h.submissions {
+		if submission.CreatedAt.After(since) {
+			recent = append(recent, submission)
+		}
+	}
+	return recent, h.err
+}
+
+func TestChecks(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	recent := []Submission{
+		{Text: "Great read, thanks!", CreatedAt: now.Add(-30 * time.Second)},
+		{Text: "Totally agree", CreatedAt: now.Add(-45 * time.Second)},
+		{Text: "Old news", CreatedAt: now.Add(-20 * time.Minute)},
+	}
+	tests := []struct {
+		name        string
+		check       Check
+		text        string
+		wantFlagged bool
+	}{
+		{name: "Few Links", check: LinkCheck{Max: 2}, text: "see https://a.com and www.b.com"},
+		{name: "Too Many Links", check: LinkCheck{Max: 2}, text: "https://a.com http://b.com www.c.com", wantFlagged: true},
+		{name: "Blocked Term", check: NewBlocklistCheck([]string{"cheap pills", "casino"}), text: "Best CASINO in town", wantFlagged: true},
+		{name: "Blocked Phrase", check: NewBlocklistCheck([]string{"cheap pills"}), text: "buy cheap pills now", wantFlagged: true},
+		{name: "Blocked Term Within A Word", check: NewBlocklistCheck([]string{"casino"}), text: "casinos are fun"},
+		{name: "Empty Blocklist", check: NewBlocklistCheck([]string{"", " "}), text: "anything goes"},
+		{name: "Duplicate", check: DuplicateCheck{Window: 10 * time.Minute}, text: "  great READ,   thanks! ", wantFlagged: true},
+		{name: "Duplicate Outside Window", check: DuplicateCheck{Window: 10 * time.Minute}, text: "old news"},
+		{name: "Original", check: DuplicateCheck{Window: 10 * time.Minute}, text: "A new thought"},
+		{name: "Burst", check: BurstCheck{Window: time.Minute, Limit: 2}, text: "again", wantFlagged: true},
+		{name: "Under Burst Limit", check: BurstCheck{Window: time.Minute, Limit: 3}, text: "again"},
+		{name: "Classifier Spam", check: ClassifierCheck{Classifier: FakeClassifier{Terms: []string{"free money"}}, Threshold: 0.8}, text: "Get FREE money", wantFlagged: true},
+		{name: "Classifier Ham", check: ClassifierCheck{Classifier: FakeClassifier{Terms: []string{"free money"}}, Threshold: 0.8}, text: "Nice post"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			finding, err := tt.check.Check(context.Background(), &Content{UserID: 1, Text: tt.text, Recent: recent, Now: now})
+			if err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+			if finding.Flagged != tt.wantFlagged {
+				t.Errorf("Got flagged:%v But Wanted:%v", finding.Flagged, tt.wantFlagged)
+			}
+			if finding.Flagged && finding.Reason == "" {
+				t.Errorf("Got a flagged finding without a reason")
+			}
+		})
+	}
+}
+
+func TestPipelineScreen(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	history := fakeHistory{submissions: []Submission{
+		{Text: "buy now", CreatedAt: now.Add(-time.Minute)},
+		{Text: "ancient", CreatedAt: now.Add(-48 * time.Hour)},
+	}}
+	classifierErr := errors.New("classifier unavailable")
+	tests := []struct {
+		name        string
+		history     History
+		checks      []Check
+		text        string
+		wantFlagged bool
+		wantReasons int
+		wantScore   float64
+		wantErr     bool
+	}{
+		{name: "Clean", history: history, checks: []Check{LinkCheck{Max: 1}, DuplicateCheck{Window: time.Hour}}, text: "hello"},
+		{name: "Loads History", history: history, checks: []Check{DuplicateCheck{Window: time.Hour}}, text: "Buy now", wantFlagged: true, wantReasons: 1},
+		{name: "History Outside Lookback", history: history, checks: []Check{DuplicateCheck{Window: 72 * time.Hour}}, text: "ancient"},
+		{name: "No History", checks: []Check{DuplicateCheck{Window: time.Hour}}, text: "buy now"},
+		{
+			name:        "Collects Reasons",
+			history:     history,
+			checks:      []Check{LinkCheck{Max: 0}, DuplicateCheck{Window: time.Hour}, ClassifierCheck{Classifier: FakeClassifier{Terms: []string{"buy"}}, Threshold: 0.5}},
+			text:        "buy now",
+			wantFlagged: true,
+			wantReasons: 2,
+			wantScore:   1,
+		},
+		{
+			name:        "Fails Open",
+			history:     history,
+			checks:      []Check{ClassifierCheck{Classifier: FakeClassifier{Err: classifierErr}, Threshold: 0.5}, LinkCheck{Max: 0}},
+			text:        "https://spam.example",
+			wantFlagged: true,
+			wantReasons: 1,
+			wantErr:     true,
+		},
+		{name: "History Error", history: fakeHistory{err: errors.New("db down")}, checks: []Check{LinkCheck{Max: 1}}, text: "hi", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(tt.history, 24*time.Hour, tt.checks...)
+			p.now = func() time.Time { return now }
+			result, err := p.Screen(context.Background(), 1, tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Got error:%v But Wanted error:%v", err, tt.wantErr)
+			}
+			if result.Flagged != tt.wantFlagged {
+				t.Errorf("Got flagged:%v But Wanted:%v", result.Flagged, tt.wantFlagged)
+			}
+			if len(result.Reasons) != tt.wantReasons {
+				t.Errorf("Got reasons:%v But Wanted %d of them", result.Reasons, tt.wantReasons)
+			}
+			if result.Score != tt.wantScore {
+				t.Errorf("Got score:%v But Wanted:%v", result.Score, tt.wantScore)
+			}
+		})
+	}
+}
+
+func TestPipelineScreenEdit(t *testing.T) {
+	now := time.Now()
+	history := fakeHistory{submissions: []Submission{
+		{ID: "edited", Text: "hello there", CreatedAt: now.Add(-time.Minute)},
+		{ID: "other", Text: "buy now", CreatedAt: now.Add(-2 * time.Minute)},
+	}}
+	tests := []struct {
+		name        string
+		text        string
+		wantFlagged bool
+	}{
+		{name: "Own Text", text: "Hello  there"},
+		{name: "Copies Another", text: "buy now", wantFlagged: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(history, time.Hour, DuplicateCheck{Window: time.Hour}, BurstCheck{Window: time.Hour, Limit: 2})
+			p.now = func() time.Time { return now }
+			result, err := p.ScreenEdit(context.Background(), 1, "edited", tt.text)
+			if err != nil {
+				t.Fatalf("Got error:%v But Wanted none", err)
+			}
+			if result.Flagged != tt.wantFlagged {
+				t.Errorf("Got flagged:%v (%v) But Wanted:%v", result.Flagged, result.Reasons, tt.wantFlagged)
+			}
+		})
+	}
+}
+
+func TestBlocklistReason(t *testing.T) {
+	finding, _ := NewBlocklistCheck([]string{"c.a.s.i.n.o"}).Check(context.Background(), &Content{Text: "visit C.A.S.I.N.O today"})
+	if !finding.Flagged || !strings.Contains(finding.Reason, `"c.a.s.i.n.o"`) {
+		t.Errorf("Got:%+v But Wanted the escaped term to match and be named", finding)
+	}
+}
@@ -28,7 +28,7 @@ ORDER BY
 LIMIT $2 OFFSET $3;
 
 -- name: AdminGetAllPaymentPlans :many
-SELECT id, name, image, description, duration, price, features, created_at, updated_at, status,version
+SELECT id, name, image, description, duration, price, features, created_at, updated_at, status, version, currency, trial_days, seats
 FROM payment_plans
 ORDER BY status ASC, price;
 
@@ -86,6 +86,7 @@ SELECT
     ct.status,
     s.plan_id,
     s.price,
+    s.currency,
     s.start_date,
     s.end_date
 FROM 
@@ -114,7 +115,6 @@ user_stats AS (
 -- Get statistics from the subscriptions table
 subscription_stats AS (
     SELECT
-        COALESCE(SUM(price), 0)::numeric AS total_revenue,
         COUNT(*) FILTER (WHERE status = 'active') AS active_subscriptions,
         COUNT(*) FILTER (WHERE status = 'cancelled') AS cancelled_subscriptions,
         COUNT(*) FILTER (WHERE status = 'expired') AS expired_subscriptions,
@@ -139,7 +139,6 @@ SELECT
     us.total_users,
     us.active_users,
     us.new_signups,
-    ss.total_revenue,
     ss.active_subscriptions,
     ss.cancelled_subscriptions,
     ss.expired_subscriptions,
@@ -151,9 +150,9 @@ FROM user_stats us, subscription_stats ss, comment_stats cs;
 
 -- name: AdminCreatePaymentPlan :one
 INSERT INTO payment_plans (
-    name, image, description, duration, price, features, status
+    name, image, description, duration, price, currency, features, status, trial_days, seats
 ) VALUES (
-    $1, $2, $3, $4, $5, $6, $7
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
 )
 RETURNING *;
 
@@ -165,16 +164,19 @@ SET
     description = $3,
     duration = $4,
     price = $5,
-    features = $6,
-    status = $7,
+    currency = $6,
+    features = $7,
+    status = $8,
+    trial_days = $9,
+    seats = $10,
     version = version + 1,
     updated_at = now()
 WHERE 
-    id = $8 AND version = $9
+    id = $11 AND version = $12
 RETURNING version;
 
 -- name: AdminGetPaymentPlanByID :one
-SELECT id, name, image, description, duration, price, features, created_at, updated_at, status, version
+SELECT id, name, image, description, duration, price, features, created_at, updated_at, status, version, currency, trial_days, seats
 FROM payment_plans
 WHERE id = $1;
 
@@ -0,0 +1,73 @@
+-- name: ClaimDueSubscriptions :many
+UPDATE subscriptions s
+SET renewal_locked_until = $2
+FROM users u
+WHERE u.id = s.user_id AND s.id IN (
+    SELECT d.id
+    FROM subscriptions d
+    WHERE d.status IN ('active', 'past_due')
+        AND d.end_date <= NOW()
+        AND (d.next_renewal_at IS NULL OR d.next_renewal_at <= NOW())
+        AND (d.renewal_locked_until IS NULL OR d.renewal_locked_until < NOW())
+        AND NOT EXISTS (
+            SELECT 1 FROM subscriptions n
+            WHERE n.user_id = d.user_id AND n.status = 'active' AND n.start_date > d.start_date
+        )
+    ORDER BY d.end_date
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING s.id, s.authorization_code, s.plan_id, s.start_date, s.end_date, s.price, s.currency, s.provider,
+    s.renewal_attempts, s.is_trial, s.renewal_locked_until, s.user_id, u.email, u.name;
+
+-- name: ExtendRenewalLock :execrows
+UPDATE subscriptions
+SET renewal_locked_until = $2
+WHERE id = $1 AND renewal_locked_until = $3 AND status IN ('active', 'past_due');
+
+-- name: RecordFailedRenewal :exec
+UPDATE subscriptions
+SET status = 'past_due', renewal_attempts = $2, next_renewal_at = $3, renewal_locked_until = NULL, updated_at = NOW()
+WHERE id = $1 AND status IN ('active', 'past_due');
+
+-- name: ExpireSubscription :exec
+UPDATE subscriptions
+SET status = 'expired', renewal_attempts = $2, next_renewal_at = NULL, renewal_locked_until = NULL, updated_at = NOW()
+WHERE id = $1 AND status IN ('active', 'past_due');
+
+-- name: CreateBillingRun :one
+INSERT INTO billing_runs (trigger)
+VALUES ($1)
+RETURNING id, status, started_at;
+
+-- name: FinishBillingRun :one
+UPDATE billing_runs
+SET status = $2, processed = $3, renewed = $4, retries_scheduled = $5, challenged = $6,
+    expired = $7, errors = $8, error_message = $9, finished_at = NOW()
+WHERE id = $1
+RETURNING finished_at;
+
+-- name: CreateBillingRunItem :exec
+INSERT INTO billing_run_items (run_id, subscription_id, user_id, attempt, outcome, message, next_attempt_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: GetBillingRuns :many
+SELECT count(*) OVER() AS total_records, id, trigger, status, processed, renewed, retries_scheduled,
+    challenged, expired, errors, error_message, started_at, finished_at
+FROM billing_runs
+ORDER BY started_at DESC, id DESC
+LIMIT $1 OFFSET $2;
+
+-- name: GetBillingRunByID :one
+SELECT id, trigger, status, processed, renewed, retries_scheduled, challenged, expired, errors,
+    error_message, started_at, finished_at
+FROM billing_runs
+WHERE id = $1;
+
+-- name: GetBillingRunItems :many
+SELECT i.id, i.run_id, i.subscription_id, i.user_id, u.name, u.email, i.attempt, i.outcome, i.message,
+    i.next_attempt_at, i.created_at
+FROM billing_run_items i
+JOIN users u ON u.id = i.user_id
+WHERE i.run_id = $1
+ORDER BY i.id;
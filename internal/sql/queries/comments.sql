@@ -1,28 +1,127 @@
 -- name: CreateComments :one
-INSERT INTO comments (id, post_id, user_id, parent_comment_id, comment_text)
-VALUES ($1, $2, $3, $4, $5)
+INSERT INTO comments (id, post_id, user_id, parent_comment_id, comment_text, status)
+VALUES ($1, $2, $3, $4, $5, $6)
 RETURNING *;
 
 -- name: GetCommentsForPost :many
+WITH RECURSIVE page AS (
+    -- the page of comments whose threads we return, top level ones unless a parent is given
+    SELECT
+        c.id,
+        count(*) OVER() AS total_records
+    FROM comments c
+    WHERE c.post_id = $1
+        AND COALESCE(c.parent_comment_id, '00000000-0000-0000-0000-000000000000') = $3::uuid
+        AND (c.status <> 'pending' OR c.user_id = $2) -- pending comments are only shown to their author
+    ORDER BY
+        CASE WHEN $5::text = 'oldest' THEN c.created_at END ASC,
+        CASE WHEN $5::text = 'top' THEN (
+            SELECT COALESCE(SUM(CASE WHEN cr.reaction = 'downvote' THEN -1 ELSE 1 END), 0)
+            FROM comment_reactions cr WHERE cr.comment_id = c.id
+        ) END DESC,
+        CASE WHEN $5::text = 'top' THEN (
+            SELECT COUNT(*) FROM comments r WHERE r.parent_comment_id = c.id AND r.status <> 'pending'
+        ) END DESC,
+        c.created_at DESC,
+        c.id DESC
+    LIMIT $6 OFFSET $7
+), thread AS (
+    SELECT
+        c.id, c.post_id, c.user_id, c.parent_comment_id, c.comment_text, c.status, c.created_at, c.edited_at, c.version,
+        0 AS depth,
+        page.total_records
+    FROM comments c
+    JOIN page ON c.id = page.id
+    UNION ALL
+    SELECT
+        c.id, c.post_id, c.user_id, c.parent_comment_id, c.comment_text, c.status, c.created_at, c.edited_at, c.version,
+        t.depth + 1,
+        t.total_records
+    FROM comments c
+    JOIN thread t ON c.parent_comment_id = t.id
+    WHERE t.depth + 1 < $4::int  -- Parameter 4: maximum depth
+        AND (c.status <> 'pending' OR c.user_id = $2)
+)
 SELECT 
-    comments.id, 
-    comments.post_id, 
-    comments.user_id, 
+    t.total_records,
+    t.id, 
+    t.post_id, 
+    t.user_id, 
     users.name as user_name, 
-    comments.parent_comment_id, 
-    comments.comment_text, 
-    comments.created_at,
-    comments.version,
-    CASE WHEN comments.user_id = $2 THEN true ELSE false END AS isEditable
+    users.handle as user_handle,
+    t.parent_comment_id, 
+    -- moderated and deleted comments keep their place in the thread without their text
+    CASE WHEN t.status IN ('visible', 'pending') THEN t.comment_text ELSE '[removed]' END AS comment_text,
+    t.status,
+    t.created_at,
+    t.edited_at,
+    t.version,
+    t.depth,
+    (
+        SELECT COUNT(*) FROM comments r
+        WHERE r.parent_comment_id = t.id AND (r.status <> 'pending' OR r.user_id = $2)
+    ) AS reply_count,
+    COALESCE(rc.score, 0)::bigint AS score,
+    COALESCE(rc.reaction_counts, '{}')::jsonb AS reaction_counts,
+    COALESCE(ur.reaction, '') AS user_reaction,
+    COALESCE((
+        SELECT jsonb_agg(jsonb_build_object('user_id', m.user_id, 'handle', m.handle))
+        FROM comment_mentions m WHERE m.comment_id = t.id
+    ), '[]')::jsonb AS mentions,
+    CASE WHEN t.user_id = $2 AND t.status = 'visible' AND t.created_at >= $8::timestamptz THEN true ELSE false END AS isEditable
+FROM thread t
+JOIN users ON t.user_id = users.id
+LEFT JOIN LATERAL (
+    -- downvotes count against a comment while every other reaction counts for it
+    SELECT
+        SUM(CASE WHEN counts.reaction = 'downvote' THEN -counts.total ELSE counts.total END) AS score,
+        jsonb_object_agg(counts.reaction, counts.total) AS reaction_counts
+    FROM (
+        SELECT cr.reaction, COUNT(*) AS total
+        FROM comment_reactions cr
+        WHERE cr.comment_id = t.id
+        GROUP BY cr.reaction
+    ) counts
+) rc ON TRUE
+LEFT JOIN comment_reactions ur ON ur.comment_id = t.id AND ur.user_id = $2
+ORDER BY t.depth, t.created_at;
+
+-- name: GetRecentUserComments :many
+SELECT id, comment_text, created_at
 FROM comments
-JOIN users ON comments.user_id = users.id
-WHERE comments.post_id = $1;
+WHERE user_id = $1 AND created_at > $2
+ORDER BY created_at DESC
+LIMIT 100;
+
+-- name: GetCommentDepth :one
+WITH RECURSIVE ancestors AS (
+    SELECT c.id, c.parent_comment_id, 0 AS depth
+    FROM comments c
+    WHERE c.id = $1
+    UNION ALL
+    SELECT c.id, c.parent_comment_id, a.depth + 1
+    FROM comments c
+    JOIN ancestors a ON c.id = a.parent_comment_id
+)
+SELECT 
+    comments.id,
+    comments.post_id,
+    (SELECT MAX(depth) FROM ancestors)::int AS depth
+FROM comments
+WHERE comments.id = $1;
 
 -- name: UpdateUserComment :one
+WITH revision AS (
+    -- keep the text being replaced, it became current when the comment was created or last edited
+    INSERT INTO comment_revisions (comment_id, version, comment_text, created_at)
+    SELECT c.id, c.version, c.comment_text, COALESCE(c.edited_at, c.created_at)
+    FROM comments c
+    WHERE c.id = $2 AND c.user_id = $3 AND c.version = $4 AND c.status = 'visible' AND c.created_at >= $5
+)
 UPDATE comments
-SET comment_text = $1, updated_at = now(), version = version + 1
-WHERE id = $2 AND user_id = $3 AND version = $4
-RETURNING version;
+SET comment_text = $1, status = $6, updated_at = now(), edited_at = now(), version = version + 1
+WHERE id = $2 AND user_id = $3 AND version = $4 AND status = 'visible' AND created_at >= $5
+RETURNING version, edited_at;
 
 -- name: GetCommentByID :one
 SELECT 
@@ -33,9 +132,67 @@ SELECT
     comment_text,
     created_at,
     updated_at,
-    version
+    version,
+    status,
+    edited_at
 FROM comments
 WHERE id = $1 AND user_id = $2;
 
--- name: DeleteComment :exec
-DELETE FROM comments WHERE id = $1 AND user_id = $2;
\ No newline at end of file
+-- name: DeleteComment :execrows
+DELETE FROM comments WHERE id = $1 AND user_id = $2
+AND NOT EXISTS (SELECT 1 FROM comments r WHERE r.parent_comment_id = comments.id);
+
+-- name: RemoveUserComment :execrows
+UPDATE comments
+SET status = 'removed', comment_text = '', updated_at = NOW(), version = version + 1
+WHERE id = $1 AND user_id = $2;
+
+-- name: GetCommentRevisions :many
+SELECT version, comment_text, created_at, replaced_at
+FROM comment_revisions
+WHERE comment_id = $1
+ORDER BY version DESC;
+
+-- name: DeleteCommentRevisions :exec
+DELETE FROM comment_revisions
+WHERE comment_id = $1;
+
+-- name: GetCommentOwner :one
+SELECT user_id, post_id FROM comments
+WHERE id = $1;
+
+-- name: UpsertCommentReaction :one
+INSERT INTO comment_reactions (comment_id, user_id, reaction)
+VALUES ($1, $2, $3)
+ON CONFLICT (comment_id, user_id) DO UPDATE
+SET reaction = EXCLUDED.reaction, updated_at = NOW()
+RETURNING *;
+
+-- name: DeleteCommentReaction :one
+DELETE FROM comment_reactions
+WHERE comment_id = $1 AND user_id = $2
+RETURNING comment_id;
+
+-- name: GetCommentReactionCounts :many
+SELECT reaction, COUNT(*) AS total
+FROM comment_reactions
+WHERE comment_id = $1
+GROUP BY reaction
+ORDER BY reaction;
+
+-- name: InsertCommentMentions :many
+INSERT INTO comment_mentions (comment_id, user_id, handle)
+SELECT $1, users.id, users.handle
+FROM users
+WHERE users.handle = ANY($2::citext[]) AND users.id <> $3  -- users can't mention themselves
+ON CONFLICT (comment_id, user_id) DO NOTHING
+RETURNING user_id;
+
+-- name: DeleteStaleCommentMentions :exec
+DELETE FROM comment_mentions
+WHERE comment_id = $1 AND NOT (handle = ANY($2::citext[]));
+
+-- name: GetCommentMentions :many
+SELECT user_id, handle FROM comment_mentions
+WHERE comment_id = $1
+ORDER BY created_at, handle;
@@ -0,0 +1,173 @@
+-- name: CreateCoupon :one
+INSERT INTO coupons (
+    code, description, discount_type, percent_off, amount_off, currency,
+    max_redemptions, expires_at, plan_ids, status
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+RETURNING id, times_redeemed, created_at, updated_at, version;
+
+-- name: GetCouponByID :one
+SELECT id, code, description, discount_type, percent_off, amount_off, currency, max_redemptions,
+    times_redeemed, pending_redemptions, expires_at, plan_ids, status, created_at, updated_at, version
+FROM coupons
+WHERE id = $1;
+
+-- name: GetCouponByCode :one
+SELECT id, code, description, discount_type, percent_off, amount_off, currency, max_redemptions,
+    times_redeemed, pending_redemptions, expires_at, plan_ids, status, created_at, updated_at, version
+FROM coupons
+WHERE code = $1;
+
+-- name: GetAllCoupons :many
+SELECT count(*) OVER() AS total_records, id, code, description, discount_type, percent_off, amount_off,
+    currency, max_redemptions, times_redeemed, pending_redemptions, expires_at, plan_ids, status, created_at,
+    updated_at, version
+FROM coupons
+WHERE ($1::text = '' OR code ILIKE '%' || $1::text || '%')
+    AND ($2::text = '' OR status = $2::text)
+ORDER BY created_at DESC, id DESC
+LIMIT $3 OFFSET $4;
+
+-- name: UpdateCoupon :one
+UPDATE coupons
+SET description = $1, max_redemptions = $2, expires_at = $3, plan_ids = $4, status = $5,
+    version = version + 1, updated_at = NOW()
+WHERE id = $6 AND version = $7
+RETURNING version, updated_at;
+
+-- name: ReserveCouponRedemption :one
+WITH reserved AS (
+    UPDATE coupons
+    SET pending_redemptions = pending_redemptions + 1, updated_at = NOW()
+    WHERE id = $1
+        AND (max_redemptions IS NULL OR times_redeemed + pending_redemptions < max_redemptions)
+    RETURNING id
+)
+INSERT INTO coupon_redemptions (coupon_id, user_id, plan_id, discount, currency, expires_at)
+SELECT reserved.id, $2, $3, $4, $5, $6
+FROM reserved
+RETURNING id, status, created_at, expires_at;
+
+-- name: GetUnredeemedCouponRedemptionByReference :one
+SELECT r.id, r.coupon_id, c.code, r.user_id, r.plan_id, r.subscription_id, r.reference, r.discount,
+    r.currency, r.status, r.created_at, r.redeemed_at
+FROM coupon_redemptions r
+JOIN coupons c ON c.id = r.coupon_id
+WHERE r.reference = $1 AND r.status IN ('pending', 'released');
+
+-- name: GetPendingCouponRedemptionBySubscriptionID :one
+SELECT r.id, r.coupon_id, c.code, r.user_id, r.plan_id, r.subscription_id, r.reference, r.discount,
+    r.currency, r.status, r.created_at, r.redeemed_at
+FROM coupon_redemptions r
+JOIN coupons c ON c.id = r.coupon_id
+WHERE r.subscription_id = $1 AND r.status = 'pending'
+ORDER BY r.id DESC
+LIMIT 1;
+
+-- name: SetCouponRedemptionReference :exec
+UPDATE coupon_redemptions
+SET reference = $2
+WHERE id = $1 AND status = 'pending';
+
+-- name: SetCouponRedemptionSubscription :exec
+UPDATE coupon_redemptions
+SET subscription_id = $2, expires_at = NULL
+WHERE id = $1 AND status = 'pending';
+
+-- name: RedeemCoupon :execrows
+WITH held AS (
+    SELECT id, coupon_id, status
+    FROM coupon_redemptions
+    WHERE coupon_redemptions.id = $1 AND coupon_redemptions.status IN ('pending', 'released')
+    FOR UPDATE
+), counted AS (
+    UPDATE coupons
+    SET times_redeemed = coupons.times_redeemed + 1,
+        pending_redemptions = coupons.pending_redemptions - CASE WHEN held.status = 'pending' THEN 1 ELSE 0 END,
+        updated_at = NOW()
+    FROM held
+    WHERE coupons.id = held.coupon_id
+        AND (held.status = 'pending' OR coupons.max_redemptions IS NULL
+            OR coupons.times_redeemed + coupons.pending_redemptions < coupons.max_redemptions)
+    RETURNING coupons.id
+)
+UPDATE coupon_redemptions
+SET status = 'redeemed', subscription_id = $2, redeemed_at = NOW()
+WHERE coupon_redemptions.id IN (SELECT id FROM held)
+    AND coupon_redemptions.coupon_id IN (SELECT id FROM counted);
+
+-- name: ReleaseCouponRedemption :exec
+WITH released AS (
+    UPDATE coupon_redemptions
+    SET status = 'released'
+    WHERE coupon_redemptions.id = $1 AND coupon_redemptions.status = 'pending'
+    RETURNING coupon_redemptions.coupon_id
+)
+UPDATE coupons
+SET pending_redemptions = coupons.pending_redemptions - 1, updated_at = NOW()
+FROM released
+WHERE coupons.id = released.coupon_id;
+
+-- name: ReleaseCheckoutCouponRedemptionByReference :exec
+WITH released AS (
+    UPDATE coupon_redemptions
+    SET status = 'released'
+    WHERE coupon_redemptions.reference = $1 AND coupon_redemptions.status = 'pending'
+        AND coupon_redemptions.subscription_id IS NULL
+    RETURNING coupon_redemptions.coupon_id
+)
+UPDATE coupons
+SET pending_redemptions = coupons.pending_redemptions - 1, updated_at = NOW()
+FROM released
+WHERE coupons.id = released.coupon_id;
+
+-- name: ReleaseUserCheckoutCouponRedemptions :exec
+WITH released AS (
+    UPDATE coupon_redemptions
+    SET status = 'released'
+    WHERE coupon_redemptions.coupon_id = $1 AND coupon_redemptions.user_id = $2
+        AND coupon_redemptions.status = 'pending' AND coupon_redemptions.subscription_id IS NULL
+    RETURNING coupon_redemptions.coupon_id
+)
+UPDATE coupons
+SET pending_redemptions = coupons.pending_redemptions - 1, updated_at = NOW()
+FROM released
+WHERE coupons.id = released.coupon_id;
+
+-- name: ReleaseExpiredCouponRedemptions :many
+WITH released AS (
+    UPDATE coupon_redemptions r
+    SET status = 'released'
+    WHERE r.status = 'pending' AND (
+        r.expires_at <= NOW()
+        OR EXISTS (
+            SELECT 1 FROM subscriptions s
+            WHERE s.id = r.subscription_id AND s.status IN ('expired', 'cancelled')
+        )
+    )
+    RETURNING r.coupon_id
+), counts AS (
+    SELECT coupon_id, COUNT(*) AS released
+    FROM released
+    GROUP BY coupon_id
+)
+UPDATE coupons
+SET pending_redemptions = coupons.pending_redemptions - counts.released, updated_at = NOW()
+FROM counts
+WHERE coupons.id = counts.coupon_id
+RETURNING coupons.code, counts.released;
+
+-- name: CouponRedemptionsByCoupon :many
+SELECT c.code, r.currency, COUNT(*) AS redemptions, CAST(SUM(r.discount) AS BIGINT) AS total_discount
+FROM coupon_redemptions r
+JOIN coupons c ON c.id = r.coupon_id
+WHERE r.status = 'redeemed'
+GROUP BY c.code, r.currency
+ORDER BY c.code, r.currency;
+
+-- name: TrialsReport :one
+SELECT
+    COUNT(*) FILTER (WHERE status = 'active' AND end_date > NOW()) AS active_trials,
+    COUNT(*) FILTER (WHERE status = 'renewed') AS converted_trials,
+    COUNT(*) FILTER (WHERE status IN ('renewed', 'expired')) AS ended_trials
+FROM subscriptions
+WHERE is_trial;
@@ -0,0 +1,73 @@
+-- name: UpsertDigestPreference :one
+INSERT INTO digest_preferences (user_id, frequency, timezone, send_hour, weekday, enabled, unsubscribe_token)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (user_id) DO UPDATE
+SET 
+    frequency = EXCLUDED.frequency,
+    timezone = EXCLUDED.timezone,
+    send_hour = EXCLUDED.send_hour,
+    weekday = EXCLUDED.weekday,
+    enabled = EXCLUDED.enabled,
+    updated_at = NOW()
+RETURNING *;
+
+-- name: GetDigestPreferenceByUserID :one
+SELECT * FROM digest_preferences
+WHERE user_id = $1;
+
+-- name: GetEnabledDigestPreferences :many
+SELECT 
+    d.*,
+    u.name,
+    u.email
+FROM digest_preferences d
+JOIN users u ON d.user_id = u.id
+WHERE d.enabled = TRUE AND u.activated = TRUE;
+
+-- name: UnsubscribeDigestByToken :one
+UPDATE digest_preferences
+SET enabled = FALSE, updated_at = NOW()
+WHERE unsubscribe_token = $1
+RETURNING user_id;
+
+-- name: MarkDigestSent :exec
+UPDATE digest_preferences
+SET last_sent_at = $2
+WHERE user_id = $1;
+
+-- name: GetDigestPostsForUser :many
+SELECT 
+    ranked.feed_id,
+    ranked.feed_name,
+    ranked.id,
+    ranked.itemtitle,
+    ranked.itemurl,
+    ranked.itempublished_at,
+    ranked.favorites_count
+FROM (
+    SELECT 
+        f.id AS feed_id,
+        f.name AS feed_name,
+        p.id,
+        p.itemtitle,
+        p.itemurl,
+        p.itempublished_at,
+        COALESCE(fc.favorites_count, 0)::bigint AS favorites_count,
+        ROW_NUMBER() OVER (
+            PARTITION BY p.feed_id 
+            ORDER BY COALESCE(fc.favorites_count, 0) DESC, p.itempublished_at DESC
+        ) AS post_rank
+    FROM 
+        rssfeed_posts p
+    JOIN 
+        feed_follows ff ON p.feed_id = ff.feed_id AND ff.user_id = $1  -- Parameter 1: user_id
+    JOIN 
+        feeds f ON p.feed_id = f.id
+    LEFT JOIN (
+        SELECT post_id, COUNT(*) AS favorites_count FROM postfavorites GROUP BY post_id
+    ) fc ON p.id = fc.post_id
+    WHERE 
+        p.created_at > $2  -- Parameter 2: posts scraped since the last digest
+) ranked
+WHERE ranked.post_rank <= $3  -- Parameter 3: posts per feed
+ORDER BY ranked.feed_name, ranked.post_rank;
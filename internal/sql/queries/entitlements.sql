@@ -0,0 +1,63 @@
+-- name: GetPlanEntitlements :many
+SELECT plan_id, name, quota, enabled, created_at, updated_at
+FROM plan_entitlements
+WHERE plan_id = $1
+ORDER BY name;
+
+-- name: GetUserPlanEntitlements :many
+-- a user's own subscription comes first, then that of the organization they are a member
+-- of as long as its plan has a seat for them, and last the free plan
+WITH user_plan AS (
+    SELECT plan.id, plan.name, plan.subscribed, plan.organization_id
+    FROM (
+        SELECT p.id, p.name, TRUE AS subscribed, NULL::BIGINT AS organization_id, 0 AS rank, s.start_date
+        FROM subscriptions s
+        JOIN payment_plans p ON s.plan_id = p.id
+        WHERE s.user_id = $1
+            AND s.status IN ('active', 'past_due', 'cancelled')
+            AND (s.status != 'cancelled' OR s.end_date > now())
+        UNION ALL
+        SELECT p.id, p.name, TRUE AS subscribed, o.id AS organization_id, 1 AS rank, s.start_date
+        FROM organization_members m
+        JOIN organizations o ON o.id = m.organization_id
+        JOIN subscriptions s ON s.user_id = o.owner_id
+        JOIN payment_plans p ON s.plan_id = p.id
+        WHERE m.user_id = $1 AND m.role <> 'owner' AND p.seats > 1
+            AND s.status IN ('active', 'past_due', 'cancelled')
+            AND (s.status != 'cancelled' OR s.end_date > now())
+            -- seats go to the owner first and then to members in the order they joined
+            AND (
+                SELECT COUNT(*) FROM organization_members om
+                WHERE om.organization_id = m.organization_id
+                    AND (om.role = 'owner' OR om.joined_at < m.joined_at OR (om.joined_at = m.joined_at AND om.user_id < m.user_id))
+            ) < p.seats
+        UNION ALL
+        SELECT p.id, p.name, FALSE AS subscribed, NULL::BIGINT AS organization_id, 2 AS rank, p.created_at AS start_date
+        FROM payment_plans p
+        WHERE p.duration = 'free' AND p.status = 'active'
+    ) plan
+    ORDER BY plan.rank, plan.start_date DESC
+    LIMIT 1
+)
+SELECT
+    up.id AS plan_id,
+    up.name AS plan_name,
+    up.subscribed::BOOLEAN AS subscribed,
+    up.organization_id,
+    pe.name AS entitlement,
+    pe.quota,
+    pe.enabled
+FROM user_plan up
+LEFT JOIN plan_entitlements pe ON pe.plan_id = up.id
+ORDER BY pe.name;
+
+-- name: UpsertPlanEntitlement :one
+INSERT INTO plan_entitlements (plan_id, name, quota, enabled)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (plan_id, name) DO UPDATE
+SET quota = EXCLUDED.quota, enabled = EXCLUDED.enabled, updated_at = NOW()
+RETURNING created_at, updated_at;
+
+-- name: DeletePlanEntitlementsExcept :exec
+DELETE FROM plan_entitlements
+WHERE plan_id = $1 AND NOT (name = ANY($2::TEXT[]));
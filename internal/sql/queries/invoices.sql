@@ -0,0 +1,70 @@
+-- name: GetBillingDetails :one
+SELECT user_id, name, company, address, country, tax_id, updated_at, version
+FROM billing_details
+WHERE user_id = $1;
+
+-- name: UpsertBillingDetails :one
+INSERT INTO billing_details (user_id, name, company, address, country, tax_id)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (user_id) DO UPDATE
+SET name = EXCLUDED.name, company = EXCLUDED.company, address = EXCLUDED.address,
+    country = EXCLUDED.country, tax_id = EXCLUDED.tax_id,
+    updated_at = NOW(), version = billing_details.version + 1
+RETURNING updated_at, version;
+
+-- name: CreateInvoice :one
+WITH next_number AS (
+    UPDATE invoice_numbers
+    SET last_number = last_number + 1
+    WHERE id
+    RETURNING last_number
+)
+INSERT INTO invoices (
+    number, user_id, subscription_id, plan_id, transaction_id, provider, payment_method, currency,
+    subtotal, discount, tax, tax_rate, tax_name, total, billing_name, billing_email, billing_company,
+    billing_address, billing_country, billing_tax_id
+)
+SELECT next_number.last_number, $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15,
+    $16, $17, $18, $19
+FROM next_number
+RETURNING id, number, status, issued_at;
+
+-- name: CreateInvoiceLineItem :exec
+INSERT INTO invoice_line_items (invoice_id, position, description, quantity, unit_amount, amount)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: GetInvoiceByID :one
+SELECT id, number, user_id, subscription_id, plan_id, transaction_id, provider, payment_method, currency,
+    subtotal, discount, tax, tax_rate, tax_name, total, billing_name, billing_email, billing_company,
+    billing_address, billing_country, billing_tax_id, status, issued_at
+FROM invoices
+WHERE id = $1;
+
+-- name: GetInvoiceLineItems :many
+SELECT id, invoice_id, position, description, quantity, unit_amount, amount
+FROM invoice_line_items
+WHERE invoice_id = $1
+ORDER BY position;
+
+-- name: GetInvoicesByUser :many
+SELECT count(*) OVER() AS total_records, id, number, user_id, subscription_id, plan_id, transaction_id,
+    provider, payment_method, currency, subtotal, discount, tax, tax_rate, tax_name, total, billing_name,
+    billing_email, billing_company, billing_address, billing_country, billing_tax_id, status, issued_at
+FROM invoices
+WHERE user_id = $1
+ORDER BY number DESC
+LIMIT $2 OFFSET $3;
+
+-- name: AdminSearchInvoices :many
+SELECT count(*) OVER() AS total_records, id, number, user_id, subscription_id, plan_id, transaction_id,
+    provider, payment_method, currency, subtotal, discount, tax, tax_rate, tax_name, total, billing_name,
+    billing_email, billing_company, billing_address, billing_country, billing_tax_id, status, issued_at
+FROM invoices
+WHERE ($1::text = '' OR billing_email ILIKE '%' || $1::text || '%' OR billing_name ILIKE '%' || $1::text || '%'
+        OR billing_company ILIKE '%' || $1::text || '%' OR number = $2::bigint)  -- Parameter 2: the number $1 is, 0 when it isn't one
+    AND ($3::bigint = 0 OR user_id = $3::bigint)
+    AND ($4::text = '' OR currency = $4::text)
+    AND ($5::timestamptz = '0001-01-01 00:00:00+00' OR issued_at >= $5::timestamptz)
+    AND ($6::timestamptz = '0001-01-01 00:00:00+00' OR issued_at <= $6::timestamptz)
+ORDER BY number DESC
+LIMIT $7 OFFSET $8;
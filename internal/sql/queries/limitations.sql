@@ -4,7 +4,8 @@ WITH user_activities AS (
     u.id AS user_id, 
     COUNT(DISTINCT ff.feed_id) AS followed_feeds,
     COUNT(DISTINCT f.id) AS created_feeds,
-    COUNT(DISTINCT c.id) AS comments_today
+    COUNT(DISTINCT c.id) AS comments_today,
+    (SELECT COUNT(*) FROM saved_searches ss WHERE ss.user_id = u.id) AS saved_searches
   FROM
     users u
   LEFT JOIN
@@ -0,0 +1,150 @@
+-- name: InsertCommentReport :one
+INSERT INTO comment_reports (comment_id, reported_by, reason, details)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (comment_id, reported_by) DO NOTHING
+RETURNING *;
+
+-- name: GetCommentModerationQueue :many
+SELECT count(*) OVER() AS total_records,
+    c.id,
+    c.post_id,
+    c.user_id,
+    users.name AS user_name,
+    c.comment_text,
+    c.status,
+    c.created_at,
+    COUNT(r.id) AS report_count,
+    array_agg(DISTINCT r.reason)::text[] AS reasons,
+    MAX(r.created_at)::timestamptz AS last_reported_at
+FROM comment_reports r
+JOIN comments c ON c.id = r.comment_id
+JOIN users ON users.id = c.user_id
+WHERE r.status = $1
+GROUP BY c.id, users.name
+ORDER BY report_count DESC, last_reported_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: GetCommentReports :many
+SELECT
+    r.id,
+    r.comment_id,
+    r.reported_by,
+    users.name AS reported_by_user_name,
+    r.reason,
+    r.details,
+    r.status,
+    r.created_at
+FROM comment_reports r
+JOIN users ON users.id = r.reported_by
+WHERE r.comment_id = $1
+ORDER BY r.created_at DESC;
+
+-- name: GetCommentForModeration :one
+SELECT
+    c.id,
+    c.user_id,
+    c.status,
+    EXISTS (SELECT 1 FROM comments r WHERE r.parent_comment_id = c.id) AS has_replies
+FROM comments c
+WHERE c.id = $1;
+
+-- name: SetCommentStatus :exec
+UPDATE comments
+SET status = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: ModerationRemoveComment :exec
+UPDATE comments
+SET status = 'removed', comment_text = '', updated_at = NOW(), version = version + 1
+WHERE id = $1;
+
+-- name: InsertCommentModerationAction :one
+INSERT INTO comment_moderation_actions (comment_id, comment_user_id, action, reason, acted_by)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: ResolveCommentReports :execrows
+UPDATE comment_reports
+SET status = $2, resolved_by = $3, resolved_at = NOW()
+WHERE comment_id = $1 AND status = 'open';
+
+-- name: GetCommentModerationActions :many
+SELECT
+    a.id,
+    a.comment_id,
+    a.comment_user_id,
+    a.action,
+    a.reason,
+    a.acted_by,
+    users.name AS acted_by_user_name,
+    a.created_at
+FROM comment_moderation_actions a
+LEFT JOIN users ON users.id = a.acted_by
+WHERE a.comment_id = $1
+ORDER BY a.created_at DESC, a.id DESC;
+
+-- name: UpsertCommentRestriction :one
+INSERT INTO comment_restrictions (user_id, restriction, reason, expires_at, restricted_by)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (user_id) DO UPDATE
+SET restriction = EXCLUDED.restriction,
+    reason = EXCLUDED.reason,
+    expires_at = EXCLUDED.expires_at,
+    restricted_by = EXCLUDED.restricted_by,
+    created_at = NOW()
+RETURNING *;
+
+-- name: DeleteCommentRestriction :one
+DELETE FROM comment_restrictions
+WHERE user_id = $1
+RETURNING user_id;
+
+-- name: GetActiveCommentRestriction :one
+SELECT user_id, restriction, reason, expires_at, restricted_by, created_at
+FROM comment_restrictions
+WHERE user_id = $1 AND (expires_at IS NULL OR expires_at > NOW());
+
+-- name: GetActiveCommentRestrictions :many
+SELECT count(*) OVER() AS total_records,
+    cr.user_id,
+    u.name AS user_name,
+    cr.restriction,
+    cr.reason,
+    cr.expires_at,
+    cr.restricted_by,
+    admin.name AS restricted_by_user_name,
+    cr.created_at
+FROM comment_restrictions cr
+JOIN users u ON u.id = cr.user_id
+LEFT JOIN users admin ON admin.id = cr.restricted_by
+WHERE cr.expires_at IS NULL OR cr.expires_at > NOW()
+ORDER BY cr.created_at DESC
+LIMIT $1 OFFSET $2;
+
+-- name: InsertCommentScreening :exec
+INSERT INTO comment_screenings (comment_id, reasons, classifier_score)
+VALUES ($1, $2, $3)
+ON CONFLICT (comment_id) DO UPDATE
+SET reasons = EXCLUDED.reasons, classifier_score = EXCLUDED.classifier_score, created_at = NOW();
+
+-- name: GetCommentScreening :one
+SELECT comment_id, reasons, classifier_score, created_at
+FROM comment_screenings
+WHERE comment_id = $1;
+
+-- name: GetPendingComments :many
+SELECT count(*) OVER() AS total_records,
+    c.id,
+    c.post_id,
+    c.user_id,
+    users.name AS user_name,
+    c.comment_text,
+    c.created_at,
+    s.reasons,
+    s.classifier_score
+FROM comments c
+JOIN users ON users.id = c.user_id
+JOIN comment_screenings s ON s.comment_id = c.id
+WHERE c.status = 'pending'
+ORDER BY c.created_at ASC, c.id ASC
+LIMIT $1 OFFSET $2;
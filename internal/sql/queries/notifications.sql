@@ -64,6 +64,7 @@ RETURNING *;
     INNER JOIN
         rssfeed_posts rp ON c.post_id = rp.id
     WHERE
+        c.status = 'visible' AND
         c.post_id IN (
             SELECT pf.post_id
             FROM postfavorites pf
@@ -89,16 +90,54 @@ UNION ALL
     INNER JOIN
         rssfeed_posts rp ON c.post_id = rp.id
     WHERE
+        c.status = 'visible' AND
         c.parent_comment_id IN (
             SELECT id
             FROM comments
             WHERE user_id = $1
         )
 )
+UNION ALL
+(
+    -- Mention Notifications
+    SELECT
+        cn.id AS notification_id,
+        cn.post_id AS post_id,
+        'You were mentioned' AS notification_type,
+        c.id AS comment_id,
+        COALESCE(c.parent_comment_id, '00000000-0000-0000-0000-000000000000') AS replied_comment_id,
+        LEFT(c.comment_text, 20) AS comment_snippet,
+        rp.itemtitle AS post_title,
+        cn.created_at
+    FROM
+        comment_notifications cn
+    INNER JOIN
+        comments c ON cn.comment_id = c.id
+    INNER JOIN
+        rssfeed_posts rp ON c.post_id = rp.id
+    WHERE
+        c.status = 'visible' AND
+        c.id IN (
+            SELECT cm.comment_id
+            FROM comment_mentions cm
+            WHERE cm.user_id = $1
+        )
+)
 ORDER BY
     created_at DESC;
 
 
 -- name: DeleteReadCommentNotification :exec
 DELETE FROM comment_notifications
-WHERE post_id=$1;
\ No newline at end of file
+WHERE post_id=$1;
+
+-- name: GetFeedFollowerIDs :many
+SELECT user_id FROM feed_follows
+WHERE feed_id = $1;
+
+-- name: GetCommentNotificationRecipients :many
+SELECT pf.user_id FROM postfavorites pf  -- users who favorited the post
+WHERE pf.post_id = $1 AND pf.user_id <> $3
+UNION
+SELECT c.user_id FROM comments c  -- the author of the comment being replied to
+WHERE c.id = $2 AND c.user_id <> $3;
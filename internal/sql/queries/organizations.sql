@@ -0,0 +1,162 @@
+-- name: CreateOrganization :one
+WITH organization AS (
+    INSERT INTO organizations (name, owner_id)
+    VALUES ($1, $2)
+    RETURNING id, owner_id, created_at, updated_at, version
+), membership AS (
+    INSERT INTO organization_members (organization_id, user_id, role)
+    SELECT id, owner_id, 'owner' FROM organization
+)
+SELECT id, created_at, updated_at, version FROM organization;
+
+-- name: GetOrganizationForUser :one
+SELECT
+    o.id, o.name, o.owner_id, o.created_at, o.updated_at, o.version, m.role, m.joined_at,
+    plan.plan_id, plan.plan_name, CAST(COALESCE(plan.seats, 1) AS INTEGER) AS seats,
+    (SELECT COUNT(*) FROM organization_members om WHERE om.organization_id = o.id) AS members,
+    (SELECT COUNT(*) FROM organization_invitations oi WHERE oi.organization_id = o.id AND oi.expires_at > NOW()) AS pending_invitations
+FROM organization_members m
+JOIN organizations o ON o.id = m.organization_id
+LEFT JOIN LATERAL (
+    SELECT p.id AS plan_id, p.name AS plan_name, p.seats
+    FROM subscriptions s
+    JOIN payment_plans p ON p.id = s.plan_id
+    WHERE s.user_id = o.owner_id
+        AND s.status IN ('active', 'past_due', 'cancelled')
+        AND (s.status != 'cancelled' OR s.end_date > now())
+    ORDER BY s.start_date DESC
+    LIMIT 1
+) plan ON TRUE
+WHERE m.user_id = $1;
+
+-- name: UpdateOrganization :one
+UPDATE organizations
+SET name = $1, updated_at = NOW(), version = version + 1
+WHERE id = $2 AND version = $3
+RETURNING updated_at, version;
+
+-- name: DeleteOrganization :execrows
+DELETE FROM organizations
+WHERE id = $1;
+
+-- name: GetOrganizationMembers :many
+-- members take the seats in the order they joined, the owner's always comes first
+SELECT
+    m.user_id, u.name, u.email, u.handle, m.role, m.joined_at,
+    ROW_NUMBER() OVER (ORDER BY m.role = 'owner' DESC, m.joined_at, m.user_id) AS seat
+FROM organization_members m
+JOIN users u ON u.id = m.user_id
+WHERE m.organization_id = $1
+ORDER BY seat;
+
+-- name: UpdateOrganizationMemberRole :execrows
+UPDATE organization_members
+SET role = $3
+WHERE organization_id = $1 AND user_id = $2 AND role <> 'owner';
+
+-- name: DeleteOrganizationMember :execrows
+DELETE FROM organization_members
+WHERE organization_id = $1 AND user_id = $2 AND role <> 'owner';
+
+-- name: CreateOrganizationInvitation :one
+INSERT INTO organization_invitations (organization_id, email, role, token_hash, invited_by, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (organization_id, email) DO UPDATE
+SET role = EXCLUDED.role, token_hash = EXCLUDED.token_hash, invited_by = EXCLUDED.invited_by,
+    expires_at = EXCLUDED.expires_at, created_at = NOW()
+RETURNING id, created_at;
+
+-- name: GetOrganizationInvitations :many
+SELECT i.id, i.email, i.role, i.invited_by, COALESCE(u.name, '') AS invited_by_name, i.expires_at, i.created_at
+FROM organization_invitations i
+LEFT JOIN users u ON u.id = i.invited_by
+WHERE i.organization_id = $1 AND i.expires_at > NOW()
+ORDER BY i.created_at DESC, i.id DESC;
+
+-- name: DeleteOrganizationInvitation :execrows
+DELETE FROM organization_invitations
+WHERE id = $1 AND organization_id = $2;
+
+-- name: GetOrganizationInvitationByToken :one
+SELECT i.id, i.organization_id, o.name AS organization_name, i.email, i.role, i.expires_at
+FROM organization_invitations i
+JOIN organizations o ON o.id = i.organization_id
+WHERE i.token_hash = $1 AND i.expires_at > NOW();
+
+-- name: AcceptOrganizationInvitation :one
+WITH invitation AS (
+    DELETE FROM organization_invitations
+    WHERE id = $1
+    RETURNING organization_id, role
+)
+INSERT INTO organization_members (organization_id, user_id, role)
+SELECT organization_id, $2::bigint, role FROM invitation
+RETURNING organization_id, role, joined_at;
+
+-- name: CreateOrganizationFolder :one
+INSERT INTO organization_folders (organization_id, name, created_by)
+VALUES ($1, $2, $3)
+RETURNING id, created_at, updated_at, version;
+
+-- name: GetOrganizationFolders :many
+SELECT
+    f.id, f.name, f.created_by, f.created_at, f.updated_at, f.version,
+    (SELECT COUNT(*) FROM organization_feeds sf WHERE sf.folder_id = f.id) AS feeds
+FROM organization_folders f
+WHERE f.organization_id = $1
+ORDER BY f.name;
+
+-- name: GetOrganizationFolderByID :one
+SELECT id, organization_id, name, created_by, created_at, updated_at, version
+FROM organization_folders
+WHERE id = $1 AND organization_id = $2;
+
+-- name: UpdateOrganizationFolder :one
+UPDATE organization_folders
+SET name = $1, updated_at = NOW(), version = version + 1
+WHERE id = $2 AND organization_id = $3 AND version = $4
+RETURNING updated_at, version;
+
+-- name: DeleteOrganizationFolder :execrows
+DELETE FROM organization_folders
+WHERE id = $1 AND organization_id = $2;
+
+-- name: ShareOrganizationFeed :one
+-- only approved feeds everyone can see are shared, into a folder of the same organization
+-- or with a folder of 0 outside of any
+INSERT INTO organization_feeds (organization_id, feed_id, folder_id, shared_by)
+SELECT $1::bigint, f.id, NULLIF($3::bigint, 0), $4::bigint
+FROM feeds f
+WHERE f.id = $2 AND NOT f.is_hidden AND f.approval_status = 'approved'
+    AND ($3::bigint = 0 OR EXISTS (
+        SELECT 1 FROM organization_folders fo WHERE fo.id = $3::bigint AND fo.organization_id = $1::bigint
+    ))
+RETURNING created_at;
+
+-- name: GetOrganizationFeeds :many
+SELECT
+    count(*) OVER() AS total_records,
+    f.id, f.name, f.url, f.img_url, f.feed_type, f.feed_description,
+    sf.folder_id, COALESCE(fo.name, '') AS folder_name,
+    sf.shared_by, COALESCE(u.name, '') AS shared_by_name, sf.created_at AS shared_at
+FROM organization_feeds sf
+JOIN feeds f ON f.id = sf.feed_id
+LEFT JOIN organization_folders fo ON fo.id = sf.folder_id
+LEFT JOIN users u ON u.id = sf.shared_by
+WHERE sf.organization_id = $1
+    AND ($2::bigint = 0 OR sf.folder_id = $2::bigint)
+ORDER BY sf.created_at DESC, f.name
+LIMIT $3 OFFSET $4;
+
+-- name: MoveOrganizationFeed :execrows
+UPDATE organization_feeds
+SET folder_id = NULLIF($3::bigint, 0)
+WHERE organization_id = $1 AND feed_id = $2
+    AND ($3::bigint = 0 OR EXISTS (
+        SELECT 1 FROM organization_folders fo WHERE fo.id = $3::bigint AND fo.organization_id = $1
+    ));
+
+-- name: UnshareOrganizationFeed :execrows
+-- members only take back the feeds they shared, admins pass 0 to take back any
+DELETE FROM organization_feeds
+WHERE organization_id = $1 AND feed_id = $2 AND ($3::bigint = 0 OR shared_by = $3::bigint);
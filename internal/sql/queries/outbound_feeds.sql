@@ -0,0 +1,28 @@
+-- name: CreateOutboundFeed :one
+INSERT INTO outbound_feeds (token_hash, user_id, source, feed_id, saved_search_id)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, created_at;
+
+-- name: GetOutboundFeedsForUser :many
+SELECT 
+    o.id,
+    o.user_id,
+    o.source,
+    o.feed_id,
+    o.saved_search_id,
+    o.created_at,
+    o.last_accessed_at
+FROM outbound_feeds o
+WHERE o.user_id = $1
+ORDER BY o.created_at DESC;
+
+-- name: GetOutboundFeedByToken :one
+UPDATE outbound_feeds
+SET last_accessed_at = NOW()
+WHERE token_hash = $1
+RETURNING id, user_id, source, feed_id, saved_search_id, created_at, last_accessed_at;
+
+-- name: DeleteOutboundFeed :one
+DELETE FROM outbound_feeds
+WHERE id = $1 AND user_id = $2
+RETURNING id;
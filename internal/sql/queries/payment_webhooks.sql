@@ -0,0 +1,34 @@
+-- name: InsertPaymentWebhookEvent :execrows
+INSERT INTO payment_webhook_events (event_id, provider, event_type)
+VALUES ($1, $2, $3)
+ON CONFLICT (event_id) DO NOTHING;
+
+-- name: DeletePaymentWebhookEvent :exec
+DELETE FROM payment_webhook_events
+WHERE event_id = $1;
+
+-- name: CreatePaymentIntent :exec
+INSERT INTO payment_intents (reference, user_id, plan_id, amount, renews_subscription_id, provider)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (reference) DO NOTHING;
+
+-- name: GetPaymentIntentByReference :one
+SELECT
+    pi.reference, pi.user_id, pi.plan_id, pi.amount, pi.renews_subscription_id, pi.provider,
+    u.name, u.email
+FROM payment_intents pi
+JOIN users u ON u.id = pi.user_id
+WHERE pi.reference = $1;
+
+-- name: CancelSubscriptionsByAuthorizationCode :many
+UPDATE subscriptions
+SET status = 'cancelled', updated_at = NOW()
+WHERE authorization_code = $1 AND status IN ('active', 'past_due')
+RETURNING id, user_id, end_date;
+
+-- name: GetLatestSubscriptionByAuthorizationCode :one
+SELECT id, user_id, plan_id, price, currency, card_last4, card_exp_month, card_exp_year, card_type
+FROM subscriptions
+WHERE authorization_code = $1
+ORDER BY start_date DESC
+LIMIT 1;
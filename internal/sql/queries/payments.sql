@@ -2,25 +2,39 @@
 INSERT INTO subscriptions (
 		user_id, plan_id, start_date, end_date, price, status, 
 		transaction_id, payment_method, authorization_code, 
-		card_last4, card_exp_month, card_exp_year, card_type, currency
+		card_last4, card_exp_month, card_exp_year, card_type, currency,
+		provider, provider_reference
 ) VALUES (
-	$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14
+	$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16
 )
 RETURNING id, created_at, updated_at;
 
+-- name: CreateTrialSubscription :one
+INSERT INTO subscriptions (
+    user_id, plan_id, start_date, end_date, price, status, transaction_id, currency, provider, is_trial
+) VALUES (
+    $1, $2, $3, $4, $5, 'active', 0, $6, $7, TRUE
+)
+RETURNING id, created_at, updated_at;
+
+-- name: HasUserHadTrial :one
+SELECT EXISTS (
+    SELECT 1 FROM subscriptions WHERE user_id = $1 AND is_trial
+);
+
 -- name: GetPaymentPlans :many
-SELECT id, name, image, description, duration, price, features, created_at, updated_at, status
+SELECT id, name, image, description, duration, price, currency, features, created_at, updated_at, status, trial_days, seats
 FROM payment_plans
 WHERE status = 'active'
 ORDER BY price;
 
 -- name: GetPaymentPlanByID :one
-SELECT id, name, image, description, duration, price, features, created_at, updated_at, status
+SELECT id, name, image, description, duration, price, currency, features, created_at, updated_at, status, trial_days, seats
 FROM payment_plans
 WHERE id = $1 AND status = 'active';
 
 -- name: GetSubscriptionByID :one
-SELECT id, user_id, plan_id, start_date, end_date, price, status
+SELECT id, user_id, plan_id, start_date, end_date, price, currency, status, is_trial
 FROM subscriptions
 WHERE user_id = $1 AND status = 'active' AND end_date > NOW();
 
@@ -83,33 +97,13 @@ JOIN
     payment_plans p ON s.plan_id = p.id
 WHERE 
     s.user_id = $1
-    AND s.status IN ('active', 'cancelled')
+    AND s.status IN ('active', 'past_due', 'cancelled')
     AND (s.status != 'cancelled' OR s.end_date > now())  -- Only include 'cancelled' if end_date is in the future
 ORDER BY 
     s.start_date DESC
 LIMIT 1;
 
 
--- name: GetAllExpiredSubscriptions :many
-SELECT count(*) OVER() as total_records,
-    s.id AS subscription_id,
-    s.authorization_code,
-    s.plan_id,
-    s.price,
-    s.currency,
-    s.user_id,
-    u.email,
-    u.name
-FROM 
-    subscriptions s
-JOIN 
-    users u ON s.user_id = u.id
-WHERE 
-    s.status = 'expired' AND s.end_date < NOW()
-ORDER BY 
-    s.end_date ASC
-LIMIT $1 OFFSET $2;
-
 -- name: GetPendingChallengedTransactionsByUser :many
 SELECT 
     id,
@@ -182,8 +176,9 @@ INSERT INTO failed_transactions (
     card_last4, 
     card_exp_month, 
     card_exp_year, 
-    card_type
-) VALUES ($1, $2, NOW(), $3, $4, $5, $6, $7, $8, $9, $10, $11)
+    card_type,
+    currency
+) VALUES ($1, $2, NOW(), $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 RETURNING id, created_at, updated_at;
 
 -- name: UpdateSubscriptionStatus :one
@@ -195,9 +190,9 @@ RETURNING updated_at;
 
 -- name: UpdateSubscriptionStatusAfterExpiration :many
 UPDATE subscriptions
-SET status = 'expired', updated_at = NOW()
-WHERE end_date < CURRENT_DATE
-AND status NOT IN ('expired', 'renewed', 'cancelled')
+SET status = 'expired', next_renewal_at = NULL, updated_at = NOW()
+WHERE end_date < $1
+AND status = 'past_due'
 RETURNING id, user_id, updated_at;
 
 -- name: UpdateChallengedTransactionStatus :one
@@ -0,0 +1,93 @@
+-- name: GetSubscriptionForPlanChange :one
+SELECT
+    s.id,
+    s.plan_id,
+    s.start_date,
+    s.end_date,
+    s.price,
+    s.status,
+    s.currency,
+    s.provider,
+    s.authorization_code,
+    s.is_trial,
+    u.id AS user_id,
+    u.name,
+    u.email
+FROM subscriptions s
+JOIN users u ON s.user_id = u.id
+WHERE s.user_id = $1
+    AND s.status = 'active'
+    AND s.end_date > NOW()
+ORDER BY s.start_date DESC
+LIMIT 1;
+
+-- name: CreatePlanChange :one
+INSERT INTO subscription_plan_changes (
+    user_id, subscription_id, from_plan_id, to_plan_id, change_type, status,
+    credit, amount_charged, currency, reference, effective_at
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+RETURNING id, created_at, updated_at;
+
+-- name: GetOpenPlanChangeBySubscriptionID :one
+SELECT id, user_id, subscription_id, new_subscription_id, from_plan_id, to_plan_id, change_type,
+    status, credit, amount_charged, currency, reference, effective_at, created_at, updated_at
+FROM subscription_plan_changes
+WHERE subscription_id = $1 AND status IN ('pending', 'scheduled');
+
+-- name: GetPendingPlanChangeByReference :one
+SELECT id, user_id, subscription_id, new_subscription_id, from_plan_id, to_plan_id, change_type,
+    status, credit, amount_charged, currency, reference, effective_at, created_at, updated_at
+FROM subscription_plan_changes
+WHERE reference = $1 AND status = 'pending';
+
+-- name: CompletePlanChange :one
+UPDATE subscription_plan_changes
+SET status = 'completed', new_subscription_id = $2, amount_charged = $3, effective_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND status IN ('pending', 'scheduled')
+RETURNING updated_at;
+
+-- name: UpdatePlanChangeStatus :one
+UPDATE subscription_plan_changes
+SET status = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING updated_at;
+
+-- name: SetPlanChangePending :one
+UPDATE subscription_plan_changes
+SET status = 'pending', reference = $2, updated_at = NOW()
+WHERE id = $1 AND status IN ('pending', 'scheduled')
+RETURNING updated_at;
+
+-- name: CancelScheduledPlanChange :one
+UPDATE subscription_plan_changes
+SET status = 'cancelled', updated_at = NOW()
+WHERE user_id = $1 AND status = 'scheduled'
+RETURNING id, user_id, subscription_id, new_subscription_id, from_plan_id, to_plan_id, change_type,
+    status, credit, amount_charged, currency, reference, effective_at, created_at, updated_at;
+
+-- name: GetPlanChangesByUser :many
+SELECT
+    count(*) OVER() AS total_records,
+    c.id,
+    c.user_id,
+    c.subscription_id,
+    c.new_subscription_id,
+    c.from_plan_id,
+    fp.name AS from_plan_name,
+    c.to_plan_id,
+    tp.name AS to_plan_name,
+    c.change_type,
+    c.status,
+    c.credit,
+    c.amount_charged,
+    c.currency,
+    c.reference,
+    c.effective_at,
+    c.created_at,
+    c.updated_at
+FROM subscription_plan_changes c
+JOIN payment_plans fp ON c.from_plan_id = fp.id
+JOIN payment_plans tp ON c.to_plan_id = tp.id
+WHERE c.user_id = $1
+ORDER BY c.created_at DESC, c.id DESC
+LIMIT $2 OFFSET $3;
@@ -0,0 +1,20 @@
+-- name: GetPaymentPlanPrices :many
+SELECT plan_id, currency, amount, created_at, updated_at
+FROM payment_plan_prices
+WHERE plan_id = $1
+ORDER BY currency;
+
+-- name: GetAllPaymentPlanPrices :many
+SELECT plan_id, currency, amount, created_at, updated_at
+FROM payment_plan_prices
+ORDER BY plan_id, currency;
+
+-- name: UpsertPaymentPlanPrice :exec
+INSERT INTO payment_plan_prices (plan_id, currency, amount)
+VALUES ($1, $2, $3)
+ON CONFLICT (plan_id, currency) DO UPDATE
+SET amount = EXCLUDED.amount, updated_at = NOW();
+
+-- name: DeletePaymentPlanPricesExcept :exec
+DELETE FROM payment_plan_prices
+WHERE plan_id = $1 AND NOT (currency = ANY($2::TEXT[]));
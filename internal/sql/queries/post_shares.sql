@@ -0,0 +1,77 @@
+-- name: CreatePostShare :one
+INSERT INTO post_shares (post_id, user_id, share_type, note, token_hash)
+SELECT p.id, $2, $3, $4, $5
+FROM rssfeed_posts p
+WHERE p.id = $1
+RETURNING id, created_at;
+
+-- name: GetShareRecipients :many
+SELECT id, handle
+FROM users
+WHERE handle = ANY($1::citext[]) AND activated = true AND id <> $2  -- users can't share with themselves
+ORDER BY handle;
+
+-- name: InsertPostShareRecipients :exec
+INSERT INTO post_share_recipients (share_id, recipient_id)
+SELECT $1, unnest($2::bigint[])
+ON CONFLICT (share_id, recipient_id) DO NOTHING;
+
+-- name: IncrementPostShareCount :exec
+INSERT INTO post_share_counts (post_id, direct_shares, link_shares)
+VALUES ($1, $2, $3)
+ON CONFLICT (post_id) DO UPDATE
+SET direct_shares = post_share_counts.direct_shares + EXCLUDED.direct_shares,
+    link_shares = post_share_counts.link_shares + EXCLUDED.link_shares;
+
+-- name: GetPostShareCount :one
+SELECT post_id, direct_shares, link_shares
+FROM post_share_counts
+WHERE post_id = $1;
+
+-- name: GetSharedPostByToken :one
+WITH share AS (
+    UPDATE post_shares
+    SET view_count = view_count + 1, last_viewed_at = NOW()
+    WHERE token_hash = $1 AND share_type = 'link'
+    RETURNING id, post_id, user_id, note, view_count, created_at
+)
+SELECT
+    share.id,
+    share.note,
+    share.view_count,
+    share.created_at,
+    users.name AS shared_by_name,
+    users.handle AS shared_by_handle,
+    p.id AS post_id,
+    p.channeltitle,
+    p.itemtitle,
+    p.itemdescription,
+    p.img_url,
+    p.itempublished_at,
+    p.feed_id,
+    COALESCE(c.direct_shares + c.link_shares, 0)::bigint AS share_count
+FROM share
+JOIN rssfeed_posts p ON p.id = share.post_id
+JOIN users ON users.id = share.user_id
+LEFT JOIN post_share_counts c ON c.post_id = share.post_id;
+
+-- name: GetPostShareLinksForUser :many
+SELECT
+    count(*) OVER() AS total_records,
+    s.id,
+    s.post_id,
+    p.itemtitle,
+    s.note,
+    s.view_count,
+    s.created_at,
+    s.last_viewed_at
+FROM post_shares s
+JOIN rssfeed_posts p ON p.id = s.post_id
+WHERE s.user_id = $1 AND s.share_type = 'link'
+ORDER BY s.created_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: DeletePostShareLink :one
+DELETE FROM post_shares
+WHERE id = $1 AND user_id = $2 AND share_type = 'link'
+RETURNING id;
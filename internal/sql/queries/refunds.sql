@@ -0,0 +1,75 @@
+-- name: GetSubscriptionForRefund :one
+SELECT
+    s.id, s.user_id, s.plan_id, s.status, s.is_trial, s.transaction_id, s.provider, s.provider_reference,
+    s.currency, s.end_date, i.id AS invoice_id,
+    CAST(COALESCE(i.total, s.price - COALESCE(cr.discount, 0)) AS BIGINT) AS amount_paid,
+    s.amount_refunded,
+    u.name AS user_name, u.email AS user_email, pp.name AS plan_name
+FROM subscriptions s
+JOIN users u ON u.id = s.user_id
+JOIN payment_plans pp ON pp.id = s.plan_id
+LEFT JOIN invoices i ON i.subscription_id = s.id
+LEFT JOIN coupon_redemptions cr ON cr.subscription_id = s.id AND cr.status = 'redeemed'
+WHERE s.id = $1;
+
+-- name: CreateRefund :one
+WITH refunded AS (
+    UPDATE subscriptions
+    SET amount_refunded = amount_refunded + $6::bigint
+    WHERE id = $1::uuid AND amount_refunded + $6::bigint <= $11::bigint  -- Parameter 11: what the subscription was paid
+    RETURNING id
+)
+INSERT INTO refunds (
+    subscription_id, user_id, transaction_id, invoice_id, provider, amount, currency, reason,
+    ended_access, refunded_by
+)
+SELECT $1::uuid, $2::bigint, $3::bigint, $4::bigint, $5::text, $6::bigint, $7::text, $8::text, $9::boolean, $10::bigint
+FROM refunded
+RETURNING id, status, created_at, updated_at;
+
+-- name: UpdateRefundStatus :one
+WITH released AS (
+    UPDATE subscriptions s
+    SET amount_refunded = s.amount_refunded - rf.amount
+    FROM refunds rf
+    WHERE rf.id = $1 AND s.id = rf.subscription_id AND rf.status <> 'failed' AND $2 = 'failed'
+)
+UPDATE refunds
+SET status = $2, provider_refund_id = $3, failure_reason = $4, updated_at = NOW()
+WHERE id = $1
+RETURNING updated_at;
+
+-- name: GetRefundsBySubscriptionID :many
+SELECT id, subscription_id, user_id, transaction_id, invoice_id, provider, provider_refund_id, amount,
+    currency, reason, status, failure_reason, ended_access, refunded_by, created_at, updated_at
+FROM refunds
+WHERE subscription_id = $1
+ORDER BY created_at DESC, id DESC;
+
+-- name: GetAllRefunds :many
+SELECT count(*) OVER() AS total_records, id, subscription_id, user_id, transaction_id, invoice_id, provider,
+    provider_refund_id, amount, currency, reason, status, failure_reason, ended_access, refunded_by,
+    created_at, updated_at
+FROM refunds
+WHERE ($1::text = '' OR status = $1::text)
+ORDER BY created_at DESC, id DESC
+LIMIT $2 OFFSET $3;
+
+-- name: EndSubscriptionAccess :one
+UPDATE subscriptions
+SET status = 'cancelled', end_date = LEAST(end_date, NOW()), updated_at = NOW()
+WHERE id = $1 AND status IN ('active', 'past_due', 'cancelled')
+RETURNING end_date;
+
+-- name: UpdateInvoiceRefundStatus :exec
+UPDATE invoices i
+SET status = CASE WHEN s.amount_refunded >= i.total THEN 'refunded' ELSE 'partially_refunded' END
+FROM subscriptions s
+WHERE i.id = $1 AND s.id = i.subscription_id;
+
+-- name: RefundsByCurrency :many
+SELECT currency, COUNT(*) AS refunds, CAST(SUM(amount) AS BIGINT) AS total_refunded
+FROM refunds
+WHERE status <> 'failed'
+GROUP BY currency
+ORDER BY currency;
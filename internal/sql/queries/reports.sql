@@ -14,12 +14,6 @@ subscription_churn_rate AS (
     WHERE status = 'cancelled' AND end_date >= NOW() - INTERVAL '30 days'
 ),
 
--- Average Revenue Per User (ARPU)
-arpu AS (
-    SELECT COALESCE(AVG(price), 0.0) AS average_revenue_per_user
-    FROM subscriptions
-),
-
 -- Average Subscription Duration
 average_subscription_duration AS (
     SELECT COALESCE(AVG(end_date - start_date), INTERVAL '0 seconds') AS average_duration
@@ -27,12 +21,6 @@ average_subscription_duration AS (
     WHERE status = 'cancelled'
 ),
 
--- Total Revenue
-total_revenue AS (
-    SELECT COALESCE(SUM(price), 0) AS total_revenue
-    FROM subscriptions
-),
-
 -- Most Popular Payment Method
 most_popular_payment_method AS (
     SELECT 
@@ -76,9 +64,7 @@ subscriptions_near_expiry AS (
 SELECT 
     (SELECT total_active_subscriptions FROM active_subscriptions) AS total_active_subscriptions,
     (SELECT churn_rate FROM subscription_churn_rate) AS churn_rate,
-    (SELECT average_revenue_per_user FROM arpu) AS average_revenue_per_user,
     (SELECT EXTRACT(EPOCH FROM average_duration) FROM average_subscription_duration) AS average_duration,
-    (SELECT total_revenue FROM total_revenue) AS total_revenue,
     (SELECT payment_method FROM most_popular_payment_method) AS most_popular_payment_method,
     (SELECT plan_id FROM most_subscribed_plan) AS most_subscribed_plan,
     (SELECT challenged_rate FROM challenged_transactions_rate) AS challenged_rate,
@@ -88,10 +74,31 @@ SELECT
 -- name: RevenueByPlan :many
 SELECT 
     pp.name AS plan_name, 
-    CAST(SUM(s.price) AS BIGINT) AS total_revenue
+    s.currency,
+    CAST(SUM(s.price - COALESCE(r.discount, 0) - COALESCE(rf.amount, 0)) AS BIGINT) AS total_revenue
 FROM subscriptions s
 JOIN payment_plans pp ON s.plan_id = pp.id
-GROUP BY pp.name;
+LEFT JOIN coupon_redemptions r ON r.subscription_id = s.id AND r.status = 'redeemed'
+LEFT JOIN (
+    SELECT subscription_id, SUM(amount) AS amount FROM refunds WHERE status <> 'failed' GROUP BY subscription_id
+) rf ON rf.subscription_id = s.id
+WHERE NOT s.is_trial
+GROUP BY pp.name, s.currency
+ORDER BY pp.name, s.currency;
+
+-- name: RevenueByCurrency :many
+SELECT 
+    s.currency,
+    CAST(SUM(s.price - COALESCE(r.discount, 0) - COALESCE(rf.amount, 0)) AS BIGINT) AS total_revenue,
+    CAST(ROUND(AVG(s.price - COALESCE(r.discount, 0) - COALESCE(rf.amount, 0))) AS BIGINT) AS average_revenue_per_user
+FROM subscriptions s
+LEFT JOIN coupon_redemptions r ON r.subscription_id = s.id AND r.status = 'redeemed'
+LEFT JOIN (
+    SELECT subscription_id, SUM(amount) AS amount FROM refunds WHERE status <> 'failed' GROUP BY subscription_id
+) rf ON rf.subscription_id = s.id
+WHERE NOT s.is_trial
+GROUP BY s.currency
+ORDER BY s.currency;
 
 
 -- name: ChallengedTransactionsOutcome :many
@@ -103,10 +110,17 @@ GROUP BY status;
 
 -- name: RevenueByPaymentMethod :many
 SELECT 
-    payment_method, 
-    COALESCE(SUM(price), 0) AS revenue
-FROM subscriptions
-GROUP BY payment_method;
+    s.payment_method, 
+    s.currency,
+    CAST(SUM(s.price - COALESCE(r.discount, 0) - COALESCE(rf.amount, 0)) AS BIGINT) AS revenue
+FROM subscriptions s
+LEFT JOIN coupon_redemptions r ON r.subscription_id = s.id AND r.status = 'redeemed'
+LEFT JOIN (
+    SELECT subscription_id, SUM(amount) AS amount FROM refunds WHERE status <> 'failed' GROUP BY subscription_id
+) rf ON rf.subscription_id = s.id
+WHERE NOT s.is_trial
+GROUP BY s.payment_method, s.currency
+ORDER BY s.payment_method, s.currency;
 
 -- name: SubscriptionsOverTime :many
 SELECT 
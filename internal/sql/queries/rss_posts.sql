@@ -74,9 +74,59 @@ LEFT JOIN (
 WHERE 
     ($2 = '' OR to_tsvector('simple', p.itemtitle) @@ plainto_tsquery('simple', $2))  -- Parameter 2: itemtitle (full-text search for item title)
     AND ($3::uuid = '00000000-0000-0000-0000-000000000000' OR p.feed_id = $3::uuid)  -- Parameter 3: feed_id (filter by feed_id if provided)
+    AND ($4::timestamptz = '0001-01-01 00:00:00+00' OR p.itempublished_at >= $4::timestamptz)  -- Parameter 4: since (published date)
+    AND ($5::timestamptz = '0001-01-01 00:00:00+00' OR p.itempublished_at <= $5::timestamptz)  -- Parameter 5: until (published date)
 ORDER BY 
-    p.created_at DESC
-LIMIT $4 OFFSET $5;  -- Parameters 4 and 5: limit and offset
+    CASE WHEN $6::text = 'published_at' THEN p.itempublished_at END ASC,  -- Parameter 6: sort
+    CASE WHEN $6::text = 'created_at' THEN p.created_at END ASC,
+    CASE WHEN $6::text = '-created_at' THEN p.created_at END DESC,
+    -- the counts are only taken when sorting by them, and only for the posts that matched
+    CASE WHEN $6::text = 'favorites_count' THEN (SELECT COUNT(*) FROM postfavorites c WHERE c.post_id = p.id) END ASC,
+    CASE WHEN $6::text = '-favorites_count' THEN (SELECT COUNT(*) FROM postfavorites c WHERE c.post_id = p.id) END DESC,
+    CASE WHEN $6::text = 'comments_count' THEN (SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id) END ASC,
+    CASE WHEN $6::text = '-comments_count' THEN (SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id) END DESC,
+    p.itempublished_at DESC,  -- default, -published_at
+    p.id DESC
+LIMIT $7 OFFSET $8;  -- Parameters 7 and 8: limit and offset
+
+-- name: GetFollowedRssPostsForUserByCursor :many
+SELECT 
+    p.*, 
+    COALESCE(pf.is_favorite, false) AS is_favorite
+FROM 
+    rssfeed_posts p
+JOIN (
+    SELECT 
+        ff.feed_id 
+    FROM 
+        feed_follows ff
+    WHERE 
+        ff.user_id = $1  -- Parameter 1: user_id
+) ff ON p.feed_id = ff.feed_id
+LEFT JOIN (
+    SELECT 
+        pf.post_id,
+        true AS is_favorite
+    FROM 
+        postfavorites pf
+    WHERE 
+        pf.user_id = $1  -- Parameter 1: user_id
+) pf ON p.id = pf.post_id
+WHERE 
+    ($2 = '' OR to_tsvector('simple', p.itemtitle) @@ plainto_tsquery('simple', $2))  -- Parameter 2: itemtitle (full-text search for item title)
+    AND ($3::uuid = '00000000-0000-0000-0000-000000000000' OR p.feed_id = $3::uuid)  -- Parameter 3: feed_id (filter by feed_id if provided)
+    AND ($8::timestamptz = '0001-01-01 00:00:00+00' OR p.itempublished_at >= $8::timestamptz)  -- Parameter 8: since (published date)
+    AND ($9::timestamptz = '0001-01-01 00:00:00+00' OR p.itempublished_at <= $9::timestamptz)  -- Parameter 9: until (published date)
+    AND (
+        ($6::boolean = false AND (p.itempublished_at, p.id) < ($4::timestamptz, $5::uuid))  -- Parameters 4 and 5: cursor keyset, older posts
+        OR ($6::boolean = true AND (p.itempublished_at, p.id) > ($4::timestamptz, $5::uuid))  -- Parameter 6: backward, newer posts
+    )
+ORDER BY 
+    CASE WHEN $6::boolean THEN p.itempublished_at END ASC,
+    CASE WHEN $6::boolean THEN p.id END ASC,
+    p.itempublished_at DESC,
+    p.id DESC
+LIMIT $7;  -- Parameter 7: limit
 
 
 -- name: GetRSSFavoritePostsForUser :many
@@ -122,9 +172,61 @@ WHERE
     f.user_id = $1  -- Parameter 1: user_id
     AND ($2 = '' OR to_tsvector('simple', p.itemtitle) @@ plainto_tsquery('simple', $2))  -- Parameter 2: itemtitle (full-text search for item title)
     AND ($3::uuid = '00000000-0000-0000-0000-000000000000' OR p.feed_id = $3::uuid)  -- Parameter 3: feed_id (filter by feed_id if provided)
+    AND ($4::timestamptz = '0001-01-01 00:00:00+00' OR p.itempublished_at >= $4::timestamptz)  -- Parameter 4: since (published date)
+    AND ($5::timestamptz = '0001-01-01 00:00:00+00' OR p.itempublished_at <= $5::timestamptz)  -- Parameter 5: until (published date)
+ORDER BY 
+    CASE WHEN $6::text = 'published_at' THEN p.itempublished_at END ASC,  -- Parameter 6: sort
+    CASE WHEN $6::text = 'created_at' THEN p.created_at END ASC,
+    CASE WHEN $6::text = '-created_at' THEN p.created_at END DESC,
+    -- the counts are only taken when sorting by them, and only for the posts that matched
+    CASE WHEN $6::text = 'favorites_count' THEN (SELECT COUNT(*) FROM postfavorites c WHERE c.post_id = p.id) END ASC,
+    CASE WHEN $6::text = '-favorites_count' THEN (SELECT COUNT(*) FROM postfavorites c WHERE c.post_id = p.id) END DESC,
+    CASE WHEN $6::text = 'comments_count' THEN (SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id) END ASC,
+    CASE WHEN $6::text = '-comments_count' THEN (SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id) END DESC,
+    p.itempublished_at DESC,  -- default, -published_at
+    p.id DESC
+LIMIT $7 OFFSET $8;
+
+-- name: GetRSSFavoritePostsOnlyForUserByCursor :many
+SELECT 
+    p.id,
+    p.created_at,
+    p.updated_at,
+    p.channeltitle,
+    p.channelurl,
+    p.channeldescription,
+    p.channellanguage,
+    p.itemtitle,
+    p.itemdescription,
+    p.itemcontent,
+    p.itempublished_at,
+    p.itemurl,
+    p.img_url,
+    p.feed_id,
+    true AS is_favorite,
+    COALESCE(ff.user_id IS NOT NULL, false) AS is_followed_feed
+FROM 
+    rssfeed_posts p
+JOIN 
+    postfavorites f ON p.id = f.post_id
+LEFT JOIN
+    feed_follows ff ON p.feed_id = ff.feed_id AND ff.user_id = $1
+WHERE 
+    f.user_id = $1  -- Parameter 1: user_id
+    AND ($2 = '' OR to_tsvector('simple', p.itemtitle) @@ plainto_tsquery('simple', $2))  -- Parameter 2: itemtitle (full-text search for item title)
+    AND ($3::uuid = '00000000-0000-0000-0000-000000000000' OR p.feed_id = $3::uuid)  -- Parameter 3: feed_id (filter by feed_id if provided)
+    AND ($8::timestamptz = '0001-01-01 00:00:00+00' OR p.itempublished_at >= $8::timestamptz)  -- Parameter 8: since (published date)
+    AND ($9::timestamptz = '0001-01-01 00:00:00+00' OR p.itempublished_at <= $9::timestamptz)  -- Parameter 9: until (published date)
+    AND (
+        ($6::boolean = false AND (p.itempublished_at, p.id) < ($4::timestamptz, $5::uuid))  -- Parameters 4 and 5: cursor keyset, older posts
+        OR ($6::boolean = true AND (p.itempublished_at, p.id) > ($4::timestamptz, $5::uuid))  -- Parameter 6: backward, newer posts
+    )
 ORDER BY 
-    p.created_at DESC
-LIMIT $4 OFFSET $5;
+    CASE WHEN $6::boolean THEN p.itempublished_at END ASC,
+    CASE WHEN $6::boolean THEN p.id END ASC,
+    p.itempublished_at DESC,
+    p.id DESC
+LIMIT $7;
 
 
 -- name: GetRandomRSSPosts :many
@@ -0,0 +1,164 @@
+-- name: CreateSavedSearch :one
+INSERT INTO saved_searches (user_id, name, query, feed_id, start_date, end_date, notify)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+-- name: GetSavedSearchesForUser :many
+SELECT 
+    s.*,
+    (
+        SELECT COUNT(p.id)
+        FROM rssfeed_posts p
+        JOIN feed_follows ff ON p.feed_id = ff.feed_id AND ff.user_id = s.user_id
+        WHERE 
+            p.created_at > s.last_viewed_at
+            AND (s.query = '' OR to_tsvector('simple', p.itemtitle) @@ plainto_tsquery('simple', s.query))
+            AND (s.feed_id IS NULL OR p.feed_id = s.feed_id)
+            AND (s.start_date IS NULL OR p.itempublished_at >= s.start_date)
+            AND (s.end_date IS NULL OR p.itempublished_at <= s.end_date)
+    ) AS unread_count
+FROM saved_searches s
+WHERE s.user_id = $1
+ORDER BY s.created_at DESC;
+
+-- name: GetSavedSearchByID :one
+SELECT * FROM saved_searches
+WHERE id = $1 AND user_id = $2;
+
+-- name: UpdateSavedSearch :one
+UPDATE saved_searches
+SET 
+    name = $1,
+    query = $2,
+    feed_id = $3,
+    start_date = $4,
+    end_date = $5,
+    notify = $6,
+    updated_at = NOW(),
+    version = version + 1
+WHERE id = $7 AND user_id = $8 AND version = $9
+RETURNING version, updated_at;
+
+-- name: DeleteSavedSearch :one
+DELETE FROM saved_searches
+WHERE id = $1 AND user_id = $2
+RETURNING id;
+
+-- name: MarkSavedSearchAsViewed :exec
+UPDATE saved_searches
+SET last_viewed_at = NOW()
+WHERE id = $1 AND user_id = $2;
+
+-- name: GetSavedSearchRssPostsForUser :many
+SELECT 
+    p.*, 
+    COALESCE(pf.is_favorite, false) AS is_favorite,
+    COUNT(*) OVER() AS total_count
+FROM 
+    rssfeed_posts p
+JOIN 
+    saved_searches s ON s.id = $2 AND s.user_id = $1  -- Parameter 2: saved search id
+JOIN 
+    feed_follows ff ON p.feed_id = ff.feed_id AND ff.user_id = $1  -- Parameter 1: user_id
+LEFT JOIN (
+    SELECT 
+        pf.post_id,
+        true AS is_favorite
+    FROM 
+        postfavorites pf
+    WHERE 
+        pf.user_id = $1
+) pf ON p.id = pf.post_id
+WHERE 
+    (s.query = '' OR to_tsvector('simple', p.itemtitle) @@ plainto_tsquery('simple', s.query))
+    AND (s.feed_id IS NULL OR p.feed_id = s.feed_id)
+    AND (s.start_date IS NULL OR p.itempublished_at >= s.start_date)
+    AND (s.end_date IS NULL OR p.itempublished_at <= s.end_date)
+ORDER BY 
+    p.itempublished_at DESC,
+    p.id DESC
+LIMIT $3 OFFSET $4;
+
+-- name: GetSavedSearchRssPostsForUserByCursor :many
+SELECT 
+    p.*, 
+    COALESCE(pf.is_favorite, false) AS is_favorite
+FROM 
+    rssfeed_posts p
+JOIN 
+    saved_searches s ON s.id = $2 AND s.user_id = $1  -- Parameter 2: saved search id
+JOIN 
+    feed_follows ff ON p.feed_id = ff.feed_id AND ff.user_id = $1  -- Parameter 1: user_id
+LEFT JOIN (
+    SELECT 
+        pf.post_id,
+        true AS is_favorite
+    FROM 
+        postfavorites pf
+    WHERE 
+        pf.user_id = $1
+) pf ON p.id = pf.post_id
+WHERE 
+    (s.query = '' OR to_tsvector('simple', p.itemtitle) @@ plainto_tsquery('simple', s.query))
+    AND (s.feed_id IS NULL OR p.feed_id = s.feed_id)
+    AND (s.start_date IS NULL OR p.itempublished_at >= s.start_date)
+    AND (s.end_date IS NULL OR p.itempublished_at <= s.end_date)
+    AND (
+        ($5::boolean = false AND (p.itempublished_at, p.id) < ($3::timestamptz, $4::uuid))  -- Parameters 3 and 4: cursor keyset, older posts
+        OR ($5::boolean = true AND (p.itempublished_at, p.id) > ($3::timestamptz, $4::uuid))  -- Parameter 5: backward, newer posts
+    )
+ORDER BY 
+    CASE WHEN $5::boolean THEN p.itempublished_at END ASC,
+    CASE WHEN $5::boolean THEN p.id END ASC,
+    p.itempublished_at DESC,
+    p.id DESC
+LIMIT $6;
+
+-- name: FetchSavedSearchNotifications :many
+SELECT
+    s.id AS saved_search_id,
+    s.user_id,
+    s.name AS saved_search_name,
+    COUNT(p.id) AS post_count
+FROM
+    saved_searches s
+INNER JOIN
+    feed_follows ff ON ff.user_id = s.user_id
+INNER JOIN
+    rssfeed_posts p ON p.feed_id = ff.feed_id
+WHERE
+    s.notify = TRUE
+    AND p.created_at >= timezone('UTC', now()) - ($1 * INTERVAL '1 minute')
+    AND p.created_at <= timezone('UTC', now())
+    AND (s.query = '' OR to_tsvector('simple', p.itemtitle) @@ plainto_tsquery('simple', s.query))
+    AND (s.feed_id IS NULL OR p.feed_id = s.feed_id)
+    AND (s.start_date IS NULL OR p.itempublished_at >= s.start_date)
+    AND (s.end_date IS NULL OR p.itempublished_at <= s.end_date)
+GROUP BY
+    s.id, s.user_id, s.name;
+
+-- name: InsertSavedSearchNotification :one
+INSERT INTO saved_search_notifications (saved_search_id, user_id, post_count, created_at)
+VALUES ($1, $2, $3, $4)
+RETURNING id;
+
+-- name: GetUserSavedSearchNotifications :many
+SELECT
+    n.id AS notification_id,
+    n.saved_search_id,
+    s.name AS saved_search_name,
+    n.post_count,
+    n.created_at
+FROM
+    saved_search_notifications n
+INNER JOIN
+    saved_searches s ON n.saved_search_id = s.id
+WHERE
+    n.user_id = $1
+    AND n.created_at >= now() - ($2 * INTERVAL '1 minute')
+ORDER BY
+    n.created_at DESC;
+
+-- name: ClearSavedSearchNotifications :exec
+DELETE FROM saved_search_notifications
+WHERE created_at <= now() - ($1 * INTERVAL '1 minute');
@@ -0,0 +1,148 @@
+-- name: GetUserProfileByHandle :one
+SELECT
+    u.id,
+    u.name,
+    u.handle,
+    u.user_img,
+    u.created_at,
+    COALESCE(ps.is_private, false) AS is_private,
+    COALESCE(ps.share_favorites, false) AS share_favorites,
+    (SELECT COUNT(*) FROM user_follows f WHERE f.followed_id = u.id AND f.status = 'accepted') AS follower_count,
+    (SELECT COUNT(*) FROM user_follows f WHERE f.follower_id = u.id AND f.status = 'accepted') AS following_count,
+    COALESCE((
+        SELECT f.status FROM user_follows f WHERE f.follower_id = $2 AND f.followed_id = u.id
+    ), '')::text AS follow_status
+FROM users u
+LEFT JOIN profile_settings ps ON ps.user_id = u.id
+WHERE u.handle = $1 AND u.activated = true;
+
+-- name: GetProfileFeeds :many
+SELECT count(*) OVER() AS total_records,
+    f.id,
+    f.name,
+    f.url,
+    f.img_url,
+    f.feed_type,
+    f.feed_description,
+    f.created_at,
+    (SELECT COUNT(*) FROM feed_follows ff WHERE ff.feed_id = f.id) AS follow_count
+FROM feeds f
+WHERE f.user_id = $1 AND f.approval_status = 'approved' AND f.is_hidden = false
+ORDER BY f.created_at DESC, f.id DESC
+LIMIT $2 OFFSET $3;
+
+-- name: GetProfileFavorites :many
+SELECT count(*) OVER() AS total_records,
+    p.id,
+    p.itemtitle,
+    p.itemurl,
+    p.img_url,
+    p.itempublished_at,
+    p.feed_id,
+    feeds.name AS feed_name,
+    pf.created_at AS favorited_at
+FROM postfavorites pf
+JOIN rssfeed_posts p ON p.id = pf.post_id
+JOIN feeds ON feeds.id = p.feed_id
+WHERE pf.user_id = $1 AND feeds.is_hidden = false
+ORDER BY pf.created_at DESC, pf.id DESC
+LIMIT $2 OFFSET $3;
+
+-- name: InsertUserFollow :one
+INSERT INTO user_follows (follower_id, followed_id, status)
+VALUES ($1, $2, $3)
+ON CONFLICT (follower_id, followed_id) DO NOTHING
+RETURNING *;
+
+-- name: DeleteUserFollow :execrows
+DELETE FROM user_follows
+WHERE follower_id = $1 AND followed_id = $2;
+
+-- name: GetFollowRequests :many
+SELECT count(*) OVER() AS total_records,
+    u.id,
+    u.name,
+    u.handle,
+    u.user_img,
+    f.created_at
+FROM user_follows f
+JOIN users u ON u.id = f.follower_id
+WHERE f.followed_id = $1 AND f.status = 'pending'
+ORDER BY f.created_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: AcceptFollowRequest :execrows
+UPDATE user_follows
+SET status = 'accepted'
+WHERE follower_id = $1 AND followed_id = $2 AND status = 'pending';
+
+-- name: AcceptAllFollowRequests :exec
+UPDATE user_follows
+SET status = 'accepted'
+WHERE followed_id = $1 AND status = 'pending';
+
+-- name: GetProfileSettings :one
+SELECT * FROM profile_settings
+WHERE user_id = $1;
+
+-- name: UpsertProfileSettings :one
+INSERT INTO profile_settings (user_id, is_private, share_favorites)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id) DO UPDATE
+SET
+    is_private = EXCLUDED.is_private,
+    share_favorites = EXCLUDED.share_favorites,
+    updated_at = NOW()
+RETURNING *;
+
+-- name: GetFollowActivity :many
+WITH followed AS (
+    SELECT followed_id FROM user_follows
+    WHERE follower_id = $1 AND status = 'accepted'
+), activity AS (
+    SELECT
+        'feed'::text AS activity_type,
+        f.user_id,
+        f.id AS feed_id,
+        f.name AS feed_name,
+        NULL::uuid AS post_id,
+        ''::text AS post_title,
+        NULL::uuid AS comment_id,
+        ''::text AS comment_text,
+        f.created_at
+    FROM feeds f
+    WHERE f.user_id IN (SELECT followed_id FROM followed)
+        AND f.approval_status = 'approved' AND f.is_hidden = false
+    UNION ALL
+    SELECT
+        'comment'::text,
+        c.user_id,
+        p.feed_id,
+        feeds.name,
+        c.post_id,
+        p.itemtitle,
+        c.id,
+        c.comment_text,
+        c.created_at
+    FROM comments c
+    JOIN rssfeed_posts p ON p.id = c.post_id
+    JOIN feeds ON feeds.id = p.feed_id
+    WHERE c.user_id IN (SELECT followed_id FROM followed) AND c.status = 'visible'
+)
+SELECT count(*) OVER() AS total_records,
+    a.activity_type,
+    a.user_id,
+    users.name AS user_name,
+    users.handle AS user_handle,
+    users.user_img,
+    a.feed_id,
+    a.feed_name,
+    a.post_id,
+    a.post_title,
+    a.comment_id,
+    a.comment_text,
+    a.created_at
+FROM activity a
+JOIN users ON users.id = a.user_id
+ORDER BY a.created_at DESC
+LIMIT $2 OFFSET $3;
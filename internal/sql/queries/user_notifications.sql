@@ -0,0 +1,97 @@
+-- name: InsertUserNotification :one
+INSERT INTO user_notifications (user_id, notification_type, message, feed_id, post_id, comment_id)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: InsertFeedFollowerNotifications :exec
+INSERT INTO user_notifications (user_id, notification_type, message, feed_id)
+SELECT ff.user_id, 'new_posts', $2::text, ff.feed_id
+FROM feed_follows ff
+WHERE ff.feed_id = $1;
+
+-- name: InsertCommentNotifications :exec
+INSERT INTO user_notifications (user_id, notification_type, message, post_id, comment_id)
+SELECT DISTINCT ON (recipients.user_id)
+    recipients.user_id,
+    recipients.notification_type,
+    LEFT(c.comment_text, 100),
+    c.post_id,
+    c.id
+FROM comments c
+JOIN LATERAL (
+    -- a reply takes precedence over a mention, which takes precedence over a favorite
+    SELECT parent.user_id, 'reply' AS notification_type, 1 AS precedence
+    FROM comments parent
+    WHERE parent.id = c.parent_comment_id
+    UNION ALL
+    SELECT cm.user_id, 'mention' AS notification_type, 2 AS precedence
+    FROM comment_mentions cm
+    WHERE cm.comment_id = c.id
+    UNION ALL
+    SELECT pf.user_id, 'favorite_comment' AS notification_type, 3 AS precedence
+    FROM postfavorites pf
+    WHERE pf.post_id = c.post_id
+) recipients ON recipients.user_id <> c.user_id
+WHERE c.id = $1
+ORDER BY recipients.user_id, recipients.precedence;
+
+-- name: InsertMentionNotifications :exec
+INSERT INTO user_notifications (user_id, notification_type, message, post_id, comment_id)
+SELECT mentioned.user_id, 'mention', LEFT(c.comment_text, 100), c.post_id, c.id
+FROM comments c
+CROSS JOIN unnest($2::bigint[]) AS mentioned(user_id)
+WHERE c.id = $1;
+
+-- name: GetUserNotificationsInbox :many
+SELECT count(*) OVER() AS total_records,
+    id,
+    user_id,
+    notification_type,
+    message,
+    feed_id,
+    post_id,
+    comment_id,
+    read_at,
+    dismissed_at,
+    created_at
+FROM 
+    user_notifications
+WHERE 
+    user_id = $1
+    AND dismissed_at IS NULL
+    AND ($2::text = 'all' OR ($2::text = 'unread' AND read_at IS NULL) OR ($2::text = 'read' AND read_at IS NOT NULL))
+    AND ($3::text = '' OR notification_type = $3::text)
+ORDER BY
+    created_at DESC, id DESC
+LIMIT $4 OFFSET $5;
+
+-- name: GetUnreadUserNotificationCount :one
+SELECT COUNT(*) FROM user_notifications
+WHERE user_id = $1 AND read_at IS NULL AND dismissed_at IS NULL;
+
+-- name: MarkUserNotificationRead :one
+UPDATE user_notifications
+SET read_at = CASE WHEN $3::boolean THEN COALESCE(read_at, NOW()) ELSE NULL END
+WHERE id = $1 AND user_id = $2 AND dismissed_at IS NULL
+RETURNING *;
+
+-- name: MarkAllUserNotificationsRead :execrows
+UPDATE user_notifications
+SET read_at = NOW()
+WHERE user_id = $1 AND read_at IS NULL AND dismissed_at IS NULL;
+
+-- name: MarkPostUserNotificationsRead :exec
+UPDATE user_notifications
+SET read_at = NOW()
+WHERE user_id = $1 AND post_id = $2 AND read_at IS NULL;
+
+-- name: DismissUserNotification :one
+UPDATE user_notifications
+SET dismissed_at = NOW(), read_at = COALESCE(read_at, NOW())
+WHERE id = $1 AND user_id = $2 AND dismissed_at IS NULL
+RETURNING id;
+
+-- name: DeleteExpiredUserNotifications :execrows
+DELETE FROM user_notifications
+WHERE created_at < NOW() - ($1::int * INTERVAL '1 day')
+    OR ((read_at IS NOT NULL OR dismissed_at IS NOT NULL) AND created_at < NOW() - ($2::int * INTERVAL '1 day'));
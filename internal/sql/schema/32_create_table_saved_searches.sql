@@ -0,0 +1,32 @@
+-- +goose Up
+CREATE TABLE saved_searches (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    query TEXT NOT NULL DEFAULT '',
+    feed_id UUID REFERENCES feeds(id) ON DELETE CASCADE, -- optional feed scope, there are no folders yet
+    start_date TIMESTAMP(0) WITH TIME ZONE,
+    end_date TIMESTAMP(0) WITH TIME ZONE,
+    notify BOOLEAN NOT NULL DEFAULT FALSE,
+    last_viewed_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    version INT NOT NULL DEFAULT 1,
+    UNIQUE(user_id, name)
+);
+
+CREATE INDEX idx_saved_searches_user_id ON saved_searches(user_id);
+
+CREATE TABLE saved_search_notifications (
+    id SERIAL PRIMARY KEY,
+    saved_search_id UUID NOT NULL REFERENCES saved_searches(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    post_count INT NOT NULL,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_saved_search_notifications_user_id ON saved_search_notifications(user_id);
+
+-- +goose Down
+DROP TABLE saved_search_notifications;
+DROP TABLE saved_searches;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- the cursor pages of the post listings seek and order on (itempublished_at, id), within
+-- the followed feeds or across every feed, so deep pages read straight off these indexes
+CREATE INDEX IF NOT EXISTS idx_rssfeed_posts_feed_id_published_keyset ON rssfeed_posts (feed_id, itempublished_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_rssfeed_posts_published_keyset ON rssfeed_posts (itempublished_at DESC, id DESC);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_rssfeed_posts_published_keyset;
+DROP INDEX IF EXISTS idx_rssfeed_posts_feed_id_published_keyset;
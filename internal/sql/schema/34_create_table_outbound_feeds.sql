@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE outbound_feeds (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    token_hash BYTEA NOT NULL UNIQUE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    source TEXT NOT NULL CHECK (source IN ('timeline', 'feed', 'saved_search', 'favorites')),
+    feed_id UUID REFERENCES feeds(id) ON DELETE CASCADE, -- only for the 'feed' source
+    saved_search_id UUID REFERENCES saved_searches(id) ON DELETE CASCADE, -- only for the 'saved_search' source
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    last_accessed_at TIMESTAMP(0) WITH TIME ZONE
+);
+
+CREATE INDEX idx_outbound_feeds_user_id ON outbound_feeds(user_id);
+
+-- +goose Down
+DROP TABLE outbound_feeds;
@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE digest_preferences (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    frequency TEXT NOT NULL DEFAULT 'daily' CHECK (frequency IN ('daily', 'weekly')),
+    timezone TEXT NOT NULL DEFAULT 'UTC',
+    send_hour INT NOT NULL DEFAULT 8 CHECK (send_hour BETWEEN 0 AND 23), -- local hour
+    weekday INT NOT NULL DEFAULT 1 CHECK (weekday BETWEEN 0 AND 6), -- weekly digests only, 0 is Sunday
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    unsubscribe_token TEXT NOT NULL UNIQUE, -- only allows unsubscribing so we keep it as is
+    last_sent_at TIMESTAMP(0) WITH TIME ZONE,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_digest_preferences_enabled ON digest_preferences(enabled);
+
+-- +goose Down
+DROP TABLE digest_preferences;
@@ -0,0 +1,19 @@
+-- +goose Up
+CREATE TABLE user_notifications (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    notification_type TEXT NOT NULL CHECK (notification_type IN ('new_posts', 'reply', 'favorite_comment', 'feed_approved', 'feed_rejected', 'billing')),
+    message TEXT NOT NULL DEFAULT '',
+    feed_id UUID REFERENCES feeds(id) ON DELETE CASCADE,
+    post_id UUID REFERENCES rssfeed_posts(id) ON DELETE CASCADE,
+    comment_id UUID REFERENCES comments(id) ON DELETE CASCADE,
+    read_at TIMESTAMP(0) WITH TIME ZONE,
+    dismissed_at TIMESTAMP(0) WITH TIME ZONE, -- dismissed notifications are hidden until retention removes them
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_user_notifications_inbox ON user_notifications(user_id, created_at DESC) WHERE dismissed_at IS NULL;
+CREATE INDEX idx_user_notifications_created_at ON user_notifications(created_at);
+
+-- +goose Down
+DROP TABLE user_notifications;
@@ -0,0 +1,20 @@
+-- +goose Up
+-- replies whose parent was deleted before we had a foreign key become top level comments
+UPDATE comments
+SET parent_comment_id = NULL
+WHERE parent_comment_id IS NOT NULL
+    AND parent_comment_id NOT IN (SELECT id FROM comments);
+
+-- deleting a comment must never take other users' replies with it, they become top level
+-- comments just like the replies above did
+ALTER TABLE comments
+ADD CONSTRAINT fk_comments_parent_comment
+FOREIGN KEY (parent_comment_id) REFERENCES comments(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_comments_post_parent ON comments(post_id, parent_comment_id, created_at DESC);
+CREATE INDEX idx_comments_parent_comment_id ON comments(parent_comment_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_comments_parent_comment_id;
+DROP INDEX IF EXISTS idx_comments_post_parent;
+ALTER TABLE comments DROP CONSTRAINT fk_comments_parent_comment;
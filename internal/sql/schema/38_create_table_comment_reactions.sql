@@ -0,0 +1,23 @@
+-- +goose Up
+CREATE TABLE comment_reactions (
+    comment_id UUID NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reaction TEXT NOT NULL CHECK (reaction IN ('upvote', 'downvote', 'heart', 'laugh', 'insightful')),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (comment_id, user_id) -- a user has a single reaction per comment
+);
+
+CREATE INDEX idx_comment_reactions_user_id ON comment_reactions(user_id);
+
+-- comment authors get an inbox notification when their comment is reacted to
+ALTER TABLE user_notifications DROP CONSTRAINT user_notifications_notification_type_check;
+ALTER TABLE user_notifications ADD CONSTRAINT user_notifications_notification_type_check
+CHECK (notification_type IN ('new_posts', 'reply', 'favorite_comment', 'comment_reaction', 'feed_approved', 'feed_rejected', 'billing'));
+
+-- +goose Down
+DELETE FROM user_notifications WHERE notification_type = 'comment_reaction';
+ALTER TABLE user_notifications DROP CONSTRAINT user_notifications_notification_type_check;
+ALTER TABLE user_notifications ADD CONSTRAINT user_notifications_notification_type_check
+CHECK (notification_type IN ('new_posts', 'reply', 'favorite_comment', 'feed_approved', 'feed_rejected', 'billing'));
+DROP TABLE comment_reactions;
@@ -0,0 +1,30 @@
+-- +goose Up
+-- handles are the public @names users are mentioned by. Existing users get one derived
+-- from their name, suffixed with their id so that it is unique.
+ALTER TABLE users ADD COLUMN handle CITEXT;
+UPDATE users SET handle = COALESCE(NULLIF(LEFT(regexp_replace(lower(name), '[^a-z0-9_]', '', 'g'), 20), ''), 'user') || '_' || id;
+ALTER TABLE users ALTER COLUMN handle SET NOT NULL;
+ALTER TABLE users ADD CONSTRAINT users_handle_key UNIQUE (handle);
+
+CREATE TABLE comment_mentions (
+    comment_id UUID NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    handle CITEXT NOT NULL, -- the handle as it was written in the comment
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (comment_id, user_id)
+);
+
+CREATE INDEX idx_comment_mentions_user_id ON comment_mentions(user_id);
+
+ALTER TABLE user_notifications DROP CONSTRAINT user_notifications_notification_type_check;
+ALTER TABLE user_notifications ADD CONSTRAINT user_notifications_notification_type_check
+CHECK (notification_type IN ('new_posts', 'reply', 'mention', 'favorite_comment', 'comment_reaction', 'feed_approved', 'feed_rejected', 'billing'));
+
+-- +goose Down
+DELETE FROM user_notifications WHERE notification_type = 'mention';
+ALTER TABLE user_notifications DROP CONSTRAINT user_notifications_notification_type_check;
+ALTER TABLE user_notifications ADD CONSTRAINT user_notifications_notification_type_check
+CHECK (notification_type IN ('new_posts', 'reply', 'favorite_comment', 'comment_reaction', 'feed_approved', 'feed_rejected', 'billing'));
+DROP TABLE comment_mentions;
+ALTER TABLE users DROP CONSTRAINT users_handle_key;
+ALTER TABLE users DROP COLUMN handle;
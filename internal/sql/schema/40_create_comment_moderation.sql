@@ -0,0 +1,50 @@
+-- +goose Up
+-- hidden comments can be restored by an admin, removed ones are soft deleted and only
+-- kept so that their replies stay in place. Both show as "[removed]".
+ALTER TABLE comments ADD COLUMN status TEXT NOT NULL DEFAULT 'visible'
+CHECK (status IN ('visible', 'hidden', 'removed'));
+
+CREATE TABLE comment_reports (
+    id BIGSERIAL PRIMARY KEY,
+    comment_id UUID NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+    reported_by BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL CHECK (reason IN ('spam', 'harassment', 'hate', 'off_topic', 'other')),
+    details TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'resolved', 'dismissed')),
+    resolved_by BIGINT REFERENCES users(id) ON DELETE SET NULL, -- admin who acted on the report
+    resolved_at TIMESTAMP(0) WITH TIME ZONE,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (comment_id, reported_by)
+);
+
+CREATE INDEX idx_comment_reports_status ON comment_reports(status, comment_id);
+
+-- the audit trail outlives hard deleted comments, hence the nullable references
+CREATE TABLE comment_moderation_actions (
+    id BIGSERIAL PRIMARY KEY,
+    comment_id UUID REFERENCES comments(id) ON DELETE SET NULL,
+    comment_user_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    action TEXT NOT NULL CHECK (action IN ('hide', 'restore', 'delete', 'dismiss')),
+    reason TEXT NOT NULL DEFAULT '',
+    acted_by BIGINT REFERENCES users(id) ON DELETE SET NULL, -- admin who took the action
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_comment_moderation_actions_comment_id ON comment_moderation_actions(comment_id);
+CREATE INDEX idx_comment_moderation_actions_acted_by ON comment_moderation_actions(acted_by);
+
+-- muted users are kept from commenting for a while, banned ones until they are lifted
+CREATE TABLE comment_restrictions (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    restriction TEXT NOT NULL CHECK (restriction IN ('mute', 'ban')),
+    reason TEXT NOT NULL DEFAULT '',
+    expires_at TIMESTAMP(0) WITH TIME ZONE,
+    restricted_by BIGINT REFERENCES users(id) ON DELETE SET NULL, -- admin who restricted the user
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+DROP TABLE comment_restrictions;
+DROP TABLE comment_moderation_actions;
+DROP TABLE comment_reports;
+ALTER TABLE comments DROP COLUMN status;
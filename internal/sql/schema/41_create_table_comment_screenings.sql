@@ -0,0 +1,32 @@
+-- +goose Up
+-- comments flagged by the screening pipeline wait in pending until an admin reviews them
+ALTER TABLE comments DROP CONSTRAINT comments_status_check;
+ALTER TABLE comments ADD CONSTRAINT comments_status_check
+CHECK (status IN ('visible', 'pending', 'hidden', 'removed'));
+
+ALTER TABLE comment_moderation_actions DROP CONSTRAINT comment_moderation_actions_action_check;
+ALTER TABLE comment_moderation_actions ADD CONSTRAINT comment_moderation_actions_action_check
+CHECK (action IN ('hide', 'restore', 'delete', 'dismiss', 'approve', 'reject'));
+
+CREATE TABLE comment_screenings (
+    comment_id UUID PRIMARY KEY REFERENCES comments(id) ON DELETE CASCADE,
+    reasons TEXT[] NOT NULL,
+    classifier_score DOUBLE PRECISION NOT NULL DEFAULT 0,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_comments_pending ON comments(created_at) WHERE status = 'pending';
+CREATE INDEX idx_comments_user_id_created_at ON comments(user_id, created_at);
+
+-- +goose Down
+DROP INDEX idx_comments_user_id_created_at;
+DROP INDEX idx_comments_pending;
+DROP TABLE comment_screenings;
+UPDATE comments SET status = 'hidden' WHERE status = 'pending';
+DELETE FROM comment_moderation_actions WHERE action IN ('approve', 'reject');
+ALTER TABLE comment_moderation_actions DROP CONSTRAINT comment_moderation_actions_action_check;
+ALTER TABLE comment_moderation_actions ADD CONSTRAINT comment_moderation_actions_action_check
+CHECK (action IN ('hide', 'restore', 'delete', 'dismiss'));
+ALTER TABLE comments DROP CONSTRAINT comments_status_check;
+ALTER TABLE comments ADD CONSTRAINT comments_status_check
+CHECK (status IN ('visible', 'hidden', 'removed'));
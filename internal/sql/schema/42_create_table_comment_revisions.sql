@@ -0,0 +1,19 @@
+-- +goose Up
+-- edited_at is only set by the author's edits, unlike updated_at which moderation also bumps
+ALTER TABLE comments ADD COLUMN edited_at TIMESTAMP(0) WITH TIME ZONE;
+UPDATE comments SET edited_at = updated_at WHERE version > 1 AND status = 'visible';
+
+-- a revision is the text a comment had at a version before it was edited
+CREATE TABLE comment_revisions (
+    id BIGSERIAL PRIMARY KEY,
+    comment_id UUID NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+    version INT NOT NULL,
+    comment_text TEXT NOT NULL,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL,
+    replaced_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (comment_id, version)
+);
+
+-- +goose Down
+DROP TABLE comment_revisions;
+ALTER TABLE comments DROP COLUMN edited_at;
@@ -0,0 +1,49 @@
+-- +goose Up
+-- follows of private profiles stay pending until the followed user accepts them
+CREATE TABLE user_follows (
+    follower_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    followed_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status TEXT NOT NULL DEFAULT 'accepted' CHECK (status IN ('accepted', 'pending')),
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (follower_id, followed_id),
+    CHECK (follower_id <> followed_id)
+);
+
+CREATE INDEX idx_user_follows_followed_id ON user_follows(followed_id, status);
+
+-- users without settings have a public profile that doesn't share their favorites
+CREATE TABLE profile_settings (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    is_private BOOLEAN NOT NULL DEFAULT FALSE,
+    share_favorites BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE user_notifications DROP CONSTRAINT user_notifications_notification_type_check;
+ALTER TABLE user_notifications ADD CONSTRAINT user_notifications_notification_type_check
+CHECK (notification_type IN ('new_posts', 'reply', 'mention', 'favorite_comment', 'comment_reaction', 'feed_approved', 'feed_rejected', 'billing', 'follow'));
+
+-- handles that match a static path under /v1/users can't be reached as profiles, users
+-- who took one before they were reserved get their id appended like the backfilled handles.
+-- Someone may have registered that handle already, so a counter is added until it's free.
+UPDATE users u SET handle = (
+    SELECT c.candidate
+    FROM (
+        SELECT u.handle || '_' || u.id AS candidate, 0 AS n
+        UNION ALL
+        SELECT u.handle || '_' || u.id || '_' || n, n FROM generate_series(1, 100) n
+    ) c
+    WHERE NOT EXISTS (SELECT 1 FROM users o WHERE o.handle = c.candidate)
+    ORDER BY c.n
+    LIMIT 1
+), version = version + 1
+WHERE lower(handle) IN ('activated', 'activity', 'digest', 'followers', 'follows', 'password', 'profile');
+
+-- +goose Down
+-- the renamed handles are kept, they are valid either way
+DELETE FROM user_notifications WHERE notification_type = 'follow';
+ALTER TABLE user_notifications DROP CONSTRAINT user_notifications_notification_type_check;
+ALTER TABLE user_notifications ADD CONSTRAINT user_notifications_notification_type_check
+CHECK (notification_type IN ('new_posts', 'reply', 'mention', 'favorite_comment', 'comment_reaction', 'feed_approved', 'feed_rejected', 'billing'));
+DROP TABLE profile_settings;
+DROP TABLE user_follows;
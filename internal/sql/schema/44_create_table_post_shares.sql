@@ -0,0 +1,42 @@
+-- +goose Up
+-- a post shared directly with other users or through a public link, only link shares
+-- have a token and are viewed by anonymous visitors
+CREATE TABLE post_shares (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    post_id UUID NOT NULL REFERENCES rssfeed_posts(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    share_type TEXT NOT NULL CHECK (share_type IN ('direct', 'link')),
+    note TEXT NOT NULL DEFAULT '',
+    token_hash BYTEA UNIQUE,
+    view_count BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    last_viewed_at TIMESTAMP(0) WITH TIME ZONE
+);
+
+CREATE INDEX idx_post_shares_user_id ON post_shares(user_id, share_type);
+
+CREATE TABLE post_share_recipients (
+    share_id UUID NOT NULL REFERENCES post_shares(id) ON DELETE CASCADE,
+    recipient_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY (share_id, recipient_id)
+);
+
+-- running totals per post, revoking a share link doesn't take it off the count
+CREATE TABLE post_share_counts (
+    post_id UUID PRIMARY KEY REFERENCES rssfeed_posts(id) ON DELETE CASCADE,
+    direct_shares BIGINT NOT NULL DEFAULT 0,
+    link_shares BIGINT NOT NULL DEFAULT 0
+);
+
+ALTER TABLE user_notifications DROP CONSTRAINT user_notifications_notification_type_check;
+ALTER TABLE user_notifications ADD CONSTRAINT user_notifications_notification_type_check
+CHECK (notification_type IN ('new_posts', 'reply', 'mention', 'favorite_comment', 'comment_reaction', 'feed_approved', 'feed_rejected', 'billing', 'follow', 'share'));
+
+-- +goose Down
+DELETE FROM user_notifications WHERE notification_type = 'share';
+ALTER TABLE user_notifications DROP CONSTRAINT user_notifications_notification_type_check;
+ALTER TABLE user_notifications ADD CONSTRAINT user_notifications_notification_type_check
+CHECK (notification_type IN ('new_posts', 'reply', 'mention', 'favorite_comment', 'comment_reaction', 'feed_approved', 'feed_rejected', 'billing', 'follow'));
+DROP TABLE post_share_counts;
+DROP TABLE post_share_recipients;
+DROP TABLE post_shares;
@@ -0,0 +1,26 @@
+-- +goose Up
+-- every webhook event we have acted on, a redelivered event finds its id here and is skipped
+CREATE TABLE payment_webhook_events (
+    event_id TEXT PRIMARY KEY,
+    provider TEXT NOT NULL DEFAULT 'paystack',
+    event_type TEXT NOT NULL,
+    received_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- what a transaction reference was started for, so a charge reported by the webhook can
+-- be turned into a subscription without the frontend. renews_subscription_id is only set
+-- for challenged renewals.
+CREATE TABLE payment_intents (
+    reference TEXT PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    plan_id INTEGER NOT NULL REFERENCES payment_plans(id) ON DELETE CASCADE,
+    amount BIGINT NOT NULL,
+    renews_subscription_id UUID REFERENCES subscriptions(id) ON DELETE CASCADE,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_payment_intents_user_id ON payment_intents(user_id);
+
+-- +goose Down
+DROP TABLE payment_intents;
+DROP TABLE payment_webhook_events;
@@ -0,0 +1,27 @@
+-- +goose Up
+-- payments can go through more than one provider. A subscription keeps the provider
+-- its authorization belongs to so renewals are charged through the same one.
+-- provider_reference is what the provider knows the payment by, it replaces the
+-- numeric transaction id as the guard against saving a payment twice as not every
+-- provider has numeric ids.
+ALTER TABLE subscriptions
+    ADD COLUMN provider TEXT NOT NULL DEFAULT 'paystack',
+    ADD COLUMN provider_reference TEXT;
+
+ALTER TABLE subscriptions DROP CONSTRAINT subscriptions_transaction_id_authorization_code_key;
+
+CREATE UNIQUE INDEX idx_subscriptions_provider_reference ON subscriptions(provider, provider_reference);
+
+ALTER TABLE payment_intents
+    ADD COLUMN provider TEXT NOT NULL DEFAULT 'paystack';
+
+-- +goose Down
+ALTER TABLE payment_intents DROP COLUMN provider;
+
+DROP INDEX idx_subscriptions_provider_reference;
+
+ALTER TABLE subscriptions ADD CONSTRAINT subscriptions_transaction_id_authorization_code_key UNIQUE (transaction_id, authorization_code);
+
+ALTER TABLE subscriptions
+    DROP COLUMN provider_reference,
+    DROP COLUMN provider;
@@ -0,0 +1,40 @@
+-- +goose Up
+-- what each plan entitles its subscribers to. Quotas carry a limit, a NULL limit is
+-- unlimited, while features are switched on or off through enabled.
+CREATE TABLE plan_entitlements (
+    plan_id INTEGER NOT NULL REFERENCES payment_plans(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    quota BIGINT CHECK (quota >= 0),
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (plan_id, name)
+);
+
+-- the pre-made plans get what their features list describes
+INSERT INTO plan_entitlements (plan_id, name, quota, enabled)
+SELECT p.id, e.name, e.quota, e.enabled
+FROM payment_plans p
+JOIN (VALUES
+    -- the free plan leaves out the quotas the limitation flags set, so -max-feeds-created,
+    -- -max-feeds-followed and -max-comments keep limiting it until an admin sets them here
+    ('free', 'saved_searches', 3::BIGINT, TRUE),
+    ('free', 'outbound_feeds', NULL, FALSE),
+    ('free', 'saved_search_alerts', NULL, FALSE),
+    ('month', 'feeds_created', 20, TRUE),
+    ('month', 'feeds_followed', 40, TRUE),
+    ('month', 'comments_per_day', 50, TRUE),
+    ('month', 'saved_searches', 20, TRUE),
+    ('month', 'outbound_feeds', NULL, TRUE),
+    ('month', 'saved_search_alerts', NULL, TRUE),
+    ('year', 'feeds_created', NULL, TRUE),
+    ('year', 'feeds_followed', NULL, TRUE),
+    ('year', 'comments_per_day', NULL, TRUE),
+    ('year', 'saved_searches', NULL, TRUE),
+    ('year', 'outbound_feeds', NULL, TRUE),
+    ('year', 'saved_search_alerts', NULL, TRUE)
+) AS e(duration, name, quota, enabled) ON e.duration = p.duration
+WHERE p.name IN ('Free', 'Monthly', 'Annual');
+
+-- +goose Down
+DROP TABLE plan_entitlements;
@@ -0,0 +1,32 @@
+-- +goose Up
+-- a subscription moving to another plan. Upgrades start straight away and are charged
+-- the new plan's price less the credit left on the current subscription, downgrades
+-- are scheduled for when the current subscription ends and are applied as it renews.
+-- pending changes wait on the customer authorizing the charge behind reference.
+CREATE TABLE subscription_plan_changes (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    subscription_id UUID NOT NULL REFERENCES subscriptions(id) ON DELETE CASCADE,
+    new_subscription_id UUID REFERENCES subscriptions(id) ON DELETE SET NULL,
+    from_plan_id INTEGER NOT NULL REFERENCES payment_plans(id) ON DELETE CASCADE,
+    to_plan_id INTEGER NOT NULL REFERENCES payment_plans(id) ON DELETE CASCADE,
+    change_type TEXT NOT NULL CHECK (change_type IN ('upgrade', 'downgrade')),
+    status TEXT NOT NULL CHECK (status IN ('pending', 'scheduled', 'completed', 'cancelled', 'failed')),
+    credit BIGINT NOT NULL DEFAULT 0 CHECK (credit >= 0),
+    amount_charged BIGINT NOT NULL DEFAULT 0 CHECK (amount_charged >= 0),
+    currency VARCHAR(3),
+    reference TEXT,
+    effective_at TIMESTAMP(0) WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_subscription_plan_changes_user_id ON subscription_plan_changes(user_id, created_at DESC);
+CREATE INDEX idx_subscription_plan_changes_reference ON subscription_plan_changes(reference);
+
+-- a subscription only ever has one change waiting on it
+CREATE UNIQUE INDEX idx_subscription_plan_changes_open ON subscription_plan_changes(subscription_id)
+    WHERE status IN ('pending', 'scheduled');
+
+-- +goose Down
+DROP TABLE subscription_plan_changes;
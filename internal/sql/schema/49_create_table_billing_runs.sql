@@ -0,0 +1,68 @@
+-- +goose Up
+-- renewals are made by the billing runner. A subscription is due once it ends, a
+-- renewal that fails leaves it past_due, still usable, while it is retried on the
+-- schedule until the grace period ends and it expires. renewal_locked_until is the
+-- lease an instance of the runner holds on a subscription while it charges it.
+ALTER TABLE subscriptions DROP CONSTRAINT subscriptions_status_check;
+ALTER TABLE subscriptions ADD CONSTRAINT subscriptions_status_check
+    CHECK (status IN ('active', 'past_due', 'renewed', 'cancelled', 'expired'));
+
+ALTER TABLE subscriptions
+    ADD COLUMN renewal_attempts INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN next_renewal_at TIMESTAMP(0) WITH TIME ZONE,
+    ADD COLUMN renewal_locked_until TIMESTAMP(0) WITH TIME ZONE;
+
+CREATE INDEX idx_subscriptions_due ON subscriptions(end_date)
+    WHERE status IN ('active', 'past_due');
+
+-- subscriptions that expired recently were still waiting on their renewal
+UPDATE subscriptions SET status = 'past_due', next_renewal_at = NOW()
+WHERE status = 'expired' AND end_date > NOW() - INTERVAL '7 days';
+
+CREATE TABLE billing_runs (
+    id BIGSERIAL PRIMARY KEY,
+    trigger TEXT NOT NULL CHECK (trigger IN ('schedule', 'admin')),
+    status TEXT NOT NULL DEFAULT 'running' CHECK (status IN ('running', 'completed', 'failed')),
+    processed INTEGER NOT NULL DEFAULT 0,
+    renewed INTEGER NOT NULL DEFAULT 0,
+    retries_scheduled INTEGER NOT NULL DEFAULT 0,
+    challenged INTEGER NOT NULL DEFAULT 0,
+    expired INTEGER NOT NULL DEFAULT 0,
+    errors INTEGER NOT NULL DEFAULT 0,
+    error_message TEXT,
+    started_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMP(0) WITH TIME ZONE
+);
+
+CREATE INDEX idx_billing_runs_started_at ON billing_runs(started_at DESC);
+
+CREATE TABLE billing_run_items (
+    id BIGSERIAL PRIMARY KEY,
+    run_id BIGINT NOT NULL REFERENCES billing_runs(id) ON DELETE CASCADE,
+    subscription_id UUID NOT NULL REFERENCES subscriptions(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    attempt INTEGER NOT NULL,
+    outcome TEXT NOT NULL CHECK (outcome IN ('renewed', 'retry_scheduled', 'challenged', 'expired', 'error')),
+    message TEXT NOT NULL DEFAULT '',
+    next_attempt_at TIMESTAMP(0) WITH TIME ZONE,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_billing_run_items_run_id ON billing_run_items(run_id);
+
+-- +goose Down
+DROP TABLE billing_run_items;
+DROP TABLE billing_runs;
+
+UPDATE subscriptions SET status = 'expired' WHERE status = 'past_due';
+
+DROP INDEX idx_subscriptions_due;
+
+ALTER TABLE subscriptions
+    DROP COLUMN renewal_locked_until,
+    DROP COLUMN next_renewal_at,
+    DROP COLUMN renewal_attempts;
+
+ALTER TABLE subscriptions DROP CONSTRAINT subscriptions_status_check;
+ALTER TABLE subscriptions ADD CONSTRAINT subscriptions_status_check
+    CHECK (status IN ('active', 'renewed', 'cancelled', 'expired'));
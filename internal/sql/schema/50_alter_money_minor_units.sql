@@ -0,0 +1,49 @@
+-- +goose Up
+-- money is kept as whole minor units (cents) of an ISO 4217 currency. Plans were saved
+-- in major units and subscriptions with what was charged in major units, both convert
+-- exactly. A plan's price is in its own currency and payment_plan_prices holds what it
+-- costs in any other currency it is sold in.
+ALTER TABLE payment_plans
+    ALTER COLUMN price TYPE BIGINT USING ROUND(price * 100)::BIGINT,
+    ADD COLUMN currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+    ADD CONSTRAINT payment_plans_price_check CHECK (price >= 0);
+
+CREATE TABLE payment_plan_prices (
+    plan_id INT NOT NULL REFERENCES payment_plans(id) ON DELETE CASCADE,
+    currency VARCHAR(3) NOT NULL,
+    amount BIGINT NOT NULL CHECK (amount >= 0),
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (plan_id, currency)
+);
+
+UPDATE subscriptions SET currency = 'USD' WHERE currency IS NULL;
+
+ALTER TABLE subscriptions
+    ALTER COLUMN price TYPE BIGINT USING ROUND(price * 100)::BIGINT,
+    ALTER COLUMN currency SET NOT NULL;
+
+ALTER TABLE failed_transactions
+    ALTER COLUMN amount TYPE BIGINT USING ROUND(amount * 100)::BIGINT,
+    ADD COLUMN currency VARCHAR(3);
+
+-- plan changes were priced in the same major units as the subscriptions
+UPDATE subscription_plan_changes SET credit = credit * 100, amount_charged = amount_charged * 100;
+
+-- +goose Down
+UPDATE subscription_plan_changes SET credit = credit / 100, amount_charged = amount_charged / 100;
+
+ALTER TABLE failed_transactions
+    DROP COLUMN currency,
+    ALTER COLUMN amount TYPE DECIMAL(10, 2) USING (amount / 100.0)::DECIMAL(10, 2);
+
+ALTER TABLE subscriptions
+    ALTER COLUMN currency DROP NOT NULL,
+    ALTER COLUMN price TYPE DECIMAL(10, 2) USING (price / 100.0)::DECIMAL(10, 2);
+
+DROP TABLE payment_plan_prices;
+
+ALTER TABLE payment_plans
+    DROP CONSTRAINT payment_plans_price_check,
+    DROP COLUMN currency,
+    ALTER COLUMN price TYPE DECIMAL(10, 2) USING (price / 100.0)::DECIMAL(10, 2);
@@ -0,0 +1,64 @@
+-- +goose Up
+-- coupons take a percentage or a fixed amount off the first payment of a subscription.
+-- Codes are saved in upper case, an empty plan_ids means the coupon works on any plan
+-- and a NULL max_redemptions or expires_at doesn't limit it. pending_redemptions counts the
+-- redemptions held for checkouts and trials that weren't paid yet, together with
+-- times_redeemed they never go past max_redemptions.
+CREATE TABLE coupons (
+    id BIGSERIAL PRIMARY KEY,
+    code TEXT NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT '',
+    discount_type TEXT NOT NULL CHECK (discount_type IN ('percent', 'fixed')),
+    percent_off INTEGER CHECK (percent_off BETWEEN 1 AND 99),
+    amount_off BIGINT CHECK (amount_off > 0),
+    currency VARCHAR(3),
+    max_redemptions INTEGER CHECK (max_redemptions > 0),
+    times_redeemed INTEGER NOT NULL DEFAULT 0,
+    pending_redemptions INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP(0) WITH TIME ZONE,
+    plan_ids INTEGER[] NOT NULL DEFAULT '{}',
+    status TEXT NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'inactive')),
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    version INTEGER NOT NULL DEFAULT 1,
+    CHECK (
+        (discount_type = 'percent' AND percent_off IS NOT NULL AND amount_off IS NULL)
+        OR (discount_type = 'fixed' AND amount_off IS NOT NULL AND currency IS NOT NULL AND percent_off IS NULL)
+    )
+);
+
+-- a redemption is pending from when a code is used until the payment it discounts goes
+-- through. The payment is either the checkout behind reference or, for a code used to
+-- start a free trial, the one the trial subscription converts with. A checkout that isn't
+-- paid by expires_at, or a trial that ends unpaid, releases its redemption.
+CREATE TABLE coupon_redemptions (
+    id BIGSERIAL PRIMARY KEY,
+    coupon_id BIGINT NOT NULL REFERENCES coupons(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    plan_id INTEGER NOT NULL REFERENCES payment_plans(id) ON DELETE CASCADE,
+    subscription_id UUID REFERENCES subscriptions(id) ON DELETE SET NULL,
+    reference TEXT UNIQUE,
+    discount BIGINT NOT NULL CHECK (discount > 0),
+    currency VARCHAR(3) NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'redeemed', 'released')),
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    redeemed_at TIMESTAMP(0) WITH TIME ZONE,
+    expires_at TIMESTAMP(0) WITH TIME ZONE
+);
+
+-- a user gets the discount of a coupon once, and holds it on one payment at a time
+CREATE UNIQUE INDEX idx_coupon_redemptions_held ON coupon_redemptions(coupon_id, user_id)
+    WHERE status IN ('pending', 'redeemed');
+CREATE INDEX idx_coupon_redemptions_expires_at ON coupon_redemptions(expires_at)
+    WHERE status = 'pending';
+CREATE INDEX idx_coupon_redemptions_subscription_id ON coupon_redemptions(subscription_id);
+
+-- plans can start with a free trial, a trial is a subscription nothing was paid for yet
+ALTER TABLE payment_plans ADD COLUMN trial_days INTEGER NOT NULL DEFAULT 0 CHECK (trial_days >= 0);
+ALTER TABLE subscriptions ADD COLUMN is_trial BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +goose Down
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS is_trial;
+ALTER TABLE payment_plans DROP COLUMN IF EXISTS trial_days;
+DROP TABLE IF EXISTS coupon_redemptions;
+DROP TABLE IF EXISTS coupons;
@@ -0,0 +1,69 @@
+-- +goose Up
+-- the details a user wants on their invoices, copied onto each invoice when it is issued
+CREATE TABLE billing_details (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL DEFAULT '',
+    company TEXT NOT NULL DEFAULT '',
+    address TEXT NOT NULL DEFAULT '',
+    country VARCHAR(2) NOT NULL DEFAULT '',
+    tax_id TEXT NOT NULL DEFAULT '',
+    updated_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    version INTEGER NOT NULL DEFAULT 1
+);
+
+-- invoice numbers are handed out from a single counter row, unlike a sequence a number
+-- taken by an insert that fails goes back so the numbers run without gaps
+CREATE TABLE invoice_numbers (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    last_number BIGINT NOT NULL DEFAULT 0
+);
+INSERT INTO invoice_numbers (id, last_number) VALUES (TRUE, 0);
+
+-- an invoice is issued for each payment that starts a subscription. Amounts are in minor
+-- units of the currency and include the tax, tax_rate is in basis points.
+CREATE TABLE invoices (
+    id BIGSERIAL PRIMARY KEY,
+    number BIGINT NOT NULL UNIQUE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    subscription_id UUID UNIQUE REFERENCES subscriptions(id) ON DELETE SET NULL,
+    plan_id INTEGER REFERENCES payment_plans(id) ON DELETE SET NULL,
+    transaction_id BIGINT NOT NULL,
+    provider TEXT NOT NULL,
+    payment_method TEXT NOT NULL DEFAULT '',
+    currency VARCHAR(3) NOT NULL,
+    subtotal BIGINT NOT NULL,
+    discount BIGINT NOT NULL DEFAULT 0 CHECK (discount >= 0),
+    tax BIGINT NOT NULL DEFAULT 0 CHECK (tax >= 0),
+    tax_rate INTEGER NOT NULL DEFAULT 0 CHECK (tax_rate >= 0),
+    tax_name TEXT NOT NULL DEFAULT '',
+    total BIGINT NOT NULL CHECK (total >= 0),
+    billing_name TEXT NOT NULL,
+    billing_email TEXT NOT NULL,
+    billing_company TEXT NOT NULL DEFAULT '',
+    billing_address TEXT NOT NULL DEFAULT '',
+    billing_country VARCHAR(2) NOT NULL DEFAULT '',
+    billing_tax_id TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'paid' CHECK (status IN ('paid')),
+    issued_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CHECK (total = subtotal - discount)
+);
+
+CREATE INDEX idx_invoices_user_id ON invoices(user_id);
+CREATE INDEX idx_invoices_issued_at ON invoices(issued_at);
+
+CREATE TABLE invoice_line_items (
+    id BIGSERIAL PRIMARY KEY,
+    invoice_id BIGINT NOT NULL REFERENCES invoices(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    description TEXT NOT NULL,
+    quantity INTEGER NOT NULL DEFAULT 1 CHECK (quantity > 0),
+    unit_amount BIGINT NOT NULL,
+    amount BIGINT NOT NULL,
+    UNIQUE (invoice_id, position)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS invoice_line_items;
+DROP TABLE IF EXISTS invoices;
+DROP TABLE IF EXISTS invoice_numbers;
+DROP TABLE IF EXISTS billing_details;
@@ -0,0 +1,42 @@
+-- +goose Up
+-- refunds give back all or part of what a subscription was paid. A refund is saved as
+-- pending before the provider is asked for it and ends up succeeded or failed, failed
+-- refunds don't count against what is left to refund nor against revenue.
+CREATE TABLE refunds (
+    id BIGSERIAL PRIMARY KEY,
+    subscription_id UUID NOT NULL REFERENCES subscriptions(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    transaction_id BIGINT NOT NULL,
+    invoice_id BIGINT REFERENCES invoices(id) ON DELETE SET NULL,
+    provider TEXT NOT NULL,
+    provider_refund_id TEXT NOT NULL DEFAULT '',
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    currency VARCHAR(3) NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'succeeded', 'failed')),
+    failure_reason TEXT NOT NULL DEFAULT '',
+    ended_access BOOLEAN NOT NULL DEFAULT FALSE,
+    refunded_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_refunds_subscription_id ON refunds(subscription_id);
+CREATE INDEX idx_refunds_created_at ON refunds(created_at);
+
+-- what has been refunded of a subscription is kept on it so a refund can check and take
+-- its share in the one row update, refunds made at once then queue up on the row instead
+-- of each summing the refunds before the others were saved. Failed refunds give theirs back.
+ALTER TABLE subscriptions ADD COLUMN amount_refunded BIGINT NOT NULL DEFAULT 0;
+
+-- an invoice shows whether what it was issued for was refunded
+ALTER TABLE invoices DROP CONSTRAINT invoices_status_check;
+ALTER TABLE invoices ADD CONSTRAINT invoices_status_check
+    CHECK (status IN ('paid', 'partially_refunded', 'refunded'));
+
+-- +goose Down
+UPDATE invoices SET status = 'paid' WHERE status <> 'paid';
+ALTER TABLE invoices DROP CONSTRAINT invoices_status_check;
+ALTER TABLE invoices ADD CONSTRAINT invoices_status_check CHECK (status IN ('paid'));
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS amount_refunded;
+DROP TABLE IF EXISTS refunds;
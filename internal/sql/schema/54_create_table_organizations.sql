@@ -0,0 +1,76 @@
+-- +goose Up
+-- a plan covers as many members of its subscriber's organization as it has seats, plans
+-- with a single seat are for individuals
+ALTER TABLE payment_plans ADD COLUMN seats INTEGER NOT NULL DEFAULT 1 CHECK (seats >= 1);
+
+-- organizations share their owner's subscription with their members. A user belongs to
+-- one organization at most and the owner is a member too, with the 'owner' role.
+CREATE TABLE organizations (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    owner_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    version INTEGER NOT NULL DEFAULT 1
+);
+
+CREATE TABLE organization_members (
+    organization_id BIGINT NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+    role TEXT NOT NULL CHECK (role IN ('owner', 'admin', 'member')),
+    joined_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (organization_id, user_id)
+);
+
+-- an invitation holds a seat until it expires, inviting the same email again replaces
+-- the earlier invitation and its token
+CREATE TABLE organization_invitations (
+    id BIGSERIAL PRIMARY KEY,
+    organization_id BIGINT NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    email CITEXT NOT NULL,
+    role TEXT NOT NULL CHECK (role IN ('admin', 'member')),
+    token_hash BYTEA NOT NULL UNIQUE,
+    invited_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    expires_at TIMESTAMP(0) WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (organization_id, email)
+);
+
+CREATE TABLE organization_folders (
+    id BIGSERIAL PRIMARY KEY,
+    organization_id BIGINT NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    created_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    version INTEGER NOT NULL DEFAULT 1,
+    UNIQUE (organization_id, name)
+);
+
+-- feeds shared with every member, a feed outside a folder sits at the top level
+CREATE TABLE organization_feeds (
+    organization_id BIGINT NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    feed_id UUID NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+    folder_id BIGINT REFERENCES organization_folders(id) ON DELETE SET NULL,
+    shared_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (organization_id, feed_id)
+);
+
+CREATE INDEX idx_organization_feeds_folder_id ON organization_feeds(folder_id);
+
+ALTER TABLE user_notifications DROP CONSTRAINT user_notifications_notification_type_check;
+ALTER TABLE user_notifications ADD CONSTRAINT user_notifications_notification_type_check
+CHECK (notification_type IN ('new_posts', 'reply', 'mention', 'favorite_comment', 'comment_reaction', 'feed_approved', 'feed_rejected', 'billing', 'follow', 'share', 'organization'));
+
+-- +goose Down
+DELETE FROM user_notifications WHERE notification_type = 'organization';
+ALTER TABLE user_notifications DROP CONSTRAINT user_notifications_notification_type_check;
+ALTER TABLE user_notifications ADD CONSTRAINT user_notifications_notification_type_check
+CHECK (notification_type IN ('new_posts', 'reply', 'mention', 'favorite_comment', 'comment_reaction', 'feed_approved', 'feed_rejected', 'billing', 'follow', 'share'));
+DROP TABLE IF EXISTS organization_feeds;
+DROP TABLE IF EXISTS organization_folders;
+DROP TABLE IF EXISTS organization_invitations;
+DROP TABLE IF EXISTS organization_members;
+DROP TABLE IF EXISTS organizations;
+ALTER TABLE payment_plans DROP COLUMN seats;
@@ -7,7 +7,9 @@ import (
 // Declare a regular expression for sanity checking the format of email addresses. This regular expression pattern is
 // taken from https://html.spec.whatwg.org/#valid-e-mail-address.
 var (
-	EmailRX = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+\\/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
+	// HandleRX matches a user's public handle i.e what follows the @ in a mention
+	HandleRX = regexp.MustCompile("^[a-zA-Z0-9_]{3,30}$")
+	EmailRX  = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+\\/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
 )
 
 // Define a new Validator type which contains a map of validation errors.